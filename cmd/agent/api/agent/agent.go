@@ -54,6 +54,7 @@ func SetupHandlers(r *mux.Router) {
 	r.HandleFunc("/config", getRuntimeConfig).Methods("GET")
 	r.HandleFunc("/tagger-list", getTaggerList).Methods("GET")
 	r.HandleFunc("/secrets", secretInfo).Methods("GET")
+	r.HandleFunc("/stream-logs", streamLogs).Methods("GET")
 }
 
 func stopAgent(w http.ResponseWriter, r *http.Request) {
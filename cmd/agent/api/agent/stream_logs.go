@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package agent
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+)
+
+var (
+	// connections holds the open connections of the server by remote address.
+	connections   = make(map[string]net.Conn)
+	connectionsMu sync.Mutex
+)
+
+// TrackConnection keeps track of the open connections of the server,
+// it's meant to be set as its ConnState hook.
+func TrackConnection(conn net.Conn, state http.ConnState) {
+	connectionsMu.Lock()
+	defer connectionsMu.Unlock()
+	switch state {
+	case http.StateNew:
+		connections[conn.RemoteAddr().String()] = conn
+	case http.StateHijacked, http.StateClosed:
+		delete(connections, conn.RemoteAddr().String())
+	}
+}
+
+// liftWriteTimeout removes the write deadline of the connection of a request,
+// the server write timeout would close the streams otherwise.
+func liftWriteTimeout(r *http.Request) {
+	connectionsMu.Lock()
+	defer connectionsMu.Unlock()
+	if conn, exists := connections[r.RemoteAddr]; exists {
+		conn.SetWriteDeadline(time.Time{})
+	}
+}
+
+// streamLogs streams the logs processed by the logs-agent matching the
+// name, type, source and service query parameters until the client disconnects.
+func streamLogs(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	receiver := logs.GetMessageReceiver()
+	if receiver == nil {
+		http.Error(w, "the logs agent is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	lines, stop := receiver.Listen(diagnostic.Filters{
+		Name:    query.Get("name"),
+		Type:    query.Get("type"),
+		Source:  query.Get("source"),
+		Service: query.Get("service"),
+	})
+	defer stop()
+
+	liftWriteTimeout(r)
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	done := r.Context().Done()
+	for {
+		select {
+		case line, isOpen := <-lines:
+			if !isOpen {
+				return
+			}
+			if _, err := w.Write([]byte(line + "\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-done:
+			return
+		}
+	}
+}
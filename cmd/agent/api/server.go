@@ -86,6 +86,8 @@ func StartServer() error {
 		}, "Error from the agent http API server: ", 0), // log errors to seelog,
 		TLSConfig:    &tlsConfig,
 		WriteTimeout: config.Datadog.GetDuration("server_timeout") * time.Second,
+		// track the connections so that the streaming endpoints can lift the write timeout
+		ConnState: agent.TrackConnection,
 	}
 	tlsListener := tls.NewListener(listener, &tlsConfig)
 
@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package app
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	streamLogsName    string
+	streamLogsType    string
+	streamLogsSource  string
+	streamLogsService string
+)
+
+func init() {
+	AgentCmd.AddCommand(streamLogsCommand)
+	streamLogsCommand.Flags().StringVar(&streamLogsName, "name", "", "Only stream the logs of the integration with this name")
+	streamLogsCommand.Flags().StringVar(&streamLogsType, "type", "", "Only stream the logs of the sources of this type, e.g. file or docker")
+	streamLogsCommand.Flags().StringVar(&streamLogsSource, "source", "", "Only stream the logs with this source")
+	streamLogsCommand.Flags().StringVar(&streamLogsService, "service", "", "Only stream the logs with this service")
+}
+
+var streamLogsCommand = &cobra.Command{
+	Use:   "stream-logs",
+	Short: "Stream the logs being processed by a running agent",
+	Long: `Stream the logs processed by the logs-agent of a running agent once the processing rules
+have been applied, until the command is interrupted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := common.SetupConfigWithoutSecrets(confFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+		if flagNoColor {
+			color.NoColor = true
+		}
+		c := util.GetClient(false) // FIX: get certificates right then make this true
+
+		// Set session token
+		err = util.SetAuthToken()
+		if err != nil {
+			return err
+		}
+
+		query := url.Values{}
+		for key, value := range map[string]string{"name": streamLogsName, "type": streamLogsType, "source": streamLogsSource, "service": streamLogsService} {
+			if value != "" {
+				query.Set(key, value)
+			}
+		}
+		u := fmt.Sprintf("https://localhost:%v/agent/stream-logs?%s", config.Datadog.GetInt("cmd_port"), query.Encode())
+
+		fmt.Fprintln(color.Output, color.GreenString("Streaming the processed logs, press Ctrl+C to stop"))
+		err = util.DoGetLines(c, u, func(line []byte) {
+			fmt.Fprintln(color.Output, string(line))
+		})
+		if err != nil {
+			return fmt.Errorf("could not stream the logs of the agent (running?): %v", err)
+		}
+		return nil
+	},
+}
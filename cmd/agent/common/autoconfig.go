@@ -7,6 +7,7 @@ package common
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers"
@@ -50,7 +51,14 @@ func SetupAutoConfig(confdPath string) {
 		filepath.Join(GetDistPath(), "conf.d"),
 		"",
 	}
-	AC.AddConfigProvider(providers.NewFileConfigProvider(confSearchPaths), false, 0)
+	fileProvider := providers.NewFileConfigProvider(confSearchPaths)
+	if reloadInterval := config.Datadog.GetInt("logs_config.config_reload_interval"); reloadInterval > 0 && logs.IsAgentRunning() {
+		// the logs configurations of the files are polled to be reloaded without restarting the agent
+		log.Infof("Reloading the logs configurations of the configuration files every %ds", reloadInterval)
+		fileProvider.IgnoreLogs()
+		AC.AddConfigProvider(providers.NewLogsFileConfigProvider(confSearchPaths), true, time.Duration(reloadInterval)*time.Second)
+	}
+	AC.AddConfigProvider(fileProvider, false, 0)
 
 	// Register additional configuration providers
 	var CP []config.ConfigurationProviders
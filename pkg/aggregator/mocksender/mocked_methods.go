@@ -59,6 +59,11 @@ func (m *MockSender) Event(e metrics.Event) {
 	m.Called(e)
 }
 
+//Log enables the log mock call.
+func (m *MockSender) Log(message string, status string, hostname string, tags []string) {
+	m.Called(message, status, hostname, tags)
+}
+
 //Commit enables the commit mock call.
 func (m *MockSender) Commit() {
 	m.Called()
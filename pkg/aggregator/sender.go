@@ -21,6 +21,22 @@ var senderInstance *checkSender
 var senderInit sync.Once
 var senderPool *checkSenderPool
 
+// LogSubmitter hands a log submitted by the check checkName to the logs-agent, it returns an error if the log is dropped.
+type LogSubmitter func(checkName, message, status, hostname string, tags []string) error
+
+var (
+	logSubmitterMu sync.RWMutex
+	logSubmitter   LogSubmitter
+)
+
+// SetLogSubmitter sets the function the logs of the checks are submitted with, the logs-agent sets it when it starts
+// so that the aggregator does not depend on it. The logs are dropped while it's nil.
+func SetLogSubmitter(submitter LogSubmitter) {
+	logSubmitterMu.Lock()
+	defer logSubmitterMu.Unlock()
+	logSubmitter = submitter
+}
+
 // Sender allows sending metrics from checks/a check
 type Sender interface {
 	Commit()
@@ -33,6 +49,7 @@ type Sender interface {
 	Historate(metric string, value float64, hostname string, tags []string)
 	ServiceCheck(checkName string, status metrics.ServiceCheckStatus, hostname string, tags []string, message string)
 	Event(e metrics.Event)
+	Log(message string, status string, hostname string, tags []string)
 	GetMetricStats() map[string]int64
 	DisableDefaultHostname(disable bool)
 	SetCheckCustomTags(tags []string)
@@ -309,6 +326,25 @@ func (s *checkSender) Event(e metrics.Event) {
 	s.metricStats.Lock.Unlock()
 }
 
+// Log submits a log to the logs-agent with the LogSubmitter it set, the logs of a check are collected with
+// the source of type check of its configuration, or a source named after it, and are dropped if the logs-agent is not running.
+func (s *checkSender) Log(message string, status string, hostname string, tags []string) {
+	tags = append(tags, s.checkTags...)
+
+	log.Trace("Log submitted with status: ", status, " for hostname: ", hostname, " tags: ", tags)
+
+	logSubmitterMu.RLock()
+	submit := logSubmitter
+	logSubmitterMu.RUnlock()
+	if submit == nil {
+		log.Debugf("Could not submit the log of the check %s: the logs-agent is not running", s.id)
+		return
+	}
+	if err := submit(check.IDToCheckName(s.id), message, status, hostname, tags); err != nil {
+		log.Debugf("Could not submit the log of the check %s: %v", s.id, err)
+	}
+}
+
 // changeAllSendersDefaultHostname u
 func (sp *checkSenderPool) changeAllSendersDefaultHostname(hostname string) {
 	sp.m.Lock()
@@ -254,6 +254,33 @@ func TestGetSenderAddCheckCustomTagsEvent(t *testing.T) {
 	assert.Equal(t, append(checkTags, customTags...), e.Tags)
 }
 
+func TestGetSenderAddCheckCustomTagsLog(t *testing.T) {
+	type submittedLog struct {
+		checkName, message, status, hostname string
+		tags                                 []string
+	}
+	var submitted []submittedLog
+	SetLogSubmitter(func(checkName, message, status, hostname string, tags []string) error {
+		submitted = append(submitted, submittedLog{checkName, message, status, hostname, tags})
+		return nil
+	})
+	defer SetLogSubmitter(nil)
+
+	checkSender := newCheckSender("okta:1a2b3c", "", nil, nil, nil)
+	checkSender.SetCheckCustomTags([]string{"custom:tag1"})
+
+	checkSender.Log("user logged in", "warn", "my-hostname", []string{"check:tag1"})
+	// the logs are submitted with the name of the check.
+	assert.Equal(t, []submittedLog{{"okta", "user logged in", "warn", "my-hostname", []string{"check:tag1", "custom:tag1"}}}, submitted)
+}
+
+func TestLogIsDroppedWithoutLogSubmitter(t *testing.T) {
+	checkSender := newCheckSender("okta:1a2b3c", "", nil, nil, nil)
+	assert.NotPanics(t, func() {
+		checkSender.Log("user logged in", "warn", "", nil)
+	})
+}
+
 func TestCheckSenderInterface(t *testing.T) {
 	senderMetricSampleChan := make(chan senderMetricSample, 10)
 	serviceCheckChan := make(chan metrics.ServiceCheck, 10)
@@ -6,6 +6,7 @@
 package util
 
 import (
+	"bufio"
 	"crypto/tls"
 	"fmt"
 	"io"
@@ -51,6 +52,32 @@ func DoGet(c *http.Client, url string) (body []byte, e error) {
 
 }
 
+// DoGetLines is a wrapper around performing HTTP GET requests streaming their response,
+// onLine is called for each line of the response until the server closes it.
+func DoGetLines(c *http.Client, url string, onLine func(line []byte)) error {
+	req, e := http.NewRequest("GET", url, nil)
+	if e != nil {
+		return e
+	}
+	req.Header.Set("Authorization", "Bearer "+GetAuthToken())
+
+	r, e := c.Do(req)
+	if e != nil {
+		return e
+	}
+	defer r.Body.Close()
+	if r.StatusCode >= 400 {
+		body, _ := ioutil.ReadAll(r.Body)
+		return fmt.Errorf("%s", body)
+	}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		onLine(scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
 // DoPost is a wrapper around performing HTTP POST requests
 func DoPost(c *http.Client, url string, contentType string, body io.Reader) (resp []byte, e error) {
 	req, e := http.NewRequest("POST", url, body)
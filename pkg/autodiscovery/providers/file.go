@@ -45,8 +45,9 @@ type configEntry struct {
 
 // FileConfigProvider collect configuration files from disk
 type FileConfigProvider struct {
-	paths  []string
-	Errors map[string]string
+	paths      []string
+	Errors     map[string]string
+	ignoreLogs bool
 }
 
 // NewFileConfigProvider creates a new FileConfigProvider searching for
@@ -58,6 +59,12 @@ func NewFileConfigProvider(paths []string) *FileConfigProvider {
 	}
 }
 
+// IgnoreLogs makes the provider leave out the logs configurations of the files which are not templates,
+// they are collected by a LogsFileConfigProvider instead so that they can be reloaded at runtime.
+func (c *FileConfigProvider) IgnoreLogs() {
+	c.ignoreLogs = true
+}
+
 // Collect scans provided paths searching for configuration files. When found,
 // it parses the files and try to unmarshall Yaml contents into a CheckConfig
 // instance
@@ -124,9 +131,29 @@ func (c *FileConfigProvider) Collect() ([]integration.Config, error) {
 		}
 	}
 
+	if c.ignoreLogs {
+		configs = withoutLogs(configs)
+	}
+
 	return configs, nil
 }
 
+// withoutLogs returns the configs without the logs configurations of the configs which are not templates,
+// the configs left with neither instances nor metrics are dropped.
+func withoutLogs(configs []integration.Config) []integration.Config {
+	filtered := []integration.Config{}
+	for _, config := range configs {
+		if !config.IsTemplate() {
+			config.LogsConfig = nil
+			if len(config.Instances) == 0 && config.MetricConfig == nil {
+				continue
+			}
+		}
+		filtered = append(filtered, config)
+	}
+	return filtered
+}
+
 // IsUpToDate is not implemented for the file Providers as the files are not meant to change very often.
 func (c *FileConfigProvider) IsUpToDate() (bool, error) {
 	return false, nil
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package providers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+)
+
+// LogsFileConfigProvider collects the logs configurations of the configuration files which are not templates,
+// it's meant to be polled so that the logs configurations added, changed or removed are applied at runtime.
+type LogsFileConfigProvider struct {
+	files       *FileConfigProvider
+	fingerprint string
+}
+
+// NewLogsFileConfigProvider creates a new LogsFileConfigProvider searching for
+// configuration files on the given paths
+func NewLogsFileConfigProvider(paths []string) *LogsFileConfigProvider {
+	return &LogsFileConfigProvider{
+		files: NewFileConfigProvider(paths),
+	}
+}
+
+// Collect returns the logs configurations of the configuration files, the configs only hold
+// their name and their logs configuration so that they change only when the latter changes.
+func (c *LogsFileConfigProvider) Collect() ([]integration.Config, error) {
+	c.fingerprint = c.fingerprintFiles()
+	configs, err := c.files.Collect()
+	if err != nil {
+		return nil, err
+	}
+	logsConfigs := []integration.Config{}
+	for _, config := range configs {
+		if config.LogsConfig == nil || config.IsTemplate() {
+			continue
+		}
+		logsConfigs = append(logsConfigs, integration.Config{
+			Name:       config.Name,
+			LogsConfig: config.LogsConfig,
+			// the configs removed are unscheduled as they were collected, the provider must be set for the
+			// logs scheduler to find their sources
+			Provider: File,
+		})
+	}
+	return logsConfigs, nil
+}
+
+// IsUpToDate returns true if none of the configuration files has been added, modified or removed since the last Collect.
+func (c *LogsFileConfigProvider) IsUpToDate() (bool, error) {
+	return c.fingerprint == c.fingerprintFiles(), nil
+}
+
+// String returns a string representation of the LogsFileConfigProvider, the logs scheduler
+// parses the configs of the file provider only.
+func (c *LogsFileConfigProvider) String() string {
+	return File
+}
+
+// fingerprintFiles returns a digest of the names, sizes and modification times of the files
+// of the paths and of their integration folders.
+func (c *LogsFileConfigProvider) fingerprintFiles() string {
+	h := fnv.New64()
+	for _, path := range c.files.paths {
+		entries, err := readDirPtr(path)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				fmt.Fprintf(h, "%s:%d:%d\n", filepath.Join(path, entry.Name()), entry.Size(), entry.ModTime().UnixNano())
+				continue
+			}
+			if filepath.Ext(entry.Name()) != ".d" {
+				continue
+			}
+			dirPath := filepath.Join(path, entry.Name())
+			subEntries, err := readDirPtr(dirPath)
+			if err != nil {
+				continue
+			}
+			for _, sEntry := range subEntries {
+				if !sEntry.IsDir() {
+					fmt.Fprintf(h, "%s:%d:%d\n", filepath.Join(dirPath, sEntry.Name()), sEntry.Size(), sEntry.ModTime().UnixNano())
+				}
+			}
+		}
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestLogsFileConfigProvider(t *testing.T) {
+	confd, err := ioutil.TempDir("", "conf.d")
+	require.Nil(t, err)
+	defer os.RemoveAll(confd)
+
+	writeConfigFile(t, filepath.Join(confd, "foo.d", "conf.yaml"), "instances:\n  - {}\nlogs:\n  - type: file\n    path: /var/log/foo.log\n")
+	writeConfigFile(t, filepath.Join(confd, "bar.d", "conf.yaml"), "logs:\n  - type: file\n    path: /var/log/bar.log\n")
+	writeConfigFile(t, filepath.Join(confd, "baz.d", "conf.yaml"), "ad_identifiers:\n  - baz\nlogs:\n  - type: docker\n")
+
+	files := NewFileConfigProvider([]string{confd})
+	files.IgnoreLogs()
+	configs, err := files.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 2)
+	for _, config := range configs {
+		switch config.Name {
+		case "foo":
+			assert.Nil(t, config.LogsConfig)
+			assert.Len(t, config.Instances, 1)
+		case "baz":
+			// the logs configurations of the templates are not reloaded
+			assert.NotNil(t, config.LogsConfig)
+		default:
+			assert.Fail(t, "unexpected config", config.Name)
+		}
+	}
+
+	provider := NewLogsFileConfigProvider([]string{confd})
+	configs, err = provider.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 2)
+	for _, config := range configs {
+		assert.Contains(t, []string{"foo", "bar"}, config.Name)
+		assert.Contains(t, string(config.LogsConfig), "/var/log/"+config.Name+".log")
+		assert.Nil(t, config.Instances)
+		assert.Equal(t, File, config.Provider)
+	}
+
+	upToDate, err := provider.IsUpToDate()
+	assert.Nil(t, err)
+	assert.True(t, upToDate)
+
+	writeConfigFile(t, filepath.Join(confd, "bar.d", "conf.yaml"), "logs:\n  - type: file\n    path: /var/log/bar/*.log\n")
+	upToDate, err = provider.IsUpToDate()
+	assert.Nil(t, err)
+	assert.False(t, upToDate)
+
+	configs, err = provider.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 2)
+	upToDate, err = provider.IsUpToDate()
+	assert.Nil(t, err)
+	assert.True(t, upToDate)
+
+	require.Nil(t, os.RemoveAll(filepath.Join(confd, "foo.d")))
+	upToDate, err = provider.IsUpToDate()
+	assert.Nil(t, err)
+	assert.False(t, upToDate)
+
+	configs, err = provider.Collect()
+	require.Nil(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "bar", configs[0].Name)
+	assert.Contains(t, string(configs[0].LogsConfig), "/var/log/bar/*.log")
+}
@@ -10,6 +10,7 @@
 PyObject* SubmitMetric(PyObject*, char*, MetricType, char*, float, PyObject*, char*);
 PyObject* SubmitServiceCheck(PyObject*, char*, char*, int, PyObject*, char*, char*);
 PyObject* SubmitEvent(PyObject*, char*, PyObject*);
+PyObject* SubmitLog(PyObject*, char*, char*, char*, PyObject*, char*);
 
 // _must_ be in the same order as the MetricType enum
 char* MetricTypeNames[] = {
@@ -84,10 +85,32 @@ static PyObject *submit_event(PyObject *self, PyObject *args) {
     return SubmitEvent(check, check_id, event);
 }
 
+static PyObject *submit_log(PyObject *self, PyObject *args) {
+    PyObject *check = NULL;
+    char *message;
+    char *status;
+    PyObject *tags = NULL;
+    char *hostname;
+    char *check_id;
+
+    PyGILState_STATE gstate;
+    gstate = PyGILState_Ensure();
+
+    // aggregator.submit_log(self, check_id, message, status, tags, hostname)
+    if (!PyArg_ParseTuple(args, "OsssOs", &check, &check_id, &message, &status, &tags, &hostname)) {
+      PyGILState_Release(gstate);
+      return NULL;
+    }
+
+    PyGILState_Release(gstate);
+    return SubmitLog(check, check_id, message, status, tags, hostname);
+}
+
 static PyMethodDef AggMethods[] = {
   {"submit_metric", (PyCFunction)submit_metric, METH_VARARGS, "Submit metrics to the aggregator."},
   {"submit_service_check", (PyCFunction)submit_service_check, METH_VARARGS, "Submit service checks to the aggregator."},
   {"submit_event", (PyCFunction)submit_event, METH_VARARGS, "Submit events to the aggregator."},
+  {"submit_log", (PyCFunction)submit_log, METH_VARARGS, "Submit logs to the logs-agent."},
   {NULL, NULL}  // guards
 };
 
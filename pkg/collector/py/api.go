@@ -129,6 +129,38 @@ func SubmitEvent(check *C.PyObject, checkID *C.char, event *C.PyObject) *C.PyObj
 	return C._none()
 }
 
+// SubmitLog is the method exposed to Python scripts to submit logs
+//export SubmitLog
+func SubmitLog(check *C.PyObject, checkID *C.char, message *C.char, status *C.char, tags *C.PyObject, hostname *C.char) *C.PyObject {
+
+	goCheckID := C.GoString(checkID)
+	var sender aggregator.Sender
+	var err error
+
+	sender, err = aggregator.GetSender(chk.ID(goCheckID))
+
+	if err != nil || sender == nil {
+		log.Errorf("Error submitting log to the Sender: %v", err)
+		return C._none()
+	}
+
+	_message := C.GoString(message)
+	_status := C.GoString(status)
+	_tags, err := extractTags(tags, goCheckID)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	_hostname := C.GoString(hostname)
+
+	// the GIL is released while the log is handed to the logs-agent so that the other python threads can run.
+	threadState := SaveThreadState()
+	sender.Log(_message, _status, _hostname, _tags)
+	C.PyEval_RestoreThread(threadState)
+
+	return C._none()
+}
+
 // extractEventFromDict returns an `Event` populated with the fields of the passed event py object
 // The caller needs to check the returned `error`, any non-nil value indicates that the error flag is set
 // on the python interpreter.
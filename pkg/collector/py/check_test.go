@@ -215,6 +215,7 @@ func TestAggregatorLink(t *testing.T) {
 	mockSender.On("Counter", "test.increment", 1., "", []string{"foo", "bar"}).Return().Times(1)
 	mockSender.On("Counter", "test.decrement", -1., "", []string{"foo", "bar", "baz"}).Return().Times(1)
 	mockSender.On("Event", mock.AnythingOfType("metrics.Event")).Return().Times(1)
+	mockSender.On("Log", "test log", "warn", "", []string{"foo", "bar"}).Return().Times(1)
 	mockSender.On("Commit").Return().Times(1)
 
 	err := check.Run()
@@ -242,6 +243,7 @@ func TestAggregatorLinkTwoRuns(t *testing.T) {
 	mockSender.On("Counter", "test.increment", 1., "", []string{"foo", "bar"}).Return().Times(2)
 	mockSender.On("Counter", "test.decrement", -1., "", []string{"foo", "bar", "baz"}).Return().Times(2)
 	mockSender.On("Event", mock.AnythingOfType("metrics.Event")).Return().Times(2)
+	mockSender.On("Log", "test log", "warn", "", []string{"foo", "bar"}).Return().Times(2)
 	mockSender.On("Commit").Return().Times(2)
 
 	err := check.Run()
@@ -5,6 +5,7 @@
 
 from datetime import datetime
 from checks import AgentCheck
+import aggregator
 
 
 class TestAggregatorCheck(AgentCheck):
@@ -34,3 +35,5 @@ class TestAggregatorCheck(AgentCheck):
             "msg_text": "test event test event",
             "tags": None
         })
+
+        aggregator.submit_log(self, self.check_id, "test log", "warn", ["foo", "bar"], "")
@@ -91,6 +91,12 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("app_key", "")
 	config.SetDefault("proxy", nil)
 	config.BindEnvAndSetDefault("skip_ssl_validation", false)
+	// FIPS mode: the data are sent through the local FIPS proxy listening on the ports from
+	// fips.port_range_start, or directly with TLS restricted to FIPS-approved algorithms when fips.use_proxy is false
+	config.BindEnvAndSetDefault("fips.enabled", false)
+	config.BindEnvAndSetDefault("fips.use_proxy", true)
+	config.BindEnvAndSetDefault("fips.local_address", "localhost")
+	config.BindEnvAndSetDefault("fips.port_range_start", 9803)
 	config.BindEnvAndSetDefault("hostname", "")
 	config.BindEnvAndSetDefault("tags", []string{})
 	config.BindEnvAndSetDefault("tag_value_split_separator", map[string]string{})
@@ -329,21 +335,137 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("log_enabled", false) // deprecated, use logs_enabled instead
 	// collect all logs from all containers:
 	config.BindEnvAndSetDefault("logs_config.container_collect_all", false)
+	// tag the logs of the files and containers without service with the name of the process writing them or their short image name:
+	config.BindEnvAndSetDefault("logs_config.auto_service_tagging", false)
+	// run as an AWS Lambda extension collecting the logs of the function through the Telemetry API on a local port:
+	config.BindEnvAndSetDefault("logs_config.lambda_extension", false)
+	config.BindEnvAndSetDefault("logs_config.lambda_telemetry_port", 8124)
+	// collect the events of the kubernetes cluster as logs, only the leader collects them when leader election is enabled:
+	config.BindEnvAndSetDefault("logs_config.kubernetes_events", false)
+	// podman storage directories where the logs of the containers using the k8s-file driver are looked for, rootless included:
+	config.BindEnvAndSetDefault("logs_config.podman_storage_paths", []string{"/var/lib/containers/storage", "/run/containers/storage", "/home/*/.local/share/containers/storage", "/run/user/*/containers"})
 	// add a socks5 proxy:
 	config.BindEnvAndSetDefault("logs_config.socks5_proxy_address", "")
+	// authenticate with the socks5 proxy:
+	config.BindEnvAndSetDefault("logs_config.socks5_proxy_username", "")
+	config.BindEnvAndSetDefault("logs_config.socks5_proxy_password", "")
+	// tunnel the TCP connections through the proxy set in 'proxy.https':
+	config.BindEnvAndSetDefault("logs_config.use_https_proxy", false)
 	// send the logs to a proxy:
 	config.BindEnv("logs_config.logs_dd_url") // must respect format '<HOST>:<PORT>' and '<PORT>' to be an integer
 	// specific logs-agent api-key
 	config.BindEnv("logs_config.api_key")
+	// time in seconds between two reads of the api key in the configuration file so that a rotated key is used without restart, 0 disables it:
+	config.BindEnvAndSetDefault("logs_config.api_key_refresh_interval", 0)
 	config.BindEnvAndSetDefault("logs_config.logs_no_ssl", false)
 	// send the logs to the port 443 of the logs-backend via TCP:
 	config.BindEnvAndSetDefault("logs_config.use_port_443", false)
-	// increase the read buffer size of the UDP sockets:
+	// send the logs in batches to the logs-backend via HTTPS:
+	config.BindEnvAndSetDefault("logs_config.use_http", false)
+	// maximum time in seconds a log waits before its batch is sent over HTTPS:
+	config.BindEnvAndSetDefault("logs_config.batch_wait", 5)
+	// increase the read buffer size of the UDP sockets and of the TCP connections:
 	config.BindEnvAndSetDefault("logs_config.frame_size", 9000)
 	// increase the number of files that can be tailed in parallel:
 	config.BindEnvAndSetDefault("logs_config.open_files_limit", 100)
+	// order in which the files matching a wildcard path are tailed when they exceed open_files_limit, by_name or by_modification_time:
+	config.BindEnvAndSetDefault("logs_config.file_wildcard_selection_mode", "by_name")
+	// files evicted to tail the ones over open_files_limit, none or least_recently_written:
+	config.BindEnvAndSetDefault("logs_config.open_files_eviction", "none")
+	// tail the files the symlinks matching the paths of the file sources link to instead of the links:
+	config.BindEnvAndSetDefault("logs_config.follow_symlinks", false)
+	// maximum age in hours and total size in bytes of the gzip archives read to backfill the logs of the sources with backfill_archives:
+	config.BindEnvAndSetDefault("logs_config.backfill_max_age_hours", 24)
+	config.BindEnvAndSetDefault("logs_config.backfill_max_size_bytes", 100*1024*1024)
+	// number of pipelines processing and sending logs in parallel, the logs of a source always use the same pipeline:
+	config.BindEnvAndSetDefault("logs_config.pipelines", 4)
+	// number of logs the channels between the components of a pipeline hold, the inputs wait for the pipeline once they are full:
+	config.BindEnvAndSetDefault("logs_config.pipeline_channel_size", 100)
+	// size in bytes of the Go heap and resident set size above which the pipelines shed the debug logs and shrink their buffers, 0 disables the check:
+	config.BindEnvAndSetDefault("logs_config.memory_heap_threshold_bytes", 0)
+	config.BindEnvAndSetDefault("logs_config.memory_rss_threshold_bytes", 0)
+	// time in seconds between two delivery canaries injected in the pipeline of each source to troubleshoot missing logs, 0 disables them:
+	config.BindEnvAndSetDefault("logs_config.delivery_canary_interval", 0)
+	// time in seconds between two checks of the configuration files for logs configurations added, changed or removed, 0 disables the reload:
+	config.BindEnvAndSetDefault("logs_config.config_reload_interval", 0)
+	// time in milliseconds to wait for the next line of a multi-line log, and maximum size in bytes of a multi-line log:
+	config.BindEnvAndSetDefault("logs_config.multi_line_flush_timeout", 1000)
+	config.BindEnvAndSetDefault("logs_config.multi_line_max_size", 256*1000)
+	// time in milliseconds a run of identical lines of a source with dedup_lines is held at most before being sent:
+	config.BindEnvAndSetDefault("logs_config.dedup_window", 10000)
+	// length above which the lines are split, or truncated with truncate_oversized_messages:
+	config.BindEnvAndSetDefault("logs_config.max_message_size_bytes", 256*1000)
+	config.BindEnvAndSetDefault("logs_config.truncate_oversized_messages", false)
+	// number of lines sampled, and fraction of them that must start with the same timestamp format, to detect multi-line logs:
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_sample_size", 500)
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_match_threshold", 0.1)
+	// attach the host tags to the logs instead of relying on the intake to add them:
+	config.BindEnvAndSetDefault("logs_config.attach_host_tags", false)
 	// add global processing rules that are applied on all logs
 	config.BindEnv("logs_config.processing_rules")
+	// add the rules of the sensitive data scanner applied to the content of all logs before they leave the host
+	config.BindEnv("logs_config.sds_rules")
+	// configure the exponential backoff applied between two connection attempts to the intake, in seconds:
+	config.BindEnvAndSetDefault("logs_config.connection_backoff_base", 1)
+	config.BindEnvAndSetDefault("logs_config.connection_backoff_max", 120)
+	// stop retrying to connect after a number of consecutive failures, 0 means retrying indefinitely:
+	config.BindEnvAndSetDefault("logs_config.connection_max_retries", 0)
+	// give up on an HTTP batch the intake keeps failing on after a number of retries, 0 means retrying indefinitely:
+	config.BindEnvAndSetDefault("logs_config.batch_max_retries", 10)
+	// bound the time spent to connect and to write to the intake, a write timeout of 0 means no timeout:
+	config.BindEnvAndSetDefault("logs_config.dial_timeout", 20)
+	config.BindEnvAndSetDefault("logs_config.ssl_handshake_timeout", 20)
+	config.BindEnvAndSetDefault("logs_config.write_timeout", 30)
+	// detect and recycle the connections dropped by NATs or firewalls, 0 disables them:
+	config.BindEnvAndSetDefault("logs_config.tcp_keepalive", 30)
+	config.BindEnvAndSetDefault("logs_config.idle_connection_timeout", 0)
+	// restrict the connections to the intake to "ipv4" or "ipv6" addresses:
+	config.BindEnvAndSetDefault("logs_config.ip_family", "any")
+	// rotate the connections to the addresses the intake does not resolve to anymore, 0 disables it:
+	config.BindEnvAndSetDefault("logs_config.dns_refresh_interval", 300)
+	// drop the logs of an additional endpoint for a while after consecutive failures, 0 disables it:
+	config.BindEnvAndSetDefault("logs_config.circuit_breaker_threshold", 5)
+	config.BindEnvAndSetDefault("logs_config.circuit_breaker_cooldown", 30)
+	// forward the logs to an aggregator agent which sends them to the intake, disabled when the url is empty:
+	config.BindEnvAndSetDefault("logs_config.aggregator_url", "")
+	config.BindEnvAndSetDefault("logs_config.aggregator_no_ssl", false)
+	// fail over to the first of the fallback endpoints that is reachable when the main endpoint is not:
+	config.BindEnvAndSetDefault("logs_config.failover_retries", 3)
+	config.BindEnvAndSetDefault("logs_config.failback_interval", 300)
+	// use the endpoint with the lowest connection latency among the main and fallback endpoints with latency:
+	config.BindEnvAndSetDefault("logs_config.fallback_endpoints_selection", "ordered")
+	// buffer the logs on disk when the intake is unreachable, disabled when the path is empty:
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_path", "")
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_size", 100*1024*1024)
+	// write the HTTP batches given up on to a quarantine directory, disabled when the path is empty:
+	config.BindEnvAndSetDefault("logs_config.quarantine_path", "")
+	config.BindEnvAndSetDefault("logs_config.quarantine_max_size", 10*1024*1024)
+	// send HTTP batches before the previous ones are acknowledged by the intake:
+	config.BindEnvAndSetDefault("logs_config.max_inflight_batches", 1)
+	// format of the HTTP payloads, json or protobuf:
+	config.BindEnvAndSetDefault("logs_config.http_payload_format", "json")
+	// compress the HTTP payloads with gzip, from 1 (best speed) to 9 (best compression):
+	config.BindEnvAndSetDefault("logs_config.use_compression", false)
+	config.BindEnvAndSetDefault("logs_config.compression_level", 6)
+	// increase the number of TCP connections each pipeline can use to send logs:
+	config.BindEnvAndSetDefault("logs_config.connection_pool_size", 1)
+	// maximum number of logs written at once over TCP, and time in milliseconds to wait for them, 0 only writes the logs already queued:
+	config.BindEnvAndSetDefault("logs_config.tcp_max_batch_size", 100)
+	config.BindEnvAndSetDefault("logs_config.tcp_batch_linger", 0)
+	// PEM files to verify the intake with a custom CA bundle and to authenticate with a client certificate:
+	config.BindEnvAndSetDefault("logs_config.ca_file", "")
+	config.BindEnvAndSetDefault("logs_config.cert_file", "")
+	config.BindEnvAndSetDefault("logs_config.key_file", "")
+	// decrypt the key file when it's encrypted, it can be a secret handle:
+	config.BindEnvAndSetDefault("logs_config.key_passphrase", "")
+	// restrict the TLS versions and cipher suites negotiated with the intake:
+	config.BindEnvAndSetDefault("logs_config.min_tls_version", "")
+	config.BindEnvAndSetDefault("logs_config.cipher_suites", []string{})
+	// base64 encoded SHA-256 digests of the public keys the certificate chain of the intake must contain:
+	config.BindEnvAndSetDefault("logs_config.pinned_public_keys", []string{})
+	// cap the outbound traffic of the logs agent, 0 means unlimited:
+	config.BindEnvAndSetDefault("logs_config.max_bytes_per_second", 0)
+	config.BindEnvAndSetDefault("logs_config.max_events_per_second", 0)
 
 	// Internal Use Only: avoid modifying those configuration parameters, this could lead to unexpected results.
 	config.BindEnvAndSetDefault("logset", "")
@@ -351,7 +473,9 @@ func initConfig(config Config) {
 	config.BindEnv("logs_config.dd_url")
 	config.BindEnvAndSetDefault("logs_config.dd_port", 10516)
 	config.BindEnvAndSetDefault("logs_config.dev_mode_use_proto", true)
-	config.BindEnvAndSetDefault("logs_config.dd_url_443", "agent-443-intake.logs.datadoghq.com")
+	// delimit the raw logs sent without protobuf with "line_break" or with their length with "octet_counting":
+	config.BindEnvAndSetDefault("logs_config.raw_framing", "line_break")
+	config.BindEnv("logs_config.dd_url_443")
 	config.BindEnvAndSetDefault("logs_config.stop_grace_period", 30)
 
 	// The cardinality of tags to send for checks and dogstatsd respectively.
@@ -193,6 +193,13 @@ func createArchive(zipFilePath string, local bool, confSearchPaths SearchPaths,
 		log.Errorf("Could not zip health check: %s", err)
 	}
 
+	if config.Datadog.GetBool("logs_enabled") || config.Datadog.GetBool("log_enabled") {
+		err = zipLogsAgent(tempDir, hostname)
+		if err != nil {
+			log.Errorf("Could not zip logs agent diagnostics: %s", err)
+		}
+	}
+
 	err = zipStackTraces(tempDir, hostname)
 	if err != nil {
 		log.Errorf("Could not collect go routine stack traces: %s", err)
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package flare
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	logsStatus "github.com/DataDog/datadog-agent/pkg/logs/status"
+)
+
+// logsTLSDiagnosisTimeout bounds the time spent diagnosing the SSL handshakes with the intakes.
+const logsTLSDiagnosisTimeout = 10 * time.Second
+
+// zipLogsAgent adds the diagnostics of the logs-agent to the flare: its registry, the status of its sources,
+// the last errors of its destinations, how its endpoints are reached and the outcome of the SSL handshakes with them.
+func zipLogsAgent(tempDir, hostname string) error {
+	dir := filepath.Join(tempDir, hostname, "logs-agent")
+
+	registry, err := ioutil.ReadFile(filepath.Join(config.Datadog.GetString("logs_config.run_path"), "registry.json"))
+	if err != nil {
+		registry = []byte(fmt.Sprintf("could not read the registry: %v", err))
+	}
+	if err := writeLogsAgentFile(dir, "registry.json", registry); err != nil {
+		return err
+	}
+
+	sources, err := yaml.Marshal(logsStatus.Get())
+	if err != nil {
+		return err
+	}
+	if err := writeLogsAgentFile(dir, "sources.yaml", sources); err != nil {
+		return err
+	}
+
+	var errors bytes.Buffer
+	for _, e := range client.RecentErrors() {
+		fmt.Fprintf(&errors, "%s %s: %s\n", e.Time.Format(time.RFC3339), e.Address, e.Error)
+	}
+	if err := writeLogsAgentFile(dir, "sender_errors.log", errors.Bytes()); err != nil {
+		return err
+	}
+
+	var endpoints, handshakes bytes.Buffer
+	resolved, err := sender.BuildEndpoints()
+	if err != nil {
+		fmt.Fprintf(&endpoints, "Invalid endpoints: %v\n", err)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), logsTLSDiagnosisTimeout)
+		defer cancel()
+		fmt.Fprintf(&endpoints, "Use HTTP: %v\n", resolved.UseHTTP)
+		for i, endpoint := range append([]client.Endpoint{resolved.Main}, resolved.Additionals...) {
+			name := "Main endpoint"
+			if i > 0 {
+				name = fmt.Sprintf("Additional endpoint #%d", i)
+			}
+			fmt.Fprintf(&endpoints, "\n=== %s ===\n%s", name, client.DescribeEndpoint(endpoint))
+			if endpoint.UseSSL {
+				fmt.Fprintf(&handshakes, "=== %s ===\n%s\n", name, client.DiagnoseTLS(ctx, endpoint))
+			}
+		}
+	}
+	if err := writeLogsAgentFile(dir, "endpoints.log", endpoints.Bytes()); err != nil {
+		return err
+	}
+	return writeLogsAgentFile(dir, "tls.log", handshakes.Bytes())
+}
+
+// writeLogsAgentFile writes a file of the diagnostics of the logs-agent with its secrets redacted.
+func writeLogsAgentFile(dir, name string, data []byte) error {
+	f := filepath.Join(dir, name)
+	err := ensureParentDirsExist(f)
+	if err != nil {
+		return err
+	}
+
+	w, err := newRedactingWriter(f, os.ModePerm, true)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = w.Write(data)
+	return err
+}
@@ -145,6 +145,35 @@ func TestZipConfigCheck(t *testing.T) {
 	assert.NotContains(t, string(content), "MySecurePass")
 }
 
+func TestZipLogsAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestZipLogsAgent")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mockConfig := config.Mock()
+	mockConfig.Set("logs_config.run_path", dir)
+	mockConfig.Set("logs_config.logs_dd_url", "localhost:10516")
+	mockConfig.Set("logs_config.logs_no_ssl", true)
+	err = ioutil.WriteFile(filepath.Join(dir, "registry.json"), []byte(`{"Version":2,"Registry":{}}`), 0644)
+	assert.Nil(t, err)
+
+	err = zipLogsAgent(dir, "test")
+	assert.Nil(t, err)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "test", "logs-agent", "registry.json"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `"Version":2`)
+	content, err = ioutil.ReadFile(filepath.Join(dir, "test", "logs-agent", "endpoints.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "Address: localhost:10516")
+	for _, name := range []string{"sources.yaml", "sender_errors.log", "tls.log"} {
+		_, err = os.Stat(filepath.Join(dir, "test", "logs-agent", name))
+		assert.Nil(t, err)
+	}
+}
+
 func TestIncludeConfigFiles(t *testing.T) {
 	assert := assert.New(t)
 
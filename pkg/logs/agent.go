@@ -6,6 +6,7 @@
 package logs
 
 import (
+	"context"
 	"time"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
@@ -13,16 +14,32 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/canary"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/auditd"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/checks"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/container"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/file"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/forward"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/intake"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/journald"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/kafka"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/kubeevents"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/lambda"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/listener"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/otlp"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/s3"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/windowsevent"
+	"github.com/DataDog/datadog-agent/pkg/logs/memory"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+	"github.com/DataDog/datadog-agent/pkg/logs/sds"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
+	"github.com/DataDog/datadog-agent/pkg/logs/tag"
 )
 
 // Agent represents the data pipeline that collects, decodes,
@@ -36,12 +53,17 @@ type Agent struct {
 	auditor          *auditor.Auditor
 	destinationsCtx  *client.DestinationsContext
 	pipelineProvider pipeline.Provider
+	diskBuffers      []*sender.DiskBuffer
+	hostTags         tag.Provider
+	diagnostics      *diagnostic.BufferedMessageReceiver
+	apiKeyRefresher  *sender.APIKeyRefresher
+	memoryMonitor    *memory.Monitor
 	inputs           []restart.Restartable
 	health           *health.Handle
 }
 
-// NewAgent returns a new Agent
-func NewAgent(sources *config.LogSources, services *service.Services, processingRules []*config.ProcessingRule, endpoints *client.Endpoints) *Agent {
+// NewAgent returns a new Agent, its pipelines scan the logs with scanner if it's not nil
+func NewAgent(sources *config.LogSources, services *service.Services, processingRules []*config.ProcessingRule, scanner *sds.Scanner, endpoints *client.Endpoints) *Agent {
 	health := health.Register("logs-agent")
 
 	// setup the auditor
@@ -50,22 +72,92 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	auditor := auditor.New(coreConfig.Datadog.GetString("logs_config.run_path"), health)
 	destinationsCtx := client.NewDestinationsContext()
 
+	// setup the quarantine the HTTP batches the intake would never accept are written to
+	quarantine, err := sender.BuildQuarantine()
+	if err != nil {
+		log.Warnf("Could not create the quarantine, the batches given up on will be dropped: %v", err)
+		quarantine = nil
+	}
+
+	// setup the provider of the host tags the processors attach to the logs
+	hostTags := tag.NoopProvider
+	if coreConfig.Datadog.GetBool("logs_config.attach_host_tags") {
+		hostTags = tag.NewHostProvider()
+	}
+
+	// setup the receiver streaming the processed logs to the stream-logs command
+	diagnostics := diagnostic.NewBufferedMessageReceiver()
+
+	// setup the refresher rotating the API key of the main endpoint when it changes in the configuration file
+	apiKeyRefresher := sender.BuildAPIKeyRefresher(endpoints)
+
+	// setup the monitor switching the pipelines to the shedding mode under memory pressure
+	memoryMonitor := memory.NewMonitor(
+		coreConfig.Datadog.GetInt64("logs_config.memory_heap_threshold_bytes"),
+		coreConfig.Datadog.GetInt64("logs_config.memory_rss_threshold_bytes"),
+	)
+
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, processingRules, endpoints, destinationsCtx)
+	numberOfPipelines := coreConfig.Datadog.GetInt("logs_config.pipelines")
+	if numberOfPipelines < 1 {
+		numberOfPipelines = config.NumberOfPipelines
+	}
+	chanSize := coreConfig.Datadog.GetInt("logs_config.pipeline_channel_size")
+	if chanSize < 1 {
+		chanSize = config.ChanSize
+	}
+
+	// setup the disk buffers the pipelines spill their logs to when the intake is unreachable
+	diskBuffers, err := sender.BuildDiskBuffers(numberOfPipelines)
+	if err != nil {
+		log.Warnf("Could not create the disk buffers, logs will not be buffered on disk: %v", err)
+		diskBuffers = nil
+	}
+	pipelineProvider := pipeline.NewProvider(numberOfPipelines, chanSize, auditor, processingRules, scanner, endpoints, destinationsCtx, diskBuffers, quarantine, hostTags, diagnostics, memoryMonitor)
+
+	// setup the limits of the archives read to backfill the logs of the files tailed for the first time
+	backfillLimits := file.BackfillLimits{
+		MaxAge:  time.Duration(coreConfig.Datadog.GetInt("logs_config.backfill_max_age_hours")) * time.Hour,
+		MaxSize: coreConfig.Datadog.GetInt64("logs_config.backfill_max_size_bytes"),
+	}
+
+	// tag the logs of the files and containers without service with the name of their process or image
+	autoTag := coreConfig.Datadog.GetBool("logs_config.auto_service_tagging")
 
 	// setup the inputs
 	inputs := []restart.Restartable{
-		file.NewScanner(sources, coreConfig.Datadog.GetInt("logs_config.open_files_limit"), pipelineProvider, auditor, file.DefaultSleepDuration),
-		container.NewLauncher(coreConfig.Datadog.GetBool("logs_config.container_collect_all"), sources, services, pipelineProvider, auditor),
+		file.NewScanner(sources, coreConfig.Datadog.GetInt("logs_config.open_files_limit"), coreConfig.Datadog.GetString("logs_config.file_wildcard_selection_mode"), coreConfig.Datadog.GetString("logs_config.open_files_eviction"), backfillLimits, autoTag, coreConfig.Datadog.GetBool("logs_config.follow_symlinks"), pipelineProvider, auditor, file.DefaultSleepDuration),
+		container.NewLauncher(coreConfig.Datadog.GetBool("logs_config.container_collect_all"), autoTag, coreConfig.Datadog.GetStringSlice("logs_config.podman_storage_paths"), sources, services, pipelineProvider, auditor),
 		listener.NewLauncher(sources, coreConfig.Datadog.GetInt("logs_config.frame_size"), pipelineProvider),
 		journald.NewLauncher(sources, pipelineProvider, auditor),
-		windowsevent.NewLauncher(sources, pipelineProvider),
+		windowsevent.NewLauncher(sources, pipelineProvider, auditor),
+		forward.NewLauncher(sources, pipelineProvider),
+		otlp.NewLauncher(sources, pipelineProvider),
+		kafka.NewLauncher(sources, pipelineProvider, auditor),
+		s3.NewLauncher(sources, pipelineProvider),
+		intake.NewLauncher(sources, pipelineProvider),
+		lambda.NewLauncher(sources, pipelineProvider),
+		kubeevents.NewLauncher(sources, pipelineProvider),
+		auditd.NewLauncher(sources, pipelineProvider, auditor),
+		aggregator.NewLauncher(sources, pipelineProvider),
+		checks.NewLauncher(sources, pipelineProvider),
+	}
+
+	// setup the injector of the delivery canaries, started and stopped with the inputs so that no canary is lost on stop
+	canaryInterval := time.Duration(coreConfig.Datadog.GetInt("logs_config.delivery_canary_interval")) * time.Second
+	if canaryInjector := canary.NewInjector(sources, pipelineProvider, canaryInterval); canaryInjector != nil {
+		inputs = append(inputs, canaryInjector)
 	}
 
 	return &Agent{
 		auditor:          auditor,
 		destinationsCtx:  destinationsCtx,
 		pipelineProvider: pipelineProvider,
+		diskBuffers:      diskBuffers,
+		hostTags:         hostTags,
+		diagnostics:      diagnostics,
+		apiKeyRefresher:  apiKeyRefresher,
+		memoryMonitor:    memoryMonitor,
 		inputs:           inputs,
 		health:           health,
 	}
@@ -74,10 +166,16 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 // Start starts all the elements of the data pipeline
 // in the right order to prevent data loss
 func (a *Agent) Start() {
-	starter := restart.NewStarter(a.destinationsCtx, a.auditor, a.pipelineProvider)
+	starter := restart.NewStarter(a.destinationsCtx, a.auditor, a.hostTags, a.pipelineProvider)
+	if a.memoryMonitor != nil {
+		starter.Add(a.memoryMonitor)
+	}
 	for _, input := range a.inputs {
 		starter.Add(input)
 	}
+	if a.apiKeyRefresher != nil {
+		starter.Add(a.apiKeyRefresher)
+	}
 	starter.Start()
 }
 
@@ -89,26 +187,33 @@ func (a *Agent) Stop() {
 		inputs.Add(input)
 	}
 	stopper := restart.NewSerialStopper(
-		inputs,
 		a.pipelineProvider,
+		a.hostTags,
 		a.auditor,
 		a.destinationsCtx,
 	)
+	timeout := time.Duration(coreConfig.Datadog.GetInt("logs_config.stop_grace_period")) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	// This will try to stop everything in order, including the potentially blocking
+	// This will stop the inputs, flush the pipelines so that the logs they hold
+	// are sent right away, then try to stop everything in order, including the potentially blocking
 	// parts like the sender. After StopTimeout it will just stop the last part of the
 	// pipeline, disconnecting it from the auditor, to make sure that the pipeline is
 	// flushed before stopping.
 	// TODO: Add this feature in the stopper.
 	c := make(chan struct{})
 	go func() {
+		inputs.Stop()
+		if err := a.pipelineProvider.Flush(ctx); err != nil {
+			log.Warnf("Could not flush the logs pipelines: %v", err)
+		}
 		stopper.Stop()
 		close(c)
 	}()
-	timeout := time.Duration(coreConfig.Datadog.GetInt("logs_config.stop_grace_period")) * time.Second
 	select {
 	case <-c:
-	case <-time.After(timeout):
+	case <-ctx.Done():
 		log.Info("Timed out when stopping logs-agent, forcing it to stop now")
 		// We force all destinations to read/flush all the messages they get without
 		// trying to write to the network.
@@ -116,4 +221,14 @@ func (a *Agent) Stop() {
 		// Wait again for the stopper to complete.
 		<-c
 	}
+	for _, diskBuffer := range a.diskBuffers {
+		diskBuffer.Close()
+	}
+	if a.apiKeyRefresher != nil {
+		a.apiKeyRefresher.Stop()
+	}
+	if a.memoryMonitor != nil {
+		a.memoryMonitor.Stop()
+	}
+	a.diagnostics.Stop()
 }
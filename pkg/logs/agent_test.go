@@ -78,7 +78,7 @@ func createAgent(endpoints *client.Endpoints) (*Agent, *config.LogSources, *serv
 	services := service.NewServices()
 
 	// setup and start the agent
-	agent = NewAgent(sources, services, nil, endpoints)
+	agent = NewAgent(sources, services, nil, nil, endpoints)
 	return agent, sources, services
 }
 
@@ -87,7 +87,7 @@ func (suite *AgentTestSuite) TestAgent() {
 	defer l.Close()
 
 	endpoint := client.AddrToEndPoint(l.Addr())
-	endpoints := client.NewEndpoints(endpoint, nil)
+	endpoints := client.NewEndpoints(endpoint, nil, false, 0)
 
 	agent, sources, _ := createAgent(endpoints)
 
@@ -116,7 +116,7 @@ func (suite *AgentTestSuite) TestAgent() {
 
 func (suite *AgentTestSuite) TestAgentStopsWithWrongBackend() {
 	endpoint := client.Endpoint{Host: "fake:", Port: 0}
-	endpoints := client.NewEndpoints(endpoint, nil)
+	endpoints := client.NewEndpoints(endpoint, nil, false, 0)
 
 	agent, sources, _ := createAgent(endpoints)
 
@@ -140,7 +140,7 @@ func (suite *AgentTestSuite) TestAgentStopsWithWrongAdditionalBackend() {
 	endpoint := client.AddrToEndPoint(l.Addr())
 	additionalEndpoint := client.Endpoint{Host: "still_fake", Port: 0}
 
-	endpoints := client.NewEndpoints(endpoint, []client.Endpoint{additionalEndpoint})
+	endpoints := client.NewEndpoints(endpoint, []client.Endpoint{additionalEndpoint}, false, 0)
 
 	agent, sources, _ := createAgent(endpoints)
 
@@ -28,6 +28,9 @@ const defaultTTL = 23 * time.Hour
 // latest version of the API used by the auditor to retrieve the registry from disk.
 const registryAPIVersion = 2
 
+// extension of the temporary file the registry is written to before replacing the registry.
+const registryTmpExtension = ".tmp"
+
 // Registry holds a list of offsets.
 type Registry interface {
 	GetOffset(identifier string) string
@@ -131,6 +134,13 @@ func (a *Auditor) run() {
 				// inputChan has been closed, no need to update the registry anymore
 				return
 			}
+			if msg.Origin == nil || msg.Origin.Replayed {
+				// messages replayed from the disk buffer have already been committed.
+				continue
+			}
+			if msg.Origin.Acknowledge != nil {
+				msg.Origin.Acknowledge()
+			}
 			// update the registry with new entry
 			a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset)
 		case <-cleanUpTicker.C:
@@ -206,14 +216,24 @@ func (a *Auditor) readOnlyRegistryCopy() map[string]RegistryEntry {
 	return r
 }
 
-// flushRegistry writes on disk the registry at the given path
+// flushRegistry writes on disk the registry at the given path,
+// the registry is written to a temporary file first and then renamed
+// so that an agent stopping in the middle of a flush does not lose the offsets.
 func (a *Auditor) flushRegistry() error {
 	r := a.readOnlyRegistryCopy()
 	mr, err := a.marshalRegistry(r)
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(a.registryPath, mr, 0644)
+	tmpPath := a.registryPath + registryTmpExtension
+	if err := ioutil.WriteFile(tmpPath, mr, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, a.registryPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
 }
 
 // marshalRegistry marshals a registry
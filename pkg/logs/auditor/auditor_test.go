@@ -18,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
 var testpath = "testpath"
@@ -71,6 +72,46 @@ func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.Equal("43", suite.a.registry[suite.source.Config.Path].Offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorIgnoresMessagesWithoutOrigin() {
+	suite.a.Start()
+	origin := message.NewOrigin(suite.source)
+	origin.Identifier = suite.source.Config.Path
+	origin.Offset = "42"
+	suite.a.Channel() <- message.NewMessage([]byte("foo"), nil, "")
+	suite.a.Channel() <- message.NewMessage([]byte("bar"), origin, "")
+	suite.a.Stop()
+	suite.Equal(1, len(suite.a.registry))
+	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
+}
+
+func (suite *AuditorTestSuite) TestAuditorIgnoresReplayedMessages() {
+	suite.a.Start()
+	origin := message.NewOrigin(suite.source)
+	origin.Identifier = suite.source.Config.Path
+	origin.Offset = "42"
+	suite.a.Channel() <- message.NewMessage([]byte("bar"), origin, "")
+	replayed := message.NewOrigin(suite.source)
+	replayed.Identifier = suite.source.Config.Path
+	replayed.Offset = "12"
+	replayed.Replayed = true
+	suite.a.Channel() <- message.NewMessage([]byte("foo"), replayed, "")
+	suite.a.Stop()
+	// the offset committed when the message was spilled is kept.
+	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
+}
+
+func (suite *AuditorTestSuite) TestAuditorAcknowledgesMessages() {
+	suite.a.Start()
+	acknowledged := 0
+	origin := message.NewOrigin(suite.source)
+	origin.Acknowledge = func() { acknowledged++ }
+	suite.a.Channel() <- message.NewMessage([]byte("foo"), origin, "")
+	suite.a.Channel() <- message.NewMessage([]byte("bar"), message.NewOrigin(suite.source), "")
+	suite.a.Stop()
+	suite.Equal(1, acknowledged)
+	suite.Equal(0, len(suite.a.registry))
+}
+
 func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
@@ -87,6 +128,37 @@ func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorFlushesRegistryAtomically() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
+		LastUpdated: time.Now().UTC(),
+		Offset:      "42",
+	}
+	suite.Nil(suite.a.flushRegistry())
+
+	_, err := os.Stat(suite.testPath + registryTmpExtension)
+	suite.True(os.IsNotExist(err))
+
+	suite.a.registry = suite.a.recoverRegistry()
+	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
+}
+
+func (suite *AuditorTestSuite) TestAuditorFlushesRegistryOnStop() {
+	suite.a.Start()
+	origin := message.NewOrigin(suite.source)
+	origin.Identifier = suite.source.Config.Path
+	origin.Offset = "42"
+	suite.a.Channel() <- message.NewMessage([]byte("foo"), origin, "")
+	suite.a.Stop()
+
+	// a new auditor resumes from the offsets of the previous one
+	a := New("", health.Register("fake"))
+	a.registryPath = suite.testPath
+	a.Start()
+	defer a.Stop()
+	suite.Equal("42", a.GetOffset(suite.source.Config.Path))
+}
+
 func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForOffset() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
@@ -0,0 +1,217 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package canary
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+const (
+	// lossIntervals is the number of intervals after which a canary not sent yet is reported as lost.
+	lossIntervals = 3
+	// reportMessageKey is the key of the delivery report displayed on the status of the sources.
+	reportMessageKey = "delivery_canary"
+	// canaryTag is the tag of the canaries, to find them in the logs explorer.
+	canaryTag = "delivery_canary:true"
+)
+
+// pipelineProvider provides the pipelines the canaries are injected in.
+type pipelineProvider interface {
+	PipelineChanForSource(source *config.LogSource) chan *message.Message
+}
+
+// delivery holds the delivery report of the canaries of a source.
+type delivery struct {
+	injected      int64
+	delivered     int64
+	lost          int64
+	blocked       int64
+	pending       map[uint64]time.Time
+	lastDelivered time.Time
+	lastLatency   time.Duration
+}
+
+// An Injector injects a canary log in the pipeline of each source at every interval and reports per source
+// how many of them were sent, as acknowledged by the auditor, to troubleshoot missing logs.
+// The canaries go through the processing rules of their source, so that a canary excluded by a rule
+// or spilled to the disk buffer is reported as lost.
+type Injector struct {
+	sources    *config.LogSources
+	provider   pipelineProvider
+	interval   time.Duration
+	now        func() time.Time
+	mu         sync.Mutex
+	sequence   uint64
+	deliveries map[*config.LogSource]*delivery
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewInjector returns an injector of canaries in the pipelines of sources at every interval,
+// or nil if interval is 0 or less.
+func NewInjector(sources *config.LogSources, provider pipelineProvider, interval time.Duration) *Injector {
+	if interval <= 0 {
+		return nil
+	}
+	return &Injector{
+		sources:    sources,
+		provider:   provider,
+		interval:   interval,
+		now:        time.Now,
+		deliveries: make(map[*config.LogSource]*delivery),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts injecting the canaries.
+func (i *Injector) Start() {
+	go i.run()
+}
+
+// Stop stops injecting the canaries and removes the delivery reports from the status of the sources.
+func (i *Injector) Stop() {
+	close(i.stop)
+	<-i.done
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for source := range i.deliveries {
+		source.Messages.RemoveMessage(reportMessageKey)
+	}
+}
+
+// run injects the canaries at every interval until the injector is stopped.
+func (i *Injector) run() {
+	defer close(i.done)
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			i.Inject()
+		case <-i.stop:
+			return
+		}
+	}
+}
+
+// Inject injects a canary in the pipeline of each source right away and reports the canaries
+// injected more than lossIntervals intervals ago and not sent yet as lost.
+func (i *Injector) Inject() {
+	now := i.now()
+	sources := i.sources.GetSources()
+	active := make(map[*config.LogSource]bool, len(sources))
+	for _, source := range sources {
+		active[source] = true
+		i.inject(source, now)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for source, d := range i.deliveries {
+		if !active[source] {
+			delete(i.deliveries, source)
+			continue
+		}
+		for sequence, injected := range d.pending {
+			if now.Sub(injected) < lossIntervals*i.interval {
+				continue
+			}
+			delete(d.pending, sequence)
+			d.lost++
+			metrics.SourceCanariesLost.Add(source.Name, 1)
+			log.Warnf("The delivery canary %d of the logs source %s was not sent within %v", sequence, source.Name, lossIntervals*i.interval)
+		}
+		i.report(source, d)
+	}
+}
+
+// inject injects a canary in the pipeline of source, the canary is not injected if the pipeline is full
+// so that the injector never blocks.
+func (i *Injector) inject(source *config.LogSource, now time.Time) {
+	i.mu.Lock()
+	i.sequence++
+	sequence := i.sequence
+	d := i.delivery(source)
+	i.mu.Unlock()
+
+	origin := message.NewOrigin(source)
+	origin.SetTags([]string{canaryTag})
+	origin.Acknowledge = func() {
+		i.acknowledge(source, sequence)
+	}
+	content := fmt.Sprintf("Delivery canary %d of the logs source %s injected at %s", sequence, source.Name, now.UTC().Format(time.RFC3339))
+	msg := message.NewMessage([]byte(content), origin, message.StatusInfo)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	select {
+	case i.provider.PipelineChanForSource(source) <- msg:
+		d.injected++
+		d.pending[sequence] = now
+		metrics.SourceCanariesInjected.Add(source.Name, 1)
+	default:
+		d.blocked++
+		log.Debugf("Could not inject the delivery canary %d of the logs source %s, its pipeline is full", sequence, source.Name)
+	}
+}
+
+// acknowledge reports the canary of source with sequence as sent.
+func (i *Injector) acknowledge(source *config.LogSource, sequence uint64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	d, exists := i.deliveries[source]
+	if !exists {
+		return
+	}
+	injected, pending := d.pending[sequence]
+	if !pending {
+		// the canary was already reported as lost.
+		return
+	}
+	delete(d.pending, sequence)
+	d.delivered++
+	d.lastDelivered = i.now()
+	d.lastLatency = d.lastDelivered.Sub(injected)
+	metrics.SourceCanariesDelivered.Add(source.Name, 1)
+	i.report(source, d)
+}
+
+// delivery returns the delivery report of source, the lock must be held.
+func (i *Injector) delivery(source *config.LogSource) *delivery {
+	d, exists := i.deliveries[source]
+	if !exists {
+		d = &delivery{pending: make(map[uint64]time.Time)}
+		i.deliveries[source] = d
+	}
+	return d
+}
+
+// report displays the delivery report d on the status of source, the lock must be held.
+func (i *Injector) report(source *config.LogSource, d *delivery) {
+	parts := []string{
+		fmt.Sprintf("%d injected", d.injected),
+		fmt.Sprintf("%d sent", d.delivered),
+		fmt.Sprintf("%d lost", d.lost),
+		fmt.Sprintf("%d pending", len(d.pending)),
+	}
+	if d.blocked > 0 {
+		parts = append(parts, fmt.Sprintf("%d not injected because the pipeline was full", d.blocked))
+	}
+	report := "Delivery canaries: " + strings.Join(parts, ", ")
+	if !d.lastDelivered.IsZero() {
+		report += fmt.Sprintf(", the last one was sent at %s in %v", d.lastDelivered.UTC().Format(time.RFC3339), d.lastLatency)
+	}
+	source.Messages.AddMessage(reportMessageKey, report)
+}
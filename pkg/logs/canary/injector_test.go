@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package canary
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+type mockProvider struct {
+	pipeline chan *message.Message
+}
+
+func (p *mockProvider) PipelineChanForSource(source *config.LogSource) chan *message.Message {
+	return p.pipeline
+}
+
+func newTestInjector(chanSize int) (*Injector, *config.LogSource, *mockProvider, *time.Time) {
+	sources := config.NewLogSources()
+	source := config.NewLogSource("canary-test", &config.LogsConfig{Type: config.TCPType, Port: 10514})
+	sources.AddSource(source)
+	provider := &mockProvider{pipeline: make(chan *message.Message, chanSize)}
+	injector := NewInjector(sources, provider, time.Minute)
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	injector.now = func() time.Time { return now }
+	return injector, source, provider, &now
+}
+
+func TestNewInjectorDisabled(t *testing.T) {
+	assert.Nil(t, NewInjector(config.NewLogSources(), &mockProvider{}, 0))
+}
+
+func TestInjectorReportsTheSentCanaries(t *testing.T) {
+	injector, source, provider, now := newTestInjector(10)
+
+	injector.Inject()
+	require.Len(t, provider.pipeline, 1)
+	msg := <-provider.pipeline
+	assert.Equal(t, source, msg.Origin.LogSource)
+	assert.Contains(t, msg.Origin.Tags(), canaryTag)
+	assert.Equal(t, "Delivery canary 1 of the logs source canary-test injected at 2019-03-01T12:00:00Z", string(msg.Content))
+	assert.Equal(t, []string{"Delivery canaries: 1 injected, 0 sent, 0 lost, 1 pending"}, source.Messages.GetMessages())
+
+	*now = now.Add(2 * time.Second)
+	msg.Origin.Acknowledge()
+	assert.Equal(t, []string{"Delivery canaries: 1 injected, 1 sent, 0 lost, 0 pending, the last one was sent at 2019-03-01T12:00:02Z in 2s"}, source.Messages.GetMessages())
+
+	// a canary is only acknowledged once
+	msg.Origin.Acknowledge()
+	assert.Equal(t, int64(1), injector.deliveries[source].delivered)
+}
+
+func TestInjectorReportsTheLostCanaries(t *testing.T) {
+	injector, source, provider, now := newTestInjector(10)
+
+	injector.Inject()
+	lost := <-provider.pipeline
+	*now = now.Add(lossIntervals * time.Minute)
+	injector.Inject()
+	assert.Equal(t, []string{"Delivery canaries: 2 injected, 0 sent, 1 lost, 1 pending"}, source.Messages.GetMessages())
+
+	// a lost canary sent late is not reported as sent
+	lost.Origin.Acknowledge()
+	assert.Equal(t, int64(0), injector.deliveries[source].delivered)
+}
+
+func TestInjectorDoesNotBlockOnAFullPipeline(t *testing.T) {
+	injector, source, provider, _ := newTestInjector(1)
+
+	injector.Inject()
+	injector.Inject()
+	assert.Len(t, provider.pipeline, 1)
+	messages := source.Messages.GetMessages()
+	require.Len(t, messages, 1)
+	assert.True(t, strings.HasSuffix(messages[0], "1 not injected because the pipeline was full"))
+}
+
+func TestInjectorForgetsTheRemovedSources(t *testing.T) {
+	injector, source, _, _ := newTestInjector(10)
+
+	injector.Inject()
+	injector.sources.RemoveSource(source)
+	injector.Inject()
+	assert.Len(t, injector.deliveries, 0)
+}
+
+func TestInjectorStopRemovesTheReports(t *testing.T) {
+	injector, source, _, _ := newTestInjector(10)
+
+	injector.Start()
+	injector.Inject()
+	injector.Stop()
+	assert.Len(t, source.Messages.GetMessages(), 0)
+}
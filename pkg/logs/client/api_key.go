@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"sync/atomic"
+)
+
+// An APIKeyHolder holds an API key which can be rotated while the logs are being sent, the destinations
+// read it every time they send a frame or a batch so that the new key is used right away, without dropping
+// the logs waiting to be sent.
+type APIKeyHolder struct {
+	key atomic.Value
+}
+
+// NewAPIKeyHolder returns a new APIKeyHolder holding key.
+func NewAPIKeyHolder(key string) *APIKeyHolder {
+	holder := &APIKeyHolder{}
+	holder.Set(key)
+	return holder
+}
+
+// Get returns the current API key.
+func (h *APIKeyHolder) Get() string {
+	return h.key.Load().(string)
+}
+
+// Set rotates the API key.
+func (h *APIKeyHolder) Set(key string) {
+	h.key.Store(key)
+}
+
+// GetAPIKey returns the current API key of the endpoint.
+func (e Endpoint) GetAPIKey() string {
+	return e.apiKeyHolder().Get()
+}
+
+// apiKeyHolder returns the holder of the API key of the endpoint, or a holder of APIKey when its key can't be rotated.
+func (e Endpoint) apiKeyHolder() *APIKeyHolder {
+	if e.APIKeyHolder != nil {
+		return e.APIKeyHolder
+	}
+	return NewAPIKeyHolder(e.APIKey)
+}
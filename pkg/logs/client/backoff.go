@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultBackoffBase = 1 * time.Second
+	defaultBackoffMax  = 120 * time.Second
+	// maxBackoffExponent prevents the exponential growth from overflowing.
+	maxBackoffExponent = 30
+)
+
+// BackoffPolicy holds the parameters of the exponential backoff applied between connection attempts.
+type BackoffPolicy struct {
+	// Base is the upper bound of the first wait, it doubles on every new attempt.
+	Base time.Duration
+	// Max caps the upper bound of the waits.
+	Max time.Duration
+	// MaxRetries is the number of consecutive failed attempts after which
+	// the connection is not retried anymore, 0 means retrying indefinitely.
+	MaxRetries int
+	// MaxPayloadRetries is the number of times a payload the intake keeps failing on is retried,
+	// the failures due to an unavailable or overloaded intake are not counted, 0 means retrying indefinitely.
+	MaxPayloadRetries int
+}
+
+// duration returns how long to wait before the next connection attempt,
+// it implements an exponential backoff with full jitter: each invocation returns a random duration
+// between 0 and min(max, base * 2^(retries-1)) so that agents losing the intake at the same time
+// don't all reconnect at the same time.
+func (p BackoffPolicy) duration(retries uint) time.Duration {
+	base, max := p.Base, p.Max
+	if base <= 0 {
+		base = defaultBackoffBase
+	}
+	if max <= 0 {
+		max = defaultBackoffMax
+	}
+	if retries == 0 {
+		return 0
+	}
+	exponent := retries - 1
+	if exponent > maxBackoffExponent {
+		exponent = maxBackoffExponent
+	}
+	ceiling := base << exponent
+	if ceiling > max || ceiling <= 0 {
+		ceiling = max
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// shouldRetry returns true if a new connection attempt should be made after retries attempts.
+func (p BackoffPolicy) shouldRetry(retries uint) bool {
+	return p.MaxRetries <= 0 || retries < uint(p.MaxRetries)
+}
+
+// shouldRetryPayload returns true if a payload the intake failed on failures times should be sent again.
+func (p BackoffPolicy) shouldRetryPayload(failures uint) bool {
+	return p.MaxPayloadRetries <= 0 || failures <= uint(p.MaxPayloadRetries)
+}
@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDurationIsBounded(t *testing.T) {
+	policy := BackoffPolicy{Base: time.Second, Max: 10 * time.Second}
+
+	assert.Equal(t, time.Duration(0), policy.duration(0))
+	for i := 0; i < 100; i++ {
+		assert.True(t, policy.duration(1) < time.Second)
+		assert.True(t, policy.duration(3) < 4*time.Second)
+		assert.True(t, policy.duration(5) < 10*time.Second)
+		assert.True(t, policy.duration(1000) < 10*time.Second)
+		assert.True(t, policy.duration(1000) >= 0)
+	}
+}
+
+func TestBackoffDurationUsesDefaults(t *testing.T) {
+	policy := BackoffPolicy{}
+	for i := 0; i < 100; i++ {
+		assert.True(t, policy.duration(1) < defaultBackoffBase)
+		assert.True(t, policy.duration(100) < defaultBackoffMax)
+	}
+}
+
+func TestBackoffShouldRetry(t *testing.T) {
+	assert.True(t, BackoffPolicy{}.shouldRetry(1000))
+	assert.True(t, BackoffPolicy{MaxRetries: 3}.shouldRetry(2))
+	assert.False(t, BackoffPolicy{MaxRetries: 3}.shouldRetry(3))
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const defaultCircuitBreakerCooldown = 30 * time.Second
+
+// errCircuitOpen is returned when a payload is dropped because its destination is considered down.
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreakerPolicy holds the parameters of the circuit breaker of an additional destination.
+type CircuitBreakerPolicy struct {
+	// Threshold is the number of consecutive failed attempts after which the breaker opens,
+	// 0 disables the breaker.
+	Threshold int
+	// Cooldown is how long the breaker stays open before letting a payload through to probe the destination.
+	Cooldown time.Duration
+}
+
+// cooldown returns how long the breaker stays open.
+func (p CircuitBreakerPolicy) cooldown() time.Duration {
+	if p.Cooldown <= 0 {
+		return defaultCircuitBreakerCooldown
+	}
+	return p.Cooldown
+}
+
+// circuitBreakerState is the state of a circuit breaker.
+type circuitBreakerState int
+
+const (
+	// circuitClosed lets all the payloads through.
+	circuitClosed circuitBreakerState = iota
+	// circuitOpen sheds all the payloads until the cooldown elapses.
+	circuitOpen
+	// circuitHalfOpen lets the payloads through until the next outcome closes or opens the breaker again.
+	circuitHalfOpen
+)
+
+// circuitBreaker sheds the payloads of a destination that keeps failing so that it does not
+// hold on to payloads it can not deliver, it probes the destination again once the cooldown elapsed.
+// A circuitBreaker is safe for concurrent use.
+type circuitBreaker struct {
+	mutex    sync.Mutex
+	name     string
+	policy   CircuitBreakerPolicy
+	state    circuitBreakerState
+	failures int
+	openedAt time.Time
+}
+
+// newCircuitBreaker returns a closed circuit breaker for the destination name.
+func newCircuitBreaker(name string, policy CircuitBreakerPolicy) *circuitBreaker {
+	return &circuitBreaker{
+		name:   name,
+		policy: policy,
+	}
+}
+
+// allow returns true if a payload can be sent, the breaker half-opens once the cooldown elapsed.
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state == circuitOpen && time.Since(b.openedAt) >= b.policy.cooldown() {
+		b.state = circuitHalfOpen
+	}
+	return b.state != circuitOpen
+}
+
+// recordSuccess closes the breaker.
+func (b *circuitBreaker) recordSuccess() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state != circuitClosed {
+		log.Infof("Destination %v is reachable again", b.name)
+	}
+	b.state = circuitClosed
+	b.failures = 0
+}
+
+// recordFailure opens the breaker after too many consecutive failures,
+// or right away when the destination is being probed.
+func (b *circuitBreaker) recordFailure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.policy.Threshold <= 0 {
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || (b.state == circuitClosed && b.failures >= b.policy.Threshold) {
+		log.Warnf("Could not send logs to %v after %d attempts, dropping its logs for %v", b.name, b.failures, b.policy.cooldown())
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+	}
+}
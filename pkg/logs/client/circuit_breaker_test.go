@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerPolicyCooldown(t *testing.T) {
+	assert.Equal(t, defaultCircuitBreakerCooldown, CircuitBreakerPolicy{}.cooldown())
+	assert.Equal(t, time.Second, CircuitBreakerPolicy{Cooldown: time.Second}.cooldown())
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker("foo", CircuitBreakerPolicy{})
+	for i := 0; i < 100; i++ {
+		b.recordFailure()
+	}
+	assert.True(t, b.allow())
+}
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b := newCircuitBreaker("foo", CircuitBreakerPolicy{Threshold: 3, Cooldown: time.Hour})
+	b.recordFailure()
+	b.recordFailure()
+	b.recordSuccess()
+	b.recordFailure()
+	b.recordFailure()
+	assert.True(t, b.allow())
+	b.recordFailure()
+	assert.False(t, b.allow())
+}
+
+func TestCircuitBreakerHalfOpensAfterCooldown(t *testing.T) {
+	b := newCircuitBreaker("foo", CircuitBreakerPolicy{Threshold: 2, Cooldown: time.Hour})
+	b.recordFailure()
+	b.recordFailure()
+	assert.False(t, b.allow())
+
+	// the cooldown elapsed, a single failure of the probe opens the breaker again.
+	b.openedAt = time.Now().Add(-2 * time.Hour)
+	assert.True(t, b.allow())
+	assert.Equal(t, circuitHalfOpen, b.state)
+	b.recordFailure()
+	assert.False(t, b.allow())
+
+	b.openedAt = time.Now().Add(-2 * time.Hour)
+	assert.True(t, b.allow())
+	b.recordSuccess()
+	assert.Equal(t, circuitClosed, b.state)
+	b.recordFailure()
+	assert.True(t, b.allow())
+}
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+// gzipContentEncoding is the content encoding of the gzip compressed payloads.
+const gzipContentEncoding = "gzip"
+
+// compress returns the payload compressed with gzip at level,
+// the level is bounded to the levels supported by gzip.
+func compress(payload []byte, level int) ([]byte, error) {
+	if level < gzip.BestSpeed || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(payload); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
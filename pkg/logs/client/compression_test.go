@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decompress(t *testing.T, payload []byte) string {
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestCompress(t *testing.T) {
+	payload := []byte(`[{"message":"foo"},{"message":"foo"},{"message":"foo"}]`)
+	for _, level := range []int{-5, 0, 1, 6, 9, 42} {
+		compressed, err := compress(payload, level)
+		assert.NoError(t, err)
+		assert.Equal(t, string(payload), decompress(t, compressed))
+	}
+}
@@ -8,9 +8,8 @@ package client
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
-	"io"
-	"math/rand"
 	"net"
 	"strconv"
 	"sync"
@@ -18,32 +17,100 @@ import (
 
 	"golang.org/x/net/proxy"
 
+	logsConfig "github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/status"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 const (
-	maxExpBackoffCount    = 7
-	connectionTimeout     = 20 * time.Second
-	statusConnectionError = "connection_error"
+	defaultDialTimeout      = 20 * time.Second
+	defaultHandshakeTimeout = 20 * time.Second
+	statusConnectionError   = "connection_error"
+	// happyEyeballsDelay is the head start given to the preferred address family
+	// before racing a connection to the other family, as recommended by RFC 8305.
+	happyEyeballsDelay = 250 * time.Millisecond
 )
 
+// ErrConnectionRetriesExhausted is returned when the connection attempts of the backoff policy all failed,
+// the logs being sent are given up on.
+var ErrConnectionRetriesExhausted = errors.New("connection retries exhausted")
+
+// The address families an endpoint can be restricted to.
+const (
+	IPv4 = "ipv4"
+	IPv6 = "ipv6"
+)
+
+// ConnectionTimeouts bounds the time spent on the network operations of a connection,
+// zero values mean the default timeouts to dial and to run the SSL handshake and no timeout to write.
+type ConnectionTimeouts struct {
+	Dial      time.Duration
+	Handshake time.Duration
+	Write     time.Duration
+	// KeepAlive is the period of the TCP keepalive probes, zero disables them.
+	KeepAlive time.Duration
+	// Idle is the duration after which an unused connection is reopened, zero disables the recycling.
+	Idle time.Duration
+}
+
+// dial returns the timeout to establish the TCP connection, including the proxy negotiation.
+func (t ConnectionTimeouts) dial() time.Duration {
+	if t.Dial <= 0 {
+		return defaultDialTimeout
+	}
+	return t.Dial
+}
+
+// keepAlive returns the keepalive period to set on the dialers, a negative period disables keepalives.
+func (t ConnectionTimeouts) keepAlive() time.Duration {
+	if t.KeepAlive <= 0 {
+		return -1
+	}
+	return t.KeepAlive
+}
+
+// handshake returns the timeout to run the SSL handshake.
+func (t ConnectionTimeouts) handshake() time.Duration {
+	if t.Handshake <= 0 {
+		return defaultHandshakeTimeout
+	}
+	return t.Handshake
+}
+
 // A ConnectionManager manages connections
 type ConnectionManager struct {
+	// endpoint is the endpoint currently in use, the primary one or one of its fallbacks.
 	endpoint  Endpoint
+	endpoints []Endpoint
+	current   int
+	failover  FailoverPolicy
+	lastProbe time.Time
+	// failures counts the consecutive failed attempts on the current endpoint, across the calls to NewConnection
+	// so that the backoff keeps growing when the connection attempts are given up on.
+	failures uint
+	// latencies are the smoothed connection latencies of the endpoints, zero when unknown or unreachable.
+	latencies []time.Duration
+	// breaker, when set, makes NewConnection give up as soon as it opens.
+	breaker   *circuitBreaker
 	mutex     sync.Mutex
 	firstConn sync.Once
 }
 
 // NewConnectionManager returns an initialized ConnectionManager
 func NewConnectionManager(endpoint Endpoint) *ConnectionManager {
+	endpoints := append([]Endpoint{endpoint}, endpoint.Failover.Fallbacks...)
 	return &ConnectionManager{
-		endpoint: endpoint,
+		endpoint:  endpoint,
+		endpoints: endpoints,
+		latencies: make([]time.Duration, len(endpoints)),
+		failover:  endpoint.Failover,
 	}
 }
 
 // NewConnection returns an initialized connection to the intake.
-// It blocks until a connection is available
+// It blocks until a connection is available or until the maximum number
+// of retries of the backoff policy is reached, it returns ErrConnectionRetriesExhausted then.
 func (cm *ConnectionManager) NewConnection(ctx context.Context) (net.Conn, error) {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
@@ -51,20 +118,40 @@ func (cm *ConnectionManager) NewConnection(ctx context.Context) (net.Conn, error
 	cm.firstConn.Do(func() {
 		if cm.endpoint.ProxyAddress != "" {
 			log.Infof("Connecting to the backend: %v, via socks5: %v, with SSL: %v", cm.address(), cm.endpoint.ProxyAddress, cm.endpoint.UseSSL)
+		} else if cm.endpoint.HTTPProxyURL != "" {
+			log.Infof("Connecting to the backend: %v, via http proxy: %v, with SSL: %v", cm.address(), sanitizeProxyURL(cm.endpoint.HTTPProxyURL), cm.endpoint.UseSSL)
 		} else {
 			log.Infof("Connecting to the backend: %v, with SSL: %v", cm.address(), cm.endpoint.UseSSL)
 		}
 	})
 
+	if cm.failover.selectsByLatency() {
+		if conn := cm.rebalance(ctx); conn != nil {
+			return conn, nil
+		}
+	} else if conn := cm.probePrimary(ctx); conn != nil {
+		return conn, nil
+	}
+
 	var retries uint
 	var err error
 	for {
 		if err != nil {
 			status.AddGlobalWarning(statusConnectionError, fmt.Sprintf("Connection to the log intake cannot be established: %v", err))
 		}
-		if retries > 0 {
+		if retries > 0 && !cm.endpoint.Backoff.shouldRetry(retries) {
+			log.Warnf("Could not connect to %v after %d attempts, giving up on the logs being sent: %v", cm.address(), retries, err)
+			return nil, ErrConnectionRetriesExhausted
+		}
+		// the previous calls may have failed too, the backoff goes on growing from their failures.
+		if retries > 0 || cm.failures > 0 {
 			log.Debugf("Connect attempt #%d", retries)
-			cm.backoff(ctx, retries)
+			if cm.failover.shouldFailOver(cm.failures) {
+				cm.failOver(cm.failures)
+				cm.failures = 0
+			} else {
+				cm.backoff(ctx, cm.failures)
+			}
 		}
 		retries++
 
@@ -78,48 +165,234 @@ func (cm *ConnectionManager) NewConnection(ctx context.Context) (net.Conn, error
 		}
 
 		var conn net.Conn
-
-		if cm.endpoint.ProxyAddress != "" {
-			var dialer proxy.Dialer
-			dialer, err = proxy.SOCKS5("tcp", cm.endpoint.ProxyAddress, nil, proxy.Direct)
-			if err != nil {
-				log.Warn(err)
-				continue
-			}
-			// TODO: handle timeouts with ctx.
-			conn, err = dialer.Dial("tcp", cm.address())
-		} else {
-			var dialer net.Dialer
-			dctx, cancel := context.WithTimeout(ctx, connectionTimeout)
-			defer cancel()
-			conn, err = dialer.DialContext(dctx, "tcp", cm.address())
-		}
+		start := time.Now()
+		conn, err = cm.connect(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				// the attempt was aborted because the caller is stopping.
+				return nil, ctx.Err()
+			}
+			cm.failures++
 			log.Warn(err)
-			continue
-		}
-		log.Debug("connected to %v", cm.address())
-
-		if cm.endpoint.UseSSL {
-			sslConn := tls.Client(conn, &tls.Config{
-				ServerName: cm.endpoint.Host,
-			})
-			// TODO: handle timeouts with ctx.
-			err = sslConn.Handshake()
-			if err != nil {
-				log.Warn(err)
-				continue
+			recordError(cm.address(), err)
+			if cm.breaker != nil {
+				cm.breaker.recordFailure()
+				if !cm.breaker.allow() {
+					return nil, errCircuitOpen
+				}
 			}
-			log.Debug("SSL handshake successful")
-			conn = sslConn
+			continue
 		}
 
-		go cm.handleServerClose(conn)
+		cm.failures = 0
+		cm.recordLatency(cm.current, time.Since(start))
 		status.RemoveGlobalWarning(statusConnectionError)
+		metrics.SetDuration(&metrics.DestinationBackoff, cm.endpoint.Host, 0)
 		return conn, nil
 	}
 }
 
+// failOver switches to the next endpoint, the primary one is used again after the last fallback.
+func (cm *ConnectionManager) failOver(failures uint) {
+	previous := cm.address()
+	cm.current = (cm.current + 1) % len(cm.endpoints)
+	cm.endpoint = cm.endpoints[cm.current]
+	cm.lastProbe = time.Now()
+	log.Warnf("Could not connect to %v after %d attempts, failing over to %v", previous, failures, cm.address())
+}
+
+// probePrimary makes a single attempt at connecting to the primary endpoint
+// at most once per failback interval when failed over, it fails back on success.
+func (cm *ConnectionManager) probePrimary(ctx context.Context) net.Conn {
+	if cm.current == 0 || time.Since(cm.lastProbe) < cm.failover.failbackInterval() {
+		return nil
+	}
+	cm.lastProbe = time.Now()
+
+	primary := &ConnectionManager{endpoint: cm.endpoints[0]}
+	conn, err := primary.connect(ctx)
+	if err != nil {
+		log.Debugf("Primary endpoint %v is still unreachable: %v", primary.address(), err)
+		return nil
+	}
+	log.Infof("Primary endpoint %v is reachable again, failing back from %v", primary.address(), cm.address())
+	cm.current = 0
+	cm.endpoint = cm.endpoints[0]
+	cm.failures = 0
+	status.RemoveGlobalWarning(statusConnectionError)
+	return conn
+}
+
+// isFailedOver returns true if the manager uses a fallback endpoint.
+func (cm *ConnectionManager) isFailedOver() bool {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	return cm.current != 0
+}
+
+// shouldReopen returns true if the connections older than the failback interval should be reopened,
+// to fail back on the primary endpoint or to rebalance on the fastest endpoint.
+func (cm *ConnectionManager) shouldReopen() bool {
+	return cm.failover.selectsByLatency() || cm.isFailedOver()
+}
+
+// connect makes a single attempt at establishing a connection to the intake
+// and keeps track of its outcome in the metrics.
+func (cm *ConnectionManager) connect(ctx context.Context) (net.Conn, error) {
+	metrics.ConnectionAttempts.Add(1)
+	conn, err := cm.dialAndHandshake(ctx)
+	if err != nil {
+		metrics.ConnectionFailures.Add(1)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dialAndHandshake dials the intake and runs the SSL handshake if needed,
+// both are bounded by the timeouts of the endpoint and aborted as soon as ctx is cancelled.
+func (cm *ConnectionManager) dialAndHandshake(ctx context.Context) (net.Conn, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, cm.endpoint.Timeouts.dial())
+	conn, err := cm.dial(dialCtx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("connected to %v", cm.address())
+
+	if cm.endpoint.UseSSL {
+		tlsConfig, err := NewTLSConfig(cm.endpoint)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		sslConn := tls.Client(conn, tlsConfig)
+		handshakeCtx, cancel := context.WithTimeout(ctx, cm.endpoint.Timeouts.handshake())
+		err = cm.handshake(handshakeCtx, sslConn)
+		cancel()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		log.Debug("SSL handshake successful")
+		conn = sslConn
+	}
+
+	return conn, nil
+}
+
+// contextDialer is implemented by the dialers that can be cancelled with a context.
+type contextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// dial opens a TCP connection to the intake, directly or through the socks5 proxy
+// or the HTTP proxy if one is set, the socks5 proxy takes precedence.
+func (cm *ConnectionManager) dial(ctx context.Context) (net.Conn, error) {
+	if cm.endpoint.ProxyAddress == "" && cm.endpoint.HTTPProxyURL != "" {
+		return cm.dialHTTPProxy(ctx)
+	}
+	if cm.endpoint.ProxyAddress == "" {
+		return cm.netDialer().DialContext(ctx, cm.network(), cm.address())
+	}
+
+	auth, err := cm.proxyAuth()
+	if err != nil {
+		return nil, err
+	}
+	dialer, err := proxy.SOCKS5(cm.network(), cm.endpoint.ProxyAddress, auth, cm.netDialer())
+	if err != nil {
+		return nil, err
+	}
+	if dialer, ok := dialer.(contextDialer); ok {
+		return dialer.DialContext(ctx, "tcp", cm.address())
+	}
+
+	// the dialer does not support contexts, make sure we do not wait for it once ctx is done.
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		conn, err := dialer.Dial("tcp", cm.address())
+		results <- result{conn, err}
+	}()
+	select {
+	case r := <-results:
+		return r.conn, r.err
+	case <-ctx.Done():
+		go func() {
+			// close the connection that may still be established in the background.
+			if r := <-results; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// netDialer returns the dialer to open the TCP connections with,
+// on dual-stack hosts it races IPv4 and IPv6 addresses so a broken family does not stall the dial.
+func (cm *ConnectionManager) netDialer() *net.Dialer {
+	return &net.Dialer{
+		KeepAlive:     cm.endpoint.Timeouts.keepAlive(),
+		FallbackDelay: happyEyeballsDelay,
+	}
+}
+
+// network returns the network to dial, restricted to the address family of the endpoint if any.
+func (cm *ConnectionManager) network() string {
+	switch cm.endpoint.IPFamily {
+	case IPv4:
+		return "tcp4"
+	case IPv6:
+		return "tcp6"
+	default:
+		return "tcp"
+	}
+}
+
+// proxyAuth returns the credentials to authenticate with the socks5 proxy,
+// or nil if the proxy does not require authentication, the secret handles of the credentials are resolved.
+func (cm *ConnectionManager) proxyAuth() (*proxy.Auth, error) {
+	if cm.endpoint.ProxyUsername == "" {
+		return nil, nil
+	}
+	username, err := logsConfig.ResolveSecret(cm.endpoint.ProxyUsername, "datadog.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the socks5 proxy username: %v", err)
+	}
+	password, err := logsConfig.ResolveSecret(cm.endpoint.ProxyPassword, "datadog.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the socks5 proxy password: %v", err)
+	}
+	return &proxy.Auth{
+		User:     username,
+		Password: password,
+	}, nil
+}
+
+// handshake runs the SSL handshake on conn and interrupts it when ctx is done.
+func (cm *ConnectionManager) handshake(ctx context.Context, conn *tls.Conn) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- conn.Handshake()
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		// setting a deadline in the past unblocks the pending handshake.
+		conn.SetDeadline(time.Now())
+		<-errs
+		return ctx.Err()
+	}
+}
+
 // address returns the address of the server to send logs to.
 func (cm *ConnectionManager) address() string {
 	return net.JoinHostPort(cm.endpoint.Host, strconv.Itoa(cm.endpoint.Port))
@@ -131,37 +404,12 @@ func (cm *ConnectionManager) CloseConnection(conn net.Conn) {
 	log.Info("Connection closed")
 }
 
-// handleServerClose lets the connection manager detect when a connection
-// has been closed by the server, and closes it for the client.
-// This is not strictly necessary but a good safeguard against callers
-// that might not handle errors properly.
-func (cm *ConnectionManager) handleServerClose(conn net.Conn) {
-	for {
-		buff := make([]byte, 1)
-		_, err := conn.Read(buff)
-		if err == io.EOF {
-			cm.CloseConnection(conn)
-			return
-		} else if err != nil {
-			log.Warn(err)
-			return
-		}
-	}
-}
-
-// backoff implements a randomized exponential backoff in case of connection failure
-// each invocation will trigger a sleep between [2^(retries-1), 2^retries) second
-// the exponent is capped at 7, which translates to max sleep between ~1min and ~2min
+// backoff waits for the duration defined by the backoff policy of the endpoint
+// or until the context is cancelled.
 func (cm *ConnectionManager) backoff(ctx context.Context, retries uint) {
-	if retries > maxExpBackoffCount {
-		retries = maxExpBackoffCount
-	}
-
-	backoffMax := 1 << retries
-	backoffMin := 1 << (retries - 1)
-	backoffDuration := time.Duration(backoffMin+rand.Intn(backoffMax-backoffMin)) * time.Second
-
-	ctx, cancel := context.WithTimeout(ctx, backoffDuration)
+	duration := cm.endpoint.Backoff.duration(retries)
+	metrics.SetDuration(&metrics.DestinationBackoff, cm.endpoint.Host, duration)
+	ctx, cancel := context.WithTimeout(ctx, duration)
 	defer cancel()
 	<-ctx.Done()
 }
@@ -6,14 +6,18 @@
 package client
 
 import (
+	"context"
+	"io"
 	"net"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client/mock"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/status"
 )
 
@@ -69,3 +73,213 @@ func TestNewConnectionReturnsWhenContextCancelled(t *testing.T) {
 	// Make sure NewConnection really returns.
 	wg.Wait()
 }
+
+func TestNewConnectionReturnsWhenContextCancelledDuringHandshake(t *testing.T) {
+	// This intake accepts connections but never answers the SSL handshake.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port := AddrToHostPort(l.Addr())
+	connManager := NewConnectionManager(Endpoint{Host: host, Port: port, UseSSL: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		conn, err := connManager.NewConnection(ctx)
+		assert.Nil(t, conn)
+		assert.Equal(t, context.Canceled, err)
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "NewConnection should return when the context is cancelled")
+	}
+}
+
+func TestNewConnectionReturnsAfterMaxRetries(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	host, port := AddrToHostPort(l.Addr())
+	// nothing listens on this address anymore.
+	l.Close()
+
+	connManager := NewConnectionManager(Endpoint{
+		Host:    host,
+		Port:    port,
+		Backoff: BackoffPolicy{Base: time.Millisecond, Max: 10 * time.Millisecond, MaxRetries: 3},
+	})
+
+	conn, err := connManager.NewConnection(context.Background())
+	assert.Nil(t, conn)
+	assert.Equal(t, ErrConnectionRetriesExhausted, err)
+	assert.Equal(t, uint(3), connManager.failures)
+
+	// the backoff goes on growing from the failures of the previous call.
+	conn, err = connManager.NewConnection(context.Background())
+	assert.Nil(t, conn)
+	assert.Equal(t, ErrConnectionRetriesExhausted, err)
+	assert.Equal(t, uint(6), connManager.failures)
+}
+
+// serveSocks5 accepts a single socks5 connection on l, negotiates the username/password authentication
+// and sends the credentials received on creds.
+func serveSocks5(t *testing.T, l net.Listener, creds chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// greeting: version, number of methods, methods.
+	buf := make([]byte, 257)
+	_, err = io.ReadFull(conn, buf[:2])
+	assert.NoError(t, err)
+	_, err = io.ReadFull(conn, buf[:buf[1]])
+	assert.NoError(t, err)
+	conn.Write([]byte{5, 2})
+
+	// username/password sub-negotiation: version, username, password.
+	_, err = io.ReadFull(conn, buf[:2])
+	assert.NoError(t, err)
+	user := make([]byte, buf[1])
+	io.ReadFull(conn, user)
+	io.ReadFull(conn, buf[:1])
+	password := make([]byte, buf[0])
+	io.ReadFull(conn, password)
+	creds <- string(user) + ":" + string(password)
+	conn.Write([]byte{1, 0})
+
+	// connect request: version, command, reserved, ipv4 address and port.
+	_, err = io.ReadFull(conn, buf[:10])
+	assert.NoError(t, err)
+	conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+}
+
+func TestNewConnectionWithProxyAuthentication(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	creds := make(chan string, 1)
+	go serveSocks5(t, l, creds)
+
+	endpoint := Endpoint{
+		Host:          "127.0.0.1",
+		Port:          10516,
+		ProxyAddress:  l.Addr().String(),
+		ProxyUsername: "foo",
+		ProxyPassword: "bar",
+		Backoff:       BackoffPolicy{MaxRetries: 1},
+	}
+	conn, err := NewConnectionManager(endpoint).NewConnection(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+	assert.Equal(t, "foo:bar", <-creds)
+	conn.Close()
+}
+
+func TestProxyAuth(t *testing.T) {
+	auth, err := NewConnectionManager(Endpoint{ProxyAddress: "foo:1234"}).proxyAuth()
+	assert.NoError(t, err)
+	assert.Nil(t, auth)
+	auth, err = NewConnectionManager(Endpoint{ProxyAddress: "foo:1234", ProxyUsername: "foo", ProxyPassword: "bar"}).proxyAuth()
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", auth.User)
+	assert.Equal(t, "bar", auth.Password)
+}
+
+func TestConnectionTimeouts(t *testing.T) {
+	timeouts := ConnectionTimeouts{}
+	assert.Equal(t, defaultDialTimeout, timeouts.dial())
+	assert.Equal(t, defaultHandshakeTimeout, timeouts.handshake())
+
+	assert.True(t, timeouts.keepAlive() < 0)
+
+	timeouts = ConnectionTimeouts{Dial: time.Second, Handshake: 2 * time.Second, KeepAlive: 3 * time.Second}
+	assert.Equal(t, time.Second, timeouts.dial())
+	assert.Equal(t, 2*time.Second, timeouts.handshake())
+	assert.Equal(t, 3*time.Second, timeouts.keepAlive())
+}
+
+func TestConnectTracksAttemptsAndFailures(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	host, port := AddrToHostPort(l.Addr())
+	connManager := NewConnectionManager(Endpoint{Host: host, Port: port})
+
+	attempts, failures := metrics.ConnectionAttempts.Value(), metrics.ConnectionFailures.Value()
+	conn, err := connManager.connect(context.Background())
+	assert.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, attempts+1, metrics.ConnectionAttempts.Value())
+	assert.Equal(t, failures, metrics.ConnectionFailures.Value())
+
+	l.Close()
+	_, err = connManager.connect(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, attempts+2, metrics.ConnectionAttempts.Value())
+	assert.Equal(t, failures+1, metrics.ConnectionFailures.Value())
+}
+
+func TestNetwork(t *testing.T) {
+	assert.Equal(t, "tcp", NewConnectionManager(Endpoint{}).network())
+	assert.Equal(t, "tcp4", NewConnectionManager(Endpoint{IPFamily: IPv4}).network())
+	assert.Equal(t, "tcp6", NewConnectionManager(Endpoint{IPFamily: IPv6}).network())
+}
+
+func TestNewConnectionWithIPFamily(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	_, port := AddrToHostPort(l.Addr())
+	connManager := NewConnectionManager(Endpoint{Host: "localhost", Port: port, IPFamily: IPv4})
+	assert.Equal(t, happyEyeballsDelay, connManager.netDialer().FallbackDelay)
+
+	conn, err := connManager.NewConnection(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp", conn.RemoteAddr().Network())
+	assert.NotNil(t, conn.RemoteAddr().(*net.TCPAddr).IP.To4())
+	connManager.CloseConnection(conn)
+}
+
+func TestNewConnectionTimesOutDuringHandshake(t *testing.T) {
+	// the server accepts the connection but never answers the handshake.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port := AddrToHostPort(l.Addr())
+	endpoint := Endpoint{
+		Host:     host,
+		Port:     port,
+		UseSSL:   true,
+		Backoff:  BackoffPolicy{MaxRetries: 1},
+		Timeouts: ConnectionTimeouts{Handshake: 50 * time.Millisecond},
+	}
+	start := time.Now()
+	_, err = NewConnectionManager(endpoint).NewConnection(context.Background())
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// unhealthyPeriod is the time during which a connection that failed is not used
+// as long as other connections of the pool are healthy.
+const unhealthyPeriod = 10 * time.Second
+
+// pooledConnection keeps track of the health of a connection of a pool.
+type pooledConnection struct {
+	conn        net.Conn
+	reader      *serverReader
+	failures    int
+	lastFailure time.Time
+	bytesSent   int64
+	openedAt    time.Time
+	lastWrite   time.Time
+	// buffer is reused to coalesce the frames written at once on the connections that do not support vectored writes.
+	buffer []byte
+}
+
+// isHealthy returns true if the connection did not fail recently.
+func (c *pooledConnection) isHealthy(now time.Time) bool {
+	return c.failures == 0 || now.Sub(c.lastFailure) > unhealthyPeriod
+}
+
+// A ConnectionPool spreads the frames to send over several connections to the same endpoint,
+// connections are opened lazily and used in turn.
+// A ConnectionPool is not thread safe, it's meant to be used by a single destination.
+type ConnectionPool struct {
+	connManager *ConnectionManager
+	resolver    *resolver
+	conns       []*pooledConnection
+	index       int
+}
+
+// NewConnectionPool returns a new pool of at most size connections.
+func NewConnectionPool(connManager *ConnectionManager, size int) *ConnectionPool {
+	if size < 1 {
+		size = 1
+	}
+	conns := make([]*pooledConnection, size)
+	for i := range conns {
+		conns[i] = &pooledConnection{}
+	}
+	return &ConnectionPool{
+		connManager: connManager,
+		resolver:    newResolver(connManager.endpoint.DNSRefreshInterval, connManager.endpoint.Timeouts.dial()),
+		conns:       conns,
+		index:       -1,
+	}
+}
+
+// Write writes a frame on the next healthy connection of the pool, the connection is opened if needed,
+// this call blocks until a connection is available or ctx is cancelled.
+// It returns a *ServerError without writing the frame when the intake closed the connection.
+func (p *ConnectionPool) Write(ctx context.Context, frame []byte) error {
+	return p.WriteFrames(ctx, [][]byte{frame})
+}
+
+// WriteFrames writes frames at once on the next healthy connection of the pool like Write,
+// either all the frames should be sent again on error or none of them.
+func (p *ConnectionPool) WriteFrames(ctx context.Context, frames [][]byte) error {
+	now := time.Now()
+	pc := p.next(now)
+	if pc.conn != nil && pc.reader != nil {
+		if err := pc.reader.closed(); err != nil {
+			// the frame would be lost, let the caller send it again on a new connection.
+			p.connManager.CloseConnection(pc.conn)
+			pc.conn = nil
+			return err
+		}
+	}
+	if pc.conn != nil && now.Sub(pc.openedAt) > p.connManager.failover.failbackInterval() && p.connManager.shouldReopen() {
+		// reopen the connection to give the manager a chance to fail back on the primary endpoint
+		// or to rebalance on the fastest one.
+		p.connManager.CloseConnection(pc.conn)
+		pc.conn = nil
+	}
+	if idle := p.connManager.endpoint.Timeouts.Idle; pc.conn != nil && idle > 0 && now.Sub(pc.lastWrite) > idle {
+		// the connection may have been silently dropped by a NAT or a firewall while unused.
+		log.Debugf("Reopening connection idle for %v", now.Sub(pc.lastWrite))
+		p.connManager.CloseConnection(pc.conn)
+		pc.conn = nil
+	}
+	if pc.conn != nil && p.isStale(ctx, pc.conn, now) {
+		// the intake moved, reconnect to spread the traffic over its new addresses.
+		log.Debugf("Reopening connection to %v, %v does not resolve to it anymore", pc.conn.RemoteAddr(), p.connManager.endpoint.Host)
+		p.connManager.CloseConnection(pc.conn)
+		pc.conn = nil
+	}
+	if pc.conn == nil {
+		conn, err := p.connManager.NewConnection(ctx)
+		if err != nil {
+			return err
+		}
+		pc.conn = conn
+		pc.reader = newServerReader(conn)
+		pc.openedAt = now
+	}
+
+	if timeout := p.connManager.endpoint.Timeouts.Write; timeout > 0 {
+		// a stalled intake must not block the destination forever.
+		pc.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	start := time.Now()
+	n, err := pc.writeFrames(frames)
+	if err != nil {
+		p.connManager.CloseConnection(pc.conn)
+		pc.conn = nil
+		pc.failures++
+		pc.lastFailure = time.Now()
+		return err
+	}
+
+	pc.failures = 0
+	pc.bytesSent += n
+	pc.lastWrite = time.Now()
+	metrics.BytesSent.Add(n)
+	metrics.SetDuration(&metrics.DestinationLatency, p.connManager.endpoint.Host, pc.lastWrite.Sub(start))
+	return nil
+}
+
+// writeFrames writes frames on the connection with as few syscalls as possible: a single vectored write
+// on plain TCP connections, a single write, and as such a single TLS record, on the other ones.
+func (c *pooledConnection) writeFrames(frames [][]byte) (int64, error) {
+	if len(frames) == 1 {
+		n, err := c.conn.Write(frames[0])
+		return int64(n), err
+	}
+	if _, isTCP := c.conn.(*net.TCPConn); isTCP {
+		// WriteTo consumes the buffers, the frames are left untouched for a retry.
+		buffers := make(net.Buffers, len(frames))
+		copy(buffers, frames)
+		return buffers.WriteTo(c.conn)
+	}
+	c.buffer = c.buffer[:0]
+	for _, frame := range frames {
+		c.buffer = append(c.buffer, frame...)
+	}
+	n, err := c.conn.Write(c.buffer)
+	return int64(n), err
+}
+
+// isStale returns true if conn is not connected to one of the addresses of the intake anymore,
+// connections through a proxy are never stale as the proxy resolves the intake.
+func (p *ConnectionPool) isStale(ctx context.Context, conn net.Conn, now time.Time) bool {
+	endpoint := p.connManager.endpoint
+	if endpoint.ProxyAddress != "" || endpoint.HTTPProxyURL != "" {
+		return false
+	}
+	return p.resolver.isStale(ctx, endpoint.Host, conn, now)
+}
+
+// Close closes all the open connections of the pool.
+func (p *ConnectionPool) Close() {
+	for _, pc := range p.conns {
+		if pc.conn != nil {
+			p.connManager.CloseConnection(pc.conn)
+			pc.conn = nil
+		}
+	}
+}
+
+// next returns the next healthy connection in a round robin fashion,
+// when all of them failed recently it falls back on the next one anyway.
+func (p *ConnectionPool) next(now time.Time) *pooledConnection {
+	for i := 0; i < len(p.conns); i++ {
+		p.index = (p.index + 1) % len(p.conns)
+		if p.conns[p.index].isHealthy(now) {
+			return p.conns[p.index]
+		}
+	}
+	p.index = (p.index + 1) % len(p.conns)
+	return p.conns[p.index]
+}
@@ -0,0 +1,269 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client/mock"
+)
+
+func TestConnectionPoolUsesConnectionsInTurn(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	pool := NewConnectionPool(newConnectionManagerForAddr(l.Addr()), 2)
+	defer pool.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, pool.Write(ctx, []byte("foo\n")))
+	}
+
+	// only two connections should have been opened and both should have been used twice.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-accepted:
+		case <-time.After(time.Second):
+			assert.Fail(t, "connection was not opened")
+		}
+	}
+	assert.Equal(t, 0, len(accepted))
+	assert.NotNil(t, pool.conns[0].conn)
+	assert.NotNil(t, pool.conns[1].conn)
+	assert.Equal(t, int64(8), pool.conns[0].bytesSent)
+	assert.Equal(t, int64(8), pool.conns[1].bytesSent)
+}
+
+func TestConnectionPoolSkipsUnhealthyConnections(t *testing.T) {
+	now := time.Now()
+	pool := NewConnectionPool(newConnectionManagerForHostPort("foo", 0), 3)
+	pool.conns[1].failures = 1
+	pool.conns[1].lastFailure = now
+
+	assert.Equal(t, pool.conns[0], pool.next(now))
+	assert.Equal(t, pool.conns[2], pool.next(now))
+	assert.Equal(t, pool.conns[0], pool.next(now))
+
+	// the failing connection is used again once the unhealthy period is over.
+	later := now.Add(2 * unhealthyPeriod)
+	assert.Equal(t, pool.conns[1], pool.next(later))
+}
+
+func TestConnectionPoolFallsBackWhenAllConnectionsAreUnhealthy(t *testing.T) {
+	now := time.Now()
+	pool := NewConnectionPool(newConnectionManagerForHostPort("foo", 0), 2)
+	for _, pc := range pool.conns {
+		pc.failures = 1
+		pc.lastFailure = now
+	}
+	assert.Equal(t, pool.conns[0], pool.next(now))
+	assert.Equal(t, pool.conns[1], pool.next(now))
+}
+
+type failingConn struct {
+	net.Conn
+}
+
+func (c *failingConn) Write(b []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func (c *failingConn) Close() error {
+	return nil
+}
+
+// countingConn counts the writes on its connection.
+type countingConn struct {
+	net.Conn
+	writes int
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	c.writes++
+	return c.Conn.Write(b)
+}
+
+func TestConnectionPoolWritesFramesAtOnce(t *testing.T) {
+	lines := make(chan string, 10)
+	l := newLineIntake(t, lines)
+	defer l.Close()
+
+	pool := NewConnectionPool(newConnectionManagerForAddr(l.Addr()), 1)
+	defer pool.Close()
+
+	frames := [][]byte{[]byte("foo\n"), []byte("bar\n"), []byte("baz\n")}
+	assert.NoError(t, pool.WriteFrames(context.Background(), frames))
+	assert.Equal(t, int64(12), pool.conns[0].bytesSent)
+	// the frames are left untouched to be sent again on error.
+	assert.Equal(t, [][]byte{[]byte("foo\n"), []byte("bar\n"), []byte("baz\n")}, frames)
+	for _, expected := range []string{"foo", "bar", "baz"} {
+		select {
+		case line := <-lines:
+			assert.Equal(t, expected, line)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "the intake did not receive the frame")
+		}
+	}
+}
+
+func TestConnectionPoolCoalescesFramesWithoutVectoredWrites(t *testing.T) {
+	pool := NewConnectionPool(newConnectionManagerForHostPort("foo", 0), 1)
+	conn, other := net.Pipe()
+	defer other.Close()
+	counting := &countingConn{Conn: conn}
+	pool.conns[0].conn = counting
+
+	received := make(chan []byte, 1)
+	go func() {
+		buf := make([]byte, 12)
+		n, _ := other.Read(buf)
+		received <- buf[:n]
+	}()
+	assert.NoError(t, pool.WriteFrames(context.Background(), [][]byte{[]byte("foo\n"), []byte("bar\n"), []byte("baz\n")}))
+	assert.Equal(t, 1, counting.writes)
+	assert.Equal(t, "foo\nbar\nbaz\n", string(<-received))
+}
+
+func TestConnectionPoolTracksFailures(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	pool := NewConnectionPool(newConnectionManagerForAddr(l.Addr()), 1)
+	pool.conns[0].conn = &failingConn{}
+
+	assert.Error(t, pool.Write(context.Background(), []byte("foo\n")))
+	assert.Nil(t, pool.conns[0].conn)
+	assert.Equal(t, 1, pool.conns[0].failures)
+
+	// a new connection is opened on the next write.
+	assert.NoError(t, pool.Write(context.Background(), []byte("foo\n")))
+	assert.NotNil(t, pool.conns[0].conn)
+	assert.Equal(t, 0, pool.conns[0].failures)
+	pool.Close()
+}
+
+func TestConnectionPoolWriteTimesOut(t *testing.T) {
+	endpoint := Endpoint{Host: "foo", Timeouts: ConnectionTimeouts{Write: 50 * time.Millisecond}}
+	pool := NewConnectionPool(NewConnectionManager(endpoint), 1)
+
+	// nobody reads on the other side of the pipe, the write blocks until the deadline.
+	conn, other := net.Pipe()
+	defer other.Close()
+	pool.conns[0].conn = conn
+
+	err := pool.Write(context.Background(), []byte("foo\n"))
+	assert.Error(t, err)
+	assert.Nil(t, pool.conns[0].conn)
+	assert.Equal(t, 1, pool.conns[0].failures)
+}
+
+func TestConnectionPoolReopensIdleConnections(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	host, port := AddrToHostPort(l.Addr())
+	endpoint := Endpoint{Host: host, Port: port, Timeouts: ConnectionTimeouts{Idle: time.Minute}}
+	pool := NewConnectionPool(NewConnectionManager(endpoint), 1)
+	defer pool.Close()
+
+	assert.NoError(t, pool.Write(context.Background(), []byte("foo\n")))
+	conn := pool.conns[0].conn
+
+	assert.NoError(t, pool.Write(context.Background(), []byte("foo\n")))
+	assert.Equal(t, conn, pool.conns[0].conn)
+
+	pool.conns[0].lastWrite = time.Now().Add(-2 * time.Minute)
+	assert.NoError(t, pool.Write(context.Background(), []byte("foo\n")))
+	assert.NotEqual(t, conn, pool.conns[0].conn)
+}
+
+func TestConnectionPoolReopensStaleConnections(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	host, port := AddrToHostPort(l.Addr())
+	endpoint := Endpoint{Host: host, Port: port, DNSRefreshInterval: time.Minute}
+	pool := NewConnectionPool(NewConnectionManager(endpoint), 1)
+	defer pool.Close()
+
+	addrs := []string{host}
+	pool.resolver.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return addrs, nil
+	}
+
+	assert.NoError(t, pool.Write(context.Background(), []byte("foo\n")))
+	conn := pool.conns[0].conn
+	assert.NoError(t, pool.Write(context.Background(), []byte("foo\n")))
+	assert.Equal(t, conn, pool.conns[0].conn)
+
+	// the intake moved to another address.
+	addrs = []string{"10.0.0.1"}
+	pool.resolver.resolvedAt = time.Now().Add(-2 * time.Minute)
+	assert.NoError(t, pool.Write(context.Background(), []byte("foo\n")))
+	assert.NotEqual(t, conn, pool.conns[0].conn)
+}
+
+func TestConnectionPoolReconnectsWhenTheIntakeClosesTheConnection(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	pool := NewConnectionPool(newConnectionManagerForAddr(l.Addr()), 1)
+	defer pool.Close()
+	ctx := context.Background()
+
+	assert.NoError(t, pool.Write(ctx, []byte("foo\n")))
+	server := <-accepted
+	server.Write([]byte("invalid api key\n"))
+	server.Close()
+
+	// wait for the pool to notice the closure.
+	deadline := time.Now().Add(time.Second)
+	for len(pool.conns[0].reader.done) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = pool.Write(ctx, []byte("foo\n"))
+	assert.IsType(t, &ServerError{}, err)
+	assert.Contains(t, err.Error(), "invalid api key")
+	assert.Nil(t, pool.conns[0].conn)
+
+	assert.NoError(t, pool.Write(ctx, []byte("foo\n")))
+	select {
+	case conn := <-accepted:
+		conn.Close()
+	case <-time.After(time.Second):
+		assert.Fail(t, "connection was not reopened")
+	}
+}
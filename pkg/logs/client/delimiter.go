@@ -8,6 +8,7 @@ package client
 import (
 	"bytes"
 	"encoding/binary"
+	"strconv"
 )
 
 // Delimiter is responsible for adding delimiters to the frames being sent.
@@ -15,11 +16,15 @@ type Delimiter interface {
 	delimit(content []byte) ([]byte, error)
 }
 
-// NewDelimiter returns a delimiter.
-func NewDelimiter(useProto bool) Delimiter {
+// NewDelimiter returns a delimiter, the raw frames are delimited with their length
+// instead of a line break with useOctetCounting.
+func NewDelimiter(useProto, useOctetCounting bool) Delimiter {
 	if useProto {
 		return &lengthPrefix
 	}
+	if useOctetCounting {
+		return &octetCounting
+	}
 	return &lineBreak
 }
 
@@ -67,3 +72,25 @@ type lineBreakDelimiter struct {
 func (l *lineBreakDelimiter) delimit(content []byte) ([]byte, error) {
 	return append(content, '\n'), nil
 }
+
+// OctetCounting is a delimiter that prepends the length of each message in ASCII decimal followed by a space,
+// as defined by RFC 6587 for syslog over TCP, so that the messages can contain line breaks.
+//
+// For example:
+// BEFORE ENCODE (300 bytes)       AFTER ENCODE (304 bytes)
+// +---------------+               +--------+-------+---------------+
+// | Raw Data      |-------------->| Length | Space | Raw Data      |
+// |  (300 bytes)  |               | "300"  | 0x20  |  (300 bytes)  |
+// +---------------+               +--------+-------+---------------+
+var octetCounting octetCountingDelimiter
+
+type octetCountingDelimiter struct {
+	Delimiter
+}
+
+func (o *octetCountingDelimiter) delimit(content []byte) ([]byte, error) {
+	frame := make([]byte, 0, len(content)+11)
+	frame = strconv.AppendInt(frame, int64(len(content)), 10)
+	frame = append(frame, ' ')
+	return append(frame, content...), nil
+}
@@ -12,8 +12,10 @@ import (
 )
 
 func TestNewDelimiter(t *testing.T) {
-	assert.Equal(t, &lengthPrefix, NewDelimiter(true))
-	assert.Equal(t, &lineBreak, NewDelimiter(false))
+	assert.Equal(t, &lengthPrefix, NewDelimiter(true, false))
+	assert.Equal(t, &lengthPrefix, NewDelimiter(true, true))
+	assert.Equal(t, &lineBreak, NewDelimiter(false, false))
+	assert.Equal(t, &octetCounting, NewDelimiter(false, true))
 }
 
 func TestLengthPrefixDelimiter(t *testing.T) {
@@ -42,3 +44,13 @@ func TestLineBreakDelimiter(t *testing.T) {
 	assert.Equal(t, "foo\n", string(bytes))
 
 }
+
+func TestOctetCountingDelimiter(t *testing.T) {
+	bytes, err := octetCounting.delimit([]byte{})
+	assert.Nil(t, err)
+	assert.Equal(t, "0 ", string(bytes))
+
+	bytes, err = octetCounting.delimit([]byte("foo\nbar"))
+	assert.Nil(t, err)
+	assert.Equal(t, "7 foo\nbar", string(bytes))
+}
@@ -6,8 +6,8 @@
 package client
 
 import (
+	"context"
 	"expvar"
-	"net"
 	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
@@ -40,11 +40,15 @@ func (e *FramingError) Error() string {
 
 // Destination is responsible for shipping logs to a remote server over TCP.
 type Destination struct {
+	apiKey              *APIKeyHolder
+	prefixMu            sync.Mutex
+	prefixKey           string
 	prefixer            *prefixer
 	delimiter           Delimiter
 	connManager         *ConnectionManager
+	connPool            *ConnectionPool
+	host                string
 	destinationsContext *DestinationsContext
-	conn                net.Conn
 	inputChan           chan []byte
 	once                sync.Once
 	warningCounter      int
@@ -52,11 +56,16 @@ type Destination struct {
 
 // NewDestination returns a new destination.
 func NewDestination(endpoint Endpoint, destinationsContext *DestinationsContext) *Destination {
-	prefix := endpoint.APIKey + string(' ')
+	apiKey := endpoint.apiKeyHolder()
+	connManager := NewConnectionManager(endpoint)
 	return &Destination{
-		prefixer:            newPrefixer(prefix),
-		delimiter:           NewDelimiter(endpoint.UseProto),
-		connManager:         NewConnectionManager(endpoint),
+		apiKey:              apiKey,
+		prefixKey:           apiKey.Get(),
+		prefixer:            newPrefixer(apiKey.Get() + string(' ')),
+		delimiter:           NewDelimiter(endpoint.UseProto, endpoint.UseOctetCounting),
+		connManager:         connManager,
+		connPool:            NewConnectionPool(connManager, endpoint.ConnectionPoolSize),
+		host:                endpoint.Host,
 		destinationsContext: destinationsContext,
 	}
 }
@@ -64,43 +73,78 @@ func NewDestination(endpoint Endpoint, destinationsContext *DestinationsContext)
 // Send transforms a message into a frame and sends it to a remote server,
 // returns an error if the operation failed.
 func (d *Destination) Send(payload []byte) error {
-	if d.conn == nil {
-		var err error
+	return d.SendWithAPIKey(payload, "")
+}
 
-		// We work only if we have a started destination context
-		ctx := d.destinationsContext.Context()
-		if d.conn, err = d.connManager.NewConnection(ctx); err != nil {
-			return err
-		}
+// SendWithAPIKey sends a message with apiKey in place of the API key of the endpoint, unless it's empty.
+func (d *Destination) SendWithAPIKey(payload []byte, apiKey string) error {
+	frame, err := d.Frame(payload, apiKey)
+	if err != nil {
+		return err
 	}
 
-	content := d.prefixer.apply(payload)
+	// We work only if we have a started destination context
+	ctx := d.destinationsContext.Context()
+	return d.connPool.Write(ctx, frame)
+}
+
+// Frame transforms a message into a frame prefixed with apiKey in place of the API key of the endpoint, unless it's empty,
+// it returns a *FramingError if the message can not be framed.
+func (d *Destination) Frame(payload []byte, apiKey string) ([]byte, error) {
+	prefixer := d.getPrefixer()
+	if apiKey != "" {
+		prefixer = newPrefixer(apiKey + string(' '))
+	}
+	content := prefixer.apply(payload)
 	frame, err := d.delimiter.delimit(content)
 	if err != nil {
-		return NewFramingError(err)
+		return nil, NewFramingError(err)
 	}
+	return frame, nil
+}
 
-	_, err = d.conn.Write(frame)
-	if err != nil {
-		d.connManager.CloseConnection(d.conn)
-		d.conn = nil
-		return err
+// SendFrames writes frames built with Frame at once to save syscalls,
+// on error either all the frames should be sent again or none of them.
+func (d *Destination) SendFrames(frames [][]byte) error {
+	ctx := d.destinationsContext.Context()
+	return d.connPool.WriteFrames(ctx, frames)
+}
+
+// getPrefixer returns the prefixer of the current API key, it's rebuilt when the key is rotated.
+func (d *Destination) getPrefixer() *prefixer {
+	key := d.apiKey.Get()
+	d.prefixMu.Lock()
+	defer d.prefixMu.Unlock()
+	if key != d.prefixKey {
+		d.prefixKey = key
+		d.prefixer = newPrefixer(key + string(' '))
 	}
+	return d.prefixer
+}
 
-	return nil
+// Close closes the connections of the destination, it must not be called while a message is being sent.
+func (d *Destination) Close() {
+	d.connPool.Close()
 }
 
 // SendAsync sends a message to the destination without blocking. If the channel is full, the incoming messages will be
 // dropped
 func (d *Destination) SendAsync(payload []byte) {
-	host := d.connManager.endpoint.Host
+	host := d.host
 	d.once.Do(func() {
 		inputChan := make(chan []byte, chanSize)
 		d.inputChan = inputChan
+		d.connManager.breaker = newCircuitBreaker(host, d.connManager.endpoint.CircuitBreaker)
 		metrics.DestinationLogsDropped.Set(host, &expvar.Int{})
 		go d.runAsync()
 	})
 
+	if !d.connManager.breaker.allow() {
+		// the destination is down, do not hold on to logs it can not receive.
+		metrics.DestinationLogsDropped.Add(host, 1)
+		return
+	}
+
 	select {
 	case d.inputChan <- payload:
 	default:
@@ -114,13 +158,50 @@ func (d *Destination) SendAsync(payload []byte) {
 
 // runAsync read the messages from the channel and send them
 func (d *Destination) runAsync() {
+	defer d.Close()
 	ctx := d.destinationsContext.Context()
 	for {
 		select {
 		case payload := <-d.inputChan:
-			d.Send(payload)
+			d.sendWithRetry(ctx, payload)
 		case <-ctx.Done():
 			return
 		}
 	}
 }
+
+// sendWithRetry keeps trying to send the message until it succeeds,
+// the connection manager of the destination applies its own backoff between connection attempts
+// so that an unreachable additional destination does not affect the other ones.
+// The message is dropped if it can not be framed, if the circuit breaker of the destination opens
+// or if the context is cancelled.
+func (d *Destination) sendWithRetry(ctx context.Context, payload []byte) {
+	breaker := d.connManager.breaker
+	for retries := 0; ; retries++ {
+		if !breaker.allow() {
+			metrics.DestinationLogsDropped.Add(d.host, 1)
+			return
+		}
+		if retries > 0 {
+			metrics.DestinationRetries.Add(1)
+		}
+		err := d.Send(payload)
+		if err == nil {
+			breaker.recordSuccess()
+			return
+		}
+		if ctx.Err() != nil {
+			// the agent is stopping.
+			return
+		}
+		metrics.DestinationErrors.Add(1)
+		if _, isFramingError := err.(*FramingError); isFramingError {
+			metrics.DestinationLogsDropped.Add(d.host, 1)
+			return
+		}
+		if err != errCircuitOpen {
+			// the connection manager keeps track of the failed connection attempts itself.
+			breaker.recordFailure()
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"bufio"
+	"expvar"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// newLineIntake returns a listener that sends every line it receives on lines.
+func newLineIntake(t *testing.T, lines chan<- string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func TestDestinationSendAsyncRetriesOnNetworkErrors(t *testing.T) {
+	lines := make(chan string, 10)
+	l := newLineIntake(t, lines)
+	defer l.Close()
+
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	host, port := AddrToHostPort(l.Addr())
+	destination := NewDestination(Endpoint{APIKey: "foo", Host: host, Port: port}, destinationsCtx)
+	// the first write fails as if the connection had been dropped.
+	destination.connPool.conns[0].conn = &failingConn{}
+
+	destination.SendAsync([]byte("bar"))
+	select {
+	case line := <-lines:
+		assert.Equal(t, "foo bar", line)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the message should have been sent again")
+	}
+}
+
+func TestDestinationSendAsyncShedsLogsWhenCircuitBreakerOpens(t *testing.T) {
+	// nothing listens on the port of a closed listener.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	l.Close()
+
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	endpoint := AddrToEndPoint(l.Addr())
+	endpoint.Backoff = BackoffPolicy{Base: time.Millisecond, Max: time.Millisecond}
+	endpoint.CircuitBreaker = CircuitBreakerPolicy{Threshold: 3, Cooldown: time.Hour}
+	destination := NewDestination(endpoint, destinationsCtx)
+
+	destination.SendAsync([]byte("foo"))
+	deadline := time.Now().Add(5 * time.Second)
+	for destination.connManager.breaker.allow() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, destination.connManager.breaker.allow())
+
+	dropped := metrics.DestinationLogsDropped.Get(endpoint.Host).(*expvar.Int).Value()
+	destination.SendAsync([]byte("bar"))
+	assert.Equal(t, dropped+1, metrics.DestinationLogsDropped.Get(endpoint.Host).(*expvar.Int).Value())
+	assert.Equal(t, 0, len(destination.inputChan))
+}
+
+func TestDestinationSendWithRotatedAPIKey(t *testing.T) {
+	lines := make(chan string, 10)
+	l := newLineIntake(t, lines)
+	defer l.Close()
+
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	host, port := AddrToHostPort(l.Addr())
+	holder := NewAPIKeyHolder("foo")
+	destination := NewDestination(Endpoint{APIKey: "foo", APIKeyHolder: holder, Host: host, Port: port}, destinationsCtx)
+	defer destination.Close()
+
+	assert.NoError(t, destination.Send([]byte("bar")))
+	holder.Set("baz")
+	assert.NoError(t, destination.Send([]byte("bar")))
+	for _, expected := range []string{"foo bar", "baz bar"} {
+		select {
+		case line := <-lines:
+			assert.Equal(t, expected, line)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "the intake did not receive the log")
+		}
+	}
+}
+
+func TestDestinationSendWithAPIKey(t *testing.T) {
+	lines := make(chan string, 10)
+	l := newLineIntake(t, lines)
+	defer l.Close()
+
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	host, port := AddrToHostPort(l.Addr())
+	destination := NewDestination(Endpoint{APIKey: "foo", Host: host, Port: port}, destinationsCtx)
+	defer destination.Close()
+
+	assert.NoError(t, destination.SendWithAPIKey([]byte("bar"), "baz"))
+	assert.NoError(t, destination.SendWithAPIKey([]byte("bar"), ""))
+	for _, expected := range []string{"baz bar", "foo bar"} {
+		select {
+		case line := <-lines:
+			assert.Equal(t, expected, line)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "the intake did not receive the log")
+		}
+	}
+}
+
+func TestDestinationSendWithOctetCounting(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		frame := make([]byte, len("11 foo bar\nbaz"))
+		if _, err := io.ReadFull(conn, frame); err == nil {
+			received <- string(frame)
+		}
+	}()
+
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	host, port := AddrToHostPort(l.Addr())
+	destination := NewDestination(Endpoint{APIKey: "foo", Host: host, Port: port, UseOctetCounting: true}, destinationsCtx)
+	defer destination.Close()
+
+	// the line break of the message is sent as is.
+	assert.NoError(t, destination.Send([]byte("bar\nbaz")))
+	select {
+	case frame := <-received:
+		assert.Equal(t, "11 foo bar\nbaz", frame)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the intake did not receive the log")
+	}
+}
@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxRecentErrors is the number of errors kept to be shipped in the flares.
+const maxRecentErrors = 20
+
+// A SendError is an error the destinations ran into while connecting or sending logs to an intake.
+type SendError struct {
+	Time    time.Time
+	Address string
+	Error   string
+}
+
+var (
+	recentErrors   []SendError
+	recentErrorsMu sync.Mutex
+)
+
+// recordError keeps track of an error the destinations ran into, only the last maxRecentErrors are kept.
+func recordError(address string, err error) {
+	recentErrorsMu.Lock()
+	defer recentErrorsMu.Unlock()
+	if len(recentErrors) == maxRecentErrors {
+		recentErrors = recentErrors[1:]
+	}
+	recentErrors = append(recentErrors, SendError{Time: time.Now(), Address: address, Error: err.Error()})
+}
+
+// RecentErrors returns the last errors the destinations ran into, from the oldest to the most recent.
+func RecentErrors() []SendError {
+	recentErrorsMu.Lock()
+	defer recentErrorsMu.Unlock()
+	return append([]SendError(nil), recentErrors...)
+}
+
+// DescribeEndpoint returns a human readable description of how the connections to an endpoint are established,
+// the credentials are left out.
+func DescribeEndpoint(endpoint Endpoint) string {
+	var b bytes.Buffer
+	cm := NewConnectionManager(endpoint)
+	fmt.Fprintf(&b, "Address: %s\n", cm.address())
+	fmt.Fprintf(&b, "SSL: %v\n", endpoint.UseSSL)
+	switch {
+	case endpoint.ProxyAddress != "":
+		fmt.Fprintf(&b, "Proxy: socks5://%s\n", endpoint.ProxyAddress)
+	case endpoint.HTTPProxyURL != "":
+		fmt.Fprintf(&b, "Proxy: %s\n", sanitizeProxyURL(endpoint.HTTPProxyURL))
+	default:
+		fmt.Fprintf(&b, "Proxy: none\n")
+	}
+	if endpoint.IPFamily != "" {
+		fmt.Fprintf(&b, "IP family: %s\n", endpoint.IPFamily)
+	}
+	for _, fallback := range endpoint.Failover.Fallbacks {
+		fmt.Fprintf(&b, "Fallback: %s\n", NewConnectionManager(fallback).address())
+	}
+	return b.String()
+}
+
+// DiagnoseTLS connects to an endpoint the way the destinations do and returns the outcome
+// of the SSL handshake, with the negotiated parameters and the certificates presented by the intake.
+func DiagnoseTLS(ctx context.Context, endpoint Endpoint) string {
+	var b bytes.Buffer
+	cm := NewConnectionManager(endpoint)
+	fmt.Fprintf(&b, "Address: %s\n", cm.address())
+
+	dialCtx, cancel := context.WithTimeout(ctx, endpoint.Timeouts.dial())
+	conn, err := cm.dial(dialCtx)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(&b, "Could not connect: %v\n", err)
+		return b.String()
+	}
+	defer conn.Close()
+
+	tlsConfig, err := NewTLSConfig(endpoint)
+	if err != nil {
+		fmt.Fprintf(&b, "Invalid TLS configuration: %v\n", err)
+		return b.String()
+	}
+	sslConn := tls.Client(conn, tlsConfig)
+	handshakeCtx, cancel := context.WithTimeout(ctx, endpoint.Timeouts.handshake())
+	err = cm.handshake(handshakeCtx, sslConn)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(&b, "Handshake failed: %v\n", err)
+		return b.String()
+	}
+
+	state := sslConn.ConnectionState()
+	fmt.Fprintf(&b, "Handshake successful\n")
+	fmt.Fprintf(&b, "Version: %s\n", tlsVersionName(state.Version))
+	fmt.Fprintf(&b, "Cipher suite: %s\n", cipherSuiteName(state.CipherSuite))
+	for i, cert := range state.PeerCertificates {
+		fmt.Fprintf(&b, "Certificate #%d: subject=%q issuer=%q not_before=%s not_after=%s\n",
+			i, cert.Subject.String(), cert.Issuer.String(), cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// tlsVersionName returns the name of a TLS version as configured in the agent.
+func tlsVersionName(version uint16) string {
+	for name, v := range tlsVersions {
+		if v == version {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+// cipherSuiteName returns the name of a cipher suite as configured in the agent,
+// the TLS 1.3 cipher suites are not configurable and are shown by their identifier.
+func cipherSuiteName(suite uint16) string {
+	for name, s := range cipherSuites {
+		if s == suite {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%04x", suite)
+}
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/status"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// latencySmoothing is the weight of a new measure in the smoothed latency of an endpoint.
+	latencySmoothing = 0.3
+	// latencySwitchRatio is how much faster than the current endpoint another one must be to switch to it,
+	// so that endpoints with similar latencies do not make the connections flap between them.
+	latencySwitchRatio = 0.8
+)
+
+// endpointProbe is the outcome of an attempt at connecting to an endpoint.
+type endpointProbe struct {
+	conn    net.Conn
+	latency time.Duration
+	err     error
+}
+
+// rebalance probes all the endpoints at once at most once per failback interval and switches to the fastest
+// reachable one, it returns the connection to it, or nil if no endpoint was probed or reached.
+func (cm *ConnectionManager) rebalance(ctx context.Context) net.Conn {
+	if !cm.lastProbe.IsZero() && time.Since(cm.lastProbe) < cm.failover.failbackInterval() {
+		return nil
+	}
+	cm.lastProbe = time.Now()
+
+	probes := cm.probeEndpoints(ctx)
+	for i, probe := range probes {
+		if probe.err != nil {
+			log.Debugf("Endpoint %v is unreachable: %v", cm.endpointAddress(i), probe.err)
+			cm.latencies[i] = 0
+			continue
+		}
+		cm.recordLatency(i, probe.latency)
+	}
+	fastest := fastestEndpoint(cm.latencies, cm.current)
+	for i, probe := range probes {
+		if probe.conn != nil && i != fastest {
+			probe.conn.Close()
+		}
+	}
+	if fastest < 0 {
+		return nil
+	}
+	if fastest != cm.current {
+		log.Infof("Switching from %v (%v) to the fastest endpoint %v (%v)", cm.address(), cm.latencies[cm.current], cm.endpointAddress(fastest), cm.latencies[fastest])
+		cm.current = fastest
+		cm.endpoint = cm.endpoints[fastest]
+	}
+	cm.failures = 0
+	status.RemoveGlobalWarning(statusConnectionError)
+	return probes[fastest].conn
+}
+
+// probeEndpoints makes a single attempt at connecting to each endpoint concurrently and measures their latency.
+func (cm *ConnectionManager) probeEndpoints(ctx context.Context) []endpointProbe {
+	probes := make([]endpointProbe, len(cm.endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range cm.endpoints {
+		wg.Add(1)
+		go func(i int, endpoint Endpoint) {
+			defer wg.Done()
+			manager := &ConnectionManager{endpoint: endpoint}
+			start := time.Now()
+			conn, err := manager.connect(ctx)
+			probes[i] = endpointProbe{conn: conn, latency: time.Since(start), err: err}
+		}(i, endpoint)
+	}
+	wg.Wait()
+	return probes
+}
+
+// recordLatency updates the smoothed latency of the endpoint at index with a new measure.
+func (cm *ConnectionManager) recordLatency(index int, latency time.Duration) {
+	if cm.latencies[index] == 0 {
+		cm.latencies[index] = latency
+	} else {
+		cm.latencies[index] = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(cm.latencies[index]))
+	}
+	metrics.SetDuration(&metrics.DestinationConnectLatency, cm.endpointAddress(index), cm.latencies[index])
+}
+
+// endpointAddress returns the address of the endpoint at index.
+func (cm *ConnectionManager) endpointAddress(index int) string {
+	manager := &ConnectionManager{endpoint: cm.endpoints[index]}
+	return manager.address()
+}
+
+// fastestEndpoint returns the index of the endpoint with the lowest latency among the reachable ones,
+// which have a non-zero latency, current is kept unless another one is faster by latencySwitchRatio.
+// It returns -1 if no endpoint is reachable.
+func fastestEndpoint(latencies []time.Duration, current int) int {
+	fastest := -1
+	for i, latency := range latencies {
+		if latency > 0 && (fastest < 0 || latency < latencies[fastest]) {
+			fastest = i
+		}
+	}
+	if fastest >= 0 && latencies[current] > 0 && float64(latencies[fastest]) > latencySwitchRatio*float64(latencies[current]) {
+		return current
+	}
+	return fastest
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client/mock"
+)
+
+func TestFastestEndpoint(t *testing.T) {
+	ms := time.Millisecond
+	assert.Equal(t, -1, fastestEndpoint([]time.Duration{0, 0}, 0))
+	assert.Equal(t, 1, fastestEndpoint([]time.Duration{0, 10 * ms}, 0))
+	assert.Equal(t, 1, fastestEndpoint([]time.Duration{20 * ms, 10 * ms, 30 * ms}, 0))
+	// the current endpoint is kept unless another one is significantly faster.
+	assert.Equal(t, 0, fastestEndpoint([]time.Duration{10 * ms, 9 * ms}, 0))
+	assert.Equal(t, 2, fastestEndpoint([]time.Duration{10 * ms, 9 * ms, 5 * ms}, 0))
+}
+
+func TestSelectsByLatency(t *testing.T) {
+	assert.False(t, FailoverPolicy{Selection: LatencySelection}.selectsByLatency())
+	assert.False(t, FailoverPolicy{Fallbacks: []Endpoint{{Host: "foo"}}}.selectsByLatency())
+	assert.True(t, FailoverPolicy{Fallbacks: []Endpoint{{Host: "foo"}}, Selection: LatencySelection}.selectsByLatency())
+}
+
+func TestRecordLatencySmoothesTheMeasures(t *testing.T) {
+	connManager := NewConnectionManager(Endpoint{Host: "foo", Port: 1234})
+	connManager.recordLatency(0, 100*time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, connManager.latencies[0])
+	connManager.recordLatency(0, 200*time.Millisecond)
+	assert.Equal(t, 130*time.Millisecond, connManager.latencies[0])
+}
+
+func TestNewConnectionSelectsTheReachableEndpoint(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	endpoint := unreachableEndpoint(t)
+	endpoint.Failover = FailoverPolicy{Fallbacks: []Endpoint{reachableEndpoint(l)}, Selection: LatencySelection, FailbackInterval: time.Minute}
+	connManager := NewConnectionManager(endpoint)
+
+	conn, err := connManager.NewConnection(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+	assert.Equal(t, l.Addr().String(), connManager.address())
+	assert.Equal(t, time.Duration(0), connManager.latencies[0])
+	assert.NotEqual(t, time.Duration(0), connManager.latencies[1])
+	conn.Close()
+
+	// the endpoints are not probed again before the failback interval.
+	assert.Nil(t, connManager.rebalance(context.Background()))
+}
+
+func TestNewConnectionRebalancesOnTheFastestEndpoint(t *testing.T) {
+	primary := mock.NewMockLogsIntake(t)
+	defer primary.Close()
+	fallback := mock.NewMockLogsIntake(t)
+	defer fallback.Close()
+
+	endpoint := reachableEndpoint(primary)
+	endpoint.Failover = FailoverPolicy{Fallbacks: []Endpoint{reachableEndpoint(fallback)}, Selection: LatencySelection, FailbackInterval: time.Minute}
+	connManager := NewConnectionManager(endpoint)
+	// the primary endpoint was measured much slower than the fallback.
+	connManager.latencies = []time.Duration{time.Hour, time.Nanosecond}
+	connManager.lastProbe = time.Now().Add(-2 * time.Minute)
+
+	conn, err := connManager.NewConnection(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+	assert.Equal(t, 1, connManager.current)
+	assert.Equal(t, fallback.Addr().String(), connManager.address())
+	assert.True(t, connManager.shouldReopen())
+	conn.Close()
+}
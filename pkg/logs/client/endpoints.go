@@ -5,6 +5,10 @@
 
 package client
 
+import (
+	"time"
+)
+
 // Endpoint holds all the organization and network parameters to send logs to Datadog.
 type Endpoint struct {
 	APIKey       string `mapstructure:"api_key"`
@@ -13,18 +17,81 @@ type Endpoint struct {
 	UseSSL       bool
 	UseProto     bool
 	ProxyAddress string
+	// UseOctetCounting delimits the raw frames with their length instead of a line break.
+	UseOctetCounting bool `mapstructure:"-"`
+	// ProxyUsername and ProxyPassword authenticate the agent with the socks5 proxy when set.
+	ProxyUsername string `mapstructure:"proxy_username"`
+	ProxyPassword string `mapstructure:"proxy_password"`
+	// HTTPProxyURL is the URL of the HTTP proxy to tunnel the connections through with CONNECT.
+	HTTPProxyURL string             `mapstructure:"-"`
+	Backoff      BackoffPolicy      `mapstructure:"-"`
+	Failover     FailoverPolicy     `mapstructure:"-"`
+	Timeouts     ConnectionTimeouts `mapstructure:"-"`
+	// CircuitBreaker sheds the logs of an additional endpoint that keeps failing.
+	CircuitBreaker CircuitBreakerPolicy `mapstructure:"-"`
+	// ConnectionPoolSize is the number of TCP connections a destination can use concurrently.
+	ConnectionPoolSize int `mapstructure:"-"`
+	// PayloadFormat is the format of the HTTP payloads, JSONPayloadFormat when empty.
+	PayloadFormat string `mapstructure:"-"`
+	// UseCompression enables the gzip compression of the HTTP payloads at CompressionLevel.
+	UseCompression   bool `mapstructure:"-"`
+	CompressionLevel int  `mapstructure:"-"`
+	// CAFile, CertFile and KeyFile are the PEM files used to verify the server and to authenticate the agent,
+	// KeyPassphrase decrypts KeyFile when it's encrypted.
+	CAFile        string `mapstructure:"-"`
+	CertFile      string `mapstructure:"-"`
+	KeyFile       string `mapstructure:"-"`
+	KeyPassphrase string `mapstructure:"-"`
+	// MinTLSVersion and CipherSuites restrict the TLS versions and cipher suites negotiated with the intake.
+	MinTLSVersion string   `mapstructure:"-"`
+	CipherSuites  []string `mapstructure:"-"`
+	// FIPS restricts the TLS versions, cipher suites and curves negotiated with the intake to the FIPS-approved ones.
+	FIPS bool `mapstructure:"-"`
+	// PinnedPublicKeys are the base64 encoded SHA-256 digests of the public keys the certificate chain
+	// of the intake must contain one of, no pinning is done when empty.
+	PinnedPublicKeys []string `mapstructure:"-"`
+	// IPFamily restricts the addresses dialed to IPv4 or IPv6 ones, both are tried by default.
+	IPFamily string `mapstructure:"-"`
+	// DNSRefreshInterval is how often the host is resolved again to rotate the connections
+	// to addresses it does not resolve to anymore, zero disables the rotation.
+	DNSRefreshInterval time.Duration `mapstructure:"-"`
+	// APIKeyHolder, when set, holds the API key in place of APIKey so that it can be rotated at runtime.
+	APIKeyHolder *APIKeyHolder `mapstructure:"-"`
 }
 
+// The formats of the HTTP payloads.
+const (
+	// JSONPayloadFormat sends the logs as a JSON array of objects.
+	JSONPayloadFormat = "json"
+	// ProtobufPayloadFormat sends the logs as a protobuf message holding them in its repeated field 1.
+	ProtobufPayloadFormat = "protobuf"
+)
+
 // Endpoints holds the main endpoint and additional ones to dualship logs.
 type Endpoints struct {
 	Main        Endpoint
 	Additionals []Endpoint
+	UseHTTP     bool
+	BatchWait   time.Duration
+	// UseAggregator sends the logs over TCP to an aggregator agent which forwards them to the intake,
+	// they are encoded in JSON to keep their hostname, their metadata and their attributes.
+	UseAggregator bool
+	// MaxInflightBatches is the number of HTTP batches that can be sent before the previous ones are acknowledged.
+	MaxInflightBatches int
+	// MaxBytesPerSecond and MaxEventsPerSecond cap the outbound traffic when positive.
+	MaxBytesPerSecond  int
+	MaxEventsPerSecond int
+	// TCPMaxBatchSize is the number of logs written at once over TCP, the sender waits up to TCPBatchLinger for them.
+	TCPMaxBatchSize int
+	TCPBatchLinger  time.Duration
 }
 
 // NewEndpoints returns a new endpoints composite.
-func NewEndpoints(main Endpoint, additionals []Endpoint) *Endpoints {
+func NewEndpoints(main Endpoint, additionals []Endpoint, useHTTP bool, batchWait time.Duration) *Endpoints {
 	return &Endpoints{
 		Main:        main,
 		Additionals: additionals,
+		UseHTTP:     useHTTP,
+		BatchWait:   batchWait,
 	}
 }
@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"time"
+)
+
+const (
+	defaultFailoverRetries  = 3
+	defaultFailbackInterval = 5 * time.Minute
+)
+
+// The ways the endpoint to send logs to is selected among the primary endpoint and its fallbacks.
+const (
+	// OrderedSelection uses the endpoints in order, the next one is only used when the current one is unreachable.
+	OrderedSelection = "ordered"
+	// LatencySelection uses the reachable endpoint with the lowest connection latency,
+	// the endpoints are probed again every failback interval to rebalance on the fastest one.
+	LatencySelection = "latency"
+)
+
+// FailoverPolicy holds the endpoints to fall back on when the intake of an endpoint is unreachable.
+type FailoverPolicy struct {
+	// Fallbacks are the endpoints to fail over to, in order.
+	Fallbacks []Endpoint
+	// Retries is the number of consecutive failed attempts after which the next endpoint is used.
+	Retries int
+	// FailbackInterval is how often the primary endpoint is probed once failed over,
+	// or how often all the endpoints are probed with the latency selection.
+	FailbackInterval time.Duration
+	// Selection is how the endpoint in use is selected, OrderedSelection if empty.
+	Selection string
+}
+
+// shouldFailOver returns true if the next endpoint should be used after failures consecutive failed attempts.
+func (p FailoverPolicy) shouldFailOver(failures uint) bool {
+	if len(p.Fallbacks) == 0 {
+		return false
+	}
+	retries := p.Retries
+	if retries <= 0 {
+		retries = defaultFailoverRetries
+	}
+	return failures >= uint(retries)
+}
+
+// selectsByLatency returns true if the fastest endpoint should be used rather than the first reachable one.
+func (p FailoverPolicy) selectsByLatency() bool {
+	return p.Selection == LatencySelection && len(p.Fallbacks) > 0
+}
+
+// failbackInterval returns how often the primary endpoint is probed once failed over,
+// or how often all the endpoints are probed with the latency selection.
+func (p FailoverPolicy) failbackInterval() time.Duration {
+	if p.FailbackInterval <= 0 {
+		return defaultFailbackInterval
+	}
+	return p.FailbackInterval
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client/mock"
+)
+
+// unreachableEndpoint returns an endpoint no server listens on.
+func unreachableEndpoint(t *testing.T) Endpoint {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	host, port := AddrToHostPort(l.Addr())
+	l.Close()
+	return Endpoint{Host: host, Port: port}
+}
+
+func reachableEndpoint(l net.Listener) Endpoint {
+	host, port := AddrToHostPort(l.Addr())
+	return Endpoint{Host: host, Port: port}
+}
+
+func TestFailoverPolicy(t *testing.T) {
+	policy := FailoverPolicy{}
+	assert.False(t, policy.shouldFailOver(10))
+	assert.Equal(t, defaultFailbackInterval, policy.failbackInterval())
+
+	policy = FailoverPolicy{Fallbacks: []Endpoint{{Host: "foo"}}}
+	assert.False(t, policy.shouldFailOver(defaultFailoverRetries-1))
+	assert.True(t, policy.shouldFailOver(defaultFailoverRetries))
+
+	policy = FailoverPolicy{Fallbacks: []Endpoint{{Host: "foo"}}, Retries: 1, FailbackInterval: time.Second}
+	assert.True(t, policy.shouldFailOver(1))
+	assert.Equal(t, time.Second, policy.failbackInterval())
+}
+
+func TestNewConnectionFailsOver(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	endpoint := unreachableEndpoint(t)
+	endpoint.Backoff = BackoffPolicy{Base: time.Millisecond, Max: time.Millisecond}
+	endpoint.Failover = FailoverPolicy{Fallbacks: []Endpoint{reachableEndpoint(l)}, Retries: 2}
+	connManager := NewConnectionManager(endpoint)
+
+	conn, err := connManager.NewConnection(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+	assert.True(t, connManager.isFailedOver())
+	assert.Equal(t, l.Addr().String(), connManager.address())
+	conn.Close()
+}
+
+func TestNewConnectionFailsBack(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	endpoint := reachableEndpoint(l)
+	endpoint.Failover = FailoverPolicy{Fallbacks: []Endpoint{unreachableEndpoint(t)}, FailbackInterval: time.Minute}
+	connManager := NewConnectionManager(endpoint)
+	connManager.failOver(1)
+	assert.True(t, connManager.isFailedOver())
+
+	// the primary endpoint is not probed before the failback interval.
+	assert.Nil(t, connManager.probePrimary(context.Background()))
+
+	connManager.lastProbe = time.Now().Add(-2 * time.Minute)
+	conn, err := connManager.NewConnection(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+	assert.False(t, connManager.isFailedOver())
+	assert.Equal(t, l.Addr().String(), connManager.address())
+	conn.Close()
+}
+
+func TestConnectionPoolReopensConnectionsWhenFailedOver(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	endpoint := reachableEndpoint(l)
+	endpoint.Failover = FailoverPolicy{Fallbacks: []Endpoint{reachableEndpoint(l)}, FailbackInterval: time.Minute}
+	connManager := NewConnectionManager(endpoint)
+	connManager.failOver(1)
+	pool := NewConnectionPool(connManager, 1)
+	defer pool.Close()
+
+	assert.NoError(t, pool.Write(context.Background(), []byte("foo\n")))
+	assert.True(t, connManager.isFailedOver())
+
+	// once the connection is older than the failback interval, it's reopened and the primary endpoint probed.
+	pool.conns[0].openedAt = time.Now().Add(-2 * time.Minute)
+	connManager.lastProbe = time.Now().Add(-2 * time.Minute)
+	assert.NoError(t, pool.Write(context.Background(), []byte("foo\n")))
+	assert.False(t, connManager.isFailedOver())
+}
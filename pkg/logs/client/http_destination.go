@@ -0,0 +1,381 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/status"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	httpTimeout          = 20 * time.Second
+	httpContentType      = "application/json"
+	httpProtoContentType = "application/x-protobuf"
+	httpPath             = "/v1/input"
+	// payloadTooLargeWarningExpiration is the time the warning about the payloads too large stays on the status page,
+	// the split payloads are accepted right after the rejected one.
+	payloadTooLargeWarningExpiration = 10 * time.Minute
+)
+
+// errClient is returned when the intake rejects a payload,
+// retrying to send the same payload would fail again.
+var errClient = errors.New("client error")
+
+// ErrRetryBudgetExhausted is returned when the intake kept failing on a payload more times than
+// the retry budget of the payloads allows, while it was neither unavailable nor overloaded.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// IsPoisonPayload returns true if err means that the intake will never accept the payload as is:
+// it rejected it with a client error or kept failing on it.
+func IsPoisonPayload(err error) bool {
+	return err == errClient || err == ErrRetryBudgetExhausted
+}
+
+// RetryableError represents an error that can occur when sending a payload
+// that is likely to disappear by itself, e.g. a network issue or an intake outage.
+type RetryableError struct {
+	err error
+	// payloadFailure is set when the intake failed on the payload while being neither unavailable nor overloaded.
+	payloadFailure bool
+}
+
+// NewRetryableError returns a new retryable error.
+func NewRetryableError(err error) *RetryableError {
+	return &RetryableError{
+		err: err,
+	}
+}
+
+// Error returns the message of the error.
+func (e *RetryableError) Error() string {
+	return e.err.Error()
+}
+
+// HTTPDestination is responsible for shipping batches of logs to a remote server over HTTP(S),
+// Send is safe for concurrent use.
+type HTTPDestination struct {
+	url              string
+	apiKey           *APIKeyHolder
+	payloadFormat    string
+	useCompression   bool
+	compressionLevel int
+	// compressionUnsupported is set once the intake rejected a compressed payload.
+	compressionUnsupported int32
+	backoff                BackoffPolicy
+	breakerPolicy          CircuitBreakerPolicy
+	// breaker, when set, makes Send give up as soon as it opens.
+	breaker             *circuitBreaker
+	host                string
+	client              *http.Client
+	destinationsContext *DestinationsContext
+	inputChan           chan []byte
+	once                sync.Once
+	// forbidden and tooLarge surface the payloads rejected for their API key and their size.
+	forbidden *intakeErrorReporter
+	tooLarge  *intakeErrorReporter
+	// refusal, when set, is returned by Send in place of posting the payloads.
+	refusal error
+}
+
+// NewHTTPDestination returns a new HTTP destination, an error is returned when the TLS configuration
+// of the endpoint can not be loaded as the payloads must not be posted without it.
+func NewHTTPDestination(endpoint Endpoint, destinationsContext *DestinationsContext) (*HTTPDestination, error) {
+	transport, err := newHTTPTransport(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPDestination{
+		url:              buildURL(endpoint),
+		apiKey:           endpoint.apiKeyHolder(),
+		payloadFormat:    endpoint.PayloadFormat,
+		useCompression:   endpoint.UseCompression,
+		compressionLevel: endpoint.CompressionLevel,
+		backoff:          endpoint.Backoff,
+		breakerPolicy:    endpoint.CircuitBreaker,
+		host:             endpoint.Host,
+		client: &http.Client{
+			Timeout:   httpTimeout,
+			Transport: transport,
+		},
+		destinationsContext: destinationsContext,
+		forbidden:           newIntakeErrorReporter("intake_forbidden", endpoint.Host, 0),
+		tooLarge:            newIntakeErrorReporter("intake_payload_too_large", endpoint.Host, payloadTooLargeWarningExpiration),
+	}, nil
+}
+
+// NewRefusingHTTPDestination returns a destination that refuses to send the payloads with err,
+// it stands in for a destination that could not be created.
+func NewRefusingHTTPDestination(endpoint Endpoint, err error) *HTTPDestination {
+	return &HTTPDestination{
+		url:           buildURL(endpoint),
+		payloadFormat: endpoint.PayloadFormat,
+		host:          endpoint.Host,
+		client:        &http.Client{},
+		refusal:       err,
+	}
+}
+
+// newHTTPTransport returns the transport to use to post the payloads,
+// it trusts the CA bundle, presents the client certificate and applies the TLS restrictions of the endpoint when set.
+func newHTTPTransport(endpoint Endpoint) (*http.Transport, error) {
+	tlsConfig, err := NewTLSConfig(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the TLS configuration of %v: %v", endpoint.Host, err)
+	}
+	transport := util.CreateHTTPTransport()
+	transport.TLSClientConfig.RootCAs = tlsConfig.RootCAs
+	transport.TLSClientConfig.Certificates = tlsConfig.Certificates
+	if tlsConfig.MinVersion != 0 {
+		transport.TLSClientConfig.MinVersion = tlsConfig.MinVersion
+	}
+	if tlsConfig.MaxVersion != 0 {
+		transport.TLSClientConfig.MaxVersion = tlsConfig.MaxVersion
+	}
+	transport.TLSClientConfig.CipherSuites = tlsConfig.CipherSuites
+	transport.TLSClientConfig.CurvePreferences = tlsConfig.CurvePreferences
+	transport.TLSClientConfig.VerifyPeerCertificate = tlsConfig.VerifyPeerCertificate
+	return transport, nil
+}
+
+// buildURL returns the URL of the intake to post the payloads to.
+func buildURL(endpoint Endpoint) string {
+	scheme := "http"
+	if endpoint.UseSSL {
+		scheme = "https"
+	}
+	address := endpoint.Host
+	if endpoint.Port != 0 {
+		address = net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, address, httpPath)
+}
+
+// PayloadFormat returns the format of the payloads the intake expects, JSONPayloadFormat or ProtobufPayloadFormat.
+func (d *HTTPDestination) PayloadFormat() string {
+	if d.payloadFormat == "" {
+		return JSONPayloadFormat
+	}
+	return d.payloadFormat
+}
+
+// Send posts a payload to the intake, it keeps retrying on network errors
+// and server errors until the payload is accepted, the payload is rejected by the intake,
+// the maximum number of retries of the backoff policy is reached, the circuit breaker
+// of the destination opens or the destinations context is cancelled.
+// ErrForbidden is returned when the intake rejects the API key and ErrPayloadTooLarge when it rejects
+// the size of the payload, which is then to be split.
+func (d *HTTPDestination) Send(payload []byte) error {
+	return d.SendWithAPIKey(payload, "")
+}
+
+// SendWithAPIKey posts a payload to the intake like Send with apiKey in place of the API key of the endpoint,
+// unless it's empty.
+func (d *HTTPDestination) SendWithAPIKey(payload []byte, apiKey string) error {
+	if d.refusal != nil {
+		return d.refusal
+	}
+	ctx := d.destinationsContext.Context()
+
+	var retries, payloadFailures uint
+	for {
+		if retries > 0 {
+			duration := d.backoff.duration(retries)
+			metrics.SetDuration(&metrics.DestinationBackoff, d.host, duration)
+			metrics.DestinationRetries.Add(1)
+			backoff, cancel := context.WithTimeout(ctx, duration)
+			<-backoff.Done()
+			cancel()
+		}
+		retries++
+
+		err := d.send(ctx, payload, apiKey)
+		if err == nil {
+			status.RemoveGlobalWarning(statusConnectionError)
+			d.forbidden.clear()
+			d.tooLarge.clear()
+			metrics.SetDuration(&metrics.DestinationBackoff, d.host, 0)
+			if d.breaker != nil {
+				d.breaker.recordSuccess()
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			// the agent is stopping.
+			return ctx.Err()
+		}
+		retryableErr, retryable := err.(*RetryableError)
+		if !retryable {
+			return err
+		}
+		if retryableErr.payloadFailure {
+			payloadFailures++
+			if !d.backoff.shouldRetryPayload(payloadFailures) {
+				log.Warnf("Giving up on a payload the intake failed on %d times: %v", payloadFailures, err)
+				recordError(d.host, err)
+				return ErrRetryBudgetExhausted
+			}
+		}
+		status.AddGlobalWarning(statusConnectionError, fmt.Sprintf("Connection to the log intake cannot be established: %v", err))
+		log.Warnf("Could not send payload: %v", err)
+		recordError(d.host, err)
+		if !d.backoff.shouldRetry(retries) {
+			return err
+		}
+		if d.breaker != nil {
+			d.breaker.recordFailure()
+			if !d.breaker.allow() {
+				return errCircuitOpen
+			}
+		}
+		metrics.DestinationErrors.Add(1)
+	}
+}
+
+// send makes a single attempt at sending the payload.
+func (d *HTTPDestination) send(ctx context.Context, payload []byte, apiKey string) error {
+	body, encoding := payload, ""
+	if d.shouldCompress() {
+		compressed, err := compress(payload, d.compressionLevel)
+		if err != nil {
+			log.Warnf("Could not compress payload, sending it uncompressed: %v", err)
+		} else {
+			body, encoding = compressed, gzipContentEncoding
+		}
+	}
+
+	req, err := http.NewRequest("POST", d.url, bytes.NewReader(body))
+	if err != nil {
+		// the request can not be built because of a misconfiguration, there is nothing to retry.
+		return err
+	}
+	req = req.WithContext(ctx)
+	if apiKey == "" {
+		apiKey = d.apiKey.Get()
+	}
+	req.Header.Set("DD-API-KEY", apiKey)
+	if d.PayloadFormat() == ProtobufPayloadFormat {
+		req.Header.Set("Content-Type", httpProtoContentType)
+	} else {
+		req.Header.Set("Content-Type", httpContentType)
+	}
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return NewRetryableError(err)
+	}
+	defer resp.Body.Close()
+	// drain the body to let the transport reuse the connection.
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusUnsupportedMediaType && encoding != "":
+		// the intake does not support compressed payloads, fall back on uncompressed ones.
+		log.Warnf("Intake %v does not support %s compressed payloads, sending uncompressed payloads instead", d.host, encoding)
+		atomic.StoreInt32(&d.compressionUnsupported, 1)
+		return d.send(ctx, payload, apiKey)
+	case resp.StatusCode == http.StatusBadGateway, resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout,
+		resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusRequestTimeout:
+		// the intake is temporarily unable to handle the payload.
+		return NewRetryableError(fmt.Errorf("server error: %s", resp.Status))
+	case resp.StatusCode >= 500:
+		// the intake failed on the payload, which may fail again no matter how long it waits.
+		return &RetryableError{err: fmt.Errorf("server error: %s", resp.Status), payloadFailure: true}
+	case resp.StatusCode == http.StatusForbidden, resp.StatusCode == http.StatusUnauthorized:
+		// the payloads will be rejected until the API key is fixed, retrying would only delay the next ones.
+		d.forbidden.report(fmt.Sprintf("The log intake %v rejected the API key (%s): the logs are dropped until a valid API key is configured", d.host, resp.Status))
+		recordError(d.host, fmt.Errorf("API key rejected by the intake: %s", resp.Status))
+		return ErrForbidden
+	case resp.StatusCode == http.StatusRequestEntityTooLarge:
+		d.tooLarge.report(fmt.Sprintf("The log intake %v rejected a payload of %d bytes as too large (%s): the payloads are split and sent again", d.host, len(body), resp.Status))
+		recordError(d.host, fmt.Errorf("payload of %d bytes rejected by the intake: %s", len(body), resp.Status))
+		return ErrPayloadTooLarge
+	case resp.StatusCode >= 400:
+		log.Warnf("Payload was rejected by the intake: %s", resp.Status)
+		recordError(d.host, fmt.Errorf("payload was rejected by the intake: %s", resp.Status))
+		return errClient
+	}
+	metrics.SetDuration(&metrics.DestinationLatency, d.host, time.Since(start))
+	metrics.BytesSent.Add(int64(len(body)))
+	metrics.BatchesSent.Add(1)
+	return nil
+}
+
+// shouldCompress returns true if the payloads should be compressed.
+func (d *HTTPDestination) shouldCompress() bool {
+	return d.useCompression && atomic.LoadInt32(&d.compressionUnsupported) == 0
+}
+
+// Close closes the idle connections of the destination.
+func (d *HTTPDestination) Close() {
+	if transport, ok := d.client.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
+	}
+}
+
+// SendAsync sends a payload to the destination without blocking. If the channel is full, the incoming payloads will be
+// dropped
+func (d *HTTPDestination) SendAsync(payload []byte) {
+	d.once.Do(func() {
+		d.inputChan = make(chan []byte, chanSize)
+		d.breaker = newCircuitBreaker(d.host, d.breakerPolicy)
+		metrics.DestinationLogsDropped.Set(d.host, &expvar.Int{})
+		go d.runAsync()
+	})
+
+	if !d.breaker.allow() {
+		// the destination is down, do not hold on to payloads it can not receive.
+		metrics.DestinationLogsDropped.Add(d.host, 1)
+		return
+	}
+
+	select {
+	case d.inputChan <- payload:
+	default:
+		if metrics.DestinationLogsDropped.Get(d.host).(*expvar.Int).Value()%warningPeriod == 0 {
+			log.Warnf("Some logs sent to additional destination %v were dropped", d.host)
+		}
+		metrics.DestinationLogsDropped.Add(d.host, 1)
+	}
+}
+
+// runAsync reads the payloads from the channel and sends them
+func (d *HTTPDestination) runAsync() {
+	ctx := d.destinationsContext.Context()
+	for {
+		select {
+		case payload := <-d.inputChan:
+			if !d.breaker.allow() {
+				metrics.DestinationLogsDropped.Add(d.host, 1)
+				continue
+			}
+			if err := d.Send(payload); err == errCircuitOpen {
+				metrics.DestinationLogsDropped.Add(d.host, 1)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/status"
+)
+
+// newHTTPDestinationForServer returns a destination that posts payloads to server and a function to stop it.
+func newHTTPDestinationForServer(server *httptest.Server) (*HTTPDestination, func()) {
+	host, port := AddrToHostPort(server.Listener.Addr())
+	endpoint := Endpoint{
+		APIKey:  "secret",
+		Host:    host,
+		Port:    port,
+		Backoff: BackoffPolicy{Base: time.Millisecond, Max: 10 * time.Millisecond},
+	}
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+	destination, _ := NewHTTPDestination(endpoint, destinationsCtx)
+	return destination, destinationsCtx.Stop
+}
+
+func TestBuildURL(t *testing.T) {
+	assert.Equal(t, "https://foo:1234/v1/input", buildURL(Endpoint{Host: "foo", Port: 1234, UseSSL: true}))
+	assert.Equal(t, "http://foo/v1/input", buildURL(Endpoint{Host: "foo"}))
+}
+
+func TestHTTPDestinationSend(t *testing.T) {
+	var body, apiKey, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		body = string(content)
+		apiKey = r.Header.Get("DD-API-KEY")
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+
+	err := destination.Send([]byte(`[{"message":"foo"}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"message":"foo"}]`, body)
+	assert.Equal(t, "secret", apiKey)
+	assert.Equal(t, "application/json", contentType)
+}
+
+func TestHTTPDestinationSendProtobufPayloads(t *testing.T) {
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+	assert.Equal(t, JSONPayloadFormat, destination.PayloadFormat())
+	destination.payloadFormat = ProtobufPayloadFormat
+
+	err := destination.Send([]byte{0x0a, 0x00})
+	assert.Nil(t, err)
+	assert.Equal(t, ProtobufPayloadFormat, destination.PayloadFormat())
+	assert.Equal(t, "application/x-protobuf", contentType)
+}
+
+func TestHTTPDestinationSendWithRotatedAPIKey(t *testing.T) {
+	apiKeys := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeys <- r.Header.Get("DD-API-KEY")
+	}))
+	defer server.Close()
+
+	host, port := AddrToHostPort(server.Listener.Addr())
+	holder := NewAPIKeyHolder("secret")
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+	destination, err := NewHTTPDestination(Endpoint{APIKey: "secret", APIKeyHolder: holder, Host: host, Port: port}, destinationsCtx)
+	assert.Nil(t, err)
+
+	assert.Nil(t, destination.Send([]byte(`[{"message":"foo"}]`)))
+	holder.Set("rotated")
+	assert.Nil(t, destination.Send([]byte(`[{"message":"foo"}]`)))
+	assert.Equal(t, "secret", <-apiKeys)
+	assert.Equal(t, "rotated", <-apiKeys)
+}
+
+func TestHTTPDestinationRetriesOnServerErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+
+	retries, batches := metrics.DestinationRetries.Value(), metrics.BatchesSent.Value()
+	err := destination.Send([]byte("[]"))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	assert.Equal(t, retries+2, metrics.DestinationRetries.Value())
+	assert.Equal(t, batches+1, metrics.BatchesSent.Value())
+	assert.Equal(t, "0", metrics.DestinationBackoff.Get(destination.host).String())
+}
+
+func TestHTTPDestinationDoesNotRetryOnClientErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+
+	err := destination.Send([]byte("[]"))
+	assert.Equal(t, errClient, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestHTTPDestinationGivesUpOnThePayloadsItKeepsFailingOn(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+	destination.backoff.MaxPayloadRetries = 2
+
+	err := destination.Send([]byte("[]"))
+	assert.Equal(t, ErrRetryBudgetExhausted, err)
+	assert.True(t, IsPoisonPayload(err))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestHTTPDestinationSurfacesTheRejectedAPIKey(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	status.Init(new(int32), config.NewLogSources())
+	defer status.Clear()
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+
+	err := destination.Send([]byte("[]"))
+	assert.Equal(t, ErrForbidden, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	warnings := status.Get().Warnings
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "rejected the API key")
+}
+
+func TestHTTPDestinationSurfacesThePayloadsTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	}))
+	defer server.Close()
+
+	status.Init(new(int32), config.NewLogSources())
+	defer status.Clear()
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+
+	err := destination.Send([]byte("[]"))
+	assert.Equal(t, ErrPayloadTooLarge, err)
+	warnings := status.Get().Warnings
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "rejected a payload of 2 bytes as too large")
+}
+
+func TestHTTPDestinationReturnsWhenContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	destination, stop := newHTTPDestinationForServer(server)
+
+	done := make(chan error)
+	go func() {
+		done <- destination.Send([]byte("[]"))
+	}()
+	stop()
+
+	select {
+	case err := <-done:
+		assert.True(t, strings.Contains(err.Error(), "context canceled"))
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "Send should return when the context is cancelled")
+	}
+}
+
+func TestHTTPDestinationCompressesPayloads(t *testing.T) {
+	var body []byte
+	var encoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		encoding = r.Header.Get("Content-Encoding")
+	}))
+	defer server.Close()
+
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+	destination.useCompression = true
+
+	err := destination.Send([]byte(`[{"message":"foo"}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, "gzip", encoding)
+	assert.Equal(t, `[{"message":"foo"}]`, decompress(t, body))
+}
+
+func TestHTTPDestinationFallsBackOnUncompressedPayloads(t *testing.T) {
+	var requests int32
+	var body, encoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Content-Encoding") != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		content, _ := ioutil.ReadAll(r.Body)
+		body = string(content)
+		encoding = r.Header.Get("Content-Encoding")
+	}))
+	defer server.Close()
+
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+	destination.useCompression = true
+
+	err := destination.Send([]byte(`[{"message":"foo"}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, `[{"message":"foo"}]`, body)
+	assert.Equal(t, "", encoding)
+	assert.False(t, destination.shouldCompress())
+}
+
+func TestHTTPDestinationSendStopsRetryingWhenCircuitBreakerOpens(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	destination, stop := newHTTPDestinationForServer(server)
+	defer stop()
+	destination.breaker = newCircuitBreaker(destination.host, CircuitBreakerPolicy{Threshold: 3, Cooldown: time.Hour})
+
+	err := destination.Send([]byte("[]"))
+	assert.Equal(t, errCircuitOpen, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	assert.False(t, destination.breaker.allow())
+}
+
+func TestNewHTTPTransportWithFIPS(t *testing.T) {
+	transport, err := newHTTPTransport(Endpoint{Host: "foo", UseSSL: true, FIPS: true})
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), transport.TLSClientConfig.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS12), transport.TLSClientConfig.MaxVersion)
+	assert.Equal(t, fipsCipherSuites, transport.TLSClientConfig.CipherSuites)
+	assert.Equal(t, fipsCurves, transport.TLSClientConfig.CurvePreferences)
+}
+
+func TestNewHTTPDestinationFailsWithoutItsTLSConfiguration(t *testing.T) {
+	endpoint := Endpoint{Host: "foo", UseSSL: true, CAFile: "/does/not/exist.pem"}
+	destination, err := NewHTTPDestination(endpoint, NewDestinationsContext())
+	assert.Nil(t, destination)
+	assert.Error(t, err)
+
+	// the payloads are not posted without the TLS configuration.
+	destination = NewRefusingHTTPDestination(endpoint, err)
+	assert.Equal(t, err, destination.Send([]byte("[]")))
+	destination.Close()
+}
@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// dialHTTPProxy opens a TCP tunnel to the intake through the HTTP proxy of the endpoint
+// using the CONNECT method, the connection to the proxy is secured when the proxy URL uses https.
+func (cm *ConnectionManager) dialHTTPProxy(ctx context.Context) (net.Conn, error) {
+	proxyURL, err := url.Parse(cm.endpoint.HTTPProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the proxy URL: %v", err)
+	}
+
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := cm.netDialer().DialContext(ctx, cm.network(), proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		sslConn := tls.Client(conn, &tls.Config{
+			ServerName: proxyURL.Hostname(),
+		})
+		if err = cm.handshake(ctx, sslConn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = sslConn
+	}
+
+	if err = cm.connectTunnel(ctx, conn, proxyURL); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connectTunnel asks the proxy to open a tunnel to the intake on conn and waits for its answer.
+func (cm *ConnectionManager) connectTunnel(ctx context.Context, conn net.Conn, proxyURL *url.URL) error {
+	// make sure we do not wait for the proxy once ctx is done.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: cm.address()},
+		Host:   cm.address(),
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused to connect to %v: %s", cm.address(), resp.Status)
+	}
+	if reader.Buffered() > 0 {
+		// the intake never speaks first, anything left is unexpected.
+		return fmt.Errorf("unexpected data received from the proxy after connecting to %v", cm.address())
+	}
+	return nil
+}
+
+// sanitizeProxyURL hides the credentials of the proxy URL to log it.
+func sanitizeProxyURL(rawURL string) string {
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if proxyURL.User != nil {
+		return fmt.Sprintf("%s://*****@%s", proxyURL.Scheme, proxyURL.Host)
+	}
+	return proxyURL.String()
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/status"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// intakeErrorLogInterval is the minimum time between two logs of the same error of the intake,
+// the occurrences in between are counted and mentioned in the next log.
+const intakeErrorLogInterval = time.Minute
+
+var (
+	// ErrForbidden is returned when the intake rejects the API key of a payload,
+	// the payloads are not retried since they would be rejected until the API key is fixed.
+	ErrForbidden = errors.New("forbidden, the API key is invalid")
+	// ErrPayloadTooLarge is returned when the intake rejects a payload because it's too large,
+	// the payload must be split to be accepted.
+	ErrPayloadTooLarge = errors.New("payload too large")
+)
+
+// intakeErrorReporter surfaces an error of the intake of a destination on the status page and in the agent logs,
+// the error is logged at most once per intakeErrorLogInterval and the warning is displayed at least for expiration
+// after the last occurrence of the error.
+type intakeErrorReporter struct {
+	warningKey string
+	expiration time.Duration
+	now        func() time.Time
+	mu         sync.Mutex
+	lastLog    time.Time
+	lastError  time.Time
+	suppressed int
+}
+
+// newIntakeErrorReporter returns a reporter of the errors of the intake of host identified by kind.
+func newIntakeErrorReporter(kind, host string, expiration time.Duration) *intakeErrorReporter {
+	return &intakeErrorReporter{
+		warningKey: kind + ":" + host,
+		expiration: expiration,
+		now:        time.Now,
+	}
+}
+
+// report displays warning on the status page and logs it unless it was logged less than intakeErrorLogInterval ago.
+func (r *intakeErrorReporter) report(warning string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.lastError = now
+	status.AddGlobalWarning(r.warningKey, warning)
+	if now.Sub(r.lastLog) < intakeErrorLogInterval {
+		r.suppressed++
+		return
+	}
+	if r.suppressed > 0 {
+		warning = fmt.Sprintf("%s (%d similar errors in the last %v)", warning, r.suppressed, now.Sub(r.lastLog).Round(time.Second))
+	}
+	log.Error(warning)
+	r.lastLog = now
+	r.suppressed = 0
+}
+
+// clear removes the warning from the status page if expiration passed since the last error.
+func (r *intakeErrorReporter) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lastError.IsZero() || r.now().Sub(r.lastError) < r.expiration {
+		return
+	}
+	r.lastError = time.Time{}
+	status.RemoveGlobalWarning(r.warningKey)
+}
@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/status"
+)
+
+func TestIntakeErrorReporterRateLimitsTheLogs(t *testing.T) {
+	now := time.Now()
+	reporter := newIntakeErrorReporter("test", "localhost", 0)
+	reporter.now = func() time.Time { return now }
+
+	reporter.report("foo")
+	assert.Equal(t, now, reporter.lastLog)
+	now = now.Add(time.Second)
+	reporter.report("foo")
+	reporter.report("foo")
+	assert.Equal(t, 2, reporter.suppressed)
+	now = now.Add(intakeErrorLogInterval)
+	reporter.report("foo")
+	assert.Equal(t, now, reporter.lastLog)
+	assert.Equal(t, 0, reporter.suppressed)
+}
+
+func TestIntakeErrorReporterClearsTheWarningAfterExpiration(t *testing.T) {
+	status.Init(new(int32), config.NewLogSources())
+	defer status.Clear()
+
+	now := time.Now()
+	reporter := newIntakeErrorReporter("test", "localhost", time.Minute)
+	reporter.now = func() time.Time { return now }
+
+	reporter.report("foo")
+	assert.Equal(t, []string{"foo"}, status.Get().Warnings)
+	reporter.clear()
+	assert.Equal(t, []string{"foo"}, status.Get().Warnings)
+	now = now.Add(time.Minute)
+	reporter.clear()
+	assert.Len(t, status.Get().Warnings, 0)
+}
@@ -17,7 +17,8 @@ func newPrefixer(prefix string) *prefixer {
 	}
 }
 
-// apply prepends the prefix to the message.
+// apply prepends the prefix to the message, the result never shares the memory of the prefix
+// so that several prefixed messages can be held at once.
 func (p *prefixer) apply(content []byte) []byte {
-	return append(p.prefix, content...)
+	return append(p.prefix[:len(p.prefix):len(p.prefix)], content...)
 }
@@ -17,3 +17,11 @@ func TestPrefixer(t *testing.T) {
 	assert.Equal(t, []byte("foo bar"), prefixer.apply([]byte("bar")))
 
 }
+
+func TestPrefixerDoesNotShareThePrefix(t *testing.T) {
+	prefixer := newPrefixer("foo ")
+	bar := prefixer.apply([]byte("bar"))
+	baz := prefixer.apply([]byte("baz"))
+	assert.Equal(t, []byte("foo bar"), bar)
+	assert.Equal(t, []byte("foo baz"), baz)
+}
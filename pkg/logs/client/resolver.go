@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// resolver keeps track of the addresses the host of an endpoint resolves to,
+// so that long-lived connections to an address removed from the DNS records can be rotated.
+// A resolver is not thread safe.
+type resolver struct {
+	interval   time.Duration
+	timeout    time.Duration
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	host       string
+	addrs      []net.IP
+	resolvedAt time.Time
+}
+
+// newResolver returns a resolver refreshing the addresses of a host every interval,
+// a zero interval disables the resolution.
+func newResolver(interval, timeout time.Duration) *resolver {
+	return &resolver{
+		interval:   interval,
+		timeout:    timeout,
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+// isStale returns true if conn is connected to an address host does not resolve to anymore,
+// host is resolved again at most once per interval.
+func (r *resolver) isStale(ctx context.Context, host string, conn net.Conn, now time.Time) bool {
+	if r.interval <= 0 {
+		return false
+	}
+	if host != r.host || now.Sub(r.resolvedAt) > r.interval {
+		r.resolve(ctx, host, now)
+	}
+	if len(r.addrs) == 0 {
+		// keep the connections as long as the host has not been resolved.
+		return false
+	}
+	remote, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, addr := range r.addrs {
+		if addr.Equal(remote.IP) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve refreshes the addresses of host, the previous ones are kept if the lookup fails.
+func (r *resolver) resolve(ctx context.Context, host string, now time.Time) {
+	if host != r.host {
+		r.host = host
+		r.addrs = nil
+	}
+	r.resolvedAt = now
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	hosts, err := r.lookupHost(ctx, host)
+	if err != nil {
+		log.Debugf("Could not resolve %v: %v", host, err)
+		return
+	}
+	addrs := make([]net.IP, 0, len(hosts))
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			addrs = append(addrs, ip)
+		}
+	}
+	r.addrs = addrs
+}
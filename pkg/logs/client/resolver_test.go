@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// remoteConn is a connection to a fixed remote address.
+type remoteConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *remoteConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func newRemoteConn(ip string) net.Conn {
+	return &remoteConn{remote: &net.TCPAddr{IP: net.ParseIP(ip), Port: 10516}}
+}
+
+func TestResolverDisabled(t *testing.T) {
+	r := newResolver(0, time.Second)
+	r.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		t.Fatal("the host should not be resolved")
+		return nil, nil
+	}
+	assert.False(t, r.isStale(context.Background(), "foo", newRemoteConn("10.0.0.1"), time.Now()))
+}
+
+func TestResolverIsStale(t *testing.T) {
+	var lookups int
+	addrs := []string{"10.0.0.1", "10.0.0.2"}
+	r := newResolver(time.Minute, time.Second)
+	r.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		assert.Equal(t, "foo", host)
+		return addrs, nil
+	}
+
+	now := time.Now()
+	assert.False(t, r.isStale(context.Background(), "foo", newRemoteConn("10.0.0.2"), now))
+	assert.True(t, r.isStale(context.Background(), "foo", newRemoteConn("10.0.0.3"), now))
+	assert.Equal(t, 1, lookups)
+
+	// the addresses are not refreshed before the interval elapsed.
+	addrs = []string{"10.0.0.3"}
+	assert.False(t, r.isStale(context.Background(), "foo", newRemoteConn("10.0.0.2"), now.Add(30*time.Second)))
+	assert.Equal(t, 1, lookups)
+
+	assert.True(t, r.isStale(context.Background(), "foo", newRemoteConn("10.0.0.2"), now.Add(2*time.Minute)))
+	assert.False(t, r.isStale(context.Background(), "foo", newRemoteConn("10.0.0.3"), now.Add(2*time.Minute)))
+	assert.Equal(t, 2, lookups)
+}
+
+func TestResolverKeepsAddressesOnLookupFailure(t *testing.T) {
+	var err error
+	r := newResolver(time.Minute, time.Second)
+	r.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"10.0.0.1"}, err
+	}
+
+	now := time.Now()
+	assert.True(t, r.isStale(context.Background(), "foo", newRemoteConn("10.0.0.2"), now))
+
+	err = errors.New("no such host")
+	assert.True(t, r.isStale(context.Background(), "foo", newRemoteConn("10.0.0.2"), now.Add(2*time.Minute)))
+
+	// connections are kept when the host has never been resolved.
+	assert.False(t, r.isStale(context.Background(), "bar", newRemoteConn("10.0.0.2"), now.Add(2*time.Minute)))
+}
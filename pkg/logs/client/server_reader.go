@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"bufio"
+	"net"
+	"strings"
+)
+
+// ServerError is returned when the intake closed the connection a frame was about to be sent on,
+// the frame can be sent again on a new connection.
+type ServerError struct {
+	// message is the last line sent by the intake before closing the connection, if any.
+	message string
+	err     error
+}
+
+// Error returns the message of the error.
+func (e *ServerError) Error() string {
+	switch {
+	case e.message != "":
+		return "connection closed by the intake: " + e.message
+	case e.err != nil:
+		return "connection to the intake lost: " + e.err.Error()
+	default:
+		return "connection closed by the intake"
+	}
+}
+
+// serverReader reads what the intake sends back on a connection. The intake does not acknowledge
+// the frames, it only writes a line describing the error that makes it close the connection,
+// the closure is reported on done along with that line.
+type serverReader struct {
+	done chan *ServerError
+}
+
+// newServerReader starts reading conn until it's closed by either side.
+func newServerReader(conn net.Conn) *serverReader {
+	r := &serverReader{
+		done: make(chan *ServerError, 1),
+	}
+	go r.run(conn)
+	return r
+}
+
+// run reads the lines sent by the intake until the connection is closed.
+func (r *serverReader) run(conn net.Conn) {
+	var message string
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			message = line
+		}
+	}
+	// the error is nil when the intake closed the connection cleanly.
+	r.done <- &ServerError{message: message, err: scanner.Err()}
+}
+
+// closed returns the reason why the connection was closed, or nil if it's still open.
+func (r *serverReader) closed() *ServerError {
+	select {
+	case err := <-r.done:
+		return err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerReaderReportsTheLastMessageOfTheIntake(t *testing.T) {
+	conn, server := net.Pipe()
+	defer conn.Close()
+	reader := newServerReader(conn)
+	assert.Nil(t, reader.closed())
+
+	server.Write([]byte("rate limited\n"))
+	server.Write([]byte("invalid api key\n\n"))
+	server.Close()
+
+	select {
+	case err := <-reader.done:
+		assert.Equal(t, "connection closed by the intake: invalid api key", err.Error())
+	case <-time.After(time.Second):
+		assert.Fail(t, "closure was not reported")
+	}
+}
+
+func TestServerErrorMessages(t *testing.T) {
+	assert.Equal(t, "connection closed by the intake", (&ServerError{}).Error())
+	assert.Equal(t, "connection to the intake lost: reset", (&ServerError{err: errors.New("reset")}).Error())
+}
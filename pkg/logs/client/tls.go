@@ -0,0 +1,211 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	logsConfig "github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// errPinMismatch is returned when none of the certificates presented by the intake matches a pin.
+var errPinMismatch = errors.New("none of the certificates presented by the intake matches the pinned public keys")
+
+// versionTLS13 is tls.VersionTLS13, which is not defined by all the Go versions the agent is built with.
+const versionTLS13 = 0x0304
+
+// tlsVersions are the TLS versions the connections can be restricted to.
+var tlsVersions = map[string]uint16{
+	"tlsv1.0": tls.VersionTLS10,
+	"tlsv1.1": tls.VersionTLS11,
+	"tlsv1.2": tls.VersionTLS12,
+	"tlsv1.3": versionTLS13,
+}
+
+// cipherSuites are the cipher suites the connections can be restricted to,
+// the TLS 1.3 cipher suites are not configurable.
+var cipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_RC4_128_SHA":                tls.TLS_RSA_WITH_RC4_128_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA256":         tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":        tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":          tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":     tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":    tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+}
+
+// fipsCipherSuites are the FIPS-approved cipher suites, the connections are restricted to in FIPS mode.
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// fipsCurves are the FIPS-approved elliptic curves.
+var fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// NewTLSConfig returns the TLS configuration to use to connect to the endpoint,
+// the CA bundle and the client certificate are loaded from the files of the endpoint when set.
+func NewTLSConfig(endpoint Endpoint) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: endpoint.Host,
+	}
+
+	if endpoint.MinTLSVersion != "" {
+		version, exists := tlsVersions[strings.ToLower(endpoint.MinTLSVersion)]
+		if !exists {
+			return nil, fmt.Errorf("unsupported TLS version: %s", endpoint.MinTLSVersion)
+		}
+		config.MinVersion = version
+	}
+
+	for _, name := range endpoint.CipherSuites {
+		suite, exists := cipherSuites[strings.ToUpper(name)]
+		if !exists {
+			return nil, fmt.Errorf("unsupported cipher suite: %s", name)
+		}
+		config.CipherSuites = append(config.CipherSuites, suite)
+	}
+
+	if endpoint.FIPS {
+		if err := restrictToFIPS(config); err != nil {
+			return nil, err
+		}
+	}
+
+	if endpoint.CAFile != "" {
+		pem, err := ioutil.ReadFile(endpoint.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("could not find any certificate in CA file %s", endpoint.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if endpoint.CertFile != "" || endpoint.KeyFile != "" {
+		if endpoint.CertFile == "" || endpoint.KeyFile == "" {
+			return nil, fmt.Errorf("both a certificate file and a key file must be set to use a client certificate")
+		}
+		cert, err := logsConfig.LoadX509KeyPair(endpoint.CertFile, endpoint.KeyFile, endpoint.KeyPassphrase, "datadog.yaml")
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(endpoint.PinnedPublicKeys) > 0 {
+		pins, err := decodePins(endpoint.PinnedPublicKeys)
+		if err != nil {
+			return nil, err
+		}
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return verifyPins(pins, rawCerts, verifiedChains)
+		}
+	}
+
+	return config, nil
+}
+
+// restrictToFIPS restricts the TLS versions, cipher suites and curves of config to the FIPS-approved ones,
+// TLS 1.3 is disabled as its cipher suites can't be restricted, an error is returned if the versions or
+// the cipher suites set are not approved.
+func restrictToFIPS(config *tls.Config) error {
+	if config.MinVersion != 0 && config.MinVersion < tls.VersionTLS12 {
+		return fmt.Errorf("TLS versions below 1.2 are not FIPS-approved")
+	}
+	config.MinVersion = tls.VersionTLS12
+	config.MaxVersion = tls.VersionTLS12
+	if len(config.CipherSuites) == 0 {
+		config.CipherSuites = fipsCipherSuites
+	}
+	for _, suite := range config.CipherSuites {
+		if !isFIPSCipherSuite(suite) {
+			return fmt.Errorf("cipher suite %s is not FIPS-approved", cipherSuiteName(suite))
+		}
+	}
+	config.CurvePreferences = fipsCurves
+	return nil
+}
+
+// isFIPSCipherSuite returns true if suite is FIPS-approved.
+func isFIPSCipherSuite(suite uint16) bool {
+	for _, approved := range fipsCipherSuites {
+		if suite == approved {
+			return true
+		}
+	}
+	return false
+}
+
+// decodePins decodes the base64 encoded SHA-256 digests of the pinned public keys.
+func decodePins(encoded []string) ([][]byte, error) {
+	pins := make([][]byte, 0, len(encoded))
+	for _, pin := range encoded {
+		digest, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("invalid public key pin %s, it must be the base64 encoded SHA-256 digest of a public key", pin)
+		}
+		pins = append(pins, digest)
+	}
+	return pins, nil
+}
+
+// verifyPins returns an error if none of the certificates of the chains presented by the intake
+// has a public key whose SHA-256 digest is pinned, the certificates are checked as presented
+// when they were not verified, e.g. when the SSL validation is skipped.
+func verifyPins(pins [][]byte, rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	var certs []*x509.Certificate
+	for _, chain := range verifiedChains {
+		certs = append(certs, chain...)
+	}
+	if len(verifiedChains) == 0 {
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+	}
+	for _, cert := range certs {
+		digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if bytes.Equal(digest[:], pin) {
+				return nil
+			}
+		}
+	}
+	return errPinMismatch
+}
@@ -0,0 +1,295 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCertificate generates a self-signed certificate valid for 127.0.0.1
+// and writes it along with its private key in dir.
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs-tls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir)
+
+	config, err := NewTLSConfig(Endpoint{Host: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", config.ServerName)
+	assert.Nil(t, config.RootCAs)
+	assert.Empty(t, config.Certificates)
+
+	config, err = NewTLSConfig(Endpoint{Host: "foo", CAFile: certFile, CertFile: certFile, KeyFile: keyFile})
+	assert.NoError(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+
+	_, err = NewTLSConfig(Endpoint{Host: "foo", CAFile: filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig(Endpoint{Host: "foo", CAFile: keyFile})
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig(Endpoint{Host: "foo", CertFile: certFile})
+	assert.Error(t, err)
+}
+
+func TestNewTLSConfigRestrictions(t *testing.T) {
+	config, err := NewTLSConfig(Endpoint{Host: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0), config.MinVersion)
+	assert.Nil(t, config.CipherSuites)
+
+	config, err = NewTLSConfig(Endpoint{Host: "foo", MinTLSVersion: "TLSv1.2", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "tls_ecdhe_ecdsa_with_aes_256_gcm_sha384"}})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}, config.CipherSuites)
+
+	_, err = NewTLSConfig(Endpoint{Host: "foo", MinTLSVersion: "sslv3"})
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig(Endpoint{Host: "foo", CipherSuites: []string{"TLS_FOO"}})
+	assert.Error(t, err)
+}
+
+func TestNewTLSConfigWithFIPS(t *testing.T) {
+	config, err := NewTLSConfig(Endpoint{Host: "foo", FIPS: true})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MaxVersion)
+	assert.Equal(t, fipsCipherSuites, config.CipherSuites)
+	assert.Equal(t, fipsCurves, config.CurvePreferences)
+
+	config, err = NewTLSConfig(Endpoint{Host: "foo", FIPS: true, CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, config.CipherSuites)
+
+	_, err = NewTLSConfig(Endpoint{Host: "foo", FIPS: true, CipherSuites: []string{"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305"}})
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig(Endpoint{Host: "foo", FIPS: true, MinTLSVersion: "TLSv1.1"})
+	assert.Error(t, err)
+}
+
+func TestNewConnectionWithMinTLSVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs-tls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir)
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MaxVersion:   tls.VersionTLS12,
+	})
+	assert.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go conn.(*tls.Conn).Handshake()
+		}
+	}()
+
+	host, port := AddrToHostPort(l.Addr())
+	endpoint := Endpoint{
+		Host:    host,
+		Port:    port,
+		UseSSL:  true,
+		CAFile:  certFile,
+		Backoff: BackoffPolicy{MaxRetries: 1},
+	}
+	conn, err := NewConnectionManager(endpoint).NewConnection(context.Background())
+	assert.NoError(t, err)
+	conn.Close()
+
+	// the server does not support the minimum version.
+	endpoint.MinTLSVersion = "tlsv1.3"
+	_, err = NewConnectionManager(endpoint).NewConnection(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNewConnectionWithClientCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs-tls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir)
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+	pool := x509.NewCertPool()
+	caPEM, err := ioutil.ReadFile(certFile)
+	assert.NoError(t, err)
+	pool.AppendCertsFromPEM(caPEM)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+	assert.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go conn.(*tls.Conn).Handshake()
+		}
+	}()
+
+	host, port := AddrToHostPort(l.Addr())
+	endpoint := Endpoint{
+		Host:     host,
+		Port:     port,
+		UseSSL:   true,
+		CAFile:   certFile,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		Backoff:  BackoffPolicy{MaxRetries: 1},
+	}
+	conn, err := NewConnectionManager(endpoint).NewConnection(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+	conn.Close()
+
+	// the server can not be verified without the CA bundle.
+	endpoint.CAFile = ""
+	_, err = NewConnectionManager(endpoint).NewConnection(context.Background())
+	assert.Error(t, err)
+}
+
+// pinOf returns the pin of the public key of the certificate in certFile.
+func pinOf(t *testing.T, certFile, keyFile string) string {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.NoError(t, err)
+	digest := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func TestNewTLSConfigWithInvalidPins(t *testing.T) {
+	_, err := NewTLSConfig(Endpoint{Host: "foo", PinnedPublicKeys: []string{"not base64"}})
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig(Endpoint{Host: "foo", PinnedPublicKeys: []string{base64.StdEncoding.EncodeToString([]byte("too short"))}})
+	assert.Error(t, err)
+}
+
+func TestVerifyPins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs-tls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+	pin := pinOf(t, certFile, keyFile)
+
+	pins, err := decodePins([]string{pin})
+	assert.NoError(t, err)
+	otherPins, err := decodePins([]string{base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))})
+	assert.NoError(t, err)
+
+	// the presented certificates are checked when the chain was not verified.
+	assert.NoError(t, verifyPins(pins, cert.Certificate, nil))
+	assert.Equal(t, errPinMismatch, verifyPins(otherPins, cert.Certificate, nil))
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.NoError(t, err)
+	assert.NoError(t, verifyPins(pins, nil, [][]*x509.Certificate{{leaf}}))
+	assert.Equal(t, errPinMismatch, verifyPins(otherPins, nil, [][]*x509.Certificate{{leaf}}))
+}
+
+func TestNewConnectionWithPinnedPublicKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs-tls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir)
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	assert.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go conn.(*tls.Conn).Handshake()
+		}
+	}()
+
+	host, port := AddrToHostPort(l.Addr())
+	endpoint := Endpoint{
+		Host:             host,
+		Port:             port,
+		UseSSL:           true,
+		CAFile:           certFile,
+		PinnedPublicKeys: []string{pinOf(t, certFile, keyFile)},
+		Backoff:          BackoffPolicy{MaxRetries: 1},
+	}
+	conn, err := NewConnectionManager(endpoint).NewConnection(context.Background())
+	assert.NoError(t, err)
+	conn.Close()
+
+	// a trusted certificate is rejected if its public key is not pinned.
+	endpoint.PinnedPublicKeys = []string{base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))}
+	_, err = NewConnectionManager(endpoint).NewConnection(context.Background())
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ package config
 
 import (
 	"encoding/json"
+	"os"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 )
@@ -14,6 +15,12 @@ import (
 // ContainerCollectAll is the name of the docker integration that collect logs from all containers
 const ContainerCollectAll = "container_collect_all"
 
+// LambdaExtension is the name of the source collecting the logs of the function when running as an AWS Lambda extension
+const LambdaExtension = "lambda_extension"
+
+// KubernetesEvents is the name of the source collecting the events of the kubernetes cluster
+const KubernetesEvents = "kubernetes_events"
+
 // DefaultSources returns the default log sources that can be directly set from the datadog.yaml or through environment variables.
 func DefaultSources() []*LogSource {
 	var sources []*LogSource
@@ -28,6 +35,27 @@ func DefaultSources() []*LogSource {
 		sources = append(sources, source)
 	}
 
+	if coreConfig.Datadog.GetBool("logs_config.lambda_extension") {
+		// append a new source to collect the logs of the function through the Telemetry API
+		source := NewLogSource(LambdaExtension, &LogsConfig{
+			Type:    LambdaType,
+			Port:    coreConfig.Datadog.GetInt("logs_config.lambda_telemetry_port"),
+			Service: os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+			Source:  "lambda",
+		})
+		sources = append(sources, source)
+	}
+
+	if coreConfig.Datadog.GetBool("logs_config.kubernetes_events") {
+		// append a new source to collect the events of the kubernetes cluster from the apiserver
+		source := NewLogSource(KubernetesEvents, &LogsConfig{
+			Type:    KubernetesEventsType,
+			Service: "kubernetes",
+			Source:  "kubernetes",
+		})
+		sources = append(sources, source)
+	}
+
 	return sources
 }
 
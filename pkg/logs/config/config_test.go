@@ -6,6 +6,7 @@
 package config
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -28,7 +29,7 @@ func (suite *ConfigTestSuite) TestDefaultDatadogConfig() {
 	suite.Equal("", suite.config.GetString("logs_config.dd_url"))
 	suite.Equal(10516, suite.config.GetInt("logs_config.dd_port"))
 	suite.Equal(false, suite.config.GetBool("logs_config.dev_mode_no_ssl"))
-	suite.Equal("agent-443-intake.logs.datadoghq.com", suite.config.GetString("logs_config.dd_url_443"))
+	suite.Equal("", suite.config.GetString("logs_config.dd_url_443"))
 	suite.Equal(false, suite.config.GetBool("logs_config.use_port_443"))
 	suite.Equal(true, suite.config.GetBool("logs_config.dev_mode_use_proto"))
 	suite.Equal(100, suite.config.GetInt("logs_config.open_files_limit"))
@@ -57,6 +58,37 @@ func (suite *ConfigTestSuite) TestDefaultSources() {
 	suite.Equal("docker", source.Config.Service)
 }
 
+func (suite *ConfigTestSuite) TestDefaultSourcesWithLambdaExtension() {
+	suite.config.Set("logs_config.lambda_extension", true)
+	os.Setenv("AWS_LAMBDA_FUNCTION_NAME", "my-function")
+	defer os.Unsetenv("AWS_LAMBDA_FUNCTION_NAME")
+
+	sources := DefaultSources()
+	suite.Equal(1, len(sources))
+
+	source := sources[0]
+	suite.Equal("lambda_extension", source.Name)
+	suite.Equal(LambdaType, source.Config.Type)
+	suite.Equal(8124, source.Config.Port)
+	suite.Equal("lambda", source.Config.Source)
+	suite.Equal("my-function", source.Config.Service)
+	suite.Nil(source.Config.Validate())
+}
+
+func (suite *ConfigTestSuite) TestDefaultSourcesWithKubernetesEvents() {
+	suite.config.Set("logs_config.kubernetes_events", true)
+
+	sources := DefaultSources()
+	suite.Equal(1, len(sources))
+
+	source := sources[0]
+	suite.Equal("kubernetes_events", source.Name)
+	suite.Equal(KubernetesEventsType, source.Config.Type)
+	suite.Equal("kubernetes", source.Config.Source)
+	suite.Equal("kubernetes", source.Config.Service)
+	suite.Nil(source.Config.Validate())
+}
+
 func (suite *ConfigTestSuite) TestGlobalProcessingRulesShouldReturnNoRulesWithEmptyValues() {
 	var (
 		rules []*ProcessingRule
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Types the attributes extracted by a grok_parser rule can be converted to.
+const (
+	CaptureString = "string"
+	CaptureInt    = "int"
+	CaptureFloat  = "float"
+)
+
+const (
+	// maxGrokDepth is the maximum depth of the grok patterns referring to other patterns.
+	maxGrokDepth = 10
+	// grokGroupPrefix prefixes the names of the groups capturing the named references.
+	grokGroupPrefix = "grok"
+)
+
+// Capture is an attribute extracted by a grok_parser rule.
+type Capture struct {
+	Name string
+	Type string
+}
+
+// grokReference matches a %{PATTERN}, %{PATTERN:name} or %{PATTERN:name:type} reference of a grok pattern.
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([\w.@-]+))?(?::(\w+))?\}`)
+
+// grokPatterns are the patterns the grok_parser rules can refer to by name.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"USER":              `[\w.@-]+`,
+	"IPV4":              `(?:\d{1,3}\.){3}\d{1,3}`,
+	"IPV6":              `[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}(?:%\w+)?`,
+	"IP":                `%{IPV6}|%{IPV4}`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":          `%{IP}|%{HOSTNAME}`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"URIPATHPARAM":      `/[^\s?#]*(?:\?[^\s#]*)?`,
+	"HTTPDATE":          `\d{2}/[A-Za-z]{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|alert|emerg(?:ency)?)`,
+}
+
+// compileGrok returns the regular expression matching whole lines described by a grok pattern,
+// along with the attributes extracted by each of its capturing groups.
+// The named groups of the regular expressions the pattern is made of are extracted as strings too.
+func compileGrok(pattern string) (*regexp.Regexp, []Capture, error) {
+	var references []Capture
+	expanded, err := expandGrok(pattern, 0, &references)
+	if err != nil {
+		return nil, nil, err
+	}
+	re, err := regexp.Compile("^(?:" + expanded + ")$")
+	if err != nil {
+		return nil, nil, err
+	}
+	captures := make([]Capture, len(re.SubexpNames()))
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		captures[i] = Capture{Name: name, Type: CaptureString}
+		if strings.HasPrefix(name, grokGroupPrefix) {
+			if index, err := strconv.Atoi(strings.TrimPrefix(name, grokGroupPrefix)); err == nil && index < len(references) {
+				captures[i] = references[index]
+			}
+		}
+	}
+	return re, captures, nil
+}
+
+// expandGrok replaces the references of pattern by the patterns they refer to, the named references are
+// replaced by groups named grok<n> where n is their index in references.
+func expandGrok(pattern string, depth int, references *[]Capture) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok patterns are nested too deeply")
+	}
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(reference string) string {
+		parts := grokReference.FindStringSubmatch(reference)
+		definition, exists := grokPatterns[parts[1]]
+		if !exists {
+			err = fmt.Errorf("unknown grok pattern %s", parts[1])
+			return ""
+		}
+		definition, expandErr := expandGrok(definition, depth+1, references)
+		if expandErr != nil {
+			err = expandErr
+			return ""
+		}
+		if parts[2] == "" {
+			return "(?:" + definition + ")"
+		}
+		captureType := strings.ToLower(parts[3])
+		switch captureType {
+		case "":
+			captureType = CaptureString
+		case CaptureString, CaptureInt, CaptureFloat:
+			break
+		default:
+			err = fmt.Errorf("unsupported type %s for grok attribute %s", parts[3], parts[2])
+			return ""
+		}
+		name := grokGroupPrefix + strconv.Itoa(len(*references))
+		*references = append(*references, Capture{Name: parts[2], Type: captureType})
+		return "(?P<" + name + ">" + definition + ")"
+	})
+	return expanded, err
+}
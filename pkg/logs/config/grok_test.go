@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileGrok(t *testing.T) {
+	re, captures, err := compileGrok(`%{IPORHOST:client} - %{USER:user} \[%{HTTPDATE}\] "%{WORD:method} %{URIPATHPARAM:path} HTTP/%{NUMBER:version:float}" %{INT:code:int} (?P<rest>.*)`)
+	assert.Nil(t, err)
+	assert.Equal(t, len(re.SubexpNames()), len(captures))
+
+	match := re.FindStringSubmatch(`10.0.0.1 - bob [01/Mar/2019:12:00:00 +0000] "GET /index.html?page=2 HTTP/1.1" 200 512 "-"`)
+	assert.NotNil(t, match)
+	extracted := make(map[string]string)
+	types := make(map[string]string)
+	for i, capture := range captures {
+		if capture.Name != "" {
+			extracted[capture.Name] = match[i]
+			types[capture.Name] = capture.Type
+		}
+	}
+	assert.Equal(t, map[string]string{"client": "10.0.0.1", "user": "bob", "method": "GET", "path": "/index.html?page=2", "version": "1.1", "code": "200", "rest": `512 "-"`}, extracted)
+	assert.Equal(t, map[string]string{"client": CaptureString, "user": CaptureString, "method": CaptureString, "path": CaptureString, "version": CaptureFloat, "code": CaptureInt, "rest": CaptureString}, types)
+
+	// the patterns match whole lines.
+	assert.False(t, re.MatchString("GET /index.html"))
+}
+
+func TestCompileGrokShouldFailWithInvalidPatterns(t *testing.T) {
+	for _, pattern := range []string{"%{UNKNOWN:name}", "%{INT:count:bool}", "%{WORD:name} (", ""} {
+		err := ValidateProcessingRules([]*ProcessingRule{{Name: "grok", Type: GrokParser, Pattern: pattern}})
+		assert.NotNil(t, err, pattern)
+	}
+	assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{{Name: "grok", Type: GrokParser, Pattern: "%{LOGLEVEL:level} %{GREEDYDATA:msg}"}}))
+}
+
+func TestCompileProcessingRulesWithGrokParser(t *testing.T) {
+	rules := []*ProcessingRule{{Name: "grok", Type: GrokParser, Pattern: "%{TIMESTAMP_ISO8601:date} %{LOGLEVEL:level} %{GREEDYDATA}"}}
+	assert.Nil(t, CompileProcessingRules(rules))
+	assert.True(t, rules[0].Regex.MatchString("2019-03-01T12:00:00Z WARN disk almost full"))
+	assert.Equal(t, []Capture{{}, {Name: "date", Type: CaptureString}, {Name: "level", Type: CaptureString}}, rules[0].Captures)
+}
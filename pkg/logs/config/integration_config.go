@@ -7,17 +7,68 @@ package config
 
 import (
 	"fmt"
+	"strings"
 )
 
 // Logs source types
 const (
-	TCPType          = "tcp"
-	UDPType          = "udp"
-	FileType         = "file"
-	ContainerdType   = "containerd"
-	DockerType       = "docker"
-	JournaldType     = "journald"
-	WindowsEventType = "windows_event"
+	TCPType              = "tcp"
+	UDPType              = "udp"
+	SocketType           = "socket"
+	FileType             = "file"
+	ContainerdType       = "containerd"
+	CRIOType             = "cri-o"
+	PodmanType           = "podman"
+	DockerType           = "docker"
+	JournaldType         = "journald"
+	WindowsEventType     = "windows_event"
+	ForwardType          = "forward"
+	HTTPType             = "http"
+	KafkaType            = "kafka"
+	LambdaType           = "lambda"
+	KubernetesEventsType = "kubernetes_events"
+	S3Type               = "s3"
+	OTLPType             = "otlp"
+	AuditdType           = "auditd"
+	AggregatorType       = "aggregator"
+	CheckType            = "check"
+)
+
+// Positions the sources start reading from when nothing was committed for them yet: the kafka sources
+// when their consumer group has no committed offset, and the file, docker and journald sources when
+// the registry has no offset for them.
+const (
+	StartPositionBeginning = "beginning"
+	StartPositionEnd       = "end"
+	// StartPositionSince prefixes the positions from a time, since=<duration> starts the duration ago,
+	// e.g. since=24h, and since=<timestamp> at an RFC3339 timestamp, the kafka sources do not support it.
+	StartPositionSince = "since="
+)
+
+// Policies applied to the logs of a source when its pipeline is blocked.
+const (
+	// BlockPolicy makes the inputs of the source wait for the pipeline, no log is lost.
+	BlockPolicy = "block"
+	// DropOldestPolicy drops the oldest logs of the source held while the pipeline is blocked to bound their latency.
+	DropOldestPolicy = "drop_oldest"
+)
+
+// Policies applied to the logs whose timestamp is too far from the clock of the agent.
+const (
+	// ClockSkewFlagPolicy keeps the timestamp of the logs and adds the skew in seconds as their clock_skew attribute.
+	ClockSkewFlagPolicy = "flag"
+	// ClockSkewCorrectPolicy adds the clock_skew attribute to the logs and replaces their timestamp with the time of the agent.
+	ClockSkewCorrectPolicy = "correct"
+)
+
+// MaxFrameSize is the maximum size of the UDP datagrams and of the read buffers of the TCP connections.
+const MaxFrameSize = 65535
+
+// Encodings the logs can be transcoded from, the logs are expected to be encoded in UTF-8 otherwise.
+const (
+	UTF16LE  = "utf-16-le"
+	UTF16BE  = "utf-16-be"
+	ShiftJIS = "shift-jis"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -26,7 +77,44 @@ type LogsConfig struct {
 	Type string
 
 	Port int    // Network
-	Path string // File, Journald
+	Path string // File, Journald, Socket, Auditd
+
+	BindHost  string `mapstructure:"bind_host" json:"bind_host"`   // UDP
+	FrameSize int    `mapstructure:"frame_size" json:"frame_size"` // UDP, TCP
+	// MaxLineLength truncates the lines longer than it received over TCP, the truncated lines end with TRUNCATED.
+	MaxLineLength int `mapstructure:"max_line_length" json:"max_line_length"` // TCP
+
+	// SenderTags tags the logs with the IP address of their sender, and with its hostname found by a cached
+	// reverse DNS lookup with ReverseDNS.
+	SenderTags bool `mapstructure:"sender_tags" json:"sender_tags"` // TCP, UDP
+	ReverseDNS bool `mapstructure:"reverse_dns" json:"reverse_dns"` // TCP, UDP
+	// SenderOverrides override the source, the service and the tags of the logs of the senders they match,
+	// the first override matching a sender applies.
+	SenderOverrides []*SenderOverride `mapstructure:"sender_overrides" json:"sender_overrides"` // TCP, UDP
+
+	TLSCertFile      string `mapstructure:"tls_cert_file" json:"tls_cert_file"`           // TCP, Kafka, Aggregator
+	TLSKeyFile       string `mapstructure:"tls_key_file" json:"tls_key_file"`             // TCP, Kafka, Aggregator
+	TLSKeyPassphrase string `mapstructure:"tls_key_passphrase" json:"tls_key_passphrase"` // TCP, Kafka, Aggregator
+	TLSClientCAFile  string `mapstructure:"tls_client_ca_file" json:"tls_client_ca_file"` // TCP, Aggregator
+
+	SharedKey string `mapstructure:"shared_key" json:"shared_key"` // Forward
+
+	HTTPPort int `mapstructure:"http_port" json:"http_port"` // OTLP
+
+	AuthToken string `mapstructure:"auth_token" json:"auth_token"` // HTTP
+
+	Brokers       []string // Kafka
+	Topics        []string // Kafka
+	ConsumerGroup string   `mapstructure:"consumer_group" json:"consumer_group"` // Kafka
+	KafkaVersion  string   `mapstructure:"kafka_version" json:"kafka_version"`   // Kafka
+	StartPosition string   `mapstructure:"start_position" json:"start_position"` // Kafka, File, Docker, Journald
+	TLS           bool     // Kafka
+	TLSCAFile     string   `mapstructure:"tls_ca_file" json:"tls_ca_file"`     // Kafka
+	SASLUsername  string   `mapstructure:"sasl_username" json:"sasl_username"` // Kafka
+	SASLPassword  string   `mapstructure:"sasl_password" json:"sasl_password"` // Kafka
+
+	QueueURL string `mapstructure:"queue_url" json:"queue_url"` // S3
+	Region   string // S3
 
 	IncludeUnits []string `mapstructure:"include_units" json:"include_units"` // Journald
 	ExcludeUnits []string `mapstructure:"exclude_units" json:"exclude_units"` // Journald
@@ -44,6 +132,51 @@ type LogsConfig struct {
 	SourceCategory  string
 	Tags            []string
 	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+	// AutoMultiLine enables the detection of the multi-line logs when no multi_line processing rule is set.
+	AutoMultiLine bool `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection"`
+	// ParseJSON enables the parsing of the log lines formatted as JSON objects.
+	ParseJSON bool `mapstructure:"parse_json" json:"parse_json"`
+	// ParseSyslog enables the parsing of the RFC5424 and RFC3164 syslog messages,
+	// and of the octet-counted framing of the messages received over TCP.
+	ParseSyslog bool `mapstructure:"parse_syslog" json:"parse_syslog"`
+	// DedupLines collapses the runs of identical consecutive lines into one log with an occurrences attribute.
+	DedupLines bool `mapstructure:"dedup_lines" json:"dedup_lines"`
+	// DetectSeverity sets the status of the logs from the severity found in their syslog priority, in their JSON
+	// severity field or in the severity token of the common logger layouts, the statuses of the parsers take precedence.
+	DetectSeverity bool `mapstructure:"detect_severity" json:"detect_severity"`
+	// SeverityMapping maps custom severity names, matched in any case, to the statuses of the logs,
+	// it takes precedence over the usual severity names. The names are upper cased by Validate.
+	SeverityMapping map[string]string `mapstructure:"severity_mapping" json:"severity_mapping"`
+	// ExtractTraceContext adds the dd.trace_id and dd.span_id attributes correlating the logs with their trace
+	// when they hold a Datadog trace context, a W3C traceparent or an OpenTelemetry trace context.
+	ExtractTraceContext bool `mapstructure:"extract_trace_context" json:"extract_trace_context"`
+	// TimestampLayouts are the Go layouts of the timestamps starting the logs, e.g. "2006-01-02 15:04:05.000",
+	// used as the time of the logs unless a parser found it. The timestamps must be as long as their layout.
+	TimestampLayouts []string `mapstructure:"timestamp_layouts" json:"timestamp_layouts"`
+	// DetectTimestamp uses the timestamps starting the logs in the usual formats as the time of the logs.
+	DetectTimestamp bool `mapstructure:"detect_timestamp" json:"detect_timestamp"`
+	// ClockSkewPolicy is what happens to the logs whose timestamp is more than MaxClockSkew seconds away from
+	// the clock of the agent, 300 seconds if not set, nothing is done by default.
+	ClockSkewPolicy string `mapstructure:"clock_skew_policy" json:"clock_skew_policy"`
+	MaxClockSkew    int    `mapstructure:"max_clock_skew" json:"max_clock_skew"`
+	// Encoding is the encoding of the logs, they are transcoded to UTF-8 when set.
+	Encoding string
+	// BackfillArchives makes the file sources send the logs of the gzip archives their files were rotated to
+	// the first time the files are tailed.
+	BackfillArchives bool `mapstructure:"backfill_archives" json:"backfill_archives"`
+	// DropPolicy is what happens to the logs of the source when its pipeline is blocked, they wait by default.
+	DropPolicy string `mapstructure:"drop_policy" json:"drop_policy"`
+	// MaxBytesPerHour and MaxBytesPerDay are the quotas of bytes of logs the source can ship per UTC hour and day,
+	// a quota that is not positive is disabled.
+	MaxBytesPerHour int64 `mapstructure:"max_bytes_per_hour" json:"max_bytes_per_hour"`
+	MaxBytesPerDay  int64 `mapstructure:"max_bytes_per_day" json:"max_bytes_per_day"`
+	// QuotaPolicy is what happens to the logs of the source once it exceeded a quota, they are dropped by default.
+	QuotaPolicy string `mapstructure:"quota_policy" json:"quota_policy"`
+	// QuotaSampleRate is the fraction of the logs kept with the sample quota policy.
+	QuotaSampleRate float64 `mapstructure:"quota_sample_rate" json:"quota_sample_rate"`
+	// APIKey sends the logs of the source to the main endpoint with this API key, e.g. to route them to another
+	// organization, instead of the API key of the endpoint.
+	APIKey string `mapstructure:"api_key" json:"api_key"`
 }
 
 // Validate returns an error if the config is misconfigured
@@ -60,10 +193,92 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.FrameSize < 0 || c.FrameSize > MaxFrameSize:
+		return fmt.Errorf("frame_size must be between 1 and %d", MaxFrameSize)
+	case c.MaxLineLength < 0:
+		return fmt.Errorf("max_line_length must not be negative")
+	case c.Type == KafkaType && (len(c.Brokers) == 0 || len(c.Topics) == 0):
+		return fmt.Errorf("kafka source must have brokers and topics")
+	case strings.HasPrefix(c.StartPosition, StartPositionSince) && c.Type == KafkaType:
+		return fmt.Errorf("unsupported start_position %s, kafka sources only support %s and %s", c.StartPosition, StartPositionBeginning, StartPositionEnd)
+	case strings.HasPrefix(c.StartPosition, StartPositionSince) && !isValidStartTime(c.StartPosition):
+		return fmt.Errorf("invalid start_position %s, the time must be a duration, e.g. %s24h, or an RFC3339 timestamp", c.StartPosition, StartPositionSince)
+	case c.StartPosition != "" && !strings.HasPrefix(c.StartPosition, StartPositionSince) && c.StartPosition != StartPositionBeginning && c.StartPosition != StartPositionEnd:
+		return fmt.Errorf("unsupported start_position %s, supported positions are %s, %s and %s<duration|timestamp>", c.StartPosition, StartPositionBeginning, StartPositionEnd, StartPositionSince)
+	case c.Type == S3Type && c.QueueURL == "":
+		return fmt.Errorf("s3 source must have a queue_url")
+	case c.Type == SocketType && c.Path == "":
+		return fmt.Errorf("socket source must have a path")
+	case c.Type == ForwardType && c.Port == 0:
+		return fmt.Errorf("forward source must have a port")
+	case c.Type == LambdaType && c.Port == 0:
+		return fmt.Errorf("lambda source must have a port")
+	case c.Type == HTTPType && c.Port == 0:
+		return fmt.Errorf("http source must have a port")
+	case c.Type == AggregatorType && c.Port == 0:
+		return fmt.Errorf("aggregator source must have a port")
+	case c.Type == OTLPType && c.Port == 0 && c.HTTPPort == 0:
+		return fmt.Errorf("otlp source must have a port or an http_port")
+	case (c.TLSCertFile == "") != (c.TLSKeyFile == ""):
+		return fmt.Errorf("tls source must have both a certificate file and a key file")
+	case c.TLSClientCAFile != "" && c.TLSCertFile == "":
+		return fmt.Errorf("tls source must have a certificate file to verify the client certificates")
+	case c.Encoding != "" && c.Encoding != UTF16LE && c.Encoding != UTF16BE && c.Encoding != ShiftJIS:
+		return fmt.Errorf("unsupported encoding %s, supported encodings are %s, %s and %s", c.Encoding, UTF16LE, UTF16BE, ShiftJIS)
+	case c.DropPolicy != "" && c.DropPolicy != BlockPolicy && c.DropPolicy != DropOldestPolicy:
+		return fmt.Errorf("unsupported drop_policy %s, supported policies are %s and %s", c.DropPolicy, BlockPolicy, DropOldestPolicy)
+	case c.ClockSkewPolicy != "" && c.ClockSkewPolicy != ClockSkewFlagPolicy && c.ClockSkewPolicy != ClockSkewCorrectPolicy:
+		return fmt.Errorf("unsupported clock_skew_policy %s, supported policies are %s and %s", c.ClockSkewPolicy, ClockSkewFlagPolicy, ClockSkewCorrectPolicy)
+	case c.MaxClockSkew < 0:
+		return fmt.Errorf("max_clock_skew must not be negative")
+	case c.QuotaPolicy != "" && c.QuotaPolicy != QuotaDropPolicy && c.QuotaPolicy != QuotaSamplePolicy:
+		return fmt.Errorf("unsupported quota_policy %s, supported policies are %s and %s", c.QuotaPolicy, QuotaDropPolicy, QuotaSamplePolicy)
+	case c.QuotaPolicy == QuotaSamplePolicy && (c.QuotaSampleRate <= 0 || c.QuotaSampleRate > 1):
+		return fmt.Errorf("quota_sample_rate must be greater than 0 and at most 1 with the %s quota policy", QuotaSamplePolicy)
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
 		return err
 	}
+	err = c.normalizeSeverityMapping()
+	if err != nil {
+		return err
+	}
+	for _, override := range c.SenderOverrides {
+		if _, err := ParseSenderNetwork(override.Sender); err != nil {
+			return err
+		}
+	}
 	return CompileProcessingRules(c.ProcessingRules)
 }
+
+// statuses are the statuses the severities of the logs can be mapped to.
+var statuses = []string{"emergency", "alert", "critical", "error", "warn", "notice", "info", "debug"}
+
+// normalizeSeverityMapping upper cases the names of the severity mapping to match them in any case
+// and returns an error if a name is mapped to an unknown status.
+func (c *LogsConfig) normalizeSeverityMapping() error {
+	if len(c.SeverityMapping) == 0 {
+		return nil
+	}
+	mapping := make(map[string]string, len(c.SeverityMapping))
+	for name, status := range c.SeverityMapping {
+		status = strings.ToLower(status)
+		if !isStatus(status) {
+			return fmt.Errorf("unsupported status %s for severity %s, supported statuses are %s", status, name, strings.Join(statuses, ", "))
+		}
+		mapping[strings.ToUpper(name)] = status
+	}
+	c.SeverityMapping = mapping
+	return nil
+}
+
+// isStatus returns true if status is one of the statuses of the logs.
+func isStatus(status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
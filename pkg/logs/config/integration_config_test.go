@@ -20,6 +20,34 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 		{Type: UDPType, Port: 5678},
 		{Type: DockerType},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: MaskSequences, BuiltinPattern: "email"}}},
+		{Type: FileType, Path: "/var/log/foo.log", Encoding: UTF16LE},
+		{Type: FileType, Path: "/var/log/foo.log", Encoding: ShiftJIS},
+		{Type: FileType, Path: "/var/log/foo.log", DropPolicy: BlockPolicy},
+		{Type: FileType, Path: "/var/log/foo.log", DropPolicy: DropOldestPolicy},
+		{Type: FileType, Path: "/var/log/foo.log", MaxBytesPerHour: 1000, QuotaPolicy: QuotaDropPolicy},
+		{Type: FileType, Path: "/var/log/foo.log", DetectTimestamp: true, ClockSkewPolicy: ClockSkewFlagPolicy},
+		{Type: FileType, Path: "/var/log/foo.log", TimestampLayouts: []string{"2006-01-02"}, ClockSkewPolicy: ClockSkewCorrectPolicy, MaxClockSkew: 60},
+		{Type: FileType, Path: "/var/log/foo.log", DetectSeverity: true, SeverityMapping: map[string]string{"oops": "error", "W": "WARN"}},
+		{Type: FileType, Path: "/var/log/foo.log", MaxBytesPerDay: 1000, QuotaPolicy: QuotaSamplePolicy, QuotaSampleRate: 0.1},
+		{Type: TCPType, Port: 1234, TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"},
+		{Type: TCPType, Port: 1234, TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", TLSClientCAFile: "ca.pem"},
+		{Type: ForwardType, Port: 24224},
+		{Type: ForwardType, Port: 24224, SharedKey: "secret"},
+		{Type: OTLPType, Port: 4317},
+		{Type: SocketType, Path: "/var/run/datadog/logs.sock"},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"logs"}},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"logs"}, StartPosition: StartPositionBeginning, TLS: true, SASLUsername: "user"},
+		{Type: S3Type, QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/logs", Region: "us-east-1"},
+		{Type: OTLPType, HTTPPort: 4318},
+		{Type: HTTPType, Port: 8080, AuthToken: "secret"},
+		{Type: UDPType, Port: 10518, BindHost: "127.0.0.1", FrameSize: MaxFrameSize},
+		{Type: TCPType, Port: 10514, FrameSize: MaxFrameSize, MaxLineLength: 1024 * 1024},
+		{Type: FileType, Path: "/var/log/foo.log", StartPosition: StartPositionEnd},
+		{Type: FileType, Path: "/var/log/foo.log", StartPosition: "since=24h"},
+		{Type: DockerType, StartPosition: "since=2019-03-01T12:00:00Z"},
+		{Type: UDPType, Port: 514, SenderTags: true, ReverseDNS: true, SenderOverrides: []*SenderOverride{{Sender: "10.0.1.0/24", Source: "cisco"}, {Sender: "::1", Service: "loopback"}}},
+		{Type: AggregatorType, Port: 10520, TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", TLSClientCAFile: "ca.pem"},
 	}
 
 	for _, config := range validConfigs {
@@ -34,6 +62,21 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: FileType},
 		{Type: TCPType},
 		{Type: UDPType},
+		{Type: ForwardType},
+		{Type: OTLPType},
+		{Type: HTTPType, AuthToken: "secret"},
+		{Type: AggregatorType},
+		{Type: UDPType, Port: 10518, FrameSize: -1},
+		{Type: UDPType, Port: 10518, FrameSize: MaxFrameSize + 1},
+		{Type: TCPType, Port: 10514, MaxLineLength: -1},
+		{Type: SocketType},
+		{Type: KafkaType, Topics: []string{"logs"}},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"logs"}, StartPosition: "middle"},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"logs"}, StartPosition: "since=1h"},
+		{Type: FileType, Path: "/var/log/foo.log", StartPosition: "since=yesterday"},
+		{Type: FileType, Path: "/var/log/foo.log", StartPosition: "since=-1h"},
+		{Type: S3Type, Region: "us-east-1"},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: "bar"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch}}},
@@ -41,6 +84,21 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Pattern: ".*"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: MaskSequences, BuiltinPattern: "phone_number"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: MaskSequences, BuiltinPattern: "email", Pattern: ".*"}}},
+		{Type: FileType, Path: "/var/log/foo.log", Encoding: "latin-1"},
+		{Type: FileType, Path: "/var/log/foo.log", DropPolicy: "drop_newest"},
+		{Type: FileType, Path: "/var/log/foo.log", MaxBytesPerDay: 1000, QuotaPolicy: "block"},
+		{Type: FileType, Path: "/var/log/foo.log", ClockSkewPolicy: "drop"},
+		{Type: FileType, Path: "/var/log/foo.log", ClockSkewPolicy: ClockSkewFlagPolicy, MaxClockSkew: -1},
+		{Type: FileType, Path: "/var/log/foo.log", SeverityMapping: map[string]string{"oops": "fatal"}},
+		{Type: FileType, Path: "/var/log/foo.log", MaxBytesPerDay: 1000, QuotaPolicy: QuotaSamplePolicy},
+		{Type: FileType, Path: "/var/log/foo.log", MaxBytesPerDay: 1000, QuotaPolicy: QuotaSamplePolicy, QuotaSampleRate: 2},
+		{Type: TCPType, Port: 1234, TLSCertFile: "cert.pem"},
+		{Type: TCPType, Port: 1234, TLSKeyFile: "key.pem"},
+		{Type: TCPType, Port: 1234, TLSClientCAFile: "ca.pem"},
+		{Type: UDPType, Port: 514, SenderOverrides: []*SenderOverride{{Sender: "router", Source: "cisco"}}},
+		{Type: TCPType, Port: 514, SenderOverrides: []*SenderOverride{{Sender: "10.0.1.0/33", Source: "cisco"}}},
 	}
 
 	for _, config := range invalidConfigs {
@@ -48,3 +106,9 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		assert.NotNil(t, err)
 	}
 }
+
+func TestValidateNormalizesTheSeverityMapping(t *testing.T) {
+	config := &LogsConfig{Type: FileType, Path: "/var/log/foo.log", SeverityMapping: map[string]string{"oops": "error", "W": "WARN"}}
+	assert.Nil(t, config.Validate())
+	assert.Equal(t, map[string]string{"OOPS": "error", "W": "warn"}, config.SeverityMapping)
+}
@@ -6,6 +6,7 @@
 package config
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
@@ -41,6 +42,22 @@ func TestParseJSONWithValidFormatShouldSucceed(t *testing.T) {
 	assert.Equal(t, "numbers", rule.Name)
 }
 
+func TestParseJSONWithScriptRule(t *testing.T) {
+	configs, err := ParseJSON([]byte(`[{"log_processing_rules":[{"type":"script","name":"tidy","steps":[{"action":"rename","field":"usr","to":"user","if":"usr != \"\""},{"action":"set","field":"team","value":"\"checkout\"","Condition":{}}]}]}]`))
+	assert.Nil(t, err)
+	rule := configs[0].ProcessingRules[0]
+	assert.Equal(t, []*ScriptStep{
+		{Action: RenameAction, If: `usr != ""`, Field: "usr", To: "user"},
+		{Action: SetAction, Field: "team", Value: `"checkout"`},
+	}, rule.Steps)
+
+	// the compiled expressions are not serialized.
+	assert.Nil(t, CompileProcessingRules([]*ProcessingRule{rule}))
+	data, err := json.Marshal(rule.Steps[0])
+	assert.Nil(t, err)
+	assert.Equal(t, `{"action":"rename","if":"usr != \"\"","field":"usr","to":"user","value":""}`, string(data))
+}
+
 func TestParseJSONWithInvalidFormatShouldFail(t *testing.T) {
 	invalidFormats := []string{
 		"``",
@@ -16,6 +16,9 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	GrokParser     = "grok_parser"
+	SampleAtMatch  = "sample_at_match"
+	Script         = "script"
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
@@ -25,16 +28,52 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// BuiltinPattern is the name of a built-in pattern to use instead of Pattern.
+	BuiltinPattern string `mapstructure:"builtin_pattern" json:"builtin_pattern"`
+	// SampleRate is the fraction of the lines matching a sample_at_match rule that are kept.
+	SampleRate float64 `mapstructure:"sample_rate" json:"sample_rate"`
+	// Steps are the steps of a script rule, applied in order to the logs once they're parsed.
+	Steps []*ScriptStep `mapstructure:"steps" json:"steps"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	// Captures are the attributes extracted by the groups of Regex of a grok_parser rule, by group index.
+	Captures []Capture
 }
 
-// ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
+// builtinPattern is a pattern of sensitive data that can be referred to by name in the processing rules,
+// along with the placeholder masking it by default.
+type builtinPattern struct {
+	pattern     string
+	placeholder string
+}
+
+// builtinPatterns are the built-in patterns by name.
+var builtinPatterns = map[string]builtinPattern{
+	// the numbers of the Visa, Mastercard, Discover and American Express cards, with or without separators.
+	"credit_card": {
+		pattern:     `\b(?:(?:4\d{3}|5[1-5]\d{2}|6011|65\d{2})(?:[ -]?\d{4}){3}|3[47]\d{2}[ -]?\d{6}[ -]?\d{5})\b`,
+		placeholder: "[masked_credit_card]",
+	},
+	"email": {
+		pattern:     `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+		placeholder: "[masked_email]",
+	},
+	// the Datadog API keys are 32 hexadecimal characters long.
+	"api_key": {
+		pattern:     `\b[a-fA-F0-9]{32}\b`,
+		placeholder: "[masked_api_key]",
+	},
+}
+
+// ValidateProcessingRules validates the rules and raises an error if one is misconfigured,
+// the sample_at_match rules without pattern sample all the lines.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles or a built-in pattern, the grok_parser rules use a grok pattern
+// - a sample rate between 0, excluded, and 1 for the sample_at_match rules
+// - valid steps for the script rules, which have no pattern
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -42,17 +81,46 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, GrokParser:
 			break
+		case SampleAtMatch:
+			if rule.SampleRate <= 0 || rule.SampleRate > 1 {
+				return fmt.Errorf("sample rate must be greater than 0 and at most 1 for processing rule: %s", rule.Name)
+			}
+		case Script:
+			if err := validateScript(rule.Steps); err != nil {
+				return fmt.Errorf("invalid script for processing rule: %s: %v", rule.Name, err)
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
 			return fmt.Errorf("type %s is not supported for processing rule `%s`", rule.Type, rule.Name)
 		}
 
+		if rule.BuiltinPattern != "" {
+			if _, exists := builtinPatterns[rule.BuiltinPattern]; !exists {
+				return fmt.Errorf("invalid builtin pattern %s for processing rule: %s", rule.BuiltinPattern, rule.Name)
+			}
+			if rule.Pattern != "" {
+				return fmt.Errorf("both a pattern and a builtin pattern provided for processing rule: %s", rule.Name)
+			}
+			continue
+		}
+
+		if rule.Pattern == "" && rule.Type == SampleAtMatch {
+			// all the lines are sampled.
+			continue
+		}
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
+		if rule.Type == GrokParser {
+			if _, _, err := compileGrok(rule.Pattern); err != nil {
+				return fmt.Errorf("invalid grok pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+			}
+			continue
+		}
 		_, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
@@ -61,15 +129,36 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 	return nil
 }
 
-// CompileProcessingRules compiles all processing rule regular expressions.
+// CompileProcessingRules compiles all processing rule regular expressions,
+// the rules using a built-in pattern mask it with its default placeholder unless they have their own.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if builtin, exists := builtinPatterns[rule.BuiltinPattern]; exists {
+			rule.Pattern = builtin.pattern
+			if rule.ReplacePlaceholder == "" {
+				rule.ReplacePlaceholder = builtin.placeholder
+			}
+		}
+		if rule.Type == Script {
+			if err := compileScript(rule.Steps); err != nil {
+				return err
+			}
+			continue
+		}
+		if rule.Type == GrokParser {
+			re, captures, err := compileGrok(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			rule.Regex, rule.Captures = re, captures
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, SampleAtMatch:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -8,6 +8,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,3 +34,79 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestCompileBuiltinPatterns(t *testing.T) {
+	rules := []*ProcessingRule{
+		{Type: MaskSequences, BuiltinPattern: "credit_card"},
+		{Type: MaskSequences, BuiltinPattern: "email", ReplacePlaceholder: "[email]"},
+		{Type: MaskSequences, BuiltinPattern: "api_key"},
+	}
+	err := CompileProcessingRules(rules)
+	assert.Nil(t, err)
+
+	creditCard := rules[0]
+	assert.Equal(t, []byte("[masked_credit_card]"), creditCard.Placeholder)
+	for _, number := range []string{"4111111111111111", "4111 1111 1111 1111", "5500-0000-0000-0004", "378282246310005", "3782 822463 10005", "6011111111111117"} {
+		assert.True(t, creditCard.Regex.MatchString("paid with "+number+" today"), number)
+	}
+	for _, number := range []string{"1234567812345678", "41111111111111112222", "order 4111"} {
+		assert.False(t, creditCard.Regex.MatchString(number), number)
+	}
+
+	email := rules[1]
+	assert.Equal(t, []byte("[email]"), email.Placeholder)
+	assert.Equal(t, "sent to [email]", string(email.Regex.ReplaceAll([]byte("sent to john.doe+logs@example.co.uk"), email.Placeholder)))
+
+	apiKey := rules[2]
+	assert.Equal(t, "api_key=[masked_api_key]", string(apiKey.Regex.ReplaceAll([]byte("api_key=0123456789abcdef0123456789ABCDEF"), apiKey.Placeholder)))
+	assert.False(t, apiKey.Regex.MatchString("0123456789abcdef0123456789abcdef0"))
+}
+
+func TestValidateSampleAtMatchRules(t *testing.T) {
+	valid := []*ProcessingRule{
+		{Name: "sample_debug", Type: SampleAtMatch, Pattern: "DEBUG", SampleRate: 0.1},
+		{Name: "sample_all", Type: SampleAtMatch, SampleRate: 1},
+	}
+	assert.Nil(t, ValidateProcessingRules(valid))
+	assert.Nil(t, CompileProcessingRules(valid))
+	assert.True(t, valid[1].Regex.MatchString("any line"))
+
+	for _, rate := range []float64{0, -0.5, 1.5} {
+		err := ValidateProcessingRules([]*ProcessingRule{{Name: "sample", Type: SampleAtMatch, Pattern: "DEBUG", SampleRate: rate}})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestValidateScriptRules(t *testing.T) {
+	valid := []*ProcessingRule{{Name: "tidy", Type: Script, Steps: []*ScriptStep{
+		{Action: DropAction, If: `status == "debug"`},
+		{Action: RenameAction, Field: "usr", To: "user"},
+		{Action: SetAction, Field: "duration_s", Value: "[duration.ms] / 1000", If: "[duration.ms] > 0"},
+		{Action: DeleteAction, Field: "password"},
+	}}}
+	assert.Nil(t, ValidateProcessingRules(valid))
+	assert.Nil(t, CompileProcessingRules(valid))
+	assert.NotNil(t, valid[0].Steps[0].Condition)
+	assert.Nil(t, valid[0].Steps[0].Expression)
+	assert.NotNil(t, valid[0].Steps[2].Condition)
+	assert.NotNil(t, valid[0].Steps[2].Expression)
+
+	tooManySteps := make([]*ScriptStep, MaxScriptSteps+1)
+	for i := range tooManySteps {
+		tooManySteps[i] = &ScriptStep{Action: DeleteAction, Field: "password"}
+	}
+	for _, steps := range [][]*ScriptStep{
+		nil,
+		tooManySteps,
+		{{Field: "password"}},
+		{{Action: "run", Field: "password"}},
+		{{Action: DropAction, Field: "password"}},
+		{{Action: SetAction, Field: "user"}},
+		{{Action: RenameAction, Field: "usr"}},
+		{{Action: DeleteAction}},
+		{{Action: DropAction, If: `status == `}},
+		{{Action: DropAction, If: strings.Repeat("a", MaxScriptExpressionLength+1)}},
+	} {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{{Name: "tidy", Type: Script, Steps: steps}}))
+	}
+}
@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// Policies applied to the logs of a source once it exceeded its quota.
+const (
+	// QuotaDropPolicy drops all the logs of the source until its quota resets.
+	QuotaDropPolicy = "drop"
+	// QuotaSamplePolicy keeps a sample of the logs of the source at quota_sample_rate until its quota resets.
+	QuotaSamplePolicy = "sample"
+)
+
+// quotaWindow accounts the bytes shipped during a window of a fixed duration, the windows are aligned on UTC.
+type quotaWindow struct {
+	duration time.Duration
+	maxBytes int64
+	start    time.Time
+	bytes    int64
+	// exceeded is set until the end of the window once a log did not fit in it,
+	// so that the logs smaller than the remaining bytes do not make the source flap.
+	exceeded bool
+}
+
+// reset starts a new window when now is past the current one.
+func (w *quotaWindow) reset(now time.Time) {
+	if start := now.Truncate(w.duration); !start.Equal(w.start) {
+		w.start = start
+		w.bytes = 0
+		w.exceeded = false
+	}
+}
+
+// fits returns true if size more bytes fit in the quota of the window, a disabled quota fits everything.
+func (w *quotaWindow) fits(size int64) bool {
+	if w.maxBytes > 0 && w.bytes+size > w.maxBytes {
+		w.exceeded = true
+	}
+	return !w.exceeded
+}
+
+// Quota accounts the bytes a source ships per UTC hour and per UTC day against its quotas,
+// a Quota is safe for concurrent use.
+type Quota struct {
+	mu       sync.Mutex
+	hour     quotaWindow
+	day      quotaWindow
+	exceeded bool
+}
+
+// NewQuota returns a quota allowing maxBytesPerHour bytes per hour and maxBytesPerDay bytes per day,
+// a quota that is not positive is disabled, it returns nil if both are.
+func NewQuota(maxBytesPerHour, maxBytesPerDay int64) *Quota {
+	if maxBytesPerHour <= 0 && maxBytesPerDay <= 0 {
+		return nil
+	}
+	return &Quota{
+		hour: quotaWindow{duration: time.Hour, maxBytes: maxBytesPerHour},
+		day:  quotaWindow{duration: 24 * time.Hour, maxBytes: maxBytesPerDay},
+	}
+}
+
+// Consume accounts size bytes shipped at now and returns true if they fit in the quotas, the bytes that do not fit
+// are not accounted and nothing fits in an exceeded quota until its window ends.
+// changed is true when the quotas were just exceeded or when they just reset after being exceeded.
+// A nil Quota allows everything.
+func (q *Quota) Consume(size int, now time.Time) (allowed bool, changed bool) {
+	if q == nil {
+		return true, false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.hour.reset(now)
+	q.day.reset(now)
+	// both windows are checked so that each one records whether it's exceeded.
+	fitsHour, fitsDay := q.hour.fits(int64(size)), q.day.fits(int64(size))
+	allowed = fitsHour && fitsDay
+	if allowed {
+		q.hour.bytes += int64(size)
+		q.day.bytes += int64(size)
+	}
+	changed = allowed == q.exceeded
+	q.exceeded = !allowed
+	return allowed, changed
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewQuota(t *testing.T) {
+	assert.Nil(t, NewQuota(0, -1))
+	assert.NotNil(t, NewQuota(10, 0))
+	assert.NotNil(t, NewQuota(0, 10))
+
+	// a nil quota allows everything.
+	var quota *Quota
+	allowed, changed := quota.Consume(1000, time.Now())
+	assert.True(t, allowed)
+	assert.False(t, changed)
+}
+
+func TestQuotaPerHour(t *testing.T) {
+	quota := NewQuota(10, 0)
+	now := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	allowed, changed := quota.Consume(6, now)
+	assert.True(t, allowed)
+	assert.False(t, changed)
+	allowed, changed = quota.Consume(4, now.Add(time.Minute))
+	assert.True(t, allowed)
+	assert.False(t, changed)
+
+	allowed, changed = quota.Consume(1, now.Add(2*time.Minute))
+	assert.False(t, allowed)
+	assert.True(t, changed)
+	allowed, changed = quota.Consume(1, now.Add(59*time.Minute))
+	assert.False(t, allowed)
+	assert.False(t, changed)
+
+	// the quota resets at the beginning of the next hour.
+	allowed, changed = quota.Consume(10, now.Add(time.Hour))
+	assert.True(t, allowed)
+	assert.True(t, changed)
+}
+
+func TestQuotaPerDay(t *testing.T) {
+	quota := NewQuota(10, 15)
+	now := time.Date(2019, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	allowed, _ := quota.Consume(10, now)
+	assert.True(t, allowed)
+	allowed, _ = quota.Consume(5, now.Add(time.Hour))
+	assert.True(t, allowed)
+	allowed, changed := quota.Consume(5, now.Add(time.Hour))
+	assert.False(t, allowed)
+	assert.True(t, changed)
+
+	// the quota resets at the beginning of the next UTC day.
+	allowed, changed = quota.Consume(5, now.Add(90*time.Minute))
+	assert.True(t, allowed)
+	assert.True(t, changed)
+}
+
+func TestQuotaDoesNotFlapOnceExceeded(t *testing.T) {
+	quota := NewQuota(10, 0)
+	now := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	allowed, _ := quota.Consume(8, now)
+	assert.True(t, allowed)
+	allowed, _ = quota.Consume(5, now)
+	assert.False(t, allowed)
+	// the logs fitting the remaining bytes are not shipped until the quota resets.
+	allowed, changed := quota.Consume(1, now)
+	assert.False(t, allowed)
+	assert.False(t, changed)
+}
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/Knetic/govaluate.v3"
+)
+
+// The actions of the steps of the script processing rules.
+const (
+	// DropAction drops the log.
+	DropAction = "drop"
+	// SetAction sets the field to the value of the expression.
+	SetAction = "set"
+	// RenameAction renames the field.
+	RenameAction = "rename"
+	// DeleteAction deletes the field.
+	DeleteAction = "delete"
+)
+
+// The limits of the scripts, the expressions have no loops and no functions so that the time and memory
+// spent on a log depend on the size of the script and of the log, and the processing of a log is bounded
+// by MaxScriptValueLength and MaxScriptDuration.
+const (
+	// MaxScriptSteps is the maximum number of steps of a script.
+	MaxScriptSteps = 32
+	// MaxScriptExpressionLength is the maximum length of the expressions of a script.
+	MaxScriptExpressionLength = 1024
+	// MaxScriptValueLength is the maximum length of the strings an expression can concatenate
+	// and a set step can set a field to.
+	MaxScriptValueLength = 64 * 1024
+	// MaxScriptDuration is the maximum time spent on the scripts of a log, their remaining steps are skipped once it's elapsed.
+	MaxScriptDuration = 10 * time.Millisecond
+)
+
+// ScriptStep is a step of a script processing rule, applied to the logs matching its condition.
+// The expressions refer to the attributes of the logs by their name, between brackets if it holds
+// other characters than letters, digits and underscores, e.g. [http.status_code], and to the message
+// and status of the logs as message and status.
+type ScriptStep struct {
+	Action string `mapstructure:"action" json:"action"`
+	// If is the condition of the step, the step is applied to all the logs if not set.
+	If string `mapstructure:"if" json:"if"`
+	// Field is the field the step sets, renames or deletes.
+	Field string `mapstructure:"field" json:"field"`
+	// To is the new name of the field of a rename step.
+	To string `mapstructure:"to" json:"to"`
+	// Value is the expression of the value of the field of a set step.
+	Value string `mapstructure:"value" json:"value"`
+	// Condition and Expression are the compiled If and Value.
+	Condition  *govaluate.EvaluableExpression `mapstructure:"-" json:"-"`
+	Expression *govaluate.EvaluableExpression `mapstructure:"-" json:"-"`
+}
+
+// validateScript returns an error if a step of the script is misconfigured.
+func validateScript(steps []*ScriptStep) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("no steps provided")
+	}
+	if len(steps) > MaxScriptSteps {
+		return fmt.Errorf("%d steps provided, at most %d are supported", len(steps), MaxScriptSteps)
+	}
+	for i, step := range steps {
+		switch step.Action {
+		case DropAction:
+			if step.Field != "" || step.To != "" || step.Value != "" {
+				return fmt.Errorf("step %d: a drop step only supports a condition", i)
+			}
+		case SetAction:
+			if step.Field == "" || step.Value == "" {
+				return fmt.Errorf("step %d: a set step must have a field and a value", i)
+			}
+		case RenameAction:
+			if step.Field == "" || step.To == "" {
+				return fmt.Errorf("step %d: a rename step must have a field and a new name", i)
+			}
+		case DeleteAction:
+			if step.Field == "" {
+				return fmt.Errorf("step %d: a delete step must have a field", i)
+			}
+		case "":
+			return fmt.Errorf("step %d: action must be set", i)
+		default:
+			return fmt.Errorf("step %d: action %s is not supported", i, step.Action)
+		}
+		for _, expression := range []string{step.If, step.Value} {
+			if _, err := compileScriptExpression(expression); err != nil {
+				return fmt.Errorf("step %d: invalid expression %s: %v", i, expression, err)
+			}
+		}
+	}
+	return nil
+}
+
+// compileScript compiles the expressions of the steps of the script.
+func compileScript(steps []*ScriptStep) error {
+	for _, step := range steps {
+		var err error
+		if step.Condition, err = compileScriptExpression(step.If); err != nil {
+			return err
+		}
+		if step.Expression, err = compileScriptExpression(step.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileScriptExpression returns the compiled expression, or nil if it's empty.
+// No functions are available to the expressions.
+func compileScriptExpression(expression string) (*govaluate.EvaluableExpression, error) {
+	if expression == "" {
+		return nil, nil
+	}
+	if len(expression) > MaxScriptExpressionLength {
+		return nil, fmt.Errorf("the expression is longer than %d characters", MaxScriptExpressionLength)
+	}
+	return govaluate.NewEvaluableExpression(expression)
+}
@@ -0,0 +1,17 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/secrets"
+)
+
+// ResolveSecret returns the secret of value when it's a secret handle 'ENC[<handle>]', or value as is otherwise.
+// The secret is fetched from the secrets backend every time it's resolved, the credentials are resolved when
+// a connection is opened so that a secret rotated in the backend is used from the next connection on.
+func ResolveSecret(value, origin string) (string, error) {
+	return secrets.Refresh(value, origin)
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SenderOverride overrides the source, the service and the tags of the logs received by a network source
+// from the senders matching Sender, an IP address or a network in CIDR notation, e.g. 10.0.1.0/24.
+// The fields that are not set are the ones of the source, the tags are added to the ones of the source.
+type SenderOverride struct {
+	Sender  string
+	Source  string
+	Service string
+	Tags    []string
+}
+
+// ParseSenderNetwork returns the network of the senders matching sender, an IP address or a network
+// in CIDR notation, an IP address matches itself only.
+func ParseSenderNetwork(sender string) (*net.IPNet, error) {
+	if strings.Contains(sender, "/") {
+		_, network, err := net.ParseCIDR(sender)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sender %s, a sender must be an IP address or a network in CIDR notation", sender)
+		}
+		return network, nil
+	}
+	ip := net.ParseIP(sender)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid sender %s, a sender must be an IP address or a network in CIDR notation", sender)
+	}
+	if ip.To4() != nil {
+		return &net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// NewSenderSource returns a copy of source with the source, the service and the tags of override,
+// which shares the status, the messages and the quota of source.
+func NewSenderSource(source *LogSource, override *SenderOverride) *LogSource {
+	overriddenConfig := *source.Config
+	if override.Source != "" {
+		overriddenConfig.Source = override.Source
+	}
+	if override.Service != "" {
+		overriddenConfig.Service = override.Service
+	}
+	overriddenConfig.Tags = append(append([]string{}, source.Config.Tags...), override.Tags...)
+	overriddenSource := NewLogSource(source.Name, &overriddenConfig)
+	overriddenSource.Status = source.Status
+	overriddenSource.Messages = source.Messages
+	overriddenSource.quota = source.quota
+	overriddenSource.sourceType = source.sourceType
+	return overriddenSource
+}
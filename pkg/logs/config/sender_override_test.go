@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSenderNetwork(t *testing.T) {
+	network, err := ParseSenderNetwork("10.0.1.0/24")
+	assert.Nil(t, err)
+	assert.True(t, network.Contains(net.ParseIP("10.0.1.42")))
+	assert.False(t, network.Contains(net.ParseIP("10.0.2.1")))
+
+	network, err = ParseSenderNetwork("10.0.1.1")
+	assert.Nil(t, err)
+	assert.True(t, network.Contains(net.ParseIP("10.0.1.1")))
+	assert.False(t, network.Contains(net.ParseIP("10.0.1.2")))
+
+	network, err = ParseSenderNetwork("fe80::1")
+	assert.Nil(t, err)
+	assert.True(t, network.Contains(net.ParseIP("fe80::1")))
+	assert.False(t, network.Contains(net.ParseIP("fe80::2")))
+
+	for _, sender := range []string{"", "router", "10.0.1.0/33", "10.0.1.256"} {
+		_, err = ParseSenderNetwork(sender)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestNewSenderSource(t *testing.T) {
+	source := NewLogSource("syslog", &LogsConfig{Type: UDPType, Port: 514, Source: "syslog", Service: "relay", Tags: []string{"site:paris"}, MaxBytesPerDay: 10})
+	overriddenSource := NewSenderSource(source, &SenderOverride{Sender: "10.0.1.0/24", Source: "cisco", Tags: []string{"vendor:cisco"}})
+
+	assert.Equal(t, "cisco", overriddenSource.Config.Source)
+	assert.Equal(t, "relay", overriddenSource.Config.Service)
+	assert.Equal(t, []string{"site:paris", "vendor:cisco"}, overriddenSource.Config.Tags)
+	assert.Equal(t, []string{"site:paris"}, source.Config.Tags)
+	assert.Equal(t, 514, overriddenSource.Config.Port)
+	assert.True(t, source.Status == overriddenSource.Status)
+	assert.True(t, source.Messages == overriddenSource.Messages)
+
+	// the quota is shared with the source.
+	allowed, _ := overriddenSource.ConsumeQuota(10)
+	assert.True(t, allowed)
+	allowed, _ = source.ConsumeQuota(1)
+	assert.False(t, allowed)
+}
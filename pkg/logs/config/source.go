@@ -7,12 +7,18 @@ package config
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // LogSource holds a reference to an integration name and a log configuration, and allows to track errors and
 // successful operations on it. Both name and configuration are static for now and determined at creation time.
 // Changing the status is designed to be thread safe.
 type LogSource struct {
+	// bytesRead and linesRead are the first fields to be 64-bit aligned for the atomic operations.
+	bytesRead int64
+	linesRead int64
+
 	Name     string
 	Config   *LogsConfig
 	Status   *LogStatus
@@ -23,11 +29,13 @@ type LogSource struct {
 	// that reads log lines for this source. E.g, a sourceType == containerd and Config.Type == file means that
 	// the agent is tailing a file to read logs of a containerd container
 	sourceType string
+	// quota is nil when the source has no quota.
+	quota *Quota
 }
 
 // NewLogSource creates a new log source.
 func NewLogSource(name string, config *LogsConfig) *LogSource {
-	return &LogSource{
+	source := &LogSource{
 		Name:     name,
 		Config:   config,
 		Status:   NewLogStatus(),
@@ -35,6 +43,10 @@ func NewLogSource(name string, config *LogsConfig) *LogSource {
 		lock:     &sync.Mutex{},
 		Messages: NewMessages(),
 	}
+	if config != nil {
+		source.quota = NewQuota(config.MaxBytesPerHour, config.MaxBytesPerDay)
+	}
+	return source
 }
 
 // AddInput registers an input as being handled by this source.
@@ -75,3 +87,25 @@ func (s *LogSource) GetSourceType() string {
 	defer s.lock.Unlock()
 	return s.sourceType
 }
+
+// RecordRead accounts a log of size bytes read from this source.
+func (s *LogSource) RecordRead(size int) {
+	atomic.AddInt64(&s.bytesRead, int64(size))
+	atomic.AddInt64(&s.linesRead, 1)
+}
+
+// GetBytesRead returns the number of bytes of the logs read from this source.
+func (s *LogSource) GetBytesRead() int64 {
+	return atomic.LoadInt64(&s.bytesRead)
+}
+
+// GetLinesRead returns the number of logs read from this source.
+func (s *LogSource) GetLinesRead() int64 {
+	return atomic.LoadInt64(&s.linesRead)
+}
+
+// ConsumeQuota accounts a log of size bytes shipped by this source and returns true if it fits in the quotas
+// of the source, changed is true when the source just exceeded its quotas or when they just reset.
+func (s *LogSource) ConsumeQuota(size int) (allowed bool, changed bool) {
+	return s.quota.Consume(size, time.Now())
+}
@@ -28,6 +28,16 @@ func (s *LogSourceSuite) TestInputs() {
 
 }
 
+func (s *LogSourceSuite) TestRecordRead() {
+	s.source = NewLogSource("", nil)
+	s.Equal(int64(0), s.source.GetBytesRead())
+	s.Equal(int64(0), s.source.GetLinesRead())
+	s.source.RecordRead(5)
+	s.source.RecordRead(10)
+	s.Equal(int64(15), s.source.GetBytesRead())
+	s.Equal(int64(2), s.source.GetLinesRead())
+}
+
 func TestTrackerSuite(t *testing.T) {
 	suite.Run(t, new(LogSourceSuite))
 }
@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StartTime returns the time the source starts reading from and true if its start position is a since= one,
+// a duration is counted back from now.
+func (c *LogsConfig) StartTime(now time.Time) (time.Time, bool) {
+	if !strings.HasPrefix(c.StartPosition, StartPositionSince) {
+		return time.Time{}, false
+	}
+	since, err := parseStartTime(strings.TrimPrefix(c.StartPosition, StartPositionSince), now)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return since, true
+}
+
+// isValidStartTime returns true if the time of the since= start position holds a duration or a timestamp.
+func isValidStartTime(position string) bool {
+	_, err := parseStartTime(strings.TrimPrefix(position, StartPositionSince), time.Now())
+	return err == nil
+}
+
+// parseStartTime returns the time of value, a positive duration counted back from now or an RFC3339 timestamp.
+func parseStartTime(value string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration < 0 {
+			return time.Time{}, fmt.Errorf("negative duration %s", value)
+		}
+		return now.Add(-duration), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartTime(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	since, ok := (&LogsConfig{StartPosition: "since=90m"}).StartTime(now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(-90*time.Minute), since)
+
+	since, ok = (&LogsConfig{StartPosition: "since=2019-02-28T08:30:00+01:00"}).StartTime(now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2019, 2, 28, 7, 30, 0, 0, time.UTC), since.UTC())
+
+	for _, position := range []string{"", StartPositionBeginning, StartPositionEnd, "since=yesterday"} {
+		_, ok = (&LogsConfig{StartPosition: position}).StartTime(now)
+		assert.False(t, ok)
+	}
+}
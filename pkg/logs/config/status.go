@@ -22,7 +22,9 @@ const (
 type LogStatus struct {
 	status status
 	err    string
-	mu     *sync.Mutex
+	// lastErr is the last error recorded, it's kept once the source recovered.
+	lastErr string
+	mu      *sync.Mutex
 }
 
 // NewLogStatus creates a new log status.
@@ -47,6 +49,7 @@ func (s *LogStatus) Error(err error) {
 	defer s.mu.Unlock()
 	s.status = isError
 	s.err = fmt.Sprintf("Error: %s", err.Error())
+	s.lastErr = s.err
 }
 
 // IsPending returns whether the current status is not yet determined.
@@ -68,3 +71,10 @@ func (s *LogStatus) IsError() bool {
 func (s *LogStatus) GetError() string {
 	return s.err
 }
+
+// GetLastError returns the last error recorded, even once the source recovered from it.
+func (s *LogStatus) GetLastError() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
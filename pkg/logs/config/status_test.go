@@ -43,6 +43,16 @@ func (s *LogStatusSuite) TestError() {
 	s.Equal("Error: bar", s.status.GetError())
 }
 
+func (s *LogStatusSuite) TestLastErrorIsKeptOnSuccess() {
+	s.status = NewLogStatus()
+	s.Equal("", s.status.GetLastError())
+	s.status.Error(errors.New("bar"))
+	s.Equal("Error: bar", s.status.GetLastError())
+	s.status.Success()
+	s.Equal("", s.status.GetError())
+	s.Equal("Error: bar", s.status.GetLastError())
+}
+
 func TestLogStatusSuite(t *testing.T) {
 	suite.Run(t, new(LogStatusSuite))
 }
@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+)
+
+// LoadX509KeyPair loads the certificate and the private key of the PEM files, the key is decrypted
+// with passphrase when it's encrypted, the passphrase can be a secret handle.
+func LoadX509KeyPair(certFile, keyFile, passphrase, origin string) (tls.Certificate, error) {
+	if passphrase == "" {
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+	passphrase, err := ResolveSecret(passphrase, origin)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not resolve the passphrase of key file %s: %v", keyFile, err)
+	}
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("could not find any key in key file %s", keyFile)
+	}
+	if x509.IsEncryptedPEMBlock(block) {
+		der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("could not decrypt key file %s: %v", keyFile, err)
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
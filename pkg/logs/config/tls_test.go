@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeEncryptedCertificate generates a self-signed certificate and writes it in dir
+// along with its private key encrypted with passphrase.
+func writeEncryptedCertificate(t *testing.T, dir, passphrase string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "foo"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	block, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", keyDer, []byte(passphrase), x509.PEMCipherAES256)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(block), 0600))
+	return certFile, keyFile
+}
+
+func TestLoadX509KeyPairWithEncryptedKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs-tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeEncryptedCertificate(t, dir, "foo")
+
+	cert, err := LoadX509KeyPair(certFile, keyFile, "foo", "test")
+	assert.NoError(t, err)
+	assert.Len(t, cert.Certificate, 1)
+
+	_, err = LoadX509KeyPair(certFile, keyFile, "bar", "test")
+	assert.Error(t, err)
+
+	_, err = LoadX509KeyPair(certFile, keyFile, "", "test")
+	assert.Error(t, err)
+
+	_, err = LoadX509KeyPair(certFile, certFile, "foo", "test")
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+	"regexp"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/parser"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// defaultSampleSize is the number of lines sampled to detect the format of the logs.
+	defaultSampleSize = 500
+	// defaultMatchThreshold is the fraction of the sampled lines that must start with the same timestamp format.
+	defaultMatchThreshold = 0.1
+	// defaultDetectionTimeout is the time after which the detection ends even if fewer lines than the sample size were read.
+	defaultDetectionTimeout = 30 * time.Second
+)
+
+// timestampPrefixes are the usual formats of the timestamps starting the first line of a log.
+var timestampPrefixes = []*regexp.Regexp{
+	// 2019-03-01T12:00:00 or 2019-03-01 12:00:00, optionally between brackets
+	regexp.MustCompile(`^\[?\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}`),
+	// 2019/03/01 12:00:00
+	regexp.MustCompile(`^\[?\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}`),
+	// 01/Mar/2019:12:00:00
+	regexp.MustCompile(`^\[?\d{2}/[A-Za-z]{3}/\d{4}:\d{2}:\d{2}:\d{2}`),
+	// Fri, 01 Mar 2019 12:00:00
+	regexp.MustCompile(`^\[?[A-Za-z]{3}, \d{2} [A-Za-z]{3} \d{4} \d{2}:\d{2}:\d{2}`),
+	// Fri Mar  1 12:00:00
+	regexp.MustCompile(`^\[?[A-Za-z]{3} [A-Za-z]{3} +\d{1,2} \d{2}:\d{2}:\d{2}`),
+	// Mar  1 12:00:00
+	regexp.MustCompile(`^\[?[A-Za-z]{3} +\d{1,2} \d{2}:\d{2}:\d{2}`),
+	// 12:00:00.000
+	regexp.MustCompile(`^\[?\d{2}:\d{2}:\d{2}[.,]\d{3}`),
+}
+
+// AutoMultiLineHandler sends the first lines of a source as single lines while it looks for a timestamp format starting them,
+// once sampleSize lines have been read or the detection timed out it aggregates the following lines like a MultiLineHandler
+// if at least matchThreshold of the sampled lines start with the same timestamp format, it keeps sending single lines otherwise.
+type AutoMultiLineHandler struct {
+	lineChan         chan []byte
+	outputChan       chan *message.Message
+	parser           parser.Parser
+	single           *SingleLineHandler
+	sampleSize       int
+	matchThreshold   float64
+	detectionTimeout time.Duration
+	flushTimeout     time.Duration
+	lenLimit         int
+	// matches counts the sampled lines starting with each of the timestamp prefixes.
+	matches []int
+	sampled int
+}
+
+// NewAutoMultiLineHandler returns a new AutoMultiLineHandler sending the lines with singleLineHandler
+// until a multi-line format is detected.
+func NewAutoMultiLineHandler(singleLineHandler *SingleLineHandler, sampleSize int, matchThreshold float64, detectionTimeout, flushTimeout time.Duration, lenLimit int) *AutoMultiLineHandler {
+	return &AutoMultiLineHandler{
+		lineChan:         singleLineHandler.lineChan,
+		outputChan:       singleLineHandler.outputChan,
+		parser:           singleLineHandler.parser,
+		single:           singleLineHandler,
+		sampleSize:       sampleSize,
+		matchThreshold:   matchThreshold,
+		detectionTimeout: detectionTimeout,
+		flushTimeout:     flushTimeout,
+		lenLimit:         lenLimit,
+		matches:          make([]int, len(timestampPrefixes)),
+	}
+}
+
+// Handle forward lines to lineChan to process them
+func (h *AutoMultiLineHandler) Handle(content []byte) {
+	h.lineChan <- content
+}
+
+// Stop stops the handler from processing lines
+func (h *AutoMultiLineHandler) Stop() {
+	close(h.lineChan)
+}
+
+// Start starts the handler
+func (h *AutoMultiLineHandler) Start() {
+	go h.run()
+}
+
+// run samples the first lines and hands the following ones over to the handler matching the detected format.
+func (h *AutoMultiLineHandler) run() {
+	detectionTimer := time.NewTimer(h.detectionTimeout)
+	defer detectionTimer.Stop()
+sampling:
+	for h.sampled < h.sampleSize {
+		select {
+		case line, isOpen := <-h.lineChan:
+			if !isOpen {
+				close(h.outputChan)
+				return
+			}
+			// the line is sampled first as the single line handler hands it over to the pipeline
+			h.sample(line)
+			h.single.process(line)
+		case <-detectionTimer.C:
+			break sampling
+		}
+	}
+
+	if re := h.detectedPattern(); re != nil {
+		log.Debugf("Aggregating multi-line logs starting with %v", re)
+		multi := NewMultiLineHandler(h.outputChan, re, h.flushTimeout, h.lenLimit, h.parser)
+		multi.lineChan = h.lineChan
+		multi.run()
+		return
+	}
+	h.single.run()
+}
+
+// sample counts the timestamp prefixes line starts with.
+func (h *AutoMultiLineHandler) sample(line []byte) {
+	unwrappedLine, err := h.parser.Unwrap(line)
+	if err != nil {
+		log.Debug(err)
+	}
+	if len(bytes.TrimSpace(unwrappedLine)) == 0 {
+		return
+	}
+	h.sampled++
+	for i, re := range timestampPrefixes {
+		if re.Match(unwrappedLine) {
+			h.matches[i]++
+		}
+	}
+}
+
+// detectedPattern returns the timestamp prefix starting the most sampled lines
+// if enough of them start with it, nil otherwise.
+func (h *AutoMultiLineHandler) detectedPattern() *regexp.Regexp {
+	if h.sampled == 0 {
+		return nil
+	}
+	best := 0
+	for i, count := range h.matches {
+		if count > h.matches[best] {
+			best = i
+		}
+	}
+	if float64(h.matches[best])/float64(h.sampled) < h.matchThreshold {
+		return nil
+	}
+	return timestampPrefixes[best]
+}
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoMultiLineHandlerAggregatesTimestampedLogs(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	h := NewAutoMultiLineHandler(NewSingleLineHandler(outputChan, contentLenLimit, false, parser.NoopParser), 3, 0.5, time.Minute, 10*time.Millisecond, 100)
+	h.Start()
+
+	// the sampled lines are sent as single lines.
+	h.Handle([]byte("2019-03-01 12:00:00 first"))
+	h.Handle([]byte("  at foo"))
+	h.Handle([]byte("2019-03-01 12:00:01 second"))
+	for _, expected := range []string{"2019-03-01 12:00:00 first", "at foo", "2019-03-01 12:00:01 second"} {
+		assert.Equal(t, expected, string((<-outputChan).Content))
+	}
+
+	h.Handle([]byte("2019-03-01 12:00:02 third"))
+	h.Handle([]byte("  at bar"))
+	h.Handle([]byte("2019-03-01 12:00:03 fourth"))
+	assert.Equal(t, `2019-03-01 12:00:02 third\n  at bar`, string((<-outputChan).Content))
+	assert.Equal(t, "2019-03-01 12:00:03 fourth", string((<-outputChan).Content))
+
+	h.Stop()
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+}
+
+func TestAutoMultiLineHandlerFallsBackOnSingleLines(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	h := NewAutoMultiLineHandler(NewSingleLineHandler(outputChan, contentLenLimit, false, parser.NoopParser), 3, 0.5, time.Minute, 10*time.Millisecond, 100)
+	h.Start()
+
+	for _, line := range []string{"2019-03-01 12:00:00 first", "second", "third", "fourth", "fifth"} {
+		h.Handle([]byte(line))
+		assert.Equal(t, line, string((<-outputChan).Content))
+	}
+
+	h.Stop()
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+}
+
+func TestAutoMultiLineHandlerDetectionTimeout(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	h := NewAutoMultiLineHandler(NewSingleLineHandler(outputChan, contentLenLimit, false, parser.NoopParser), 100, 0.5, 10*time.Millisecond, 10*time.Millisecond, 100)
+	h.Start()
+
+	h.Handle([]byte("Mar  1 12:00:00 first"))
+	assert.Equal(t, "Mar  1 12:00:00 first", string((<-outputChan).Content))
+	time.Sleep(50 * time.Millisecond)
+
+	h.Handle([]byte("Mar  1 12:00:01 second"))
+	h.Handle([]byte("continued"))
+	// the last log is flushed after the flush timeout.
+	assert.Equal(t, `Mar  1 12:00:01 second\ncontinued`, string((<-outputChan).Content))
+
+	h.Stop()
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+}
+
+func TestAutoMultiLineHandlerStopWhileSampling(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	h := NewAutoMultiLineHandler(NewSingleLineHandler(outputChan, contentLenLimit, false, parser.NoopParser), 100, 0.5, time.Minute, 10*time.Millisecond, 100)
+	h.Start()
+
+	h.Handle([]byte("2019-03-01 12:00:00 first"))
+	assert.Equal(t, "2019-03-01 12:00:00 first", string((<-outputChan).Content))
+
+	h.Stop()
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+}
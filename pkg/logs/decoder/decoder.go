@@ -7,23 +7,33 @@ package decoder
 
 import (
 	"bytes"
+	"time"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/parser"
 )
 
-// contentLenLimit represents the length limit above which we want to truncate the output content
+// contentLenLimit represents the default length limit above which we want to truncate the output content
 var contentLenLimit = 256 * 1000
 
 // Input represents a list of bytes consumed by the Decoder
 type Input struct {
 	content []byte
+	// pooled is true when content was got from the buffer pool, it's returned to the pool once decoded.
+	pooled bool
 }
 
 // NewInput returns a new input
 func NewInput(content []byte) *Input {
-	return &Input{content}
+	return &Input{content: content}
+}
+
+// NewPooledInput returns a new input of content got from message.GetBuffer,
+// the decoder returns content to the pool once decoded so it must not be used by the caller anymore.
+func NewPooledInput(content []byte) *Input {
+	return &Input{content: content, pooled: true}
 }
 
 // Decoder splits raw data into lines and passes them to a lineHandler that emits outputs
@@ -33,39 +43,88 @@ type Decoder struct {
 
 	lineBuffer  *bytes.Buffer
 	lineHandler LineHandler
+	// lenLimit is the length above which the lines are split.
+	lenLimit int
+	// transcoder, when set, transcodes the lines to UTF-8 before handing them to lineHandler,
+	// which sends its outputs to handlerOutputChan to have their raw data length fixed.
+	transcoder        *transcoder
+	handlerOutputChan chan *message.Message
+	// transcodedOutputChan is where the transcoded outputs are forwarded to, OutputChan unless they are deduplicated.
+	transcodedOutputChan chan *message.Message
+	// deduplicator, when set, collapses the identical consecutive outputs before sending them to OutputChan.
+	deduplicator *deduplicator
 }
 
 // InitializeDecoder returns a properly initialized Decoder
 func InitializeDecoder(source *config.LogSource, parser parser.Parser) *Decoder {
+	return InitializeDecoderWithEncoding(source, parser, source.Config.Encoding)
+}
+
+// InitializeDecoderWithEncoding returns a properly initialized Decoder transcoding
+// the data from encoding to UTF-8 unless encoding is empty.
+func InitializeDecoderWithEncoding(source *config.LogSource, parser parser.Parser, encoding string) *Decoder {
 	inputChan := make(chan *Input)
 	outputChan := make(chan *message.Message)
 
+	var deduplicator *deduplicator
+	dedupInputChan := outputChan
+	if source.Config.DedupLines {
+		deduplicator = newDeduplicator(outputChan, dedupWindow())
+		dedupInputChan = deduplicator.inputChan
+	}
+
+	transcoder := newTranscoder(encoding)
+	handlerOutputChan := dedupInputChan
+	if transcoder != nil {
+		handlerOutputChan = make(chan *message.Message)
+	}
+
+	lenLimit := maxMessageSize()
+	singleLineHandler := NewSingleLineHandler(handlerOutputChan, lenLimit, coreConfig.Datadog.GetBool("logs_config.truncate_oversized_messages"), parser)
+
 	var lineHandler LineHandler
 	for _, rule := range source.Config.ProcessingRules {
 		if rule.Type == config.MultiLine {
-			lineHandler = NewMultiLineHandler(outputChan, rule.Regex, defaultFlushTimeout, parser)
+			lineHandler = NewMultiLineHandler(handlerOutputChan, rule.Regex, multiLineFlushTimeout(), multiLineLenLimit(), parser)
 		}
 	}
+	if lineHandler == nil && source.Config.AutoMultiLine {
+		lineHandler = NewAutoMultiLineHandler(singleLineHandler, autoMultiLineSampleSize(), autoMultiLineMatchThreshold(), defaultDetectionTimeout, multiLineFlushTimeout(), multiLineLenLimit())
+	}
 	if lineHandler == nil {
-		lineHandler = NewSingleLineHandler(outputChan, parser)
+		lineHandler = singleLineHandler
 	}
 
-	return New(inputChan, outputChan, lineHandler)
+	decoder := New(inputChan, outputChan, lineHandler, lenLimit)
+	if transcoder != nil {
+		decoder.transcoder = transcoder
+		decoder.handlerOutputChan = handlerOutputChan
+		decoder.transcodedOutputChan = dedupInputChan
+	}
+	decoder.deduplicator = deduplicator
+	return decoder
 }
 
-// New returns an initialized Decoder
-func New(InputChan chan *Input, OutputChan chan *message.Message, lineHandler LineHandler) *Decoder {
+// New returns an initialized Decoder splitting the lines longer than lenLimit
+func New(InputChan chan *Input, OutputChan chan *message.Message, lineHandler LineHandler, lenLimit int) *Decoder {
 	var lineBuffer bytes.Buffer
 	return &Decoder{
 		InputChan:   InputChan,
 		OutputChan:  OutputChan,
 		lineBuffer:  &lineBuffer,
 		lineHandler: lineHandler,
+		lenLimit:    lenLimit,
 	}
 }
 
 // Start starts the Decoder
 func (d *Decoder) Start() {
+	if d.deduplicator != nil {
+		d.deduplicator.start()
+	}
+	if d.transcoder != nil {
+		go d.forwardTranscodedOutputs()
+	}
 	d.lineHandler.Start()
 	go d.run()
 }
@@ -78,7 +137,15 @@ func (d *Decoder) Stop() {
 // run lets the Decoder handle data coming from InputChan
 func (d *Decoder) run() {
 	for data := range d.InputChan {
-		d.decodeIncomingData(data.content)
+		if d.transcoder != nil {
+			d.decodeTranscodedData(data.content)
+		} else {
+			d.decodeIncomingData(data.content)
+		}
+		if data.pooled {
+			// the data has been copied to lineBuffer
+			message.PutBuffer(data.content)
+		}
 	}
 	// finish to stop decoder
 	d.lineHandler.Stop()
@@ -88,7 +155,7 @@ func (d *Decoder) run() {
 func (d *Decoder) decodeIncomingData(inBuf []byte) {
 	i, j := 0, 0
 	n := len(inBuf)
-	maxj := contentLenLimit - d.lineBuffer.Len()
+	maxj := d.lenLimit - d.lineBuffer.Len()
 
 	for ; j < n; j++ {
 		if j == maxj {
@@ -96,21 +163,129 @@ func (d *Decoder) decodeIncomingData(inBuf []byte) {
 			d.lineBuffer.Write(inBuf[i:j])
 			d.sendLine()
 			i = j
-			maxj = i + contentLenLimit
+			maxj = i + d.lenLimit
 		} else if inBuf[j] == '\n' {
 			d.lineBuffer.Write(inBuf[i:j])
 			d.sendLine()
 			i = j + 1 // +1 as we skip the `\n`
-			maxj = i + contentLenLimit
+			maxj = i + d.lenLimit
 		}
 	}
 	d.lineBuffer.Write(inBuf[i:j])
 }
 
-// sendLine copies content from lineBuffer which is passed to lineHandler
+// sendLine copies content from lineBuffer to a pooled buffer which is passed to lineHandler
 func (d *Decoder) sendLine() {
-	content := make([]byte, d.lineBuffer.Len())
+	content := message.GetBuffer(d.lineBuffer.Len())
 	copy(content, d.lineBuffer.Bytes())
 	d.lineBuffer.Reset()
 	d.lineHandler.Handle(content)
 }
+
+// decodeTranscodedData splits raw data based on the end of line sequence of its encoding,
+// the lines are transcoded to UTF-8 before being split in chunks of lenLimit bytes.
+func (d *Decoder) decodeTranscodedData(inBuf []byte) {
+	rawLenLimit := rawLenLimitFactor * d.lenLimit
+	i := 0
+	for j := range inBuf {
+		if d.transcoder.isEndOfLine(d.lineBuffer.Bytes(), inBuf[i:j+1]) {
+			d.lineBuffer.Write(inBuf[i : j+1])
+			d.sendTranscodedLine(len(d.transcoder.newLine))
+			i = j + 1
+		} else if d.lineBuffer.Len()+j+1-i >= rawLenLimit && (d.lineBuffer.Len()+j+1-i)%len(d.transcoder.newLine) == 0 {
+			// send the beginning of the line because it is too long
+			d.lineBuffer.Write(inBuf[i : j+1])
+			d.sendTranscodedLine(0)
+			i = j + 1
+		}
+	}
+	d.lineBuffer.Write(inBuf[i:])
+}
+
+// sendTranscodedLine transcodes the content of lineBuffer without its last newLineLen bytes
+// and passes it to lineHandler in chunks of at most lenLimit bytes,
+// blank lines are skipped as the line handlers would not account for their length.
+func (d *Decoder) sendTranscodedLine(newLineLen int) {
+	rawLen := d.lineBuffer.Len()
+	content := d.transcoder.transcode(d.lineBuffer.Bytes()[:rawLen-newLineLen])
+	d.lineBuffer.Reset()
+	if len(bytes.TrimSpace(content)) == 0 {
+		d.transcoder.skip(rawLen)
+		return
+	}
+	for len(content) >= d.lenLimit {
+		// the length of the whole line is accounted to its last chunk
+		chunk := make([]byte, d.lenLimit)
+		copy(chunk, content)
+		d.transcoder.add(chunk, 0)
+		d.lineHandler.Handle(chunk)
+		content = content[d.lenLimit:]
+	}
+	d.transcoder.add(content, rawLen)
+	d.lineHandler.Handle(content)
+}
+
+// forwardTranscodedOutputs forwards the outputs of lineHandler to transcodedOutputChan
+// with the length of the data they were made of in its source encoding.
+func (d *Decoder) forwardTranscodedOutputs() {
+	for output := range d.handlerOutputChan {
+		output.RawDataLen = d.transcoder.rawDataLen(output.RawDataLen)
+		d.transcodedOutputChan <- output
+	}
+	close(d.transcodedOutputChan)
+}
+
+// multiLineFlushTimeout returns the time to wait for the next line of a multi-line log before sending it.
+func multiLineFlushTimeout() time.Duration {
+	timeout := time.Duration(coreConfig.Datadog.GetInt("logs_config.multi_line_flush_timeout")) * time.Millisecond
+	if timeout <= 0 {
+		return defaultFlushTimeout
+	}
+	return timeout
+}
+
+// dedupWindow returns the time a run of identical lines is held at most before being sent.
+func dedupWindow() time.Duration {
+	window := time.Duration(coreConfig.Datadog.GetInt("logs_config.dedup_window")) * time.Millisecond
+	if window <= 0 {
+		return defaultDedupWindow
+	}
+	return window
+}
+
+// maxMessageSize returns the length above which a line is truncated or split.
+func maxMessageSize() int {
+	size := coreConfig.Datadog.GetInt("logs_config.max_message_size_bytes")
+	if size <= 0 {
+		return contentLenLimit
+	}
+	return size
+}
+
+// multiLineLenLimit returns the length above which a multi-line log is truncated.
+func multiLineLenLimit() int {
+	limit := coreConfig.Datadog.GetInt("logs_config.multi_line_max_size")
+	if limit <= 0 {
+		return maxMessageSize()
+	}
+	return limit
+}
+
+// autoMultiLineSampleSize returns the number of lines sampled to detect the multi-line logs.
+func autoMultiLineSampleSize() int {
+	size := coreConfig.Datadog.GetInt("logs_config.auto_multi_line_sample_size")
+	if size <= 0 {
+		return defaultSampleSize
+	}
+	return size
+}
+
+// autoMultiLineMatchThreshold returns the fraction of the sampled lines that must start
+// with the same timestamp format to detect multi-line logs.
+func autoMultiLineMatchThreshold() float64 {
+	threshold := coreConfig.Datadog.GetFloat64("logs_config.auto_multi_line_match_threshold")
+	if threshold <= 0 || threshold > 1 {
+		return defaultMatchThreshold
+	}
+	return threshold
+}
@@ -6,10 +6,17 @@
 package decoder
 
 import (
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/parser"
 )
 
 type MockLineHandler struct {
@@ -36,7 +43,7 @@ func (h *MockLineHandler) Stop() {
 
 func TestDecodeIncomingData(t *testing.T) {
 	h := NewMockLineHandler()
-	d := New(nil, nil, h)
+	d := New(nil, nil, h, contentLenLimit)
 
 	var line []byte
 
@@ -100,7 +107,7 @@ func TestDecodeIncomingData(t *testing.T) {
 
 func TestDecoderLifeCycle(t *testing.T) {
 	h := NewMockLineHandler()
-	d := New(nil, nil, h)
+	d := New(nil, nil, h, contentLenLimit)
 
 	// lineHandler should not receive any lines
 	d.Start()
@@ -120,3 +127,101 @@ func TestDecoderLifeCycle(t *testing.T) {
 		assert.Fail(t, "LineHandler should be stopped")
 	}
 }
+
+func TestDecoderDecodesPooledInputs(t *testing.T) {
+	h := NewMockLineHandler()
+	d := New(make(chan *Input), nil, h, contentLenLimit)
+	d.Start()
+
+	for _, data := range []string{"hello", "world\nhow", " are you\n"} {
+		buf := message.GetBuffer(len(data))
+		copy(buf, data)
+		d.InputChan <- NewPooledInput(buf)
+	}
+	// the lines do not alias the inputs, which are reused once decoded.
+	for i := 0; i < 10; i++ {
+		buf := message.GetBuffer(4096)
+		copy(buf, "garbage")
+		message.PutBuffer(buf)
+	}
+	assert.Equal(t, "helloworld", string(<-h.lineChan))
+	assert.Equal(t, "how are you", string(<-h.lineChan))
+	d.Stop()
+}
+
+func TestInitializeDecoderWithMultiLineSettings(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{
+		ProcessingRules: []*config.ProcessingRule{{Type: config.MultiLine, Regex: regexp.MustCompile("^[0-9]+\\.")}},
+	})
+
+	handler := InitializeDecoder(source, parser.NoopParser).lineHandler.(*MultiLineHandler)
+	assert.Equal(t, defaultFlushTimeout, handler.flushTimeout)
+	assert.Equal(t, contentLenLimit, handler.lenLimit)
+
+	coreConfig.Datadog.Set("logs_config.multi_line_flush_timeout", 5000)
+	coreConfig.Datadog.Set("logs_config.multi_line_max_size", 1000)
+	defer coreConfig.Datadog.Set("logs_config.multi_line_flush_timeout", 1000)
+	defer coreConfig.Datadog.Set("logs_config.multi_line_max_size", 256*1000)
+
+	handler = InitializeDecoder(source, parser.NoopParser).lineHandler.(*MultiLineHandler)
+	assert.Equal(t, 5*time.Second, handler.flushTimeout)
+	assert.Equal(t, 1000, handler.lenLimit)
+}
+
+func TestInitializeDecoderWithAutoMultiLine(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{AutoMultiLine: true})
+	handler := InitializeDecoder(source, parser.NoopParser).lineHandler.(*AutoMultiLineHandler)
+	assert.Equal(t, defaultSampleSize, handler.sampleSize)
+	assert.Equal(t, defaultMatchThreshold, handler.matchThreshold)
+
+	// an explicit pattern takes precedence over the detection.
+	source = config.NewLogSource("", &config.LogsConfig{
+		AutoMultiLine:   true,
+		ProcessingRules: []*config.ProcessingRule{{Type: config.MultiLine, Regex: regexp.MustCompile("^[0-9]+\\.")}},
+	})
+	_, isMultiLine := InitializeDecoder(source, parser.NoopParser).lineHandler.(*MultiLineHandler)
+	assert.True(t, isMultiLine)
+}
+
+func TestInitializeDecoderWithDedupLines(t *testing.T) {
+	assert.Nil(t, InitializeDecoder(config.NewLogSource("", &config.LogsConfig{}), parser.NoopParser).deduplicator)
+
+	source := config.NewLogSource("", &config.LogsConfig{DedupLines: true, Encoding: config.UTF16LE})
+	d := InitializeDecoder(source, parser.NoopParser)
+	assert.Equal(t, defaultDedupWindow, d.deduplicator.window)
+	d.Start()
+
+	d.InputChan <- NewInput([]byte("f\x00o\x00o\x00\n\x00f\x00o\x00o\x00\n\x00b\x00a\x00r\x00\n\x00"))
+	output := <-d.OutputChan
+	assert.Equal(t, "foo", string(output.Content))
+	assert.Equal(t, 2, output.Occurrences)
+	// the length of the duplicates in their source encoding is accounted to the run.
+	assert.Equal(t, 16, output.RawDataLen)
+
+	d.Stop()
+	output = <-d.OutputChan
+	assert.Equal(t, "bar", string(output.Content))
+	assert.Equal(t, 1, output.Occurrences)
+	_, isOpen := <-d.OutputChan
+	assert.False(t, isOpen)
+}
+
+func TestInitializeDecoderWithMaxMessageSize(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	d := InitializeDecoder(source, parser.NoopParser)
+	handler := d.lineHandler.(*SingleLineHandler)
+	assert.Equal(t, contentLenLimit, d.lenLimit)
+	assert.Equal(t, contentLenLimit, handler.lenLimit)
+	assert.False(t, handler.truncate)
+
+	coreConfig.Datadog.Set("logs_config.max_message_size_bytes", 1000)
+	coreConfig.Datadog.Set("logs_config.truncate_oversized_messages", true)
+	defer coreConfig.Datadog.Set("logs_config.max_message_size_bytes", 256*1000)
+	defer coreConfig.Datadog.Set("logs_config.truncate_oversized_messages", false)
+
+	d = InitializeDecoder(source, parser.NoopParser)
+	handler = d.lineHandler.(*SingleLineHandler)
+	assert.Equal(t, 1000, d.lenLimit)
+	assert.Equal(t, 1000, handler.lenLimit)
+	assert.True(t, handler.truncate)
+}
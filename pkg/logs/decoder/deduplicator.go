@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// defaultDedupWindow is the time a run of identical lines is held at most before being sent.
+const defaultDedupWindow = 10 * time.Second
+
+// deduplicator collapses the runs of identical consecutive outputs of a decoder into their first output,
+// whose Occurrences is the number of outputs of the run, like syslog's "last message repeated N times".
+// A run is sent when a different output is received, window after its first output or when the decoder stops.
+type deduplicator struct {
+	inputChan  chan *message.Message
+	outputChan chan *message.Message
+	window     time.Duration
+	// pending is the first output of the current run.
+	pending *message.Message
+}
+
+// newDeduplicator returns a deduplicator sending the runs of identical outputs received on its input channel
+// to outputChan, which is closed once the input channel is.
+func newDeduplicator(outputChan chan *message.Message, window time.Duration) *deduplicator {
+	return &deduplicator{
+		inputChan:  make(chan *message.Message),
+		outputChan: outputChan,
+		window:     window,
+	}
+}
+
+// start starts the deduplicator.
+func (d *deduplicator) start() {
+	go d.run()
+}
+
+// run collapses the outputs of inputChan and sends the pending run when window expires.
+func (d *deduplicator) run() {
+	flushTimer := time.NewTimer(d.window)
+	flushTimer.Stop()
+	defer func() {
+		flushTimer.Stop()
+		d.flush()
+		close(d.outputChan)
+	}()
+	for {
+		select {
+		case output, isOpen := <-d.inputChan:
+			if !isOpen {
+				// inputChan has been closed, the pending run is sent before returning
+				return
+			}
+			if d.pending != nil && bytes.Equal(d.pending.Content, output.Content) {
+				// the offsets of the duplicates are accounted to the run
+				d.pending.Occurrences++
+				d.pending.RawDataLen += output.RawDataLen
+				output.Release()
+				continue
+			}
+			if !flushTimer.Stop() {
+				// drain a tick that happened at the same time to not send the new run too early
+				select {
+				case <-flushTimer.C:
+				default:
+				}
+			}
+			d.flush()
+			output.Occurrences = 1
+			d.pending = output
+			flushTimer.Reset(d.window)
+		case <-flushTimer.C:
+			d.flush()
+		}
+	}
+}
+
+// flush sends the pending run, if any.
+func (d *deduplicator) flush() {
+	if d.pending != nil {
+		d.outputChan <- d.pending
+		d.pending = nil
+	}
+}
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newDedupOutput(content string) *message.Message {
+	output := message.NewMessage([]byte(content), nil, "")
+	output.RawDataLen = len(content) + 1
+	return output
+}
+
+func TestDeduplicatorCollapsesIdenticalConsecutiveOutputs(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	d := newDeduplicator(outputChan, time.Hour)
+	d.start()
+
+	for _, content := range []string{"foo", "foo", "foo", "bar", "foo", "foo"} {
+		d.inputChan <- newDedupOutput(content)
+	}
+	close(d.inputChan)
+
+	var contents []string
+	var occurrences, rawDataLens []int
+	for output := range outputChan {
+		contents = append(contents, string(output.Content))
+		occurrences = append(occurrences, output.Occurrences)
+		rawDataLens = append(rawDataLens, output.RawDataLen)
+	}
+	assert.Equal(t, []string{"foo", "bar", "foo"}, contents)
+	assert.Equal(t, []int{3, 1, 2}, occurrences)
+	// the offsets of the duplicates are not lost.
+	assert.Equal(t, []int{12, 4, 8}, rawDataLens)
+}
+
+func TestDeduplicatorSendsTheRunsOnceTheWindowExpires(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	d := newDeduplicator(outputChan, 10*time.Millisecond)
+	d.start()
+	defer close(d.inputChan)
+
+	d.inputChan <- newDedupOutput("foo")
+	d.inputChan <- newDedupOutput("foo")
+	select {
+	case output := <-outputChan:
+		assert.Equal(t, "foo", string(output.Content))
+		assert.Equal(t, 2, output.Occurrences)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the run should be sent once the window expires")
+	}
+
+	// a new run starts after the window.
+	d.inputChan <- newDedupOutput("foo")
+	select {
+	case output := <-outputChan:
+		assert.Equal(t, 1, output.Occurrences)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the run should be sent once the window expires")
+	}
+}
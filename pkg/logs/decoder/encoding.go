@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+	"sync"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// rawLenLimitFactor is the number of times the length limit a line in its source encoding
+// can reach before it is split, whatever the length of its UTF-8 representation.
+const rawLenLimitFactor = 4
+
+// Byte order marks of the encodings that can be detected.
+var (
+	utf16leBOM = []byte{0xFF, 0xFE}
+	utf16beBOM = []byte{0xFE, 0xFF}
+)
+
+// DetectEncoding returns the encoding of the data starting with head
+// if it starts with a byte order mark, or an empty string otherwise.
+func DetectEncoding(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, utf16leBOM):
+		return config.UTF16LE
+	case bytes.HasPrefix(head, utf16beBOM):
+		return config.UTF16BE
+	default:
+		return ""
+	}
+}
+
+// transcodedLine is a line, or a chunk of line, sent to the line handler once transcoded to UTF-8.
+type transcodedLine struct {
+	// len is the length the line handler accounts for the line.
+	len int
+	// rawLen is the length of the line in its source encoding.
+	rawLen int
+}
+
+// transcoder transcodes lines to UTF-8 and keeps track of their length in their source encoding,
+// so that the outputs of the line handler can be given their length in the source encoding too.
+type transcoder struct {
+	decoder *encoding.Decoder
+	// newLine is the end of line sequence in the source encoding, one or two bytes long.
+	newLine []byte
+	lines   []transcodedLine
+	// skippedLen is the length of the blank lines skipped since the last line sent.
+	skippedLen int
+	mu         sync.Mutex
+}
+
+// newTranscoder returns a transcoder from enc, or nil if enc is not supported.
+func newTranscoder(enc string) *transcoder {
+	switch enc {
+	case config.UTF16LE:
+		return &transcoder{
+			decoder: unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder(),
+			newLine: []byte{'\n', 0},
+		}
+	case config.UTF16BE:
+		return &transcoder{
+			decoder: unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder(),
+			newLine: []byte{0, '\n'},
+		}
+	case config.ShiftJIS:
+		// '\n' is never part of a multi-byte character in SHIFT-JIS.
+		return &transcoder{
+			decoder: japanese.ShiftJIS.NewDecoder(),
+			newLine: []byte{'\n'},
+		}
+	default:
+		return nil
+	}
+}
+
+// isEndOfLine returns true if the data made of buffered followed by pending
+// ends with an end of line sequence aligned on the characters of the encoding.
+func (t *transcoder) isEndOfLine(buffered, pending []byte) bool {
+	n := len(buffered) + len(pending)
+	if n%len(t.newLine) != 0 || n < len(t.newLine) {
+		return false
+	}
+	for i := range t.newLine {
+		// the i-th byte of the sequence is the k-th byte of the data
+		k := n - len(t.newLine) + i
+		var b byte
+		if k < len(buffered) {
+			b = buffered[k]
+		} else {
+			b = pending[k-len(buffered)]
+		}
+		if b != t.newLine[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// transcode returns the UTF-8 representation of line.
+func (t *transcoder) transcode(line []byte) []byte {
+	content, err := t.decoder.Bytes(line)
+	if err != nil {
+		// invalid sequences are replaced, this should not happen,
+		// the line is copied as the line handlers take ownership of their contents
+		return append([]byte(nil), line...)
+	}
+	return content
+}
+
+// add records that a chunk of content of the given length in its source encoding
+// is about to be sent to the line handler.
+func (t *transcoder) add(content []byte, rawLen int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// the line handlers account for the end of line in addition to the content of the lines
+	t.lines = append(t.lines, transcodedLine{len: len(content) + 1, rawLen: rawLen + t.skippedLen})
+	t.skippedLen = 0
+}
+
+// skip records that a line of the given length in its source encoding is not sent to the line handler,
+// its length is accounted to the next line.
+func (t *transcoder) skip(rawLen int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.skippedLen += rawLen
+}
+
+// rawDataLen returns the length in the source encoding of the lines
+// an output of the line handler of length rawDataLen has been made from.
+func (t *transcoder) rawDataLen(rawDataLen int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	length, rawLen := 0, 0
+	for length < rawDataLen && len(t.lines) > 0 {
+		length += t.lines[0].len
+		rawLen += t.lines[0].rawLen
+		t.lines = t.lines[1:]
+	}
+	return rawLen
+}
@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/parser"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, content string) []byte {
+	encoded, err := enc.NewEncoder().Bytes([]byte(content))
+	assert.Nil(t, err)
+	return encoded
+}
+
+func TestDetectEncoding(t *testing.T) {
+	assert.Equal(t, config.UTF16LE, DetectEncoding([]byte{0xFF, 0xFE, 'h', 0}))
+	assert.Equal(t, config.UTF16BE, DetectEncoding([]byte{0xFE, 0xFF, 0, 'h'}))
+	assert.Equal(t, "", DetectEncoding([]byte("hello")))
+	assert.Equal(t, "", DetectEncoding([]byte{0xFF}))
+	assert.Equal(t, "", DetectEncoding(nil))
+}
+
+func TestTranscoderIsEndOfLine(t *testing.T) {
+	le := newTranscoder(config.UTF16LE)
+	assert.True(t, le.isEndOfLine([]byte{'a', 0}, []byte{'\n', 0}))
+	assert.True(t, le.isEndOfLine([]byte{'a', 0, '\n'}, []byte{0}))
+	assert.False(t, le.isEndOfLine([]byte{'a', 0, '\n'}, nil))
+	// U+0A01 U+0100 contains the end of line sequence across two characters
+	assert.False(t, le.isEndOfLine(nil, []byte{0x01, '\n', 0}))
+
+	be := newTranscoder(config.UTF16BE)
+	assert.True(t, be.isEndOfLine([]byte{0, 'a'}, []byte{0, '\n'}))
+	assert.False(t, be.isEndOfLine([]byte{'a', 0}, []byte{'\n'}))
+
+	sjis := newTranscoder(config.ShiftJIS)
+	assert.True(t, sjis.isEndOfLine([]byte("a"), []byte("\n")))
+	assert.False(t, sjis.isEndOfLine([]byte("a"), nil))
+
+	assert.Nil(t, newTranscoder(""))
+	assert.Nil(t, newTranscoder("latin-1"))
+}
+
+func TestDecoderTranscodesUTF16LE(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	d := InitializeDecoderWithEncoding(source, parser.NoopParser, config.UTF16LE)
+	d.Start()
+	defer d.Stop()
+
+	bom := []byte{0xFF, 0xFE}
+	first := append(bom, encode(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), "hello wörld\r\n")...)
+	second := encode(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), "\nਁĀ bye\n")
+
+	// split the data in the middle of the end of line sequence
+	data := append(first, second...)
+	d.InputChan <- NewInput(data[:len(first)-1])
+	d.InputChan <- NewInput(data[len(first)-1:])
+
+	msg := <-d.OutputChan
+	assert.Equal(t, "hello wörld", string(msg.Content))
+	assert.Equal(t, len(first), msg.RawDataLen)
+
+	// the blank line is accounted to the next line
+	msg = <-d.OutputChan
+	assert.Equal(t, "ਁĀ bye", string(msg.Content))
+	assert.Equal(t, len(second), msg.RawDataLen)
+}
+
+func TestDecoderTranscodesUTF16BE(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	d := InitializeDecoderWithEncoding(source, parser.NoopParser, config.UTF16BE)
+	d.Start()
+	defer d.Stop()
+
+	line := encode(t, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), "hello world\n")
+	d.InputChan <- NewInput(append(line, line...))
+
+	for i := 0; i < 2; i++ {
+		msg := <-d.OutputChan
+		assert.Equal(t, "hello world", string(msg.Content))
+		assert.Equal(t, len(line), msg.RawDataLen)
+	}
+}
+
+func TestDecoderTranscodesShiftJIS(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Encoding: config.ShiftJIS})
+	d := InitializeDecoder(source, parser.NoopParser)
+	d.Start()
+	defer d.Stop()
+
+	line := encode(t, japanese.ShiftJIS, "こんにちは世界\n")
+	d.InputChan <- NewInput(line)
+
+	msg := <-d.OutputChan
+	assert.Equal(t, "こんにちは世界", string(msg.Content))
+	assert.Equal(t, len(line), msg.RawDataLen)
+}
+
+func TestDecoderTranscodesAndSplitsLongLines(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	d := InitializeDecoderWithEncoding(source, parser.NoopParser, config.UTF16LE)
+	d.lenLimit = 10
+	d.lineHandler = NewSingleLineHandler(d.handlerOutputChan, 10, false, parser.NoopParser)
+	d.Start()
+	defer d.Stop()
+
+	line := encode(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), "0123456789abc\n")
+	d.InputChan <- NewInput(line)
+
+	msg := <-d.OutputChan
+	assert.Equal(t, "0123456789...TRUNCATED...", string(msg.Content))
+	assert.Equal(t, 0, msg.RawDataLen)
+
+	// the length of the whole line is accounted to its last chunk
+	msg = <-d.OutputChan
+	assert.Equal(t, "...TRUNCATED...abc", string(msg.Content))
+	assert.Equal(t, len(line), msg.RawDataLen)
+}
@@ -7,6 +7,8 @@ package decoder
 
 import (
 	"bytes"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
 // LineBuffer accumulates lines in buffer escaping all '\n'
@@ -55,9 +57,9 @@ func (l *LineBuffer) AddTruncate(line []byte) {
 	l.buffer.Write(TRUNCATED)
 }
 
-// Content returns the content in buffer and the length of the data that enabled to compute this content
+// Content returns the content in buffer, copied to a pooled buffer, and the length of the data that enabled to compute this content
 func (l *LineBuffer) Content() ([]byte, int) {
-	content := make([]byte, l.buffer.Len())
+	content := message.GetBuffer(l.buffer.Len())
 	copy(content, l.buffer.Bytes())
 	return content, l.rawDataLen
 }
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/parser"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
@@ -18,7 +19,8 @@ import (
 // TRUNCATED is the warning we add at the beginning or/and at the end of a truncated message
 var TRUNCATED = []byte("...TRUNCATED...")
 
-// LineHandler handles byte slices to form line output
+// LineHandler handles byte slices to form line output,
+// it takes ownership of content which can be a pooled buffer returned to the pool once not used anymore.
 type LineHandler interface {
 	Handle(content []byte)
 	Start()
@@ -30,14 +32,23 @@ type SingleLineHandler struct {
 	lineChan       chan []byte
 	outputChan     chan *message.Message
 	shouldTruncate bool
-	parser         parser.Parser
+	// lenLimit is the length of the chunks the lines above it are split into by the decoder.
+	lenLimit int
+	// truncate drops the chunks following the first one of a line instead of sending them.
+	truncate bool
+	// droppedLen is the length of the chunks dropped since the last output.
+	droppedLen int
+	parser     parser.Parser
 }
 
-// NewSingleLineHandler returns a new SingleLineHandler
-func NewSingleLineHandler(outputChan chan *message.Message, parser parser.Parser) *SingleLineHandler {
+// NewSingleLineHandler returns a new SingleLineHandler handling lines split in chunks of lenLimit bytes,
+// the chunks following the first one of a line are dropped if truncate is set and sent otherwise.
+func NewSingleLineHandler(outputChan chan *message.Message, lenLimit int, truncate bool, parser parser.Parser) *SingleLineHandler {
 	return &SingleLineHandler{
 		lineChan:   make(chan []byte),
 		outputChan: outputChan,
+		lenLimit:   lenLimit,
+		truncate:   truncate,
 		parser:     parser,
 	}
 }
@@ -70,12 +81,26 @@ func (h *SingleLineHandler) run() {
 // When lines are too long, they are truncated
 func (h *SingleLineHandler) process(line []byte) {
 	lineLen := len(line)
+	buffer := line
+	if h.shouldTruncate && h.truncate {
+		// drop the rest of the line, its length is added to the next output to keep the offsets right.
+		h.droppedLen += lineLen
+		if lineLen < h.lenLimit {
+			h.droppedLen++
+			h.shouldTruncate = false
+		}
+		message.PutBuffer(buffer)
+		return
+	}
 	line = bytes.TrimSpace(line)
 	if len(line) == 0 {
+		h.drop(lineLen)
+		message.PutBuffer(buffer)
 		return
 	}
 
 	var content []byte
+	isContinuation := h.shouldTruncate
 	if h.shouldTruncate {
 		// add TRUNCATED at the beginning of content
 		content = append(TRUNCATED, line...)
@@ -85,7 +110,7 @@ func (h *SingleLineHandler) process(line []byte) {
 		content = line
 	}
 
-	if lineLen < contentLenLimit {
+	if lineLen < h.lenLimit {
 		// send content
 		// add 1 to take into account '\n' that we didn't include in content
 		output, err := h.parser.Parse(content)
@@ -93,8 +118,12 @@ func (h *SingleLineHandler) process(line []byte) {
 			log.Debug(err)
 		}
 		if output != nil && len(output.Content) > 0 {
-			output.RawDataLen = lineLen + 1
+			output.RawDataLen = lineLen + 1 + h.droppedLen
+			output.SetBuffer(buffer)
+			h.droppedLen = 0
 			h.outputChan <- output
+		} else {
+			h.drop(lineLen)
 		}
 	} else {
 		// add TRUNCATED at the end of content and send it
@@ -104,13 +133,28 @@ func (h *SingleLineHandler) process(line []byte) {
 			log.Debug(err)
 		}
 		if output != nil && len(output.Content) > 0 {
-			output.RawDataLen = lineLen
+			output.RawDataLen = lineLen + h.droppedLen
+			output.SetBuffer(buffer)
+			h.droppedLen = 0
+			if !isContinuation {
+				metrics.LogsTruncated.Add(1)
+			}
 			h.outputChan <- output
 			h.shouldTruncate = true
 		}
 	}
 }
 
+// drop accounts the length of a line that did not produce any output to the next output
+// to keep the offsets right, e.g. a blank line or a part of a line waiting for the next parts.
+func (h *SingleLineHandler) drop(lineLen int) {
+	h.droppedLen += lineLen
+	if lineLen < h.lenLimit {
+		// add 1 to take into account '\n' that we didn't include in content
+		h.droppedLen++
+	}
+}
+
 // defaultFlushTimeout represents the time we want to wait before flushing lineBuffer
 // when no more line is received
 const defaultFlushTimeout = 1000 * time.Millisecond
@@ -123,17 +167,20 @@ type MultiLineHandler struct {
 	lineBuffer   *LineBuffer
 	newContentRe *regexp.Regexp
 	flushTimeout time.Duration
-	parser       parser.Parser
+	// lenLimit is the length above which the content is truncated and sent.
+	lenLimit int
+	parser   parser.Parser
 }
 
-// NewMultiLineHandler returns a new MultiLineHandler
-func NewMultiLineHandler(outputChan chan *message.Message, newContentRe *regexp.Regexp, flushTimeout time.Duration, parser parser.Parser) *MultiLineHandler {
+// NewMultiLineHandler returns a new MultiLineHandler that aggregates lines into contents of at most lenLimit bytes
+func NewMultiLineHandler(outputChan chan *message.Message, newContentRe *regexp.Regexp, flushTimeout time.Duration, lenLimit int, parser parser.Parser) *MultiLineHandler {
 	return &MultiLineHandler{
 		lineChan:     make(chan []byte),
 		outputChan:   outputChan,
 		lineBuffer:   NewLineBuffer(),
 		newContentRe: newContentRe,
 		flushTimeout: flushTimeout,
+		lenLimit:     lenLimit,
 		parser:       parser,
 	}
 }
@@ -178,6 +225,8 @@ func (h *MultiLineHandler) run() {
 				}
 			}
 			h.process(line)
+			// the line has been copied to lineBuffer
+			message.PutBuffer(line)
 			flushTimer.Reset(h.flushTimeout)
 		case <-flushTimer.C:
 			// the timout expired, the content is ready to be sent
@@ -203,11 +252,12 @@ func (h *MultiLineHandler) process(line []byte) {
 		// add '\n' to content in lineBuffer
 		h.lineBuffer.AddEndOfLine()
 	}
-	if len(line)+h.lineBuffer.Length() < contentLenLimit {
+	if len(line)+h.lineBuffer.Length() < h.lenLimit {
 		// add line to content in lineBuffer
 		h.lineBuffer.Add(line)
 	} else {
 		// add line and truncate and flush content in lineBuffer
+		metrics.LogsTruncated.Add(1)
 		h.lineBuffer.AddIncompleteLine(line)
 		h.lineBuffer.AddTruncate(line)
 		// send content from lineBuffer
@@ -221,15 +271,19 @@ func (h *MultiLineHandler) process(line []byte) {
 func (h *MultiLineHandler) sendContent() {
 	defer h.lineBuffer.Reset()
 	content, rawDataLen := h.lineBuffer.Content()
+	buffer := content
 	content = bytes.TrimSpace(content)
-	if len(content) > 0 {
-		output, err := h.parser.Parse(content)
-		if err != nil {
-			log.Debug(err)
-		}
-		if output != nil && len(output.Content) > 0 {
-			output.RawDataLen = rawDataLen
-			h.outputChan <- output
-		}
+	if len(content) == 0 {
+		message.PutBuffer(buffer)
+		return
+	}
+	output, err := h.parser.Parse(content)
+	if err != nil {
+		log.Debug(err)
+	}
+	if output != nil && len(output.Content) > 0 {
+		output.RawDataLen = rawDataLen
+		output.SetBuffer(buffer)
+		h.outputChan <- output
 	}
 }
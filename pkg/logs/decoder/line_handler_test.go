@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/parser"
 	"github.com/stretchr/testify/assert"
 )
@@ -86,7 +87,7 @@ func (u *MockFailingParser) Unwrap(line []byte) ([]byte, error) {
 
 func TestSingleLineHandler(t *testing.T) {
 	outputChan := make(chan *message.Message, 10)
-	h := NewSingleLineHandler(outputChan, parser.NoopParser)
+	h := NewSingleLineHandler(outputChan, contentLenLimit, false, parser.NoopParser)
 	h.Start()
 
 	var output *message.Message
@@ -108,7 +109,8 @@ func TestSingleLineHandler(t *testing.T) {
 	h.Handle([]byte(line))
 	output = <-outputChan
 	assert.Equal(t, len(line)+len(TRUNCATED), len(output.Content))
-	assert.Equal(t, len(line), output.RawDataLen)
+	// the end of the empty line is accounted too
+	assert.Equal(t, 1+len(line), output.RawDataLen)
 
 	line = strings.Repeat("a", contentLenLimit+10)
 	h.Handle([]byte(line))
@@ -125,9 +127,52 @@ func TestSingleLineHandler(t *testing.T) {
 	h.Stop()
 }
 
+func TestSingleLineHandlerTruncatesOversizedLines(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	h := NewSingleLineHandler(outputChan, 10, true, parser.NoopParser)
+	h.Start()
+	truncated := metrics.LogsTruncated.Value()
+
+	// the decoder splits the lines in chunks of at most 10 bytes.
+	h.Handle([]byte("aaaaaaaaaa"))
+	output := <-outputChan
+	assert.Equal(t, "aaaaaaaaaa"+string(TRUNCATED), string(output.Content))
+	assert.Equal(t, 10, output.RawDataLen)
+
+	// the rest of the line is dropped.
+	h.Handle([]byte("bbbbbbbbbb"))
+	h.Handle([]byte("ccc"))
+	h.Handle([]byte("hello"))
+	output = <-outputChan
+	assert.Equal(t, "hello", string(output.Content))
+	// the dropped bytes are accounted for in the next output.
+	assert.Equal(t, 10+3+1+5+1, output.RawDataLen)
+	assert.Equal(t, 0, len(outputChan))
+	assert.Equal(t, truncated+1, metrics.LogsTruncated.Value())
+
+	h.Stop()
+}
+
+func TestSingleLineHandlerCountsSplitLinesOnce(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	h := NewSingleLineHandler(outputChan, 10, false, parser.NoopParser)
+	h.Start()
+	truncated := metrics.LogsTruncated.Value()
+
+	h.Handle([]byte("aaaaaaaaaa"))
+	h.Handle([]byte("bbbbbbbbbb"))
+	h.Handle([]byte("ccc"))
+	assert.Equal(t, "aaaaaaaaaa"+string(TRUNCATED), string((<-outputChan).Content))
+	assert.Equal(t, string(TRUNCATED)+"bbbbbbbbbb"+string(TRUNCATED), string((<-outputChan).Content))
+	assert.Equal(t, string(TRUNCATED)+"ccc", string((<-outputChan).Content))
+	assert.Equal(t, truncated+1, metrics.LogsTruncated.Value())
+
+	h.Stop()
+}
+
 func TestTrimSingleLine(t *testing.T) {
 	outputChan := make(chan *message.Message, 10)
-	h := NewSingleLineHandler(outputChan, parser.NoopParser)
+	h := NewSingleLineHandler(outputChan, contentLenLimit, false, parser.NoopParser)
 	h.Start()
 
 	var output *message.Message
@@ -146,7 +191,7 @@ func TestTrimSingleLine(t *testing.T) {
 func TestMultiLineHandler(t *testing.T) {
 	re := regexp.MustCompile("[0-9]+\\.")
 	outputChan := make(chan *message.Message, 10)
-	h := NewMultiLineHandler(outputChan, re, 100*time.Millisecond, parser.NoopParser)
+	h := NewMultiLineHandler(outputChan, re, 100*time.Millisecond, contentLenLimit, parser.NoopParser)
 	h.Start()
 
 	var output *message.Message
@@ -199,7 +244,7 @@ func TestMultiLineHandler(t *testing.T) {
 func TestTrimMultiLine(t *testing.T) {
 	re := regexp.MustCompile("[0-9]+\\.")
 	outputChan := make(chan *message.Message, 10)
-	h := NewMultiLineHandler(outputChan, re, 10*time.Millisecond, parser.NoopParser)
+	h := NewMultiLineHandler(outputChan, re, 10*time.Millisecond, contentLenLimit, parser.NoopParser)
 	h.Start()
 
 	var output *message.Message
@@ -225,7 +270,7 @@ func TestUnwrapMultiLine(t *testing.T) {
 
 	re := regexp.MustCompile("[0-9]+\\.")
 	outputChan := make(chan *message.Message, 10)
-	h := NewMultiLineHandler(outputChan, re, 10*time.Millisecond, NewMockUnwrapper(header))
+	h := NewMultiLineHandler(outputChan, re, 10*time.Millisecond, contentLenLimit, NewMockUnwrapper(header))
 	h.Start()
 
 	var output *message.Message
@@ -259,7 +304,7 @@ func TestUnwrapMultiLine(t *testing.T) {
 func TestSingleLineHandlerDropsEmptyMessages(t *testing.T) {
 	const header = "HEADER"
 	outputChan := make(chan *message.Message, 10)
-	h := NewSingleLineHandler(outputChan, NewMockParser(header))
+	h := NewSingleLineHandler(outputChan, contentLenLimit, false, NewMockParser(header))
 	h.Start()
 
 	line := header
@@ -272,11 +317,27 @@ func TestSingleLineHandlerDropsEmptyMessages(t *testing.T) {
 	assert.Equal(t, "one message", string(output.Content))
 }
 
+func TestSingleLineHandlerAccountsDroppedLinesToTheNextOutput(t *testing.T) {
+	const header = "HEADER"
+	outputChan := make(chan *message.Message, 10)
+	h := NewSingleLineHandler(outputChan, contentLenLimit, false, NewMockParser(header))
+	h.Start()
+
+	h.Handle([]byte(header))
+	h.Handle([]byte(whitespace))
+	h.Handle([]byte(header + "one message"))
+
+	output := <-outputChan
+	assert.Equal(t, "one message", string(output.Content))
+	assert.Equal(t, len(header)+1+len(whitespace)+1+len(header+"one message")+1, output.RawDataLen)
+	h.Stop()
+}
+
 func TestMultiLineHandlerDropsEmptyMessages(t *testing.T) {
 	const header = "HEADER"
 	outputChan := make(chan *message.Message, 10)
 	re := regexp.MustCompile("[0-9]+\\.")
-	h := NewMultiLineHandler(outputChan, re, 10*time.Millisecond, NewMockParser(header))
+	h := NewMultiLineHandler(outputChan, re, 10*time.Millisecond, contentLenLimit, NewMockParser(header))
 	h.Start()
 
 	h.Handle([]byte(header))
@@ -293,7 +354,7 @@ func TestMultiLineHandlerDropsEmptyMessages(t *testing.T) {
 func TestSingleLineHandlerSendsRawInvalidMessages(t *testing.T) {
 	const header = "HEADER"
 	outputChan := make(chan *message.Message, 10)
-	h := NewSingleLineHandler(outputChan, NewMockFailingParser(header))
+	h := NewSingleLineHandler(outputChan, contentLenLimit, false, NewMockFailingParser(header))
 	h.Start()
 
 	h.Handle([]byte("one message"))
@@ -308,7 +369,7 @@ func TestMultiLineHandlerSendsRawInvalidMessages(t *testing.T) {
 	const header = "HEADER"
 	outputChan := make(chan *message.Message, 10)
 	re := regexp.MustCompile("[0-9]+\\.")
-	h := NewMultiLineHandler(outputChan, re, 10*time.Millisecond, NewMockFailingParser(header))
+	h := NewMultiLineHandler(outputChan, re, 10*time.Millisecond, contentLenLimit, NewMockFailingParser(header))
 	h.Start()
 
 	h.Handle([]byte("1.third line"))
@@ -319,3 +380,22 @@ func TestMultiLineHandlerSendsRawInvalidMessages(t *testing.T) {
 	output = <-outputChan
 	assert.Equal(t, "1.third line\\nfourth line", string(output.Content))
 }
+
+func TestMultiLineHandlerLenLimit(t *testing.T) {
+	re := regexp.MustCompile("[0-9]+\\.")
+	outputChan := make(chan *message.Message, 10)
+	h := NewMultiLineHandler(outputChan, re, 10*time.Millisecond, 30, parser.NoopParser)
+	h.Start()
+
+	// the content is sent as soon as it reaches the limit, the rest of the log is truncated too.
+	h.Handle([]byte("1. first line"))
+	h.Handle([]byte("a long second line"))
+	output := <-outputChan
+	assert.Equal(t, "1. first line\\na long second line"+string(TRUNCATED), string(output.Content))
+
+	h.Handle([]byte("third"))
+	output = <-outputChan
+	assert.Equal(t, string(TRUNCATED)+"third", string(output.Content))
+
+	h.Stop()
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package diagnostic
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// listenerBufferSize is the number of messages buffered for a listener, the messages
+// a listener can not keep up with are dropped to not slow down the pipelines.
+const listenerBufferSize = 100
+
+// MessageReceiver handles the messages processed by the pipelines.
+type MessageReceiver interface {
+	HandleMessage(msg *message.Message, redactedMsg []byte)
+}
+
+// NoopMessageReceiver discards the messages.
+var NoopMessageReceiver MessageReceiver = &noopMessageReceiver{}
+
+type noopMessageReceiver struct{}
+
+// HandleMessage does nothing
+func (r *noopMessageReceiver) HandleMessage(msg *message.Message, redactedMsg []byte) {}
+
+// Filters select the messages streamed to a listener, the empty filters match all the messages.
+type Filters struct {
+	Name    string
+	Type    string
+	Source  string
+	Service string
+}
+
+// match returns true if the message matches all the filters.
+func (f Filters) match(msg *message.Message) bool {
+	source := msg.Origin.LogSource
+	return (f.Name == "" || f.Name == source.Name) &&
+		(f.Type == "" || f.Type == source.Config.Type) &&
+		(f.Source == "" || f.Source == msg.Origin.Source()) &&
+		(f.Service == "" || f.Service == msg.Origin.Service())
+}
+
+// listener receives the formatted messages matching its filters.
+type listener struct {
+	filters Filters
+	lines   chan string
+}
+
+// BufferedMessageReceiver streams the messages processed by the pipelines to its listeners,
+// it does nothing as long as there is no listener.
+type BufferedMessageReceiver struct {
+	hasListeners int32
+	mu           sync.Mutex
+	listeners    map[*listener]struct{}
+	stopped      bool
+}
+
+// NewBufferedMessageReceiver returns an initialized BufferedMessageReceiver
+func NewBufferedMessageReceiver() *BufferedMessageReceiver {
+	return &BufferedMessageReceiver{
+		listeners: make(map[*listener]struct{}),
+	}
+}
+
+// Listen returns the channel of the messages matching filters and the function to call to stop listening,
+// the channel is closed once the function is called or the receiver is stopped.
+func (r *BufferedMessageReceiver) Listen(filters Filters) (<-chan string, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l := &listener{
+		filters: filters,
+		lines:   make(chan string, listenerBufferSize),
+	}
+	if r.stopped {
+		close(l.lines)
+		return l.lines, func() {}
+	}
+	r.listeners[l] = struct{}{}
+	atomic.StoreInt32(&r.hasListeners, 1)
+	return l.lines, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.remove(l)
+	}
+}
+
+// Stop closes the channels of all the listeners.
+func (r *BufferedMessageReceiver) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for l := range r.listeners {
+		r.remove(l)
+	}
+	r.stopped = true
+}
+
+// remove closes the channel of a listener, the lock must be held.
+func (r *BufferedMessageReceiver) remove(l *listener) {
+	if _, exists := r.listeners[l]; !exists {
+		return
+	}
+	delete(r.listeners, l)
+	close(l.lines)
+	if len(r.listeners) == 0 {
+		atomic.StoreInt32(&r.hasListeners, 0)
+	}
+}
+
+// HandleMessage sends a message to the listeners it matches, redactedMsg is its content once the
+// processing rules were applied.
+func (r *BufferedMessageReceiver) HandleMessage(msg *message.Message, redactedMsg []byte) {
+	if atomic.LoadInt32(&r.hasListeners) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var line string
+	for l := range r.listeners {
+		if !l.filters.match(msg) {
+			continue
+		}
+		if line == "" {
+			line = format(msg, redactedMsg)
+		}
+		select {
+		case l.lines <- line:
+		default:
+			// the listener can not keep up
+		}
+	}
+}
+
+// format returns a human readable representation of a message.
+func format(msg *message.Message, redactedMsg []byte) string {
+	source := msg.Origin.LogSource
+	return fmt.Sprintf("Integration Name: %s | Type: %s | Status: %s | Service: %s | Source: %s | Tags: %s | %s",
+		source.Name, source.Config.Type, msg.GetStatus(), msg.Origin.Service(), msg.Origin.Source(), strings.Join(msg.Origin.Tags(), ","), redactedMsg)
+}
@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package diagnostic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newMessage(content string, cfg *config.LogsConfig) *message.Message {
+	return message.NewMessage([]byte(content), message.NewOrigin(config.NewLogSource("nginx", cfg)), message.StatusInfo)
+}
+
+func TestBufferedMessageReceiverStreamsTheMatchingMessages(t *testing.T) {
+	receiver := NewBufferedMessageReceiver()
+	all, stopAll := receiver.Listen(Filters{})
+	defer stopAll()
+	files, stopFiles := receiver.Listen(Filters{Type: config.FileType, Service: "web"})
+	defer stopFiles()
+
+	msg := newMessage("password=secret", &config.LogsConfig{Type: config.FileType, Service: "web", Source: "nginx", Tags: []string{"env:prod"}})
+	receiver.HandleMessage(msg, []byte("password=xxxxxx"))
+	receiver.HandleMessage(newMessage("hello", &config.LogsConfig{Type: config.DockerType}), []byte("hello"))
+
+	line := "Integration Name: nginx | Type: file | Status: info | Service: web | Source: nginx | Tags: env:prod | password=xxxxxx"
+	assert.Equal(t, line, <-all)
+	assert.Contains(t, <-all, "Type: docker")
+	assert.Equal(t, line, <-files)
+	assert.Len(t, files, 0)
+}
+
+func TestBufferedMessageReceiverDropsTheMessagesListenersCanNotKeepUpWith(t *testing.T) {
+	receiver := NewBufferedMessageReceiver()
+	lines, stop := receiver.Listen(Filters{})
+	defer stop()
+
+	msg := newMessage("hello", &config.LogsConfig{})
+	for i := 0; i < listenerBufferSize+10; i++ {
+		receiver.HandleMessage(msg, msg.Content)
+	}
+	assert.Len(t, lines, listenerBufferSize)
+}
+
+func TestBufferedMessageReceiverClosesTheListeners(t *testing.T) {
+	receiver := NewBufferedMessageReceiver()
+	first, stopFirst := receiver.Listen(Filters{})
+	second, _ := receiver.Listen(Filters{})
+
+	stopFirst()
+	_, isOpen := <-first
+	assert.False(t, isOpen)
+	// stopping twice is a no-op
+	stopFirst()
+
+	receiver.Stop()
+	_, isOpen = <-second
+	assert.False(t, isOpen)
+
+	third, _ := receiver.Listen(Filters{})
+	_, isOpen = <-third
+	assert.False(t, isOpen)
+
+	// the messages are discarded once there is no listener
+	msg := newMessage("hello", &config.LogsConfig{})
+	receiver.HandleMessage(msg, msg.Content)
+}
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package hook
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// Stage is a stage of the pipelines the hooks are run at.
+type Stage int
+
+// The stages of the pipelines, in the order the messages go through them.
+const (
+	// PostDecode runs the hooks on the messages decoded by the inputs, before the processing rules are applied.
+	PostDecode Stage = iota
+	// PostProcess runs the hooks on the processed messages before they are encoded,
+	// their content is the redacted content, which is the one encoded once the hooks ran.
+	PostProcess
+	// PreSend runs the hooks on the encoded messages before they are handed to the sender,
+	// their content is the payload sent to the intake.
+	PreSend
+	stageCount
+)
+
+// String returns the name of the stage.
+func (s Stage) String() string {
+	switch s {
+	case PostDecode:
+		return "post_decode"
+	case PostProcess:
+		return "post_process"
+	case PreSend:
+		return "pre_send"
+	default:
+		return fmt.Sprintf("stage_%d", int(s))
+	}
+}
+
+// A Hook observes or mutates a message at a stage of the pipelines, it returns false to drop the message.
+// The hooks are called concurrently by all the pipelines, they must be thread safe and must not block.
+type Hook func(msg *message.Message) bool
+
+// namedHook is a hook registered under a name.
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+var (
+	// mu serializes the registrations, the hooks of each stage are swapped atomically so that running them never locks.
+	mu    sync.Mutex
+	hooks [stageCount]atomic.Value
+)
+
+func init() {
+	for i := range hooks {
+		hooks[i].Store([]namedHook(nil))
+	}
+}
+
+// Register adds hook to the hooks run at stage under name, after the hooks registered before it,
+// so that other components can observe or mutate the logs without changing the pipelines.
+// It can be called at any time, the pipelines run the hook on the next messages.
+func Register(stage Stage, name string, hook Hook) error {
+	if stage < 0 || stage >= stageCount {
+		return fmt.Errorf("unknown pipeline stage %v", stage)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current := hooks[stage].Load().([]namedHook)
+	for _, h := range current {
+		if h.name == name {
+			return fmt.Errorf("a hook named %s is already registered at the %v stage", name, stage)
+		}
+	}
+	updated := append(append([]namedHook(nil), current...), namedHook{name: name, hook: hook})
+	hooks[stage].Store(updated)
+	return nil
+}
+
+// Unregister removes the hook registered at stage under name, if any.
+func Unregister(stage Stage, name string) {
+	if stage < 0 || stage >= stageCount {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current := hooks[stage].Load().([]namedHook)
+	updated := make([]namedHook, 0, len(current))
+	for _, h := range current {
+		if h.name != name {
+			updated = append(updated, h)
+		}
+	}
+	hooks[stage].Store(updated)
+}
+
+// Run runs the hooks of stage on msg in order and returns false as soon as one of them drops it.
+// A hook that panics is skipped, the message goes on to the next hooks.
+func Run(stage Stage, msg *message.Message) bool {
+	for _, h := range hooks[stage].Load().([]namedHook) {
+		if !run(stage, h, msg) {
+			metrics.HookLogsDropped.Add(h.name, 1)
+			return false
+		}
+	}
+	return true
+}
+
+// run runs h on msg and recovers from its panics.
+func run(stage Stage, h namedHook, msg *message.Message) (keep bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("The logs pipeline hook %s panicked at the %v stage: %v", h.name, stage, r)
+			keep = true
+		}
+	}()
+	return h.hook(msg)
+}
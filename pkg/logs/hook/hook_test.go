@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package hook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+func newMessage(content string) *message.Message {
+	return message.NewMessage([]byte(content), message.NewOrigin(config.NewLogSource("", &config.LogsConfig{})), "")
+}
+
+func TestRunWithoutHooks(t *testing.T) {
+	assert.True(t, Run(PostDecode, newMessage("hello")))
+}
+
+func TestRunAppliesTheHooksInOrder(t *testing.T) {
+	assert.NoError(t, Register(PostProcess, "world", func(msg *message.Message) bool {
+		msg.Content = append(msg.Content, " world"...)
+		return true
+	}))
+	defer Unregister(PostProcess, "world")
+	assert.NoError(t, Register(PostProcess, "suffix", func(msg *message.Message) bool {
+		msg.Content = append(msg.Content, '!')
+		return true
+	}))
+	defer Unregister(PostProcess, "suffix")
+
+	msg := newMessage("hello")
+	assert.True(t, Run(PostProcess, msg))
+	assert.Equal(t, "hello world!", string(msg.Content))
+
+	// the hooks only run at their stage.
+	msg = newMessage("hello")
+	assert.True(t, Run(PreSend, msg))
+	assert.Equal(t, "hello", string(msg.Content))
+}
+
+func TestRunStopsAtTheFirstHookDroppingTheMessage(t *testing.T) {
+	assert.NoError(t, Register(PreSend, "drop", func(msg *message.Message) bool {
+		return string(msg.Content) != "secret"
+	}))
+	defer Unregister(PreSend, "drop")
+	called := false
+	assert.NoError(t, Register(PreSend, "observe", func(msg *message.Message) bool {
+		called = true
+		return true
+	}))
+	defer Unregister(PreSend, "observe")
+
+	assert.False(t, Run(PreSend, newMessage("secret")))
+	assert.False(t, called)
+	assert.Equal(t, "1", metrics.HookLogsDropped.Get("drop").String())
+
+	assert.True(t, Run(PreSend, newMessage("hello")))
+	assert.True(t, called)
+}
+
+func TestRunRecoversFromPanickingHooks(t *testing.T) {
+	assert.NoError(t, Register(PostDecode, "panic", func(msg *message.Message) bool {
+		panic("oops")
+	}))
+	defer Unregister(PostDecode, "panic")
+
+	assert.True(t, Run(PostDecode, newMessage("hello")))
+}
+
+func TestRegister(t *testing.T) {
+	hook := func(msg *message.Message) bool { return false }
+	assert.NoError(t, Register(PostDecode, "foo", hook))
+	assert.Error(t, Register(PostDecode, "foo", hook))
+	assert.NoError(t, Register(PreSend, "foo", hook))
+	assert.Error(t, Register(Stage(42), "bar", hook))
+
+	Unregister(PostDecode, "foo")
+	Unregister(PreSend, "foo")
+	assert.True(t, Run(PostDecode, newMessage("hello")))
+	assert.True(t, Run(PreSend, newMessage("hello")))
+	assert.NoError(t, Register(PostDecode, "foo", hook))
+	Unregister(PostDecode, "foo")
+}
+
+func TestStageString(t *testing.T) {
+	assert.Equal(t, "post_decode", PostDecode.String())
+	assert.Equal(t, "post_process", PostProcess.String())
+	assert.Equal(t, "pre_send", PreSend.String())
+	assert.Equal(t, "stage_42", Stage(42).String())
+}
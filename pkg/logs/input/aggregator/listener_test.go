@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+// dial connects to the listener.
+func dial(t *testing.T, listener *Listener) net.Conn {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", listener.listener.Addr().(*net.TCPAddr).Port))
+	assert.NoError(t, err)
+	return conn
+}
+
+func TestListenerReadsTheForwardedLogs(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewListener(pp, config.NewLogSource("", &config.LogsConfig{Type: config.AggregatorType}))
+	listener.Start()
+	defer listener.Stop()
+
+	conn := dial(t, listener)
+	defer conn.Close()
+	conn.Write([]byte("azerty {\"message\":\"hello\",\"hostname\":\"web-1\"}\nazerty not json\n\nazerty {\"message\":\"world\",\"hostname\":\"web-2\"}\n"))
+
+	msg := <-msgChan
+	assert.Equal(t, "hello", string(msg.Content))
+	assert.Equal(t, "web-1", msg.Hostname)
+	// the lines that can not be parsed are dropped.
+	msg = <-msgChan
+	assert.Equal(t, "world", string(msg.Content))
+	assert.Equal(t, "web-2", msg.Hostname)
+}
+
+func TestListenerFailsWithAnInvalidCertificate(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.AggregatorType, TLSCertFile: "missing-cert.pem", TLSKeyFile: "missing-key.pem"})
+	listener := NewListener(mock.NewMockProvider(), source)
+	listener.Start()
+	defer listener.Stop()
+
+	assert.True(t, source.Status.IsError())
+	assert.Nil(t, listener.listener)
+}
@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// The standard fields of the logs encoded in JSON by the forwarding agents, the other fields are attributes.
+const (
+	messageField     = "message"
+	statusField      = "status"
+	timestampField   = "timestamp"
+	hostnameField    = "hostname"
+	serviceField     = "service"
+	sourceField      = "ddsource"
+	tagsField        = "ddtags"
+	occurrencesField = "occurrences"
+)
+
+// errMissingAPIKey is returned when a line is not prefixed with the API key of the forwarding agent.
+var errMissingAPIKey = errors.New("the log is not prefixed with an API key")
+
+// parseLog returns the message of a line sent by a forwarding agent, made of its API key and of the log
+// encoded in JSON. The log keeps the hostname, the metadata, the time and the attributes it was sent with,
+// the API key is dropped since the aggregator sends the logs with its own.
+func parseLog(source *config.LogSource, line []byte) (*message.Message, error) {
+	separator := bytes.IndexByte(line, ' ')
+	if separator < 0 {
+		return nil, errMissingAPIKey
+	}
+	decoder := json.NewDecoder(bytes.NewReader(line[separator+1:]))
+	// keep the numeric attributes as they were sent.
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	content := stringField(fields, messageField)
+	status := stringField(fields, statusField)
+	if status == "" {
+		status = message.StatusInfo
+	}
+	origin := message.NewOrigin(source)
+	origin.SetService(stringField(fields, serviceField))
+	origin.SetSource(stringField(fields, sourceField))
+	if tags := stringField(fields, tagsField); tags != "" {
+		origin.SetTags(strings.Split(tags, ","))
+	}
+	msg := message.NewMessage([]byte(content), origin, status)
+	msg.Hostname = stringField(fields, hostnameField)
+	msg.Occurrences = int(intField(fields, occurrencesField))
+
+	structured := &message.Structured{Message: content}
+	if timestamp := intField(fields, timestampField); timestamp > 0 {
+		structured.Timestamp = time.Unix(0, timestamp*int64(time.Millisecond)).UTC()
+	}
+	for _, field := range []string{messageField, statusField, timestampField, hostnameField, serviceField, sourceField, tagsField, occurrencesField} {
+		delete(fields, field)
+	}
+	if len(fields) > 0 {
+		structured.Attributes = fields
+	}
+	msg.Structured = structured
+	return msg, nil
+}
+
+// stringField returns the field of fields called key if it's a string, an empty string otherwise.
+func stringField(fields map[string]interface{}, key string) string {
+	value, _ := fields[key].(string)
+	return value
+}
+
+// intField returns the field of fields called key if it's an integer, 0 otherwise.
+func intField(fields map[string]interface{}, key string) int64 {
+	number, _ := fields[key].(json.Number)
+	value, _ := number.Int64()
+	return value
+}
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package aggregator
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseLog(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.AggregatorType})
+	line := `azerty {"message":"disk almost full","status":"warn","timestamp":1551441600123,"hostname":"web-1","service":"api","ddsource":"nginx","ddtags":"env:prod,team:logs","occurrences":3,"user":"bob","bytes":12345678901234}`
+
+	msg, err := parseLog(source, []byte(line))
+	assert.NoError(t, err)
+	assert.Equal(t, "disk almost full", string(msg.Content))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, "web-1", msg.Hostname)
+	assert.Equal(t, "api", msg.Origin.Service())
+	assert.Equal(t, "nginx", msg.Origin.Source())
+	assert.Equal(t, []string{"env:prod", "team:logs"}, msg.Origin.Tags())
+	assert.Equal(t, 3, msg.Occurrences)
+	assert.Equal(t, "disk almost full", msg.Structured.Message)
+	assert.Equal(t, time.Date(2019, 3, 1, 12, 0, 0, 123000000, time.UTC), msg.Structured.Timestamp)
+	assert.Equal(t, map[string]interface{}{"user": "bob", "bytes": json.Number("12345678901234")}, msg.Structured.Attributes)
+}
+
+func TestParseLogDefaults(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.AggregatorType, Service: "aggregated"})
+
+	msg, err := parseLog(source, []byte(` {"message":"hello","service":"api"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(msg.Content))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Equal(t, "", msg.Hostname)
+	// the service of the source takes precedence.
+	assert.Equal(t, "aggregated", msg.Origin.Service())
+	assert.Empty(t, msg.Origin.Tags())
+	assert.True(t, msg.Structured.Timestamp.IsZero())
+	assert.Nil(t, msg.Structured.Attributes)
+}
+
+func TestParseLogErrors(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.AggregatorType})
+
+	_, err := parseLog(source, []byte(`{"message":"hello"}`))
+	assert.Equal(t, errMissingAPIKey, err)
+
+	_, err = parseLog(source, []byte(`azerty <13>1 2019-03-01T12:00:00Z web-1 api - - - hello`))
+	assert.Error(t, err)
+}
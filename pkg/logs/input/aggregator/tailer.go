@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package aggregator
+
+import (
+	"bufio"
+	"net"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const (
+	// defaultTimeout represents the time after which a connection is closed when no data is read
+	defaultTimeout = time.Minute
+	// maxLineSize is the maximum size of a log sent by a forwarding agent, its content is 256KB at most
+	// but it can grow once escaped in JSON along with its attributes.
+	maxLineSize = 4 * 1024 * 1024
+)
+
+// Tailer reads the logs sent by a forwarding agent from a connection, one per line.
+type Tailer struct {
+	source     *config.LogSource
+	conn       net.Conn
+	outputChan chan *message.Message
+	done       chan struct{}
+}
+
+// NewTailer returns a new Tailer
+func NewTailer(source *config.LogSource, conn net.Conn, outputChan chan *message.Message) *Tailer {
+	return &Tailer{
+		source:     source,
+		conn:       conn,
+		outputChan: outputChan,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts reading the logs from the connection
+func (t *Tailer) Start() {
+	go t.readForever()
+}
+
+// Stop closes the connection and waits for the logs being read to be forwarded
+func (t *Tailer) Stop() {
+	t.conn.Close()
+	<-t.done
+}
+
+// readForever reads the logs from conn until it's closed, the logs that can not be parsed are dropped.
+func (t *Tailer) readForever() {
+	defer func() {
+		t.conn.Close()
+		close(t.done)
+	}()
+	scanner := bufio.NewScanner(t.conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for {
+		t.conn.SetReadDeadline(time.Now().Add(defaultTimeout))
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil && !isClosedConnError(err) {
+				log.Warnf("Couldn't read logs from forwarding agent %s: %v", t.conn.RemoteAddr(), err)
+				t.source.Status.Error(err)
+			}
+			return
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		msg, err := parseLog(t.source, line)
+		if err != nil {
+			log.Warnf("Couldn't parse log from forwarding agent %s: %v", t.conn.RemoteAddr(), err)
+			continue
+		}
+		t.outputChan <- msg
+	}
+}
@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"time"
+)
+
+const (
+	// maxEventAge is the time after which an event whose EOE record was not received is sent as is.
+	maxEventAge = 2 * time.Second
+	// maxPendingEvents is the number of incomplete events above which the oldest one is sent as is.
+	maxPendingEvents = 128
+)
+
+// An event is made of the records sharing the same identifier.
+type event struct {
+	id      string
+	records []*record
+	// start is the position of the first record of the event in the audit log.
+	start int64
+	// offset is the position following the last record of the event in the audit log.
+	offset int64
+	// committed is the position the log can be read again from once the event is sent, it's before
+	// the first record of the oldest pending event so that it is not skipped after a restart.
+	committed int64
+	received  time.Time
+}
+
+// An assembler reassembles the records of the events, the records of the different events can be interleaved.
+// An event is complete once its EOE record is received, the events made of a single record are complete right away.
+// It's not thread safe.
+type assembler struct {
+	pending map[string]*event
+	// order holds the identifiers of the pending events in the order they were received.
+	order []string
+	// handle is called with each complete event.
+	handle func(*event)
+}
+
+// newAssembler returns an initialized assembler
+func newAssembler(handle func(*event)) *assembler {
+	return &assembler{
+		pending: make(map[string]*event),
+		handle:  handle,
+	}
+}
+
+// add adds a record to its event, start and offset are the positions of the record and following it in the audit log.
+func (a *assembler) add(r *record, start, offset int64, now time.Time) {
+	e, found := a.pending[r.id]
+	if !found {
+		if r.recordType == eoeType {
+			// the event was already sent
+			return
+		}
+		e = &event{id: r.id, start: start, received: now}
+		if !multipartTypes[r.recordType] {
+			e.records, e.offset = []*record{r}, offset
+			a.send(e)
+			return
+		}
+		a.pending[r.id] = e
+		a.order = append(a.order, r.id)
+	}
+	e.offset = offset
+	if r.recordType == eoeType {
+		a.complete(r.id)
+		return
+	}
+	e.records = append(e.records, r)
+	if len(a.order) > maxPendingEvents {
+		a.complete(a.order[0])
+	}
+}
+
+// flush sends the pending events received before maxEventAge.
+func (a *assembler) flush(now time.Time) {
+	for len(a.order) > 0 && now.Sub(a.pending[a.order[0]].received) >= maxEventAge {
+		a.complete(a.order[0])
+	}
+}
+
+// flushAll sends all the pending events.
+func (a *assembler) flushAll() {
+	for len(a.order) > 0 {
+		a.complete(a.order[0])
+	}
+}
+
+// complete sends the pending event id.
+func (a *assembler) complete(id string) {
+	e := a.pending[id]
+	delete(a.pending, id)
+	for i := range a.order {
+		if a.order[i] == id {
+			a.order = append(a.order[:i], a.order[i+1:]...)
+			break
+		}
+	}
+	a.send(e)
+}
+
+// send hands a complete event to handle, the offset to commit does not go past the oldest pending event.
+func (a *assembler) send(e *event) {
+	e.committed = e.offset
+	if len(a.order) > 0 {
+		if start := a.pending[a.order[0]].start; start < e.committed {
+			e.committed = start
+		}
+	}
+	a.handle(e)
+}
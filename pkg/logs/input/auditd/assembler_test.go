@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRecord(recordType, id string) *record {
+	return &record{recordType: recordType, id: id, fields: map[string]string{}}
+}
+
+func TestAssemblerReassemblesEvents(t *testing.T) {
+	var events []*event
+	a := newAssembler(func(e *event) { events = append(events, e) })
+	now := time.Now()
+
+	a.add(newRecord("SYSCALL", "1.000:1"), 0, 10, now)
+	a.add(newRecord("SYSCALL", "1.000:2"), 10, 20, now)
+	a.add(newRecord("CWD", "1.000:1"), 20, 30, now)
+	a.add(newRecord("PATH", "1.000:2"), 30, 40, now)
+	assert.Equal(t, 0, len(events))
+
+	a.add(newRecord(eoeType, "1.000:2"), 40, 50, now)
+	a.add(newRecord(eoeType, "1.000:1"), 50, 60, now)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "1.000:2", events[0].id)
+	assert.Equal(t, []*record{newRecord("SYSCALL", "1.000:2"), newRecord("PATH", "1.000:2")}, events[0].records)
+	assert.Equal(t, int64(50), events[0].offset)
+	assert.Equal(t, "1.000:1", events[1].id)
+	assert.Equal(t, 2, len(events[1].records))
+	assert.Equal(t, int64(60), events[1].offset)
+	assert.Equal(t, 0, len(a.pending))
+	assert.Equal(t, 0, len(a.order))
+}
+
+func TestAssemblerSendsSingleRecordEventsRightAway(t *testing.T) {
+	var events []*event
+	a := newAssembler(func(e *event) { events = append(events, e) })
+
+	a.add(newRecord("USER_LOGIN", "1.000:1"), 0, 10, time.Now())
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, int64(10), events[0].offset)
+
+	// the EOE records of the events already sent are ignored
+	a.add(newRecord(eoeType, "1.000:1"), 10, 20, time.Now())
+	assert.Equal(t, 1, len(events))
+}
+
+func TestAssemblerDoesNotCommitPastThePendingEvents(t *testing.T) {
+	var events []*event
+	a := newAssembler(func(e *event) { events = append(events, e) })
+	now := time.Now()
+
+	a.add(newRecord("SYSCALL", "1.000:1"), 0, 10, now)
+	a.add(newRecord("USER_LOGIN", "1.000:2"), 10, 20, now)
+	a.add(newRecord("SYSCALL", "1.000:3"), 20, 30, now)
+	a.add(newRecord("PATH", "1.000:1"), 30, 40, now)
+	a.add(newRecord("USER_LOGIN", "1.000:4"), 40, 50, now)
+
+	// the single record events are sent right away but the records of the pending events must be read again after a restart.
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, int64(20), events[0].offset)
+	assert.Equal(t, int64(0), events[0].committed)
+	assert.Equal(t, int64(50), events[1].offset)
+	assert.Equal(t, int64(0), events[1].committed)
+
+	a.add(newRecord(eoeType, "1.000:1"), 50, 60, now)
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, int64(20), events[2].committed)
+
+	a.add(newRecord(eoeType, "1.000:3"), 60, 70, now)
+	assert.Equal(t, 4, len(events))
+	assert.Equal(t, int64(70), events[3].committed)
+}
+
+func TestAssemblerFlushesOldEvents(t *testing.T) {
+	var events []*event
+	a := newAssembler(func(e *event) { events = append(events, e) })
+	now := time.Now()
+
+	a.add(newRecord("SYSCALL", "1.000:1"), 0, 10, now)
+	a.add(newRecord("SYSCALL", "1.000:2"), 10, 20, now.Add(time.Second))
+
+	a.flush(now.Add(time.Second))
+	assert.Equal(t, 0, len(events))
+
+	a.flush(now.Add(maxEventAge))
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "1.000:1", events[0].id)
+
+	a.flushAll()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "1.000:2", events[1].id)
+}
+
+func TestAssemblerBoundsPendingEvents(t *testing.T) {
+	var events []*event
+	a := newAssembler(func(e *event) { events = append(events, e) })
+	now := time.Now()
+
+	for i := 0; i <= maxPendingEvents; i++ {
+		a.add(newRecord("SYSCALL", fmt.Sprintf("1.000:%d", i)), int64(i), int64(i+1), now)
+	}
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "1.000:0", events[0].id)
+	assert.Equal(t, maxPendingEvents, len(a.pending))
+}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+)
+
+// Launcher starts a tailer of the audit log for each auditd source with a path
+// and a reader of the audit socket for the other ones.
+type Launcher struct {
+	pipelineProvider pipeline.Provider
+	registry         auditor.Registry
+	sources          chan *config.LogSource
+	readers          []restart.Stoppable
+	stop             chan struct{}
+}
+
+// NewLauncher returns an initialized Launcher
+func NewLauncher(sources *config.LogSources, pipelineProvider pipeline.Provider, registry auditor.Registry) *Launcher {
+	return &Launcher{
+		pipelineProvider: pipelineProvider,
+		registry:         registry,
+		sources:          sources.GetAddedForType(config.AuditdType),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the launcher.
+func (l *Launcher) Start() {
+	go l.run()
+}
+
+// run starts new readers.
+func (l *Launcher) run() {
+	for {
+		select {
+		case source := <-l.sources:
+			reader, err := l.setupReader(source)
+			if err != nil {
+				log.Errorf("Can't collect the audit records of source %s: %v", source.Name, err)
+				continue
+			}
+			l.readers = append(l.readers, reader)
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// setupReader starts a new reader for source.
+func (l *Launcher) setupReader(source *config.LogSource) (restart.Stoppable, error) {
+	outputChan := l.pipelineProvider.PipelineChanForSource(source)
+	if source.Config.Path == "" {
+		reader := NewSocketReader(source, outputChan)
+		return reader, reader.Start()
+	}
+	tailer := NewTailer(source, outputChan)
+	return tailer, tailer.Start(l.registry.GetOffset(tailer.Identifier()))
+}
+
+// Stop stops all readers
+func (l *Launcher) Stop() {
+	l.stop <- struct{}{}
+	stopper := restart.NewParallelStopper()
+	for _, r := range l.readers {
+		stopper.Add(r)
+	}
+	stopper.Stop()
+}
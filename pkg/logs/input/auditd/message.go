@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// attributeFields are the fields of the records promoted to the attributes of the events,
+// the fields of the first record holding them are kept.
+var attributeFields = []string{
+	"syscall", "success", "exit", "exe", "comm", "pid", "ppid", "auid", "uid", "gid", "euid", "ses", "tty",
+	"key", "cwd", "proctitle", "op", "acct", "res", "terminal", "addr", "hostname",
+}
+
+// toMessage converts an event to a message holding its records, one per line, and its main fields as attributes.
+func toMessage(source *config.LogSource, e *event) *message.Message {
+	lines := make([]string, 0, len(e.records))
+	types := make([]string, 0, len(e.records))
+	var paths []string
+	auditd := map[string]interface{}{
+		"id": e.id,
+	}
+	for _, r := range e.records {
+		lines = append(lines, r.raw)
+		types = append(types, r.recordType)
+		if name, exists := r.fields["name"]; r.recordType == "PATH" && exists {
+			paths = append(paths, name)
+		}
+		for _, field := range attributeFields {
+			if _, found := auditd[field]; found {
+				continue
+			}
+			if value, exists := r.fields[field]; exists {
+				auditd[field] = value
+			}
+		}
+	}
+	auditd["types"] = types
+	if len(paths) > 0 {
+		auditd["paths"] = paths
+	}
+
+	content := strings.Join(lines, "\n")
+	msg := message.NewMessage([]byte(content), message.NewOrigin(source), message.StatusInfo)
+	msg.Structured = &message.Structured{
+		Message:    content,
+		Timestamp:  e.records[0].timestamp,
+		Attributes: map[string]interface{}{"auditd": auditd},
+	}
+	return msg
+}
@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestToMessage(t *testing.T) {
+	var records []*record
+	for _, line := range []string{
+		`type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 success=no exit=-13 ppid=2686 pid=3538 auid=1000 uid=1000 comm="cat" exe="/bin/cat" key="sshd_config"`,
+		`type=CWD msg=audit(1364481363.243:24287):  cwd="/home/shadowman"`,
+		`type=PATH msg=audit(1364481363.243:24287): item=0 name="/etc/ssh/sshd_config" inode=409248`,
+	} {
+		r, err := parseRecord([]byte(line))
+		assert.Nil(t, err)
+		records = append(records, r)
+	}
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.AuditdType})
+	msg := toMessage(source, &event{id: "1364481363.243:24287", records: records})
+
+	assert.Equal(t, records[0].raw+"\n"+records[1].raw+"\n"+records[2].raw, string(msg.Content))
+	assert.Equal(t, string(msg.Content), msg.Structured.Message)
+	assert.Equal(t, time.Unix(1364481363, 243*int64(time.Millisecond)), msg.Structured.Timestamp)
+	assert.Equal(t, map[string]interface{}{
+		"auditd": map[string]interface{}{
+			"id":      "1364481363.243:24287",
+			"types":   []string{"SYSCALL", "CWD", "PATH"},
+			"syscall": "2",
+			"success": "no",
+			"exit":    "-13",
+			"exe":     "/bin/cat",
+			"comm":    "cat",
+			"pid":     "3538",
+			"ppid":    "2686",
+			"auid":    "1000",
+			"uid":     "1000",
+			"key":     "sshd_config",
+			"cwd":     "/home/shadowman",
+			"paths":   []string{"/etc/ssh/sshd_config"},
+		},
+	}, msg.Structured.Attributes)
+}
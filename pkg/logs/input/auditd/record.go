@@ -0,0 +1,183 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eoeType is the type of the record ending the events made of several records.
+const eoeType = "EOE"
+
+// multipartTypes are the types of the records emitted by the kernel along with other records of the same event,
+// their events end with an EOE record.
+var multipartTypes = map[string]bool{
+	"SYSCALL":     true,
+	"PATH":        true,
+	"IPC":         true,
+	"SOCKETCALL":  true,
+	"SOCKADDR":    true,
+	"CWD":         true,
+	"EXECVE":      true,
+	"BPRM_FCAPS":  true,
+	"CAPSET":      true,
+	"MMAP":        true,
+	"FD_PAIR":     true,
+	"OBJ_PID":     true,
+	"PROCTITLE":   true,
+	"AVC":         true,
+	"SELINUX_ERR": true,
+}
+
+// hexEncodedFields are the fields whose values are hex encoded when they hold spaces or special characters.
+var hexEncodedFields = map[string]bool{
+	"exe":       true,
+	"comm":      true,
+	"cwd":       true,
+	"name":      true,
+	"proctitle": true,
+	"key":       true,
+	"acct":      true,
+	"cmd":       true,
+}
+
+// A record is a line of the audit log, e.g.
+// type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 success=no exit=-13 ...
+type record struct {
+	recordType string
+	// id identifies the event of the record, it's made of the timestamp and the serial number of the event.
+	id        string
+	timestamp time.Time
+	fields    map[string]string
+	raw       string
+}
+
+// parseRecord parses a line of the audit log.
+func parseRecord(line []byte) (*record, error) {
+	raw := string(bytes.TrimRight(line, "\r\n\x00"))
+	if !strings.HasPrefix(raw, "type=") {
+		return nil, errors.New("missing record type")
+	}
+	end := strings.IndexByte(raw, ' ')
+	if end < 0 {
+		return nil, errors.New("missing record header")
+	}
+	recordType := raw[len("type="):end]
+	header := strings.TrimLeft(raw[end:], " ")
+	if strings.HasPrefix(header, "msg=") {
+		header = header[len("msg="):]
+	}
+	r, err := parseBody(recordType, header)
+	if err != nil {
+		return nil, err
+	}
+	r.raw = raw
+	return r, nil
+}
+
+// parseBody parses the part of a record following its type, as received from the audit socket, e.g.
+// audit(1364481363.243:24287): arch=c000003e syscall=2 success=no exit=-13 ...
+func parseBody(recordType string, body string) (*record, error) {
+	if !strings.HasPrefix(body, "audit(") {
+		return nil, errors.New("missing event identifier")
+	}
+	end := strings.Index(body, "):")
+	if end < 0 {
+		return nil, errors.New("missing end of event identifier")
+	}
+	id := body[len("audit("):end]
+	timestamp, err := parseTimestamp(id)
+	if err != nil {
+		return nil, err
+	}
+	return &record{
+		recordType: recordType,
+		id:         id,
+		timestamp:  timestamp,
+		fields:     parseFields(body[end+len("):"):]),
+		raw:        fmt.Sprintf("type=%s msg=%s", recordType, body),
+	}, nil
+}
+
+// parseTimestamp returns the timestamp of an event identifier, e.g. 1364481363.243:24287.
+func parseTimestamp(id string) (time.Time, error) {
+	separator := strings.IndexByte(id, ':')
+	if separator < 0 {
+		return time.Time{}, fmt.Errorf("invalid event identifier %q", id)
+	}
+	seconds, err := strconv.ParseFloat(id[:separator], 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid event identifier %q: %v", id, err)
+	}
+	millis := int64(seconds*1000 + 0.5)
+	return time.Unix(millis/1000, (millis%1000)*int64(time.Millisecond)), nil
+}
+
+// parseFields returns the key=value fields of a record, the quotes of the values are removed and
+// the hex encoded values are decoded. The fields of the msg='...' field of the user space records are
+// returned with the other fields.
+func parseFields(s string) map[string]string {
+	fields := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ")
+		separator := strings.IndexByte(s, '=')
+		if separator < 0 {
+			break
+		}
+		key := s[:separator]
+		s = s[separator+1:]
+		var value string
+		quoted := len(s) > 0 && (s[0] == '"' || s[0] == '\'')
+		if quoted {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				end = len(s) - 1
+			}
+			value, s = s[1:end+1], s[min(end+2, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ' ')
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		switch {
+		case key == "msg" && quoted:
+			for k, v := range parseFields(value) {
+				fields[k] = v
+			}
+		case !quoted && hexEncodedFields[key]:
+			fields[key] = decodeHex(value)
+		default:
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// decodeHex decodes the values hex encoded by auditd, the other values are returned as is,
+// the NUL separating the arguments of a proctitle are replaced by spaces.
+func decodeHex(value string) string {
+	decoded, err := hex.DecodeString(value)
+	if err != nil || len(decoded) == 0 {
+		return value
+	}
+	return string(bytes.Replace(decoded, []byte{0}, []byte{' '}, -1))
+}
+
+// min returns the smallest of a and b.
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
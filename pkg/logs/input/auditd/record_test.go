@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSyscallRecord(t *testing.T) {
+	r, err := parseRecord([]byte(`type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 success=no exit=-13 a0=7fffd19c5592 ppid=2686 pid=3538 auid=1000 uid=1000 tty=pts0 ses=1 comm="cat" exe="/bin/cat" key="sshd_config"` + "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "SYSCALL", r.recordType)
+	assert.Equal(t, "1364481363.243:24287", r.id)
+	assert.Equal(t, time.Unix(1364481363, 243*int64(time.Millisecond)), r.timestamp)
+	assert.Equal(t, "2", r.fields["syscall"])
+	assert.Equal(t, "/bin/cat", r.fields["exe"])
+	assert.Equal(t, "cat", r.fields["comm"])
+	assert.Equal(t, "1000", r.fields["auid"])
+	assert.Equal(t, "sshd_config", r.fields["key"])
+	assert.Equal(t, `type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 success=no exit=-13 a0=7fffd19c5592 ppid=2686 pid=3538 auid=1000 uid=1000 tty=pts0 ses=1 comm="cat" exe="/bin/cat" key="sshd_config"`, r.raw)
+}
+
+func TestParseUserRecord(t *testing.T) {
+	r, err := parseRecord([]byte(`type=USER_LOGIN msg=audit(1547635832.452:812): pid=2319 uid=0 auid=4294967295 ses=4294967295 msg='op=login acct="root" exe="/usr/sbin/sshd" hostname=? addr=10.0.2.2 terminal=sshd res=failed'`))
+	assert.Nil(t, err)
+	assert.Equal(t, "USER_LOGIN", r.recordType)
+	assert.Equal(t, "2319", r.fields["pid"])
+	assert.Equal(t, "login", r.fields["op"])
+	assert.Equal(t, "root", r.fields["acct"])
+	assert.Equal(t, "/usr/sbin/sshd", r.fields["exe"])
+	assert.Equal(t, "10.0.2.2", r.fields["addr"])
+	assert.Equal(t, "failed", r.fields["res"])
+}
+
+func TestParseHexEncodedFields(t *testing.T) {
+	r, err := parseRecord([]byte(`type=PROCTITLE msg=audit(1364481363.243:24287): proctitle=636174002F6574632F7373682F737368645F636F6E666967`))
+	assert.Nil(t, err)
+	assert.Equal(t, "cat /etc/ssh/sshd_config", r.fields["proctitle"])
+
+	r, err = parseRecord([]byte(`type=PATH msg=audit(1364481363.243:24287): item=0 name=2F746D702F6D792066696C65 inode=409248 nametype=NORMAL`))
+	assert.Nil(t, err)
+	assert.Equal(t, "/tmp/my file", r.fields["name"])
+	assert.Equal(t, "409248", r.fields["inode"])
+
+	r, err = parseRecord([]byte(`type=SYSCALL msg=audit(1364481363.243:24287): syscall=2 key=(null)`))
+	assert.Nil(t, err)
+	assert.Equal(t, "(null)", r.fields["key"])
+}
+
+func TestParseBody(t *testing.T) {
+	r, err := parseBody("CWD", `audit(1364481363.243:24287):  cwd="/home/shadowman"`)
+	assert.Nil(t, err)
+	assert.Equal(t, "CWD", r.recordType)
+	assert.Equal(t, "1364481363.243:24287", r.id)
+	assert.Equal(t, "/home/shadowman", r.fields["cwd"])
+	assert.Equal(t, `type=CWD msg=audit(1364481363.243:24287):  cwd="/home/shadowman"`, r.raw)
+}
+
+func TestParseInvalidRecords(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"hello world",
+		"type=SYSCALL",
+		"type=SYSCALL msg=foo",
+		"type=SYSCALL msg=audit(1364481363.243",
+		"type=SYSCALL msg=audit(foo:24287): syscall=2",
+	} {
+		_, err := parseRecord([]byte(line))
+		assert.NotNil(t, err, line)
+	}
+}
@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// receiveRetryPeriod is the time waited before receiving the records again after an error.
+const receiveRetryPeriod = 10 * time.Second
+
+// auditSocket receives the records from the kernel.
+type auditSocket interface {
+	// receive returns the records received, there are none when the receive timeout expired.
+	receive() ([]*record, error)
+	close() error
+}
+
+// A SocketReader reads the records multicast by the kernel on the audit socket and forwards their events
+// to the pipeline. It runs along with auditd, which keeps configuring the audit rules and writing the audit log,
+// and needs the CAP_AUDIT_READ capability.
+type SocketReader struct {
+	source     *config.LogSource
+	outputChan chan *message.Message
+	assembler  *assembler
+	socket     auditSocket
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewSocketReader returns an initialized SocketReader
+func NewSocketReader(source *config.LogSource, outputChan chan *message.Message) *SocketReader {
+	r := &SocketReader{
+		source:     source,
+		outputChan: outputChan,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	r.assembler = newAssembler(r.forward)
+	return r
+}
+
+// Start starts reading the audit socket.
+func (r *SocketReader) Start() error {
+	log.Info("Starting auditd socket reader")
+	socket, err := newAuditSocket()
+	if err != nil {
+		r.source.Status.Error(err)
+		return err
+	}
+	r.socket = socket
+	r.source.Status.Success()
+	go r.run()
+	return nil
+}
+
+// Stop stops reading the audit socket, the incomplete events are sent as is.
+func (r *SocketReader) Stop() {
+	log.Info("Stopping auditd socket reader")
+	close(r.stop)
+	<-r.done
+}
+
+// run reads the records until the reader is stopped.
+func (r *SocketReader) run() {
+	defer func() {
+		r.assembler.flushAll()
+		r.socket.close()
+		close(r.done)
+	}()
+	for {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+		records, err := r.socket.receive()
+		if err != nil {
+			log.Warnf("Couldn't receive audit records: %v", err)
+			r.source.Status.Error(err)
+			select {
+			case <-r.stop:
+				return
+			case <-time.After(receiveRetryPeriod):
+			}
+			continue
+		}
+		now := time.Now()
+		for _, record := range records {
+			r.assembler.add(record, 0, 0, now)
+		}
+		r.assembler.flush(now)
+	}
+}
+
+// forward sends an event to the pipeline.
+func (r *SocketReader) forward(e *event) {
+	r.outputChan <- toMessage(r.source, e)
+}
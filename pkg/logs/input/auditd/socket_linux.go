@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"bytes"
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// auditNetlinkGroupReadLog is the multicast group the kernel sends the records to, AUDIT_NLGRP_READLOG.
+	auditNetlinkGroupReadLog = 1
+	// receiveTimeout bounds the time a receive blocks so that the reader can be stopped.
+	receiveTimeout = time.Second
+	// maxMessageSize is the maximum size of the audit messages, MAX_AUDIT_MESSAGE_LENGTH.
+	maxMessageSize = 8970
+)
+
+// recordTypes are the names of the types of the records as written in the audit log,
+// the other types are written as UNKNOWN[type].
+var recordTypes = map[uint16]string{
+	1006: "LOGIN",
+	1100: "USER_AUTH",
+	1101: "USER_ACCT",
+	1102: "USER_MGMT",
+	1103: "CRED_ACQ",
+	1104: "CRED_DISP",
+	1105: "USER_START",
+	1106: "USER_END",
+	1107: "USER_AVC",
+	1108: "USER_CHAUTHTOK",
+	1109: "USER_ERR",
+	1110: "CRED_REFR",
+	1111: "USYS_CONFIG",
+	1112: "USER_LOGIN",
+	1113: "USER_LOGOUT",
+	1114: "ADD_USER",
+	1115: "DEL_USER",
+	1116: "ADD_GROUP",
+	1117: "DEL_GROUP",
+	1123: "USER_CMD",
+	1130: "SERVICE_START",
+	1131: "SERVICE_STOP",
+	1300: "SYSCALL",
+	1302: "PATH",
+	1303: "IPC",
+	1304: "SOCKETCALL",
+	1305: "CONFIG_CHANGE",
+	1306: "SOCKADDR",
+	1307: "CWD",
+	1309: "EXECVE",
+	1317: "FD_PAIR",
+	1318: "OBJ_PID",
+	1320: eoeType,
+	1321: "BPRM_FCAPS",
+	1322: "CAPSET",
+	1323: "MMAP",
+	1324: "NETFILTER_PKT",
+	1325: "NETFILTER_CFG",
+	1326: "SECCOMP",
+	1327: "PROCTITLE",
+	1400: "AVC",
+	1401: "SELINUX_ERR",
+	1701: "ANOM_ABEND",
+}
+
+// netlinkSocket is the audit netlink socket joined to the multicast group of the records.
+type netlinkSocket struct {
+	fd  int
+	buf []byte
+}
+
+// newAuditSocket opens the audit socket.
+func newAuditSocket() (auditSocket, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_AUDIT)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the audit socket: %v", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: auditNetlinkGroupReadLog}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("could not join the multicast group of the audit socket, the CAP_AUDIT_READ capability is required: %v", err)
+	}
+	timeout := syscall.NsecToTimeval(receiveTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("could not set the receive timeout of the audit socket: %v", err)
+	}
+	return &netlinkSocket{
+		fd:  fd,
+		buf: make([]byte, syscall.NLMSG_HDRLEN+maxMessageSize),
+	}, nil
+}
+
+// receive returns the record of the next audit message, the kernel sends one record per message
+// and does not always account for the header in their length so that it's not used.
+func (s *netlinkSocket) receive() ([]*record, error) {
+	n, _, err := syscall.Recvfrom(s.fd, s.buf, 0)
+	switch {
+	case err == syscall.EAGAIN || err == syscall.EINTR:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case n < syscall.NLMSG_HDRLEN:
+		return nil, nil
+	}
+	header := (*syscall.NlMsghdr)(unsafe.Pointer(&s.buf[0]))
+	recordType, found := recordTypes[header.Type]
+	if !found {
+		recordType = fmt.Sprintf("UNKNOWN[%d]", header.Type)
+	}
+	body := string(bytes.TrimRight(s.buf[syscall.NLMSG_HDRLEN:n], "\x00\n"))
+	r, err := parseBody(recordType, body)
+	if err != nil {
+		log.Debugf("Invalid audit record: %v", err)
+		return nil, nil
+	}
+	return []*record{r}, nil
+}
+
+// close closes the audit socket.
+func (s *netlinkSocket) close() error {
+	return syscall.Close(s.fd)
+}
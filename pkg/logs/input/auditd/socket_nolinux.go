@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !linux
+
+package auditd
+
+import (
+	"errors"
+)
+
+// ErrLinuxOnly is returned on non-linux platforms
+var ErrLinuxOnly = errors.New("the audit socket is only available on Linux hosts")
+
+// newAuditSocket returns a "not implemented" error on non-linux hosts
+func newAuditSocket() (auditSocket, error) {
+	return nil, ErrLinuxOnly
+}
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// auditdIntegration is the prefix of the identifiers of the audit logs in the registry.
+const auditdIntegration = "auditd"
+
+// defaultSleepDuration is the time waited before reading the audit log again once its end was reached.
+const defaultSleepDuration = time.Second
+
+// A Tailer tails the audit log of a source and forwards its events to the pipeline, it starts from its
+// last committed offset or from the end of the log and reads the new audit log from its beginning once rotated.
+type Tailer struct {
+	source        *config.LogSource
+	outputChan    chan *message.Message
+	assembler     *assembler
+	file          *os.File
+	reader        *bufio.Reader
+	partialLine   []byte
+	offset        int64
+	sleepDuration time.Duration
+	stop          chan struct{}
+	done          chan struct{}
+}
+
+// NewTailer returns an initialized Tailer
+func NewTailer(source *config.LogSource, outputChan chan *message.Message) *Tailer {
+	t := &Tailer{
+		source:        source,
+		outputChan:    outputChan,
+		sleepDuration: defaultSleepDuration,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	t.assembler = newAssembler(t.forward)
+	return t
+}
+
+// Identifier returns the unique identifier of the audit log being tailed.
+func (t *Tailer) Identifier() string {
+	return auditdIntegration + ":" + t.source.Config.Path
+}
+
+// Start starts tailing the audit log from offset or from its end when offset is not set or is past its end.
+func (t *Tailer) Start(offset string) error {
+	log.Infof("Starting auditd tailer for %s", t.source.Config.Path)
+	file, err := os.Open(t.source.Config.Path)
+	if err != nil {
+		t.source.Status.Error(err)
+		return err
+	}
+	whence := io.SeekEnd
+	var position int64
+	if info, err := file.Stat(); err == nil && offset != "" {
+		if position, err = strconv.ParseInt(offset, 10, 64); err == nil && position <= info.Size() {
+			whence = io.SeekStart
+		} else {
+			position = 0
+		}
+	}
+	if t.offset, err = file.Seek(position, whence); err != nil {
+		file.Close()
+		t.source.Status.Error(err)
+		return err
+	}
+	t.file = file
+	t.reader = bufio.NewReader(file)
+	t.source.Status.Success()
+	go t.run()
+	return nil
+}
+
+// Stop stops tailing the audit log, the incomplete events are sent as is.
+func (t *Tailer) Stop() {
+	log.Infof("Stopping auditd tailer for %s", t.source.Config.Path)
+	close(t.stop)
+	<-t.done
+}
+
+// run reads the audit log until the tailer is stopped.
+func (t *Tailer) run() {
+	defer func() {
+		t.assembler.flushAll()
+		t.file.Close()
+		close(t.done)
+	}()
+	for {
+		t.readToEnd()
+		t.assembler.flush(time.Now())
+		if t.isRotated() {
+			// the new audit log is read once the records of the rotated one have all been read
+			t.readToEnd()
+			t.reopen()
+		}
+		select {
+		case <-t.stop:
+			return
+		case <-time.After(t.sleepDuration):
+		}
+	}
+}
+
+// readToEnd reads the records of the audit log up to its end.
+func (t *Tailer) readToEnd() {
+	for {
+		line, err := t.reader.ReadBytes('\n')
+		if err != nil {
+			// the last line is not complete yet
+			t.partialLine = append(t.partialLine, line...)
+			if err != io.EOF {
+				log.Warnf("Couldn't read %s: %v", t.source.Config.Path, err)
+			}
+			return
+		}
+		if len(t.partialLine) > 0 {
+			line = append(t.partialLine, line...)
+			t.partialLine = nil
+		}
+		start := t.offset
+		t.offset += int64(len(line))
+		r, err := parseRecord(line)
+		if err != nil {
+			log.Debugf("Invalid audit record in %s: %v", t.source.Config.Path, err)
+			continue
+		}
+		t.assembler.add(r, start, t.offset, time.Now())
+	}
+}
+
+// isRotated returns true if the audit log was rotated or truncated.
+func (t *Tailer) isRotated() bool {
+	info, err := os.Stat(t.source.Config.Path)
+	if err != nil {
+		// the new audit log is not created yet
+		return false
+	}
+	current, err := t.file.Stat()
+	if err != nil {
+		return true
+	}
+	return !os.SameFile(info, current) || info.Size() < t.offset
+}
+
+// reopen reads the audit log from its beginning.
+func (t *Tailer) reopen() {
+	file, err := os.Open(t.source.Config.Path)
+	if err != nil {
+		log.Warnf("Couldn't open %s: %v", t.source.Config.Path, err)
+		t.source.Status.Error(err)
+		return
+	}
+	t.file.Close()
+	t.file = file
+	t.reader = bufio.NewReader(file)
+	t.partialLine = nil
+	t.offset = 0
+	t.source.Status.Success()
+}
+
+// forward sends an event to the pipeline.
+func (t *Tailer) forward(e *event) {
+	msg := toMessage(t.source, e)
+	msg.Origin.Identifier = t.Identifier()
+	msg.Origin.Offset = strconv.FormatInt(e.committed, 10)
+	t.outputChan <- msg
+}
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package auditd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const (
+	syscallRecord = `type=SYSCALL msg=audit(1364481363.243:24287): syscall=2 exe="/bin/cat"` + "\n"
+	cwdRecord     = `type=CWD msg=audit(1364481363.243:24287):  cwd="/home/shadowman"` + "\n"
+	eoeRecord     = `type=EOE msg=audit(1364481363.243:24287): ` + "\n"
+	loginRecord   = `type=USER_LOGIN msg=audit(1364481364.000:24288): pid=2319 uid=0 msg='op=login acct="root" res=failed'` + "\n"
+)
+
+type TailerTestSuite struct {
+	suite.Suite
+	testDir    string
+	testPath   string
+	testFile   *os.File
+	outputChan chan *message.Message
+	source     *config.LogSource
+	tailer     *Tailer
+}
+
+func (suite *TailerTestSuite) SetupTest() {
+	var err error
+	suite.testDir, err = ioutil.TempDir("", "log-auditd-test-")
+	suite.Nil(err)
+	suite.testPath = filepath.Join(suite.testDir, "audit.log")
+	suite.testFile, err = os.Create(suite.testPath)
+	suite.Nil(err)
+	suite.outputChan = make(chan *message.Message, 10)
+	suite.source = config.NewLogSource("", &config.LogsConfig{Type: config.AuditdType, Path: suite.testPath})
+	suite.tailer = NewTailer(suite.source, suite.outputChan)
+	suite.tailer.sleepDuration = 10 * time.Millisecond
+}
+
+func (suite *TailerTestSuite) TearDownTest() {
+	suite.testFile.Close()
+	os.RemoveAll(suite.testDir)
+}
+
+func (suite *TailerTestSuite) write(content string) {
+	_, err := suite.testFile.WriteString(content)
+	suite.Nil(err)
+}
+
+func (suite *TailerTestSuite) TestTailFromEnd() {
+	suite.write(loginRecord)
+	suite.Nil(suite.tailer.Start(""))
+	defer suite.tailer.Stop()
+
+	suite.write(syscallRecord + cwdRecord)
+	suite.write(eoeRecord)
+
+	msg := <-suite.outputChan
+	suite.Equal(syscallRecord+cwdRecord[:len(cwdRecord)-1], string(msg.Content))
+	suite.Equal("auditd:"+suite.testPath, msg.Origin.Identifier)
+	suite.Equal(strconv.Itoa(len(loginRecord+syscallRecord+cwdRecord+eoeRecord)), msg.Origin.Offset)
+	suite.True(suite.source.Status.IsSuccess())
+}
+
+func (suite *TailerTestSuite) TestTailFromOffset() {
+	suite.write(loginRecord + loginRecord)
+	suite.Nil(suite.tailer.Start(strconv.Itoa(len(loginRecord))))
+	defer suite.tailer.Stop()
+
+	msg := <-suite.outputChan
+	suite.Equal(loginRecord[:len(loginRecord)-1], string(msg.Content))
+	suite.Equal(strconv.Itoa(2*len(loginRecord)), msg.Origin.Offset)
+}
+
+func (suite *TailerTestSuite) TestTailWaitsForCompleteLines() {
+	suite.Nil(suite.tailer.Start(""))
+	defer suite.tailer.Stop()
+
+	suite.write(loginRecord[:10])
+	time.Sleep(50 * time.Millisecond)
+	suite.write(loginRecord[10:])
+
+	msg := <-suite.outputChan
+	suite.Equal(loginRecord[:len(loginRecord)-1], string(msg.Content))
+	suite.Equal(strconv.Itoa(len(loginRecord)), msg.Origin.Offset)
+}
+
+func (suite *TailerTestSuite) TestTailAfterRotation() {
+	suite.Nil(suite.tailer.Start(""))
+	defer suite.tailer.Stop()
+
+	suite.write(syscallRecord)
+	time.Sleep(50 * time.Millisecond)
+	suite.Nil(os.Rename(suite.testPath, suite.testPath+".1"))
+	suite.write(eoeRecord)
+	suite.testFile.Close()
+
+	var err error
+	suite.testFile, err = os.Create(suite.testPath)
+	suite.Nil(err)
+	suite.write(loginRecord)
+
+	msg := <-suite.outputChan
+	suite.Equal(syscallRecord[:len(syscallRecord)-1], string(msg.Content))
+	msg = <-suite.outputChan
+	suite.Equal(loginRecord[:len(loginRecord)-1], string(msg.Content))
+	suite.Equal(strconv.Itoa(len(loginRecord)), msg.Origin.Offset)
+}
+
+func (suite *TailerTestSuite) TestStopSendsIncompleteEvents() {
+	suite.Nil(suite.tailer.Start(""))
+	suite.write(syscallRecord)
+	time.Sleep(50 * time.Millisecond)
+	suite.tailer.Stop()
+
+	msg := <-suite.outputChan
+	suite.Equal(syscallRecord[:len(syscallRecord)-1], string(msg.Content))
+}
+
+func TestTailerTestSuite(t *testing.T) {
+	suite.Run(t, new(TailerTestSuite))
+}
+
+func TestStartFailsWithoutAuditLog(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.AuditdType, Path: "/does/not/exist/audit.log"})
+	tailer := NewTailer(source, make(chan *message.Message))
+	assert.NotNil(t, tailer.Start(""))
+	assert.True(t, source.Status.IsError())
+}
@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package checks
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+)
+
+var (
+	// ErrNotRunning is returned when a check submits a log while the logs-agent is not running.
+	ErrNotRunning = errors.New("the logs-agent is not running")
+	// ErrPipelineFull is returned when a check submits a log while its pipeline is full, the log is dropped.
+	ErrPipelineFull = errors.New("the pipeline is full")
+)
+
+var (
+	// mu guards the running launcher, the submissions hold it for reading so that it can not stop in the middle of one.
+	mu      sync.RWMutex
+	running *Launcher
+)
+
+// Submit hands a log submitted by the check checkName to the pipelines, it never blocks since the python checks
+// submit logs holding the GIL, the log is dropped if its pipeline is full.
+// The logs-agent sets it as the log submitter of the aggregator, which the senders of the checks submit logs with.
+// The log has the info status if status is empty and the hostname of the agent if hostname is empty.
+func Submit(checkName, content, status, hostname string, tags []string) error {
+	mu.RLock()
+	defer mu.RUnlock()
+	if running == nil {
+		return ErrNotRunning
+	}
+	return running.submit(checkName, content, status, hostname, tags)
+}
+
+// Launcher collects the logs submitted by the checks, each check has its own source: the source of type check
+// of the logs section of its configuration if any, which sets the service, the tags or the processing rules of its logs,
+// and a source named after the check otherwise.
+type Launcher struct {
+	sources          *config.LogSources
+	pipelineProvider pipeline.Provider
+	added            chan *config.LogSource
+	removed          chan *config.LogSource
+	mu               sync.Mutex
+	configured       map[string]*config.LogSource
+	defaults         map[string]*config.LogSource
+	stop             chan struct{}
+}
+
+// NewLauncher returns an initialized Launcher
+func NewLauncher(sources *config.LogSources, pipelineProvider pipeline.Provider) *Launcher {
+	return &Launcher{
+		sources:          sources,
+		pipelineProvider: pipelineProvider,
+		added:            sources.GetAddedForType(config.CheckType),
+		removed:          sources.GetRemovedForType(config.CheckType),
+		configured:       make(map[string]*config.LogSource),
+		defaults:         make(map[string]*config.LogSource),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the launcher, the checks can submit logs from then on.
+func (l *Launcher) Start() {
+	go l.run()
+	mu.Lock()
+	running = l
+	mu.Unlock()
+}
+
+// Stop stops the launcher once the logs being submitted are handed to the pipelines,
+// the sources created for the checks are removed.
+func (l *Launcher) Stop() {
+	mu.Lock()
+	running = nil
+	mu.Unlock()
+
+	l.mu.Lock()
+	defaults := l.defaults
+	l.defaults = make(map[string]*config.LogSource)
+	l.mu.Unlock()
+	for _, source := range defaults {
+		l.sources.RemoveSource(source)
+	}
+	l.stop <- struct{}{}
+}
+
+// run keeps track of the sources of type check.
+func (l *Launcher) run() {
+	for {
+		select {
+		case source := <-l.added:
+			l.mu.Lock()
+			// the sources created for the checks are added too, they are not configured ones.
+			if l.defaults[source.Name] != source {
+				l.configured[source.Name] = source
+				source.Status.Success()
+			}
+			l.mu.Unlock()
+		case source := <-l.removed:
+			l.mu.Lock()
+			if l.configured[source.Name] == source {
+				delete(l.configured, source.Name)
+			}
+			l.mu.Unlock()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// submit hands a log of the check checkName to the pipeline of its source, or drops it if the pipeline is full.
+func (l *Launcher) submit(checkName, content, status, hostname string, tags []string) error {
+	source := l.sourceFor(checkName)
+	origin := message.NewOrigin(source)
+	origin.SetTags(tags)
+	msg := message.NewMessage([]byte(content), origin, status)
+	msg.Hostname = hostname
+	select {
+	case l.pipelineProvider.PipelineChanForSource(source) <- msg:
+		return nil
+	default:
+		metrics.CheckLogsDropped.Add(1)
+		return ErrPipelineFull
+	}
+}
+
+// sourceFor returns the source of the logs of the check checkName, the source named after it
+// is created and added to the sources, so that it shows in the status, the first time it submits a log.
+func (l *Launcher) sourceFor(checkName string) *config.LogSource {
+	l.mu.Lock()
+	if source, exists := l.configured[checkName]; exists {
+		l.mu.Unlock()
+		return source
+	}
+	if source, exists := l.defaults[checkName]; exists {
+		l.mu.Unlock()
+		return source
+	}
+	source := config.NewLogSource(checkName, &config.LogsConfig{Type: config.CheckType, Source: checkName})
+	source.Status.Success()
+	l.defaults[checkName] = source
+	l.mu.Unlock()
+
+	// the source is added once the lock is released since run receives it.
+	l.sources.AddSource(source)
+	return source
+}
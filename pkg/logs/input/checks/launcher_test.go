@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package checks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+// bufferedProvider provides a single pipeline buffering up to its capacity.
+type bufferedProvider struct {
+	pipeline.Provider
+	msgChan chan *message.Message
+}
+
+func newBufferedProvider(size int) *bufferedProvider {
+	return &bufferedProvider{Provider: mock.NewMockProvider(), msgChan: make(chan *message.Message, size)}
+}
+
+func (p *bufferedProvider) NextPipelineChan() chan *message.Message {
+	return p.msgChan
+}
+
+func (p *bufferedProvider) PipelineChanForSource(source *config.LogSource) chan *message.Message {
+	return p.msgChan
+}
+
+// submit submits a log and returns the message handed to the pipelines.
+func submit(t *testing.T, msgChan chan *message.Message, checkName, content, status, hostname string, tags []string) *message.Message {
+	assert.NoError(t, Submit(checkName, content, status, hostname, tags))
+	return <-msgChan
+}
+
+// waitConfigured waits until the configured source of the check checkName is source.
+func waitConfigured(t *testing.T, launcher *Launcher, checkName string, source *config.LogSource) {
+	for i := 0; i < 100; i++ {
+		launcher.mu.Lock()
+		configured := launcher.configured[checkName]
+		launcher.mu.Unlock()
+		if configured == source {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "the configured source was not updated")
+}
+
+func TestSubmitFailsWhenTheLauncherIsNotRunning(t *testing.T) {
+	assert.Equal(t, ErrNotRunning, Submit("okta", "hello", "", "", nil))
+
+	launcher := NewLauncher(config.NewLogSources(), newBufferedProvider(1))
+	launcher.Start()
+	launcher.Stop()
+	assert.Equal(t, ErrNotRunning, Submit("okta", "hello", "", "", nil))
+}
+
+func TestSubmitDropsTheLogsWhenThePipelineIsFull(t *testing.T) {
+	pp := newBufferedProvider(1)
+	launcher := NewLauncher(config.NewLogSources(), pp)
+	launcher.Start()
+	defer launcher.Stop()
+
+	dropped := metrics.CheckLogsDropped.Value()
+	assert.NoError(t, Submit("okta", "user logged in", "", "", nil))
+	// the pipeline is saturated, the check is not blocked.
+	assert.Equal(t, ErrPipelineFull, Submit("okta", "user logged out", "", "", nil))
+	assert.Equal(t, dropped+1, metrics.CheckLogsDropped.Value())
+
+	assert.Equal(t, "user logged in", string((<-pp.msgChan).Content))
+	assert.NoError(t, Submit("okta", "user logged out", "", "", nil))
+	assert.Equal(t, "user logged out", string((<-pp.msgChan).Content))
+}
+
+func TestSubmitUsesASourceNamedAfterTheCheck(t *testing.T) {
+	sources := config.NewLogSources()
+	pp := newBufferedProvider(1)
+	launcher := NewLauncher(sources, pp)
+	launcher.Start()
+	defer launcher.Stop()
+
+	msg := submit(t, pp.NextPipelineChan(), "okta", "user logged in", message.StatusWarning, "", []string{"org:acme"})
+	assert.Equal(t, "user logged in", string(msg.Content))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, "", msg.Hostname)
+	assert.Equal(t, "okta", msg.Origin.LogSource.Name)
+	assert.Equal(t, "okta", msg.Origin.Source())
+	assert.Equal(t, []string{"org:acme"}, msg.Origin.Tags())
+	// the source shows in the status.
+	assert.Equal(t, []*config.LogSource{msg.Origin.LogSource}, sources.GetSources())
+	assert.True(t, msg.Origin.LogSource.Status.IsSuccess())
+
+	// the source is created once per check.
+	other := submit(t, pp.NextPipelineChan(), "okta", "user logged out", "", "api.okta.com", nil)
+	assert.Equal(t, msg.Origin.LogSource, other.Origin.LogSource)
+	assert.Equal(t, message.StatusInfo, other.GetStatus())
+	assert.Equal(t, "api.okta.com", other.Hostname)
+	assert.Len(t, sources.GetSources(), 1)
+
+	other = submit(t, pp.NextPipelineChan(), "cloudflare", "request blocked", "", "", nil)
+	assert.Equal(t, "cloudflare", other.Origin.LogSource.Name)
+	assert.Len(t, sources.GetSources(), 2)
+}
+
+func TestSubmitUsesTheConfiguredSourceOfTheCheck(t *testing.T) {
+	sources := config.NewLogSources()
+	pp := newBufferedProvider(1)
+	launcher := NewLauncher(sources, pp)
+	launcher.Start()
+	defer launcher.Stop()
+
+	source := config.NewLogSource("okta", &config.LogsConfig{Type: config.CheckType, Service: "identity", Source: "okta", Tags: []string{"env:prod"}})
+	sources.AddSource(source)
+	waitConfigured(t, launcher, "okta", source)
+
+	msg := submit(t, pp.NextPipelineChan(), "okta", "user logged in", "", "", []string{"org:acme"})
+	assert.Equal(t, source, msg.Origin.LogSource)
+	assert.Equal(t, "identity", msg.Origin.Service())
+	assert.Equal(t, []string{"org:acme", "env:prod"}, msg.Origin.Tags())
+
+	// the source named after the check is used once the configured one is removed.
+	sources.RemoveSource(source)
+	waitConfigured(t, launcher, "okta", nil)
+	msg = submit(t, pp.NextPipelineChan(), "okta", "user logged in", "", "", nil)
+	assert.NotEqual(t, source, msg.Origin.LogSource)
+	assert.Equal(t, "okta", msg.Origin.LogSource.Name)
+}
+
+func TestStopRemovesTheSourcesOfTheChecks(t *testing.T) {
+	sources := config.NewLogSources()
+	pp := newBufferedProvider(1)
+	launcher := NewLauncher(sources, pp)
+	launcher.Start()
+
+	configured := config.NewLogSource("okta", &config.LogsConfig{Type: config.CheckType})
+	sources.AddSource(configured)
+	waitConfigured(t, launcher, "okta", configured)
+	submit(t, pp.NextPipelineChan(), "cloudflare", "request blocked", "", "", nil)
+	assert.Len(t, sources.GetSources(), 2)
+
+	launcher.Stop()
+	assert.Equal(t, []*config.LogSource{configured}, sources.GetSources())
+}
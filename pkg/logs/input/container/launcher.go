@@ -10,6 +10,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/docker"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/kubernetes"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/podman"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
@@ -23,10 +24,13 @@ import (
 // When a docker launcher cannot be initialized properly, the launcher will attempt to
 // initialize a kubernetes launcher which will tail logs files (in '/var/log/pods') of all
 // the containers running on the kubernetes cluster and matching the autodiscovery configuration.
-func NewLauncher(collectAll bool, sources *config.LogSources, services *service.Services, pipelineProvider pipeline.Provider, registry auditor.Registry) restart.Restartable {
+// When none of them can be initialized, the launcher will attempt to initialize a podman launcher
+// which will tail the log files of all the podman containers found in podmanStoragePaths.
+// When autoTag is set, the logs of the containers without service are tagged with their short image name.
+func NewLauncher(collectAll bool, autoTag bool, podmanStoragePaths []string, sources *config.LogSources, services *service.Services, pipelineProvider pipeline.Provider, registry auditor.Registry) restart.Restartable {
 	// attempt to initialize a docker launcher
 	log.Info("Trying to initialize docker launcher")
-	launcher, err := docker.NewLauncher(sources, services, pipelineProvider, registry)
+	launcher, err := docker.NewLauncher(sources, services, pipelineProvider, registry, autoTag)
 	if err == nil {
 		log.Info("Docker launcher initialized")
 		return launcher
@@ -35,12 +39,21 @@ func NewLauncher(collectAll bool, sources *config.LogSources, services *service.
 
 	// attempt to initialize a kubernetes launcher
 	log.Info("Trying to initialize kubernetes launcher")
-	kubernetesLauncher, err := kubernetes.NewLauncher(sources, services, collectAll)
+	kubernetesLauncher, err := kubernetes.NewLauncher(sources, services, collectAll, autoTag)
 	if err == nil {
 		log.Info("Kubernetes launcher initialized")
 		return kubernetesLauncher
 	}
 	log.Infof("Could not setup the kubernetes launcher: %v", err)
+
+	// attempt to initialize a podman launcher
+	log.Info("Trying to initialize podman launcher")
+	podmanLauncher, err := podman.NewLauncher(sources, podmanStoragePaths, collectAll)
+	if err == nil {
+		log.Info("Podman launcher initialized")
+		return podmanLauncher
+	}
+	log.Infof("Could not setup the podman launcher: %v", err)
 	log.Infof("Container logs won't be collected")
 	return NewNoopLauncher()
 }
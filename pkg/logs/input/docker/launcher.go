@@ -43,10 +43,11 @@ type Launcher struct {
 	erroredContainerID chan string
 	lock               *sync.Mutex
 	collectAllSource   *config.LogSource
+	autoTag            bool
 }
 
 // NewLauncher returns a new launcher
-func NewLauncher(sources *config.LogSources, services *service.Services, pipelineProvider pipeline.Provider, registry auditor.Registry) (*Launcher, error) {
+func NewLauncher(sources *config.LogSources, services *service.Services, pipelineProvider pipeline.Provider, registry auditor.Registry, autoTag bool) (*Launcher, error) {
 	launcher := &Launcher{
 		pipelineProvider:   pipelineProvider,
 		tailers:            make(map[string]*Tailer),
@@ -55,6 +56,7 @@ func NewLauncher(sources *config.LogSources, services *service.Services, pipelin
 		stop:               make(chan struct{}),
 		erroredContainerID: make(chan string),
 		lock:               &sync.Mutex{},
+		autoTag:            autoTag,
 	}
 	err := launcher.setup()
 	if err != nil {
@@ -201,10 +203,17 @@ func (l *Launcher) startTailer(container *Container, source *config.LogSource) {
 
 	// overridenSource == source if the containerCollectAll option is not activated or the container has AD labels
 	overridenSource := l.overrideSource(container, source)
-	tailer := NewTailer(l.cli, containerID, overridenSource, l.pipelineProvider.NextPipelineChan(), l.erroredContainerID)
+	tailer := NewTailer(l.cli, containerID, overridenSource, l.pipelineProvider.PipelineChanForSource(overridenSource), l.erroredContainerID)
+	if l.autoTag && overridenSource.Config.Service == "" {
+		if shortName, err := container.getShortImageName(); err == nil {
+			tailer.service = shortName
+		} else {
+			log.Debugf("Could not get short image name for container %v: %v", ShortContainerID(containerID), err)
+		}
+	}
 
 	// compute the offset to prevent from missing or duplicating logs
-	since, err := Since(l.registry, tailer.Identifier(), container.service.CreationTime)
+	since, err := Since(l.registry, tailer.Identifier(), container.service.CreationTime, source.Config)
 	if err != nil {
 		log.Warnf("Could not recover tailing from last committed offset %v: %v", ShortContainerID(containerID), err)
 	}
@@ -248,10 +257,10 @@ func (l *Launcher) restartTailer(containerID string) {
 		l.removeTailer(containerID)
 	}
 
-	tailer := NewTailer(l.cli, containerID, source, l.pipelineProvider.NextPipelineChan(), l.erroredContainerID)
+	tailer := NewTailer(l.cli, containerID, source, l.pipelineProvider.PipelineChanForSource(source), l.erroredContainerID)
 
 	// compute the offset to prevent from missing or duplicating logs
-	since, err := Since(l.registry, tailer.Identifier(), service.Before)
+	since, err := Since(l.registry, tailer.Identifier(), service.Before, source.Config)
 	if err != nil {
 		log.Warnf("Could not recover last committed offset for container %v: %v", ShortContainerID(containerID), err)
 	}
@@ -8,6 +8,7 @@
 package docker
 
 import (
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
@@ -15,8 +16,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
 )
 
-// Since returns the date from when logs should be collected.
-func Since(registry auditor.Registry, identifier string, creationTime service.CreationTime) (time.Time, error) {
+// Since returns the date from when logs should be collected,
+// the start position of sourceConfig applies to the containers launched before the agent start.
+func Since(registry auditor.Registry, identifier string, creationTime service.CreationTime, sourceConfig *config.LogsConfig) (time.Time, error) {
 	var since time.Time
 	var err error
 	offset := registry.GetOffset(identifier)
@@ -30,6 +32,15 @@ func Since(registry auditor.Registry, identifier string, creationTime service.Cr
 	case creationTime == service.After:
 		// a new service has been discovered and was launched after the agent start, tail from the beginning
 		since = time.Time{}
+	case sourceConfig.StartPosition == config.StartPositionBeginning:
+		// the source starts from the beginning of the logs of the containers
+		since = time.Time{}
+	case strings.HasPrefix(sourceConfig.StartPosition, config.StartPositionSince):
+		// the source starts from a time, the end is used if it's invalid
+		var ok bool
+		if since, ok = sourceConfig.StartTime(time.Now()); !ok {
+			since = time.Now().UTC()
+		}
 	case creationTime == service.Before:
 		// a new config has been discovered and was launched before the agent start, tail from the end
 		since = time.Now().UTC()
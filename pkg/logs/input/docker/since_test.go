@@ -25,27 +25,41 @@ func TestSince(t *testing.T) {
 	var since time.Time
 	var err error
 
-	since, err = Since(registry, "", service.Before)
+	since, err = Since(registry, "", service.Before, &config.LogsConfig{})
 	assert.Nil(t, err)
 	assert.True(t, since.Equal(now) || since.After(now))
 
-	since, err = Since(registry, "", service.After)
+	since, err = Since(registry, "", service.After, &config.LogsConfig{})
 	assert.Nil(t, err)
 	assert.Equal(t, time.Time{}, since)
 
 	registry.SetOffset("2008-01-12T01:01:01.000000001Z")
-	since, err = Since(registry, "", service.Before)
+	since, err = Since(registry, "", service.Before, &config.LogsConfig{})
 	assert.Nil(t, err)
 	assert.Equal(t, "2008-01-12T01:01:01.000000001Z", since.Format(config.DateFormat))
 
 	// Not properly formated
 	registry.SetOffset("2008-01-12T01:01.000000001Z")
-	since, err = Since(registry, "", service.Before)
+	since, err = Since(registry, "", service.Before, &config.LogsConfig{})
 	assert.NotNil(t, err)
 	assert.True(t, since.After(now))
 
 	registry.SetOffset("foo")
-	since, err = Since(registry, "", service.Before)
+	since, err = Since(registry, "", service.Before, &config.LogsConfig{})
 	assert.NotNil(t, err)
 	assert.True(t, since.After(now))
+
+	registry.SetOffset("")
+	since, err = Since(registry, "", service.Before, &config.LogsConfig{StartPosition: config.StartPositionBeginning})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Time{}, since)
+
+	since, err = Since(registry, "", service.Before, &config.LogsConfig{StartPosition: "since=1h"})
+	assert.Nil(t, err)
+	assert.True(t, since.Before(now.Add(-59*time.Minute)) && since.After(now.Add(-61*time.Minute)))
+
+	// the containers launched after the agent start are tailed from the beginning whatever the position.
+	since, err = Since(registry, "", service.After, &config.LogsConfig{StartPosition: config.StartPositionEnd})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Time{}, since)
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/tag"
 
 	"github.com/docker/docker/api/types"
@@ -41,6 +42,8 @@ type Tailer struct {
 	cli         *client.Client
 	source      *config.LogSource
 	tagProvider tag.Provider
+	// service is the name the logs are tagged with as service and source when their source sets none.
+	service string
 
 	sleepDuration      time.Duration
 	shouldStop         bool
@@ -166,7 +169,12 @@ func (t *Tailer) readForever() {
 			// stop reading new logs from container
 			return
 		default:
-			inBuf := make([]byte, 4096)
+			// stop reading new logs from the container while they can not be sent
+			if !pipeline.WaitForCapacity(t.outputChan, t.stop) {
+				return
+			}
+			// the buffer is left to the garbage collector on errors as it can still be read into after a timeout
+			inBuf := message.GetBuffer(4096)
 			n, err := t.read(inBuf, readTimeout)
 			if err != nil { // an error occurred, stop from reading new logs
 				switch {
@@ -194,11 +202,12 @@ func (t *Tailer) readForever() {
 				}
 			}
 			if n == 0 {
+				message.PutBuffer(inBuf)
 				// wait for new data to come
 				t.wait()
 				continue
 			}
-			t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+			t.decoder.InputChan <- decoder.NewPooledInput(inBuf[:n])
 		}
 	}
 }
@@ -244,6 +253,10 @@ func (t *Tailer) forwardMessages() {
 			t.setLastSince(output.Timestamp)
 			origin.Identifier = t.Identifier()
 			origin.SetTags(t.tagProvider.GetTags())
+			if t.service != "" {
+				origin.SetService(t.service)
+				origin.SetSource(t.service)
+			}
 			output.Origin = origin
 			t.outputChan <- output
 		}
@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// archiveExtension is the extension of the archives a file is rotated to.
+const archiveExtension = ".gz"
+
+// BackfillLimits bounds the archives read to backfill the logs of a file,
+// a zero value means no limit.
+type BackfillLimits struct {
+	// MaxAge is the age above which an archive is not read.
+	MaxAge time.Duration
+	// MaxSize is the maximum compressed size of all the archives read.
+	MaxSize int64
+}
+
+// archivesToBackfill returns the paths of the gzip archives the file at path has been rotated to, e.g. app.log.1.gz,
+// the most recent archives are selected within limits and returned oldest first.
+func archivesToBackfill(path string, limits BackfillLimits, now time.Time) []string {
+	dir, base := filepath.Dir(path), filepath.Base(path)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Warnf("Could not list the archives of %s: %v", path, err)
+		return nil
+	}
+	var archives []os.FileInfo
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || name == base || !strings.HasPrefix(name, base) || !strings.HasSuffix(name, archiveExtension) {
+			continue
+		}
+		if limits.MaxAge > 0 && now.Sub(info.ModTime()) > limits.MaxAge {
+			continue
+		}
+		archives = append(archives, info)
+	}
+	sort.SliceStable(archives, func(i, j int) bool {
+		return archives[i].ModTime().After(archives[j].ModTime())
+	})
+
+	var paths []string
+	var size int64
+	for _, info := range archives {
+		if limits.MaxSize > 0 && size+info.Size() > limits.MaxSize {
+			break
+		}
+		size += info.Size()
+		paths = append([]string{filepath.Join(dir, info.Name())}, paths...)
+	}
+	return paths
+}
+
+// backfill sends the logs of the archives of the file, oldest first,
+// returns false if the tailer has been stopped in the meantime.
+func (t *Tailer) backfill() bool {
+	for _, path := range t.archives {
+		if !t.backfillArchive(path) {
+			return false
+		}
+	}
+	t.archives = nil
+	return true
+}
+
+// backfillArchive decompresses the archive at path and sends its logs,
+// their offsets are not tracked as they do not belong to the file.
+// It returns false if the tailer has been stopped in the meantime.
+func (t *Tailer) backfillArchive(path string) bool {
+	f, err := openFile(path)
+	if err != nil {
+		log.Warnf("Could not open archive %s: %v", path, err)
+		return true
+	}
+	defer f.Close()
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		log.Warnf("Could not decompress archive %s: %v", path, err)
+		return true
+	}
+	defer reader.Close()
+
+	log.Infof("Backfilling the logs of %s from %s", t.path, path)
+	d := decoder.InitializeDecoderWithEncoding(t.source, t.parser, t.encoding)
+	d.Start()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for output := range d.OutputChan {
+			origin := message.NewOrigin(t.source)
+			origin.SetTags(append(t.tags, t.tagProvider.GetTags()...))
+			output.Origin = origin
+			t.stats.AddLinesDecoded(1)
+			t.outputChan <- output
+		}
+	}()
+	defer func() {
+		// wait for the decoder to be flushed
+		d.Stop()
+		<-done
+	}()
+
+	for {
+		select {
+		case <-t.stop:
+			return false
+		default:
+			inBuf := message.GetBuffer(4096)
+			n, err := reader.Read(inBuf)
+			if n > 0 {
+				d.InputChan <- decoder.NewPooledInput(inBuf[:n])
+				t.stats.AddBytesRead(n)
+			} else {
+				message.PutBuffer(inBuf)
+			}
+			if err == io.EOF {
+				return true
+			}
+			if err != nil {
+				log.Warnf("Could not read archive %s: %v", path, err)
+				return true
+			}
+		}
+	}
+}
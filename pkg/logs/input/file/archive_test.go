@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func writeArchive(t *testing.T, path string, content string, modTime time.Time) {
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	writer := gzip.NewWriter(f)
+	_, err = writer.Write([]byte(content))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, f.Close())
+	assert.Nil(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestArchivesToBackfill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log-archive-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	path := filepath.Join(dir, "app.log")
+	writeArchive(t, path+".1.gz", "1\n", now.Add(-1*time.Hour))
+	writeArchive(t, path+".2.gz", "2\n", now.Add(-2*time.Hour))
+	writeArchive(t, path+".3.gz", "3\n", now.Add(-48*time.Hour))
+	writeArchive(t, filepath.Join(dir, "other.log.1.gz"), "other\n", now)
+	assert.Nil(t, ioutil.WriteFile(path+".1", []byte("1\n"), 0644))
+
+	archives := archivesToBackfill(path, BackfillLimits{}, now)
+	assert.Equal(t, []string{path + ".3.gz", path + ".2.gz", path + ".1.gz"}, archives)
+
+	archives = archivesToBackfill(path, BackfillLimits{MaxAge: 24 * time.Hour}, now)
+	assert.Equal(t, []string{path + ".2.gz", path + ".1.gz"}, archives)
+
+	info, err := os.Stat(path + ".1.gz")
+	assert.Nil(t, err)
+	archives = archivesToBackfill(path, BackfillLimits{MaxSize: info.Size()}, now)
+	assert.Equal(t, []string{path + ".1.gz"}, archives)
+
+	assert.Nil(t, archivesToBackfill(filepath.Join(dir, "missing", "app.log"), BackfillLimits{}, now))
+}
+
+func TestTailerBackfillsArchives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log-archive-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	writeArchive(t, path+".2.gz", "first\nsecond\n", time.Now())
+	writeArchive(t, path+".1.gz", "third\n", time.Now())
+	assert.Nil(t, ioutil.WriteFile(path, []byte("fourth\n"), 0644))
+
+	outputChan := make(chan *message.Message, 10)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	tailer := NewTailer(outputChan, source, path, 10*time.Millisecond, false)
+	tailer.archives = []string{path + ".2.gz", path + ".1.gz"}
+	assert.Nil(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	for _, content := range []string{"first", "second", "third"} {
+		msg := <-outputChan
+		assert.Equal(t, content, string(msg.Content))
+		assert.Equal(t, "", msg.Origin.Identifier)
+		assert.Equal(t, []string{"filename:app.log"}, msg.Origin.Tags())
+	}
+
+	msg := <-outputChan
+	assert.Equal(t, "fourth", string(msg.Content))
+	assert.Equal(t, tailer.Identifier(), msg.Origin.Identifier)
+	assert.Equal(t, "7", msg.Origin.Offset)
+}
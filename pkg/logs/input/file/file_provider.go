@@ -10,7 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/status"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -22,6 +22,22 @@ import (
 // files are tailed
 const openFilesLimitWarningType = "open_files_limit_warning"
 
+// The orders in which the files matching a wildcard path are tailed when they can't all be.
+const (
+	// WildcardByName tails the files in reverse lexicographical order.
+	WildcardByName = "by_name"
+	// WildcardByModificationTime tails the most recently modified files first.
+	WildcardByModificationTime = "by_modification_time"
+)
+
+// The policies applied when more files match the paths of the sources than the limit allows to tail.
+const (
+	// EvictNone keeps tailing the files already tailed, the other ones are tailed once a file is not tailed anymore.
+	EvictNone = "none"
+	// EvictLeastRecentlyWritten stops tailing the least recently written files to tail the most recently written ones.
+	EvictLeastRecentlyWritten = "least_recently_written"
+)
+
 // File represents a file to tail
 type File struct {
 	Path           string
@@ -40,25 +56,48 @@ func NewFile(path string, source *config.LogSource) *File {
 // Provider implements the logic to retrieve at most filesLimit Files defined in sources
 type Provider struct {
 	filesLimit      int
+	wildcardOrder   string
+	evictionPolicy  string
+	followSymlinks  bool
 	shouldLogErrors bool
+	// evicted holds the keys of the paths of the files left out by the last call to FilesToTail
+	// because they were written less recently than the ones returned.
+	evicted map[string]bool
 }
 
-// NewProvider returns a new Provider
-func NewProvider(filesLimit int) *Provider {
+// NewProvider returns a new Provider returning the files matching a wildcard path in wildcardOrder,
+// the files over filesLimit are picked according to evictionPolicy and the symlinks are replaced
+// with the files they link to when followSymlinks is set.
+func NewProvider(filesLimit int, wildcardOrder string, evictionPolicy string, followSymlinks bool) *Provider {
+	if wildcardOrder != WildcardByName && wildcardOrder != WildcardByModificationTime {
+		log.Warnf("Unknown wildcard selection mode %q, the files are selected %s", wildcardOrder, WildcardByName)
+		wildcardOrder = WildcardByName
+	}
+	if evictionPolicy != EvictNone && evictionPolicy != EvictLeastRecentlyWritten {
+		log.Warnf("Unknown open files eviction policy %q, the policy %s is applied", evictionPolicy, EvictNone)
+		evictionPolicy = EvictNone
+	}
 	return &Provider{
 		filesLimit:      filesLimit,
+		wildcardOrder:   wildcardOrder,
+		evictionPolicy:  evictionPolicy,
+		followSymlinks:  followSymlinks,
 		shouldLogErrors: true,
 	}
 }
 
 // FilesToTail returns all the Files matching paths in sources,
 // it cannot return more than filesLimit Files.
-// For now, there is no way to prioritize specific Files over others,
-// they are just returned in reverse lexicographical order, see `searchFiles`
+// The files matching a wildcard path are returned in reverse lexicographical order
+// or from the most recently modified one depending on wildcardOrder, see `searchFiles`,
+// the most recently written files of all the sources are returned with EvictLeastRecentlyWritten.
 func (p *Provider) FilesToTail(sources []*config.LogSource) []*File {
 	var filesToTail []*File
 	shouldLogErrors := p.shouldLogErrors
 	p.shouldLogErrors = false // Let's log errors on first run only
+	if p.evictionPolicy == EvictLeastRecentlyWritten {
+		return p.filesToTailByWriteTime(sources, shouldLogErrors)
+	}
 
 	for i := 0; i < len(sources); i++ {
 		source := sources[i]
@@ -82,17 +121,7 @@ func (p *Provider) FilesToTail(sources []*config.LogSource) []*File {
 			tailedFileCounter++
 		}
 
-		if len(filesToTail) >= p.filesLimit {
-			status.AddGlobalWarning(
-				openFilesLimitWarningType,
-				fmt.Sprintf(
-					"The limit on the maximum number of files in use (%d) has been reached. Increase this limit (thanks to the attribute logs_config.open_files_limit in datadog.yaml) or decrease the number of tailed file.",
-					p.filesLimit,
-				),
-			)
-		} else {
-			status.RemoveGlobalWarning(openFilesLimitWarningType)
-		}
+		p.setLimitWarning(len(filesToTail) >= p.filesLimit)
 
 		if isWildcardPath {
 			source.Messages.AddMessage(source.Config.Path, fmt.Sprintf("%d files tailed out of %d files matching", tailedFileCounter, len(files)))
@@ -107,26 +136,132 @@ func (p *Provider) FilesToTail(sources []*config.LogSource) []*File {
 	return filesToTail
 }
 
+// filesToTailByWriteTime returns the filesLimit most recently written files matching the paths of sources,
+// the files left out are recorded in evicted so that the tailers of the least recently written files
+// are stopped to tail the most recently written ones.
+func (p *Provider) filesToTailByWriteTime(sources []*config.LogSource, shouldLogErrors bool) []*File {
+	var candidates []*File
+	modTimes := make(map[*File]time.Time)
+	matching := make(map[*config.LogSource]int)
+	for _, source := range sources {
+		files, err := p.CollectFiles(source)
+		isWildcardPath := p.containsWildcard(source.Config.Path)
+		if err != nil {
+			source.Status.Error(err)
+			if isWildcardPath {
+				source.Messages.AddMessage(source.Config.Path, fmt.Sprintf("0 files tailed out of %d files matching", len(files)))
+			}
+			if shouldLogErrors {
+				log.Warnf("Could not collect files: %v", err)
+			}
+			continue
+		}
+		for _, file := range files {
+			file.IsWildcardPath = isWildcardPath
+			if info, err := os.Stat(file.Path); err == nil {
+				modTimes[file] = info.ModTime()
+			}
+			candidates = append(candidates, file)
+		}
+		matching[source] = len(files)
+	}
+
+	// the order of the sources and of the wildcard paths is used to break ties.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return modTimes[candidates[i]].After(modTimes[candidates[j]])
+	})
+	filesToTail := candidates
+	p.evicted = make(map[string]bool)
+	if len(candidates) > p.filesLimit {
+		filesToTail = candidates[:p.filesLimit]
+		for _, file := range candidates[p.filesLimit:] {
+			p.evicted[pathKey(file.Path)] = true
+		}
+	}
+	p.setLimitWarning(len(candidates) >= p.filesLimit)
+
+	tailed := make(map[*config.LogSource]int)
+	for _, file := range filesToTail {
+		tailed[file.Source]++
+	}
+	for _, source := range sources {
+		if count, exists := matching[source]; exists && p.containsWildcard(source.Config.Path) {
+			source.Messages.AddMessage(source.Config.Path, fmt.Sprintf("%d files tailed out of %d files matching", tailed[source], count))
+		}
+	}
+	return filesToTail
+}
+
+// isEvicted returns true if the file at path was left out by the last call to FilesToTail
+// because it was written less recently than the files returned.
+func (p *Provider) isEvicted(path string) bool {
+	return p.evicted[pathKey(path)]
+}
+
+// setLimitWarning displays a warning on the status while the limit on the number of files tailed is reached.
+func (p *Provider) setLimitWarning(reached bool) {
+	if !reached {
+		status.RemoveGlobalWarning(openFilesLimitWarningType)
+		return
+	}
+	status.AddGlobalWarning(
+		openFilesLimitWarningType,
+		fmt.Sprintf(
+			"The limit on the maximum number of files in use (%d) has been reached. Increase this limit (thanks to the attribute logs_config.open_files_limit in datadog.yaml) or decrease the number of tailed file.",
+			p.filesLimit,
+		),
+	)
+}
+
 // CollectFiles returns all the files matching the source path.
 func (p *Provider) CollectFiles(source *config.LogSource) ([]*File, error) {
 	path := source.Config.Path
 	fileExists := p.exists(path)
+	var files []*File
+	var err error
 	switch {
 	case fileExists:
-		return []*File{
+		files = []*File{
 			NewFile(path, source),
-		}, nil
+		}
 	case p.containsWildcard(path):
 		pattern := path
-		return p.searchFiles(pattern, source)
+		files, err = p.searchFiles(pattern, source)
 	default:
 		return nil, fmt.Errorf("file %s does not exist", path)
 	}
+	if err != nil || !p.followSymlinks {
+		return files, err
+	}
+	return p.resolveSymlinks(files), nil
+}
+
+// resolveSymlinks replaces the paths of files with the paths of the files they link to, through chains of symlinks,
+// so that a file is tailed once whatever the links it's found through, and tailed again when a link changes.
+// The files linking to the same file are only returned once and the links that can't be resolved,
+// e.g. the loops of symlinks or the links to removed files, are skipped.
+func (p *Provider) resolveSymlinks(files []*File) []*File {
+	resolved := files[:0]
+	targets := make(map[string]bool, len(files))
+	for _, file := range files {
+		target, err := filepath.EvalSymlinks(file.Path)
+		if err != nil {
+			log.Debugf("Could not resolve the symlinks of %s: %v", file.Path, err)
+			continue
+		}
+		if targets[pathKey(target)] {
+			continue
+		}
+		targets[pathKey(target)] = true
+		file.Path = target
+		resolved = append(resolved, file)
+	}
+	return resolved
 }
 
 // searchFiles returns all the files matching the source path pattern.
 func (p *Provider) searchFiles(pattern string, source *config.LogSource) ([]*File, error) {
-	paths, err := filepath.Glob(pattern)
+	paths, err := glob(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("malformed pattern, could not find any file: %s", pattern)
 	}
@@ -150,6 +285,18 @@ func (p *Provider) searchFiles(pattern string, source *config.LogSource) ([]*Fil
 	sort.SliceStable(paths, func(i, j int) bool {
 		return filepath.Base(paths[i]) > filepath.Base(paths[j])
 	})
+	if p.wildcardOrder == WildcardByModificationTime {
+		// the files that are still written to are the most recent ones, the names are used to break ties.
+		modTimes := make(map[string]time.Time, len(paths))
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil {
+				modTimes[path] = info.ModTime()
+			}
+		}
+		sort.SliceStable(paths, func(i, j int) bool {
+			return modTimes[paths[i]].After(modTimes[paths[j]])
+		})
+	}
 	for _, path := range paths {
 		files = append(files, NewFile(path, source))
 	}
@@ -168,5 +315,5 @@ func (p *Provider) exists(filePath string) bool {
 
 // containsWildcard returns true if the path contains any wildcard character
 func (p *Provider) containsWildcard(path string) bool {
-	return strings.ContainsAny(path, "*?[")
+	return hasMeta(path)
 }
@@ -11,7 +11,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 
@@ -75,7 +77,7 @@ func (suite *ProviderTestSuite) TearDownTest() {
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFile() {
 	path := fmt.Sprintf("%s/1/1.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardByName, EvictNone, false)
 	logSources := suite.newLogSources(path)
 	status.CreateSources(logSources)
 	files := fileProvider.FilesToTail(logSources)
@@ -88,7 +90,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFile() {
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromDirectory() {
 	path := fmt.Sprintf("%s/1/*.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardByName, EvictNone, false)
 	logSources := suite.newLogSources(path)
 	status.CreateSources(logSources)
 	files := fileProvider.FilesToTail(logSources)
@@ -111,7 +113,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromDirectory() {
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromAnyDirectoryWithRightPermissions() {
 	path := fmt.Sprintf("%s/*/*1.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardByName, EvictNone, false)
 	logSources := suite.newLogSources(path)
 	status.CreateSources(logSources)
 	files := fileProvider.FilesToTail(logSources)
@@ -126,7 +128,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromAnyDirectoryWi
 
 func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFileWithWildcard() {
 	path := fmt.Sprintf("%s/1/?.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardByName, EvictNone, false)
 	logSources := suite.newLogSources(path)
 	status.CreateSources(logSources)
 	files := fileProvider.FilesToTail(logSources)
@@ -150,7 +152,7 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsSpecificFileWithWildcard()
 func (suite *ProviderTestSuite) TestWildcardPathsAreSorted() {
 	filesLimit := 6
 	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
-	fileProvider := NewProvider(filesLimit)
+	fileProvider := NewProvider(filesLimit, WildcardByName, EvictNone, false)
 	logSources := suite.newLogSources(path)
 	files := fileProvider.FilesToTail(logSources)
 	suite.Equal(5, len(files))
@@ -164,9 +166,26 @@ func (suite *ProviderTestSuite) TestWildcardPathsAreSorted() {
 	suite.Equal(fmt.Sprintf("%s/1/1.log", suite.testDir), files[4].Path)
 }
 
+func (suite *ProviderTestSuite) TestWildcardPathsAreSortedByModificationTime() {
+	now := time.Now()
+	for i, name := range []string{"2/1.log", "1/1.log", "1/3.log", "2/2.log", "1/2.log"} {
+		modTime := now.Add(-time.Duration(i) * time.Hour)
+		suite.Nil(os.Chtimes(fmt.Sprintf("%s/%s", suite.testDir, name), modTime, modTime))
+	}
+	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
+	fileProvider := NewProvider(suite.filesLimit, WildcardByModificationTime, EvictNone, false)
+	logSources := suite.newLogSources(path)
+	status.CreateSources(logSources)
+	files := fileProvider.FilesToTail(logSources)
+	suite.Equal(3, len(files))
+	suite.Equal(fmt.Sprintf("%s/2/1.log", suite.testDir), files[0].Path)
+	suite.Equal(fmt.Sprintf("%s/1/1.log", suite.testDir), files[1].Path)
+	suite.Equal(fmt.Sprintf("%s/1/3.log", suite.testDir), files[2].Path)
+}
+
 func (suite *ProviderTestSuite) TestNumberOfFilesToTailDoesNotExceedLimit() {
 	path := fmt.Sprintf("%s/*/*.log", suite.testDir)
-	fileProvider := NewProvider(suite.filesLimit)
+	fileProvider := NewProvider(suite.filesLimit, WildcardByName, EvictNone, false)
 	logSources := suite.newLogSources(path)
 	status.CreateSources(logSources)
 	files := fileProvider.FilesToTail(logSources)
@@ -180,9 +199,34 @@ func (suite *ProviderTestSuite) TestNumberOfFilesToTailDoesNotExceedLimit() {
 	)
 }
 
+func (suite *ProviderTestSuite) TestFilesToTailAreTheMostRecentlyWrittenOfAllTheSources() {
+	now := time.Now()
+	for i, name := range []string{"2/2.log", "1/3.log", "2/1.log", "1/1.log", "1/2.log"} {
+		modTime := now.Add(-time.Duration(i) * time.Hour)
+		suite.Nil(os.Chtimes(fmt.Sprintf("%s/%s", suite.testDir, name), modTime, modTime))
+	}
+	fileProvider := NewProvider(suite.filesLimit, WildcardByName, EvictLeastRecentlyWritten, false)
+	logSources := []*config.LogSource{
+		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/1/*.log", suite.testDir)}),
+		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/2/*.log", suite.testDir)}),
+	}
+	status.CreateSources(logSources)
+	files := fileProvider.FilesToTail(logSources)
+	suite.Equal(3, len(files))
+	suite.Equal(fmt.Sprintf("%s/2/2.log", suite.testDir), files[0].Path)
+	suite.Equal(fmt.Sprintf("%s/1/3.log", suite.testDir), files[1].Path)
+	suite.Equal(fmt.Sprintf("%s/2/1.log", suite.testDir), files[2].Path)
+	suite.Equal([]string{"1 files tailed out of 3 files matching"}, logSources[0].Messages.GetMessages())
+	suite.Equal([]string{"2 files tailed out of 2 files matching"}, logSources[1].Messages.GetMessages())
+
+	suite.True(fileProvider.isEvicted(fmt.Sprintf("%s/1/1.log", suite.testDir)))
+	suite.True(fileProvider.isEvicted(fmt.Sprintf("%s/1/2.log", suite.testDir)))
+	suite.False(fileProvider.isEvicted(fmt.Sprintf("%s/2/2.log", suite.testDir)))
+}
+
 func (suite *ProviderTestSuite) TestAllWildcardPathsAreUpdated() {
 	filesLimit := 2
-	fileProvider := NewProvider(filesLimit)
+	fileProvider := NewProvider(filesLimit, WildcardByName, EvictNone, false)
 	logSources := []*config.LogSource{
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/1/*.log", suite.testDir)}),
 		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/2/*.log", suite.testDir)}),
@@ -232,3 +276,37 @@ func (suite *ProviderTestSuite) TestAllWildcardPathsAreUpdated() {
 func TestProviderTestSuite(t *testing.T) {
 	suite.Run(t, new(ProviderTestSuite))
 }
+
+func (suite *ProviderTestSuite) TestCollectFilesFollowsSymlinks() {
+	testDir, err := filepath.EvalSymlinks(suite.testDir)
+	suite.Nil(err)
+	linksDir := filepath.Join(testDir, "links")
+	suite.Nil(os.Mkdir(linksDir, os.ModePerm))
+	defer os.RemoveAll(linksDir)
+	// a chain of symlinks, like the ones of the kubernetes log files, a link to the same file, a loop and a dangling link.
+	suite.Nil(os.Symlink(filepath.Join(testDir, "1", "1.log"), filepath.Join(linksDir, "pod.log")))
+	suite.Nil(os.Symlink(filepath.Join(linksDir, "pod.log"), filepath.Join(linksDir, "container.log")))
+	suite.Nil(os.Symlink(filepath.Join(testDir, "1", "1.log"), filepath.Join(linksDir, "other.log")))
+	suite.Nil(os.Symlink(filepath.Join(linksDir, "loop2.log"), filepath.Join(linksDir, "loop1.log")))
+	suite.Nil(os.Symlink(filepath.Join(linksDir, "loop1.log"), filepath.Join(linksDir, "loop2.log")))
+	suite.Nil(os.Symlink(filepath.Join(testDir, "1", "missing.log"), filepath.Join(linksDir, "dangling.log")))
+
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: filepath.Join(linksDir, "*.log")})
+	files, err := NewProvider(suite.filesLimit, WildcardByName, EvictNone, false).CollectFiles(source)
+	suite.Nil(err)
+	suite.Len(files, 6)
+
+	files, err = NewProvider(suite.filesLimit, WildcardByName, EvictNone, true).CollectFiles(source)
+	suite.Nil(err)
+	suite.Len(files, 1)
+	suite.Equal(filepath.Join(testDir, "1", "1.log"), files[0].Path)
+
+	// the new target of a link is tailed.
+	suite.Nil(os.Remove(filepath.Join(linksDir, "pod.log")))
+	suite.Nil(os.Symlink(filepath.Join(testDir, "2", "2.log"), filepath.Join(linksDir, "pod.log")))
+	source = config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: filepath.Join(linksDir, "container.log")})
+	files, err = NewProvider(suite.filesLimit, WildcardByName, EvictNone, true).CollectFiles(source)
+	suite.Nil(err)
+	suite.Len(files, 1)
+	suite.Equal(filepath.Join(testDir, "2", "2.log"), files[0].Path)
+}
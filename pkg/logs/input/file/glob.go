@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// globFold returns the paths of the files matching pattern like filepath.Glob but ignoring case,
+// the paths hold the names of the files as found on the disk.
+func globFold(pattern string) ([]string, error) {
+	// check the pattern is well-formed
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !hasMeta(pattern) {
+		if _, err := os.Lstat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+	dir, file := filepath.Split(pattern)
+	dir = cleanGlobPath(dir)
+	if !hasMeta(dir) {
+		return globDirFold(dir, file, nil), nil
+	}
+	// the directory can not be the whole pattern as it holds a separator less.
+	dirs, err := globFold(dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, dir := range dirs {
+		matches = globDirFold(dir, file, matches)
+	}
+	return matches, nil
+}
+
+// globDirFold appends to matches the paths of the files of dir whose name matches pattern ignoring case.
+func globDirFold(dir string, pattern string, matches []string) []string {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return matches
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return matches
+	}
+	defer d.Close()
+	names, _ := d.Readdirnames(-1)
+	sort.Strings(names)
+	pattern = strings.ToLower(pattern)
+	for _, name := range names {
+		if matched, _ := filepath.Match(pattern, strings.ToLower(name)); matched {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	return matches
+}
+
+// cleanGlobPath prepares path for a glob matching, like filepath.Glob does.
+func cleanGlobPath(path string) string {
+	switch path {
+	case "":
+		return "."
+	case string(filepath.Separator):
+		return path
+	}
+	if volume := filepath.VolumeName(path); len(path) == len(volume)+1 {
+		// keep the separator of the root of the volume, e.g. C:\
+		return path
+	}
+	return path[:len(path)-1]
+}
+
+// hasMeta returns true if path holds any wildcard character.
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
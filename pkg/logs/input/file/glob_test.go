@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobFold(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glob-fold-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	for _, path := range []string{"W3SVC1/u_ex190301.LOG", "W3SVC1/u_ex190302.log", "W3SVC1/readme.txt", "w3svc2/U_EX190301.log"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, path), nil, 0644))
+	}
+
+	matches, err := globFold(filepath.Join(dir, "W3SVC1", "*.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "W3SVC1", "u_ex190301.LOG"), filepath.Join(dir, "W3SVC1", "u_ex190302.log")}, matches)
+
+	matches, err = globFold(filepath.Join(dir, "w3svc*", "u_ex190301.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "W3SVC1", "u_ex190301.LOG"), filepath.Join(dir, "w3svc2", "U_EX190301.log")}, matches)
+
+	matches, err = globFold(filepath.Join(dir, "W3SVC1", "readme.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "W3SVC1", "readme.txt")}, matches)
+
+	matches, err = globFold(filepath.Join(dir, "missing", "*.log"))
+	assert.Nil(t, err)
+	assert.Len(t, matches, 0)
+
+	_, err = globFold(filepath.Join(dir, "[", "*.log"))
+	assert.Equal(t, filepath.ErrBadPattern, err)
+}
@@ -17,13 +17,24 @@ const (
 	// Containerd log stream type
 	stdout = "stdout"
 	stderr = "stderr"
+	// partialFlag is the flag of the lines split by the container runtime, the last part of a line is flagged F.
+	partialFlag = "P"
 )
 
-// containerdFileParser parses containerd file logs
-var containerdFileParser *parser
+// maxPartialLineSize is the size above which the parts of a line are sent without waiting for its last part.
+const maxPartialLineSize = 256 * 1000
 
+// parser parses the file logs of containerd and CRI-O, it's not thread safe.
 type parser struct {
 	logParser.Parser
+	// partialContent is the content of the parts of a line received so far.
+	partialContent []byte
+}
+
+// newContainerdFileParser returns a parser for the file logs of containerd and CRI-O
+// reassembling the lines split by the container runtime.
+func newContainerdFileParser() *parser {
+	return &parser{}
 }
 
 // Parse parse log lines of containerd
@@ -31,6 +42,7 @@ type parser struct {
 // Timestamp ouputchannel partial_flag msg
 // Example:
 // 2018-09-20T11:54:11.753589172Z stdout F This is my message
+// The parts of a line flagged P are returned as an empty message until its last part.
 func (p *parser) Parse(msg []byte) (*message.Message, error) {
 	components, err := parse(msg)
 	if err != nil {
@@ -38,7 +50,18 @@ func (p *parser) Parse(msg []byte) (*message.Message, error) {
 	}
 	status := getContainerdStatus(components[1])
 
-	parsedMsg := message.NewMessage(components[3], nil, status)
+	content := components[3]
+	if len(p.partialContent) > 0 {
+		content = append(p.partialContent, content...)
+		p.partialContent = nil
+	}
+	if string(components[2]) == partialFlag && len(content) < maxPartialLineSize {
+		// wait for the next parts of the line
+		p.partialContent = content
+		return &message.Message{}, nil
+	}
+
+	parsedMsg := message.NewMessage(content, nil, status)
 	parsedMsg.Timestamp = string(components[0])
 	return parsedMsg, nil
 }
@@ -6,6 +6,7 @@
 package file
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -21,7 +22,7 @@ func TestGetContainerdSeverity(t *testing.T) {
 }
 
 func TestContainerdParserShouldSucceedWithValidInput(t *testing.T) {
-	parser := containerdFileParser
+	parser := newContainerdFileParser()
 	validMessage := containerdHeaderOut + " " + "anything"
 	containerdMsg, err := parser.Parse([]byte(validMessage))
 	assert.Nil(t, err)
@@ -30,14 +31,14 @@ func TestContainerdParserShouldSucceedWithValidInput(t *testing.T) {
 }
 
 func TestContainerdParserShouldHandleEmptyMessage(t *testing.T) {
-	parser := containerdFileParser
+	parser := newContainerdFileParser()
 	msg, err := parser.Parse([]byte(containerdHeaderOut))
 	assert.Nil(t, err)
 	assert.Equal(t, 0, len(msg.Content))
 }
 
 func TestContainerdParserShouldFailWithInvalidInput(t *testing.T) {
-	parser := containerdFileParser
+	parser := newContainerdFileParser()
 	// Only timestamp
 	var err error
 	log := []byte("2018-09-20T11:54:11.753589172Z foo")
@@ -51,3 +52,32 @@ func TestContainerdParserShouldFailWithInvalidInput(t *testing.T) {
 	_, err = parser.Parse(log)
 	assert.Nil(t, err)
 }
+
+func TestContainerdParserShouldReassemblePartialLines(t *testing.T) {
+	parser := newContainerdFileParser()
+	msg, err := parser.Parse([]byte("2018-09-20T11:54:11.753589172Z stdout P hello "))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(msg.Content))
+	msg, err = parser.Parse([]byte("2018-09-20T11:54:11.753589173Z stdout P big "))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(msg.Content))
+	msg, err = parser.Parse([]byte("2018-09-20T11:54:11.753589174Z stdout F world"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hello big world"), msg.Content)
+	assert.Equal(t, "2018-09-20T11:54:11.753589174Z", msg.Timestamp)
+
+	msg, err = parser.Parse([]byte(containerdHeaderOut + " " + "anything"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("anything"), msg.Content)
+}
+
+func TestContainerdParserShouldNotBufferTooLongPartialLines(t *testing.T) {
+	parser := newContainerdFileParser()
+	part := strings.Repeat("a", maxPartialLineSize/2)
+	msg, err := parser.Parse([]byte("2018-09-20T11:54:11.753589172Z stdout P " + part))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(msg.Content))
+	msg, err = parser.Parse([]byte("2018-09-20T11:54:11.753589172Z stdout P " + part))
+	assert.Nil(t, err)
+	assert.Equal(t, part+part, string(msg.Content))
+}
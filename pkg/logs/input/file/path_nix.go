@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !windows
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// glob returns the paths of the files matching pattern, the paths are case-sensitive on *nix OSes.
+func glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// pathKey returns the key identifying the file at path among the tailed files.
+func pathKey(path string) string {
+	return path
+}
+
+// isDeletePending returns false, the files removed while they're open only keep their data on *nix OSes,
+// a new file can be created at their path right away.
+func isDeletePending(file *os.File) bool {
+	return false
+}
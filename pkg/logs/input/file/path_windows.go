@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build windows
+
+package file
+
+import (
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32                      = windows.NewLazyDLL("kernel32.dll")
+	procGetFileInformationByHandleEx = modkernel32.NewProc("GetFileInformationByHandleEx")
+)
+
+// fileStandardInfo is the FILE_STANDARD_INFO structure of GetFileInformationByHandleEx.
+type fileStandardInfo struct {
+	allocationSize int64
+	endOfFile      int64
+	numberOfLinks  uint32
+	deletePending  byte
+	directory      byte
+}
+
+// fileStandardInfoClass is the FileStandardInfo value of the FILE_INFO_BY_HANDLE_CLASS enumeration.
+const fileStandardInfoClass = 1
+
+// glob returns the paths of the files matching pattern, the paths are case-insensitive on Windows,
+// e.g. C:\inetpub\logs\*.log matches the u_ex190301.LOG files of IIS.
+func glob(pattern string) ([]string, error) {
+	return globFold(pattern)
+}
+
+// pathKey returns the key identifying the file at path among the tailed files,
+// the same file can be referred to with paths of different cases on Windows.
+func pathKey(path string) string {
+	return strings.ToLower(path)
+}
+
+// isDeletePending returns true if file was removed while it's open, e.g. by ReplaceFile or by a rotation
+// deleting the file, Windows keeps the file at its path until all its handles are closed, which prevents
+// the writer from creating a new file there.
+func isDeletePending(file *os.File) bool {
+	var info fileStandardInfo
+	r, _, _ := procGetFileInformationByHandleEx.Call(file.Fd(), fileStandardInfoClass, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info))
+	return r != 0 && info.deletePending != 0
+}
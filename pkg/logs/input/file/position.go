@@ -7,9 +7,12 @@ package file
 
 import (
 	"io"
+	"os"
 	"strconv"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 )
 
 // Position returns the position from where logs should be collected.
@@ -35,3 +38,21 @@ func Position(registry auditor.Registry, identifier string, tailFromBeginning bo
 	}
 	return offset, whence, err
 }
+
+// startsFromBeginning returns true if a file of a source just added is tailed from its beginning when no offset
+// was registered for it: always with the beginning start position and never with the end one, only if it was
+// modified since the time of a since= start position as the lines of a file hold no time to start from,
+// and only when its source comes from a service discovery otherwise.
+func startsFromBeginning(file *File, discovered bool, now time.Time) bool {
+	switch file.Source.Config.StartPosition {
+	case config.StartPositionBeginning:
+		return true
+	case config.StartPositionEnd:
+		return false
+	}
+	if since, ok := file.Source.Config.StartTime(now); ok {
+		info, err := os.Stat(file.Path)
+		return err == nil && !info.ModTime().Before(since)
+	}
+	return discovered
+}
@@ -7,11 +7,15 @@ package file
 
 import (
 	"io"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 )
 
 func TestPosition(t *testing.T) {
@@ -43,3 +47,27 @@ func TestPosition(t *testing.T) {
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 }
+
+func TestStartsFromBeginning(t *testing.T) {
+	f, err := ioutil.TempFile("", "log-position-test-")
+	assert.Nil(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+	now := time.Now()
+	modTime := now.Add(-2 * time.Hour)
+	assert.Nil(t, os.Chtimes(f.Name(), modTime, modTime))
+
+	newFile := func(position string) *File {
+		return NewFile(f.Name(), config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: f.Name(), StartPosition: position}))
+	}
+
+	assert.False(t, startsFromBeginning(newFile(""), false, now))
+	assert.True(t, startsFromBeginning(newFile(""), true, now))
+	assert.True(t, startsFromBeginning(newFile(config.StartPositionBeginning), false, now))
+	assert.False(t, startsFromBeginning(newFile(config.StartPositionEnd), true, now))
+
+	// the files modified since the start time are read from their beginning.
+	assert.True(t, startsFromBeginning(newFile("since=3h"), false, now))
+	assert.False(t, startsFromBeginning(newFile("since=1h"), true, now))
+	assert.True(t, startsFromBeginning(newFile("since="+modTime.Add(-time.Minute).Format(time.RFC3339)), false, now))
+}
@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procRoot is the directory the processes are listed in.
+const procRoot = "/proc"
+
+// processName returns the name of a process holding path open, other than the agent, or an empty string if there is none.
+func processName(path string) string {
+	return processNameIn(procRoot, path, os.Getpid())
+}
+
+// processNameIn returns the command name of a process listed in procRoot, other than excludedPID,
+// with a file descriptor opened on path, or an empty string if there is none.
+func processNameIn(procRoot string, path string, excludedPID int) string {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	dirs, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return ""
+	}
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(dir.Name())
+		if err != nil || pid == excludedPID {
+			continue
+		}
+		fdDir := filepath.Join(procRoot, dir.Name(), "fd")
+		fds, err := ioutil.ReadDir(fdDir)
+		if err != nil {
+			// the process ended or is not readable by the agent
+			continue
+		}
+		for _, fd := range fds {
+			if target, err := os.Readlink(filepath.Join(fdDir, fd.Name())); err != nil || target != path {
+				continue
+			}
+			comm, err := ioutil.ReadFile(filepath.Join(procRoot, dir.Name(), "comm"))
+			if err != nil {
+				break
+			}
+			if name := strings.TrimSpace(string(comm)); name != "" {
+				return name
+			}
+			break
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessNameIn(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(procRoot)
+	path := filepath.Join(procRoot, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, nil, 0644))
+
+	addProcess := func(pid string, comm string, files ...string) {
+		fdDir := filepath.Join(procRoot, pid, "fd")
+		assert.Nil(t, os.MkdirAll(fdDir, 0755))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(procRoot, pid, "comm"), []byte(comm+"\n"), 0644))
+		for i, file := range files {
+			assert.Nil(t, os.Symlink(file, filepath.Join(fdDir, strconv.Itoa(i))))
+		}
+	}
+	addProcess("10", "agent", path)
+	addProcess("20", "bash", "/dev/null")
+	assert.Equal(t, "", processNameIn(procRoot, path, 10))
+
+	addProcess("30", "nginx", "/dev/null", path)
+	assert.Equal(t, "nginx", processNameIn(procRoot, path, 10))
+	assert.Equal(t, "", processNameIn(procRoot, filepath.Join(procRoot, "other.log"), 10))
+	assert.Equal(t, "", processNameIn(filepath.Join(procRoot, "missing"), path, 10))
+}
+
+func TestProcessNameInProc(t *testing.T) {
+	file, err := ioutil.TempFile("", "process-name-")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	comm, err := ioutil.ReadFile("/proc/self/comm")
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(string(comm)), processNameIn(procRoot, file.Name(), -1))
+	// the agent is not the process writing the files it tails.
+	assert.Equal(t, "", processName(file.Name()))
+}
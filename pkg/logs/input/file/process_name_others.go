@@ -0,0 +1,13 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !linux
+
+package file
+
+// processName returns an empty string, the processes holding a file open are only found on Linux.
+func processName(path string) string {
+	return ""
+}
@@ -14,11 +14,19 @@ import (
 // - renamed and recreated
 // - removed and recreated
 // - truncated
+// A tailer detects by itself that its file was truncated when it reaches the end of the file,
+// see handleTruncation, DidRotate catches the truncations happening in between.
+// On Windows, a file removed while it's open stays at its path until it's closed,
+// it's reported as rotated so that the tailer releases it for the new file to be created.
 func DidRotate(file *os.File, lastReadOffset int64) (bool, error) {
 	f, err := openFile(file.Name())
 	if err != nil {
+		if isDeletePending(file) {
+			return true, nil
+		}
 		return false, err
 	}
+	defer f.Close()
 
 	fi1, err := f.Stat()
 	if err != nil {
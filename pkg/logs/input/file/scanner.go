@@ -6,14 +6,17 @@
 package file
 
 import (
+	"io"
 	"sync/atomic"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
 )
@@ -31,28 +34,38 @@ type Scanner struct {
 	tailingLimit        int
 	fileProvider        *Provider
 	tailers             map[string]*Tailer
+	backfillLimits      BackfillLimits
+	autoTag             bool
 	registry            auditor.Registry
 	tailerSleepDuration time.Duration
+	health              *health.Handle
 	stop                chan struct{}
 }
 
-// NewScanner returns a new scanner.
-func NewScanner(sources *config.LogSources, tailingLimit int, pipelineProvider pipeline.Provider, registry auditor.Registry, tailerSleepDuration time.Duration) *Scanner {
+// NewScanner returns a new scanner tailing at most tailingLimit files, picked in wildcardOrder among the ones matching a wildcard path,
+// the tailed files are evicted to tail the ones over the limit according to evictionPolicy, the archives read to backfill the logs of the files tailed for the first time are bounded by backfillLimits.
+// When autoTag is set, the logs of the files without service are tagged with the name of the process writing them.
+// When followSymlinks is set, the files linked to by the symlinks matching the paths are tailed instead of the links.
+func NewScanner(sources *config.LogSources, tailingLimit int, wildcardOrder string, evictionPolicy string, backfillLimits BackfillLimits, autoTag bool, followSymlinks bool, pipelineProvider pipeline.Provider, registry auditor.Registry, tailerSleepDuration time.Duration) *Scanner {
 	return &Scanner{
 		pipelineProvider:    pipelineProvider,
 		tailingLimit:        tailingLimit,
 		addedSources:        sources.GetAddedForType(config.FileType),
 		removedSources:      sources.GetRemovedForType(config.FileType),
-		fileProvider:        NewProvider(tailingLimit),
+		fileProvider:        NewProvider(tailingLimit, wildcardOrder, evictionPolicy, followSymlinks),
 		tailers:             make(map[string]*Tailer),
+		backfillLimits:      backfillLimits,
+		autoTag:             autoTag,
 		registry:            registry,
 		tailerSleepDuration: tailerSleepDuration,
 		stop:                make(chan struct{}),
 	}
 }
 
-// Start starts the Scanner
+// Start starts the Scanner, it reports itself unhealthy when it's stuck starting or stopping tailers.
 func (s *Scanner) Start() {
+	s.health = health.Register("logs-file-scanner")
+	metrics.OpenFilesLimit.Set(int64(s.tailingLimit))
 	go s.run()
 }
 
@@ -66,9 +79,13 @@ func (s *Scanner) Stop() {
 // run checks periodically if there are new files to tail and the state of its tailers until stop
 func (s *Scanner) run() {
 	scanTicker := time.NewTicker(scanPeriod)
-	defer scanTicker.Stop()
+	defer func() {
+		scanTicker.Stop()
+		s.health.Deregister()
+	}()
 	for {
 		select {
+		case <-s.health.C:
 		case source := <-s.addedSources:
 			s.addSource(source)
 		case source := <-s.removedSources:
@@ -88,9 +105,10 @@ func (s *Scanner) cleanup() {
 	stopper := restart.NewParallelStopper()
 	for _, tailer := range s.tailers {
 		stopper.Add(tailer)
-		delete(s.tailers, tailer.path)
+		delete(s.tailers, pathKey(tailer.path))
 	}
 	stopper.Stop()
+	metrics.OpenFiles.Set(0)
 }
 
 // scan checks all the files we're expected to tail,
@@ -103,10 +121,11 @@ func (s *Scanner) cleanup() {
 func (s *Scanner) scan() {
 	files := s.fileProvider.FilesToTail(s.activeSources)
 	filesTailed := make(map[string]bool)
+	s.evictTailers()
 	tailersLen := len(s.tailers)
 
 	for _, file := range files {
-		tailer, isTailed := s.tailers[file.Path]
+		tailer, isTailed := s.tailers[pathKey(file.Path)]
 		if isTailed && atomic.LoadInt32(&tailer.shouldStop) != 0 {
 			// skip this tailer as it must be stopped
 			continue
@@ -124,7 +143,7 @@ func (s *Scanner) scan() {
 				continue
 			}
 			tailersLen++
-			filesTailed[file.Path] = true
+			filesTailed[pathKey(file.Path)] = true
 			continue
 		}
 
@@ -141,7 +160,7 @@ func (s *Scanner) scan() {
 			}
 		}
 
-		filesTailed[file.Path] = true
+		filesTailed[pathKey(file.Path)] = true
 	}
 
 	for path, tailer := range s.tailers {
@@ -151,12 +170,26 @@ func (s *Scanner) scan() {
 			s.stopTailer(tailer)
 		}
 	}
+	metrics.OpenFiles.Set(int64(len(s.tailers)))
+}
+
+// evictTailers stops the tailers of the files evicted by the file provider right away
+// so that the files written more recently can be tailed in the same scan.
+func (s *Scanner) evictTailers() {
+	for _, tailer := range s.tailers {
+		if s.fileProvider.isEvicted(tailer.path) {
+			log.Infof("Stopped tailing %s to tail more recently written files, the limit of %d open files is reached", tailer.path, s.tailingLimit)
+			metrics.FilesEvicted.Add(1)
+			s.stopTailer(tailer)
+		}
+	}
 }
 
 // addSource keeps track of the new source and launch new tailers for this source.
 func (s *Scanner) addSource(source *config.LogSource) {
 	s.activeSources = append(s.activeSources, source)
 	s.launchTailers(source)
+	metrics.OpenFiles.Set(int64(len(s.tailers)))
 }
 
 // removeSource removes the source from cache.
@@ -182,7 +215,7 @@ func (s *Scanner) launchTailers(source *config.LogSource) {
 		if len(s.tailers) >= s.tailingLimit {
 			return
 		}
-		if _, isTailed := s.tailers[file.Path]; isTailed {
+		if _, isTailed := s.tailers[pathKey(file.Path)]; isTailed {
 			continue
 		}
 		var tailFromBeginning bool
@@ -192,19 +225,24 @@ func (s *Scanner) launchTailers(source *config.LogSource) {
 			// FIXME: better detect a source that has been generated from a service discovery.
 			tailFromBeginning = true
 		}
-		s.startNewTailer(file, tailFromBeginning)
+		s.startNewTailer(file, startsFromBeginning(file, tailFromBeginning, time.Now()))
 	}
 }
 
 // startNewTailer creates a new tailer, making it tail from the last committed offset, the beginning or the end of the file,
 // returns true if the operation succeeded, false otherwise
 func (s *Scanner) startNewTailer(file *File, tailFromBeginning bool) bool {
-	tailer := s.createTailer(file, s.pipelineProvider.NextPipelineChan())
+	tailer := s.createTailer(file, s.pipelineProvider.PipelineChanForSource(file.Source))
 
 	offset, whence, err := Position(s.registry, tailer.Identifier(), tailFromBeginning)
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
+	if file.Source.Config.BackfillArchives && s.registry.GetOffset(tailer.Identifier()) == "" {
+		// the file has never been tailed, send the logs of its archives and then the ones of the file
+		tailer.archives = archivesToBackfill(file.Path, s.backfillLimits, time.Now())
+		offset, whence = 0, io.SeekStart
+	}
 
 	err = tailer.Start(offset, whence)
 	if err != nil {
@@ -212,14 +250,14 @@ func (s *Scanner) startNewTailer(file *File, tailFromBeginning bool) bool {
 		return false
 	}
 
-	s.tailers[file.Path] = tailer
+	s.tailers[pathKey(file.Path)] = tailer
 	return true
 }
 
 // stopTailer stops the tailer
 func (s *Scanner) stopTailer(tailer *Tailer) {
 	go tailer.Stop()
-	delete(s.tailers, tailer.path)
+	delete(s.tailers, pathKey(tailer.path))
 }
 
 // restartTailer safely stops tailer and starts a new one
@@ -234,11 +272,15 @@ func (s *Scanner) restartTailerAfterFileRotation(tailer *Tailer, file *File) boo
 		log.Warn(err)
 		return false
 	}
-	s.tailers[file.Path] = tailer
+	s.tailers[pathKey(file.Path)] = tailer
 	return true
 }
 
 // createTailer returns a new initialized tailer
 func (s *Scanner) createTailer(file *File, outputChan chan *message.Message) *Tailer {
-	return NewTailer(outputChan, file.Source, file.Path, s.tailerSleepDuration, file.IsWildcardPath)
+	tailer := NewTailer(outputChan, file.Source, file.Path, s.tailerSleepDuration, file.IsWildcardPath)
+	if s.autoTag && file.Source.Config.Service == "" {
+		tailer.service = processName(file.Path)
+	}
+	return tailer
 }
@@ -20,6 +20,7 @@ import (
 	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
 	"github.com/DataDog/datadog-agent/pkg/logs/status"
@@ -61,7 +62,7 @@ func (suite *ScannerTestSuite) SetupTest() {
 	suite.openFilesLimit = 100
 	suite.source = config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: suite.testPath})
 	sleepDuration := 20 * time.Millisecond
-	suite.s = NewScanner(config.NewLogSources(), suite.openFilesLimit, suite.pipelineProvider, auditor.NewRegistry(), sleepDuration)
+	suite.s = NewScanner(config.NewLogSources(), suite.openFilesLimit, WildcardByName, EvictNone, BackfillLimits{}, false, false, suite.pipelineProvider, auditor.NewRegistry(), sleepDuration)
 	suite.s.activeSources = append(suite.s.activeSources, suite.source)
 	status.CreateSources([]*config.LogSource{suite.source})
 	suite.s.scan()
@@ -80,6 +81,7 @@ func (suite *ScannerTestSuite) TestScannerStartsTailers() {
 	suite.Nil(err)
 	msg := <-suite.outputChan
 	suite.Equal("hello world", string(msg.Content))
+	suite.Equal(int64(1), metrics.OpenFiles.Value())
 }
 
 func (suite *ScannerTestSuite) TestScannerScanWithoutLogRotation() {
@@ -157,12 +159,13 @@ func (suite *ScannerTestSuite) TestScannerScanWithLogRotationCopyTruncate() {
 	_, err = suite.testFile.WriteString("third\n")
 	suite.Nil(err)
 
-	s.scan()
-	newTailer = s.tailers[source.Config.Path]
-	suite.True(tailer != newTailer)
-
 	msg = <-suite.outputChan
 	suite.Equal("third", string(msg.Content))
+
+	// the tailer tails the truncated file from the beginning by itself
+	s.scan()
+	newTailer = s.tailers[source.Config.Path]
+	suite.True(tailer == newTailer)
 }
 
 func (suite *ScannerTestSuite) TestScannerScanWithFileRemovedAndCreated() {
@@ -188,10 +191,12 @@ func (suite *ScannerTestSuite) TestLifeCycle() {
 	s := suite.s
 	suite.Equal(1, len(s.tailers))
 	s.Start()
+	suite.Equal(int64(suite.openFilesLimit), metrics.OpenFilesLimit.Value())
 
 	// all tailers should be stopped
 	s.Stop()
 	suite.Equal(0, len(s.tailers))
+	suite.Equal(int64(0), metrics.OpenFiles.Value())
 }
 
 func TestScannerTestSuite(t *testing.T) {
@@ -212,7 +217,7 @@ func TestScannerScanStartNewTailer(t *testing.T) {
 	path = fmt.Sprintf("%s/*.log", testDir)
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
-	scanner := NewScanner(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
+	scanner := NewScanner(config.NewLogSources(), openFilesLimit, WildcardByName, EvictNone, BackfillLimits{}, false, false, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
 	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
 	scanner.activeSources = append(scanner.activeSources, source)
 	status.Clear()
@@ -264,7 +269,7 @@ func TestScannerScanWithTooManyFiles(t *testing.T) {
 	path = fmt.Sprintf("%s/*.log", testDir)
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
-	scanner := NewScanner(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
+	scanner := NewScanner(config.NewLogSources(), openFilesLimit, WildcardByName, EvictNone, BackfillLimits{}, false, false, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
 	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
 	scanner.activeSources = append(scanner.activeSources, source)
 	status.Clear()
@@ -285,3 +290,46 @@ func TestScannerScanWithTooManyFiles(t *testing.T) {
 	scanner.scan()
 	assert.Equal(t, 2, len(scanner.tailers))
 }
+
+func TestScannerEvictsTheLeastRecentlyWrittenFiles(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-scanner-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	now := time.Now()
+	paths := []string{fmt.Sprintf("%s/1.log", testDir), fmt.Sprintf("%s/2.log", testDir), fmt.Sprintf("%s/other.txt", testDir)}
+	for i, path := range paths {
+		_, err = os.Create(path)
+		assert.Nil(t, err)
+		modTime := now.Add(time.Duration(i-len(paths)) * time.Hour)
+		assert.Nil(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	// the limit applies to the files of all the sources.
+	openFilesLimit := 2
+	sleepDuration := 20 * time.Millisecond
+	scanner := NewScanner(config.NewLogSources(), openFilesLimit, WildcardByName, EvictLeastRecentlyWritten, BackfillLimits{}, false, false, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
+	defer scanner.cleanup()
+	sources := []*config.LogSource{
+		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.log", testDir)}),
+		config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: paths[2]}),
+	}
+	scanner.activeSources = append(scanner.activeSources, sources...)
+	status.Clear()
+	status.CreateSources(sources)
+	defer status.Clear()
+
+	scanner.scan()
+	assert.Equal(t, 2, len(scanner.tailers))
+	assert.NotNil(t, scanner.tailers[paths[1]])
+	assert.NotNil(t, scanner.tailers[paths[2]])
+
+	// the least recently written file is evicted to tail the file just written.
+	evicted := metrics.FilesEvicted.Value()
+	assert.Nil(t, os.Chtimes(paths[0], now, now))
+	scanner.scan()
+	assert.Equal(t, 2, len(scanner.tailers))
+	assert.NotNil(t, scanner.tailers[paths[0]])
+	assert.NotNil(t, scanner.tailers[paths[2]])
+	assert.Equal(t, int64(1), metrics.FilesEvicted.Value()-evicted)
+}
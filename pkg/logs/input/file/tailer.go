@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,6 +21,8 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/tag"
 )
 
@@ -38,27 +41,43 @@ type Tailer struct {
 
 	readOffset    int64
 	decodedOffset int64
+	// truncations are the read offsets at which the file was found truncated and read again from its beginning,
+	// the decoded offset is rebased once it reaches them.
+	truncations   []int64
+	truncationsMu sync.Mutex
 
 	outputChan  chan *message.Message
 	decoder     *decoder.Decoder
 	source      *config.LogSource
 	tagProvider tag.Provider
+	parser      logParser.Parser
+	encoding    string
+	// service is the name the logs are tagged with as service and source when their source sets none.
+	service string
+	// archives are the gzip archives to backfill the logs of, oldest first, before tailing the file.
+	archives []string
+	// stats are the metrics of the tailer, published while it's running.
+	stats *metrics.TailerStats
 
 	sleepDuration time.Duration
 
 	closeTimeout  time.Duration
 	shouldStop    int32
 	didFileRotate int32
-	stop          chan struct{}
-	done          chan struct{}
+	// shouldDrain makes the tailer stop once it read its rotated file to the end.
+	shouldDrain int32
+	stop        chan struct{}
+	done        chan struct{}
 }
 
 // NewTailer returns an initialized Tailer
 func NewTailer(outputChan chan *message.Message, source *config.LogSource, path string, sleepDuration time.Duration, isWildcardPath bool) *Tailer {
 	var parser logParser.Parser
-	if source.GetSourceType() == config.ContainerdType {
-		parser = containerdFileParser
-	} else {
+	switch source.GetSourceType() {
+	case config.ContainerdType, config.CRIOType, config.PodmanType:
+		// the k8s-file log driver of podman writes the logs in the same format as containerd and CRI-O
+		parser = newContainerdFileParser()
+	default:
 		parser = logParser.NoopParser
 	}
 	var tagProvider tag.Provider
@@ -67,12 +86,16 @@ func NewTailer(outputChan chan *message.Message, source *config.LogSource, path
 	} else {
 		tagProvider = tag.NoopProvider
 	}
-	return &Tailer{
+	encoding := source.Config.Encoding
+	if encoding == "" {
+		encoding = detectEncoding(path)
+	}
+	t := &Tailer{
 		path:           path,
 		outputChan:     outputChan,
-		decoder:        decoder.InitializeDecoder(source, parser),
 		source:         source,
 		tagProvider:    tagProvider,
+		encoding:       encoding,
 		readOffset:     0,
 		sleepDuration:  sleepDuration,
 		closeTimeout:   defaultCloseTimeout,
@@ -80,6 +103,23 @@ func NewTailer(outputChan chan *message.Message, source *config.LogSource, path
 		done:           make(chan struct{}, 1),
 		isWildcardPath: isWildcardPath,
 	}
+	t.stats = metrics.NewTailerStats(t.lag)
+	t.parser = &countingParser{Parser: parser, stats: t.stats}
+	t.decoder = decoder.InitializeDecoderWithEncoding(source, t.parser, encoding)
+	return t
+}
+
+// detectEncoding returns the encoding of the file at path if it starts with a byte order mark,
+// or an empty string otherwise.
+func detectEncoding(path string) string {
+	f, err := openFile(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, 2)
+	n, _ := io.ReadFull(f, head)
+	return decoder.DetectEncoding(head[:n])
 }
 
 // Identifier returns a string that uniquely identifies a source
@@ -96,6 +136,7 @@ func (t *Tailer) Start(offset int64, whence int) error {
 	}
 	t.source.Status.Success()
 	t.source.AddInput(t.path)
+	metrics.Tailers.Set(t.Identifier(), t.stats)
 
 	t.tagProvider.Start()
 	go t.forwardMessages()
@@ -142,14 +183,22 @@ func (t *Tailer) buildTailerTags() []string {
 // until it is closed or the tailer is stopped.
 func (t *Tailer) readForever() {
 	defer t.onStop()
+	if !t.backfill() {
+		// the tailer was stopped while backfilling
+		return
+	}
 	for {
 		select {
 		case <-t.stop:
 			// stop reading data from file
 			return
 		default:
+			if !t.waitForCapacity() {
+				// the tailer was stopped while paused
+				return
+			}
 			// keep reading data from file
-			inBuf := make([]byte, 4096)
+			inBuf := message.GetBuffer(4096)
 			n, err := t.file.Read(inBuf)
 			if err != nil && err != io.EOF {
 				// an unexpected error occurred, stop the tailor
@@ -158,11 +207,19 @@ func (t *Tailer) readForever() {
 				return
 			}
 			if n == 0 {
+				message.PutBuffer(inBuf)
+				if atomic.LoadInt32(&t.shouldDrain) != 0 {
+					// the rotated file has been read to the end
+					return
+				}
+				if t.handleTruncation() {
+					continue
+				}
 				// wait for new data to come
 				t.wait()
 				continue
 			}
-			t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+			t.decoder.InputChan <- decoder.NewPooledInput(inBuf[:n])
 			t.incrementReadOffset(n)
 		}
 	}
@@ -192,16 +249,57 @@ func (t *Tailer) StopAfterFileRotation() {
 	t.source.RemoveInput(t.path)
 }
 
-// startStopTimer initialises and starts a timer to stop the tailor after the timeout
+// startStopTimer initialises and starts a timer to stop the tailor after the timeout,
+// the tailer stops once it has read the rest of the file so that no line is lost.
 func (t *Tailer) startStopTimer() {
 	stopTimer := time.NewTimer(t.closeTimeout)
 	<-stopTimer.C
-	t.stop <- struct{}{}
+	atomic.StoreInt32(&t.shouldDrain, 1)
+}
+
+// handleTruncation makes the tailer read its file from the beginning if it has been truncated,
+// e.g. by a copytruncate log rotation, and returns true if it did.
+func (t *Tailer) handleTruncation() bool {
+	if atomic.LoadInt32(&t.didFileRotate) != 0 {
+		// the file is not tailed anymore, only its remaining lines are
+		return false
+	}
+	info, err := t.file.Stat()
+	if err != nil {
+		return false
+	}
+	readOffset := t.GetReadOffset()
+	if info.Size() >= readOffset {
+		return false
+	}
+	log.Infof("File %s was truncated, tailing it from the beginning", t.path)
+	if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+		log.Warnf("Could not tail %s from the beginning: %v", t.path, err)
+		return false
+	}
+	t.truncationsMu.Lock()
+	t.truncations = append(t.truncations, readOffset)
+	t.truncationsMu.Unlock()
+	atomic.StoreInt64(&t.readOffset, 0)
+	return true
+}
+
+// rebaseOffset returns the offset in the file of the data decoded up to offset,
+// offset is counted from the beginning of the file before it was truncated while its data was being decoded.
+func (t *Tailer) rebaseOffset(offset int64) int64 {
+	t.truncationsMu.Lock()
+	defer t.truncationsMu.Unlock()
+	if len(t.truncations) > 0 && offset >= t.truncations[0] {
+		offset -= t.truncations[0]
+		t.truncations = t.truncations[1:]
+	}
+	return offset
 }
 
 // onStop finishes to stop the tailer
 func (t *Tailer) onStop() {
 	log.Info("Closing ", t.path)
+	metrics.Tailers.Remove(t.Identifier(), t.stats)
 	t.file.Close()
 	t.decoder.Stop()
 }
@@ -214,7 +312,7 @@ func (t *Tailer) forwardMessages() {
 		t.done <- struct{}{}
 	}()
 	for output := range t.decoder.OutputChan {
-		offset := t.decodedOffset + int64(output.RawDataLen)
+		offset := t.rebaseOffset(t.decodedOffset + int64(output.RawDataLen))
 		identifier := t.Identifier()
 		if !t.shouldTrackOffset() {
 			offset = 0
@@ -225,13 +323,31 @@ func (t *Tailer) forwardMessages() {
 		origin.Identifier = identifier
 		origin.Offset = strconv.FormatInt(offset, 10)
 		origin.SetTags(append(t.tags, t.tagProvider.GetTags()...))
+		if t.service != "" {
+			origin.SetService(t.service)
+			origin.SetSource(t.service)
+		}
 		output.Origin = origin
+		t.stats.AddLinesDecoded(1)
 		t.outputChan <- output
 	}
 }
 
 func (t *Tailer) incrementReadOffset(n int) {
 	atomic.AddInt64(&t.readOffset, int64(n))
+	t.stats.AddBytesRead(n)
+}
+
+// lag returns the number of bytes between the last byte read and the end of the file.
+func (t *Tailer) lag() int64 {
+	info, err := t.file.Stat()
+	if err != nil {
+		return 0
+	}
+	if lag := info.Size() - t.GetReadOffset(); lag > 0 {
+		return lag
+	}
+	return 0
 }
 
 // GetReadOffset returns the position of the last byte read in file
@@ -251,3 +367,31 @@ func (t *Tailer) shouldTrackOffset() bool {
 func (t *Tailer) wait() {
 	time.Sleep(t.sleepDuration)
 }
+
+// waitForCapacity pauses the tailer while its pipeline is saturated so that the file is not read,
+// and its offset not advanced, until the logs can be sent.
+// It returns false if the tailer was stopped in the meantime.
+func (t *Tailer) waitForCapacity() bool {
+	if !pipeline.IsSaturated(t.outputChan) {
+		return true
+	}
+	log.Debugf("Pausing the tailer of %s while its pipeline is saturated", t.path)
+	t.stats.SetPaused(true)
+	defer t.stats.SetPaused(false)
+	return pipeline.WaitForCapacity(t.outputChan, t.stop)
+}
+
+// countingParser counts the lines its parser fails to decode in the stats of the tailer.
+type countingParser struct {
+	logParser.Parser
+	stats *metrics.TailerStats
+}
+
+// Parse parses msg with the parser and counts the errors.
+func (p *countingParser) Parse(msg []byte) (*message.Message, error) {
+	output, err := p.Parser.Parse(msg)
+	if err != nil {
+		p.stats.AddDecodingErrors(1)
+	}
+	return output, err
+}
@@ -13,6 +13,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 )
 
 var chanSize = 10
@@ -158,6 +160,73 @@ func (suite *TailerTestSuite) TestRecoverTailing() {
 	suite.Equal(len(lines[0])+len(lines[1])+len(lines[2]), int(suite.tl.decodedOffset))
 }
 
+func (suite *TailerTestSuite) TestTailTruncatedFile() {
+	var msg *message.Message
+	var err error
+
+	suite.tl.StartFromBeginning()
+
+	_, err = suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	msg = <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+
+	// the file is truncated, as by a copytruncate log rotation
+	suite.Nil(suite.testFile.Truncate(0))
+	_, err = suite.testFile.Seek(0, io.SeekStart)
+	suite.Nil(err)
+	_, err = suite.testFile.WriteString("bye\n")
+	suite.Nil(err)
+
+	msg = <-suite.outputChan
+	suite.Equal("bye", string(msg.Content))
+	suite.Equal(len("bye\n"), toInt(msg.Origin.Offset))
+}
+
+func (suite *TailerTestSuite) TestTailRotatedFileToTheEnd() {
+	var msg *message.Message
+	var err error
+
+	suite.tl.closeTimeout = 200 * time.Millisecond
+	suite.tl.StartFromBeginning()
+
+	_, err = suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	msg = <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+
+	suite.tl.StopAfterFileRotation()
+
+	// the lines written to the rotated file before the tailer stops should be tailed
+	_, err = suite.testFile.WriteString("hello again\n")
+	suite.Nil(err)
+	msg = <-suite.outputChan
+	suite.Equal("hello again", string(msg.Content))
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&suite.tl.shouldStop) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	suite.Equal(int32(1), atomic.LoadInt32(&suite.tl.shouldStop))
+}
+
+func (suite *TailerTestSuite) TestTailUTF16FileWithByteOrderMark() {
+	var msg *message.Message
+	var err error
+
+	// little endian UTF-16 byte order mark followed by "hello\n"
+	line := []byte{0xFF, 0xFE, 'h', 0, 'e', 0, 'l', 0, 'l', 0, 'o', 0, '\n', 0}
+	_, err = suite.testFile.Write(line)
+	suite.Nil(err)
+
+	suite.tl = NewTailer(suite.outputChan, suite.source, suite.testPath, 10*time.Millisecond, false)
+	suite.tl.StartFromBeginning()
+
+	msg = <-suite.outputChan
+	suite.Equal("hello", string(msg.Content))
+	suite.Equal(len(line), toInt(msg.Origin.Offset))
+}
+
 func (suite *TailerTestSuite) TestTailerIdentifier() {
 	suite.tl.StartFromBeginning()
 	suite.Equal(fmt.Sprintf("file:%s/tailer.log", suite.testDir), suite.tl.Identifier())
@@ -176,6 +245,18 @@ func (suite *TailerTestSuite) TestOriginTagsWhenTailingFiles() {
 	suite.Equal("filename:"+filepath.Base(suite.testFile.Name()), tags[0])
 }
 
+func (suite *TailerTestSuite) TestOriginServiceWhenTailingFilesOfAProcess() {
+	suite.tl.service = "nginx"
+	suite.tl.StartFromBeginning()
+
+	_, err := suite.testFile.WriteString("foo\n")
+	suite.Nil(err)
+
+	msg := <-suite.outputChan
+	suite.Equal("nginx", msg.Origin.Service())
+	suite.Equal("nginx", msg.Origin.Source())
+}
+
 func (suite *TailerTestSuite) TestDirTagWhenTailingFiles() {
 
 	dirTaggedSource := config.NewLogSource("", &config.LogsConfig{
@@ -225,6 +306,83 @@ func (suite *TailerTestSuite) TestBuildTagsFileDir() {
 	suite.Equal("dirname:"+filepath.Dir(suite.testFile.Name()), tags[1])
 }
 
+func (suite *TailerTestSuite) TestTailerStats() {
+	lines := []string{"hello world\n", "hello again\n"}
+	for _, line := range lines {
+		_, err := suite.testFile.WriteString(line)
+		suite.Nil(err)
+	}
+
+	suite.tl.StartFromBeginning()
+	<-suite.outputChan
+	<-suite.outputChan
+
+	stats, isPublished := metrics.Tailers.Values()[suite.tl.Identifier()]
+	suite.True(isPublished)
+	suite.Equal(int64(len(lines[0])+len(lines[1])), stats.BytesRead)
+	suite.Equal(int64(2), stats.LinesDecoded)
+	suite.Equal(int64(0), stats.DecodingErrors)
+	suite.Equal(int64(0), stats.Lag)
+}
+
+func (suite *TailerTestSuite) TestTailerLag() {
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	suite.tl.Start(0, io.SeekEnd)
+	suite.Equal(int64(0), suite.tl.lag())
+
+	// this tailer is not started so that it does not read the file.
+	tailer := NewTailer(suite.outputChan, suite.source, suite.testPath, 10*time.Millisecond, false)
+	suite.Nil(tailer.setup(0, io.SeekStart))
+	defer tailer.file.Close()
+	suite.Equal(int64(len("hello world\n")), tailer.lag())
+
+	tailer.incrementReadOffset(len("hello"))
+	suite.Equal(int64(len(" world\n")), tailer.lag())
+}
+
+func (suite *TailerTestSuite) TestTailerPausesWhileThePipelineIsSaturated() {
+	for i := 0; i < chanSize; i++ {
+		suite.outputChan <- message.NewMessage([]byte("queued"), nil, "")
+	}
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+
+	suite.tl.StartFromBeginning()
+	time.Sleep(100 * time.Millisecond)
+	// the file is not read while the logs can not be sent.
+	suite.Equal(int64(0), suite.tl.GetReadOffset())
+	suite.True(metrics.Tailers.Values()[suite.tl.Identifier()].Paused)
+
+	for i := 0; i < chanSize; i++ {
+		<-suite.outputChan
+	}
+	msg := <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+	suite.Equal(int64(len("hello world\n")), suite.tl.GetReadOffset())
+	suite.False(metrics.Tailers.Values()[suite.tl.Identifier()].Paused)
+}
+
+func (suite *TailerTestSuite) TestStopPausedTailer() {
+	suite.tl.Start(0, io.SeekEnd)
+
+	outputChan := make(chan *message.Message, 1)
+	outputChan <- message.NewMessage([]byte("queued"), nil, "")
+	tailer := NewTailer(outputChan, suite.source, suite.testPath, 10*time.Millisecond, false)
+	tailer.StartFromBeginning()
+
+	stopped := make(chan struct{})
+	go func() {
+		tailer.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		suite.Fail("a paused tailer should be stopped")
+	}
+}
+
 func toInt(str string) int {
 	if value, err := strconv.ParseInt(str, 10, 64); err == nil {
 		return int(value)
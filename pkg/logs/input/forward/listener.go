@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package forward
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+)
+
+// A Listener accepts the TCP connections of the fluentd and fluent bit forwarders
+// and delegates the read operations to a tailer.
+type Listener struct {
+	pipelineProvider pipeline.Provider
+	source           *config.LogSource
+	hostname         string
+	listener         net.Listener
+	tailers          []*Tailer
+	mu               sync.Mutex
+	stop             chan struct{}
+}
+
+// NewListener returns an initialized Listener
+func NewListener(pipelineProvider pipeline.Provider, source *config.LogSource) *Listener {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return &Listener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		hostname:         hostname,
+		tailers:          []*Tailer{},
+		stop:             make(chan struct{}, 1),
+	}
+}
+
+// Start starts the listener to accepts new incoming connections.
+func (l *Listener) Start() {
+	log.Infof("Starting forward listener on port %d", l.source.Config.Port)
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", l.source.Config.Port))
+	if err != nil {
+		log.Errorf("Can't start forward listener on port %d: %v", l.source.Config.Port, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.listener = listener
+	l.source.Status.Success()
+	go l.run()
+}
+
+// Stop stops the listener from accepting new connections and all the active tailers.
+func (l *Listener) Stop() {
+	log.Infof("Stopping forward listener on port %d", l.source.Config.Port)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.listener == nil {
+		return
+	}
+	l.stop <- struct{}{}
+	l.listener.Close()
+	stopper := restart.NewParallelStopper()
+	for _, tailer := range l.tailers {
+		stopper.Add(tailer)
+	}
+	stopper.Stop()
+}
+
+// run accepts new TCP connections and create a dedicated tailer for each.
+func (l *Listener) run() {
+	defer l.listener.Close()
+	for {
+		select {
+		case <-l.stop:
+			// stop accepting new connections.
+			return
+		default:
+			conn, err := l.listener.Accept()
+			switch {
+			case err != nil && isClosedConnError(err):
+				return
+			case err != nil:
+				log.Warnf("Can't accept connection on port %d: %v", l.source.Config.Port, err)
+				l.source.Status.Error(err)
+				continue
+			default:
+				l.startNewTailer(conn)
+				l.source.Status.Success()
+			}
+		}
+	}
+}
+
+// startNewTailer creates and starts a new tailer that reads from the connection,
+// the tailer is released once the connection is closed.
+func (l *Listener) startNewTailer(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tailer := NewTailer(l.source, conn, l.pipelineProvider.PipelineChanForSource(l.source), l.hostname)
+	l.tailers = append(l.tailers, tailer)
+	tailer.Start()
+	go func() {
+		<-tailer.done
+		l.removeTailer(tailer)
+	}()
+}
+
+// removeTailer removes the tailer from the active tailers.
+func (l *Listener) removeTailer(tailer *Tailer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, t := range l.tailers {
+		if t == tailer {
+			l.tailers = append(l.tailers[:i], l.tailers[i+1:]...)
+			break
+		}
+	}
+}
+
+// isClosedConnError returns true if the error is related to a closed connection,
+// for more details, see: https://golang.org/src/internal/poll/fd.go#L18.
+func isClosedConnError(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package forward
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+// dial connects to the listener.
+func dial(t *testing.T, listener *Listener) net.Conn {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", listener.listener.Addr().(*net.TCPAddr).Port))
+	assert.NoError(t, err)
+	return conn
+}
+
+func TestListenerForwardsRecordsAndAcknowledgesChunks(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewListener(pp, config.NewLogSource("", &config.LogsConfig{Type: config.ForwardType}))
+	listener.Start()
+	defer listener.Stop()
+
+	conn := dial(t, listener)
+	defer conn.Close()
+	timestamp := time.Date(2019, 3, 1, 12, 0, 0, 500, time.UTC)
+	conn.Write(encode(t, []interface{}{"app.access", []interface{}{
+		[]interface{}{eventTime(timestamp), map[string]interface{}{"log": "hello world"}},
+		[]interface{}{eventTime(timestamp), map[string]interface{}{"log": "bye", "timestamp": "now"}},
+	}, map[string]interface{}{"chunk": "abc"}}))
+
+	msg := <-msgChan
+	assert.Equal(t, `{"log":"hello world","timestamp":"2019-03-01T12:00:00.0000005Z"}`, string(msg.Content))
+	assert.Equal(t, []string{"fluent_tag:app.access"}, msg.Origin.Tags())
+	msg = <-msgChan
+	assert.Equal(t, `{"log":"bye","timestamp":"now"}`, string(msg.Content))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	ack, err := msgp.NewReader(conn).ReadIntf()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"ack": "abc"}, ack)
+}
+
+func TestListenerAuthenticatesClients(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewListener(pp, config.NewLogSource("", &config.LogsConfig{Type: config.ForwardType, SharedKey: "secret"}))
+	listener.Start()
+	defer listener.Stop()
+
+	for _, sharedKey := range []string{"other", "secret"} {
+		conn := dial(t, listener)
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		r := msgp.NewReader(conn)
+		helo, err := r.ReadIntf()
+		if !assert.NoError(t, err) {
+			return
+		}
+		nonce := helo.([]interface{})[1].(map[string]interface{})["nonce"].([]byte)
+		conn.Write(encode(t, []interface{}{"PING", "client", "salt", sharedKeyDigest("salt", "client", nonce, sharedKey), "", ""}))
+		pong, err := r.ReadIntf()
+		assert.NoError(t, err)
+		authenticated := pong.([]interface{})[1].(bool)
+		assert.Equal(t, sharedKey == "secret", authenticated)
+		if !authenticated {
+			// the connection is closed by the listener
+			_, err = r.ReadIntf()
+			assert.Error(t, err)
+			continue
+		}
+		conn.Write(encode(t, []interface{}{"app", int64(1551441600), map[string]interface{}{"log": "authenticated"}}))
+		msg := <-msgChan
+		assert.Equal(t, `{"log":"authenticated","timestamp":"2019-03-01T12:00:00Z"}`, string(msg.Content))
+	}
+}
@@ -0,0 +1,323 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package forward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// The options of the messages of the forward protocol.
+const (
+	chunkOption      = "chunk"
+	compressedOption = "compressed"
+	gzipCompression  = "gzip"
+)
+
+// eventTimeExtension is the type of the msgpack extension holding the EventTime of an event,
+// the seconds and the nanoseconds since the epoch as two big-endian 32-bit integers.
+const eventTimeExtension = 0
+
+// An event is a record emitted by fluentd or fluent bit.
+type event struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// readMessage reads the next message of the forward protocol and returns its tag, its events and its options,
+// the messages can be in any of the Message, Forward, PackedForward and CompressedPackedForward modes:
+//
+//	Message:                   [tag, time, record, option]
+//	Forward:                   [tag, [[time, record], ...], option]
+//	(Compressed)PackedForward: [tag, msgpack stream of [time, record], option]
+func readMessage(r *msgp.Reader) (string, []event, map[string]interface{}, error) {
+	size, err := r.ReadArrayHeader()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if size < 2 {
+		return "", nil, nil, fmt.Errorf("invalid message of %d elements", size)
+	}
+	tag, err := readString(r)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	typ, err := r.NextType()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	var events []event
+	var entries []byte
+	read := uint32(2)
+	switch typ {
+	case msgp.ArrayType:
+		events, err = readEntries(r)
+	case msgp.StrType, msgp.BinType:
+		entries, err = readBytes(r)
+	default:
+		if size < 3 {
+			return "", nil, nil, fmt.Errorf("invalid message of %d elements", size)
+		}
+		var e event
+		e, err = readEvent(r)
+		events = []event{e}
+		read = 3
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+	options := make(map[string]interface{})
+	if size > read {
+		if typ, err = r.NextType(); err != nil {
+			return "", nil, nil, err
+		}
+		if typ == msgp.MapType {
+			err = r.ReadMapStrIntf(options)
+		} else {
+			err = r.Skip()
+		}
+		if err != nil {
+			return "", nil, nil, err
+		}
+		read++
+	}
+	for ; read < size; read++ {
+		if err := r.Skip(); err != nil {
+			return "", nil, nil, err
+		}
+	}
+	if entries != nil {
+		if events, err = readPackedEntries(entries, options[compressedOption] == gzipCompression); err != nil {
+			return "", nil, nil, err
+		}
+	}
+	return tag, events, options, nil
+}
+
+// readEntries reads an array of [time, record] entries.
+func readEntries(r *msgp.Reader) ([]event, error) {
+	size, err := r.ReadArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]event, 0, size)
+	for i := uint32(0); i < size; i++ {
+		e, err := readEntry(r)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// readPackedEntries reads the [time, record] entries of a msgpack stream, optionally compressed with gzip.
+func readPackedEntries(entries []byte, compressed bool) ([]event, error) {
+	if compressed {
+		reader, err := gzip.NewReader(bytes.NewReader(entries))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if entries, err = ioutil.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+	r := msgp.NewReader(bytes.NewReader(entries))
+	var events []event
+	for {
+		if _, err := r.NextType(); err == io.EOF {
+			return events, nil
+		}
+		e, err := readEntry(r)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+}
+
+// readEntry reads a [time, record] entry.
+func readEntry(r *msgp.Reader) (event, error) {
+	size, err := r.ReadArrayHeader()
+	if err != nil {
+		return event{}, err
+	}
+	if size < 2 {
+		return event{}, fmt.Errorf("invalid entry of %d elements", size)
+	}
+	e, err := readEvent(r)
+	if err != nil {
+		return event{}, err
+	}
+	for i := uint32(2); i < size; i++ {
+		if err := r.Skip(); err != nil {
+			return event{}, err
+		}
+	}
+	return e, nil
+}
+
+// readEvent reads the time followed by the record of an event.
+func readEvent(r *msgp.Reader) (event, error) {
+	value, err := r.ReadIntf()
+	if err != nil {
+		return event{}, err
+	}
+	timestamp, err := toTime(value)
+	if err != nil {
+		return event{}, err
+	}
+	value, err = r.ReadIntf()
+	if err != nil {
+		return event{}, err
+	}
+	record, ok := normalize(value).(map[string]interface{})
+	if !ok {
+		return event{}, fmt.Errorf("invalid record of type %T", value)
+	}
+	return event{time: timestamp, record: record}, nil
+}
+
+// toTime converts the time of an event, either an EventTime or a number of seconds since the epoch.
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case int64:
+		return time.Unix(v, 0), nil
+	case uint64:
+		return time.Unix(int64(v), 0), nil
+	case float64:
+		return time.Unix(0, int64(v*float64(time.Second))), nil
+	case *msgp.RawExtension:
+		if v.Type == eventTimeExtension && len(v.Data) == 8 {
+			return time.Unix(int64(binary.BigEndian.Uint32(v.Data[:4])), int64(binary.BigEndian.Uint32(v.Data[4:]))), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid event time of type %T", value)
+}
+
+// normalize converts the binary values of value to strings,
+// the strings are sent as binary values by the older forwarders.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+	}
+	return value
+}
+
+// readString reads a string or a binary value as a string.
+func readString(r *msgp.Reader) (string, error) {
+	value, err := readBytes(r)
+	return string(value), err
+}
+
+// readBytes reads a string or a binary value.
+func readBytes(r *msgp.Reader) ([]byte, error) {
+	typ, err := r.NextType()
+	if err != nil {
+		return nil, err
+	}
+	if typ == msgp.BinType {
+		return r.ReadBytes(nil)
+	}
+	return r.ReadStringAsBytes(nil)
+}
+
+// writeAck acknowledges the chunk of a message.
+func writeAck(w *msgp.Writer, chunk string) error {
+	if err := w.WriteMapHeader(1); err != nil {
+		return err
+	}
+	if err := w.WriteString("ack"); err != nil {
+		return err
+	}
+	if err := w.WriteString(chunk); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// handshake authenticates the client with the shared key, returns an error if it failed:
+//
+//	server: ["HELO", {"nonce": nonce, "auth": "", "keepalive": true}]
+//	client: ["PING", client_hostname, shared_key_salt, sha512_hex(shared_key_salt + client_hostname + nonce + shared_key), username, password]
+//	server: ["PONG", authenticated, reason, server_hostname, sha512_hex(shared_key_salt + server_hostname + nonce + shared_key)]
+func handshake(r *msgp.Reader, w *msgp.Writer, nonce []byte, sharedKey, hostname string) error {
+	err := writeValues(w, "HELO", map[string]interface{}{"nonce": nonce, "auth": "", "keepalive": true})
+	if err != nil {
+		return err
+	}
+	size, err := r.ReadArrayHeader()
+	if err != nil {
+		return err
+	}
+	if size < 4 {
+		return fmt.Errorf("invalid PING message of %d elements", size)
+	}
+	var values []string
+	for i := uint32(0); i < 4; i++ {
+		value, err := readString(r)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+	}
+	for i := uint32(4); i < size; i++ {
+		if err := r.Skip(); err != nil {
+			return err
+		}
+	}
+	if values[0] != "PING" {
+		return fmt.Errorf("expected a PING message, got %s", values[0])
+	}
+	clientHostname, salt, digest := values[1], values[2], values[3]
+	if digest != sharedKeyDigest(salt, clientHostname, nonce, sharedKey) {
+		writeValues(w, "PONG", false, "shared_key mismatch", hostname, "")
+		return fmt.Errorf("shared key mismatch for client %s", clientHostname)
+	}
+	return writeValues(w, "PONG", true, "", hostname, sharedKeyDigest(salt, hostname, nonce, sharedKey))
+}
+
+// sharedKeyDigest returns the hexadecimal SHA512 digest of the salt, the hostname, the nonce and the shared key.
+func sharedKeyDigest(salt, hostname string, nonce []byte, sharedKey string) string {
+	digest := sha512.New()
+	digest.Write([]byte(salt))
+	digest.Write([]byte(hostname))
+	digest.Write(nonce)
+	digest.Write([]byte(sharedKey))
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// writeValues writes the values as an array and flushes the writer.
+func writeValues(w *msgp.Writer, values ...interface{}) error {
+	if err := w.WriteArrayHeader(uint32(len(values))); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := w.WriteIntf(value); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package forward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// eventTime returns the EventTime extension of timestamp.
+func eventTime(timestamp time.Time) *msgp.RawExtension {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data[:4], uint32(timestamp.Unix()))
+	binary.BigEndian.PutUint32(data[4:], uint32(timestamp.Nanosecond()))
+	return &msgp.RawExtension{Type: eventTimeExtension, Data: data}
+}
+
+// encode returns the msgpack encoding of the values.
+func encode(t *testing.T, values ...interface{}) []byte {
+	var buf bytes.Buffer
+	w := msgp.NewWriter(&buf)
+	for _, value := range values {
+		assert.NoError(t, w.WriteIntf(value))
+	}
+	assert.NoError(t, w.Flush())
+	return buf.Bytes()
+}
+
+func TestReadMessageModes(t *testing.T) {
+	timestamp := time.Unix(1551441600, 500)
+	record := map[string]interface{}{"log": "hello world"}
+	entries := append(encode(t, []interface{}{eventTime(timestamp), record}), encode(t, []interface{}{int64(1551441601), map[string]interface{}{"log": []byte("bye")}})...)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(entries)
+	gz.Close()
+
+	messages := [][]byte{
+		encode(t, []interface{}{"app", eventTime(timestamp), record}),
+		encode(t, []interface{}{"app", []interface{}{[]interface{}{eventTime(timestamp), record}, []interface{}{int64(1551441601), map[string]interface{}{"log": "bye"}}}, map[string]interface{}{"chunk": "abc"}}),
+		encode(t, []interface{}{"app", entries}),
+		encode(t, []interface{}{"app", compressed.Bytes(), map[string]interface{}{"compressed": "gzip", "size": 2}}),
+	}
+	for i, data := range messages {
+		tag, events, options, err := readMessage(msgp.NewReader(bytes.NewReader(data)))
+		assert.NoError(t, err)
+		assert.Equal(t, "app", tag)
+		if i == 0 {
+			assert.Len(t, events, 1)
+		} else {
+			assert.Len(t, events, 2)
+			assert.Equal(t, time.Unix(1551441601, 0), events[1].time)
+			assert.Equal(t, map[string]interface{}{"log": "bye"}, events[1].record)
+		}
+		assert.Equal(t, timestamp, events[0].time)
+		assert.Equal(t, record, events[0].record)
+		if i == 1 {
+			assert.Equal(t, map[string]interface{}{"chunk": "abc"}, options)
+		}
+	}
+}
+
+func TestReadMessageFailsWithInvalidMessages(t *testing.T) {
+	messages := [][]byte{
+		encode(t, []interface{}{"app"}),
+		encode(t, []interface{}{"app", int64(1551441600)}),
+		encode(t, []interface{}{"app", "not an event time", map[string]interface{}{}}),
+		encode(t, []interface{}{"app", int64(1551441600), "not a record"}),
+		encode(t, []interface{}{"app", []byte("not gzip"), map[string]interface{}{"compressed": "gzip"}}),
+	}
+	for _, data := range messages {
+		_, _, _, err := readMessage(msgp.NewReader(bytes.NewReader(data)))
+		assert.Error(t, err)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	nonce := []byte("0123456789abcdef")
+	for _, digest := range []string{sharedKeyDigest("salt", "client", nonce, "secret"), sharedKeyDigest("salt", "client", nonce, "other")} {
+		var output bytes.Buffer
+		ping := encode(t, []interface{}{"PING", "client", "salt", digest, "", ""})
+		err := handshake(msgp.NewReader(bytes.NewReader(ping)), msgp.NewWriter(&output), nonce, "secret", "server")
+
+		r := msgp.NewReader(&output)
+		helo, herr := r.ReadIntf()
+		assert.NoError(t, herr)
+		assert.Equal(t, []interface{}{"HELO", map[string]interface{}{"nonce": nonce, "auth": "", "keepalive": true}}, helo)
+		pong, perr := r.ReadIntf()
+		assert.NoError(t, perr)
+		if digest == sharedKeyDigest("salt", "client", nonce, "secret") {
+			assert.NoError(t, err)
+			assert.Equal(t, []interface{}{"PONG", true, "", "server", sharedKeyDigest("salt", "server", nonce, "secret")}, pong)
+		} else {
+			assert.Error(t, err)
+			assert.Equal(t, []interface{}{"PONG", false, "shared_key mismatch", "server", ""}, pong)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package forward
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// defaultTimeout represents the time after which a connection is closed when no data is read
+const defaultTimeout = time.Minute
+
+// timestampKey is the key of the record holding the time of the event.
+const timestampKey = "timestamp"
+
+// Tailer reads the messages of the forward protocol from a connection
+type Tailer struct {
+	source     *config.LogSource
+	conn       net.Conn
+	outputChan chan *message.Message
+	hostname   string
+	done       chan struct{}
+}
+
+// NewTailer returns a new Tailer
+func NewTailer(source *config.LogSource, conn net.Conn, outputChan chan *message.Message, hostname string) *Tailer {
+	return &Tailer{
+		source:     source,
+		conn:       conn,
+		outputChan: outputChan,
+		hostname:   hostname,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts reading the messages from the connection
+func (t *Tailer) Start() {
+	go t.readForever()
+}
+
+// Stop closes the connection and waits for the messages being read to be forwarded
+func (t *Tailer) Stop() {
+	t.conn.Close()
+	<-t.done
+}
+
+// readForever authenticates the client when the source has a shared key,
+// then reads the messages from conn and acknowledges them when requested.
+func (t *Tailer) readForever() {
+	defer func() {
+		t.conn.Close()
+		close(t.done)
+	}()
+	r := msgp.NewReader(t.conn)
+	w := msgp.NewWriter(t.conn)
+	if t.source.Config.SharedKey != "" {
+		t.conn.SetReadDeadline(time.Now().Add(defaultTimeout))
+		nonce := make([]byte, 16)
+		rand.Read(nonce)
+		if err := handshake(r, w, nonce, t.source.Config.SharedKey, t.hostname); err != nil {
+			log.Warnf("Couldn't authenticate forward client %s: %v", t.conn.RemoteAddr(), err)
+			t.source.Status.Error(err)
+			return
+		}
+	}
+	for {
+		t.conn.SetReadDeadline(time.Now().Add(defaultTimeout))
+		tag, events, options, err := readMessage(r)
+		if err == io.EOF || err != nil && isClosedConnError(err) {
+			// the connection has been closed, stop from reading new data
+			return
+		}
+		if err != nil {
+			log.Warnf("Couldn't read message from connection: %v", err)
+			t.source.Status.Error(err)
+			return
+		}
+		for _, e := range events {
+			msg, err := t.toMessage(tag, e)
+			if err != nil {
+				log.Warnf("Couldn't encode record with tag %s: %v", tag, err)
+				continue
+			}
+			t.outputChan <- msg
+		}
+		if chunk, ok := normalize(options[chunkOption]).(string); ok {
+			if err := writeAck(w, chunk); err != nil {
+				log.Warnf("Couldn't acknowledge chunk %s: %v", chunk, err)
+				return
+			}
+		}
+	}
+}
+
+// toMessage converts an event to a message holding its record as a JSON object,
+// with the time of the event when the record has no timestamp.
+func (t *Tailer) toMessage(tag string, e event) (*message.Message, error) {
+	if _, exists := e.record[timestampKey]; !exists {
+		e.record[timestampKey] = e.time.UTC().Format(time.RFC3339Nano)
+	}
+	content, err := json.Marshal(e.record)
+	if err != nil {
+		return nil, err
+	}
+	origin := message.NewOrigin(t.source)
+	origin.SetTags([]string{"fluent_tag:" + tag})
+	return message.NewMessage(content, origin, message.StatusInfo), nil
+}
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package intake
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// parsePayload returns the events of the body of a request, a JSON payload is either a single event
+// or an array of events, the string events are sent as is and the other ones as JSON on a single line.
+// The other payloads hold one event per line.
+func parsePayload(body []byte, isJSON bool) ([][]byte, error) {
+	if !isJSON {
+		return parseLines(body), nil
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(body, &raws); err != nil {
+			return nil, err
+		}
+		events := make([][]byte, 0, len(raws))
+		for _, raw := range raws {
+			event, err := parseJSONEvent(raw)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+		}
+		return events, nil
+	}
+	event, err := parseJSONEvent(body)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{event}, nil
+}
+
+// parseJSONEvent returns the content of a JSON event.
+func parseJSONEvent(raw []byte) ([]byte, error) {
+	var content string
+	if err := json.Unmarshal(raw, &content); err == nil {
+		return []byte(content), nil
+	}
+	var event bytes.Buffer
+	if err := json.Compact(&event, raw); err != nil {
+		return nil, err
+	}
+	return event.Bytes(), nil
+}
+
+// parseLines returns the non-empty lines of body.
+func parseLines(body []byte) [][]byte {
+	var events [][]byte
+	for _, line := range bytes.Split(body, []byte{'\n'}) {
+		if line = bytes.TrimRight(line, "\r"); len(line) > 0 {
+			events = append(events, line)
+		}
+	}
+	return events
+}
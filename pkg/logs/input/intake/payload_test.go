@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package intake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// toStrings converts events to strings.
+func toStrings(events [][]byte) []string {
+	var strs []string
+	for _, event := range events {
+		strs = append(strs, string(event))
+	}
+	return strs
+}
+
+func TestParsePayloadWithLines(t *testing.T) {
+	events, err := parsePayload([]byte("hello\r\n\nworld\n"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hello", "world"}, toStrings(events))
+}
+
+func TestParsePayloadWithJSONArray(t *testing.T) {
+	events, err := parsePayload([]byte(` ["hello", {"message": "world", "level": "info"}, 42]`), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hello", `{"message":"world","level":"info"}`, "42"}, toStrings(events))
+}
+
+func TestParsePayloadWithJSONObject(t *testing.T) {
+	events, err := parsePayload([]byte("{\n  \"message\": \"hello\"\n}\n"), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"message":"hello"}`}, toStrings(events))
+}
+
+func TestParsePayloadWithInvalidJSON(t *testing.T) {
+	for _, body := range []string{"hello", `["hello"`, `{"message":}`} {
+		_, err := parsePayload([]byte(body), true)
+		assert.Error(t, err, body)
+	}
+}
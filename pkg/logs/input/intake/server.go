@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package intake
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+)
+
+// inputPath is the path of the endpoint receiving the logs.
+const inputPath = "/v1/input"
+
+// maxRequestSize is the maximum size of the body of a request once uncompressed.
+const maxRequestSize = 5 * 1024 * 1024
+
+// stopTimeout is the time given to the requests being processed to complete when the server is stopped.
+const stopTimeout = 5 * time.Second
+
+// A Server receives the logs of the applications posted over HTTP on the port of its source.
+// The requests must present the token of the source, when it's set, either as a bearer token
+// or in the DD-API-KEY header so that no API key has to be shared with the applications.
+// The service, the source and the tags of the logs of a request can be set with the "service",
+// "ddsource" and "ddtags" query parameters, those of the source configuration take precedence.
+type Server struct {
+	source     *config.LogSource
+	outputChan chan *message.Message
+	httpServer *http.Server
+}
+
+// NewServer returns an initialized Server
+func NewServer(pipelineProvider pipeline.Provider, source *config.LogSource) *Server {
+	return &Server{
+		source:     source,
+		outputChan: pipelineProvider.PipelineChanForSource(source),
+	}
+}
+
+// Start starts listening to the port of the source.
+func (s *Server) Start() {
+	log.Infof("Starting HTTP intake on port %d", s.source.Config.Port)
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.source.Config.Port))
+	if err != nil {
+		log.Errorf("Can't listen on port %d: %v", s.source.Config.Port, err)
+		s.source.Status.Error(err)
+		return
+	}
+	s.source.Status.Success()
+	mux := http.NewServeMux()
+	mux.HandleFunc(inputPath, s.handle)
+	s.httpServer = &http.Server{Handler: mux}
+	go s.httpServer.Serve(listener)
+}
+
+// Stop stops the server and waits for the requests being processed to complete.
+func (s *Server) Stop() {
+	log.Infof("Stopping HTTP intake on port %d", s.source.Config.Port)
+	if s.httpServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	s.httpServer.Shutdown(ctx)
+}
+
+// handle forwards the events of a request to the pipeline, the request returns once all of them have been forwarded.
+func (s *Server) handle(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.isAuthorized(req) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		reader, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer reader.Close()
+		body = reader
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxRequestSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxRequestSize {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	events, err := parsePayload(data, strings.HasPrefix(req.Header.Get("Content-Type"), "application/json"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	query := req.URL.Query()
+	var tags []string
+	if ddtags := query.Get("ddtags"); ddtags != "" {
+		tags = strings.Split(ddtags, ",")
+	}
+	for _, event := range events {
+		origin := message.NewOrigin(s.source)
+		origin.SetService(query.Get("service"))
+		origin.SetSource(query.Get("ddsource"))
+		origin.SetTags(tags)
+		select {
+		case s.outputChan <- message.NewMessage(event, origin, message.StatusInfo):
+		case <-req.Context().Done():
+			http.Error(w, req.Context().Err().Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("{}"))
+}
+
+// isAuthorized returns true if the source has no token or if the request presents it.
+func (s *Server) isAuthorized(req *http.Request) bool {
+	token := s.source.Config.AuthToken
+	if token == "" {
+		return true
+	}
+	presented := req.Header.Get("DD-API-KEY")
+	if authorization := req.Header.Get("Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+		presented = strings.TrimPrefix(authorization, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
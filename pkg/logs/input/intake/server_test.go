@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package intake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/testutil"
+)
+
+// freePort returns a port available on the loopback interface.
+// post sends the request in the background as the server returns once the messages have been consumed.
+func post(req *http.Request) chan *http.Response {
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			close(responses)
+			return
+		}
+		responses <- resp
+	}()
+	return responses
+}
+
+func TestServerForwardsEvents(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	port := testutil.FreePort(t)
+	server := NewServer(pp, config.NewLogSource("", &config.LogsConfig{Type: config.HTTPType, Port: port}))
+	server.Start()
+	defer server.Stop()
+	url := fmt.Sprintf("http://127.0.0.1:%d/v1/input?service=app&ddsource=go&ddtags=env:prod,team:logs", port)
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader("hello\nworld\n"))
+	assert.NoError(t, err)
+	responses := post(req)
+	msg := <-msgChan
+	assert.Equal(t, "hello", string(msg.Content))
+	assert.Equal(t, "app", msg.Origin.Service())
+	assert.Equal(t, "go", msg.Origin.Source())
+	assert.Equal(t, []string{"env:prod", "team:logs"}, msg.Origin.Tags())
+	msg = <-msgChan
+	assert.Equal(t, "world", string(msg.Content))
+	resp, ok := <-responses
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	var body bytes.Buffer
+	writer := gzip.NewWriter(&body)
+	writer.Write([]byte(`[{"message":"compressed"}]`))
+	writer.Close()
+	req, err = http.NewRequest(http.MethodPost, url, &body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	responses = post(req)
+	msg = <-msgChan
+	assert.Equal(t, `{"message":"compressed"}`, string(msg.Content))
+	resp, ok = <-responses
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+}
+
+func TestServerRejectsInvalidRequests(t *testing.T) {
+	pp := mock.NewMockProvider()
+	port := testutil.FreePort(t)
+	server := NewServer(pp, config.NewLogSource("", &config.LogsConfig{Type: config.HTTPType, Port: port}))
+	server.Start()
+	defer server.Stop()
+	url := fmt.Sprintf("http://127.0.0.1:%d/v1/input", port)
+
+	resp, err := http.Get(url)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	resp, err = http.Post(url, "application/json", strings.NewReader("[hello"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	resp, err = http.Post(url, "text/plain", bytes.NewReader(make([]byte, maxRequestSize+1)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		resp.Body.Close()
+	}
+}
+
+func TestServerChecksToken(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	port := testutil.FreePort(t)
+	server := NewServer(pp, config.NewLogSource("", &config.LogsConfig{Type: config.HTTPType, Port: port, AuthToken: "secret"}))
+	server.Start()
+	defer server.Stop()
+	url := fmt.Sprintf("http://127.0.0.1:%d/v1/input", port)
+
+	for _, header := range [][]string{nil, {"Authorization", "Bearer wrong"}, {"DD-API-KEY", "wrong"}} {
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader("hello"))
+		assert.NoError(t, err)
+		if header != nil {
+			req.Header.Set(header[0], header[1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			resp.Body.Close()
+		}
+	}
+
+	for _, header := range [][]string{{"Authorization", "Bearer secret"}, {"DD-API-KEY", "secret"}} {
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader("hello"))
+		assert.NoError(t, err)
+		req.Header.Set(header[0], header[1])
+		responses := post(req)
+		msg := <-msgChan
+		assert.Equal(t, "hello", string(msg.Content))
+		resp, ok := <-responses
+		if assert.True(t, ok) {
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			resp.Body.Close()
+		}
+	}
+}
@@ -76,7 +76,7 @@ func (l *Launcher) Stop() {
 // setupTailer configures and starts a new tailer,
 // returns the tailer or an error.
 func (l *Launcher) setupTailer(source *config.LogSource) (*Tailer, error) {
-	tailer := NewTailer(source, l.pipelineProvider.NextPipelineChan())
+	tailer := NewTailer(source, l.pipelineProvider.PipelineChanForSource(source))
 	cursor := l.registry.GetOffset(tailer.Identifier())
 	err := tailer.Start(cursor)
 	if err != nil {
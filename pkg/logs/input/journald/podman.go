@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build systemd
+
+package journald
+
+import (
+	"github.com/coreos/go-systemd/sdjournal"
+)
+
+// containerNameKey represents the key of the container name in a journal entry.
+const containerNameKey = "CONTAINER_NAME"
+
+// podmanCommand is the name of the process writing the logs of the podman containers in the journal,
+// it's used to tell podman containers apart from docker containers.
+const podmanCommand = "conmon"
+
+// isPodmanEntry returns true if the entry comes from a podman container,
+// including the ones of rootless podman which are written in the user journals.
+func (t *Tailer) isPodmanEntry(entry *sdjournal.JournalEntry) bool {
+	return t.isContainerEntry(entry) && entry.Fields[sdjournal.SD_JOURNAL_FIELD_COMM] == podmanCommand
+}
+
+// getPodmanTags returns the tags of a podman container,
+// those are computed from the journal entry as the tagger does not collect podman containers.
+func (t *Tailer) getPodmanTags(entry *sdjournal.JournalEntry) []string {
+	tags := []string{"container_id:" + t.getContainerID(entry)}
+	if name, exists := entry.Fields[containerNameKey]; exists {
+		tags = append(tags, "container_name:"+name)
+	}
+	return tags
+}
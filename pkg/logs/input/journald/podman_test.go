@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build systemd
+
+package journald
+
+import (
+	"testing"
+
+	"github.com/coreos/go-systemd/sdjournal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestIsPodmanEntry(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	tailer := NewTailer(source, nil)
+
+	assert.True(t, tailer.isPodmanEntry(&sdjournal.JournalEntry{
+		Fields: map[string]string{
+			sdjournal.SD_JOURNAL_FIELD_COMM: "conmon",
+			containerIDKey:                  "0123456789",
+		},
+	}))
+
+	assert.False(t, tailer.isPodmanEntry(&sdjournal.JournalEntry{
+		Fields: map[string]string{
+			sdjournal.SD_JOURNAL_FIELD_COMM: "dockerd",
+			containerIDKey:                  "0123456789",
+		},
+	}))
+
+	assert.False(t, tailer.isPodmanEntry(&sdjournal.JournalEntry{
+		Fields: map[string]string{
+			sdjournal.SD_JOURNAL_FIELD_COMM: "conmon",
+		},
+	}))
+}
+
+func TestPodmanEntryNameAndTags(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	tailer := NewTailer(source, nil)
+
+	entry := &sdjournal.JournalEntry{
+		Fields: map[string]string{
+			sdjournal.SD_JOURNAL_FIELD_SYSLOG_IDENTIFIER: "foo",
+			sdjournal.SD_JOURNAL_FIELD_COMM:              "conmon",
+			containerIDKey:                               "0123456789",
+			containerNameKey:                             "bar",
+		},
+	}
+	assert.Equal(t, "podman", tailer.getApplicationName(entry))
+	assert.Equal(t, []string{"container_id:0123456789", "container_name:bar"}, tailer.getTags(entry))
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -104,8 +105,8 @@ func (t *Tailer) setup() error {
 	return nil
 }
 
-// seek seeks to the cursor if it is not empty or the end of the journal,
-// returns an error if the operation failed.
+// seek seeks to the cursor if it is not empty or else to the start position of the source,
+// the end of the journal by default, returns an error if the operation failed.
 func (t *Tailer) seek(cursor string) error {
 	if cursor != "" {
 		err := t.journal.SeekCursor(cursor)
@@ -115,9 +116,14 @@ func (t *Tailer) seek(cursor string) error {
 		// must skip one entry since the cursor points to the last committed one.
 		_, err = t.journal.NextSkip(1)
 		return err
-	} else {
-		return t.journal.SeekTail()
 	}
+	if t.source.Config.StartPosition == config.StartPositionBeginning {
+		return t.journal.SeekHead()
+	}
+	if since, ok := t.source.Config.StartTime(time.Now()); ok {
+		return t.journal.SeekRealtimeUsec(uint64(since.UnixNano() / int64(time.Microsecond)))
+	}
+	return t.journal.SeekTail()
 }
 
 // tail tails the journal until a message stop is received.
@@ -132,6 +138,10 @@ func (t *Tailer) tail() {
 			// stop tailing journal
 			return
 		default:
+			// stop reading new entries from the journal while they can not be sent
+			if !pipeline.WaitForCapacity(t.outputChan, t.stop) {
+				return
+			}
 			n, err := t.journal.Next()
 			if err != nil && err != io.EOF {
 				err := fmt.Errorf("cant't tail journal %s: %s", t.journalPath(), err)
@@ -237,6 +247,9 @@ var applicationKeys = []string{
 
 // getApplicationName returns the name of the application from where the entry is from.
 func (t *Tailer) getApplicationName(entry *sdjournal.JournalEntry) string {
+	if t.isPodmanEntry(entry) {
+		return "podman"
+	}
 	if t.isContainerEntry(entry) {
 		return "docker"
 	}
@@ -251,6 +264,9 @@ func (t *Tailer) getApplicationName(entry *sdjournal.JournalEntry) string {
 // getTags returns a list of tags matching with the journal entry.
 func (t *Tailer) getTags(entry *sdjournal.JournalEntry) []string {
 	var tags []string
+	if t.isPodmanEntry(entry) {
+		return t.getPodmanTags(entry)
+	}
 	if t.isContainerEntry(entry) {
 		tags = append(tags, t.getContainerTags(t.getContainerID(entry))...)
 	}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// Defaults of the kafka sources.
+const (
+	defaultConsumerGroup = "datadog-agent"
+	defaultKafkaVersion  = "1.0.0"
+	clientID             = "datadog-agent"
+)
+
+// consumerGroup returns the consumer group of the source.
+func consumerGroup(cfg *config.LogsConfig) string {
+	if cfg.ConsumerGroup != "" {
+		return cfg.ConsumerGroup
+	}
+	return defaultConsumerGroup
+}
+
+// newSaramaConfig returns the configuration of the consumer group of the source,
+// the consumer connects with TLS and authenticates with SASL/PLAIN when configured.
+// The secret handles of the SASL password and of the key passphrase are resolved every time.
+func newSaramaConfig(cfg *config.LogsConfig) (*sarama.Config, error) {
+	kafkaVersion := cfg.KafkaVersion
+	if kafkaVersion == "" {
+		kafkaVersion = defaultKafkaVersion
+	}
+	version, err := sarama.ParseKafkaVersion(kafkaVersion)
+	if err != nil {
+		return nil, err
+	}
+	if !version.IsAtLeast(sarama.V0_10_2_0) {
+		return nil, fmt.Errorf("consumer groups require kafka 0.10.2 or later, got %s", kafkaVersion)
+	}
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = clientID
+	saramaConfig.Version = version
+	saramaConfig.Consumer.Return.Errors = true
+	if cfg.StartPosition == config.StartPositionBeginning {
+		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+	} else {
+		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
+	if cfg.TLS {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = tlsConfig
+	}
+	if cfg.SASLUsername != "" {
+		password, err := config.ResolveSecret(cfg.SASLPassword, cfg.Type)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve the SASL password: %v", err)
+		}
+		saramaConfig.Net.SASL.Enable = true
+		saramaConfig.Net.SASL.Handshake = true
+		saramaConfig.Net.SASL.User = cfg.SASLUsername
+		saramaConfig.Net.SASL.Password = password
+	}
+	if err := saramaConfig.Validate(); err != nil {
+		return nil, err
+	}
+	return saramaConfig, nil
+}
+
+// newTLSConfig returns the TLS configuration verifying the brokers with the CA of the source when set,
+// or with the system CAs otherwise, and presenting the client certificate of the source when set.
+func newTLSConfig(cfg *config.LogsConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if cfg.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("could not find any certificate in CA file %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLSCertFile != "" {
+		cert, err := config.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSKeyPassphrase, cfg.Type)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package kafka
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestNewSaramaConfig(t *testing.T) {
+	saramaConfig, err := newSaramaConfig(&config.LogsConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, sarama.V1_0_0_0, saramaConfig.Version)
+	assert.Equal(t, sarama.OffsetNewest, saramaConfig.Consumer.Offsets.Initial)
+	assert.False(t, saramaConfig.Net.TLS.Enable)
+	assert.False(t, saramaConfig.Net.SASL.Enable)
+
+	saramaConfig, err = newSaramaConfig(&config.LogsConfig{
+		KafkaVersion:  "2.1.0",
+		StartPosition: config.StartPositionBeginning,
+		TLS:           true,
+		SASLUsername:  "user",
+		SASLPassword:  "password",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, sarama.V2_1_0_0, saramaConfig.Version)
+	assert.Equal(t, sarama.OffsetOldest, saramaConfig.Consumer.Offsets.Initial)
+	assert.True(t, saramaConfig.Net.TLS.Enable)
+	assert.Nil(t, saramaConfig.Net.TLS.Config.RootCAs)
+	assert.True(t, saramaConfig.Net.SASL.Enable)
+	assert.Equal(t, "user", saramaConfig.Net.SASL.User)
+	assert.Equal(t, "password", saramaConfig.Net.SASL.Password)
+}
+
+func TestNewSaramaConfigFailsWithInvalidConfigs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kafka-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	invalidCAFile := filepath.Join(dir, "ca.pem")
+	assert.NoError(t, ioutil.WriteFile(invalidCAFile, []byte("not a certificate"), 0600))
+
+	for _, cfg := range []*config.LogsConfig{
+		{KafkaVersion: "zero"},
+		{KafkaVersion: "0.9.0.0"},
+		{TLS: true, TLSCAFile: filepath.Join(dir, "missing.pem")},
+		{TLS: true, TLSCAFile: invalidCAFile},
+		{TLS: true, TLSCertFile: invalidCAFile, TLSKeyFile: invalidCAFile},
+	} {
+		_, err := newSaramaConfig(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package kafka
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// retryPeriod is the time waited before joining the consumer group again after an error.
+const retryPeriod = 10 * time.Second
+
+// A Consumer consumes the topics of a kafka source as a member of its consumer group.
+type Consumer struct {
+	source     *config.LogSource
+	outputChan chan *message.Message
+	registry   auditor.Registry
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewConsumer returns an initialized Consumer
+func NewConsumer(source *config.LogSource, outputChan chan *message.Message, registry auditor.Registry) *Consumer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Consumer{
+		source:     source,
+		outputChan: outputChan,
+		registry:   registry,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts consuming the topics of the source.
+func (c *Consumer) Start() {
+	log.Infof("Starting kafka consumer of topics %s", strings.Join(c.source.Config.Topics, ", "))
+	saramaConfig, err := newSaramaConfig(c.source.Config)
+	if err != nil {
+		log.Errorf("Invalid kafka configuration: %v", err)
+		c.source.Status.Error(err)
+		close(c.done)
+		return
+	}
+	go c.run(saramaConfig)
+}
+
+// Stop leaves the consumer group once the messages being forwarded have been sent to the pipeline
+// and the offsets of the messages sent have been committed.
+func (c *Consumer) Stop() {
+	log.Infof("Stopping kafka consumer of topics %s", strings.Join(c.source.Config.Topics, ", "))
+	c.cancel()
+	<-c.done
+}
+
+// run joins the consumer group and consumes the topics until the consumer is stopped,
+// the consumer rejoins the group after each rebalance and retries after the errors.
+func (c *Consumer) run(saramaConfig *sarama.Config) {
+	defer close(c.done)
+	handler := &handler{
+		source:     c.source,
+		outputChan: c.outputChan,
+		registry:   c.registry,
+		group:      consumerGroup(c.source.Config),
+	}
+	for {
+		group, err := sarama.NewConsumerGroup(c.source.Config.Brokers, handler.group, saramaConfig)
+		if err == nil {
+			go c.reportErrors(group)
+			c.source.Status.Success()
+			err = c.consume(group, handler)
+			group.Close()
+		}
+		if err != nil {
+			log.Warnf("Couldn't consume kafka topics %s: %v", strings.Join(c.source.Config.Topics, ", "), err)
+			c.source.Status.Error(err)
+		}
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(retryPeriod):
+		}
+		// the secrets are fetched again before joining the group again, in case they were rotated.
+		refreshed, err := newSaramaConfig(c.source.Config)
+		if err != nil {
+			log.Warnf("Couldn't refresh the kafka configuration, using the previous one: %v", err)
+			continue
+		}
+		saramaConfig = refreshed
+	}
+}
+
+// consume consumes the topics through the sessions of the group until the consumer is stopped or an error occurs.
+func (c *Consumer) consume(group sarama.ConsumerGroup, handler sarama.ConsumerGroupHandler) error {
+	for {
+		if err := group.Consume(c.ctx, c.source.Config.Topics, handler); err != nil {
+			return err
+		}
+		if c.ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// reportErrors logs the errors of the group until it's closed.
+func (c *Consumer) reportErrors(group sarama.ConsumerGroup) {
+	for err := range group.Errors() {
+		log.Warnf("Error while consuming kafka topics %s: %v", strings.Join(c.source.Config.Topics, ", "), err)
+		c.source.Status.Error(err)
+	}
+}
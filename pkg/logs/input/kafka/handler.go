@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package kafka
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// commitPeriod is the period the offsets of the messages successfully sent are marked to be committed at.
+const commitPeriod = time.Second
+
+// handler forwards the messages of the claimed partitions to the pipeline,
+// the offset of a partition is committed once the messages before it have been sent.
+type handler struct {
+	source     *config.LogSource
+	outputChan chan *message.Message
+	registry   auditor.Registry
+	group      string
+}
+
+// identifier returns the identifier of the partition in the registry.
+func (h *handler) identifier(topic string, partition int32) string {
+	return fmt.Sprintf("kafka:%s:%s:%d", h.group, topic, partition)
+}
+
+// Setup implements sarama.ConsumerGroupHandler.
+func (h *handler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup marks the offsets sent during the session before they're committed for the last time.
+func (h *handler) Cleanup(session sarama.ConsumerGroupSession) error {
+	for topic, partitions := range session.Claims() {
+		for _, partition := range partitions {
+			h.markOffset(session, topic, partition)
+		}
+	}
+	return nil
+}
+
+// ConsumeClaim forwards the messages of the partition to the pipeline until the session ends.
+func (h *handler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ticker := time.NewTicker(commitPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg, isOpen := <-claim.Messages():
+			if !isOpen {
+				return nil
+			}
+			h.outputChan <- h.toMessage(msg)
+		case <-ticker.C:
+			h.markOffset(session, claim.Topic(), claim.Partition())
+		}
+	}
+}
+
+// markOffset marks the offset of the partition following the last message sent to be committed.
+func (h *handler) markOffset(session sarama.ConsumerGroupSession, topic string, partition int32) {
+	offset, err := strconv.ParseInt(h.registry.GetOffset(h.identifier(topic, partition)), 10, 64)
+	if err != nil {
+		// no message has been sent yet
+		return
+	}
+	session.MarkOffset(topic, partition, offset, "")
+}
+
+// toMessage converts a kafka message to a message tagged with its topic and its partition,
+// its offset is the offset of the next message of the partition.
+func (h *handler) toMessage(msg *sarama.ConsumerMessage) *message.Message {
+	origin := message.NewOrigin(h.source)
+	origin.Identifier = h.identifier(msg.Topic, msg.Partition)
+	origin.Offset = strconv.FormatInt(msg.Offset+1, 10)
+	origin.SetTags([]string{
+		"kafka_topic:" + msg.Topic,
+		"kafka_partition:" + strconv.Itoa(int(msg.Partition)),
+	})
+	return message.NewMessage(bytes.TrimRight(msg.Value, "\r\n"), origin, message.StatusInfo)
+}
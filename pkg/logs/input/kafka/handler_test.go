@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// registry holds the offsets by identifier.
+type registry map[string]string
+
+func (r registry) GetOffset(identifier string) string {
+	return r[identifier]
+}
+
+// session records the offsets marked during a session.
+type session struct {
+	claims map[string][]int32
+	marked map[string]int64
+	mu     sync.Mutex
+}
+
+func (s *session) Claims() map[string][]int32 { return s.claims }
+func (s *session) MemberID() string           { return "member" }
+func (s *session) GenerationID() int32        { return 1 }
+func (s *session) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+}
+func (s *session) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {}
+func (s *session) Context() context.Context                                 { return context.Background() }
+func (s *session) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked[topic] = offset
+}
+
+// claim is a claim on the partition 3 of the topic logs.
+type claim struct {
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *claim) Topic() string                            { return "logs" }
+func (c *claim) Partition() int32                         { return 3 }
+func (c *claim) InitialOffset() int64                     { return 0 }
+func (c *claim) HighWaterMarkOffset() int64               { return 0 }
+func (c *claim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func TestHandlerForwardsMessages(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.KafkaType})
+	h := &handler{source: source, outputChan: outputChan, registry: registry{}, group: "agents"}
+	c := &claim{messages: make(chan *sarama.ConsumerMessage, 2)}
+	c.messages <- &sarama.ConsumerMessage{Topic: "logs", Partition: 3, Offset: 41, Value: []byte("hello world\n")}
+	c.messages <- &sarama.ConsumerMessage{Topic: "logs", Partition: 3, Offset: 42, Value: []byte("bye")}
+	close(c.messages)
+
+	assert.NoError(t, h.ConsumeClaim(&session{marked: map[string]int64{}}, c))
+	msg := <-outputChan
+	assert.Equal(t, "hello world", string(msg.Content))
+	assert.Equal(t, "kafka:agents:logs:3", msg.Origin.Identifier)
+	assert.Equal(t, "42", msg.Origin.Offset)
+	assert.Equal(t, []string{"kafka_topic:logs", "kafka_partition:3"}, msg.Origin.Tags())
+	msg = <-outputChan
+	assert.Equal(t, "bye", string(msg.Content))
+	assert.Equal(t, "43", msg.Origin.Offset)
+}
+
+func TestHandlerMarksTheOffsetsOfTheMessagesSent(t *testing.T) {
+	h := &handler{registry: registry{"kafka:agents:logs:3": "42"}, group: "agents"}
+	s := &session{
+		claims: map[string][]int32{"logs": {3}, "other": {1}},
+		marked: map[string]int64{},
+	}
+	assert.NoError(t, h.Cleanup(s))
+	// the offsets of the partitions without messages sent are not marked
+	assert.Equal(t, map[string]int64{"logs": 42}, s.marked)
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package kafka
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+)
+
+// Launcher starts a consumer for each kafka source.
+type Launcher struct {
+	pipelineProvider pipeline.Provider
+	registry         auditor.Registry
+	sources          chan *config.LogSource
+	consumers        []restart.Restartable
+	stop             chan struct{}
+}
+
+// NewLauncher returns an initialized Launcher
+func NewLauncher(sources *config.LogSources, pipelineProvider pipeline.Provider, registry auditor.Registry) *Launcher {
+	return &Launcher{
+		pipelineProvider: pipelineProvider,
+		registry:         registry,
+		sources:          sources.GetAddedForType(config.KafkaType),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the launcher.
+func (l *Launcher) Start() {
+	go l.run()
+}
+
+// run starts new consumers.
+func (l *Launcher) run() {
+	for {
+		select {
+		case source := <-l.sources:
+			consumer := NewConsumer(source, l.pipelineProvider.PipelineChanForSource(source), l.registry)
+			consumer.Start()
+			l.consumers = append(l.consumers, consumer)
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Stop stops all consumers
+func (l *Launcher) Stop() {
+	l.stop <- struct{}{}
+	stopper := restart.NewParallelStopper()
+	for _, c := range l.consumers {
+		stopper.Add(c)
+	}
+	stopper.Stop()
+}
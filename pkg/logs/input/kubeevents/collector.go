@@ -0,0 +1,165 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build kubeapiserver
+
+package kubeevents
+
+import (
+	"time"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const (
+	// collectPeriod is the time waited between two collections of the events.
+	collectPeriod = 5 * time.Second
+	// eventTokenKey is the key of the resource version of the last events sent in the configmap of the agent,
+	// it's distinct from the one of the kubernetes_apiserver check so that both can collect the events.
+	eventTokenKey = "logs_event"
+	// eventTokenTimeout is the age in seconds after which the stored resource version is not used anymore.
+	eventTokenTimeout = 3600
+)
+
+// A Collector watches the events of the kubernetes cluster on the apiserver and forwards them to the pipeline,
+// when leader election is enabled only the leader forwards them so that they are sent once per cluster.
+type Collector struct {
+	source             *config.LogSource
+	outputChan         chan *message.Message
+	client             *apiserver.APIClient
+	dedup              *deduplicator
+	token              string
+	configMapAvailable bool
+	stop               chan struct{}
+	done               chan struct{}
+}
+
+// NewCollector returns an initialized Collector
+func NewCollector(source *config.LogSource, outputChan chan *message.Message) *Collector {
+	return &Collector{
+		source:     source,
+		outputChan: outputChan,
+		dedup:      newDeduplicator(),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts collecting the events.
+func (c *Collector) Start() {
+	log.Info("Starting collection of the kubernetes events")
+	go c.run()
+}
+
+// Stop stops collecting the events.
+func (c *Collector) Stop() {
+	log.Info("Stopping collection of the kubernetes events")
+	close(c.stop)
+	<-c.done
+}
+
+// run collects the events periodically until the collector is stopped.
+func (c *Collector) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(collectPeriod)
+	defer ticker.Stop()
+	for {
+		c.collect()
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect forwards the events which occurred since the last collection to the pipeline.
+func (c *Collector) collect() {
+	if coreConfig.Datadog.GetBool("leader_election") && !c.isLeader() {
+		return
+	}
+	if c.client == nil {
+		client, err := apiserver.GetAPIClient()
+		if err != nil {
+			log.Warnf("Couldn't connect to the apiserver: %v", err)
+			c.source.Status.Error(err)
+			return
+		}
+		c.client = client
+		c.initToken()
+	}
+
+	timeout := time.Duration(coreConfig.Datadog.GetInt("kubernetes_event_collection_timeout")) * time.Millisecond
+	added, modified, token, err := c.client.LatestEvents(c.token, timeout)
+	if err != nil {
+		log.Warnf("Couldn't collect the events from the apiserver: %v", err)
+		c.source.Status.Error(err)
+		return
+	}
+	c.source.Status.Success()
+
+	now := time.Now()
+	for _, event := range append(added, modified...) {
+		if !c.dedup.isNew(event, now) {
+			continue
+		}
+		select {
+		case c.outputChan <- toMessage(c.source, event):
+		case <-c.stop:
+			return
+		}
+	}
+	c.dedup.prune(now)
+
+	// a "0" token restarts the watch from the cache of the apiserver, the events sent again are deduplicated.
+	if token != c.token && token != "0" && c.configMapAvailable {
+		if err := c.client.UpdateTokenInConfigmap(eventTokenKey, token); err != nil {
+			log.Warnf("Couldn't store the resource version of the events in the configmap: %v", err)
+		}
+	}
+	c.token = token
+}
+
+// initToken restores the resource version of the last events sent before the agent restarted.
+func (c *Collector) initToken() {
+	token, found, err := c.client.GetTokenFromConfigmap(eventTokenKey, eventTokenTimeout)
+	switch {
+	case err == nil:
+		c.configMapAvailable = found
+		c.token = token
+	case err == apiserver.ErrOutdated:
+		c.configMapAvailable = found
+		c.token = "0"
+	default:
+		c.token = "0"
+	}
+}
+
+// isLeader returns true if this agent is the leader of the cluster.
+func (c *Collector) isLeader() bool {
+	engine, err := leaderelection.GetLeaderEngine()
+	if err != nil {
+		log.Warnf("Couldn't instantiate the leader elector, not collecting the kubernetes events: %v", err)
+		c.source.Status.Error(err)
+		return false
+	}
+	if err := engine.EnsureLeaderElectionRuns(); err != nil {
+		log.Warnf("Couldn't start the leader election, not collecting the kubernetes events: %v", err)
+		c.source.Status.Error(err)
+		return false
+	}
+	if !engine.IsLeader() {
+		log.Debugf("Leader is %q, not collecting the kubernetes events", engine.GetLeader())
+		c.source.Status.Success()
+		return false
+	}
+	return true
+}
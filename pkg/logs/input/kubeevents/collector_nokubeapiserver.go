@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !kubeapiserver
+
+package kubeevents
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Collector is not supported without the apiserver support
+type Collector struct {
+	source *config.LogSource
+}
+
+// NewCollector returns a new collector
+func NewCollector(source *config.LogSource, outputChan chan *message.Message) *Collector {
+	return &Collector{
+		source: source,
+	}
+}
+
+// Start reports that the events can't be collected
+func (c *Collector) Start() {
+	log.Errorf("Can't collect the kubernetes events: %v", apiserver.ErrNotCompiled)
+	c.source.Status.Error(apiserver.ErrNotCompiled)
+}
+
+// Stop does nothing
+func (c *Collector) Stop() {}
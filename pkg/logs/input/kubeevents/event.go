@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package kubeevents
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// dedupTTL is the time an event is remembered after it was last seen, the apiserver keeps the events one hour by default.
+const dedupTTL = time.Hour
+
+// toMessage converts a kubernetes event to a message holding its involved object, reason and count.
+func toMessage(source *config.LogSource, event *v1.Event) *message.Message {
+	status := message.StatusInfo
+	if event.Type == v1.EventTypeWarning {
+		status = message.StatusWarning
+	}
+	origin := message.NewOrigin(source)
+	tags := []string{"kube_kind:" + event.InvolvedObject.Kind, "kube_name:" + event.InvolvedObject.Name}
+	if event.InvolvedObject.Namespace != "" {
+		tags = append(tags, "kube_namespace:"+event.InvolvedObject.Namespace)
+	}
+	if event.Source.Component != "" {
+		tags = append(tags, "source_component:"+event.Source.Component)
+	}
+	origin.SetTags(tags)
+
+	msg := message.NewMessage([]byte(event.Message), origin, status)
+	msg.Structured = &message.Structured{
+		Message:   event.Message,
+		Timestamp: lastTimestamp(event),
+		Attributes: map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"event": map[string]interface{}{
+					"reason":          event.Reason,
+					"type":            event.Type,
+					"count":           event.Count,
+					"first_timestamp": event.FirstTimestamp.UTC().Format(time.RFC3339),
+					"last_timestamp":  lastTimestamp(event).UTC().Format(time.RFC3339),
+					"involved_object": map[string]interface{}{
+						"kind":      event.InvolvedObject.Kind,
+						"name":      event.InvolvedObject.Name,
+						"namespace": event.InvolvedObject.Namespace,
+						"uid":       string(event.InvolvedObject.UID),
+					},
+					"source": map[string]interface{}{
+						"component": event.Source.Component,
+						"host":      event.Source.Host,
+					},
+				},
+			},
+		},
+	}
+	return msg
+}
+
+// lastTimestamp returns the time of the last occurrence of an event.
+func lastTimestamp(event *v1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.FirstTimestamp.Time
+}
+
+// seenEvent is an event already sent.
+type seenEvent struct {
+	count    int32
+	lastSeen time.Time
+}
+
+// A deduplicator keeps track of the events already sent so that an event is only sent again when it occurred again,
+// the apiserver aggregates the repeated events into a single one whose count is incremented and sends
+// the same events again when the watch restarts from its cache.
+type deduplicator struct {
+	events map[types.UID]seenEvent
+}
+
+// newDeduplicator returns an initialized deduplicator
+func newDeduplicator() *deduplicator {
+	return &deduplicator{
+		events: make(map[types.UID]seenEvent),
+	}
+}
+
+// isNew returns true if the event or a new occurrence of it has not been sent yet and remembers it.
+func (d *deduplicator) isNew(event *v1.Event, now time.Time) bool {
+	count := event.Count
+	if count == 0 {
+		count = 1
+	}
+	seen, found := d.events[event.UID]
+	if found && count <= seen.count {
+		d.events[event.UID] = seenEvent{count: seen.count, lastSeen: now}
+		return false
+	}
+	d.events[event.UID] = seenEvent{count: count, lastSeen: now}
+	return true
+}
+
+// prune forgets the events not seen since dedupTTL.
+func (d *deduplicator) prune(now time.Time) {
+	for uid, seen := range d.events {
+		if now.Sub(seen.lastSeen) > dedupTTL {
+			delete(d.events, uid)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package kubeevents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newEvent(uid string, count int32) *v1.Event {
+	first := time.Date(2019, 1, 10, 12, 0, 0, 0, time.UTC)
+	return &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{UID: k8stypes.UID("event-" + uid)},
+		InvolvedObject: v1.ObjectReference{
+			Kind:      "Pod",
+			Name:      "redis-6c4f7d6f6-x2x4c",
+			Namespace: "default",
+			UID:       k8stypes.UID(uid),
+		},
+		Reason:         "BackOff",
+		Message:        "Back-off restarting failed container",
+		Count:          count,
+		Type:           v1.EventTypeWarning,
+		Source:         v1.EventSource{Component: "kubelet", Host: "node-1"},
+		FirstTimestamp: metav1.NewTime(first),
+		LastTimestamp:  metav1.NewTime(first.Add(time.Minute)),
+	}
+}
+
+func TestToMessage(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.KubernetesEventsType})
+	msg := toMessage(source, newEvent("pod-uid", 3))
+
+	assert.Equal(t, "Back-off restarting failed container", string(msg.Content))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, []string{"kube_kind:Pod", "kube_name:redis-6c4f7d6f6-x2x4c", "kube_namespace:default", "source_component:kubelet"}, msg.Origin.Tags())
+	assert.Equal(t, time.Date(2019, 1, 10, 12, 1, 0, 0, time.UTC), msg.Structured.Timestamp.UTC())
+
+	event := msg.Structured.Attributes["kubernetes"].(map[string]interface{})["event"].(map[string]interface{})
+	assert.Equal(t, "BackOff", event["reason"])
+	assert.Equal(t, int32(3), event["count"])
+	assert.Equal(t, "2019-01-10T12:00:00Z", event["first_timestamp"])
+	assert.Equal(t, "2019-01-10T12:01:00Z", event["last_timestamp"])
+	assert.Equal(t, map[string]interface{}{
+		"kind":      "Pod",
+		"name":      "redis-6c4f7d6f6-x2x4c",
+		"namespace": "default",
+		"uid":       "pod-uid",
+	}, event["involved_object"])
+}
+
+func TestToMessageNormalEvent(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.KubernetesEventsType})
+	event := newEvent("node-uid", 1)
+	event.Type = v1.EventTypeNormal
+	event.InvolvedObject.Namespace = ""
+	event.Source.Component = ""
+
+	msg := toMessage(source, event)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Equal(t, []string{"kube_kind:Pod", "kube_name:redis-6c4f7d6f6-x2x4c"}, msg.Origin.Tags())
+}
+
+func TestDeduplicator(t *testing.T) {
+	dedup := newDeduplicator()
+	now := time.Now()
+
+	assert.True(t, dedup.isNew(newEvent("a", 1), now))
+	assert.False(t, dedup.isNew(newEvent("a", 1), now))
+	assert.True(t, dedup.isNew(newEvent("b", 1), now))
+
+	// the repeated event is sent again once per new occurrence
+	assert.True(t, dedup.isNew(newEvent("a", 2), now))
+	assert.False(t, dedup.isNew(newEvent("a", 2), now))
+
+	// an older version of the event sent again from the cache of the apiserver is ignored
+	assert.False(t, dedup.isNew(newEvent("a", 1), now))
+	assert.False(t, dedup.isNew(newEvent("a", 2), now))
+}
+
+func TestDeduplicatorPrune(t *testing.T) {
+	dedup := newDeduplicator()
+	now := time.Now()
+
+	assert.True(t, dedup.isNew(newEvent("a", 1), now))
+	assert.True(t, dedup.isNew(newEvent("b", 1), now.Add(dedupTTL)))
+
+	dedup.prune(now.Add(dedupTTL + time.Second))
+	assert.Equal(t, 1, len(dedup.events))
+	assert.True(t, dedup.isNew(newEvent("a", 1), now))
+	assert.False(t, dedup.isNew(newEvent("b", 1), now))
+}
@@ -35,11 +35,14 @@ type Launcher struct {
 	dockerRemovedServices     chan *service.Service
 	containerdAddedServices   chan *service.Service
 	containerdRemovedServices chan *service.Service
+	crioAddedServices         chan *service.Service
+	crioRemovedServices       chan *service.Service
 	collectAll                bool
+	autoTag                   bool
 }
 
 // NewLauncher returns a new launcher.
-func NewLauncher(sources *config.LogSources, services *service.Services, collectAll bool) (*Launcher, error) {
+func NewLauncher(sources *config.LogSources, services *service.Services, collectAll bool, autoTag bool) (*Launcher, error) {
 	if !isIntegrationAvailable() {
 		return nil, fmt.Errorf("%s not found", podsDirectoryPath)
 	}
@@ -54,6 +57,7 @@ func NewLauncher(sources *config.LogSources, services *service.Services, collect
 		stopped:            make(chan struct{}),
 		kubeutil:           kubeutil,
 		collectAll:         collectAll,
+		autoTag:            autoTag,
 	}
 	err = launcher.setup()
 	if err != nil {
@@ -67,6 +71,8 @@ func NewLauncher(sources *config.LogSources, services *service.Services, collect
 	launcher.dockerRemovedServices = services.GetRemovedServices(service.Docker)
 	launcher.containerdAddedServices = services.GetAddedServices(service.Containerd)
 	launcher.containerdRemovedServices = services.GetRemovedServices(service.Containerd)
+	launcher.crioAddedServices = services.GetAddedServices(service.CRIO)
+	launcher.crioRemovedServices = services.GetRemovedServices(service.CRIO)
 	return launcher, nil
 }
 
@@ -110,6 +116,10 @@ func (l *Launcher) run() {
 			l.addSource(service)
 		case service := <-l.containerdRemovedServices:
 			l.removeSource(service)
+		case service := <-l.crioAddedServices:
+			l.addSource(service)
+		case service := <-l.crioRemovedServices:
+			l.removeSource(service)
 		case <-l.stopped:
 			log.Info("Kubernetes launcher stopped")
 			return
@@ -181,6 +191,17 @@ func (l *Launcher) getSource(pod *kubelet.Pod, container kubelet.ContainerStatus
 			return nil, fmt.Errorf("could not parse kubernetes annotation %v", annotation)
 		}
 		cfg = configs[0]
+		if l.autoTag && (cfg.Service == "" || cfg.Source == "") {
+			// the service and source of the annotation take precedence over the short image name
+			if shortImageName, err := l.getShortImageName(container); err == nil {
+				if cfg.Service == "" {
+					cfg.Service = shortImageName
+				}
+				if cfg.Source == "" {
+					cfg.Source = shortImageName
+				}
+			}
+		}
 	} else {
 		if !l.collectAll {
 			return nil, collectAllDisabledError
@@ -77,6 +77,38 @@ func TestGetSourceShouldBeOverridenByAutoDiscoveryAnnotation(t *testing.T) {
 	assert.True(t, contains(source.Config.Tags, "tag1", "tag2"))
 }
 
+func TestGetSourceShouldTagWithTheShortImageNameWhenAutoTagIsEnabled(t *testing.T) {
+	container := kubelet.ContainerStatus{
+		Name:  "foo",
+		Image: "registry.com/bar:latest",
+		ID:    "boo",
+	}
+	pod := &kubelet.Pod{
+		Metadata: kubelet.PodMetadata{
+			Name:      "fuz",
+			Namespace: "buu",
+			UID:       "baz",
+			Annotations: map[string]string{
+				"ad.datadoghq.com/foo.logs": `[{"source":"any_source","tags":["tag1"]}]`,
+			},
+		},
+		Status: kubelet.Status{
+			Containers: []kubelet.ContainerStatus{container},
+		},
+	}
+
+	source, err := (&Launcher{}).getSource(pod, container)
+	assert.Nil(t, err)
+	assert.Equal(t, "any_source", source.Config.Source)
+	assert.Equal(t, "", source.Config.Service)
+
+	// the annotation still takes precedence over the short image name
+	source, err = (&Launcher{autoTag: true}).getSource(pod, container)
+	assert.Nil(t, err)
+	assert.Equal(t, "any_source", source.Config.Source)
+	assert.Equal(t, "bar", source.Config.Service)
+}
+
 func TestGetSourceShouldFailWithInvalidAutoDiscoveryAnnotation(t *testing.T) {
 	launcher := &Launcher{collectAll: true}
 	container := kubelet.ContainerStatus{
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// The versions of the Lambda APIs used by the extension.
+const (
+	extensionAPIVersion    = "2020-01-01"
+	telemetryAPIVersion    = "2022-07-01"
+	telemetrySchemaVersion = "2022-12-13"
+)
+
+// The types of the lifecycle events the extension registers to.
+const (
+	invokeEvent   = "INVOKE"
+	shutdownEvent = "SHUTDOWN"
+)
+
+// extensionIDHeader is the header identifying the extension in the calls to the Lambda APIs once it's registered.
+const extensionIDHeader = "Lambda-Extension-Identifier"
+
+// event is a lifecycle event of the execution environment.
+type event struct {
+	EventType      string `json:"eventType"`
+	RequestID      string `json:"requestId"`
+	DeadlineMs     int64  `json:"deadlineMs"`
+	ShutdownReason string `json:"shutdownReason"`
+}
+
+// apiClient calls the Extensions API and the Telemetry API exposed by the Lambda runtime on runtimeAPI,
+// as described in https://docs.aws.amazon.com/lambda/latest/dg/runtimes-extensions-api.html
+type apiClient struct {
+	runtimeAPI  string
+	httpClient  *http.Client
+	extensionID string
+}
+
+// newAPIClient returns a new apiClient, the events are long polled so the calls have no timeout.
+func newAPIClient(runtimeAPI string) *apiClient {
+	return &apiClient{
+		runtimeAPI: runtimeAPI,
+		httpClient: &http.Client{},
+	}
+}
+
+// register registers the extension with name, which must be the name of its executable, to the lifecycle events.
+func (c *apiClient) register(ctx context.Context, name string) error {
+	body, err := json.Marshal(map[string][]string{"events": {invokeEvent, shutdownEvent}})
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodPost, extensionAPIVersion+"/extension/register", body, map[string]string{"Lambda-Extension-Name": name})
+	if err != nil {
+		return err
+	}
+	c.extensionID = resp.Header.Get(extensionIDHeader)
+	if c.extensionID == "" {
+		return fmt.Errorf("no extension identifier returned by the extensions API")
+	}
+	return nil
+}
+
+// subscribe subscribes the extension to the logs of the function and to the platform events,
+// they're posted to destination as soon as possible to not delay the end of the invocations.
+func (c *apiClient) subscribe(ctx context.Context, destination string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": telemetrySchemaVersion,
+		"types":         []string{"platform", "function"},
+		"buffering": map[string]int{
+			"maxItems":  1000,
+			"maxBytes":  256 * 1024,
+			"timeoutMs": 25,
+		},
+		"destination": map[string]string{
+			"protocol": "HTTP",
+			"URI":      destination,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, http.MethodPut, telemetryAPIVersion+"/telemetry", body, map[string]string{extensionIDHeader: c.extensionID})
+	return err
+}
+
+// next blocks until the next lifecycle event.
+func (c *apiClient) next(ctx context.Context) (*event, error) {
+	resp, err := c.do(ctx, http.MethodGet, extensionAPIVersion+"/extension/event/next", nil, map[string]string{extensionIDHeader: c.extensionID})
+	if err != nil {
+		return nil, err
+	}
+	var e event
+	if err := json.Unmarshal(resp.body, &e); err != nil {
+		return nil, fmt.Errorf("invalid event: %v", err)
+	}
+	return &e, nil
+}
+
+// response is the response to a call to a Lambda API with its body read.
+type response struct {
+	*http.Response
+	body []byte
+}
+
+// do calls path on the runtime API, the calls which don't succeed return an error.
+func (c *apiClient) do(ctx context.Context, method, path string, body []byte, headers map[string]string) (*response, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s/%s", c.runtimeAPI, path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, path, resp.Status, bytes.TrimSpace(data))
+	}
+	return &response{Response: resp, body: data}, nil
+}
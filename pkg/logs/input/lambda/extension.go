@@ -0,0 +1,225 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+)
+
+// runtimeAPIEnv is the environment variable holding the address of the Lambda runtime API.
+const runtimeAPIEnv = "AWS_LAMBDA_RUNTIME_API"
+
+// defaultFlushTimeout bounds the flushes of the events which have no deadline.
+const defaultFlushTimeout = 2 * time.Second
+
+// stopTimeout is the time given to the telemetry requests being processed to complete when the extension is stopped.
+const stopTimeout = 2 * time.Second
+
+// An Extension runs the logs agent as an AWS Lambda extension, it receives the logs of the function from the
+// Telemetry API on the port of its source and flushes the pipelines at the end of each invocation, once the runtime
+// is done, so that the logs are sent before the execution environment is frozen.
+type Extension struct {
+	source           *config.LogSource
+	pipelineProvider pipeline.Provider
+	outputChan       chan *message.Message
+	client           *apiClient
+	httpServer       *http.Server
+	runtimeDone      chan string
+	mu               sync.Mutex
+	requestID        string
+	ctx              context.Context
+	cancel           context.CancelFunc
+	done             chan struct{}
+}
+
+// NewExtension returns an initialized Extension
+func NewExtension(pipelineProvider pipeline.Provider, source *config.LogSource) *Extension {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Extension{
+		source:           source,
+		pipelineProvider: pipelineProvider,
+		outputChan:       pipelineProvider.PipelineChanForSource(source),
+		runtimeDone:      make(chan string, 10),
+		ctx:              ctx,
+		cancel:           cancel,
+		done:             make(chan struct{}),
+	}
+}
+
+// Start registers the extension and subscribes to the logs of the function.
+func (e *Extension) Start() {
+	log.Infof("Starting Lambda extension with telemetry listener on port %d", e.source.Config.Port)
+	runtimeAPI := os.Getenv(runtimeAPIEnv)
+	if runtimeAPI == "" {
+		err := fmt.Errorf("%s is not set, the agent is not running in a Lambda execution environment", runtimeAPIEnv)
+		log.Errorf("Can't start Lambda extension: %v", err)
+		e.source.Status.Error(err)
+		close(e.done)
+		return
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", e.source.Config.Port))
+	if err != nil {
+		log.Errorf("Can't listen on port %d: %v", e.source.Config.Port, err)
+		e.source.Status.Error(err)
+		close(e.done)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", e.handleTelemetry)
+	e.httpServer = &http.Server{Handler: mux}
+	go e.httpServer.Serve(listener)
+
+	e.client = newAPIClient(runtimeAPI)
+	go e.run()
+}
+
+// Stop stops waiting for the lifecycle events and stops the telemetry listener.
+func (e *Extension) Stop() {
+	log.Info("Stopping Lambda extension")
+	e.cancel()
+	<-e.done
+	if e.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer cancel()
+		e.httpServer.Shutdown(ctx)
+	}
+}
+
+// run registers the extension and handles the lifecycle events until the execution environment shuts down.
+func (e *Extension) run() {
+	defer close(e.done)
+	if err := e.client.register(e.ctx, filepath.Base(os.Args[0])); err != nil {
+		log.Errorf("Can't register Lambda extension: %v", err)
+		e.source.Status.Error(err)
+		return
+	}
+	if err := e.client.subscribe(e.ctx, fmt.Sprintf("http://sandbox.localdomain:%d", e.source.Config.Port)); err != nil {
+		log.Errorf("Can't subscribe to the Lambda telemetry: %v", err)
+		e.source.Status.Error(err)
+		return
+	}
+	e.source.Status.Success()
+	for {
+		event, err := e.client.next(e.ctx)
+		if e.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Errorf("Can't get the next Lambda event: %v", err)
+			e.source.Status.Error(err)
+			return
+		}
+		deadline := time.Now().Add(defaultFlushTimeout)
+		if event.DeadlineMs > 0 {
+			deadline = time.Unix(0, event.DeadlineMs*int64(time.Millisecond))
+		}
+		switch event.EventType {
+		case invokeEvent:
+			e.setRequestID(event.RequestID)
+			e.waitRuntimeDone(event.RequestID, deadline)
+			e.flush(deadline)
+		case shutdownEvent:
+			log.Infof("Lambda execution environment shutting down: %s", event.ShutdownReason)
+			e.flush(deadline)
+			return
+		}
+	}
+}
+
+// waitRuntimeDone waits for the runtime to be done with the invocation requestID,
+// the logs of the invocation are all posted before the platform.runtimeDone event.
+func (e *Extension) waitRuntimeDone(requestID string, deadline time.Time) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		select {
+		case done := <-e.runtimeDone:
+			if done == requestID {
+				return
+			}
+		case <-timer.C:
+			log.Warnf("Lambda invocation %s reached its deadline before the runtime was done", requestID)
+			return
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+// flush sends the logs held by the pipelines right away.
+func (e *Extension) flush(deadline time.Time) {
+	ctx, cancel := context.WithDeadline(e.ctx, deadline)
+	defer cancel()
+	if err := e.pipelineProvider.Flush(ctx); err != nil {
+		log.Warnf("Could not flush the logs pipelines: %v", err)
+	}
+}
+
+// handleTelemetry forwards the logs of the function posted by the Telemetry API to the pipeline
+// and notifies the end of the invocations.
+func (e *Extension) handleTelemetry(w http.ResponseWriter, req *http.Request) {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var events []telemetryEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		http.Error(w, fmt.Sprintf("invalid telemetry events: %v", err), http.StatusBadRequest)
+		return
+	}
+	for i := range events {
+		switch events[i].Type {
+		case functionType:
+			select {
+			case e.outputChan <- toMessage(e.source, &events[i], e.getRequestID()):
+			case <-req.Context().Done():
+				http.Error(w, req.Context().Err().Error(), http.StatusServiceUnavailable)
+				return
+			}
+		case platformStart:
+			e.setRequestID(events[i].requestID())
+		case platformRuntimeDone:
+			select {
+			case e.runtimeDone <- events[i].requestID():
+			default:
+				// nobody waits for the end of a previous invocation
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// setRequestID sets the identifier of the current invocation.
+func (e *Extension) setRequestID(requestID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if requestID != "" {
+		e.requestID = requestID
+	}
+}
+
+// getRequestID returns the identifier of the current invocation.
+func (e *Extension) getRequestID() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.requestID
+}
@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/testutil"
+)
+
+// flushRecorder records the flushes of the pipelines.
+type flushRecorder struct {
+	pipeline.Provider
+	flushes chan struct{}
+}
+
+func (p *flushRecorder) Flush(ctx context.Context) error {
+	p.flushes <- struct{}{}
+	return nil
+}
+
+// fakeRuntimeAPI serves the events to the extension once it subscribed to the telemetry.
+type fakeRuntimeAPI struct {
+	events      chan *event
+	subscribed  chan map[string]interface{}
+	extensionID string
+}
+
+func (f *fakeRuntimeAPI) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case "/2020-01-01/extension/register":
+		if req.Header.Get("Lambda-Extension-Name") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(extensionIDHeader, f.extensionID)
+	case "/2022-07-01/telemetry":
+		var subscription map[string]interface{}
+		data, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(data, &subscription)
+		if req.Header.Get(extensionIDHeader) != f.extensionID {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		f.subscribed <- subscription
+	case "/2020-01-01/extension/event/next":
+		select {
+		case e := <-f.events:
+			json.NewEncoder(w).Encode(e)
+		case <-req.Context().Done():
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// freePort returns a port available on the loopback interface.
+func TestExtensionFlushesAtTheEndOfEachInvocation(t *testing.T) {
+	runtimeAPI := &fakeRuntimeAPI{
+		events:      make(chan *event),
+		subscribed:  make(chan map[string]interface{}, 1),
+		extensionID: "extension-id",
+	}
+	server := httptest.NewServer(runtimeAPI)
+	defer server.Close()
+	os.Setenv(runtimeAPIEnv, strings.TrimPrefix(server.URL, "http://"))
+	defer os.Unsetenv(runtimeAPIEnv)
+
+	mockProvider := mock.NewMockProvider()
+	msgChan := mockProvider.NextPipelineChan()
+	pp := &flushRecorder{Provider: mockProvider, flushes: make(chan struct{}, 2)}
+	port := testutil.FreePort(t)
+	extension := NewExtension(pp, config.NewLogSource("", &config.LogsConfig{Type: config.LambdaType, Port: port}))
+	extension.Start()
+
+	subscription := <-runtimeAPI.subscribed
+	assert.Equal(t, []interface{}{"platform", "function"}, subscription["types"])
+	assert.Equal(t, fmt.Sprintf("http://sandbox.localdomain:%d", port), subscription["destination"].(map[string]interface{})["URI"])
+
+	deadline := time.Now().Add(10 * time.Second)
+	runtimeAPI.events <- &event{EventType: invokeEvent, RequestID: "1", DeadlineMs: deadline.UnixNano() / int64(time.Millisecond)}
+	// the function logs while the runtime is running
+	go http.Post(fmt.Sprintf("http://127.0.0.1:%d", port), "application/json", strings.NewReader(`[
+		{"time": "2022-10-12T00:03:50.000Z", "type": "platform.start", "record": {"requestId": "1"}},
+		{"time": "2022-10-12T00:03:50.001Z", "type": "function", "record": "hello"}
+	]`))
+	msg := <-msgChan
+	assert.Equal(t, "hello", string(msg.Content))
+	assert.Equal(t, "1", msg.Structured.Attributes["lambda"].(map[string]interface{})["request_id"])
+	select {
+	case <-pp.flushes:
+		assert.Fail(t, "the pipelines must not be flushed before the runtime is done")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	resp, err := http.Post(fmt.Sprintf("http://127.0.0.1:%d", port), "application/json", strings.NewReader(`[
+		{"time": "2022-10-12T00:03:50.002Z", "type": "platform.runtimeDone", "record": {"requestId": "1", "status": "success"}}
+	]`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+	<-pp.flushes
+
+	runtimeAPI.events <- &event{EventType: shutdownEvent, ShutdownReason: "spindown"}
+	<-pp.flushes
+	extension.Stop()
+}
+
+func TestExtensionRequiresTheRuntimeAPI(t *testing.T) {
+	os.Unsetenv(runtimeAPIEnv)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.LambdaType, Port: testutil.FreePort(t)})
+	extension := NewExtension(mock.NewMockProvider(), source)
+	extension.Start()
+	extension.Stop()
+	assert.True(t, source.Status.IsError())
+}
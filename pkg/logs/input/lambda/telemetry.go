@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package lambda
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// The types of the telemetry events the extension handles.
+const (
+	functionType        = "function"
+	platformStart       = "platform.start"
+	platformRuntimeDone = "platform.runtimeDone"
+)
+
+// telemetryEvent is an event posted by the Telemetry API, as described in
+// https://docs.aws.amazon.com/lambda/latest/dg/telemetry-schema-reference.html
+type telemetryEvent struct {
+	Time   string          `json:"time"`
+	Type   string          `json:"type"`
+	Record json.RawMessage `json:"record"`
+}
+
+// platformRecord is the record of the platform events.
+type platformRecord struct {
+	RequestID string `json:"requestId"`
+}
+
+// requestID returns the identifier of the invocation of a platform event.
+func (e *telemetryEvent) requestID() string {
+	var record platformRecord
+	json.Unmarshal(e.Record, &record)
+	return record.RequestID
+}
+
+// toMessage converts the log of the function of a telemetry event to a message of the invocation requestID,
+// the logs written in the JSON format of the Lambda runtimes are sent on a single line.
+func toMessage(source *config.LogSource, e *telemetryEvent, requestID string) *message.Message {
+	var content []byte
+	var line string
+	if err := json.Unmarshal(e.Record, &line); err == nil {
+		content = bytes.TrimRight([]byte(line), "\r\n")
+	} else {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, e.Record); err != nil {
+			compacted.Write(e.Record)
+		}
+		content = compacted.Bytes()
+	}
+	msg := message.NewMessage(content, message.NewOrigin(source), message.StatusInfo)
+	timestamp, _ := time.Parse(time.RFC3339Nano, e.Time)
+	msg.Structured = &message.Structured{
+		Message:   string(content),
+		Timestamp: timestamp,
+	}
+	if requestID != "" {
+		msg.Structured.Attributes = map[string]interface{}{
+			"lambda": map[string]interface{}{"request_id": requestID},
+		}
+	}
+	return msg
+}
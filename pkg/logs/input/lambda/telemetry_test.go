@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package lambda
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestToMessageWithTextLog(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.LambdaType})
+	msg := toMessage(source, &telemetryEvent{Time: "2022-10-12T00:03:50.000Z", Type: functionType, Record: []byte(`"hello world\n"`)}, "8476a536")
+	assert.Equal(t, "hello world", string(msg.Content))
+	assert.Equal(t, "hello world", msg.Structured.Message)
+	assert.Equal(t, time.Date(2022, 10, 12, 0, 3, 50, 0, time.UTC), msg.Structured.Timestamp)
+	assert.Equal(t, map[string]interface{}{"lambda": map[string]interface{}{"request_id": "8476a536"}}, msg.Structured.Attributes)
+}
+
+func TestToMessageWithJSONLog(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.LambdaType})
+	msg := toMessage(source, &telemetryEvent{Time: "invalid", Type: functionType, Record: []byte(`{ "level": "INFO", "message": "hello" }`)}, "")
+	assert.Equal(t, `{"level":"INFO","message":"hello"}`, string(msg.Content))
+	assert.True(t, msg.Structured.Timestamp.IsZero())
+	assert.Nil(t, msg.Structured.Attributes)
+}
+
+func TestRequestID(t *testing.T) {
+	e := &telemetryEvent{Type: platformRuntimeDone, Record: []byte(`{"requestId": "8476a536", "status": "success"}`)}
+	assert.Equal(t, "8476a536", e.requestID())
+	e = &telemetryEvent{Type: platformRuntimeDone, Record: []byte(`"invalid"`)}
+	assert.Equal(t, "", e.requestID())
+}
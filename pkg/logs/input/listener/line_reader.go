@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// lineReader reads the lines of a connection through a buffer of bufferSize bytes, a line longer than the buffer
+// is read in several chunks. Each read returns the complete lines already buffered, or the next chunk of a line.
+// A lineReader is not thread safe.
+type lineReader struct {
+	reader    *bufio.Reader
+	truncator *lineTruncator
+	// err is the error the reader ran into after reading the data returned last.
+	err error
+}
+
+// newLineReader returns a reader of the lines of r through a buffer of bufferSize bytes,
+// which truncates the lines longer than maxLineLength, or none if it's 0.
+func newLineReader(r io.Reader, bufferSize int, maxLineLength int) *lineReader {
+	return &lineReader{
+		reader:    bufio.NewReaderSize(r, bufferSize),
+		truncator: newLineTruncator(maxLineLength),
+	}
+}
+
+// read returns the next lines or chunk of a line, it blocks until a line is complete or the buffer is full.
+// The data read before an error is returned before the error.
+func (r *lineReader) read() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var data []byte
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		data = append(data, chunk...)
+		if err != nil && err != bufio.ErrBufferFull {
+			if len(data) == 0 {
+				return nil, err
+			}
+			r.err = err
+			break
+		}
+		if err == bufio.ErrBufferFull || !r.hasBufferedLine() {
+			break
+		}
+	}
+	return r.truncator.truncate(data), nil
+}
+
+// hasBufferedLine returns true if a complete line can be read without blocking.
+func (r *lineReader) hasBufferedLine() bool {
+	buffered, _ := r.reader.Peek(r.reader.Buffered())
+	return bytes.IndexByte(buffered, '\n') >= 0
+}
+
+// lineTruncator truncates the lines of a stream handed over in chunks to maxLength bytes,
+// a truncated line is flagged with TRUNCATED at its end and the rest of the line is dropped.
+// A lineTruncator is not thread safe.
+type lineTruncator struct {
+	maxLength int
+	// length is the length of the current line so far, truncating is set while its rest is dropped.
+	length     int
+	truncating bool
+}
+
+// newLineTruncator returns a truncator of the lines longer than maxLength, or of none if it's 0.
+func newLineTruncator(maxLength int) *lineTruncator {
+	return &lineTruncator{
+		maxLength: maxLength,
+	}
+}
+
+// truncate returns chunk with its lines truncated.
+func (t *lineTruncator) truncate(chunk []byte) []byte {
+	if t.maxLength <= 0 {
+		return chunk
+	}
+	truncated := chunk[:0:0]
+	for len(chunk) > 0 {
+		line, complete := chunk, false
+		if end := bytes.IndexByte(chunk, '\n'); end >= 0 {
+			line, chunk, complete = chunk[:end], chunk[end+1:], true
+		} else {
+			chunk = nil
+		}
+		switch {
+		case t.truncating:
+			// drop the rest of the line.
+		case t.length+len(line) > t.maxLength:
+			truncated = append(truncated, line[:t.maxLength-t.length]...)
+			truncated = append(truncated, decoder.TRUNCATED...)
+			truncated = append(truncated, '\n')
+			metrics.LogsTruncated.Add(1)
+			t.truncating = true
+		default:
+			truncated = append(truncated, line...)
+			t.length += len(line)
+			if complete {
+				truncated = append(truncated, '\n')
+			}
+		}
+		if complete {
+			t.length = 0
+			t.truncating = false
+		}
+	}
+	return truncated
+}
@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listener
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+func TestLineReaderReadsTheBufferedLines(t *testing.T) {
+	reader := newLineReader(strings.NewReader("foo\nbar\nbaz"), 16, 0)
+
+	data, err := reader.read()
+	assert.Nil(t, err)
+	assert.Equal(t, "foo\nbar\n", string(data))
+
+	// the data read before the end of the stream is returned first.
+	data, err = reader.read()
+	assert.Nil(t, err)
+	assert.Equal(t, "baz", string(data))
+
+	_, err = reader.read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLineReaderReadsTheLinesLongerThanTheBufferInChunks(t *testing.T) {
+	reader := newLineReader(strings.NewReader(strings.Repeat("a", 40)+"\n"), 16, 0)
+
+	var line string
+	for {
+		data, err := reader.read()
+		if err != nil {
+			break
+		}
+		assert.True(t, len(data) <= 16)
+		line += string(data)
+	}
+	assert.Equal(t, strings.Repeat("a", 40)+"\n", line)
+}
+
+func TestLineTruncatorTruncatesTheLongLines(t *testing.T) {
+	truncated := metrics.LogsTruncated.Value()
+	truncator := newLineTruncator(5)
+
+	assert.Equal(t, "foo\nabcde...TRUNCATED...\nbar\n", string(truncator.truncate([]byte("foo\nabcdefgh\nbar\n"))))
+	assert.Equal(t, truncated+1, metrics.LogsTruncated.Value())
+
+	// the lines are truncated across chunks.
+	assert.Equal(t, "abc", string(truncator.truncate([]byte("abc"))))
+	assert.Equal(t, "de...TRUNCATED...\n", string(truncator.truncate([]byte("defg"))))
+	assert.Equal(t, "", string(truncator.truncate([]byte("hij"))))
+	assert.Equal(t, "baz\n", string(truncator.truncate([]byte("k\nbaz\n"))))
+	assert.Equal(t, truncated+2, metrics.LogsTruncated.Value())
+}
+
+func TestLineTruncatorWithoutMaxLength(t *testing.T) {
+	truncator := newLineTruncator(0)
+	assert.Equal(t, "abcdefgh\n", string(truncator.truncate([]byte("abcdefgh\n"))))
+}
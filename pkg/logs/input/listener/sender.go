@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listener
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// The reverse DNS lookups of the senders are cached, failures included,
+// so that a sender costs at most one lookup per expiration.
+const (
+	reverseDNSTimeout    = 2 * time.Second
+	reverseDNSExpiration = 5 * time.Minute
+	maxCachedHostnames   = 4096
+)
+
+// senderOverride holds the source of the logs of the senders in network.
+type senderOverride struct {
+	network *net.IPNet
+	source  *config.LogSource
+}
+
+// hostnameEntry is a cached reverse DNS lookup, hostname is empty if it failed.
+type hostnameEntry struct {
+	hostname   string
+	expiration time.Time
+}
+
+// senderResolver returns the origins of the logs received by a listener from their senders,
+// tagged with their IP address and hostname and with the source of the override matching them.
+type senderResolver struct {
+	source     *config.LogSource
+	tags       bool
+	reverseDNS bool
+	overrides  []senderOverride
+	lookupAddr func(ctx context.Context, addr string) ([]string, error)
+	mu         sync.Mutex
+	hostnames  map[string]hostnameEntry
+}
+
+// newSenderResolver returns a resolver of the senders of the logs of source,
+// or nil if the source neither tags nor overrides its senders.
+func newSenderResolver(source *config.LogSource) *senderResolver {
+	if !source.Config.SenderTags && len(source.Config.SenderOverrides) == 0 {
+		return nil
+	}
+	resolver := &senderResolver{
+		source:     source,
+		tags:       source.Config.SenderTags,
+		reverseDNS: source.Config.SenderTags && source.Config.ReverseDNS,
+		lookupAddr: net.DefaultResolver.LookupAddr,
+		hostnames:  make(map[string]hostnameEntry),
+	}
+	for _, override := range source.Config.SenderOverrides {
+		network, err := config.ParseSenderNetwork(override.Sender)
+		if err != nil {
+			log.Warnf("Ignoring the sender override of the logs source %s: %v", source.Name, err)
+			continue
+		}
+		resolver.overrides = append(resolver.overrides, senderOverride{
+			network: network,
+			source:  config.NewSenderSource(source, override),
+		})
+	}
+	return resolver
+}
+
+// origin returns the origin of the logs of sender, an IP address or an empty string if it's unknown.
+func (r *senderResolver) origin(sender string) *message.Origin {
+	source := r.source
+	if ip := net.ParseIP(sender); ip != nil {
+		for _, override := range r.overrides {
+			if override.network.Contains(ip) {
+				source = override.source
+				break
+			}
+		}
+	}
+	origin := message.NewOrigin(source)
+	if r.tags && sender != "" {
+		tags := []string{"sender_ip:" + sender}
+		if r.reverseDNS {
+			if hostname := r.hostname(sender); hostname != "" {
+				tags = append(tags, "sender_host:"+hostname)
+			}
+		}
+		origin.SetTags(tags)
+	}
+	return origin
+}
+
+// hostname returns the hostname of the IP address ip found by a cached reverse DNS lookup,
+// or an empty string if there is none.
+func (r *senderResolver) hostname(ip string) string {
+	now := time.Now()
+	r.mu.Lock()
+	entry, exists := r.hostnames[ip]
+	r.mu.Unlock()
+	if exists && now.Before(entry.expiration) {
+		return entry.hostname
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reverseDNSTimeout)
+	defer cancel()
+	entry = hostnameEntry{expiration: now.Add(reverseDNSExpiration)}
+	if names, err := r.lookupAddr(ctx, ip); err == nil && len(names) > 0 {
+		entry.hostname = strings.TrimSuffix(names[0], ".")
+	} else if err != nil {
+		log.Debugf("Could not find the hostname of the sender %s: %v", ip, err)
+	}
+	r.mu.Lock()
+	if len(r.hostnames) >= maxCachedHostnames {
+		r.hostnames = make(map[string]hostnameEntry)
+	}
+	r.hostnames[ip] = entry
+	r.mu.Unlock()
+	return entry.hostname
+}
+
+// senderIP returns the IP address of the sender at addr, or an empty string if it has none.
+func senderIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	case nil:
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil || net.ParseIP(host) == nil {
+		return ""
+	}
+	return host
+}
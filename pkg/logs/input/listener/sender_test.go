@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listener
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestNewSenderResolver(t *testing.T) {
+	assert.Nil(t, newSenderResolver(config.NewLogSource("", &config.LogsConfig{ReverseDNS: true})))
+	assert.NotNil(t, newSenderResolver(config.NewLogSource("", &config.LogsConfig{SenderTags: true})))
+	assert.NotNil(t, newSenderResolver(config.NewLogSource("", &config.LogsConfig{SenderOverrides: []*config.SenderOverride{{Sender: "10.0.1.1"}}})))
+}
+
+func TestSenderResolverTagsTheSenders(t *testing.T) {
+	resolver := newSenderResolver(config.NewLogSource("", &config.LogsConfig{SenderTags: true, ReverseDNS: true}))
+	lookups := 0
+	resolver.lookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		lookups++
+		if addr == "10.0.1.1" {
+			return []string{"router.example.com."}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	assert.Equal(t, []string{"sender_ip:10.0.1.1", "sender_host:router.example.com"}, resolver.origin("10.0.1.1").Tags())
+	assert.Equal(t, []string{"sender_ip:10.0.1.2"}, resolver.origin("10.0.1.2").Tags())
+	assert.Empty(t, resolver.origin("").Tags())
+
+	// the lookups are cached, failures included.
+	resolver.origin("10.0.1.1")
+	resolver.origin("10.0.1.2")
+	assert.Equal(t, 2, lookups)
+}
+
+func TestSenderResolverDoesNotLookUpTheHostnamesWithoutReverseDNS(t *testing.T) {
+	resolver := newSenderResolver(config.NewLogSource("", &config.LogsConfig{SenderTags: true}))
+	resolver.lookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		assert.Fail(t, "no lookup should be done")
+		return nil, nil
+	}
+	assert.Equal(t, []string{"sender_ip:10.0.1.1"}, resolver.origin("10.0.1.1").Tags())
+}
+
+func TestSenderResolverOverridesTheSources(t *testing.T) {
+	source := config.NewLogSource("relay", &config.LogsConfig{
+		Source:  "syslog",
+		Service: "relay",
+		Tags:    []string{"site:paris"},
+		SenderOverrides: []*config.SenderOverride{
+			{Sender: "10.0.1.1", Service: "core-router"},
+			{Sender: "10.0.1.0/24", Source: "cisco", Tags: []string{"vendor:cisco"}},
+			{Sender: "router"},
+		},
+	})
+	resolver := newSenderResolver(source)
+	assert.Len(t, resolver.overrides, 2)
+
+	origin := resolver.origin("10.0.1.1")
+	assert.Equal(t, "syslog", origin.Source())
+	assert.Equal(t, "core-router", origin.Service())
+
+	origin = resolver.origin("10.0.1.2")
+	assert.Equal(t, "cisco", origin.Source())
+	assert.Equal(t, "relay", origin.Service())
+	assert.Equal(t, []string{"site:paris", "vendor:cisco"}, origin.Tags())
+
+	for _, sender := range []string{"10.0.2.1", ""} {
+		origin = resolver.origin(sender)
+		assert.True(t, source == origin.LogSource)
+		assert.Equal(t, []string{"site:paris"}, origin.Tags())
+	}
+}
+
+func TestSenderIP(t *testing.T) {
+	assert.Equal(t, "10.0.1.1", senderIP(&net.UDPAddr{IP: net.ParseIP("10.0.1.1"), Port: 514}))
+	assert.Equal(t, "fe80::1", senderIP(&net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 514}))
+	assert.Equal(t, "", senderIP(&net.UnixAddr{Name: "/var/run/logs.sock", Net: "unix"}))
+	assert.Equal(t, "", senderIP(nil))
+}
@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listener
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+)
+
+// A SocketListener listens to a Unix domain socket, or to a named pipe on Windows, accepts the connections
+// of the local applications and delegates the read operations to a tailer.
+type SocketListener struct {
+	pipelineProvider pipeline.Provider
+	source           *config.LogSource
+	frameSize        int
+	listener         net.Listener
+	tailers          []*Tailer
+	mu               sync.Mutex
+	stop             chan struct{}
+}
+
+// NewSocketListener returns an initialized SocketListener
+func NewSocketListener(pipelineProvider pipeline.Provider, source *config.LogSource, frameSize int) *SocketListener {
+	return &SocketListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		frameSize:        frameSize,
+		tailers:          []*Tailer{},
+		stop:             make(chan struct{}, 1),
+	}
+}
+
+// Start starts the listener to accepts new incoming connections.
+func (l *SocketListener) Start() {
+	log.Infof("Starting socket forwarder on %s, with read buffer size: %d", l.source.Config.Path, l.frameSize)
+	listener, err := listenSocket(l.source.Config.Path)
+	if err != nil {
+		log.Errorf("Can't start socket forwarder on %s: %v", l.source.Config.Path, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.listener = listener
+	l.source.Status.Success()
+	go l.run()
+}
+
+// Stop stops the listener from accepting new connections and all the active tailers,
+// the socket is removed once closed.
+func (l *SocketListener) Stop() {
+	log.Infof("Stopping socket forwarder on %s", l.source.Config.Path)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.listener == nil {
+		return
+	}
+	l.stop <- struct{}{}
+	l.listener.Close()
+	stopper := restart.NewParallelStopper()
+	for _, tailer := range l.tailers {
+		stopper.Add(tailer)
+	}
+	stopper.Stop()
+}
+
+// run accepts new connections and create a dedicated tailer for each.
+func (l *SocketListener) run() {
+	defer l.listener.Close()
+	for {
+		select {
+		case <-l.stop:
+			// stop accepting new connections.
+			return
+		default:
+			conn, err := l.listener.Accept()
+			switch {
+			case err != nil && isClosedConnError(err):
+				return
+			case err != nil:
+				log.Warnf("Can't accept connection on %s: %v", l.source.Config.Path, err)
+				l.source.Status.Error(err)
+				continue
+			default:
+				l.startNewTailer(conn)
+				l.source.Status.Success()
+			}
+		}
+	}
+}
+
+// read reads data from connection, returns an error if it failed and stop the tailer.
+func (l *SocketListener) read(tailer *Tailer) ([]byte, error) {
+	tailer.conn.SetReadDeadline(time.Now().Add(defaultTimeout))
+	frame := make([]byte, l.frameSize)
+	n, err := tailer.conn.Read(frame)
+	if err != nil {
+		go l.stopTailer(tailer)
+		return nil, err
+	}
+	return frame[:n], nil
+}
+
+// startNewTailer creates and starts a new tailer that reads from the connection,
+// the octet-counted frames are converted to newline-terminated ones for the syslog sources.
+func (l *SocketListener) startNewTailer(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	read := l.read
+	if l.source.Config.ParseSyslog {
+		framer := newSyslogFramer()
+		read = func(tailer *Tailer) ([]byte, error) {
+			data, err := l.read(tailer)
+			if err != nil {
+				return nil, err
+			}
+			return framer.frame(data), nil
+		}
+	}
+	tailer := NewTailer(l.source, conn, l.pipelineProvider.PipelineChanForSource(l.source), read)
+	l.tailers = append(l.tailers, tailer)
+	tailer.Start()
+}
+
+// stopTailer stops the tailer.
+func (l *SocketListener) stopTailer(tailer *Tailer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tailer.Stop()
+	for i, t := range l.tailers {
+		if t == tailer {
+			l.tailers = append(l.tailers[:i], l.tailers[i+1:]...)
+			break
+		}
+	}
+}
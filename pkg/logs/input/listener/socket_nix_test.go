@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !windows
+
+package listener
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+func TestSocketListenerReceivesMessages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listener-socket-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs.sock")
+
+	// the socket left by a previous run is replaced
+	previous, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	previous.(*net.UnixListener).SetUnlinkOnClose(false)
+	previous.Close()
+
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewSocketListener(pp, config.NewLogSource("", &config.LogsConfig{Type: config.SocketType, Path: path}), 9000)
+	listener.Start()
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketMode), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	fmt.Fprintf(conn, "hello world\nbye\n")
+	assert.Equal(t, "hello world", string((<-msgChan).Content))
+	assert.Equal(t, "bye", string((<-msgChan).Content))
+	conn.Close()
+
+	listener.Stop()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSocketListenerDoesNotReplaceOtherFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listener-socket-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs.sock")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("data"), 0644))
+
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.SocketType, Path: path})
+	listener := NewSocketListener(mock.NewMockProvider(), source, 9000)
+	listener.Start()
+	defer listener.Stop()
+	assert.True(t, source.Status.IsError())
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+}
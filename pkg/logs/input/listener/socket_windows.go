@@ -0,0 +1,25 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build windows
+
+package listener
+
+import (
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// pipeSecurityDescriptor grants full control of the pipes to the system and the administrators,
+// and lets the authenticated users write to them.
+const pipeSecurityDescriptor = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GRGW;;;AU)"
+
+// listenSocket listens to the named pipe at path, e.g. \\.\pipe\datadog-logs.
+func listenSocket(path string) (net.Listener, error) {
+	return winio.ListenPipe(path, &winio.PipeConfig{
+		SecurityDescriptor: pipeSecurityDescriptor,
+	})
+}
@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listener
+
+import (
+	"strconv"
+)
+
+// maxSyslogFrameLengthDigits is the maximum number of digits of the length of an octet-counted frame.
+const maxSyslogFrameLengthDigits = 9
+
+// The states of a syslogFramer.
+const (
+	// frameStart is the state at the beginning of a frame.
+	frameStart = iota
+	// frameLength is the state while reading the length of an octet-counted frame.
+	frameLength
+	// frameOctets is the state while reading the content of an octet-counted frame.
+	frameOctets
+	// frameLine is the state while reading a frame terminated by a newline.
+	frameLine
+)
+
+// syslogFramer converts the octet-counted syslog frames received over TCP, e.g. "11 <34>1 hello",
+// to frames terminated by a newline as expected by the decoder, as described in RFC6587.
+// The frames already terminated by a newline are left untouched and the newlines of the content
+// of the octet-counted frames are replaced by spaces. A syslogFramer holds the state of the frame
+// being read across the reads of a connection, it's not thread safe.
+type syslogFramer struct {
+	state     int
+	length    []byte
+	remaining int
+}
+
+// newSyslogFramer returns a new syslogFramer.
+func newSyslogFramer() *syslogFramer {
+	return &syslogFramer{
+		state: frameStart,
+	}
+}
+
+// frame returns data framed with newlines.
+func (f *syslogFramer) frame(data []byte) []byte {
+	framed := make([]byte, 0, len(data)+1)
+	for _, b := range data {
+		switch f.state {
+		case frameStart:
+			switch {
+			case b >= '1' && b <= '9':
+				f.state = frameLength
+				f.length = append(f.length[:0], b)
+			case b == '\n':
+				// skip empty frames
+			default:
+				f.state = frameLine
+				framed = append(framed, b)
+			}
+		case frameLength:
+			switch {
+			case b >= '0' && b <= '9' && len(f.length) < maxSyslogFrameLengthDigits:
+				f.length = append(f.length, b)
+			case b == ' ':
+				f.remaining, _ = strconv.Atoi(string(f.length))
+				f.state = frameOctets
+			default:
+				// this is not an octet-counted frame, the digits are part of the content
+				framed = append(framed, f.length...)
+				framed = append(framed, b)
+				f.state = frameLine
+				if b == '\n' {
+					f.state = frameStart
+				}
+			}
+		case frameOctets:
+			if b == '\n' {
+				b = ' '
+			}
+			framed = append(framed, b)
+			f.remaining--
+			if f.remaining == 0 {
+				framed = append(framed, '\n')
+				f.state = frameStart
+			}
+		case frameLine:
+			framed = append(framed, b)
+			if b == '\n' {
+				f.state = frameStart
+			}
+		}
+	}
+	return framed
+}
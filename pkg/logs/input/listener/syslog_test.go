@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listener
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslogFramerFramesOctetCountedMessages(t *testing.T) {
+	framer := newSyslogFramer()
+	assert.Equal(t, "<34>1 hello\n<34>1 a b\n", string(framer.frame([]byte("11 <34>1 hello9 <34>1 a\nb"))))
+}
+
+func TestSyslogFramerKeepsNewlineTerminatedMessages(t *testing.T) {
+	framer := newSyslogFramer()
+	assert.Equal(t, "<34>1 hello\n<34>1 bye\n", string(framer.frame([]byte("<34>1 hello\n\n<34>1 bye\n"))))
+
+	// digits which are not followed by a space are part of the content
+	assert.Equal(t, "123abc\n", string(framer.frame([]byte("123abc\n"))))
+}
+
+func TestSyslogFramerKeepsItsStateAcrossReads(t *testing.T) {
+	framer := newSyslogFramer()
+	assert.Equal(t, "", string(framer.frame([]byte("1"))))
+	assert.Equal(t, "<34>1", string(framer.frame([]byte("1 <34>1"))))
+	assert.Equal(t, " hello\n<34>", string(framer.frame([]byte(" hello<34>"))))
+	assert.Equal(t, "1 mixed\n", string(framer.frame([]byte("1 mixed\n"))))
+}
@@ -8,6 +8,7 @@ package listener
 import (
 	"io"
 	"net"
+	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -26,6 +27,18 @@ type Tailer struct {
 	decoder    *decoder.Decoder
 	stop       chan struct{}
 	done       chan struct{}
+	// resolver returns the origins of the messages from their sender when the source tags or overrides them,
+	// sender is the sender of the connection or else the senders of the data read are queued in senders.
+	resolver    *senderResolver
+	sender      string
+	senders     []senderSpan
+	sendersLock sync.Mutex
+}
+
+// senderSpan is a number of bytes read from a sender that are not decoded yet.
+type senderSpan struct {
+	sender    string
+	remaining int
 }
 
 // NewTailer returns a new Tailer
@@ -62,12 +75,56 @@ func (t *Tailer) forwardMessages() {
 		t.done <- struct{}{}
 	}()
 	for output := range t.decoder.OutputChan {
-		output.Origin = message.NewOrigin(t.source)
+		output.Origin = t.origin(output.RawDataLen)
 		output.SetStatus(message.StatusInfo)
 		t.outputChan <- output
 	}
 }
 
+// origin returns the origin of a message made of rawDataLen bytes of the data read.
+func (t *Tailer) origin(rawDataLen int) *message.Origin {
+	if t.resolver == nil {
+		return message.NewOrigin(t.source)
+	}
+	sender := t.sender
+	if sender == "" {
+		sender = t.popSender(rawDataLen)
+	}
+	return t.resolver.origin(sender)
+}
+
+// addSender queues the sender of the n bytes read last.
+func (t *Tailer) addSender(sender string, n int) {
+	if t.resolver == nil || n == 0 {
+		return
+	}
+	t.sendersLock.Lock()
+	defer t.sendersLock.Unlock()
+	t.senders = append(t.senders, senderSpan{sender: sender, remaining: n})
+}
+
+// popSender consumes the rawDataLen bytes of a message from the queued senders and returns
+// the sender of its last byte, the decoder accounts for all the bytes read in the messages.
+func (t *Tailer) popSender(rawDataLen int) string {
+	t.sendersLock.Lock()
+	defer t.sendersLock.Unlock()
+	var sender string
+	for len(t.senders) > 0 {
+		span := &t.senders[0]
+		sender = span.sender
+		if rawDataLen < span.remaining {
+			span.remaining -= rawDataLen
+			break
+		}
+		rawDataLen -= span.remaining
+		t.senders = t.senders[1:]
+		if rawDataLen == 0 {
+			break
+		}
+	}
+	return sender
+}
+
 // readForever reads the data from conn.
 func (t *Tailer) readForever() {
 	defer func() {
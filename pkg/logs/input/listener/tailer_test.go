@@ -57,6 +57,21 @@ func TestReadShouldFailWithError(t *testing.T) {
 	tailer.Stop()
 }
 
+func TestPopSenderReturnsTheSenderOfTheLastByteOfTheMessages(t *testing.T) {
+	tailer := NewTailer(config.NewLogSource("", &config.LogsConfig{}), nil, nil, read)
+	tailer.resolver = &senderResolver{}
+	tailer.addSender("10.0.1.1", 4)
+	tailer.addSender("10.0.1.2", 8)
+	tailer.addSender("10.0.1.3", 4)
+
+	assert.Equal(t, "10.0.1.1", tailer.popSender(4))
+	assert.Equal(t, "10.0.1.2", tailer.popSender(0))
+	assert.Equal(t, "10.0.1.2", tailer.popSender(4))
+	assert.Equal(t, "10.0.1.3", tailer.popSender(8))
+	assert.Empty(t, tailer.senders)
+	assert.Equal(t, "", tailer.popSender(4))
+}
+
 func read(tailer *Tailer) ([]byte, error) {
 	inBuf := make([]byte, 4096)
 	n, err := tailer.conn.Read(inBuf)
@@ -6,6 +6,7 @@
 package listener
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"sync"
@@ -26,18 +27,24 @@ type TCPListener struct {
 	pipelineProvider pipeline.Provider
 	source           *config.LogSource
 	frameSize        int
+	resolver         *senderResolver
 	listener         net.Listener
 	tailers          []*Tailer
 	mu               sync.Mutex
 	stop             chan struct{}
 }
 
-// NewTCPListener returns an initialized TCPListener
+// NewTCPListener returns an initialized TCPListener reading the connections through buffers of frameSize bytes,
+// the frame size of the source takes precedence over frameSize when it's set.
 func NewTCPListener(pipelineProvider pipeline.Provider, source *config.LogSource, frameSize int) *TCPListener {
+	if source.Config.FrameSize > 0 {
+		frameSize = source.Config.FrameSize
+	}
 	return &TCPListener{
 		pipelineProvider: pipelineProvider,
 		source:           source,
 		frameSize:        frameSize,
+		resolver:         newSenderResolver(source),
 		tailers:          []*Tailer{},
 		stop:             make(chan struct{}, 1),
 	}
@@ -103,34 +110,61 @@ func (l *TCPListener) run() {
 	}
 }
 
-// startListener starts a new listener, returns an error if it failed.
+// startListener starts a new listener terminating TLS when the source has a certificate,
+// returns an error if it failed.
 func (l *TCPListener) startListener() error {
+	tlsConfig, err := NewTLSConfig(l.source.Config)
+	if err != nil {
+		return err
+	}
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", l.source.Config.Port))
 	if err != nil {
 		return err
 	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
 	l.listener = listener
 	return nil
 }
 
-// read reads data from connection, returns an error if it failed and stop the tailer.
-func (l *TCPListener) read(tailer *Tailer) ([]byte, error) {
+// read reads data from the connection of tailer with readData, returns an error if it failed and stop the tailer.
+func (l *TCPListener) read(tailer *Tailer, readData func() ([]byte, error)) ([]byte, error) {
 	tailer.conn.SetReadDeadline(time.Now().Add(defaultTimeout))
-	frame := make([]byte, l.frameSize)
-	n, err := tailer.conn.Read(frame)
+	data, err := readData()
 	if err != nil {
 		l.source.Status.Error(err)
 		go l.stopTailer(tailer)
 		return nil, err
 	}
-	return frame[:n], nil
+	return data, nil
 }
 
-// startNewTailer creates and starts a new tailer that reads from the connection.
+// startNewTailer creates and starts a new tailer that reads the lines of the connection, truncated to the
+// max line length of the source, the octet-counted frames are converted to newline-terminated ones for the syslog sources.
 func (l *TCPListener) startNewTailer(conn net.Conn) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	tailer := NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), l.read)
+	readData := newLineReader(conn, l.frameSize, l.source.Config.MaxLineLength).read
+	if l.source.Config.ParseSyslog {
+		// the octet-counted frames are not terminated by a newline, the data is framed as soon as it's read.
+		framer := newSyslogFramer()
+		truncator := newLineTruncator(l.source.Config.MaxLineLength)
+		readData = func() ([]byte, error) {
+			frame := make([]byte, l.frameSize)
+			n, err := conn.Read(frame)
+			if err != nil {
+				return nil, err
+			}
+			return truncator.truncate(framer.frame(frame[:n])), nil
+		}
+	}
+	read := func(tailer *Tailer) ([]byte, error) {
+		return l.read(tailer, readData)
+	}
+	tailer := NewTailer(l.source, conn, l.pipelineProvider.PipelineChanForSource(l.source), read)
+	tailer.resolver = l.resolver
+	tailer.sender = senderIP(conn.RemoteAddr())
 	l.tailers = append(l.tailers, tailer)
 	tailer.Start()
 }
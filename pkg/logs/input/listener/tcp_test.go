@@ -51,17 +51,84 @@ func TestTCPDoesNotTruncateMessagesThatAreBiggerThanTheReadBufferSize(t *testing
 
 	var msg *message.Message
 
-	fmt.Fprintf(conn, strings.Repeat("a", 80)+"\n")
+	fmt.Fprint(conn, strings.Repeat("a", 80)+"\n")
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", 80), string(msg.Content))
 
-	fmt.Fprintf(conn, strings.Repeat("a", 200)+"\n")
+	fmt.Fprint(conn, strings.Repeat("a", 200)+"\n")
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", 200), string(msg.Content))
 
-	fmt.Fprintf(conn, strings.Repeat("a", 70)+"\n")
+	fmt.Fprint(conn, strings.Repeat("a", 70)+"\n")
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", 70), string(msg.Content))
 
 	listener.Stop()
 }
+
+func TestTCPReceivesMessagesBiggerThanADatagram(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: tcpTestPort, FrameSize: 4096}), 9000)
+	listener.Start()
+	assert.Equal(t, 4096, listener.frameSize)
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("%s", listener.listener.Addr()))
+	assert.Nil(t, err)
+
+	fmt.Fprint(conn, strings.Repeat("a", 100*1024)+"\n")
+	assert.Equal(t, strings.Repeat("a", 100*1024), string((<-msgChan).Content))
+
+	listener.Stop()
+}
+
+func TestTCPTruncatesTheLinesLongerThanTheMaxLineLength(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: tcpTestPort, MaxLineLength: 150}), 100)
+	listener.Start()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("%s", listener.listener.Addr()))
+	assert.Nil(t, err)
+
+	fmt.Fprint(conn, strings.Repeat("a", 400)+"\n")
+	fmt.Fprint(conn, strings.Repeat("b", 70)+"\n")
+	assert.Equal(t, strings.Repeat("a", 150)+"...TRUNCATED...", string((<-msgChan).Content))
+	assert.Equal(t, strings.Repeat("b", 70), string((<-msgChan).Content))
+
+	listener.Stop()
+}
+
+func TestTCPFramesOctetCountedSyslogMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: tcpTestPort, ParseSyslog: true}), 9000)
+	listener.Start()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("%s", listener.listener.Addr()))
+	assert.Nil(t, err)
+
+	fmt.Fprintf(conn, "11 <34>1 hello16 <34>1 multi\nline<34>1 newline\n")
+	assert.Equal(t, "<34>1 hello", string((<-msgChan).Content))
+	assert.Equal(t, "<34>1 multi line", string((<-msgChan).Content))
+	assert.Equal(t, "<34>1 newline", string((<-msgChan).Content))
+
+	listener.Stop()
+}
+
+func TestTCPTagsTheMessagesWithTheirSender(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: tcpTestPort, SenderTags: true}), 9000)
+	listener.Start()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", listener.listener.Addr().(*net.TCPAddr).Port))
+	assert.Nil(t, err)
+
+	fmt.Fprintf(conn, "hello world\n")
+	msg := <-msgChan
+	assert.Equal(t, "hello world", string(msg.Content))
+	assert.Equal(t, []string{"sender_ip:127.0.0.1"}, msg.Origin.Tags())
+
+	listener.Stop()
+}
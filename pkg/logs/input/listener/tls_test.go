@@ -0,0 +1,168 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listener
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+// writeCertificate generates a self-signed certificate valid for 127.0.0.1
+// and writes it along with its private key in dir with the given prefix.
+func writeCertificate(t *testing.T, dir, prefix string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile = filepath.Join(dir, prefix+"-cert.pem")
+	keyFile = filepath.Join(dir, prefix+"-key.pem")
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+// newClientTLSConfig returns a TLS configuration trusting the certificate in caFile.
+func newClientTLSConfig(t *testing.T, caFile string) *tls.Config {
+	pem, err := ioutil.ReadFile(caFile)
+	assert.NoError(t, err)
+	pool := x509.NewCertPool()
+	assert.True(t, pool.AppendCertsFromPEM(pem))
+	return &tls.Config{RootCAs: pool}
+}
+
+// localAddr returns the loopback address the listener can be reached at with a certificate valid for 127.0.0.1.
+func localAddr(listener *TCPListener) string {
+	return fmt.Sprintf("127.0.0.1:%d", listener.listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listener-tls-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir, "server")
+
+	tlsConfig, err := NewTLSConfig(&config.LogsConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	tlsConfig, err = NewTLSConfig(&config.LogsConfig{TLSCertFile: certFile, TLSKeyFile: keyFile})
+	assert.NoError(t, err)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+
+	tlsConfig, err = NewTLSConfig(&config.LogsConfig{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: certFile})
+	assert.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+
+	_, err = NewTLSConfig(&config.LogsConfig{TLSCertFile: certFile, TLSKeyFile: filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig(&config.LogsConfig{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: keyFile})
+	assert.Error(t, err)
+}
+
+func TestTCPListenerTerminatesTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listener-tls-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir, "server")
+
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: tcpTestPort, TLSCertFile: certFile, TLSKeyFile: keyFile}), 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := tls.Dial("tcp", localAddr(listener), newClientTLSConfig(t, certFile))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "hello world\n")
+	msg := <-msgChan
+	assert.Equal(t, "hello world", string(msg.Content))
+}
+
+func TestTCPListenerVerifiesClientCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listener-tls-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir, "server")
+	clientCertFile, clientKeyFile := writeCertificate(t, dir, "client")
+	otherCertFile, otherKeyFile := writeCertificate(t, dir, "other")
+
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: tcpTestPort, TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: clientCertFile}), 9000)
+	listener.Start()
+	defer listener.Stop()
+	addr := localAddr(listener)
+
+	// the connections without a certificate signed by the client CA are rejected
+	for _, files := range [][]string{nil, {otherCertFile, otherKeyFile}} {
+		tlsConfig := newClientTLSConfig(t, certFile)
+		if files != nil {
+			cert, err := tls.LoadX509KeyPair(files[0], files[1])
+			assert.NoError(t, err)
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err == nil {
+			fmt.Fprintf(conn, "rejected\n")
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			_, err = conn.Read(make([]byte, 1))
+			conn.Close()
+		}
+		assert.Error(t, err)
+	}
+
+	tlsConfig := newClientTLSConfig(t, certFile)
+	cert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	assert.NoError(t, err)
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "accepted\n")
+	msg := <-msgChan
+	assert.Equal(t, "accepted", string(msg.Content))
+}
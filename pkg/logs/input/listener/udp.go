@@ -6,8 +6,8 @@
 package listener
 
 import (
-	"fmt"
 	"net"
+	"strconv"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -31,15 +31,21 @@ type UDPListener struct {
 	pipelineProvider pipeline.Provider
 	source           *config.LogSource
 	frameSize        int
+	resolver         *senderResolver
 	tailer           *Tailer
 }
 
-// NewUDPListener returns an initialized UDPListener
+// NewUDPListener returns an initialized UDPListener,
+// the frame size of the source takes precedence over frameSize when it's set.
 func NewUDPListener(pipelineProvider pipeline.Provider, source *config.LogSource, frameSize int) *UDPListener {
+	if source.Config.FrameSize > 0 {
+		frameSize = source.Config.FrameSize
+	}
 	return &UDPListener{
 		pipelineProvider: pipelineProvider,
 		source:           source,
 		frameSize:        frameSize,
+		resolver:         newSenderResolver(source),
 	}
 }
 
@@ -67,15 +73,16 @@ func (l *UDPListener) startNewTailer() error {
 	if err != nil {
 		return err
 	}
-	l.tailer = NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), l.read)
+	l.tailer = NewTailer(l.source, conn, l.pipelineProvider.PipelineChanForSource(l.source), l.read)
+	l.tailer.resolver = l.resolver
 	l.tailer.Start()
 	return nil
 }
 
-// newUDPConnection returns a new UDP connection,
-// returns an error if the creation failed.
+// newUDPConnection returns a new UDP connection bound to the host of the source, or to all the interfaces
+// if it's not set, returns an error if the creation failed.
 func (l *UDPListener) newUDPConnection() (net.Conn, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", l.source.Config.Port))
+	udpAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(l.source.Config.BindHost, strconv.Itoa(l.source.Config.Port)))
 	if err != nil {
 		return nil, err
 	}
@@ -83,9 +90,17 @@ func (l *UDPListener) newUDPConnection() (net.Conn, error) {
 }
 
 // read reads data from the tailer connection, returns an error if it failed and reset the tailer.
+// The senders of the datagrams are queued when the tailer resolves them.
 func (l *UDPListener) read(tailer *Tailer) ([]byte, error) {
 	frame := make([]byte, l.frameSize+1)
-	n, err := tailer.conn.Read(frame)
+	var n int
+	var addr net.Addr
+	var err error
+	if packetConn, ok := tailer.conn.(net.PacketConn); ok && tailer.resolver != nil {
+		n, addr, err = packetConn.ReadFrom(frame)
+	} else {
+		n, err = tailer.conn.Read(frame)
+	}
 	switch {
 	case err != nil && isClosedConnError(err):
 		return nil, err
@@ -102,6 +117,7 @@ func (l *UDPListener) read(tailer *Tailer) ([]byte, error) {
 			frame[n] = '\n'
 			n++
 		}
+		tailer.addSender(senderIP(addr), n)
 		return frame[:n], nil
 	}
 }
@@ -32,21 +32,21 @@ func TestUDPShoulProperlyCollectLogSplitPerDatadgram(t *testing.T) {
 
 	var msg *message.Message
 
-	fmt.Fprintf(conn, strings.Repeat("a", 10))
+	fmt.Fprint(conn, strings.Repeat("a", 10))
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", 10), string(msg.Content))
 
-	fmt.Fprintf(conn, strings.Repeat("a", 10)+"\n")
+	fmt.Fprint(conn, strings.Repeat("a", 10)+"\n")
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", 10), string(msg.Content))
 
-	fmt.Fprintf(conn, strings.Repeat("a", 10)+"\n"+strings.Repeat("a", 10))
+	fmt.Fprint(conn, strings.Repeat("a", 10)+"\n"+strings.Repeat("a", 10))
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", 10), string(msg.Content))
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", 10), string(msg.Content))
 
-	fmt.Fprintf(conn, strings.Repeat("a", 10)+"\n"+strings.Repeat("a", 10)+"\n")
+	fmt.Fprint(conn, strings.Repeat("a", 10)+"\n"+strings.Repeat("a", 10)+"\n")
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", 10), string(msg.Content))
 	msg = <-msgChan
@@ -67,15 +67,15 @@ func TestUDPShouldProperlyTruncateBigMessages(t *testing.T) {
 
 	var msg *message.Message
 
-	fmt.Fprintf(conn, strings.Repeat("a", frameSize-10))
+	fmt.Fprint(conn, strings.Repeat("a", frameSize-10))
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", frameSize-10), string(msg.Content))
 
-	fmt.Fprintf(conn, strings.Repeat("a", frameSize))
+	fmt.Fprint(conn, strings.Repeat("a", frameSize))
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", frameSize), string(msg.Content))
 
-	fmt.Fprintf(conn, strings.Repeat("a", frameSize+10))
+	fmt.Fprint(conn, strings.Repeat("a", frameSize+10))
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", frameSize), string(msg.Content))
 
@@ -98,15 +98,32 @@ func TestUDPShoulDropTooBigMessages(t *testing.T) {
 
 	var msg *message.Message
 
-	fmt.Fprintf(conn, strings.Repeat("a", maxUDPFrameLen-100)+"\n")
+	fmt.Fprint(conn, strings.Repeat("a", maxUDPFrameLen-100)+"\n")
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", maxUDPFrameLen-100), string(msg.Content))
 
 	// the first frame should be dropped as it's too big compare to the limit.
-	fmt.Fprintf(conn, strings.Repeat("a", maxUDPFrameLen+100)+"\n")
-	fmt.Fprintf(conn, strings.Repeat("a", maxUDPFrameLen-200)+"\n")
+	fmt.Fprint(conn, strings.Repeat("a", maxUDPFrameLen+100)+"\n")
+	fmt.Fprint(conn, strings.Repeat("a", maxUDPFrameLen-200)+"\n")
 	msg = <-msgChan
 	assert.Equal(t, strings.Repeat("a", maxUDPFrameLen-200), string(msg.Content))
 
 	listener.Stop()
 }
+
+func TestUDPShouldUseTheFrameSizeAndTheHostOfTheSource(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewUDPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: udpTestPort, BindHost: "127.0.0.1", FrameSize: 10}), 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	addr := listener.tailer.conn.LocalAddr().(*net.UDPAddr)
+	assert.True(t, addr.IP.Equal(net.ParseIP("127.0.0.1")))
+
+	conn, err := net.Dial("udp", addr.String())
+	assert.Nil(t, err)
+	fmt.Fprint(conn, strings.Repeat("a", 20))
+	msg := <-msgChan
+	assert.Equal(t, strings.Repeat("a", 10), string(msg.Content))
+}
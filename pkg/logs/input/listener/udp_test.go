@@ -37,3 +37,29 @@ func TestUDPShouldReceiveMessage(t *testing.T) {
 
 	listener.Stop()
 }
+
+func TestUDPTagsTheMessagesWithTheirSender(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	source := config.NewLogSource("", &config.LogsConfig{
+		Port:            udpTestPort,
+		BindHost:        "127.0.0.1",
+		SenderTags:      true,
+		SenderOverrides: []*config.SenderOverride{{Sender: "127.0.0.0/8", Source: "loopback"}},
+	})
+	listener := NewUDPListener(pp, source, 9000)
+	listener.Start()
+
+	conn, err := net.Dial("udp", fmt.Sprintf("%s", listener.tailer.conn.LocalAddr()))
+	assert.Nil(t, err)
+
+	fmt.Fprintf(conn, "hello\nworld")
+	for _, content := range []string{"hello", "world"} {
+		msg := <-msgChan
+		assert.Equal(t, content, string(msg.Content))
+		assert.Equal(t, []string{"sender_ip:127.0.0.1"}, msg.Origin.Tags())
+		assert.Equal(t, "loopback", msg.Origin.Source())
+	}
+
+	listener.Stop()
+}
@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// The types of the JSON encoding of the OTLP logs, the 64-bit integers can be encoded as strings
+// and the trace and span identifiers are encoded in hexadecimal.
+type (
+	jsonRequest struct {
+		ResourceLogs []jsonResourceLogs `json:"resourceLogs"`
+	}
+	jsonResourceLogs struct {
+		Resource struct {
+			Attributes []jsonKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs                  []jsonScopeLogs `json:"scopeLogs"`
+		InstrumentationLibraryLogs []jsonScopeLogs `json:"instrumentationLibraryLogs"`
+	}
+	jsonScopeLogs struct {
+		Scope                  jsonScope       `json:"scope"`
+		InstrumentationLibrary jsonScope       `json:"instrumentationLibrary"`
+		LogRecords             []jsonLogRecord `json:"logRecords"`
+	}
+	jsonScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	jsonLogRecord struct {
+		TimeUnixNano         jsonInt        `json:"timeUnixNano"`
+		ObservedTimeUnixNano jsonInt        `json:"observedTimeUnixNano"`
+		SeverityNumber       int32          `json:"severityNumber"`
+		SeverityText         string         `json:"severityText"`
+		Body                 *jsonAnyValue  `json:"body"`
+		Attributes           []jsonKeyValue `json:"attributes"`
+		TraceID              jsonHex        `json:"traceId"`
+		SpanID               jsonHex        `json:"spanId"`
+	}
+	jsonKeyValue struct {
+		Key   string        `json:"key"`
+		Value *jsonAnyValue `json:"value"`
+	}
+	jsonAnyValue struct {
+		StringValue *string  `json:"stringValue"`
+		BoolValue   *bool    `json:"boolValue"`
+		IntValue    *jsonInt `json:"intValue"`
+		DoubleValue *float64 `json:"doubleValue"`
+		ArrayValue  *struct {
+			Values []*jsonAnyValue `json:"values"`
+		} `json:"arrayValue"`
+		KvlistValue *struct {
+			Values []jsonKeyValue `json:"values"`
+		} `json:"kvlistValue"`
+		BytesValue []byte `json:"bytesValue"`
+	}
+)
+
+// jsonInt is a 64-bit integer encoded as a number or as a string.
+type jsonInt int64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *jsonInt) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	if value, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		*i = jsonInt(value)
+		return nil
+	}
+	// the timestamps in nanoseconds are unsigned
+	value, err := strconv.ParseUint(string(data), 10, 64)
+	*i = jsonInt(value)
+	return err
+}
+
+// jsonHex is a binary value encoded in hexadecimal.
+type jsonHex []byte
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (h *jsonHex) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(value)
+	*h = decoded
+	return err
+}
+
+// UnmarshalJSON decodes the JSON encoding of an ExportLogsServiceRequest.
+func (m *ExportLogsServiceRequest) UnmarshalJSON(data []byte) error {
+	var request jsonRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return err
+	}
+	for _, resourceLogs := range request.ResourceLogs {
+		resource := &ResourceLogs{
+			Resource: toAttributes(resourceLogs.Resource.Attributes),
+		}
+		for _, scopeLogs := range append(resourceLogs.ScopeLogs, resourceLogs.InstrumentationLibraryLogs...) {
+			scope := scopeLogs.Scope
+			if scope.Name == "" {
+				scope = scopeLogs.InstrumentationLibrary
+			}
+			logs := &ScopeLogs{
+				ScopeName:    scope.Name,
+				ScopeVersion: scope.Version,
+			}
+			for _, record := range scopeLogs.LogRecords {
+				logs.LogRecords = append(logs.LogRecords, &LogRecord{
+					TimeUnixNano:         uint64(record.TimeUnixNano),
+					ObservedTimeUnixNano: uint64(record.ObservedTimeUnixNano),
+					SeverityNumber:       record.SeverityNumber,
+					SeverityText:         record.SeverityText,
+					Body:                 record.Body.toValue(),
+					Attributes:           toAttributes(record.Attributes),
+					TraceID:              record.TraceID,
+					SpanID:               record.SpanID,
+				})
+			}
+			resource.ScopeLogs = append(resource.ScopeLogs, logs)
+		}
+		m.ResourceLogs = append(m.ResourceLogs, resource)
+	}
+	return nil
+}
+
+// toAttributes converts key values to a map.
+func toAttributes(keyValues []jsonKeyValue) map[string]interface{} {
+	if len(keyValues) == 0 {
+		return nil
+	}
+	attributes := make(map[string]interface{}, len(keyValues))
+	for _, keyValue := range keyValues {
+		attributes[keyValue.Key] = keyValue.Value.toValue()
+	}
+	return attributes
+}
+
+// toValue converts an AnyValue to a Go value, nil if it's empty.
+func (v *jsonAnyValue) toValue() interface{} {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.ArrayValue != nil:
+		values := []interface{}{}
+		for _, value := range v.ArrayValue.Values {
+			values = append(values, value.toValue())
+		}
+		return values
+	case v.KvlistValue != nil:
+		values := toAttributes(v.KvlistValue.Values)
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+		return values
+	case v.BytesValue != nil:
+		return v.BytesValue
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package otlp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalJSONExportLogsServiceRequest(t *testing.T) {
+	data := `{"resourceLogs":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},
+		"scopeLogs":[{"scope":{"name":"my.library","version":"1.0.0"},"logRecords":[{
+			"timeUnixNano":"1551441600000000500",
+			"observedTimeUnixNano":1551441600000000600,
+			"severityNumber":17,
+			"severityText":"ERROR",
+			"body":{"stringValue":"hello world"},
+			"attributes":[
+				{"key":"http.status_code","value":{"intValue":"500"}},
+				{"key":"retry","value":{"boolValue":true}},
+				{"key":"ratio","value":{"doubleValue":0.5}},
+				{"key":"list","value":{"arrayValue":{"values":[{"stringValue":"a"},{"intValue":2}]}}},
+				{"key":"map","value":{"kvlistValue":{"values":[{"key":"nested","value":{"stringValue":"value"}}]}}},
+				{"key":"empty","value":{}}
+			],
+			"traceId":"0102030405060708000000000000002a",
+			"spanId":"0000000000000007"
+		}]}]
+	}]}`
+	request := &ExportLogsServiceRequest{}
+	assert.NoError(t, json.Unmarshal([]byte(data), request))
+	assert.Len(t, request.ResourceLogs, 1)
+	assert.Equal(t, map[string]interface{}{"service.name": "checkout"}, request.ResourceLogs[0].Resource)
+	assert.Equal(t, "my.library", request.ResourceLogs[0].ScopeLogs[0].ScopeName)
+	assert.Equal(t, "1.0.0", request.ResourceLogs[0].ScopeLogs[0].ScopeVersion)
+	assert.Equal(t, []*LogRecord{{
+		TimeUnixNano:         1551441600000000500,
+		ObservedTimeUnixNano: 1551441600000000600,
+		SeverityNumber:       17,
+		SeverityText:         "ERROR",
+		Body:                 "hello world",
+		Attributes: map[string]interface{}{
+			"http.status_code": int64(500),
+			"retry":            true,
+			"ratio":            0.5,
+			"list":             []interface{}{"a", int64(2)},
+			"map":              map[string]interface{}{"nested": "value"},
+			"empty":            nil,
+		},
+		TraceID: []byte{1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, 42},
+		SpanID:  []byte{0, 0, 0, 0, 0, 0, 0, 7},
+	}}, request.ResourceLogs[0].ScopeLogs[0].LogRecords)
+}
+
+func TestUnmarshalJSONFailsWithInvalidRequests(t *testing.T) {
+	for _, data := range []string{
+		`{"resourceLogs":"foo"}`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"not hex"}]}]}]}`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"yesterday"}]}]}]}`,
+	} {
+		request := &ExportLogsServiceRequest{}
+		assert.Error(t, json.Unmarshal([]byte(data), request), data)
+	}
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package otlp
+
+import (
+	"fmt"
+)
+
+// ExportLogsServiceRequest is the request of the Export method of the OTLP logs service,
+// opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest.
+type ExportLogsServiceRequest struct {
+	ResourceLogs []*ResourceLogs
+}
+
+// ResourceLogs holds the logs emitted by a resource.
+type ResourceLogs struct {
+	// Resource holds the attributes of the resource.
+	Resource  map[string]interface{}
+	ScopeLogs []*ScopeLogs
+}
+
+// ScopeLogs holds the logs emitted by an instrumentation scope of a resource.
+type ScopeLogs struct {
+	ScopeName    string
+	ScopeVersion string
+	LogRecords   []*LogRecord
+}
+
+// LogRecord is a log emitted by an instrumentation scope.
+type LogRecord struct {
+	TimeUnixNano         uint64
+	ObservedTimeUnixNano uint64
+	SeverityNumber       int32
+	SeverityText         string
+	// Body is a string, a bool, an int64, a float64, a []byte, a []interface{} or a map[string]interface{}.
+	Body       interface{}
+	Attributes map[string]interface{}
+	TraceID    []byte
+	SpanID     []byte
+}
+
+// Reset implements proto.Message.
+func (m *ExportLogsServiceRequest) Reset() { *m = ExportLogsServiceRequest{} }
+
+// String implements proto.Message.
+func (m *ExportLogsServiceRequest) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage implements proto.Message.
+func (*ExportLogsServiceRequest) ProtoMessage() {}
+
+// ExportLogsServiceResponse is the response of the Export method of the OTLP logs service,
+// it's always empty as the partial successes are not reported.
+type ExportLogsServiceResponse struct{}
+
+// Reset implements proto.Message.
+func (m *ExportLogsServiceResponse) Reset() {}
+
+// String implements proto.Message.
+func (m *ExportLogsServiceResponse) String() string { return "{}" }
+
+// ProtoMessage implements proto.Message.
+func (*ExportLogsServiceResponse) ProtoMessage() {}
+
+// Marshal implements proto.Marshaler.
+func (*ExportLogsServiceResponse) Marshal() ([]byte, error) { return []byte{}, nil }
+
+// Unmarshal implements proto.Unmarshaler.
+func (*ExportLogsServiceResponse) Unmarshal(data []byte) error { return nil }
@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// serviceAttribute is the attribute of the resources holding the name of their service.
+const serviceAttribute = "service.name"
+
+// severityStatuses maps the ranges of four severity numbers of OpenTelemetry, from TRACE to FATAL, to the statuses of the messages.
+var severityStatuses = []string{
+	message.StatusDebug,
+	message.StatusDebug,
+	message.StatusInfo,
+	message.StatusWarning,
+	message.StatusError,
+	message.StatusCritical,
+}
+
+// severityTexts maps the lowercase severity texts to the statuses of the messages
+// for the records without a severity number.
+var severityTexts = map[string]string{
+	"trace":    message.StatusDebug,
+	"debug":    message.StatusDebug,
+	"info":     message.StatusInfo,
+	"warn":     message.StatusWarning,
+	"warning":  message.StatusWarning,
+	"error":    message.StatusError,
+	"fatal":    message.StatusCritical,
+	"critical": message.StatusCritical,
+}
+
+// toMessages converts the log records of the request to messages holding JSON objects made of the attributes
+// of their resource and of their own attributes, along with their body, their timestamp, their severity and
+// the identifiers of their trace.
+func toMessages(source *config.LogSource, request *ExportLogsServiceRequest) ([]*message.Message, error) {
+	var messages []*message.Message
+	for _, resourceLogs := range request.ResourceLogs {
+		service, _ := resourceLogs.Resource[serviceAttribute].(string)
+		for _, scopeLogs := range resourceLogs.ScopeLogs {
+			for _, record := range scopeLogs.LogRecords {
+				content, err := json.Marshal(recordAttributes(resourceLogs.Resource, scopeLogs, record))
+				if err != nil {
+					return nil, err
+				}
+				origin := message.NewOrigin(source)
+				origin.SetService(service)
+				messages = append(messages, message.NewMessage(content, origin, toStatus(record)))
+			}
+		}
+	}
+	return messages, nil
+}
+
+// recordAttributes returns the attributes of the JSON object of a record, its attributes override the ones of its resource.
+func recordAttributes(resource map[string]interface{}, scopeLogs *ScopeLogs, record *LogRecord) map[string]interface{} {
+	attributes := make(map[string]interface{}, len(resource)+len(record.Attributes)+8)
+	for key, value := range resource {
+		attributes[key] = value
+	}
+	for key, value := range record.Attributes {
+		attributes[key] = value
+	}
+	switch body := record.Body.(type) {
+	case nil:
+	case string:
+		attributes["message"] = body
+	default:
+		if content, err := json.Marshal(body); err == nil {
+			attributes["message"] = string(content)
+		}
+	}
+	timestamp := record.TimeUnixNano
+	if timestamp == 0 {
+		timestamp = record.ObservedTimeUnixNano
+	}
+	if timestamp != 0 {
+		attributes["timestamp"] = time.Unix(0, int64(timestamp)).UTC().Format(time.RFC3339Nano)
+	}
+	if record.SeverityText != "" {
+		attributes["otel.severity_text"] = record.SeverityText
+	}
+	if record.SeverityNumber != 0 {
+		attributes["otel.severity_number"] = record.SeverityNumber
+	}
+	if scopeLogs.ScopeName != "" {
+		attributes["otel.scope.name"] = scopeLogs.ScopeName
+	}
+	if scopeLogs.ScopeVersion != "" {
+		attributes["otel.scope.version"] = scopeLogs.ScopeVersion
+	}
+	// the traces of Datadog are identified by the lower 64 bits of the identifiers of OpenTelemetry
+	if len(record.TraceID) == 16 {
+		attributes["otel.trace_id"] = hex.EncodeToString(record.TraceID)
+		attributes["dd.trace_id"] = strconv.FormatUint(binary.BigEndian.Uint64(record.TraceID[8:]), 10)
+	}
+	if len(record.SpanID) == 8 {
+		attributes["otel.span_id"] = hex.EncodeToString(record.SpanID)
+		attributes["dd.span_id"] = strconv.FormatUint(binary.BigEndian.Uint64(record.SpanID), 10)
+	}
+	return attributes
+}
+
+// toStatus returns the status of a record from its severity number, or from its severity text when it has none.
+func toStatus(record *LogRecord) string {
+	if record.SeverityNumber >= 1 && record.SeverityNumber <= 24 {
+		return severityStatuses[(record.SeverityNumber-1)/4]
+	}
+	if status, exists := severityTexts[strings.ToLower(record.SeverityText)]; exists {
+		return status
+	}
+	return message.StatusInfo
+}
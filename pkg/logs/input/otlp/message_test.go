@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package otlp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestToMessages(t *testing.T) {
+	request := &ExportLogsServiceRequest{}
+	assert.NoError(t, request.Unmarshal(exportRequest()))
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.OTLPType})
+	messages, err := toMessages(source, request)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	msg := messages[0]
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "checkout", msg.Origin.Service())
+	var attributes map[string]interface{}
+	assert.NoError(t, json.Unmarshal(msg.Content, &attributes))
+	assert.Equal(t, map[string]interface{}{
+		"service.name":         "checkout",
+		"host.name":            "web-1",
+		"http.status_code":     float64(500),
+		"retry":                true,
+		"ratio":                0.5,
+		"list":                 []interface{}{"a", "b"},
+		"map":                  map[string]interface{}{"nested": "value"},
+		"message":              "hello world",
+		"timestamp":            "2019-03-01T12:00:00.0000005Z",
+		"otel.severity_text":   "ERROR",
+		"otel.severity_number": float64(17),
+		"otel.scope.name":      "my.library",
+		"otel.scope.version":   "1.0.0",
+		"otel.trace_id":        "0102030405060708000000000000002a",
+		"otel.span_id":         "0000000000000007",
+		"dd.trace_id":          "42",
+		"dd.span_id":           "7",
+	}, attributes)
+}
+
+func TestToMessagesEncodesStructuredBodies(t *testing.T) {
+	request := &ExportLogsServiceRequest{ResourceLogs: []*ResourceLogs{{ScopeLogs: []*ScopeLogs{{LogRecords: []*LogRecord{
+		{Body: map[string]interface{}{"event": "login"}, ObservedTimeUnixNano: 1551441600000000000},
+	}}}}}}
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.OTLPType, Service: "web"})
+	messages, err := toMessages(source, request)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"message":"{\"event\":\"login\"}","timestamp":"2019-03-01T12:00:00Z"}`, string(messages[0].Content))
+	assert.Equal(t, message.StatusInfo, messages[0].GetStatus())
+	assert.Equal(t, "web", messages[0].Origin.Service())
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		record *LogRecord
+		status string
+	}{
+		{&LogRecord{SeverityNumber: 1}, message.StatusDebug},
+		{&LogRecord{SeverityNumber: 8}, message.StatusDebug},
+		{&LogRecord{SeverityNumber: 9}, message.StatusInfo},
+		{&LogRecord{SeverityNumber: 13, SeverityText: "error"}, message.StatusWarning},
+		{&LogRecord{SeverityNumber: 20}, message.StatusError},
+		{&LogRecord{SeverityNumber: 24}, message.StatusCritical},
+		{&LogRecord{SeverityText: "Warning"}, message.StatusWarning},
+		{&LogRecord{SeverityText: "FATAL"}, message.StatusCritical},
+		{&LogRecord{SeverityText: "unknown"}, message.StatusInfo},
+		{&LogRecord{}, message.StatusInfo},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.status, toStatus(test.record), "%+v", test.record)
+	}
+}
@@ -0,0 +1,343 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package otlp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The wire types of the protobuf encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errTruncated is returned when a message ends in the middle of a field.
+var errTruncated = errors.New("truncated protobuf message")
+
+// fieldReader reads the fields of a protobuf message one after the other.
+type fieldReader struct {
+	data []byte
+}
+
+// next returns the number and the wire type of the next field, done is true when there are no more fields.
+func (r *fieldReader) next() (number int, wireType int, done bool, err error) {
+	if len(r.data) == 0 {
+		return 0, 0, true, nil
+	}
+	key, err := r.varint()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return int(key >> 3), int(key & 7), false, nil
+}
+
+// varint reads a varint.
+func (r *fieldReader) varint() (uint64, error) {
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.data = r.data[n:]
+	return value, nil
+}
+
+// fixed64 reads a little-endian 64-bit integer.
+func (r *fieldReader) fixed64() (uint64, error) {
+	if len(r.data) < 8 {
+		return 0, errTruncated
+	}
+	value := binary.LittleEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return value, nil
+}
+
+// bytes reads a length-delimited value.
+func (r *fieldReader) bytes() ([]byte, error) {
+	length, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.data)) < length {
+		return nil, errTruncated
+	}
+	value := r.data[:length]
+	r.data = r.data[length:]
+	return value, nil
+}
+
+// skip skips the value of a field of the wire type.
+func (r *fieldReader) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		if len(r.data) < 4 {
+			return errTruncated
+		}
+		r.data = r.data[4:]
+	default:
+		err = fmt.Errorf("unsupported wire type %d", wireType)
+	}
+	return err
+}
+
+// readFields calls read with the number and the wire type of each field of data,
+// read returns false for the fields it does not know about so that they're skipped.
+func readFields(data []byte, read func(r *fieldReader, number int, wireType int) (bool, error)) error {
+	r := &fieldReader{data: data}
+	for {
+		number, wireType, done, err := r.next()
+		if err != nil || done {
+			return err
+		}
+		ok, err := read(r, number, wireType)
+		if err != nil {
+			return fmt.Errorf("invalid field %d: %v", number, err)
+		}
+		if !ok {
+			if err := r.skip(wireType); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Unmarshal implements proto.Unmarshaler.
+func (m *ExportLogsServiceRequest) Unmarshal(data []byte) error {
+	return readFields(data, func(r *fieldReader, number int, wireType int) (bool, error) {
+		if number != 1 || wireType != wireBytes {
+			return false, nil
+		}
+		value, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		resourceLogs := &ResourceLogs{}
+		if err := resourceLogs.unmarshal(value); err != nil {
+			return true, err
+		}
+		m.ResourceLogs = append(m.ResourceLogs, resourceLogs)
+		return true, nil
+	})
+}
+
+// unmarshal decodes a ResourceLogs, the deprecated instrumentation_library_logs are decoded as scope_logs.
+func (m *ResourceLogs) unmarshal(data []byte) error {
+	return readFields(data, func(r *fieldReader, number int, wireType int) (bool, error) {
+		if wireType != wireBytes || number != 1 && number != 2 && number != 1000 {
+			return false, nil
+		}
+		value, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		if number == 1 {
+			// the attributes of the resource
+			m.Resource = make(map[string]interface{})
+			return true, readFields(value, func(r *fieldReader, number int, wireType int) (bool, error) {
+				if number != 1 || wireType != wireBytes {
+					return false, nil
+				}
+				data, err := r.bytes()
+				if err != nil {
+					return true, err
+				}
+				_, err = unmarshalKeyValue(data, m.Resource)
+				return true, err
+			})
+		}
+		scopeLogs := &ScopeLogs{}
+		if err := scopeLogs.unmarshal(value); err != nil {
+			return true, err
+		}
+		m.ScopeLogs = append(m.ScopeLogs, scopeLogs)
+		return true, nil
+	})
+}
+
+// unmarshal decodes a ScopeLogs.
+func (m *ScopeLogs) unmarshal(data []byte) error {
+	return readFields(data, func(r *fieldReader, number int, wireType int) (bool, error) {
+		if wireType != wireBytes || number != 1 && number != 2 {
+			return false, nil
+		}
+		value, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		if number == 1 {
+			// the name and the version of the scope
+			return true, readFields(value, func(r *fieldReader, number int, wireType int) (bool, error) {
+				if wireType != wireBytes || number != 1 && number != 2 {
+					return false, nil
+				}
+				value, err := r.bytes()
+				if number == 1 {
+					m.ScopeName = string(value)
+				} else {
+					m.ScopeVersion = string(value)
+				}
+				return true, err
+			})
+		}
+		logRecord := &LogRecord{}
+		if err := logRecord.unmarshal(value); err != nil {
+			return true, err
+		}
+		m.LogRecords = append(m.LogRecords, logRecord)
+		return true, nil
+	})
+}
+
+// unmarshal decodes a LogRecord.
+func (m *LogRecord) unmarshal(data []byte) error {
+	return readFields(data, func(r *fieldReader, number int, wireType int) (bool, error) {
+		var err error
+		switch {
+		case number == 1 && wireType == wireFixed64:
+			m.TimeUnixNano, err = r.fixed64()
+		case number == 11 && wireType == wireFixed64:
+			m.ObservedTimeUnixNano, err = r.fixed64()
+		case number == 2 && wireType == wireVarint:
+			var value uint64
+			value, err = r.varint()
+			m.SeverityNumber = int32(value)
+		case number == 3 && wireType == wireBytes:
+			var value []byte
+			value, err = r.bytes()
+			m.SeverityText = string(value)
+		case number == 5 && wireType == wireBytes:
+			var value []byte
+			if value, err = r.bytes(); err == nil {
+				m.Body, err = unmarshalAnyValue(value)
+			}
+		case number == 6 && wireType == wireBytes:
+			var value []byte
+			if value, err = r.bytes(); err == nil {
+				m.Attributes, err = unmarshalKeyValue(value, m.Attributes)
+			}
+		case number == 9 && wireType == wireBytes:
+			m.TraceID, err = r.bytes()
+		case number == 10 && wireType == wireBytes:
+			m.SpanID, err = r.bytes()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+// unmarshalKeyValue decodes the KeyValue of data and adds it to attributes, which is allocated when nil.
+func unmarshalKeyValue(data []byte, attributes map[string]interface{}) (map[string]interface{}, error) {
+	if attributes == nil {
+		attributes = make(map[string]interface{})
+	}
+	var key string
+	var value interface{}
+	err := readFields(data, func(r *fieldReader, number int, wireType int) (bool, error) {
+		if wireType != wireBytes || number != 1 && number != 2 {
+			return false, nil
+		}
+		data, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		if number == 1 {
+			key = string(data)
+		} else {
+			value, err = unmarshalAnyValue(data)
+		}
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	attributes[key] = value
+	return attributes, nil
+}
+
+// unmarshalAnyValue decodes an AnyValue to a Go value, nil if it's empty.
+func unmarshalAnyValue(data []byte) (interface{}, error) {
+	var value interface{}
+	err := readFields(data, func(r *fieldReader, number int, wireType int) (bool, error) {
+		switch {
+		case number == 1 && wireType == wireBytes:
+			data, err := r.bytes()
+			value = string(data)
+			return true, err
+		case number == 2 && wireType == wireVarint:
+			data, err := r.varint()
+			value = data != 0
+			return true, err
+		case number == 3 && wireType == wireVarint:
+			data, err := r.varint()
+			value = int64(data)
+			return true, err
+		case number == 4 && wireType == wireFixed64:
+			data, err := r.fixed64()
+			value = math.Float64frombits(data)
+			return true, err
+		case number == 5 && wireType == wireBytes:
+			// an ArrayValue made of repeated AnyValue
+			data, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			values := []interface{}{}
+			err = readFields(data, func(r *fieldReader, number int, wireType int) (bool, error) {
+				if number != 1 || wireType != wireBytes {
+					return false, nil
+				}
+				data, err := r.bytes()
+				if err != nil {
+					return true, err
+				}
+				item, err := unmarshalAnyValue(data)
+				values = append(values, item)
+				return true, err
+			})
+			value = values
+			return true, err
+		case number == 6 && wireType == wireBytes:
+			// a KeyValueList made of repeated KeyValue
+			data, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			values := map[string]interface{}{}
+			err = readFields(data, func(r *fieldReader, number int, wireType int) (bool, error) {
+				if number != 1 || wireType != wireBytes {
+					return false, nil
+				}
+				data, err := r.bytes()
+				if err != nil {
+					return true, err
+				}
+				_, err = unmarshalKeyValue(data, values)
+				return true, err
+			})
+			value = values
+			return true, err
+		case number == 7 && wireType == wireBytes:
+			data, err := r.bytes()
+			value = append([]byte{}, data...)
+			return true, err
+		}
+		return false, nil
+	})
+	return value, err
+}
@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package otlp
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// appendVarint appends the varint encoding of value to data.
+func appendVarint(data []byte, value uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(data, buf[:binary.PutUvarint(buf, value)]...)
+}
+
+// varintField returns the protobuf encoding of a varint field.
+func varintField(number int, value uint64) []byte {
+	data := appendVarint(nil, uint64(number<<3|wireVarint))
+	return appendVarint(data, value)
+}
+
+// fixed64Field returns the protobuf encoding of a fixed64 field.
+func fixed64Field(number int, value uint64) []byte {
+	data := appendVarint(nil, uint64(number<<3|wireFixed64))
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, value)
+	return append(data, buf...)
+}
+
+// bytesField returns the protobuf encoding of a length-delimited field made of the values.
+func bytesField(number int, values ...[]byte) []byte {
+	var value []byte
+	for _, v := range values {
+		value = append(value, v...)
+	}
+	data := appendVarint(nil, uint64(number<<3|wireBytes))
+	data = appendVarint(data, uint64(len(value)))
+	return append(data, value...)
+}
+
+// stringValue returns the protobuf encoding of the AnyValue of a string.
+func stringValue(value string) []byte {
+	return bytesField(1, []byte(value))
+}
+
+// keyValue returns the protobuf encoding of a KeyValue.
+func keyValue(key string, value []byte) []byte {
+	return append(bytesField(1, []byte(key)), bytesField(2, value)...)
+}
+
+// exportRequest returns the protobuf encoding of an ExportLogsServiceRequest made of a single record.
+func exportRequest() []byte {
+	traceID := []byte{1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, 42}
+	spanID := []byte{0, 0, 0, 0, 0, 0, 0, 7}
+	record := bytesField(2,
+		fixed64Field(1, 1551441600000000500),
+		varintField(2, 17),
+		bytesField(3, []byte("ERROR")),
+		bytesField(5, stringValue("hello world")),
+		bytesField(6, keyValue("http.status_code", varintField(3, 500))),
+		bytesField(6, keyValue("retry", varintField(2, 1))),
+		bytesField(6, keyValue("ratio", fixed64Field(4, math.Float64bits(0.5)))),
+		bytesField(6, keyValue("list", bytesField(5, bytesField(1, stringValue("a")), bytesField(1, stringValue("b"))))),
+		bytesField(6, keyValue("map", bytesField(6, bytesField(1, keyValue("nested", stringValue("value")))))),
+		fixed64Field(8, 1),
+		bytesField(9, traceID),
+		bytesField(10, spanID),
+		varintField(100, 1),
+	)
+	scope := bytesField(1, bytesField(1, []byte("my.library")), bytesField(2, []byte("1.0.0")))
+	resource := bytesField(1,
+		bytesField(1, keyValue("service.name", stringValue("checkout"))),
+		bytesField(1, keyValue("host.name", stringValue("web-1"))),
+	)
+	return bytesField(1, resource, bytesField(2, scope, record))
+}
+
+func TestUnmarshalExportLogsServiceRequest(t *testing.T) {
+	request := &ExportLogsServiceRequest{}
+	assert.NoError(t, request.Unmarshal(exportRequest()))
+	assert.Len(t, request.ResourceLogs, 1)
+	resourceLogs := request.ResourceLogs[0]
+	assert.Equal(t, map[string]interface{}{"service.name": "checkout", "host.name": "web-1"}, resourceLogs.Resource)
+	assert.Len(t, resourceLogs.ScopeLogs, 1)
+	assert.Equal(t, "my.library", resourceLogs.ScopeLogs[0].ScopeName)
+	assert.Equal(t, "1.0.0", resourceLogs.ScopeLogs[0].ScopeVersion)
+	assert.Equal(t, []*LogRecord{{
+		TimeUnixNano:   1551441600000000500,
+		SeverityNumber: 17,
+		SeverityText:   "ERROR",
+		Body:           "hello world",
+		Attributes: map[string]interface{}{
+			"http.status_code": int64(500),
+			"retry":            true,
+			"ratio":            0.5,
+			"list":             []interface{}{"a", "b"},
+			"map":              map[string]interface{}{"nested": "value"},
+		},
+		TraceID: []byte{1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, 42},
+		SpanID:  []byte{0, 0, 0, 0, 0, 0, 0, 7},
+	}}, resourceLogs.ScopeLogs[0].LogRecords)
+}
+
+func TestUnmarshalDeprecatedInstrumentationLibraryLogs(t *testing.T) {
+	request := &ExportLogsServiceRequest{}
+	data := bytesField(1, bytesField(1000, bytesField(1, bytesField(1, []byte("my.library"))), bytesField(2, bytesField(5, stringValue("hello")))))
+	assert.NoError(t, request.Unmarshal(data))
+	assert.Equal(t, "my.library", request.ResourceLogs[0].ScopeLogs[0].ScopeName)
+	assert.Equal(t, "hello", request.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body)
+}
+
+func TestUnmarshalFailsWithTruncatedRequests(t *testing.T) {
+	data := exportRequest()
+	for _, size := range []int{1, 10, len(data) - 1} {
+		request := &ExportLogsServiceRequest{}
+		assert.Error(t, request.Unmarshal(data[:size]))
+	}
+}
@@ -0,0 +1,210 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+)
+
+// logsPath is the path of the OTLP/HTTP logs endpoint.
+const logsPath = "/v1/logs"
+
+// maxRequestSize is the maximum size of the body of an OTLP/HTTP request once uncompressed.
+const maxRequestSize = 16 * 1024 * 1024
+
+// stopTimeout is the time given to the requests being processed to complete when the receiver is stopped.
+const stopTimeout = 5 * time.Second
+
+// logsServer is the interface of the OTLP logs service, opentelemetry.proto.collector.logs.v1.LogsService.
+type logsServer interface {
+	Export(ctx context.Context, request *ExportLogsServiceRequest) (*ExportLogsServiceResponse, error)
+}
+
+// logsServiceDesc describes the OTLP logs service to the gRPC server.
+var logsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opentelemetry.proto.collector.logs.v1.LogsService",
+	HandlerType: (*logsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Export",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				request := &ExportLogsServiceRequest{}
+				if err := dec(request); err != nil {
+					return nil, err
+				}
+				return srv.(logsServer).Export(ctx, request)
+			},
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "opentelemetry/proto/collector/logs/v1/logs_service.proto",
+}
+
+// A Receiver receives the logs of the OpenTelemetry SDKs and collectors over OTLP/gRPC on the port of its source
+// and over OTLP/HTTP on the HTTP port of its source.
+type Receiver struct {
+	source       *config.LogSource
+	outputChan   chan *message.Message
+	grpcServer   *grpc.Server
+	httpServer   *http.Server
+	grpcListener net.Listener
+	httpListener net.Listener
+}
+
+// NewReceiver returns an initialized Receiver
+func NewReceiver(pipelineProvider pipeline.Provider, source *config.LogSource) *Receiver {
+	return &Receiver{
+		source:     source,
+		outputChan: pipelineProvider.PipelineChanForSource(source),
+	}
+}
+
+// Start starts listening to the ports of the source.
+func (r *Receiver) Start() {
+	if err := r.startListeners(); err != nil {
+		log.Errorf("Can't start OTLP receiver: %v", err)
+		r.source.Status.Error(err)
+		r.closeListeners()
+		return
+	}
+	r.source.Status.Success()
+	if r.grpcListener != nil {
+		log.Infof("Starting OTLP/gRPC receiver on port %d", r.source.Config.Port)
+		r.grpcServer = grpc.NewServer()
+		r.grpcServer.RegisterService(&logsServiceDesc, r)
+		go r.grpcServer.Serve(r.grpcListener)
+	}
+	if r.httpListener != nil {
+		log.Infof("Starting OTLP/HTTP receiver on port %d", r.source.Config.HTTPPort)
+		mux := http.NewServeMux()
+		mux.HandleFunc(logsPath, r.handleHTTP)
+		r.httpServer = &http.Server{Handler: mux}
+		go r.httpServer.Serve(r.httpListener)
+	}
+}
+
+// Stop stops the servers and waits for the requests being processed to complete.
+func (r *Receiver) Stop() {
+	log.Info("Stopping OTLP receiver")
+	if r.grpcServer != nil {
+		r.grpcServer.GracefulStop()
+	}
+	if r.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer cancel()
+		r.httpServer.Shutdown(ctx)
+	}
+}
+
+// startListeners listens to the ports defined by the source.
+func (r *Receiver) startListeners() error {
+	var err error
+	if r.source.Config.Port != 0 {
+		if r.grpcListener, err = net.Listen("tcp", fmt.Sprintf(":%d", r.source.Config.Port)); err != nil {
+			return err
+		}
+	}
+	if r.source.Config.HTTPPort != 0 {
+		if r.httpListener, err = net.Listen("tcp", fmt.Sprintf(":%d", r.source.Config.HTTPPort)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeListeners closes the listeners that have been started.
+func (r *Receiver) closeListeners() {
+	if r.grpcListener != nil {
+		r.grpcListener.Close()
+	}
+	if r.httpListener != nil {
+		r.httpListener.Close()
+	}
+}
+
+// Export implements the Export method of the OTLP logs service,
+// the call returns once all the records have been forwarded to the pipeline.
+func (r *Receiver) Export(ctx context.Context, request *ExportLogsServiceRequest) (*ExportLogsServiceResponse, error) {
+	messages, err := toMessages(r.source, request)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range messages {
+		select {
+		case r.outputChan <- msg:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &ExportLogsServiceResponse{}, nil
+}
+
+// handleHTTP handles the OTLP/HTTP requests, encoded either with protobuf or with JSON and optionally compressed with gzip.
+func (r *Receiver) handleHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		reader, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer reader.Close()
+		body = reader
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxRequestSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxRequestSize {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	isJSON := strings.HasPrefix(req.Header.Get("Content-Type"), "application/json")
+	request := &ExportLogsServiceRequest{}
+	if isJSON {
+		err = json.Unmarshal(data, request)
+	} else {
+		err = request.Unmarshal(data)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if _, err := r.Export(req.Context(), request); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
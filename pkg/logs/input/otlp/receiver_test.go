@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/testutil"
+)
+
+// rawRequest is an encoded request sent by the gRPC client.
+type rawRequest []byte
+
+func (r rawRequest) Reset()                   {}
+func (r rawRequest) String() string           { return "raw" }
+func (r rawRequest) ProtoMessage()            {}
+func (r rawRequest) Marshal() ([]byte, error) { return r, nil }
+
+// freePort returns a port available on the loopback interface.
+func TestReceiverExportsOverGRPC(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	port := testutil.FreePort(t)
+	receiver := NewReceiver(pp, config.NewLogSource("", &config.LogsConfig{Type: config.OTLPType, Port: port}))
+	receiver.Start()
+	defer receiver.Stop()
+
+	conn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", port), grpc.WithInsecure())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- conn.Invoke(ctx, "/opentelemetry.proto.collector.logs.v1.LogsService/Export", rawRequest(exportRequest()), &ExportLogsServiceResponse{})
+	}()
+
+	msg := <-msgChan
+	assert.Contains(t, string(msg.Content), `"message":"hello world"`)
+	assert.Equal(t, "checkout", msg.Origin.Service())
+	assert.NoError(t, <-errc)
+}
+
+// post sends the request in the background, the messages must be read for the response to be received.
+func post(req *http.Request) chan *http.Response {
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			close(responses)
+			return
+		}
+		responses <- resp
+	}()
+	return responses
+}
+
+func TestReceiverExportsOverHTTP(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	port := testutil.FreePort(t)
+	receiver := NewReceiver(pp, config.NewLogSource("", &config.LogsConfig{Type: config.OTLPType, HTTPPort: port}))
+	receiver.Start()
+	defer receiver.Stop()
+	url := fmt.Sprintf("http://127.0.0.1:%d/v1/logs", port)
+
+	// protobuf
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(exportRequest()))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	responses := post(req)
+	assert.Contains(t, string((<-msgChan).Content), `"message":"hello world"`)
+	resp, ok := <-responses
+	if !assert.True(t, ok) {
+		return
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// JSON compressed with gzip
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write([]byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"stringValue":"from json"}}]}]}]}`))
+	gz.Close()
+	req, _ = http.NewRequest(http.MethodPost, url, &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	responses = post(req)
+	assert.Equal(t, `{"message":"from json"}`, string((<-msgChan).Content))
+	resp, ok = <-responses
+	if !assert.True(t, ok) {
+		return
+	}
+	content, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "{}", string(content))
+
+	// invalid requests
+	resp, err := http.Post(url, "application/json", strings.NewReader("not json"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, err = http.Get(url)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,213 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// scanPeriod represents the period of time between two scans of the storage directories.
+const scanPeriod = 10 * time.Second
+
+// podmanIntegration represents the name of the integration.
+const podmanIntegration = "podman"
+
+// The layout of a podman storage directory, the containers are stored in a directory named after the storage driver,
+// e.g. overlay-containers, where the k8s-file log driver writes the logs of a container in <id>/userdata/ctr.log.
+const (
+	containersDirectoryPattern = "*-containers"
+	containersFileName         = "containers.json"
+	logFilePattern             = "*/userdata/ctr.log"
+)
+
+// container represents a podman container writing its logs in a file.
+type container struct {
+	id      string
+	name    string
+	logPath string
+}
+
+// storedContainer represents a container in the containers.json file of a storage directory.
+type storedContainer struct {
+	ID    string   `json:"id"`
+	Names []string `json:"names"`
+}
+
+// Launcher looks for new and removed podman containers in the storage directories to create or delete one logs-source per container.
+type Launcher struct {
+	sources            *config.LogSources
+	storagePaths       []string
+	sourcesByContainer map[string]*config.LogSource
+	stop               chan struct{}
+}
+
+// NewLauncher returns a new launcher collecting the logs of the containers of the storage directories matching storagePaths,
+// those can contain wildcards to match the storage directories of the rootless containers of every user.
+func NewLauncher(sources *config.LogSources, storagePaths []string, collectAll bool) (*Launcher, error) {
+	if !collectAll {
+		return nil, fmt.Errorf("%s disabled", config.ContainerCollectAll)
+	}
+	if len(findStorageDirectories(storagePaths)) == 0 {
+		return nil, fmt.Errorf("no podman storage directory found in %v", storagePaths)
+	}
+	return &Launcher{
+		sources:            sources,
+		storagePaths:       storagePaths,
+		sourcesByContainer: make(map[string]*config.LogSource),
+		stop:               make(chan struct{}),
+	}, nil
+}
+
+// Start starts the launcher
+func (l *Launcher) Start() {
+	log.Info("Starting podman launcher")
+	go l.run()
+}
+
+// Stop stops the launcher
+func (l *Launcher) Stop() {
+	log.Info("Stopping podman launcher")
+	l.stop <- struct{}{}
+}
+
+// run looks periodically for new and removed containers until stop
+func (l *Launcher) run() {
+	l.scan()
+	ticker := time.NewTicker(scanPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.scan()
+		case <-l.stop:
+			log.Info("Podman launcher stopped")
+			return
+		}
+	}
+}
+
+// scan creates a source for each new container and removes the sources of the containers that disappeared.
+func (l *Launcher) scan() {
+	containers := make(map[string]bool)
+	for _, container := range listContainers(l.storagePaths) {
+		containers[container.id] = true
+		if _, exists := l.sourcesByContainer[container.id]; !exists {
+			l.addSource(container)
+		}
+	}
+	for id, source := range l.sourcesByContainer {
+		if !containers[id] {
+			delete(l.sourcesByContainer, id)
+			l.sources.RemoveSource(source)
+		}
+	}
+}
+
+// addSource creates a new log-source tailing the log file of the container.
+func (l *Launcher) addSource(container container) {
+	source := newSource(container)
+	source.SetSourceType(config.PodmanType)
+	l.sourcesByContainer[container.id] = source
+	l.sources.AddSource(source)
+}
+
+// newSource returns a new source for the container,
+// the container tags are set from the storage as the tagger does not collect podman containers.
+func newSource(container container) *config.LogSource {
+	tags := []string{"container_id:" + container.id}
+	sourceName := podmanIntegration + "/" + container.id
+	if container.name != "" {
+		tags = append(tags, "container_name:"+container.name)
+		sourceName = podmanIntegration + "/" + container.name
+	}
+	return config.NewLogSource(sourceName, &config.LogsConfig{
+		Type:       config.FileType,
+		Path:       container.logPath,
+		Identifier: container.id,
+		Source:     podmanIntegration,
+		Service:    podmanIntegration,
+		Tags:       tags,
+	})
+}
+
+// findStorageDirectories returns the storage directories matching storagePaths.
+func findStorageDirectories(storagePaths []string) []string {
+	var directories []string
+	for _, pattern := range storagePaths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Warnf("Invalid podman storage path %s: %v", pattern, err)
+			continue
+		}
+		directories = append(directories, matches...)
+	}
+	return directories
+}
+
+// listContainers returns the containers having a log file in the storage directories matching storagePaths.
+// The names of the containers are only recorded in their graph root, e.g. /var/lib/containers/storage,
+// which can differ from the directory of their log file, e.g. the run root of rootless podman /run/user/<uid>/containers.
+func listContainers(storagePaths []string) []container {
+	var containersDirectories []string
+	for _, directory := range findStorageDirectories(storagePaths) {
+		matches, _ := filepath.Glob(filepath.Join(directory, containersDirectoryPattern))
+		containersDirectories = append(containersDirectories, matches...)
+	}
+
+	names := make(map[string]string)
+	for _, directory := range containersDirectories {
+		for id, name := range readContainerNames(filepath.Join(directory, containersFileName)) {
+			names[id] = name
+		}
+	}
+
+	var containers []container
+	seen := make(map[string]bool)
+	for _, directory := range containersDirectories {
+		logPaths, _ := filepath.Glob(filepath.Join(directory, logFilePattern))
+		for _, logPath := range logPaths {
+			id := filepath.Base(filepath.Dir(filepath.Dir(logPath)))
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			containers = append(containers, container{
+				id:      id,
+				name:    names[id],
+				logPath: logPath,
+			})
+		}
+	}
+	return containers
+}
+
+// readContainerNames returns the names of the containers listed in the file at path by identifier.
+func readContainerNames(path string) map[string]string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var storedContainers []storedContainer
+	if err := json.Unmarshal(content, &storedContainers); err != nil {
+		log.Debugf("Could not parse podman containers file %s: %v", path, err)
+		return nil
+	}
+	names := make(map[string]string)
+	for _, container := range storedContainers {
+		if len(container.Names) > 0 {
+			names[container.ID] = container.Names[0]
+		}
+	}
+	return names
+}
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package podman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func createLogFile(t *testing.T, storage, id string) string {
+	directory := filepath.Join(storage, "overlay-containers", id, "userdata")
+	assert.Nil(t, os.MkdirAll(directory, 0755))
+	path := filepath.Join(directory, "ctr.log")
+	assert.Nil(t, ioutil.WriteFile(path, nil, 0644))
+	return path
+}
+
+func TestListContainers(t *testing.T) {
+	root, err := ioutil.TempDir("", "podman-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	// the graph root of a rootful podman
+	graphRoot := filepath.Join(root, "var", "lib", "containers", "storage")
+	rootfulLogPath := createLogFile(t, graphRoot, "aaa")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(graphRoot, "overlay-containers", "containers.json"), []byte(`[{"id":"aaa","names":["web"]}]`), 0644))
+
+	// the graph root and the run root of a rootless podman with the log file in the run root
+	userGraphRoot := filepath.Join(root, "home", "user", ".local", "share", "containers", "storage")
+	assert.Nil(t, os.MkdirAll(filepath.Join(userGraphRoot, "overlay-containers"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(userGraphRoot, "overlay-containers", "containers.json"), []byte(`[{"id":"bbb","names":["db"]}]`), 0644))
+	rootlessLogPath := createLogFile(t, filepath.Join(root, "run", "user", "1000", "containers"), "bbb")
+
+	// a container without name
+	unnamedLogPath := createLogFile(t, graphRoot, "ccc")
+
+	storagePaths := []string{
+		filepath.Join(root, "var", "lib", "containers", "storage"),
+		filepath.Join(root, "home", "*", ".local", "share", "containers", "storage"),
+		filepath.Join(root, "run", "user", "*", "containers"),
+	}
+	containers := listContainers(storagePaths)
+	assert.ElementsMatch(t, []container{
+		{id: "aaa", name: "web", logPath: rootfulLogPath},
+		{id: "bbb", name: "db", logPath: rootlessLogPath},
+		{id: "ccc", name: "", logPath: unnamedLogPath},
+	}, containers)
+
+	assert.Nil(t, listContainers([]string{filepath.Join(root, "missing")}))
+}
+
+func TestNewSource(t *testing.T) {
+	source := newSource(container{id: "aaa", name: "web", logPath: "/tmp/ctr.log"})
+	assert.Equal(t, "podman/web", source.Name)
+	assert.Equal(t, config.FileType, source.Config.Type)
+	assert.Equal(t, "/tmp/ctr.log", source.Config.Path)
+	assert.Equal(t, "aaa", source.Config.Identifier)
+	assert.Equal(t, "podman", source.Config.Source)
+	assert.Equal(t, "podman", source.Config.Service)
+	assert.Equal(t, []string{"container_id:aaa", "container_name:web"}, source.Config.Tags)
+
+	source = newSource(container{id: "aaa", logPath: "/tmp/ctr.log"})
+	assert.Equal(t, "podman/aaa", source.Name)
+	assert.Equal(t, []string{"container_id:aaa"}, source.Config.Tags)
+}
+
+func TestLauncherScan(t *testing.T) {
+	root, err := ioutil.TempDir("", "podman-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	sources := config.NewLogSources()
+	launcher, err := NewLauncher(sources, []string{root}, true)
+	assert.Nil(t, err)
+
+	createLogFile(t, root, "aaa")
+	launcher.scan()
+	assert.Len(t, sources.GetSources(), 1)
+	assert.Equal(t, config.PodmanType, sources.GetSources()[0].GetSourceType())
+
+	// the source is created once
+	launcher.scan()
+	assert.Len(t, sources.GetSources(), 1)
+
+	assert.Nil(t, os.RemoveAll(filepath.Join(root, "overlay-containers", "aaa")))
+	launcher.scan()
+	assert.Len(t, sources.GetSources(), 0)
+}
+
+func TestNewLauncher(t *testing.T) {
+	root, err := ioutil.TempDir("", "podman-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	_, err = NewLauncher(config.NewLogSources(), []string{root}, false)
+	assert.NotNil(t, err)
+
+	_, err = NewLauncher(config.NewLogSources(), []string{filepath.Join(root, "missing")}, true)
+	assert.NotNil(t, err)
+}
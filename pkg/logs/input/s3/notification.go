@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package s3
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// object represents an object of a bucket.
+type object struct {
+	bucket string
+	key    string
+}
+
+// snsNotification is the envelope of the notifications delivered to the queue through an SNS topic.
+type snsNotification struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// s3Notification is an S3 event notification, as described in
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/notification-content-structure.html
+type s3Notification struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// parseNotification returns the objects created according to the S3 event notification body,
+// the notifications published to an SNS topic subscribed by the queue are unwrapped first.
+// The test events sent by S3 when the notifications are configured do not hold any object.
+func parseNotification(body string) ([]object, error) {
+	var sns snsNotification
+	if err := json.Unmarshal([]byte(body), &sns); err == nil && sns.Type == "Notification" && sns.Message != "" {
+		body = sns.Message
+	}
+	var notification s3Notification
+	if err := json.Unmarshal([]byte(body), &notification); err != nil {
+		return nil, err
+	}
+	var objects []object
+	for _, record := range notification.Records {
+		if !strings.HasPrefix(record.EventName, "ObjectCreated:") {
+			continue
+		}
+		// the keys are URL encoded, with the spaces replaced by '+'
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object{bucket: record.S3.Bucket.Name, key: key})
+	}
+	return objects, nil
+}
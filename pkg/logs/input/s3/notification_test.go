@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package s3
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testNotification = `{"Records":[
+	{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"logs"},"object":{"key":"AWSLogs/elb/my+file%3D1.log.gz"}}},
+	{"eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"logs"},"object":{"key":"removed.log"}}}
+]}`
+
+func TestParseNotification(t *testing.T) {
+	objects, err := parseNotification(testNotification)
+	assert.NoError(t, err)
+	assert.Equal(t, []object{{bucket: "logs", key: "AWSLogs/elb/my file=1.log.gz"}}, objects)
+}
+
+func TestParseNotificationUnwrapsSNSNotifications(t *testing.T) {
+	body, err := json.Marshal(map[string]string{"Type": "Notification", "Message": testNotification})
+	assert.NoError(t, err)
+	objects, err := parseNotification(string(body))
+	assert.NoError(t, err)
+	assert.Equal(t, []object{{bucket: "logs", key: "AWSLogs/elb/my file=1.log.gz"}}, objects)
+}
+
+func TestParseNotificationWithTestEvent(t *testing.T) {
+	objects, err := parseNotification(`{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"logs"}`)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 0)
+}
+
+func TestParseNotificationWithInvalidBody(t *testing.T) {
+	_, err := parseNotification("hello world")
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const (
+	// retryPeriod is the time waited before polling the queue again after an error.
+	retryPeriod = 10 * time.Second
+	// waitTimeSeconds is the duration of the long polling of the queue.
+	waitTimeSeconds = 20
+	// maxNumberOfMessages is the maximum number of notifications received at once.
+	maxNumberOfMessages = 10
+)
+
+// sqsAPI is the part of the SQS API used to receive the notifications.
+type sqsAPI interface {
+	ReceiveMessageWithContext(aws.Context, *sqs.ReceiveMessageInput, ...request.Option) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(*sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error)
+}
+
+// s3API is the part of the S3 API used to download the objects.
+type s3API interface {
+	GetObjectWithContext(aws.Context, *s3.GetObjectInput, ...request.Option) (*s3.GetObjectOutput, error)
+}
+
+// A Poller polls the SQS queue of a source for the notifications of the objects created in S3 buckets,
+// downloads the objects and forwards their events to the pipeline. A notification is deleted from the queue
+// once the events of its objects have been sent to the pipeline, it's received again after the visibility
+// timeout of the queue otherwise.
+type Poller struct {
+	source     *config.LogSource
+	outputChan chan *message.Message
+	sqs        sqsAPI
+	s3         s3API
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewPoller returns an initialized Poller
+func NewPoller(source *config.LogSource, outputChan chan *message.Message) *Poller {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Poller{
+		source:     source,
+		outputChan: outputChan,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts polling the queue of the source, the credentials are looked up in the environment,
+// the shared credentials file and the instance metadata.
+func (p *Poller) Start() {
+	log.Infof("Starting polling of queue %s", p.source.Config.QueueURL)
+	sess, err := newSession(p.source.Config)
+	if err != nil {
+		log.Errorf("Can't create AWS session: %v", err)
+		p.source.Status.Error(err)
+		close(p.done)
+		return
+	}
+	p.sqs = sqs.New(sess)
+	p.s3 = s3.New(sess)
+	go p.run()
+}
+
+// Stop stops polling the queue, the events of the objects being downloaded are not sent so that
+// their notifications are received again.
+func (p *Poller) Stop() {
+	log.Infof("Stopping polling of queue %s", p.source.Config.QueueURL)
+	p.cancel()
+	<-p.done
+}
+
+// run polls the queue until the poller is stopped.
+func (p *Poller) run() {
+	defer close(p.done)
+	for {
+		output, err := p.sqs.ReceiveMessageWithContext(p.ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(p.source.Config.QueueURL),
+			MaxNumberOfMessages: aws.Int64(maxNumberOfMessages),
+			WaitTimeSeconds:     aws.Int64(waitTimeSeconds),
+		})
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warnf("Couldn't receive messages from queue %s: %v", p.source.Config.QueueURL, err)
+			p.source.Status.Error(err)
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(retryPeriod):
+			}
+			continue
+		}
+		p.source.Status.Success()
+		for _, msg := range output.Messages {
+			if err := p.handleMessage(msg); err != nil {
+				if p.ctx.Err() != nil {
+					return
+				}
+				log.Warnf("Couldn't process notification %s of queue %s: %v", aws.StringValue(msg.MessageId), p.source.Config.QueueURL, err)
+				continue
+			}
+			p.deleteMessage(msg)
+		}
+	}
+}
+
+// handleMessage forwards the events of the objects of the notification to the pipeline,
+// the notifications which can't be parsed are deleted to not be received again and again.
+func (p *Poller) handleMessage(msg *sqs.Message) error {
+	objects, err := parseNotification(aws.StringValue(msg.Body))
+	if err != nil {
+		log.Warnf("Invalid notification %s in queue %s: %v", aws.StringValue(msg.MessageId), p.source.Config.QueueURL, err)
+		return nil
+	}
+	for _, object := range objects {
+		if err := p.handleObject(object); err != nil {
+			return fmt.Errorf("could not read object s3://%s/%s: %v", object.bucket, object.key, err)
+		}
+	}
+	return nil
+}
+
+// handleObject downloads the object and forwards its events to the pipeline.
+func (p *Poller) handleObject(object object) error {
+	output, err := p.s3.GetObjectWithContext(p.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(object.bucket),
+		Key:    aws.String(object.key),
+	})
+	if err != nil {
+		return err
+	}
+	defer output.Body.Close()
+	tags := []string{"s3_bucket:" + object.bucket, "s3_key:" + object.key}
+	return readEvents(output.Body, func(event []byte) error {
+		origin := message.NewOrigin(p.source)
+		origin.SetTags(tags)
+		select {
+		case p.outputChan <- message.NewMessage(event, origin, message.StatusInfo):
+			return nil
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	})
+}
+
+// deleteMessage deletes the notification from the queue.
+func (p *Poller) deleteMessage(msg *sqs.Message) {
+	_, err := p.sqs.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(p.source.Config.QueueURL),
+		ReceiptHandle: msg.ReceiptHandle,
+	})
+	if err != nil {
+		log.Warnf("Couldn't delete notification %s from queue %s: %v", aws.StringValue(msg.MessageId), p.source.Config.QueueURL, err)
+	}
+}
+
+// newSession returns the AWS session of the source, in the region of the source or of its queue.
+func newSession(cfg *config.LogsConfig) (*session.Session, error) {
+	region := cfg.Region
+	if region == "" {
+		region = queueRegion(cfg.QueueURL)
+	}
+	if region == "" {
+		return nil, fmt.Errorf("could not find the region of queue %s, set the region of the source", cfg.QueueURL)
+	}
+	return session.NewSession(&aws.Config{Region: aws.String(region)})
+}
+
+// queueRegion returns the region of a queue from its URL, e.g. https://sqs.us-east-1.amazonaws.com/123456789012/logs,
+// or an empty string when it can't be found.
+func queueRegion(queueURL string) string {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(u.Hostname(), ".")
+	switch {
+	case len(parts) > 2 && parts[0] == "sqs":
+		return parts[1]
+	case len(parts) > 2 && parts[1] == "queue":
+		// legacy endpoint, e.g. https://us-east-1.queue.amazonaws.com/123456789012/logs
+		return parts[0]
+	}
+	return ""
+}
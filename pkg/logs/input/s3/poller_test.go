@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package s3
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// fakeSQS returns its messages once and then blocks until the context is done.
+type fakeSQS struct {
+	sync.Mutex
+	messages []*sqs.Message
+	deleted  []string
+}
+
+func (f *fakeSQS) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	f.Lock()
+	messages := f.messages
+	f.messages = nil
+	f.Unlock()
+	if len(messages) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (f *fakeSQS) DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.deleted = append(f.deleted, aws.StringValue(input.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (f *fakeSQS) deletedMessages() []string {
+	f.Lock()
+	defer f.Unlock()
+	return f.deleted
+}
+
+// fakeS3 serves the objects of a bucket by key.
+type fakeS3 map[string]string
+
+func (f fakeS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	content, exists := f[aws.StringValue(input.Key)]
+	if !exists {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(strings.NewReader(content))}, nil
+}
+
+func newNotification(id string, keys ...string) *sqs.Message {
+	var records []string
+	for _, key := range keys {
+		records = append(records, `{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"logs"},"object":{"key":"`+key+`"}}}`)
+	}
+	return &sqs.Message{
+		MessageId:     aws.String(id),
+		ReceiptHandle: aws.String(id),
+		Body:          aws.String(`{"Records":[` + strings.Join(records, ",") + `]}`),
+	}
+}
+
+func TestPollerForwardsEventsAndDeletesNotifications(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.S3Type, QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/logs"})
+	outputChan := make(chan *message.Message, 10)
+	queue := &fakeSQS{messages: []*sqs.Message{
+		newNotification("first", "a.log", "b.log"),
+		newNotification("missing", "missing.log"),
+		{MessageId: aws.String("invalid"), ReceiptHandle: aws.String("invalid"), Body: aws.String("hello")},
+	}}
+	poller := NewPoller(source, outputChan)
+	poller.sqs = queue
+	poller.s3 = fakeS3{"a.log": "hello\nworld\n", "b.log": "bye"}
+	go poller.run()
+
+	var contents []string
+	for i := 0; i < 3; i++ {
+		msg := <-outputChan
+		contents = append(contents, string(msg.Content))
+		assert.Contains(t, msg.Origin.Tags(), "s3_bucket:logs")
+	}
+	poller.Stop()
+
+	assert.Equal(t, []string{"hello", "world", "bye"}, contents)
+	// the notifications whose objects can't be read are received again, the invalid ones are dropped
+	assert.Equal(t, []string{"first", "invalid"}, queue.deletedMessages())
+}
+
+func TestPollerStopsWhileBlocked(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.S3Type, QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/logs"})
+	queue := &fakeSQS{messages: []*sqs.Message{newNotification("first", "a.log")}}
+	poller := NewPoller(source, make(chan *message.Message))
+	poller.sqs = queue
+	poller.s3 = fakeS3{"a.log": "hello"}
+	go poller.run()
+	poller.Stop()
+	assert.Len(t, queue.deletedMessages(), 0)
+}
+
+func TestQueueRegion(t *testing.T) {
+	assert.Equal(t, "eu-west-1", queueRegion("https://sqs.eu-west-1.amazonaws.com/123456789012/logs"))
+	assert.Equal(t, "us-east-1", queueRegion("https://us-east-1.queue.amazonaws.com/123456789012/logs"))
+	assert.Equal(t, "", queueRegion("http://localhost:9324/queue/logs"))
+}
+
+func TestNewSession(t *testing.T) {
+	sess, err := newSession(&config.LogsConfig{QueueURL: "https://sqs.eu-west-1.amazonaws.com/123456789012/logs"})
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", aws.StringValue(sess.Config.Region))
+
+	sess, err = newSession(&config.LogsConfig{QueueURL: "http://localhost:9324/queue/logs", Region: "us-west-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "us-west-2", aws.StringValue(sess.Config.Region))
+
+	_, err = newSession(&config.LogsConfig{QueueURL: "http://localhost:9324/queue/logs"})
+	assert.Error(t, err)
+}
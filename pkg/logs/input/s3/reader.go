@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+)
+
+// gzipMagic starts the content of the gzip compressed objects.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// readEvents reads the events of the content of an object and calls handle for each one of them.
+// The gzip compressed objects are decompressed, the objects holding a JSON document with a "Records" array,
+// like the CloudTrail logs, are split into one event per record, the other objects into one event per line.
+func readEvents(content io.Reader, handle func(event []byte) error) error {
+	reader := bufio.NewReader(content)
+	if magic, _ := reader.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = bufio.NewReader(gzipReader)
+	}
+	if first, _ := reader.Peek(1); len(first) == 1 && first[0] == '{' {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if records, ok := parseRecords(data); ok {
+			return handleRecords(records, handle)
+		}
+		reader = bufio.NewReader(bytes.NewReader(data))
+	}
+	return readLines(reader, handle)
+}
+
+// parseRecords returns the records of data when it's a JSON document holding a "Records" array.
+func parseRecords(data []byte) ([]json.RawMessage, bool) {
+	var document struct {
+		Records []json.RawMessage `json:"Records"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil || document.Records == nil {
+		return nil, false
+	}
+	return document.Records, true
+}
+
+// handleRecords calls handle for each record compacted on a single line.
+func handleRecords(records []json.RawMessage, handle func(event []byte) error) error {
+	for _, record := range records {
+		var event bytes.Buffer
+		if err := json.Compact(&event, record); err != nil {
+			return err
+		}
+		if err := handle(event.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLines calls handle for each non-empty line of reader.
+func readLines(reader *bufio.Reader, handle func(event []byte) error) error {
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+			if err := handle(line); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readAll returns the events of content.
+func readAll(t *testing.T, content []byte) []string {
+	var events []string
+	err := readEvents(bytes.NewReader(content), func(event []byte) error {
+		events = append(events, string(event))
+		return nil
+	})
+	assert.NoError(t, err)
+	return events
+}
+
+// compress returns content compressed with gzip.
+func compress(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestReadEventsSplitsLines(t *testing.T) {
+	assert.Equal(t, []string{"first line", "second line", "third line"}, readAll(t, []byte("first line\r\nsecond line\n\nthird line")))
+}
+
+func TestReadEventsDecompressesGzip(t *testing.T) {
+	assert.Equal(t, []string{"2 123456789012 eni-1 ACCEPT OK", "2 123456789012 eni-2 REJECT OK"}, readAll(t, compress(t, "2 123456789012 eni-1 ACCEPT OK\n2 123456789012 eni-2 REJECT OK\n")))
+}
+
+func TestReadEventsSplitsRecords(t *testing.T) {
+	content := compress(t, "{\"Records\": [\n  {\"eventName\": \"ConsoleLogin\"},\n  {\"eventName\": \"GetObject\", \"requestParameters\": {\"key\": \"a\"}}\n]}")
+	assert.Equal(t, []string{`{"eventName":"ConsoleLogin"}`, `{"eventName":"GetObject","requestParameters":{"key":"a"}}`}, readAll(t, content))
+}
+
+func TestReadEventsSplitsJSONLines(t *testing.T) {
+	assert.Equal(t, []string{`{"Records":1}`, `{"message":"hello"}`}, readAll(t, []byte("{\"Records\":1}\n{\"message\":\"hello\"}\n")))
+}
+
+func TestReadEventsStopsOnError(t *testing.T) {
+	count := 0
+	err := readEvents(strings.NewReader("a\nb\nc\n"), func(event []byte) error {
+		count++
+		return errors.New("stopped")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestReadEventsWithInvalidGzip(t *testing.T) {
+	err := readEvents(bytes.NewReader([]byte{0x1f, 0x8b, 0x00}), func(event []byte) error { return nil })
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ package windowsevent
 import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
@@ -17,15 +18,17 @@ import (
 type Launcher struct {
 	sources          chan *config.LogSource
 	pipelineProvider pipeline.Provider
+	registry         auditor.Registry
 	tailers          map[string]*Tailer
 	stop             chan struct{}
 }
 
 // NewLauncher returns a new Launcher.
-func NewLauncher(sources *config.LogSources, pipelineProvider pipeline.Provider) *Launcher {
+func NewLauncher(sources *config.LogSources, pipelineProvider pipeline.Provider, registry auditor.Registry) *Launcher {
 	return &Launcher{
 		sources:          sources.GetAddedForType(config.WindowsEventType),
 		pipelineProvider: pipelineProvider,
+		registry:         registry,
 		tailers:          make(map[string]*Tailer),
 		stop:             make(chan struct{}),
 	}
@@ -84,11 +87,12 @@ func (l *Launcher) sanitizedConfig(sourceConfig *config.LogsConfig) *Config {
 	return config
 }
 
-// setupTailer configures and starts a new tailer
+// setupTailer configures and starts a new tailer,
+// the tailer resumes after the bookmark of the last event sent if any.
 func (l *Launcher) setupTailer(source *config.LogSource) (*Tailer, error) {
 	sanitizedConfig := l.sanitizedConfig(source.Config)
 	config := &Config{sanitizedConfig.ChannelPath, sanitizedConfig.Query}
-	tailer := NewTailer(source, config, l.pipelineProvider.NextPipelineChan())
-	tailer.Start()
+	tailer := NewTailer(source, config, l.pipelineProvider.PipelineChanForSource(source))
+	tailer.Start(l.registry.GetOffset(tailer.Identifier()))
 	return tailer, nil
 }
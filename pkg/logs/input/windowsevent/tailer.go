@@ -23,6 +23,7 @@ const (
 	binaryPath   = "Event.EventData.Binary"
 	dataPath     = "Event.EventData.Data"
 	taskPath     = "Event.System.Task"
+	levelPath    = "Event.System.Level"
 	fabricPrefix = "Microsoft-ServiceFabric/"
 )
 
@@ -46,6 +47,10 @@ type Tailer struct {
 	done       chan struct{}
 
 	context *eventContext
+	// subscription and bookmark are the handles of the event subscription and of the bookmark
+	// of the last event sent, they are only used on windows.
+	subscription uintptr
+	bookmark     uintptr
 }
 
 // NewTailer returns a new tailer.
@@ -119,7 +124,36 @@ func (t *Tailer) toMessage(event string) (*message.Message, error) {
 	}
 	jsonEvent = replaceTextKeyToValue(jsonEvent)
 	log.Debug("Sending JSON:", string(jsonEvent))
-	return message.NewMessage(jsonEvent, message.NewOrigin(t.source), message.StatusInfo), nil
+	origin := message.NewOrigin(t.source)
+	origin.Identifier = t.Identifier()
+	return message.NewMessage(jsonEvent, origin, extractStatus(mv)), nil
+}
+
+// levelStatusMapping represents the mapping between the levels of the windows events and the statuses,
+// the level 0 (LogAlways) and the unknown levels are mapped to "info".
+var levelStatusMapping = map[string]string{
+	"1": message.StatusCritical,
+	"2": message.StatusError,
+	"3": message.StatusWarning,
+	"4": message.StatusInfo,
+	"5": message.StatusDebug,
+}
+
+// extractStatus returns the status matching the level in {"Event": {"System": {"Level": <LEVEL> }}}
+func extractStatus(mv mxj.Map) string {
+	values, err := mv.ValuesForPath(levelPath)
+	if err != nil || len(values) == 0 {
+		return message.StatusInfo
+	}
+	level, ok := values[0].(string)
+	if !ok {
+		return message.StatusInfo
+	}
+	status, exists := levelStatusMapping[level]
+	if !exists {
+		return message.StatusInfo
+	}
+	return status
 }
 
 // extractTaskName looks for the TASK_ID in {"Event": {"System": {"Task": <TASK_ID> }}}
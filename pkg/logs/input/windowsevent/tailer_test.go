@@ -6,9 +6,12 @@
 package windowsevent
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
 func TestToMessage(t *testing.T) {
@@ -76,3 +79,21 @@ func TestToMessageWithFabric(t *testing.T) {
 	actual, _ = tailer.toMessage(evt5)
 	assert.Equal(t, expected5, string(actual.Content))
 }
+
+func TestToMessageStatusAndIdentifier(t *testing.T) {
+	tailer := NewTailer(nil, &Config{ChannelPath: "System", Query: "*"}, nil)
+	event := `<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><Provider Name='Service Control Manager'/><EventID>7036</EventID><Level>%s</Level></System></Event>`
+
+	levels := []string{"0", "1", "2", "3", "4", "5", "foo"}
+	statuses := []string{message.StatusInfo, message.StatusCritical, message.StatusError, message.StatusWarning, message.StatusInfo, message.StatusDebug, message.StatusInfo}
+	for i, level := range levels {
+		msg, err := tailer.toMessage(fmt.Sprintf(event, level))
+		assert.Nil(t, err)
+		assert.Equal(t, statuses[i], msg.GetStatus())
+		assert.Equal(t, "eventlog:System;*", msg.Origin.Identifier)
+	}
+
+	msg, err := tailer.toMessage(`<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><EventID>7036</EventID></System></Event>`)
+	assert.Nil(t, err)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+}
@@ -21,10 +21,10 @@ import (
 	"golang.org/x/sys/windows"
 )
 
-// Start starts tailing the event log.
-func (t *Tailer) Start() {
+// Start starts tailing the event log after bookmark, or from the future events if empty.
+func (t *Tailer) Start(bookmark string) {
 	log.Infof("Starting windows event log tailing for channel %s query %s", t.config.ChannelPath, t.config.Query)
-	go t.tail()
+	go t.tail(bookmark)
 }
 
 // Stop stops the tailer
@@ -35,25 +35,67 @@ func (t *Tailer) Stop() {
 }
 
 // tail subscribes to the channel for the windows events
-func (t *Tailer) tail() {
+func (t *Tailer) tail(bookmark string) {
 	t.context = &eventContext{
 		id: indexForTailer(t),
 	}
-	C.startEventSubscribe(
+	flags := EvtSubscribeToFutureEvents
+	if bookmark != "" {
+		handle, err := evtCreateBookmark(bookmark)
+		if err != nil {
+			log.Warnf("Could not restore the bookmark of %s, only the future events will be collected: %v", t.Identifier(), err)
+		} else {
+			t.bookmark = handle
+			flags = EvtSubscribeStartAfterBookmark
+		}
+	}
+	if t.bookmark == 0 {
+		handle, err := evtCreateBookmark("")
+		if err != nil {
+			log.Warnf("Could not create a bookmark for %s, the events could be sent again on restart: %v", t.Identifier(), err)
+		}
+		t.bookmark = handle
+	}
+	t.subscription = uintptr(C.startEventSubscribe(
 		C.CString(t.config.ChannelPath),
 		C.CString(t.config.Query),
-		C.ULONGLONG(0),
-		C.int(EvtSubscribeToFutureEvents),
+		C.ULONGLONG(t.bookmark),
+		C.int(flags),
 		C.PVOID(uintptr(unsafe.Pointer(t.context))),
-	)
+	))
 	t.source.Status.Success()
 
 	// wait for stop signal
 	<-t.stop
+	// closing the subscription waits for the callback in progress to return
+	if t.subscription != 0 {
+		procEvtClose.Call(t.subscription)
+	}
+	if t.bookmark != 0 {
+		procEvtClose.Call(t.bookmark)
+	}
 	t.done <- struct{}{}
 	return
 }
 
+// renderBookmark updates the bookmark of the tailer to the event and returns its XML representation,
+// it's used as the offset of the message to resume after the event on restart.
+func (t *Tailer) renderBookmark(event C.ULONGLONG) string {
+	if t.bookmark == 0 {
+		return ""
+	}
+	if err := evtUpdateBookmark(t.bookmark, event); err != nil {
+		log.Debugf("Could not update the bookmark of %s: %v", t.Identifier(), err)
+		return ""
+	}
+	bookmark, err := evtRender(C.ULONGLONG(t.bookmark), EvtRenderBookmark)
+	if err != nil {
+		log.Debugf("Could not render the bookmark of %s: %v", t.Identifier(), err)
+		return ""
+	}
+	return bookmark
+}
+
 /*
 	Windows related methods
 */
@@ -91,6 +133,7 @@ func goNotificationCallback(handle C.ULONGLONG, ctx C.PVOID) {
 		log.Warnf("Couldn't convert xml to json: %s for event %s", err, xml)
 		return
 	}
+	msg.Origin.Offset = t.renderBookmark(handle)
 
 	t.outputChan <- msg
 }
@@ -104,16 +147,23 @@ var (
 	procEvtOpenChannelEnum = modWinEvtAPI.NewProc("EvtOpenChannelEnum")
 	procEvtNextChannelPath = modWinEvtAPI.NewProc("EvtNextChannelPath")
 	procEvtNext            = modWinEvtAPI.NewProc("EvtNext")
+	procEvtCreateBookmark  = modWinEvtAPI.NewProc("EvtCreateBookmark")
+	procEvtUpdateBookmark  = modWinEvtAPI.NewProc("EvtUpdateBookmark")
 )
 
 // EvtRender takes an event handle and reders it to XML
 func EvtRender(h C.ULONGLONG) (xml string, err error) {
+	return evtRender(h, EvtRenderEventXml)
+}
+
+// evtRender takes an event or a bookmark handle and renders it to XML according to flags
+func evtRender(h C.ULONGLONG, flags uint32) (xml string, err error) {
 	var bufSize uint32
 	var bufUsed uint32
 
 	_, _, err = procEvtRender.Call(uintptr(0), // this handle is always null for XML renders
-		uintptr(h),                 // handle of event we're rendering
-		uintptr(EvtRenderEventXml), // for now, always render in xml
+		uintptr(h),     // handle of event or bookmark we're rendering
+		uintptr(flags), // always rendered in xml
 		uintptr(bufSize),
 		uintptr(0),                        // no buffer for now, just getting necessary size
 		uintptr(unsafe.Pointer(&bufUsed)), // filled in with necessary buffer size
@@ -125,8 +175,8 @@ func EvtRender(h C.ULONGLONG) (xml string, err error) {
 	bufSize = bufUsed
 	buf := make([]uint8, bufSize)
 	ret, _, err := procEvtRender.Call(uintptr(0), // this handle is always null for XML renders
-		uintptr(h),                 // handle of event we're rendering
-		uintptr(EvtRenderEventXml), // for now, always render in xml
+		uintptr(h),     // handle of event or bookmark we're rendering
+		uintptr(flags), // always rendered in xml
 		uintptr(bufSize),
 		uintptr(unsafe.Pointer(&buf[0])),  // actual buffer used
 		uintptr(unsafe.Pointer(&bufUsed)), // filled in with necessary buffer size
@@ -141,6 +191,33 @@ func EvtRender(h C.ULONGLONG) (xml string, err error) {
 
 }
 
+// evtCreateBookmark returns the handle of a bookmark created from its XML representation,
+// or of a new bookmark if xml is empty
+func evtCreateBookmark(xml string) (uintptr, error) {
+	var xmlPtr uintptr
+	if xml != "" {
+		p, err := windows.UTF16PtrFromString(xml)
+		if err != nil {
+			return 0, err
+		}
+		xmlPtr = uintptr(unsafe.Pointer(p))
+	}
+	ret, _, err := procEvtCreateBookmark.Call(xmlPtr)
+	if ret == 0 {
+		return 0, err
+	}
+	return ret, nil
+}
+
+// evtUpdateBookmark moves the bookmark to the event
+func evtUpdateBookmark(bookmark uintptr, event C.ULONGLONG) error {
+	ret, _, err := procEvtUpdateBookmark.Call(bookmark, uintptr(event))
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
+
 type evtSubscribeNotifyAction int32
 type evtSubscribeFlags int32
 
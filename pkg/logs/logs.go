@@ -10,11 +10,15 @@ import (
 	"fmt"
 	"sync/atomic"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/checks"
 	"github.com/DataDog/datadog-agent/pkg/logs/scheduler"
+	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
 	"github.com/DataDog/datadog-agent/pkg/logs/status"
 )
@@ -22,6 +26,7 @@ import (
 const (
 	// key used to display a warning message on the agent status
 	invalidProcessingRules = "invalid_global_processing_rules"
+	invalidSDSRules        = "invalid_sds_rules"
 	invalidEndpoints       = "invalid_endpoints"
 )
 
@@ -67,13 +72,28 @@ func Start() error {
 		return errors.New(message)
 	}
 
+	// setup the sensitive data scanner
+	var scanner *sds.Scanner
+	sdsRules, err := sds.GlobalRules()
+	if err == nil {
+		scanner, err = sds.NewScanner(sdsRules)
+	}
+	if err != nil {
+		message := fmt.Sprintf("Invalid sensitive data scanner rules: %v", err)
+		status.AddGlobalError(invalidSDSRules, message)
+		return errors.New(message)
+	}
+
 	// setup and start the agent
-	agent = NewAgent(sources, services, processingRules, endpoints)
+	agent = NewAgent(sources, services, processingRules, scanner, endpoints)
 	log.Info("Starting logs-agent...")
 	agent.Start()
 	atomic.StoreInt32(&isRunning, 1)
 	log.Info("logs-agent started")
 
+	// let the checks submit logs through their sender
+	aggregator.SetLogSubmitter(checks.Submit)
+
 	// add the default sources
 	for _, source := range config.DefaultSources() {
 		sources.AddSource(source)
@@ -87,6 +107,7 @@ func Start() error {
 func Stop() {
 	log.Info("Stopping logs-agent")
 	if IsAgentRunning() {
+		aggregator.SetLogSubmitter(nil)
 		if agent != nil {
 			agent.Stop()
 			agent = nil
@@ -115,3 +136,11 @@ func GetStatus() status.Status {
 func GetScheduler() *scheduler.Scheduler {
 	return adScheduler
 }
+
+// GetMessageReceiver returns the receiver of the processed logs if the logs-agent is running.
+func GetMessageReceiver() *diagnostic.BufferedMessageReceiver {
+	if agent == nil {
+		return nil
+	}
+	return agent.diagnostics
+}
@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package memory
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/status"
+)
+
+const (
+	// checkInterval is the time between two checks of the memory used by the agent.
+	checkInterval = 5 * time.Second
+	// recoveryRatio is the ratio of the thresholds the memory used must go below to leave the shedding mode,
+	// so that the pipelines do not switch back and forth around the thresholds.
+	recoveryRatio = 0.8
+	// sheddingWarningType is the key of the warning displayed on the status while shedding.
+	sheddingWarningType = "memory_shedding"
+)
+
+// A Monitor checks the memory used by the agent and switches the logs pipelines to a shedding mode
+// while the Go heap or the resident set size of the process is above its threshold: the processors drop
+// the debug logs and the buffers of the pipelines are shrunk until the memory used goes back down.
+// A nil Monitor never sheds.
+type Monitor struct {
+	heapThreshold uint64
+	rssThreshold  uint64
+	heap          func() uint64
+	rss           func() (uint64, error)
+	shedding      int32
+	stop          chan struct{}
+	done          chan struct{}
+}
+
+// NewMonitor returns a monitor of the memory used by the agent with the thresholds in bytes of the Go heap
+// and of the resident set size, a threshold of 0 or less is not checked, or nil if none are checked.
+func NewMonitor(heapThreshold, rssThreshold int64) *Monitor {
+	if heapThreshold <= 0 && rssThreshold <= 0 {
+		return nil
+	}
+	if heapThreshold < 0 {
+		heapThreshold = 0
+	}
+	if rssThreshold < 0 {
+		rssThreshold = 0
+	}
+	return &Monitor{
+		heapThreshold: uint64(heapThreshold),
+		rssThreshold:  uint64(rssThreshold),
+		heap:          heapAlloc,
+		rss:           residentSetSize,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// Start starts checking the memory used by the agent.
+func (m *Monitor) Start() {
+	go m.run()
+}
+
+// Stop stops checking the memory used by the agent and leaves the shedding mode.
+func (m *Monitor) Stop() {
+	close(m.stop)
+	<-m.done
+	if m.Shedding() {
+		m.setShedding(false, "the logs agent stopped")
+	}
+}
+
+// Shedding returns true while the pipelines are shedding logs.
+func (m *Monitor) Shedding() bool {
+	return m != nil && atomic.LoadInt32(&m.shedding) == 1
+}
+
+// run checks the memory used by the agent until the monitor is stopped.
+func (m *Monitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.Check()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Check checks the memory used right away, it switches the shedding mode on when it's above a threshold
+// and off when it's below recoveryRatio of the thresholds.
+func (m *Monitor) Check() {
+	heap := m.heap()
+	var rss uint64
+	if m.rssThreshold > 0 {
+		var err error
+		if rss, err = m.rss(); err != nil {
+			log.Debugf("Could not get the resident set size of the agent: %v", err)
+			rss = 0
+		}
+	}
+	shedding := m.Shedding()
+	ratio := 1.0
+	if shedding {
+		ratio = recoveryRatio
+	}
+	above := (m.heapThreshold > 0 && float64(heap) > ratio*float64(m.heapThreshold)) ||
+		(m.rssThreshold > 0 && float64(rss) > ratio*float64(m.rssThreshold))
+	if above != shedding {
+		m.setShedding(above, fmt.Sprintf("the Go heap is of %d bytes and the resident set size is of %d bytes", heap, rss))
+	}
+}
+
+// setShedding switches the shedding mode on or off for reason.
+func (m *Monitor) setShedding(shedding bool, reason string) {
+	message.SetBufferPooling(!shedding)
+	if shedding {
+		atomic.StoreInt32(&m.shedding, 1)
+		metrics.MemoryShedding.Set(1)
+		warning := fmt.Sprintf("The logs agent is under memory pressure, %s: the debug logs are dropped and the buffers are shrunk until it goes below %v%% of the thresholds.", reason, recoveryRatio*100)
+		log.Warn(warning)
+		status.AddGlobalWarning(sheddingWarningType, warning)
+		// give the memory of the buffers dropped back to the system right away
+		debug.FreeOSMemory()
+		return
+	}
+	atomic.StoreInt32(&m.shedding, 0)
+	metrics.MemoryShedding.Set(0)
+	log.Infof("The logs agent left the shedding mode, %s", reason)
+	status.RemoveGlobalWarning(sheddingWarningType)
+}
+
+// heapAlloc returns the size in bytes of the objects allocated on the Go heap.
+func heapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+func TestNewMonitor(t *testing.T) {
+	assert.Nil(t, NewMonitor(0, 0))
+	assert.Nil(t, NewMonitor(-1, 0))
+	assert.NotNil(t, NewMonitor(1024, 0))
+	assert.NotNil(t, NewMonitor(0, 1024))
+
+	// a nil monitor never sheds.
+	var monitor *Monitor
+	assert.False(t, monitor.Shedding())
+}
+
+func TestMonitorShedsAboveTheHeapThreshold(t *testing.T) {
+	monitor := NewMonitor(1000, 0)
+	var heap uint64
+	monitor.heap = func() uint64 { return heap }
+	monitor.rss = func() (uint64, error) {
+		assert.Fail(t, "the resident set size should not be checked")
+		return 0, nil
+	}
+	monitor.Start()
+	defer monitor.Stop()
+
+	heap = 1000
+	monitor.Check()
+	assert.False(t, monitor.Shedding())
+
+	heap = 1001
+	monitor.Check()
+	assert.True(t, monitor.Shedding())
+	assert.Equal(t, int64(1), metrics.MemoryShedding.Value())
+
+	// the monitor keeps shedding until the heap goes below the recovery ratio of the threshold.
+	heap = 900
+	monitor.Check()
+	assert.True(t, monitor.Shedding())
+
+	heap = 799
+	monitor.Check()
+	assert.False(t, monitor.Shedding())
+	assert.Equal(t, int64(0), metrics.MemoryShedding.Value())
+}
+
+func TestMonitorShedsAboveTheRSSThreshold(t *testing.T) {
+	monitor := NewMonitor(0, 1000)
+	monitor.heap = func() uint64 { return 1 << 40 }
+	var err error
+	monitor.rss = func() (uint64, error) { return 2000, err }
+	monitor.Start()
+	defer monitor.Stop()
+
+	monitor.Check()
+	assert.True(t, monitor.Shedding())
+
+	err = errors.New("not supported")
+	monitor.Check()
+	assert.False(t, monitor.Shedding())
+}
+
+func TestMonitorStopLeavesTheSheddingMode(t *testing.T) {
+	monitor := NewMonitor(1, 0)
+	monitor.Start()
+	monitor.Check()
+	assert.True(t, monitor.Shedding())
+
+	monitor.Stop()
+	assert.False(t, monitor.Shedding())
+}
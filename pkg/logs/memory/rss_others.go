@@ -0,0 +1,15 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !linux
+
+package memory
+
+import "fmt"
+
+// residentSetSize is only supported on Linux.
+func residentSetSize() (uint64, error) {
+	return 0, fmt.Errorf("the resident set size is only available on Linux")
+}
@@ -5,6 +5,10 @@
 
 package message
 
+import (
+	"time"
+)
+
 // Message represents a log line sent to datadog, with its metadata
 type Message struct {
 	Content    []byte
@@ -12,6 +16,25 @@ type Message struct {
 	status     string
 	Timestamp  string
 	RawDataLen int
+	// Structured is set when the log line has been parsed from a structured format.
+	Structured *Structured
+	// Occurrences is the number of identical consecutive lines the message stands for when they were deduplicated.
+	Occurrences int
+	// HostTags are the tags of the host the message is attached to on top of the tags of its origin.
+	HostTags []string
+	// Hostname is the host the message was emitted on when it was forwarded by another agent,
+	// the host of the agent otherwise.
+	Hostname string
+	// buffer is the pooled buffer the content was decoded in, if any.
+	buffer []byte
+}
+
+// Structured holds the standard fields promoted from a structured log line and its other attributes.
+type Structured struct {
+	Message string
+	// Timestamp is the time the log was emitted at, it's zero if the log line does not hold it.
+	Timestamp  time.Time
+	Attributes map[string]interface{}
 }
 
 // NewMessage returns a new message
@@ -23,6 +46,21 @@ func NewMessage(content []byte, origin *Origin, status string) *Message {
 	}
 }
 
+// SetBuffer attaches the pooled buffer the content of the message is held in to the message,
+// the buffer is returned to the pool once the message is released.
+func (m *Message) SetBuffer(buffer []byte) {
+	m.buffer = buffer
+}
+
+// Release returns the pooled buffer of the message to the pool, if any, once its content is not used anymore,
+// e.g. when it has been encoded, the content decoded in the buffer must not be used afterwards.
+func (m *Message) Release() {
+	if m.buffer != nil {
+		PutBuffer(m.buffer)
+		m.buffer = nil
+	}
+}
+
 // GetStatus returns the status of the message
 func (m *Message) GetStatus() string {
 	if m.status == "" {
@@ -35,3 +73,14 @@ func (m *Message) GetStatus() string {
 func (m *Message) SetStatus(status string) {
 	m.status = status
 }
+
+// Tags returns the tags of the origin of the message followed by its host tags.
+func (m *Message) Tags() []string {
+	tags := append([]string{}, m.Origin.Tags()...)
+	return append(tags, m.HostTags...)
+}
+
+// TagsPayload returns the raw tag payload of the origin of the message including its host tags.
+func (m *Message) TagsPayload() []byte {
+	return m.Origin.tagsPayload(m.HostTags)
+}
@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 )
 
 func TestMessage(t *testing.T) {
@@ -21,3 +23,18 @@ func TestMessage(t *testing.T) {
 	assert.Equal(t, StatusInfo, message.GetStatus())
 
 }
+
+func TestMessageTagsIncludeHostTags(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Source: "a", Tags: []string{"c:d"}})
+	origin := NewOrigin(source)
+	origin.SetTags([]string{"foo:bar"})
+
+	message := NewMessage([]byte("hello"), origin, "")
+	message.HostTags = []string{"env:prod"}
+	assert.Equal(t, []string{"foo:bar", "c:d", "env:prod"}, message.Tags())
+	assert.Equal(t, "[dd ddsource=\"a\"][dd ddtags=\"c:d,foo:bar,env:prod\"]", string(message.TagsPayload()))
+
+	// the tags of the origin are left untouched.
+	assert.Equal(t, []string{"foo:bar", "c:d"}, origin.Tags())
+	assert.Equal(t, "[dd ddsource=\"a\"][dd ddtags=\"c:d,foo:bar\"]", string(origin.TagsPayload()))
+}
@@ -16,9 +16,14 @@ type Origin struct {
 	Identifier string
 	LogSource  *config.LogSource
 	Offset     string
-	service    string
-	source     string
-	tags       []string
+	// Acknowledge is called by the auditor once the message has been sent if it's set,
+	// it is not called for the messages replayed from the disk buffer.
+	Acknowledge func()
+	// Replayed is true for the messages replayed from the disk buffer, their offsets were committed when they were spilled.
+	Replayed bool
+	service  string
+	source   string
+	tags     []string
 }
 
 // NewOrigin returns a new Origin
@@ -43,6 +48,11 @@ func (o *Origin) Tags() []string {
 
 // TagsPayload returns the raw tag payload of the origin.
 func (o *Origin) TagsPayload() []byte {
+	return o.tagsPayload(nil)
+}
+
+// tagsPayload returns the raw tag payload of the origin followed by extraTags.
+func (o *Origin) tagsPayload(extraTags []string) []byte {
 	var tagsPayload []byte
 
 	source := o.Source()
@@ -57,6 +67,7 @@ func (o *Origin) TagsPayload() []byte {
 	var tags []string
 	tags = append(tags, o.LogSource.Config.Tags...)
 	tags = append(tags, o.tags...)
+	tags = append(tags, extraTags...)
 
 	if len(tags) > 0 {
 		tagsPayload = append(tagsPayload, []byte("[dd ddtags=\""+strings.Join(tags, ",")+"\"]")...)
@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package message
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// the pooled buffers hold from 2^minBufferSizeBits to 2^maxBufferSizeBits bytes,
+	// the bigger ones are left to the garbage collector.
+	minBufferSizeBits = 6
+	maxBufferSizeBits = 20
+)
+
+// bufferPools hold the buffers by size class, the class i holds buffers of 2^(i+minBufferSizeBits) bytes
+// so that a buffer of any size can be reused for a smaller content.
+var bufferPools [maxBufferSizeBits - minBufferSizeBits + 1]sync.Pool
+
+// poolingDisabled is set while the buffers are left to the garbage collector instead of being pooled.
+var poolingDisabled int32
+
+// SetBufferPooling enables or disables the pooling of the buffers returned with PutBuffer,
+// the buffers are left to the garbage collector while it's disabled to shrink the memory used.
+func SetBufferPooling(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&poolingDisabled, 0)
+	} else {
+		atomic.StoreInt32(&poolingDisabled, 1)
+	}
+}
+
+// GetBuffer returns a buffer of size bytes, reused from the pool when possible,
+// the buffer should be returned to the pool with PutBuffer once it's not used anymore.
+func GetBuffer(size int) []byte {
+	class := 0
+	if size > 1 {
+		class = bits.Len(uint(size-1)) - minBufferSizeBits
+	}
+	if class < 0 {
+		class = 0
+	}
+	if class >= len(bufferPools) {
+		return make([]byte, size)
+	}
+	if buf, ok := bufferPools[class].Get().(*[]byte); ok {
+		return (*buf)[:size]
+	}
+	return make([]byte, size, 1<<uint(class+minBufferSizeBits))
+}
+
+// PutBuffer returns buf to the pool, neither buf nor any slice of it must be used afterwards.
+func PutBuffer(buf []byte) {
+	// the largest class buf can be reused for
+	class := bits.Len(uint(cap(buf))) - 1 - minBufferSizeBits
+	if class < 0 || class >= len(bufferPools) || atomic.LoadInt32(&poolingDisabled) == 1 {
+		return
+	}
+	buf = buf[:0]
+	bufferPools[class].Put(&buf)
+}
@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBuffer(t *testing.T) {
+	for _, size := range []int{0, 1, 64, 65, 4096, 256 * 1000} {
+		buf := GetBuffer(size)
+		assert.Len(t, buf, size)
+		assert.True(t, cap(buf) >= size)
+		PutBuffer(buf)
+		// the buffers of the pool can hold the requested size whatever they were used for before.
+		buf = GetBuffer(size)
+		assert.Len(t, buf, size)
+		assert.True(t, cap(buf) >= size)
+	}
+
+	// the biggest buffers are not pooled.
+	buf := GetBuffer(1<<maxBufferSizeBits + 1)
+	assert.Len(t, buf, 1<<maxBufferSizeBits+1)
+	assert.Equal(t, 1<<maxBufferSizeBits+1, cap(buf))
+	PutBuffer(buf)
+}
+
+func TestPutBufferOfAnySize(t *testing.T) {
+	// a buffer which was not got from the pool is reused for the contents it can hold.
+	PutBuffer(make([]byte, 100))
+	PutBuffer(make([]byte, 0, 10))
+	buf := GetBuffer(64)
+	assert.Len(t, buf, 64)
+	assert.True(t, cap(buf) >= 64)
+}
+
+func TestPutBufferWithPoolingDisabled(t *testing.T) {
+	SetBufferPooling(false)
+	defer SetBufferPooling(true)
+
+	buf := GetBuffer(1 << 12)
+	buf[0] = 42
+	PutBuffer(buf)
+	// the buffer was left to the garbage collector.
+	assert.NotEqual(t, byte(42), GetBuffer(1 << 12)[0])
+}
+
+func TestMessageRelease(t *testing.T) {
+	buf := GetBuffer(5)
+	copy(buf, "hello")
+	message := NewMessage(buf, nil, "")
+	message.SetBuffer(buf)
+	assert.Equal(t, "hello", string(message.Content))
+
+	message.Release()
+	assert.Nil(t, message.buffer)
+	// releasing a message twice does not put its buffer twice in the pool.
+	message.Release()
+	assert.Nil(t, message.buffer)
+
+	// the messages without buffer can be released too.
+	NewMessage([]byte("hello"), nil, "").Release()
+}
@@ -7,6 +7,7 @@ package metrics
 
 import (
 	"expvar"
+	"time"
 )
 
 var (
@@ -16,12 +17,81 @@ var (
 	LogsDecoded = expvar.Int{}
 	// LogsProcessed is the total number of processed logs.
 	LogsProcessed = expvar.Int{}
+	// LogsFiltered is the total number of logs dropped by the exclude_at_match, include_at_match and sample_at_match processing rules.
+	LogsFiltered = expvar.Int{}
+	// LogsDropped is the total number of logs dropped by the sources with the drop_oldest policy because their pipeline was blocked.
+	LogsDropped = expvar.Int{}
+	// LogsGivenUp is the total number of logs dropped by the TCP senders because the connection to the intake
+	// could not be established within 'logs_config.connection_max_retries' attempts.
+	LogsGivenUp = expvar.Int{}
+	// SourceLogsDropped is the number of logs dropped per source with the drop_oldest policy.
+	SourceLogsDropped = expvar.Map{}
+	// SourceLogsOverQuota is the number of logs dropped per source because the source exceeded its quotas.
+	SourceLogsOverQuota = expvar.Map{}
+	// LogsShed is the total number of debug logs dropped by the processors while the agent was under memory pressure.
+	LogsShed = expvar.Int{}
+	// SourceLogsShed is the number of logs dropped per source while the agent was under memory pressure.
+	SourceLogsShed = expvar.Map{}
+	// MemoryShedding is 1 while the agent is under memory pressure and sheds logs, 0 otherwise.
+	MemoryShedding = expvar.Int{}
+	// LogsTruncated is the total number of logs truncated or split because they were too long.
+	LogsTruncated = expvar.Int{}
+	// ScriptTimeouts is the total number of logs the script processing rules stopped on because they ran for too long.
+	ScriptTimeouts = expvar.Int{}
 	// LogsSent is the total number of sent logs.
 	LogsSent = expvar.Int{}
 	// DestinationErrors is the total number of network errors.
 	DestinationErrors = expvar.Int{}
 	// DestinationLogsDropped is the total number of logs dropped per Destination
 	DestinationLogsDropped = expvar.Map{}
+	// LogsBuffered is the total number of logs buffered on disk.
+	LogsBuffered = expvar.Int{}
+	// ConnectionAttempts is the total number of attempts at connecting to the intakes.
+	ConnectionAttempts = expvar.Int{}
+	// ConnectionFailures is the total number of failed attempts at connecting to the intakes.
+	ConnectionFailures = expvar.Int{}
+	// DestinationRetries is the total number of times a payload was sent again after a failure.
+	DestinationRetries = expvar.Int{}
+	// BytesSent is the total number of bytes sent to all the destinations.
+	BytesSent = expvar.Int{}
+	// BatchesSent is the total number of HTTP batches accepted by all the destinations.
+	BatchesSent = expvar.Int{}
+	// BatchesPoisoned is the total number of HTTP batches given up on because the intake rejected them or kept failing on them.
+	BatchesPoisoned = expvar.Int{}
+	// BatchesQuarantined is the total number of HTTP batches given up on written to 'logs_config.quarantine_path'.
+	BatchesQuarantined = expvar.Int{}
+	// BatchSize is the number of logs of the last HTTP batch sent.
+	BatchSize = expvar.Int{}
+	// BatchWait is the time in milliseconds the logs currently wait at most in an HTTP batch.
+	BatchWait = expvar.Int{}
+	// DestinationBackoff is the current backoff in milliseconds per Destination.
+	DestinationBackoff = expvar.Map{}
+	// DestinationConnectLatency is the smoothed latency in milliseconds to connect to each TCP endpoint by address,
+	// used to select the fastest endpoint with 'logs_config.fallback_endpoints_selection: latency'.
+	DestinationConnectLatency = expvar.Map{}
+	// DestinationLatency is the latency in milliseconds of the last payload sent per Destination.
+	DestinationLatency = expvar.Map{}
+	// Tailers are the metrics of the running file tailers by identifier.
+	Tailers = TailerStatsMap{}
+	// OpenFiles is the number of files currently tailed.
+	OpenFiles = expvar.Int{}
+	// OpenFilesLimit is the maximum number of files which can be tailed, set by 'logs_config.open_files_limit'.
+	OpenFilesLimit = expvar.Int{}
+	// FilesEvicted is the total number of files not tailed anymore to tail more recently written files
+	// over 'logs_config.open_files_limit', with the least_recently_written eviction policy.
+	FilesEvicted = expvar.Int{}
+	// SourceCanariesInjected is the number of delivery canaries injected per source, set by 'logs_config.delivery_canary_interval'.
+	SourceCanariesInjected = expvar.Map{}
+	// SourceCanariesDelivered is the number of delivery canaries sent per source.
+	SourceCanariesDelivered = expvar.Map{}
+	// SourceCanariesLost is the number of delivery canaries per source which were not sent in time.
+	SourceCanariesLost = expvar.Map{}
+	// HookLogsDropped is the number of logs dropped per hook of the pipelines.
+	HookLogsDropped = expvar.Map{}
+	// CheckLogsDropped is the total number of logs submitted by the checks dropped because their pipeline was full.
+	CheckLogsDropped = expvar.Int{}
+	// SDSMatches is the number of matches per rule of the sensitive data scanner.
+	SDSMatches = expvar.Map{}
 	// TODO: Add LogsCollected for the total number of collected logs.
 )
 
@@ -29,7 +99,47 @@ func init() {
 	LogsExpvars = expvar.NewMap("logs-agent")
 	LogsExpvars.Set("LogsDecoded", &LogsDecoded)
 	LogsExpvars.Set("LogsProcessed", &LogsProcessed)
+	LogsExpvars.Set("LogsFiltered", &LogsFiltered)
+	LogsExpvars.Set("LogsDropped", &LogsDropped)
+	LogsExpvars.Set("LogsGivenUp", &LogsGivenUp)
+	LogsExpvars.Set("SourceLogsDropped", &SourceLogsDropped)
+	LogsExpvars.Set("SourceLogsOverQuota", &SourceLogsOverQuota)
+	LogsExpvars.Set("LogsShed", &LogsShed)
+	LogsExpvars.Set("SourceLogsShed", &SourceLogsShed)
+	LogsExpvars.Set("MemoryShedding", &MemoryShedding)
+	LogsExpvars.Set("LogsTruncated", &LogsTruncated)
+	LogsExpvars.Set("ScriptTimeouts", &ScriptTimeouts)
 	LogsExpvars.Set("LogsSent", &LogsSent)
 	LogsExpvars.Set("DestinationErrors", &DestinationErrors)
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
+	LogsExpvars.Set("LogsBuffered", &LogsBuffered)
+	LogsExpvars.Set("ConnectionAttempts", &ConnectionAttempts)
+	LogsExpvars.Set("ConnectionFailures", &ConnectionFailures)
+	LogsExpvars.Set("DestinationRetries", &DestinationRetries)
+	LogsExpvars.Set("BytesSent", &BytesSent)
+	LogsExpvars.Set("BatchesSent", &BatchesSent)
+	LogsExpvars.Set("BatchesPoisoned", &BatchesPoisoned)
+	LogsExpvars.Set("BatchesQuarantined", &BatchesQuarantined)
+	LogsExpvars.Set("BatchSize", &BatchSize)
+	LogsExpvars.Set("BatchWait", &BatchWait)
+	LogsExpvars.Set("DestinationBackoff", &DestinationBackoff)
+	LogsExpvars.Set("DestinationConnectLatency", &DestinationConnectLatency)
+	LogsExpvars.Set("DestinationLatency", &DestinationLatency)
+	LogsExpvars.Set("Tailers", &Tailers)
+	LogsExpvars.Set("OpenFiles", &OpenFiles)
+	LogsExpvars.Set("OpenFilesLimit", &OpenFilesLimit)
+	LogsExpvars.Set("FilesEvicted", &FilesEvicted)
+	LogsExpvars.Set("SourceCanariesInjected", &SourceCanariesInjected)
+	LogsExpvars.Set("SourceCanariesDelivered", &SourceCanariesDelivered)
+	LogsExpvars.Set("SourceCanariesLost", &SourceCanariesLost)
+	LogsExpvars.Set("HookLogsDropped", &HookLogsDropped)
+	LogsExpvars.Set("CheckLogsDropped", &CheckLogsDropped)
+	LogsExpvars.Set("SDSMatches", &SDSMatches)
+}
+
+// SetDuration sets the value of key in m to d in milliseconds.
+func SetDuration(m *expvar.Map, key string, d time.Duration) {
+	v := &expvar.Int{}
+	v.Set(int64(d / time.Millisecond))
+	m.Set(key, v)
 }
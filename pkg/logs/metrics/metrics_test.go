@@ -6,11 +6,45 @@
 package metrics
 
 import (
+	"expvar"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestMetrics(t *testing.T) {
-	assert.Equal(t, LogsExpvars.String(), `{"DestinationErrors": 0, "DestinationLogsDropped": {}, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0}`)
+	assert.Equal(t, LogsExpvars.String(), `{"BatchSize": 0, "BatchWait": 0, "BatchesPoisoned": 0, "BatchesQuarantined": 0, "BatchesSent": 0, "BytesSent": 0, "CheckLogsDropped": 0, "ConnectionAttempts": 0, "ConnectionFailures": 0, "DestinationBackoff": {}, "DestinationConnectLatency": {}, "DestinationErrors": 0, "DestinationLatency": {}, "DestinationLogsDropped": {}, "DestinationRetries": 0, "FilesEvicted": 0, "HookLogsDropped": {}, "LogsBuffered": 0, "LogsDecoded": 0, "LogsDropped": 0, "LogsFiltered": 0, "LogsGivenUp": 0, "LogsProcessed": 0, "LogsSent": 0, "LogsShed": 0, "LogsTruncated": 0, "MemoryShedding": 0, "OpenFiles": 0, "OpenFilesLimit": 0, "SDSMatches": {}, "ScriptTimeouts": 0, "SourceCanariesDelivered": {}, "SourceCanariesInjected": {}, "SourceCanariesLost": {}, "SourceLogsDropped": {}, "SourceLogsOverQuota": {}, "SourceLogsShed": {}, "Tailers": {}}`)
+}
+
+func TestSetDuration(t *testing.T) {
+	m := &expvar.Map{}
+	SetDuration(m, "foo", 1500*time.Millisecond)
+	assert.Equal(t, `{"foo": 1500}`, m.String())
+	SetDuration(m, "foo", 0)
+	assert.Equal(t, `{"foo": 0}`, m.String())
+}
+
+func TestTailerStatsMap(t *testing.T) {
+	m := &TailerStatsMap{}
+	assert.Equal(t, `{}`, m.String())
+
+	lag := int64(10)
+	stats := NewTailerStats(func() int64 { return lag })
+	m.Set("file:/var/log/foo.log", stats)
+	stats.AddBytesRead(42)
+	stats.AddLinesDecoded(2)
+	stats.AddDecodingErrors(1)
+	stats.SetPaused(true)
+	assert.Equal(t, `{"file:/var/log/foo.log":{"BytesRead":42,"LinesDecoded":2,"DecodingErrors":1,"Lag":10,"Paused":true}}`, m.String())
+	stats.SetPaused(false)
+	assert.False(t, m.Values()["file:/var/log/foo.log"].Paused)
+
+	// the stats of the tailer which replaced the previous one are kept.
+	newStats := NewTailerStats(nil)
+	m.Set("file:/var/log/foo.log", newStats)
+	m.Remove("file:/var/log/foo.log", stats)
+	assert.Equal(t, TailerStatsValues{}, m.Values()["file:/var/log/foo.log"])
+	m.Remove("file:/var/log/foo.log", newStats)
+	assert.Equal(t, `{}`, m.String())
 }
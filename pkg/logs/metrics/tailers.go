@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package metrics
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+// TailerStats holds the metrics of a tailer.
+type TailerStats struct {
+	// the counters are the first fields to be 64-bit aligned for the atomic operations.
+	bytesRead      int64
+	linesDecoded   int64
+	decodingErrors int64
+	paused         int32
+	// lag returns the number of bytes the tailer is behind the end of its input.
+	lag func() int64
+}
+
+// NewTailerStats returns new stats computing the lag of the tailer with lag, when they are read, unless it's nil.
+func NewTailerStats(lag func() int64) *TailerStats {
+	return &TailerStats{
+		lag: lag,
+	}
+}
+
+// AddBytesRead accounts n bytes read by the tailer.
+func (s *TailerStats) AddBytesRead(n int) {
+	atomic.AddInt64(&s.bytesRead, int64(n))
+}
+
+// AddLinesDecoded accounts n lines decoded by the tailer.
+func (s *TailerStats) AddLinesDecoded(n int) {
+	atomic.AddInt64(&s.linesDecoded, int64(n))
+}
+
+// AddDecodingErrors accounts n lines the tailer failed to decode.
+func (s *TailerStats) AddDecodingErrors(n int) {
+	atomic.AddInt64(&s.decodingErrors, int64(n))
+}
+
+// SetPaused reports whether the tailer is paused because its pipeline is saturated.
+func (s *TailerStats) SetPaused(paused bool) {
+	var value int32
+	if paused {
+		value = 1
+	}
+	atomic.StoreInt32(&s.paused, value)
+}
+
+// TailerStatsValues are the values of the metrics of a tailer.
+type TailerStatsValues struct {
+	BytesRead      int64
+	LinesDecoded   int64
+	DecodingErrors int64
+	Lag            int64
+	Paused         bool
+}
+
+// Values returns the current values of the metrics of the tailer.
+func (s *TailerStats) Values() TailerStatsValues {
+	values := TailerStatsValues{
+		BytesRead:      atomic.LoadInt64(&s.bytesRead),
+		LinesDecoded:   atomic.LoadInt64(&s.linesDecoded),
+		DecodingErrors: atomic.LoadInt64(&s.decodingErrors),
+		Paused:         atomic.LoadInt32(&s.paused) != 0,
+	}
+	if s.lag != nil {
+		values.Lag = s.lag()
+	}
+	return values
+}
+
+// TailerStatsMap is an expvar publishing the metrics of the running tailers by identifier,
+// unlike an expvar.Map the tailers are removed once they are stopped.
+type TailerStatsMap struct {
+	mu    sync.Mutex
+	stats map[string]*TailerStats
+}
+
+// Set publishes the stats of the tailer identified by id.
+func (m *TailerStatsMap) Set(id string, stats *TailerStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stats == nil {
+		m.stats = make(map[string]*TailerStats)
+	}
+	m.stats[id] = stats
+}
+
+// Remove stops publishing the stats of the tailer identified by id, unless they were replaced
+// by the ones of another tailer, e.g. the tailer of a file rotated over.
+// The lag of the stats is not computed anymore once Remove returns.
+func (m *TailerStatsMap) Remove(id string, stats *TailerStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stats[id] == stats {
+		delete(m.stats, id)
+	}
+}
+
+// Values returns the current values of the metrics of the tailers by identifier.
+func (m *TailerStatsMap) Values() map[string]TailerStatsValues {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make(map[string]TailerStatsValues, len(m.stats))
+	for id, stats := range m.stats {
+		values[id] = stats.Values()
+	}
+	return values
+}
+
+// String returns the JSON representation of the metrics of the tailers.
+func (m *TailerStatsMap) String() string {
+	content, err := json.Marshal(m.Values())
+	if err != nil {
+		return "{}"
+	}
+	return string(content)
+}
@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package pipeline
+
+import (
+	"context"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/memory"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// sheddingBufferRatio is the ratio the buffers of the forwarders are shrunk by while the agent sheds logs.
+const sheddingBufferRatio = 4
+
+// A dropOldestForwarder forwards the logs of a source with the drop_oldest policy to its pipeline,
+// it holds up to bufferSize logs while the pipeline is blocked and drops the oldest ones to make room
+// for the new ones once full, so that the inputs of the source are never blocked.
+// It holds sheddingBufferRatio times less logs while monitor sheds.
+type dropOldestForwarder struct {
+	name       string
+	monitor    *memory.Monitor
+	inputChan  chan *message.Message
+	outputChan chan *message.Message
+	buffer     []*message.Message
+	bufferSize int
+	flushChan  chan chan struct{}
+	done       chan struct{}
+}
+
+// newDropOldestForwarder returns a forwarder of the logs of the source name to outputChan holding up to bufferSize logs,
+// monitor can be nil.
+func newDropOldestForwarder(name string, outputChan chan *message.Message, bufferSize int, monitor *memory.Monitor) *dropOldestForwarder {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &dropOldestForwarder{
+		name:       name,
+		monitor:    monitor,
+		inputChan:  make(chan *message.Message, bufferSize),
+		outputChan: outputChan,
+		bufferSize: bufferSize,
+		flushChan:  make(chan chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts forwarding the logs.
+func (f *dropOldestForwarder) Start() {
+	go f.run()
+}
+
+// Stop stops the forwarder, this call blocks until the logs it holds are forwarded.
+func (f *dropOldestForwarder) Stop() {
+	close(f.inputChan)
+	<-f.done
+}
+
+// Flush forwards the logs held by the forwarder right away,
+// this call blocks until they are pushed to the pipeline or ctx is done.
+func (f *dropOldestForwarder) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case f.flushChan <- flushed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run forwards the logs until inputChan is closed.
+func (f *dropOldestForwarder) run() {
+	defer close(f.done)
+	for {
+		// only try to forward a log when there is one.
+		var outputChan chan *message.Message
+		var next *message.Message
+		if len(f.buffer) > 0 {
+			outputChan = f.outputChan
+			next = f.buffer[0]
+		}
+		select {
+		case msg, isOpen := <-f.inputChan:
+			if !isOpen {
+				f.forwardAll()
+				return
+			}
+			f.add(msg)
+		case outputChan <- next:
+			f.buffer = f.buffer[1:]
+		case flushed := <-f.flushChan:
+			for queued := len(f.inputChan); queued > 0; queued-- {
+				msg, isOpen := <-f.inputChan
+				if !isOpen {
+					break
+				}
+				f.add(msg)
+			}
+			f.forwardAll()
+			close(flushed)
+		}
+	}
+}
+
+// add adds a log to the buffer, dropping the oldest ones when it's full.
+func (f *dropOldestForwarder) add(msg *message.Message) {
+	bufferSize := f.bufferSize
+	if f.monitor.Shedding() && bufferSize > sheddingBufferRatio {
+		bufferSize /= sheddingBufferRatio
+	}
+	for len(f.buffer) >= bufferSize {
+		f.buffer = f.buffer[1:]
+		metrics.LogsDropped.Add(1)
+		metrics.SourceLogsDropped.Add(f.name, 1)
+	}
+	f.buffer = append(f.buffer, msg)
+}
+
+// forwardAll pushes all the logs of the buffer to the pipeline.
+func (f *dropOldestForwarder) forwardAll() {
+	for _, msg := range f.buffer {
+		f.outputChan <- msg
+	}
+	f.buffer = nil
+}
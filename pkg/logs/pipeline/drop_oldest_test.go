@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package pipeline
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/memory"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+func newTestMessage(content string) *message.Message {
+	return message.NewMessage([]byte(content), message.NewOrigin(config.NewLogSource("", &config.LogsConfig{})), "")
+}
+
+func TestDropOldestForwarderForwardsTheLogsInOrder(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	f := newDropOldestForwarder("in-order", outputChan, 10, nil)
+	f.Start()
+	for i := 0; i < 5; i++ {
+		f.inputChan <- newTestMessage(fmt.Sprintf("%d", i))
+	}
+	f.Stop()
+
+	assert.Len(t, outputChan, 5)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, fmt.Sprintf("%d", i), string((<-outputChan).Content))
+	}
+}
+
+func TestDropOldestForwarderDropsTheOldestLogsWhenThePipelineIsBlocked(t *testing.T) {
+	dropped := metrics.LogsDropped.Value()
+	metrics.SourceLogsDropped.Set("blocked", &expvar.Int{})
+	outputChan := make(chan *message.Message)
+	f := newDropOldestForwarder("blocked", outputChan, 3, nil)
+	f.Start()
+
+	// the inputs are never blocked.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			f.inputChan <- newTestMessage(fmt.Sprintf("%d", i))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the input was blocked")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, f.Flush(ctx))
+
+	go f.Stop()
+	var contents []string
+	for msg := range outputChan {
+		contents = append(contents, string(msg.Content))
+		if contents[len(contents)-1] == "9" {
+			break
+		}
+	}
+	// only the most recent logs are left, whatever was sent before getting blocked.
+	assert.Equal(t, []string{"7", "8", "9"}, contents[len(contents)-3:])
+	assert.Equal(t, int64(10-len(contents)), metrics.LogsDropped.Value()-dropped)
+	assert.Equal(t, int64(10-len(contents)), metrics.SourceLogsDropped.Get("blocked").(*expvar.Int).Value())
+}
+
+func TestDropOldestForwarderShrinksItsBufferUnderMemoryPressure(t *testing.T) {
+	monitor := memory.NewMonitor(1, 0)
+	monitor.Start()
+	defer monitor.Stop()
+	f := newDropOldestForwarder("shrunk", nil, 8, monitor)
+	for i := 0; i < 4; i++ {
+		f.add(newTestMessage(fmt.Sprintf("%d", i)))
+	}
+	assert.Len(t, f.buffer, 4)
+
+	monitor.Check()
+	f.add(newTestMessage("4"))
+	assert.Len(t, f.buffer, 8/sheddingBufferRatio)
+	assert.Equal(t, "4", string(f.buffer[len(f.buffer)-1].Content))
+}
+
+func TestDropOldestForwarderFlush(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	f := newDropOldestForwarder("flush", outputChan, 10, nil)
+	f.Start()
+	defer f.Stop()
+	for i := 0; i < 3; i++ {
+		f.inputChan <- newTestMessage("hello")
+	}
+	assert.Nil(t, f.Flush(context.Background()))
+	assert.Len(t, outputChan, 3)
+}
@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package pipeline
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// capacityCheckPeriod is the period at which a paused input checks whether its pipeline can take new logs again.
+var capacityCheckPeriod = 100 * time.Millisecond
+
+// IsSaturated returns true if the pipeline of inputChan can not take any new log without blocking,
+// the channels of a pipeline fill up from the sender to the input channel when the sender can not keep up,
+// e.g. while the connection to the intake is down.
+func IsSaturated(inputChan chan *message.Message) bool {
+	return cap(inputChan) > 0 && len(inputChan) == cap(inputChan)
+}
+
+// WaitForCapacity blocks while the pipeline of inputChan is saturated so that an input stops reading
+// new logs, and as such stops advancing its offset, until the pipeline can take them.
+// It returns right away when the pipeline is not saturated and false if stop is signaled in the meantime.
+func WaitForCapacity(inputChan chan *message.Message, stop <-chan struct{}) bool {
+	if !IsSaturated(inputChan) {
+		return true
+	}
+	ticker := time.NewTicker(capacityCheckPeriod)
+	defer ticker.Stop()
+	for IsSaturated(inputChan) {
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestIsSaturated(t *testing.T) {
+	assert.False(t, IsSaturated(make(chan *message.Message)))
+
+	inputChan := make(chan *message.Message, 2)
+	assert.False(t, IsSaturated(inputChan))
+	inputChan <- newTestMessage("foo")
+	assert.False(t, IsSaturated(inputChan))
+	inputChan <- newTestMessage("bar")
+	assert.True(t, IsSaturated(inputChan))
+}
+
+func TestWaitForCapacityBlocksUntilThePipelineCanTakeNewLogs(t *testing.T) {
+	defer func(period time.Duration) { capacityCheckPeriod = period }(capacityCheckPeriod)
+	capacityCheckPeriod = time.Millisecond
+
+	inputChan := make(chan *message.Message, 1)
+	assert.True(t, WaitForCapacity(inputChan, nil))
+
+	inputChan <- newTestMessage("foo")
+	done := make(chan bool)
+	go func() {
+		done <- WaitForCapacity(inputChan, nil)
+	}()
+	select {
+	case <-done:
+		assert.Fail(t, "the input should be paused while the pipeline is saturated")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-inputChan
+	select {
+	case ok := <-done:
+		assert.True(t, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the input should resume once the pipeline can take new logs")
+	}
+}
+
+func TestWaitForCapacityReturnsWhenStopped(t *testing.T) {
+	inputChan := make(chan *message.Message, 1)
+	inputChan <- newTestMessage("foo")
+	stop := make(chan struct{}, 1)
+	stop <- struct{}{}
+	assert.False(t, WaitForCapacity(inputChan, stop))
+}
@@ -6,6 +6,9 @@
 package mock
 
 import (
+	"context"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 )
@@ -28,7 +31,17 @@ func (p *mockProvider) Start() {}
 // Stop does nothing
 func (p *mockProvider) Stop() {}
 
+// Flush does nothing
+func (p *mockProvider) Flush(ctx context.Context) error {
+	return nil
+}
+
 // NextPipelineChan returns the next pipeline
 func (p *mockProvider) NextPipelineChan() chan *message.Message {
 	return p.msgChan
 }
+
+// PipelineChanForSource returns the pipeline
+func (p *mockProvider) PipelineChanForSource(source *config.LogSource) chan *message.Message {
+	return p.msgChan
+}
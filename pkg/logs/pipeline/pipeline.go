@@ -6,58 +6,143 @@
 package pipeline
 
 import (
+	"context"
+
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/memory"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/processor"
+	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	"github.com/DataDog/datadog-agent/pkg/logs/tag"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Pipeline processes and sends messages to the backend
 type Pipeline struct {
 	InputChan chan *message.Message
 	processor *processor.Processor
-	sender    *sender.Sender
+	spiller   *sender.Spiller
+	sender    flushableSender
 }
 
-// NewPipeline returns a new Pipeline
-func NewPipeline(outputChan chan *message.Message, processingRules []*config.ProcessingRule, endpoints *client.Endpoints, destinationsContext *client.DestinationsContext) *Pipeline {
-	// initialize the main destination
-	main := client.NewDestination(endpoints.Main, destinationsContext)
+// flushableSender is a sender that can send the messages it holds on demand.
+type flushableSender interface {
+	restart.Restartable
+	Flush(ctx context.Context) error
+}
 
-	// initialize the additional destinations
-	var additionals []*client.Destination
-	for _, endpoint := range endpoints.Additionals {
-		additionals = append(additionals, client.NewDestination(endpoint, destinationsContext))
+// NewPipeline returns a new Pipeline scanning the messages with scanner if it's not nil, the messages the sender can not keep up with
+// are spilled to diskBuffer if it's not nil, the HTTP batches given up on are written to quarantine if it's not nil, the outbound traffic is capped by limiter if it's not nil
+// and the tags of hostTags are attached to the messages, the processed messages are handed to diagnostics
+// and the debug messages are dropped while monitor sheds, monitor can be nil.
+// The channels between the components of the pipeline hold up to chanSize messages.
+func NewPipeline(outputChan chan *message.Message, chanSize int, processingRules []*config.ProcessingRule, scanner *sds.Scanner, endpoints *client.Endpoints, destinationsContext *client.DestinationsContext, diskBuffer *sender.DiskBuffer, quarantine *sender.Quarantine, limiter *sender.RateLimiter, hostTags tag.Provider, diagnostics diagnostic.MessageReceiver, monitor *memory.Monitor) *Pipeline {
+	senderChan := make(chan *message.Message, chanSize)
+
+	// initialize the spiller
+	processorChan := senderChan
+	var spiller *sender.Spiller
+	if diskBuffer != nil {
+		processorChan = make(chan *message.Message, chanSize)
+		spiller = sender.NewSpiller(processorChan, senderChan, outputChan, diskBuffer)
 	}
 
-	// initialize the sender
-	destinations := client.NewDestinations(main, additionals)
-	senderChan := make(chan *message.Message, config.ChanSize)
-	sender := sender.NewSender(senderChan, outputChan, destinations)
+	var sender flushableSender
+	var encoder processor.Encoder
+	if endpoints.UseHTTP {
+		sender = newHTTPSender(senderChan, outputChan, endpoints, destinationsContext, limiter, quarantine)
+		if endpoints.Main.PayloadFormat == client.ProtobufPayloadFormat {
+			encoder = processor.NewEncoder(true)
+		} else {
+			encoder = processor.NewJSONEncoder()
+		}
+	} else if endpoints.UseAggregator {
+		sender = newTCPSender(senderChan, outputChan, endpoints, destinationsContext, limiter)
+		encoder = processor.NewJSONEncoder()
+	} else {
+		sender = newTCPSender(senderChan, outputChan, endpoints, destinationsContext, limiter)
+		encoder = processor.NewEncoder(endpoints.Main.UseProto)
+	}
 
 	// initialize the input chan
-	inputChan := make(chan *message.Message, config.ChanSize)
+	inputChan := make(chan *message.Message, chanSize)
 
 	// initialize the processor
-	encoder := processor.NewEncoder(endpoints.Main.UseProto)
-	processor := processor.New(inputChan, senderChan, processingRules, encoder)
+	processor := processor.New(inputChan, processorChan, processingRules, scanner, encoder, hostTags, diagnostics, monitor)
 
 	return &Pipeline{
 		InputChan: inputChan,
 		processor: processor,
+		spiller:   spiller,
 		sender:    sender,
 	}
 }
 
+// newTCPSender returns a sender that streams the logs to TCP destinations.
+func newTCPSender(senderChan, outputChan chan *message.Message, endpoints *client.Endpoints, destinationsContext *client.DestinationsContext, limiter *sender.RateLimiter) *sender.Sender {
+	// initialize the main destination
+	main := client.NewDestination(endpoints.Main, destinationsContext)
+
+	// initialize the additional destinations
+	var additionals []*client.Destination
+	for _, endpoint := range endpoints.Additionals {
+		additionals = append(additionals, client.NewDestination(endpoint, destinationsContext))
+	}
+
+	destinations := client.NewDestinations(main, additionals)
+	return sender.NewSender(senderChan, outputChan, destinations, limiter, endpoints.TCPMaxBatchSize, endpoints.TCPBatchLinger)
+}
+
+// newHTTPSender returns a sender that sends batches of logs to HTTP destinations.
+func newHTTPSender(senderChan, outputChan chan *message.Message, endpoints *client.Endpoints, destinationsContext *client.DestinationsContext, limiter *sender.RateLimiter, quarantine *sender.Quarantine) *sender.HTTPSender {
+	// initialize the main destination, the logs are not sent when it can not be created.
+	main, err := client.NewHTTPDestination(endpoints.Main, destinationsContext)
+	if err != nil {
+		log.Errorf("Could not create the destination, the logs will not be sent: %v", err)
+		main = client.NewRefusingHTTPDestination(endpoints.Main, err)
+	}
+
+	// initialize the additional destinations
+	var additionals []*client.HTTPDestination
+	for _, endpoint := range endpoints.Additionals {
+		additional, err := client.NewHTTPDestination(endpoint, destinationsContext)
+		if err != nil {
+			log.Errorf("Could not create the additional destination, the logs will not be sent to it: %v", err)
+			continue
+		}
+		additionals = append(additionals, additional)
+	}
+
+	return sender.NewHTTPSender(senderChan, outputChan, main, additionals, endpoints.BatchWait, endpoints.MaxInflightBatches, limiter, quarantine)
+}
+
 // Start launches the pipeline
 func (p *Pipeline) Start() {
 	p.sender.Start()
+	if p.spiller != nil {
+		p.spiller.Start()
+	}
 	p.processor.Start()
 }
 
+// Flush processes the messages queued in the pipeline and sends the messages held by its sender right away,
+// this call blocks until they are sent or ctx is done.
+func (p *Pipeline) Flush(ctx context.Context) error {
+	if err := p.processor.Flush(ctx); err != nil {
+		return err
+	}
+	return p.sender.Flush(ctx)
+}
+
 // Stop stops the pipeline
 func (p *Pipeline) Stop() {
 	p.processor.Stop()
+	if p.spiller != nil {
+		p.spiller.Stop()
+	}
 	p.sender.Stop()
 }
@@ -6,44 +6,76 @@
 package pipeline
 
 import (
+	"context"
+	"hash/fnv"
+	"sync"
 	"sync/atomic"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/memory"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+	"github.com/DataDog/datadog-agent/pkg/logs/sds"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	"github.com/DataDog/datadog-agent/pkg/logs/tag"
 )
 
 // Provider provides message channels
 type Provider interface {
 	Start()
 	Stop()
+	Flush(ctx context.Context) error
 	NextPipelineChan() chan *message.Message
+	PipelineChanForSource(source *config.LogSource) chan *message.Message
 }
 
 // provider implements providing logic
 type provider struct {
 	numberOfPipelines int
+	chanSize          int
 	auditor           *auditor.Auditor
 	outputChan        chan *message.Message
 	processingRules   []*config.ProcessingRule
+	scanner           *sds.Scanner
 	endpoints         *client.Endpoints
+	diskBuffers       []*sender.DiskBuffer
+	quarantine        *sender.Quarantine
+	hostTags          tag.Provider
+	diagnostics       diagnostic.MessageReceiver
+	monitor           *memory.Monitor
 
 	pipelines            []*Pipeline
 	currentPipelineIndex int32
 	destinationsContext  *client.DestinationsContext
+
+	// forwarders hold the logs of the sources with the drop_oldest policy by source.
+	forwarders   map[string]*dropOldestForwarder
+	forwardersMu sync.Mutex
 }
 
-// NewProvider returns a new Provider
-func NewProvider(numberOfPipelines int, auditor *auditor.Auditor, processingRules []*config.ProcessingRule, endpoints *client.Endpoints, destinationsContext *client.DestinationsContext) Provider {
+// NewProvider returns a new Provider of pipelines whose channels hold up to chanSize messages, scanner and quarantine are shared by all the pipelines and can be nil,
+// the pipelines spill their messages to their own disk buffer of diskBuffers, one per pipeline, unless it's nil,
+// hostTags provides the tags of the host the pipelines attach to the messages, diagnostics receives the processed messages
+// and the pipelines shed logs while monitor does, monitor can be nil.
+func NewProvider(numberOfPipelines int, chanSize int, auditor *auditor.Auditor, processingRules []*config.ProcessingRule, scanner *sds.Scanner, endpoints *client.Endpoints, destinationsContext *client.DestinationsContext, diskBuffers []*sender.DiskBuffer, quarantine *sender.Quarantine, hostTags tag.Provider, diagnostics diagnostic.MessageReceiver, monitor *memory.Monitor) Provider {
 	return &provider{
 		numberOfPipelines:   numberOfPipelines,
+		chanSize:            chanSize,
 		auditor:             auditor,
 		processingRules:     processingRules,
+		scanner:             scanner,
 		endpoints:           endpoints,
+		diskBuffers:         diskBuffers,
+		quarantine:          quarantine,
+		hostTags:            hostTags,
+		diagnostics:         diagnostics,
+		monitor:             monitor,
 		pipelines:           []*Pipeline{},
 		destinationsContext: destinationsContext,
+		forwarders:          make(map[string]*dropOldestForwarder),
 	}
 }
 
@@ -52,16 +84,31 @@ func (p *provider) Start() {
 	// This requires the auditor to be started before.
 	p.outputChan = p.auditor.Channel()
 
+	// the rate limits apply to the whole logs agent, not to each pipeline.
+	limiter := sender.NewRateLimiter(p.endpoints.MaxBytesPerSecond, p.endpoints.MaxEventsPerSecond, p.destinationsContext)
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext)
+		var diskBuffer *sender.DiskBuffer
+		if p.diskBuffers != nil {
+			diskBuffer = p.diskBuffers[i]
+		}
+		pipeline := NewPipeline(p.outputChan, p.chanSize, p.processingRules, p.scanner, p.endpoints, p.destinationsContext, diskBuffer, p.quarantine, limiter, p.hostTags, p.diagnostics, p.monitor)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
 }
 
-// Stop stops all pipelines in parallel,
-// this call blocks until all pipelines are stopped
+// Stop stops all pipelines in parallel once the logs held for the sources with the drop_oldest policy
+// are forwarded to them, this call blocks until all pipelines are stopped
 func (p *provider) Stop() {
+	p.forwardersMu.Lock()
+	forwarders := restart.NewParallelStopper()
+	for key, forwarder := range p.forwarders {
+		forwarders.Add(forwarder)
+		delete(p.forwarders, key)
+	}
+	p.forwardersMu.Unlock()
+	forwarders.Stop()
+
 	stopper := restart.NewParallelStopper()
 	for _, pipeline := range p.pipelines {
 		stopper.Add(pipeline)
@@ -71,6 +118,37 @@ func (p *provider) Stop() {
 	p.outputChan = nil
 }
 
+// Flush flushes all pipelines in parallel, after the logs held for the sources with the drop_oldest policy,
+// this call blocks until all pipelines are flushed or ctx is done.
+func (p *provider) Flush(ctx context.Context) error {
+	p.forwardersMu.Lock()
+	forwarders := make([]*dropOldestForwarder, 0, len(p.forwarders))
+	for _, forwarder := range p.forwarders {
+		forwarders = append(forwarders, forwarder)
+	}
+	p.forwardersMu.Unlock()
+	for _, forwarder := range forwarders {
+		if err := forwarder.Flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	errs := make(chan error, len(p.pipelines))
+	var wg sync.WaitGroup
+	for _, pipeline := range p.pipelines {
+		wg.Add(1)
+		go func(pipeline *Pipeline) {
+			defer wg.Done()
+			if err := pipeline.Flush(ctx); err != nil {
+				errs <- err
+			}
+		}(pipeline)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
 // NextPipelineChan returns the next pipeline input channel
 func (p *provider) NextPipelineChan() chan *message.Message {
 	pipelinesLen := len(p.pipelines)
@@ -82,3 +160,35 @@ func (p *provider) NextPipelineChan() chan *message.Message {
 	nextPipeline := p.pipelines[index]
 	return nextPipeline.InputChan
 }
+
+// PipelineChanForSource returns the input channel of the pipeline the logs of source are sent to,
+// the logs of a source always go through the same pipeline so that a noisy or slow source
+// backs up its own pipeline instead of starving the sources of the other pipelines.
+// The logs of the sources with the drop_oldest policy go through a forwarder that never blocks.
+func (p *provider) PipelineChanForSource(source *config.LogSource) chan *message.Message {
+	pipelinesLen := len(p.pipelines)
+	if pipelinesLen == 0 {
+		return nil
+	}
+	if source == nil || source.Config == nil {
+		return p.NextPipelineChan()
+	}
+	// the containers collected with container_collect_all share the same source name.
+	key := source.Name + "\x00" + source.Config.Source
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	inputChan := p.pipelines[hash.Sum32()%uint32(pipelinesLen)].InputChan
+	if source.Config.DropPolicy != config.DropOldestPolicy {
+		return inputChan
+	}
+
+	p.forwardersMu.Lock()
+	defer p.forwardersMu.Unlock()
+	forwarder, exists := p.forwarders[key]
+	if !exists {
+		forwarder = newDropOldestForwarder(source.Name, inputChan, p.chanSize, p.monitor)
+		forwarder.Start()
+		p.forwarders[key] = forwarder
+	}
+	return forwarder.inputChan
+}
@@ -6,7 +6,12 @@
 package pipeline
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 
@@ -14,24 +19,41 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/tag"
 )
 
 type ProviderTestSuite struct {
 	suite.Suite
-	p *provider
-	a *auditor.Auditor
+	p       *provider
+	a       *auditor.Auditor
+	testDir string
 }
 
 func (suite *ProviderTestSuite) SetupTest() {
-	suite.a = auditor.New("", health.Register("fake"))
+	var err error
+	suite.testDir, err = ioutil.TempDir("", "tests")
+	suite.NoError(err)
+
+	suite.a = auditor.New(suite.testDir, health.Register("fake"))
 	suite.p = &provider{
 		numberOfPipelines: 3,
+		chanSize:          config.ChanSize,
 		auditor:           suite.a,
 		pipelines:         []*Pipeline{},
-		endpoints:         client.NewEndpoints(client.Endpoint{}, nil),
+		endpoints:         client.NewEndpoints(client.Endpoint{}, nil, false, 0),
+		hostTags:          tag.NoopProvider,
+		diagnostics:       diagnostic.NoopMessageReceiver,
+		forwarders:        make(map[string]*dropOldestForwarder),
 	}
 }
 
+func (suite *ProviderTestSuite) TearDownTest() {
+	os.RemoveAll(suite.testDir)
+}
+
 func (suite *ProviderTestSuite) TestProvider() {
 	suite.a.Start()
 	suite.p.Start()
@@ -54,6 +76,64 @@ func (suite *ProviderTestSuite) TestProvider() {
 	suite.Nil(suite.p.NextPipelineChan())
 }
 
+func (suite *ProviderTestSuite) TestProviderFlush() {
+	suite.a.Start()
+	suite.p.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	suite.Nil(suite.p.Flush(ctx))
+
+	suite.p.Stop()
+	suite.a.Stop()
+}
+
+func (suite *ProviderTestSuite) TestProviderPipelineChanForSourceWithDropOldestPolicy() {
+	suite.a.Start()
+	suite.p.Start()
+
+	source := config.NewLogSource("nginx", &config.LogsConfig{Source: "nginx", DropPolicy: config.DropOldestPolicy})
+	c := suite.p.PipelineChanForSource(source)
+	suite.NotNil(c)
+	// the logs of a source go through its forwarder.
+	suite.Equal(c, suite.p.PipelineChanForSource(config.NewLogSource("nginx", &config.LogsConfig{Source: "nginx", DropPolicy: config.DropOldestPolicy})))
+	suite.NotEqual(c, suite.p.PipelineChanForSource(config.NewLogSource("nginx", &config.LogsConfig{Source: "nginx"})))
+	suite.Len(suite.p.forwarders, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	suite.Nil(suite.p.Flush(ctx))
+
+	suite.p.Stop()
+	suite.a.Stop()
+	suite.Len(suite.p.forwarders, 0)
+}
+
+func (suite *ProviderTestSuite) TestProviderPipelineChanForSource() {
+	suite.a.Start()
+	suite.p.Start()
+
+	nginx := config.NewLogSource("nginx", &config.LogsConfig{Source: "nginx"})
+	c := suite.p.PipelineChanForSource(nginx)
+	suite.NotNil(c)
+	// the logs of a source always go through the same pipeline.
+	for i := 0; i < 10; i++ {
+		suite.Equal(c, suite.p.PipelineChanForSource(config.NewLogSource("nginx", &config.LogsConfig{Source: "nginx"})))
+	}
+
+	// the sources are spread over the pipelines.
+	chans := make(map[chan *message.Message]bool)
+	for i := 0; i < 100; i++ {
+		source := config.NewLogSource(config.ContainerCollectAll, &config.LogsConfig{Source: fmt.Sprintf("image-%d", i)})
+		chans[suite.p.PipelineChanForSource(source)] = true
+	}
+	suite.Equal(3, len(chans))
+
+	suite.p.Stop()
+	suite.a.Stop()
+	suite.Nil(suite.p.PipelineChanForSource(nginx))
+}
+
 func TestProviderTestSuite(t *testing.T) {
 	suite.Run(t, new(ProviderTestSuite))
 }
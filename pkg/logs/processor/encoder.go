@@ -6,7 +6,9 @@
 package processor
 
 import (
+	"encoding/json"
 	"regexp"
+	"strings"
 	"time"
 	"unicode"
 	"unicode/utf8"
@@ -17,7 +19,8 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util"
 )
 
-// Encoder turns a message into a raw byte array ready to be sent.
+// Encoder turns a message into a raw byte array ready to be sent,
+// the array must not alias the content of the message as it's returned to the buffer pool once encoded.
 type Encoder interface {
 	encode(msg *message.Message, redactedMsg []byte) ([]byte, error)
 }
@@ -28,6 +31,9 @@ var rawEncoder raw
 // Proto is an encoder implementation that writes messages as protocol buffers.
 var protoEncoder proto
 
+// JSON is an encoder implementation that writes messages as json objects.
+var jsonEncoder jsonPayload
+
 // NewEncoder returns an encoder.
 func NewEncoder(useProto bool) Encoder {
 	if useProto {
@@ -36,6 +42,11 @@ func NewEncoder(useProto bool) Encoder {
 	return &rawEncoder
 }
 
+// NewJSONEncoder returns the encoder used to build the payloads sent over HTTP.
+func NewJSONEncoder() Encoder {
+	return &jsonEncoder
+}
+
 var rfc5424Pattern, _ = regexp.Compile("<[0-9]{1,3}>[0-9] ")
 
 type raw struct{}
@@ -57,10 +68,10 @@ func (r *raw) encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
 		extraContent = append(extraContent, ' ')
 
 		// Timestamp
-		extraContent = time.Now().UTC().AppendFormat(extraContent, config.DateFormat)
+		extraContent = timestamp(msg).AppendFormat(extraContent, config.DateFormat)
 		extraContent = append(extraContent, ' ')
 
-		extraContent = append(extraContent, []byte(getHostname())...)
+		extraContent = append(extraContent, []byte(hostname(msg))...)
 		extraContent = append(extraContent, ' ')
 
 		// Service
@@ -75,7 +86,7 @@ func (r *raw) encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
 		extraContent = append(extraContent, []byte(" - - ")...)
 
 		// Tags
-		tagsPayload := msg.Origin.TagsPayload()
+		tagsPayload := msg.TagsPayload()
 		if len(tagsPayload) > 0 {
 			extraContent = append(extraContent, tagsPayload...)
 		} else {
@@ -87,7 +98,7 @@ func (r *raw) encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
 
 	}
 
-	return redactedMsg, nil
+	return append([]byte(nil), redactedMsg...), nil
 }
 
 func (r *raw) isRFC5424Formatted(content []byte) bool {
@@ -107,11 +118,11 @@ func (p *proto) encode(msg *message.Message, redactedMsg []byte) ([]byte, error)
 	return (&pb.Log{
 		Message:   p.toValidUtf8(redactedMsg),
 		Status:    msg.GetStatus(),
-		Timestamp: time.Now().UTC().UnixNano(),
-		Hostname:  getHostname(),
+		Timestamp: timestamp(msg).UnixNano(),
+		Hostname:  hostname(msg),
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
-		Tags:      msg.Origin.Tags(),
+		Tags:      msg.Tags(),
 	}).Marshal()
 }
 
@@ -131,6 +142,72 @@ func (p *proto) toValidUtf8(msg []byte) string {
 	return string(str)
 }
 
+type jsonPayload struct{}
+
+// jsonLog is the representation of a log expected by the HTTP intake.
+type jsonLog struct {
+	Message   string `json:"message"`
+	Status    string `json:"status"`
+	Timestamp int64  `json:"timestamp"`
+	Hostname  string `json:"hostname"`
+	Service   string `json:"service"`
+	Source    string `json:"ddsource"`
+	Tags      string `json:"ddtags"`
+	// Occurrences is only set when the log stands for several identical lines.
+	Occurrences int `json:"occurrences,omitempty"`
+}
+
+func (j *jsonPayload) encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
+	log := jsonLog{
+		Message:   protoEncoder.toValidUtf8(redactedMsg),
+		Status:    msg.GetStatus(),
+		Timestamp: timestamp(msg).UnixNano() / int64(time.Millisecond),
+		Hostname:  hostname(msg),
+		Service:   msg.Origin.Service(),
+		Source:    msg.Origin.Source(),
+		Tags:      strings.Join(msg.Tags(), ","),
+	}
+	if msg.Occurrences > 1 {
+		log.Occurrences = msg.Occurrences
+	}
+	if msg.Structured == nil {
+		return json.Marshal(log)
+	}
+
+	// the attributes of a structured log are sent alongside the standard fields, which take precedence.
+	fields := make(map[string]interface{}, len(msg.Structured.Attributes)+7)
+	for key, value := range msg.Structured.Attributes {
+		fields[key] = value
+	}
+	fields["message"] = protoEncoder.toValidUtf8([]byte(msg.Structured.Message))
+	fields["status"] = log.Status
+	fields["timestamp"] = log.Timestamp
+	fields["hostname"] = log.Hostname
+	fields["service"] = log.Service
+	fields["ddsource"] = log.Source
+	fields["ddtags"] = log.Tags
+	if log.Occurrences > 0 {
+		fields["occurrences"] = log.Occurrences
+	}
+	return json.Marshal(fields)
+}
+
+// timestamp returns the time the message was emitted at when it's known, the current time otherwise.
+func timestamp(msg *message.Message) time.Time {
+	if msg.Structured != nil && !msg.Structured.Timestamp.IsZero() {
+		return msg.Structured.Timestamp.UTC()
+	}
+	return time.Now().UTC()
+}
+
+// hostname returns the host the message was emitted on, the host of the agent unless it was forwarded by another agent.
+func hostname(msg *message.Message) string {
+	if msg.Hostname != "" {
+		return msg.Hostname
+	}
+	return getHostname()
+}
+
 // getHostname returns the hostname for the agent.
 func getHostname() string {
 	// Compute the hostname
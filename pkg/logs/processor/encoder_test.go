@@ -6,6 +6,7 @@
 package processor
 
 import (
+	"encoding/json"
 	"testing"
 
 	"strings"
@@ -21,6 +22,7 @@ import (
 func TestNewEncoder(t *testing.T) {
 	assert.Equal(t, &protoEncoder, NewEncoder(true))
 	assert.Equal(t, &rawEncoder, NewEncoder(false))
+	assert.Equal(t, &jsonEncoder, NewJSONEncoder())
 }
 
 func TestRawEncoder(t *testing.T) {
@@ -107,6 +109,20 @@ func TestRawEncoderEmpty(t *testing.T) {
 
 }
 
+func TestRawEncoderDoesNotAliasTheContent(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	content := []byte("<46>0 the rest of the message")
+	msg := newMessage(content, source, "")
+
+	raw, err := rawEncoder.encode(msg, content)
+	assert.Nil(t, err)
+	assert.Equal(t, "<46>0 the rest of the message", string(raw))
+
+	// the content is returned to the buffer pool once encoded.
+	copy(content, "garbage")
+	assert.Equal(t, "<46>0 the rest of the message", string(raw))
+}
+
 func TestIsRFC5424Formatted(t *testing.T) {
 	assert.False(t, rawEncoder.isRFC5424Formatted([]byte("<- test message ->")))
 	assert.False(t, rawEncoder.isRFC5424Formatted([]byte("- test message ->")))
@@ -196,3 +212,115 @@ func TestProtoEncoderToValidUTF8(t *testing.T) {
 	assert.Equal(t, "a���z", protoEncoder.toValidUtf8([]byte("a\xed\xa0\x80z")))
 	assert.Equal(t, "a����z", protoEncoder.toValidUtf8([]byte("a\xf0\x8f\xbf\xbfz")))
 }
+
+func TestJSONEncoder(t *testing.T) {
+	logsConfig := &config.LogsConfig{
+		Service:        "Service",
+		Source:         "Source",
+		SourceCategory: "SourceCategory",
+		Tags:           []string{"foo:bar", "baz"},
+	}
+
+	source := config.NewLogSource("", logsConfig)
+
+	msg := newMessage([]byte("message"), source, message.StatusError)
+	msg.Origin.SetTags([]string{"a", "b:c"})
+
+	encoded, err := jsonEncoder.encode(msg, []byte("redacted\xfe"))
+	assert.Nil(t, err)
+
+	log := &jsonLog{}
+	err = json.Unmarshal(encoded, log)
+	assert.Nil(t, err)
+
+	assert.NotEmpty(t, log.Hostname)
+	assert.Equal(t, "Service", log.Service)
+	assert.Equal(t, "Source", log.Source)
+	assert.Equal(t, "a,b:c,sourcecategory:SourceCategory,foo:bar,baz", log.Tags)
+	assert.Equal(t, "redacted�", log.Message)
+	assert.Equal(t, message.StatusError, log.Status)
+	assert.NotEmpty(t, log.Timestamp)
+}
+
+func TestEncodersUseTheHostnameOfForwardedLogs(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("message"), source, "")
+	msg.Hostname = "web-1"
+
+	encoded, err := jsonEncoder.encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	log := &jsonLog{}
+	assert.Nil(t, json.Unmarshal(encoded, log))
+	assert.Equal(t, "web-1", log.Hostname)
+
+	encoded, err = protoEncoder.encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	pbLog := &pb.Log{}
+	assert.Nil(t, pbLog.Unmarshal(encoded))
+	assert.Equal(t, "web-1", pbLog.Hostname)
+
+	encoded, err = rawEncoder.encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(encoded), " web-1 ")
+}
+
+func TestJSONEncoderStructured(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Service: "Service", Source: "Source"})
+	msg := newMessage([]byte("message"), source, message.StatusError)
+	msg.Structured = &message.Structured{
+		Message:    "hello",
+		Timestamp:  time.Unix(1551441600, 0),
+		Attributes: map[string]interface{}{"user": "bob", "service": "overridden"},
+	}
+
+	encoded, err := jsonEncoder.encode(msg, []byte(`{"message":"hello"}`))
+	assert.Nil(t, err)
+
+	fields := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(encoded, &fields))
+	assert.Equal(t, "hello", fields["message"])
+	assert.Equal(t, message.StatusError, fields["status"])
+	assert.Equal(t, float64(1551441600000), fields["timestamp"])
+	assert.Equal(t, "Service", fields["service"])
+	assert.Equal(t, "Source", fields["ddsource"])
+	assert.Equal(t, "bob", fields["user"])
+}
+
+func TestJSONEncoderOccurrences(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("message"), source, "")
+
+	// the occurrences are only sent for the deduplicated logs.
+	encoded, err := jsonEncoder.encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(encoded), "occurrences")
+
+	msg.Occurrences = 3
+	encoded, err = jsonEncoder.encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	log := &jsonLog{}
+	assert.Nil(t, json.Unmarshal(encoded, log))
+	assert.Equal(t, 3, log.Occurrences)
+
+	msg.Structured = &message.Structured{Message: "hello", Attributes: map[string]interface{}{"occurrences": "overridden"}}
+	encoded, err = jsonEncoder.encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	fields := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(encoded, &fields))
+	assert.Equal(t, float64(3), fields["occurrences"])
+}
+
+func TestProtoEncoderStructuredTimestamp(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("message"), source, "")
+	msg.Structured = &message.Structured{Timestamp: time.Unix(1551441600, 0)}
+
+	encoded, err := protoEncoder.encode(msg, []byte(`{"ts":1551441600}`))
+	assert.Nil(t, err)
+
+	log := &pb.Log{}
+	assert.Nil(t, log.Unmarshal(encoded))
+	assert.Equal(t, time.Unix(1551441600, 0).UnixNano(), log.Timestamp)
+	// the line is sent as is to keep the attributes.
+	assert.Equal(t, `{"ts":1551441600}`, log.Message)
+}
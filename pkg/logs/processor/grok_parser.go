@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// parseGrok returns the attributes extracted from content by a grok_parser rule along with its status,
+// or nil if the rule does not match content. The attributes that can not be converted to their type are kept as strings.
+func parseGrok(rule *config.ProcessingRule, content []byte) (*message.Structured, string) {
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		return nil, ""
+	}
+	attributes := make(map[string]interface{})
+	for i, capture := range rule.Captures {
+		if capture.Name == "" || match[i] == nil {
+			continue
+		}
+		attributes[capture.Name] = convertCapture(string(match[i]), capture.Type)
+	}
+
+	structured := &message.Structured{
+		Message:    string(content),
+		Attributes: attributes,
+	}
+	return structured, promoteSeverity(attributes)
+}
+
+// convertCapture returns value converted to captureType.
+func convertCapture(value, captureType string) interface{} {
+	switch captureType {
+	case config.CaptureInt:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case config.CaptureFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newGrokRule(t *testing.T, pattern string) *config.ProcessingRule {
+	rules := []*config.ProcessingRule{{Name: "grok", Type: config.GrokParser, Pattern: pattern}}
+	assert.Nil(t, config.CompileProcessingRules(rules))
+	return rules[0]
+}
+
+func TestParseGrok(t *testing.T) {
+	rule := newGrokRule(t, `%{IP:client} "%{WORD:method} %{NOTSPACE:path}" %{INT:status:int} %{NUMBER:duration:float} %{LOGLEVEL:level}`)
+
+	content := []byte(`10.0.0.1 "GET /index.html" 200 0.042 error`)
+	structured, status := parseGrok(rule, content)
+	assert.NotNil(t, structured)
+	assert.Equal(t, message.StatusError, status)
+	assert.Equal(t, string(content), structured.Message)
+	assert.Equal(t, map[string]interface{}{"client": "10.0.0.1", "method": "GET", "path": "/index.html", "status": int64(200), "duration": 0.042}, structured.Attributes)
+
+	structured, status = parseGrok(rule, []byte("hello world"))
+	assert.Nil(t, structured)
+	assert.Equal(t, "", status)
+}
+
+func TestParseGrokKeepsUnconvertibleAttributes(t *testing.T) {
+	rule := newGrokRule(t, `%{NOTSPACE:count:int}`)
+	structured, _ := parseGrok(rule, []byte("many"))
+	assert.Equal(t, map[string]interface{}{"count": "many"}, structured.Attributes)
+}
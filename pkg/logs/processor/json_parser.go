@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// The keys of the standard fields promoted from the JSON logs, by order of precedence.
+var (
+	jsonMessageKeys   = []string{"message", "msg", "log"}
+	jsonTimestampKeys = []string{"timestamp", "@timestamp", "time", "ts"}
+	jsonSeverityKeys  = []string{"status", "severity", "level"}
+)
+
+// severityStatuses maps the usual severity names to the statuses of the messages.
+var severityStatuses = map[string]string{
+	"emerg":         message.StatusEmergency,
+	"emergency":     message.StatusEmergency,
+	"alert":         message.StatusAlert,
+	"crit":          message.StatusCritical,
+	"critical":      message.StatusCritical,
+	"fatal":         message.StatusCritical,
+	"err":           message.StatusError,
+	"error":         message.StatusError,
+	"warn":          message.StatusWarning,
+	"warning":       message.StatusWarning,
+	"notice":        message.StatusNotice,
+	"info":          message.StatusInfo,
+	"informational": message.StatusInfo,
+	"debug":         message.StatusDebug,
+	"trace":         message.StatusDebug,
+}
+
+// parseJSON returns the standard fields and the other attributes of content along with its status,
+// or nil if content is not a JSON object. The fields that can not be promoted are kept as attributes.
+func parseJSON(content []byte) (*message.Structured, string) {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || content[0] != '{' {
+		return nil, ""
+	}
+	var attributes map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// keep the precision of the large integers, e.g. identifiers.
+	decoder.UseNumber()
+	if err := decoder.Decode(&attributes); err != nil {
+		return nil, ""
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		// the object is followed by something else, this is not a JSON log.
+		return nil, ""
+	}
+
+	structured := &message.Structured{
+		Attributes: attributes,
+	}
+	if key, value, found := lookupString(attributes, jsonMessageKeys); found {
+		structured.Message = value
+		delete(attributes, key)
+	}
+	for _, key := range jsonTimestampKeys {
+		if timestamp, ok := parseTimestamp(attributes[key]); ok {
+			structured.Timestamp = timestamp
+			delete(attributes, key)
+			break
+		}
+	}
+	return structured, promoteSeverity(attributes)
+}
+
+// promoteSeverity removes the first of the severity keys holding a known severity from attributes
+// and returns the matching status, or an empty string if there is none.
+func promoteSeverity(attributes map[string]interface{}) string {
+	if key, value, found := lookupString(attributes, jsonSeverityKeys); found {
+		if status, exists := severityStatuses[strings.ToLower(value)]; exists {
+			delete(attributes, key)
+			return status
+		}
+	}
+	return ""
+}
+
+// lookupString returns the first of keys holding a string in attributes along with its value.
+func lookupString(attributes map[string]interface{}, keys []string) (string, string, bool) {
+	for _, key := range keys {
+		if value, ok := attributes[key].(string); ok {
+			return key, value, true
+		}
+	}
+	return "", "", false
+}
+
+// parseTimestamp returns the time represented by value, either an RFC3339 date
+// or a number of seconds or milliseconds since the epoch.
+func parseTimestamp(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		timestamp, err := time.Parse(time.RFC3339Nano, v)
+		return timestamp, err == nil
+	case json.Number:
+		if epoch, err := v.Int64(); err == nil && epoch > 0 {
+			if epoch >= 1e12 {
+				// such a number of seconds is far in the future, these are milliseconds.
+				return time.Unix(0, epoch*int64(time.Millisecond)), true
+			}
+			return time.Unix(epoch, 0), true
+		}
+		if epoch, err := v.Float64(); err == nil && epoch > 0 && epoch < 1e12 {
+			return time.Unix(0, int64(epoch*float64(time.Second))), true
+		}
+	}
+	return time.Time{}, false
+}
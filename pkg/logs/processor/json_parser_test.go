@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseJSONPromotesStandardFields(t *testing.T) {
+	structured, status := parseJSON([]byte(` {"msg":"hello","level":"WARNING","time":"2019-03-01T12:00:00.5Z","user":{"id":12345678901234567890}} `))
+	assert.NotNil(t, structured)
+	assert.Equal(t, message.StatusWarning, status)
+	assert.Equal(t, "hello", structured.Message)
+	assert.Equal(t, time.Date(2019, 3, 1, 12, 0, 0, 500000000, time.UTC), structured.Timestamp.UTC())
+	assert.Equal(t, map[string]interface{}{"user": map[string]interface{}{"id": json.Number("12345678901234567890")}}, structured.Attributes)
+}
+
+func TestParseJSONTimestamps(t *testing.T) {
+	structured, _ := parseJSON([]byte(`{"message":"a","timestamp":1551441600}`))
+	assert.Equal(t, time.Unix(1551441600, 0), structured.Timestamp)
+
+	structured, _ = parseJSON([]byte(`{"message":"a","ts":1551441600123}`))
+	assert.Equal(t, time.Unix(1551441600, 123000000), structured.Timestamp)
+
+	// a timestamp that can not be parsed is kept as an attribute.
+	structured, _ = parseJSON([]byte(`{"message":"a","timestamp":"yesterday"}`))
+	assert.True(t, structured.Timestamp.IsZero())
+	assert.Equal(t, "yesterday", structured.Attributes["timestamp"])
+}
+
+func TestParseJSONKeepsUnknownSeverities(t *testing.T) {
+	structured, status := parseJSON([]byte(`{"message":"a","level":"verbose"}`))
+	assert.Equal(t, "", status)
+	assert.Equal(t, "verbose", structured.Attributes["level"])
+}
+
+func TestParseJSONIgnoresOtherFormats(t *testing.T) {
+	for _, content := range []string{"", "hello world", `["a"]`, `{"message":`, `{"message":"a"} trailing`} {
+		structured, status := parseJSON([]byte(content))
+		assert.Nil(t, structured, content)
+		assert.Equal(t, "", status)
+	}
+}
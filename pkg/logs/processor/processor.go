@@ -6,11 +6,24 @@
 package processor
 
 import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/hook"
+	"github.com/DataDog/datadog-agent/pkg/logs/memory"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sds"
+	"github.com/DataDog/datadog-agent/pkg/logs/tag"
 )
 
 // A Processor updates messages from an inputChan and pushes
@@ -19,23 +32,37 @@ type Processor struct {
 	inputChan       chan *message.Message
 	outputChan      chan *message.Message
 	processingRules []*config.ProcessingRule
+	scanner         *sds.Scanner
 	encoder         Encoder
+	hostTags        tag.Provider
+	diagnostics     diagnostic.MessageReceiver
+	monitor         *memory.Monitor
+	flushChan       chan chan struct{}
+	health          *health.Handle
 	done            chan struct{}
 }
 
-// New returns an initialized Processor.
-func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder) *Processor {
+// New returns an initialized Processor applying the rules of scanner to the content of the messages,
+// scanner can be nil, and attaching the tags of hostTags to the messages, the processed messages are handed to diagnostics before being encoded and the debug messages
+// are dropped while monitor sheds, monitor can be nil.
+func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, scanner *sds.Scanner, encoder Encoder, hostTags tag.Provider, diagnostics diagnostic.MessageReceiver, monitor *memory.Monitor) *Processor {
 	return &Processor{
 		inputChan:       inputChan,
 		outputChan:      outputChan,
 		processingRules: processingRules,
+		scanner:         scanner,
 		encoder:         encoder,
+		hostTags:        hostTags,
+		diagnostics:     diagnostics,
+		monitor:         monitor,
+		flushChan:       make(chan chan struct{}),
 		done:            make(chan struct{}),
 	}
 }
 
-// Start starts the Processor.
+// Start starts the Processor, it reports itself unhealthy when it's blocked on a message.
 func (p *Processor) Start() {
+	p.health = health.Register("logs-processor")
 	go p.run()
 }
 
@@ -46,30 +73,323 @@ func (p *Processor) Stop() {
 	<-p.done
 }
 
+// Flush processes the messages queued in inputChan right away,
+// this call blocks until they are pushed to outputChan or ctx is done.
+func (p *Processor) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case p.flushChan <- flushed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // run starts the processing of the inputChan
 func (p *Processor) run() {
 	defer func() {
+		p.health.Deregister()
 		p.done <- struct{}{}
 	}()
-	for msg := range p.inputChan {
-		metrics.LogsDecoded.Add(1)
-		if shouldProcess, redactedMsg := p.applyRedactingRules(msg); shouldProcess {
-			metrics.LogsProcessed.Add(1)
-
-			// Encode the message to its final format
-			content, err := p.encoder.encode(msg, redactedMsg)
-			if err != nil {
-				log.Error("unable to encode msg ", err)
-				continue
+	for {
+		select {
+		case <-p.health.C:
+		case msg, isOpen := <-p.inputChan:
+			if !isOpen {
+				return
+			}
+			p.process(msg)
+		case flushed := <-p.flushChan:
+			for queued := len(p.inputChan); queued > 0; queued-- {
+				msg, isOpen := <-p.inputChan
+				if !isOpen {
+					return
+				}
+				p.process(msg)
+			}
+			close(flushed)
+		}
+	}
+}
+
+// process processes the message and pushes it to outputChan unless it's filtered out.
+func (p *Processor) process(msg *message.Message) {
+	metrics.LogsDecoded.Add(1)
+	msg.Origin.LogSource.RecordRead(len(msg.Content))
+	if !hook.Run(hook.PostDecode, msg) {
+		msg.Release()
+		return
+	}
+	shouldProcess, redactedMsg := p.applyRedactingRules(msg)
+	if !shouldProcess || !p.applyQuota(msg, redactedMsg) {
+		msg.Release()
+		return
+	}
+	metrics.LogsProcessed.Add(1)
+	// the sensitive data is scanned before the attributes are extracted so that it's never sent.
+	redactedMsg = p.scanner.Scan(redactedMsg)
+	if msg.Structured != nil {
+		// the message of a log structured by its input, e.g. forwarded by another agent, is redacted like its content.
+		msg.Structured.Message = string(redactedMsg)
+	}
+
+	if msg.Origin.LogSource.Config.DetectSeverity {
+		p.applySeverityDetection(msg, redactedMsg)
+	}
+	if msg.Origin.LogSource.Config.ParseJSON {
+		p.applyJSONParsing(msg, redactedMsg)
+	}
+	if msg.Structured == nil && msg.Origin.LogSource.Config.ParseSyslog {
+		p.applySyslogParsing(msg, redactedMsg)
+	}
+	if msg.Structured == nil {
+		p.applyGrokParsing(msg, redactedMsg)
+	}
+	if msg.Origin.LogSource.Config.ExtractTraceContext {
+		p.applyTraceContextExtraction(msg, redactedMsg)
+	}
+	if sourceConfig := msg.Origin.LogSource.Config; sourceConfig.DetectTimestamp || len(sourceConfig.TimestampLayouts) > 0 {
+		p.applyTimestampExtraction(msg, redactedMsg)
+	}
+	if msg.Origin.LogSource.Config.ClockSkewPolicy != "" {
+		p.applyClockSkewPolicy(msg, time.Now())
+	}
+	if !p.applyScripts(msg, redactedMsg) {
+		metrics.LogsFiltered.Add(1)
+		msg.Release()
+		return
+	}
+	if msg.GetStatus() == message.StatusDebug && p.monitor.Shedding() {
+		metrics.LogsShed.Add(1)
+		metrics.SourceLogsShed.Add(msg.Origin.LogSource.Name, 1)
+		msg.Release()
+		return
+	}
+
+	if msg.Hostname == "" {
+		// the logs forwarded by other agents already hold the tags of their host.
+		msg.HostTags = p.hostTags.GetTags()
+	}
+	// the hooks mutate the redacted content, which is the one encoded.
+	msg.Content = redactedMsg
+	if !hook.Run(hook.PostProcess, msg) {
+		msg.Release()
+		return
+	}
+	redactedMsg = msg.Content
+	p.diagnostics.HandleMessage(msg, redactedMsg)
+
+	// Encode the message to its final format
+	content, err := p.encoder.encode(msg, redactedMsg)
+	// the decoded content is not used anymore once encoded
+	msg.Release()
+	if err != nil {
+		log.Error("unable to encode msg ", err)
+		return
+	}
+	msg.Content = content
+	if !hook.Run(hook.PreSend, msg) {
+		return
+	}
+	p.outputChan <- msg
+}
+
+// quotaMessageKey is the key of the status message of the sources which exceeded their quotas.
+const quotaMessageKey = "quota"
+
+// applyQuota returns true if the message fits in the quotas of its source or is part of the sample kept
+// by the sources with the sample quota policy, it warns when the source exceeds its quotas and when they reset.
+func (p *Processor) applyQuota(msg *message.Message, redactedMsg []byte) bool {
+	source := msg.Origin.LogSource
+	allowed, changed := source.ConsumeQuota(len(redactedMsg))
+	if changed && allowed {
+		log.Infof("The quota of the logs source %s reset, its logs are shipped again", source.Name)
+		source.Messages.RemoveMessage(quotaMessageKey)
+	} else if changed {
+		action := "dropped"
+		if source.Config.QuotaPolicy == config.QuotaSamplePolicy {
+			action = fmt.Sprintf("sampled at %v", source.Config.QuotaSampleRate)
+		}
+		var quotas []string
+		if source.Config.MaxBytesPerHour > 0 {
+			quotas = append(quotas, fmt.Sprintf("%d bytes per hour", source.Config.MaxBytesPerHour))
+		}
+		if source.Config.MaxBytesPerDay > 0 {
+			quotas = append(quotas, fmt.Sprintf("%d bytes per day", source.Config.MaxBytesPerDay))
+		}
+		warning := fmt.Sprintf("The logs source %s exceeded its quota of %s, its logs are %s until it resets", source.Name, strings.Join(quotas, " and "), action)
+		log.Warn(warning)
+		source.Messages.AddMessage(quotaMessageKey, warning)
+	}
+	if allowed {
+		return true
+	}
+	if source.Config.QuotaPolicy == config.QuotaSamplePolicy && isSampled(redactedMsg, source.Config.QuotaSampleRate) {
+		return true
+	}
+	metrics.SourceLogsOverQuota.Add(source.Name, 1)
+	return false
+}
+
+// applySeverityDetection sets the status of the message from the severity found in it, if any,
+// the parsers applied afterwards can override it with the status of their standard fields.
+func (p *Processor) applySeverityDetection(msg *message.Message, redactedMsg []byte) {
+	if status := detectSeverity(redactedMsg, msg.Origin.LogSource.Config.SeverityMapping); status != "" {
+		msg.SetStatus(status)
+	}
+}
+
+// applyJSONParsing promotes the standard fields of the message when it's a JSON object,
+// the other messages are left untouched.
+func (p *Processor) applyJSONParsing(msg *message.Message, redactedMsg []byte) {
+	structured, status := parseJSON(redactedMsg)
+	if structured == nil {
+		return
+	}
+	msg.Structured = structured
+	if status != "" {
+		msg.SetStatus(status)
+	}
+}
+
+// applySyslogParsing promotes the message, the timestamp and the severity of the message when it's
+// an RFC5424 or an RFC3164 syslog message and keeps its other header fields as attributes.
+func (p *Processor) applySyslogParsing(msg *message.Message, redactedMsg []byte) {
+	structured, status := parseSyslog(redactedMsg, time.Now())
+	if structured == nil {
+		return
+	}
+	msg.Structured = structured
+	msg.SetStatus(status)
+}
+
+// applyGrokParsing extracts the attributes of the message with the first grok_parser rule matching it,
+// the global rules are tried before the ones of the source of the message.
+func (p *Processor) applyGrokParsing(msg *message.Message, redactedMsg []byte) {
+	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+	for _, rule := range rules {
+		if rule.Type != config.GrokParser {
+			continue
+		}
+		if structured, status := parseGrok(rule, redactedMsg); structured != nil {
+			msg.Structured = structured
+			if status != "" {
+				msg.SetStatus(status)
 			}
-			msg.Content = content
-			p.outputChan <- msg
+			return
 		}
 	}
 }
 
+// applyTraceContextExtraction adds the dd.trace_id and dd.span_id attributes correlating the message with
+// its trace when it holds a trace context, unless it already holds them.
+func (p *Processor) applyTraceContextExtraction(msg *message.Message, redactedMsg []byte) {
+	var attributes map[string]interface{}
+	if msg.Structured != nil {
+		attributes = msg.Structured.Attributes
+	}
+	dd, isMap := attributes["dd"].(map[string]interface{})
+	if (attributes["dd"] != nil && !isMap) || dd["trace_id"] != nil {
+		return
+	}
+	traceID, spanID := parseTraceContext(redactedMsg, attributes)
+	if traceID == "" {
+		return
+	}
+	if msg.Structured == nil {
+		msg.Structured = &message.Structured{Message: string(redactedMsg)}
+	}
+	if msg.Structured.Attributes == nil {
+		msg.Structured.Attributes = make(map[string]interface{})
+	}
+	if dd == nil {
+		dd = make(map[string]interface{})
+		msg.Structured.Attributes["dd"] = dd
+	}
+	dd["trace_id"] = traceID
+	if spanID != "" {
+		dd["span_id"] = spanID
+	}
+}
+
+// applyTimestampExtraction sets the time of the message to the timestamp starting it unless a parser found it,
+// the timestamps without time zone are in the time zone of the agent.
+func (p *Processor) applyTimestampExtraction(msg *message.Message, redactedMsg []byte) {
+	if msg.Structured != nil && !msg.Structured.Timestamp.IsZero() {
+		return
+	}
+	sourceConfig := msg.Origin.LogSource.Config
+	timestamp, found := parseTimestampPrefix(redactedMsg, sourceConfig.TimestampLayouts, sourceConfig.DetectTimestamp, time.Now(), time.Local)
+	if !found {
+		return
+	}
+	if msg.Structured == nil {
+		msg.Structured = &message.Structured{Message: string(redactedMsg)}
+	}
+	msg.Structured.Timestamp = timestamp
+}
+
+// defaultMaxClockSkew is the time a timestamp can be away from the clock of the agent when max_clock_skew is not set.
+const defaultMaxClockSkew = 5 * time.Minute
+
+// applyClockSkewPolicy adds the clock_skew attribute, in seconds, to the message when its time is too far from now,
+// the time is dropped to use the one of the agent instead with the correct policy.
+func (p *Processor) applyClockSkewPolicy(msg *message.Message, now time.Time) {
+	if msg.Structured == nil || msg.Structured.Timestamp.IsZero() {
+		return
+	}
+	sourceConfig := msg.Origin.LogSource.Config
+	maxSkew := time.Duration(sourceConfig.MaxClockSkew) * time.Second
+	if maxSkew == 0 {
+		maxSkew = defaultMaxClockSkew
+	}
+	skew := msg.Structured.Timestamp.Sub(now)
+	if skew <= maxSkew && skew >= -maxSkew {
+		return
+	}
+	if msg.Structured.Attributes == nil {
+		msg.Structured.Attributes = make(map[string]interface{})
+	}
+	msg.Structured.Attributes["clock_skew"] = int64(skew / time.Second)
+	if sourceConfig.ClockSkewPolicy == config.ClockSkewCorrectPolicy {
+		msg.Structured.Timestamp = time.Time{}
+	}
+}
+
+// applyScripts applies the script rules to the message once it's parsed and returns false if one dropped it,
+// the global rules are applied before the ones of the source of the message.
+// The remaining steps are skipped once the scripts ran for MaxScriptDuration on the message.
+func (p *Processor) applyScripts(msg *message.Message, redactedMsg []byte) bool {
+	return p.applyScriptsUntil(msg, redactedMsg, time.Now().Add(config.MaxScriptDuration))
+}
+
+// applyScriptsUntil applies the script rules to the message until the deadline.
+func (p *Processor) applyScriptsUntil(msg *message.Message, redactedMsg []byte, deadline time.Time) bool {
+	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+	for _, rule := range rules {
+		if rule.Type != config.Script {
+			continue
+		}
+		keep, completed := runScript(rule.Steps, msg, redactedMsg, deadline)
+		if !keep {
+			return false
+		}
+		if !completed {
+			metrics.ScriptTimeouts.Add(1)
+			return true
+		}
+	}
+	return true
+}
+
 // applyRedactingRules returns given a message if we should process it or not,
-// and a copy of the message with some fields redacted, depending on config
+// and a copy of the message with some fields redacted, depending on config,
+// the global rules are applied before the ones of the source of the message.
 func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 	content := msg.Content
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
@@ -77,10 +397,17 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 		switch rule.Type {
 		case config.ExcludeAtMatch:
 			if rule.Regex.Match(content) {
+				metrics.LogsFiltered.Add(1)
 				return false, nil
 			}
 		case config.IncludeAtMatch:
 			if !rule.Regex.Match(content) {
+				metrics.LogsFiltered.Add(1)
+				return false, nil
+			}
+		case config.SampleAtMatch:
+			if rule.Regex.Match(content) && !isSampled(content, rule.SampleRate) {
+				metrics.LogsFiltered.Add(1)
 				return false, nil
 			}
 		case config.MaskSequences:
@@ -89,3 +416,18 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 	}
 	return true, content
 }
+
+// isSampled returns true if content is part of the sample of the lines kept at rate,
+// the decision only depends on content so that it's the same across restarts and agents.
+func isSampled(content []byte, rate float64) bool {
+	hash := fnv.New64a()
+	hash.Write(content)
+	// the lines often differ by their last characters only, mix the bits of the hash to spread them uniformly.
+	sum := hash.Sum64()
+	sum ^= sum >> 33
+	sum *= 0xff51afd7ed558ccd
+	sum ^= sum >> 33
+	sum *= 0xc4ceb9fe1a85ec53
+	sum ^= sum >> 33
+	return float64(sum) < rate*math.MaxUint64
+}
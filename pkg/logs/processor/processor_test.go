@@ -6,11 +6,22 @@
 package processor
 
 import (
+	"context"
+	"expvar"
+	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/hook"
+	"github.com/DataDog/datadog-agent/pkg/logs/memory"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sds"
+	"github.com/DataDog/datadog-agent/pkg/logs/tag"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -144,3 +155,420 @@ func newMessage(content []byte, source *config.LogSource, status string) *messag
 	origin := message.NewOrigin(source)
 	return message.NewMessage(content, origin, status)
 }
+
+func TestJSONParsing(t *testing.T) {
+	p := &Processor{}
+
+	source := config.NewLogSource("", &config.LogsConfig{ParseJSON: true})
+	msg := newMessage([]byte(`{"message":"hello","level":"error","user":"bob"}`), source, "")
+	p.applyJSONParsing(msg, msg.Content)
+	assert.Equal(t, "hello", msg.Structured.Message)
+	assert.Equal(t, map[string]interface{}{"user": "bob"}, msg.Structured.Attributes)
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+
+	msg = newMessage([]byte("hello"), source, message.StatusWarning)
+	p.applyJSONParsing(msg, msg.Content)
+	assert.Nil(t, msg.Structured)
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+}
+
+func TestProcessorParsesJSONOnlyWhenEnabled(t *testing.T) {
+	inputChan := make(chan *message.Message, 2)
+	outputChan := make(chan *message.Message, 2)
+	p := New(inputChan, outputChan, nil, nil, NewJSONEncoder(), tag.NoopProvider, diagnostic.NoopMessageReceiver, nil)
+	p.Start()
+
+	content := []byte(`{"message":"hello"}`)
+	inputChan <- newMessage(content, config.NewLogSource("", &config.LogsConfig{ParseJSON: true}), "")
+	inputChan <- newMessage(content, config.NewLogSource("", &config.LogsConfig{}), "")
+	assert.NotNil(t, (<-outputChan).Structured)
+	assert.Nil(t, (<-outputChan).Structured)
+
+	p.Stop()
+}
+
+func TestProcessorFlushProcessesQueuedMessages(t *testing.T) {
+	inputChan := make(chan *message.Message, 3)
+	outputChan := make(chan *message.Message, 3)
+	p := New(inputChan, outputChan, nil, nil, NewJSONEncoder(), tag.NoopProvider, diagnostic.NoopMessageReceiver, nil)
+	source := config.NewLogSource("", &config.LogsConfig{})
+	for i := 0; i < 3; i++ {
+		inputChan <- newMessage([]byte("hello"), source, "")
+	}
+
+	// the processor is not running yet
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, p.Flush(ctx))
+
+	p.Start()
+	defer p.Stop()
+	assert.NoError(t, p.Flush(context.Background()))
+	assert.Len(t, outputChan, 3)
+}
+
+func TestProcessorAccountsTheLogsReadBySource(t *testing.T) {
+	inputChan := make(chan *message.Message, 2)
+	outputChan := make(chan *message.Message, 2)
+	p := New(inputChan, outputChan, []*config.ProcessingRule{newProcessingRule("exclude_at_match", "", "debug")}, nil, NewJSONEncoder(), tag.NoopProvider, diagnostic.NoopMessageReceiver, nil)
+	p.Start()
+	defer p.Stop()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+	inputChan <- newMessage([]byte("hello"), source, "")
+	inputChan <- newMessage([]byte("debug"), source, "")
+	assert.NoError(t, p.Flush(context.Background()))
+
+	// the filtered out logs were read as well
+	assert.Equal(t, int64(10), source.GetBytesRead())
+	assert.Equal(t, int64(2), source.GetLinesRead())
+}
+
+func TestProcessorReportsItsHealth(t *testing.T) {
+	inputChan := make(chan *message.Message)
+	outputChan := make(chan *message.Message)
+	p := New(inputChan, outputChan, nil, nil, NewJSONEncoder(), tag.NoopProvider, diagnostic.NoopMessageReceiver, nil)
+	p.Start()
+
+	// the health checks are acknowledged while the processor is running
+	status := health.GetStatus()
+	assert.Contains(t, append(status.Healthy, status.Unhealthy...), "logs-processor")
+	for i := 0; len(p.health.C) > 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Len(t, p.health.C, 0)
+
+	p.Stop()
+	status = health.GetStatus()
+	assert.NotContains(t, append(status.Healthy, status.Unhealthy...), "logs-processor")
+}
+
+func TestSyslogParsing(t *testing.T) {
+	p := &Processor{}
+
+	source := config.NewLogSource("", &config.LogsConfig{ParseSyslog: true})
+	msg := newMessage([]byte("<11>1 - host app - - - hello"), source, message.StatusInfo)
+	p.applySyslogParsing(msg, msg.Content)
+	assert.Equal(t, "hello", msg.Structured.Message)
+	assert.Equal(t, "app", msg.Structured.Attributes["syslog"].(map[string]interface{})["appname"])
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+
+	msg = newMessage([]byte("hello"), source, message.StatusWarning)
+	p.applySyslogParsing(msg, msg.Content)
+	assert.Nil(t, msg.Structured)
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+}
+
+func TestProcessorParsesSyslogOnlyWhenEnabled(t *testing.T) {
+	inputChan := make(chan *message.Message, 2)
+	outputChan := make(chan *message.Message, 2)
+	p := New(inputChan, outputChan, nil, nil, NewJSONEncoder(), tag.NoopProvider, diagnostic.NoopMessageReceiver, nil)
+	p.Start()
+
+	content := []byte("<11>1 - - - - - - hello")
+	inputChan <- newMessage(content, config.NewLogSource("", &config.LogsConfig{ParseSyslog: true}), "")
+	inputChan <- newMessage(content, config.NewLogSource("", &config.LogsConfig{}), "")
+	assert.NotNil(t, (<-outputChan).Structured)
+	assert.Nil(t, (<-outputChan).Structured)
+
+	p.Stop()
+}
+
+func TestFilteredLogsAreCounted(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{newProcessingRule("exclude_at_match", "", "debug")}}
+	source := newSource("include_at_match", "", "user")
+	filtered := metrics.LogsFiltered.Value()
+
+	shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("debug: user created"), &source, ""))
+	assert.False(t, shouldProcess)
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("info: disk full"), &source, ""))
+	assert.False(t, shouldProcess)
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("info: user created"), &source, ""))
+	assert.True(t, shouldProcess)
+
+	assert.Equal(t, filtered+2, metrics.LogsFiltered.Value())
+}
+
+func TestGrokParsing(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{newGrokRule(t, "%{WORD:method} %{NOTSPACE:path}")}}
+	source := config.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{newGrokRule(t, "%{LOGLEVEL:level} %{GREEDYDATA:text}")}})
+
+	msg := newMessage([]byte("GET /index.html"), source, "")
+	p.applyGrokParsing(msg, msg.Content)
+	assert.Equal(t, map[string]interface{}{"method": "GET", "path": "/index.html"}, msg.Structured.Attributes)
+
+	msg = newMessage([]byte("warn disk almost full"), source, message.StatusInfo)
+	p.applyGrokParsing(msg, msg.Content)
+	assert.Equal(t, map[string]interface{}{"text": "disk almost full"}, msg.Structured.Attributes)
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+
+	msg = newMessage([]byte("something else entirely"), source, message.StatusInfo)
+	p.applyGrokParsing(msg, msg.Content)
+	assert.Nil(t, msg.Structured)
+}
+
+func TestProcessorAttachesHostTags(t *testing.T) {
+	inputChan := make(chan *message.Message, 1)
+	outputChan := make(chan *message.Message, 1)
+	p := New(inputChan, outputChan, nil, nil, NewJSONEncoder(), &hostTagsProvider{tags: []string{"env:prod"}}, diagnostic.NoopMessageReceiver, nil)
+	p.Start()
+
+	inputChan <- newMessage([]byte("hello"), config.NewLogSource("", &config.LogsConfig{Tags: []string{"team:logs"}}), "")
+	msg := <-outputChan
+	assert.Equal(t, []string{"env:prod"}, msg.HostTags)
+	assert.Contains(t, string(msg.Content), `"ddtags":"team:logs,env:prod"`)
+
+	p.Stop()
+}
+
+func TestProcessorHandlesForwardedLogs(t *testing.T) {
+	inputChan := make(chan *message.Message, 1)
+	outputChan := make(chan *message.Message, 1)
+	p := New(inputChan, outputChan, nil, nil, NewJSONEncoder(), &hostTagsProvider{tags: []string{"env:prod"}}, diagnostic.NoopMessageReceiver, nil)
+	p.Start()
+
+	source := config.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{newProcessingRule("mask_sequences", "[masked_world]", "world")}})
+	msg := newMessage([]byte("hello world"), source, "")
+	msg.Hostname = "web-1"
+	msg.Structured = &message.Structured{Message: "hello world", Attributes: map[string]interface{}{"user": "bob"}}
+	inputChan <- msg
+	msg = <-outputChan
+	// the forwarded logs hold the tags of their own host and their structured message is redacted.
+	assert.Empty(t, msg.HostTags)
+	assert.Contains(t, string(msg.Content), `"message":"hello [masked_world]"`)
+	assert.Contains(t, string(msg.Content), `"hostname":"web-1"`)
+	assert.Contains(t, string(msg.Content), `"user":"bob"`)
+
+	p.Stop()
+}
+
+func TestProcessorScansTheSensitiveData(t *testing.T) {
+	scanner, err := sds.NewScanner([]*sds.Rule{{Name: "card", Pattern: `\d{16}`, Keywords: []string{"card"}, Action: sds.PartialRedactAction, Characters: 12, Direction: sds.FirstCharacters}})
+	assert.NoError(t, err)
+	inputChan := make(chan *message.Message, 1)
+	outputChan := make(chan *message.Message, 1)
+	p := New(inputChan, outputChan, nil, scanner, NewJSONEncoder(), tag.NoopProvider, diagnostic.NoopMessageReceiver, nil)
+	p.Start()
+
+	source := config.NewLogSource("", &config.LogsConfig{ParseJSON: true})
+	inputChan <- newMessage([]byte(`{"msg":"paid","card":"4111111111111111","order":"1234567890123456"}`), source, "")
+	msg := <-outputChan
+	// the attributes are extracted from the scanned content.
+	assert.Contains(t, string(msg.Content), `"card":"************1111"`)
+	assert.Contains(t, string(msg.Content), `"order":"1234567890123456"`)
+	assert.NotContains(t, string(msg.Content), "4111111111111111")
+
+	p.Stop()
+}
+
+func TestProcessorRunsTheHooks(t *testing.T) {
+	inputChan := make(chan *message.Message, 2)
+	outputChan := make(chan *message.Message, 2)
+	p := New(inputChan, outputChan, nil, nil, NewJSONEncoder(), tag.NoopProvider, diagnostic.NoopMessageReceiver, nil)
+	p.Start()
+	defer p.Stop()
+
+	assert.NoError(t, hook.Register(hook.PostDecode, "drop", func(msg *message.Message) bool {
+		return string(msg.Content) != "drop me"
+	}))
+	defer hook.Unregister(hook.PostDecode, "drop")
+	assert.NoError(t, hook.Register(hook.PostProcess, "mutate", func(msg *message.Message) bool {
+		msg.Content = append([]byte("[hooked] "), msg.Content...)
+		return true
+	}))
+	defer hook.Unregister(hook.PostProcess, "mutate")
+	var sent []string
+	assert.NoError(t, hook.Register(hook.PreSend, "observe", func(msg *message.Message) bool {
+		sent = append(sent, string(msg.Content))
+		return true
+	}))
+	defer hook.Unregister(hook.PreSend, "observe")
+
+	source := config.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{newProcessingRule("mask_sequences", "[masked_world]", "world")}})
+	inputChan <- newMessage([]byte("drop me"), source, "")
+	inputChan <- newMessage([]byte("hello world"), source, "")
+	msg := <-outputChan
+	assert.Contains(t, string(msg.Content), `"message":"[hooked] hello [masked_world]"`)
+	assert.Equal(t, []string{string(msg.Content)}, sent)
+}
+
+func TestProcessorShedsTheDebugLogsUnderMemoryPressure(t *testing.T) {
+	monitor := memory.NewMonitor(1, 0)
+	monitor.Start()
+	monitor.Check()
+	inputChan := make(chan *message.Message, 2)
+	outputChan := make(chan *message.Message, 2)
+	p := New(inputChan, outputChan, nil, nil, NewJSONEncoder(), tag.NoopProvider, diagnostic.NoopMessageReceiver, monitor)
+	p.Start()
+
+	source := config.NewLogSource("shed", &config.LogsConfig{})
+	inputChan <- newMessage([]byte("debug"), source, message.StatusDebug)
+	inputChan <- newMessage([]byte("info"), source, message.StatusInfo)
+	msg := <-outputChan
+	assert.Contains(t, string(msg.Content), `"message":"info"`)
+	assert.Equal(t, int64(1), metrics.SourceLogsShed.Get("shed").(*expvar.Int).Value())
+
+	p.Stop()
+	monitor.Stop()
+	assert.False(t, monitor.Shedding())
+}
+
+// hostTagsProvider provides a fixed list of tags.
+type hostTagsProvider struct {
+	tags []string
+}
+
+func (p *hostTagsProvider) GetTags() []string { return p.tags }
+func (p *hostTagsProvider) Start()            {}
+func (p *hostTagsProvider) Stop()             {}
+
+func TestSampling(t *testing.T) {
+	p := &Processor{}
+	source := config.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{{Type: config.SampleAtMatch, SampleRate: 0.1, Regex: regexp.MustCompile("DEBUG")}}})
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		content := []byte(fmt.Sprintf("DEBUG request %d", i))
+		shouldProcess, _ := p.applyRedactingRules(newMessage(content, source, ""))
+		if shouldProcess {
+			kept++
+		}
+		// the decision is the same for the same line.
+		shouldProcessAgain, _ := p.applyRedactingRules(newMessage(content, source, ""))
+		assert.Equal(t, shouldProcess, shouldProcessAgain)
+	}
+	assert.InDelta(t, 1000, kept, 100)
+
+	// the lines not matching the pattern are all kept.
+	for i := 0; i < 100; i++ {
+		shouldProcess, _ := p.applyRedactingRules(newMessage([]byte(fmt.Sprintf("INFO request %d", i)), source, ""))
+		assert.True(t, shouldProcess)
+	}
+}
+
+func TestProcessorDropsTheLogsOverQuota(t *testing.T) {
+	inputChan := make(chan *message.Message, 10)
+	outputChan := make(chan *message.Message, 10)
+	p := New(inputChan, outputChan, nil, nil, NewJSONEncoder(), tag.NoopProvider, diagnostic.NoopMessageReceiver, nil)
+	p.Start()
+	defer p.Stop()
+
+	source := config.NewLogSource("over_quota", &config.LogsConfig{MaxBytesPerHour: 10})
+	for i := 0; i < 4; i++ {
+		inputChan <- newMessage([]byte("hello"), source, "")
+	}
+	assert.NoError(t, p.Flush(context.Background()))
+
+	assert.Len(t, outputChan, 2)
+	assert.Equal(t, int64(2), metrics.SourceLogsOverQuota.Get("over_quota").(*expvar.Int).Value())
+	assert.Len(t, source.Messages.GetMessages(), 1)
+	// the logs over quota were read as well
+	assert.Equal(t, int64(4), source.GetLinesRead())
+}
+
+func TestQuotaSampling(t *testing.T) {
+	p := &Processor{}
+	source := config.NewLogSource("", &config.LogsConfig{MaxBytesPerDay: 1, QuotaPolicy: config.QuotaSamplePolicy, QuotaSampleRate: 0.1})
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		content := []byte(fmt.Sprintf("request %d", i))
+		if p.applyQuota(newMessage(content, source, ""), content) {
+			kept++
+		}
+	}
+	assert.InDelta(t, 1000, kept, 100)
+}
+
+func TestProcessorDetectsSeverityOnlyWhenEnabled(t *testing.T) {
+	p := &Processor{encoder: &jsonEncoder, hostTags: tag.NoopProvider, diagnostics: diagnostic.NoopMessageReceiver, outputChan: make(chan *message.Message, 3)}
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+	p.process(newMessage([]byte("12:00:00 ERROR disk full"), source, ""))
+	assert.Equal(t, message.StatusInfo, (<-p.outputChan).GetStatus())
+
+	source = config.NewLogSource("", &config.LogsConfig{DetectSeverity: true})
+	p.process(newMessage([]byte("12:00:00 ERROR disk full"), source, ""))
+	assert.Equal(t, message.StatusError, (<-p.outputChan).GetStatus())
+
+	// the status of the parsers takes precedence.
+	source = config.NewLogSource("", &config.LogsConfig{DetectSeverity: true, ParseJSON: true})
+	p.process(newMessage([]byte(`{"message":"ERROR disk full","level":"debug"}`), source, ""))
+	assert.Equal(t, message.StatusDebug, (<-p.outputChan).GetStatus())
+}
+
+func TestTimestampExtraction(t *testing.T) {
+	p := &Processor{}
+
+	source := config.NewLogSource("", &config.LogsConfig{DetectTimestamp: true})
+	msg := newMessage([]byte("2019-03-01T12:00:00Z hello"), source, "")
+	p.applyTimestampExtraction(msg, msg.Content)
+	assert.Equal(t, "2019-03-01T12:00:00Z hello", msg.Structured.Message)
+	assert.Equal(t, time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC), msg.Structured.Timestamp.UTC())
+
+	// the timestamp found by a parser takes precedence.
+	msg = newMessage([]byte("2019-03-01T12:00:00Z hello"), source, "")
+	msg.Structured = &message.Structured{Message: "hello", Timestamp: time.Unix(1551441600, 0)}
+	p.applyTimestampExtraction(msg, msg.Content)
+	assert.Equal(t, time.Unix(1551441600, 0), msg.Structured.Timestamp)
+
+	msg = newMessage([]byte("hello"), source, "")
+	p.applyTimestampExtraction(msg, msg.Content)
+	assert.Nil(t, msg.Structured)
+}
+
+func TestTraceContextExtraction(t *testing.T) {
+	p := &Processor{}
+
+	source := config.NewLogSource("", &config.LogsConfig{ExtractTraceContext: true})
+	msg := newMessage([]byte("hello dd.trace_id=1234 dd.span_id=5678"), source, "")
+	p.applyTraceContextExtraction(msg, msg.Content)
+	assert.Equal(t, "hello dd.trace_id=1234 dd.span_id=5678", msg.Structured.Message)
+	assert.Equal(t, map[string]interface{}{"trace_id": "1234", "span_id": "5678"}, msg.Structured.Attributes["dd"])
+
+	// the trace context is merged with the other dd attributes.
+	msg = newMessage([]byte(`{"message":"hello","traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`), source, "")
+	msg.Structured = &message.Structured{Message: "hello", Attributes: map[string]interface{}{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"dd":          map[string]interface{}{"env": "prod"},
+	}}
+	p.applyTraceContextExtraction(msg, msg.Content)
+	assert.Equal(t, map[string]interface{}{"env": "prod", "trace_id": "11803532876627986230", "span_id": "67667974448284343"}, msg.Structured.Attributes["dd"])
+
+	// the trace context already promoted by the tracer is kept.
+	msg = newMessage([]byte("hello dd.trace_id=1234"), source, "")
+	msg.Structured = &message.Structured{Message: "hello", Attributes: map[string]interface{}{
+		"dd": map[string]interface{}{"trace_id": "42"},
+	}}
+	p.applyTraceContextExtraction(msg, msg.Content)
+	assert.Equal(t, map[string]interface{}{"trace_id": "42"}, msg.Structured.Attributes["dd"])
+
+	msg = newMessage([]byte("hello"), source, "")
+	p.applyTraceContextExtraction(msg, msg.Content)
+	assert.Nil(t, msg.Structured)
+}
+
+func TestClockSkewPolicy(t *testing.T) {
+	p := &Processor{}
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	source := config.NewLogSource("", &config.LogsConfig{ClockSkewPolicy: config.ClockSkewFlagPolicy})
+	msg := newMessage([]byte("hello"), source, "")
+	msg.Structured = &message.Structured{Timestamp: now.Add(-4 * time.Minute)}
+	p.applyClockSkewPolicy(msg, now)
+	assert.Nil(t, msg.Structured.Attributes)
+
+	msg.Structured = &message.Structured{Timestamp: now.Add(-time.Hour)}
+	p.applyClockSkewPolicy(msg, now)
+	assert.Equal(t, int64(-3600), msg.Structured.Attributes["clock_skew"])
+	assert.Equal(t, now.Add(-time.Hour), msg.Structured.Timestamp)
+
+	source = config.NewLogSource("", &config.LogsConfig{ClockSkewPolicy: config.ClockSkewCorrectPolicy, MaxClockSkew: 60})
+	msg = newMessage([]byte("hello"), source, "")
+	msg.Structured = &message.Structured{Timestamp: now.Add(2 * time.Minute), Attributes: map[string]interface{}{"user": "bob"}}
+	p.applyClockSkewPolicy(msg, now)
+	assert.Equal(t, int64(120), msg.Structured.Attributes["clock_skew"])
+	assert.Equal(t, "bob", msg.Structured.Attributes["user"])
+	// the log is sent with the time of the agent.
+	assert.True(t, msg.Structured.Timestamp.IsZero())
+}
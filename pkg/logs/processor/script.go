@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/Knetic/govaluate.v3"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// The fields of the logs the scripts refer to on top of their attributes.
+const (
+	scriptMessageField = "message"
+	scriptStatusField  = "status"
+)
+
+// scriptParameters exposes the message, the status and the attributes of a log to the expressions of the scripts.
+type scriptParameters struct {
+	msg         *message.Message
+	redactedMsg []byte
+}
+
+// Get returns the value of the field name of the log, the JSON numbers are turned into floats to be compared.
+func (p scriptParameters) Get(name string) (interface{}, error) {
+	switch name {
+	case scriptMessageField:
+		if p.msg.Structured != nil {
+			return p.msg.Structured.Message, nil
+		}
+		return string(p.redactedMsg), nil
+	case scriptStatusField:
+		return p.msg.GetStatus(), nil
+	}
+	if p.msg.Structured != nil {
+		if value, exists := p.msg.Structured.Attributes[name]; exists {
+			if number, ok := value.(json.Number); ok {
+				if f, err := number.Float64(); err == nil {
+					return f, nil
+				}
+				return number.String(), nil
+			}
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("no field %s", name)
+}
+
+// runScript applies the steps of script to the message and returns false if a drop step matched it,
+// completed is false if the deadline elapsed before all the steps were applied.
+// The steps with an expression failing on the message, e.g. referring to a field it does not hold,
+// or concatenating strings longer than MaxScriptValueLength, are skipped.
+func runScript(steps []*config.ScriptStep, msg *message.Message, redactedMsg []byte, deadline time.Time) (keep bool, completed bool) {
+	parameters := scriptParameters{msg: msg, redactedMsg: redactedMsg}
+	for _, step := range steps {
+		if time.Now().After(deadline) {
+			return true, false
+		}
+		if step.Condition != nil {
+			if !withinValueLength(step.Condition, parameters) {
+				continue
+			}
+			if matches, err := step.Condition.Eval(parameters); err != nil || matches != true {
+				continue
+			}
+		}
+		switch step.Action {
+		case config.DropAction:
+			return false, true
+		case config.SetAction:
+			if !withinValueLength(step.Expression, parameters) {
+				continue
+			}
+			value, err := step.Expression.Eval(parameters)
+			if err != nil {
+				continue
+			}
+			if s, ok := value.(string); ok && len(s) > config.MaxScriptValueLength {
+				continue
+			}
+			setField(msg, redactedMsg, step.Field, value)
+		case config.RenameAction:
+			if msg.Structured == nil {
+				continue
+			}
+			if value, exists := msg.Structured.Attributes[step.Field]; exists {
+				delete(msg.Structured.Attributes, step.Field)
+				msg.Structured.Attributes[step.To] = value
+			}
+		case config.DeleteAction:
+			if msg.Structured != nil {
+				delete(msg.Structured.Attributes, step.Field)
+			}
+		}
+	}
+	return true, true
+}
+
+// withinValueLength returns false if the expression concatenates strings and the literals and the fields
+// of the log it refers to are longer than MaxScriptValueLength altogether, so that it's not evaluated,
+// they are each used at most once since the expressions have no loops.
+func withinValueLength(expression *govaluate.EvaluableExpression, parameters scriptParameters) bool {
+	tokens := expression.Tokens()
+	concatenates := false
+	for _, token := range tokens {
+		if token.Kind == govaluate.MODIFIER && token.Value == "+" {
+			concatenates = true
+			break
+		}
+	}
+	if !concatenates {
+		return true
+	}
+	length := 0
+	for _, token := range tokens {
+		switch token.Kind {
+		case govaluate.STRING:
+			length += len(token.Value.(string))
+		case govaluate.NUMERIC, govaluate.BOOLEAN:
+			length += len(fmt.Sprint(token.Value))
+		case govaluate.VARIABLE:
+			name, _ := token.Value.(string)
+			value, err := parameters.Get(name)
+			if err != nil {
+				// the expression fails on the log.
+				continue
+			}
+			if s, ok := value.(string); ok {
+				length += len(s)
+			} else {
+				length += len(fmt.Sprint(value))
+			}
+		}
+		if length > config.MaxScriptValueLength {
+			return false
+		}
+	}
+	return true
+}
+
+// setField sets the message, the status or an attribute of the message to value,
+// the status is only set to the usual severity names.
+func setField(msg *message.Message, redactedMsg []byte, field string, value interface{}) {
+	if field == scriptStatusField {
+		if status, exists := severityStatuses[strings.ToLower(fmt.Sprint(value))]; exists {
+			msg.SetStatus(status)
+		}
+		return
+	}
+	if msg.Structured == nil {
+		msg.Structured = &message.Structured{Message: string(redactedMsg)}
+	}
+	if field == scriptMessageField {
+		msg.Structured.Message = fmt.Sprint(value)
+		return
+	}
+	if msg.Structured.Attributes == nil {
+		msg.Structured.Attributes = make(map[string]interface{})
+	}
+	msg.Structured.Attributes[field] = value
+}
@@ -0,0 +1,151 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+func newScriptRule(t *testing.T, steps ...*config.ScriptStep) *config.ProcessingRule {
+	rule := &config.ProcessingRule{Name: "script", Type: config.Script, Steps: steps}
+	assert.Nil(t, config.ValidateProcessingRules([]*config.ProcessingRule{rule}))
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	return rule
+}
+
+// run applies the steps to the message without deadline and returns false if it's dropped.
+func run(t *testing.T, steps []*config.ScriptStep, msg *message.Message) bool {
+	keep, completed := runScript(steps, msg, msg.Content, time.Now().Add(time.Hour))
+	assert.True(t, completed)
+	return keep
+}
+
+func TestRunScript(t *testing.T) {
+	rule := newScriptRule(t,
+		&config.ScriptStep{Action: config.DropAction, If: `status == "debug"`},
+		&config.ScriptStep{Action: config.RenameAction, Field: "usr", To: "user"},
+		&config.ScriptStep{Action: config.SetAction, Field: "duration_s", Value: "[duration.ms] / 1000", If: "[duration.ms] > 0"},
+		&config.ScriptStep{Action: config.SetAction, Field: "status", Value: `"warn"`, If: "duration_s >= 1"},
+		&config.ScriptStep{Action: config.SetAction, Field: "message", Value: `message + " by " + user`, If: `user != ""`},
+		&config.ScriptStep{Action: config.DeleteAction, Field: "password"},
+	)
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	msg := newMessage([]byte("hello"), source, message.StatusDebug)
+	assert.False(t, run(t, rule.Steps, msg))
+
+	msg = newMessage([]byte(`{"message":"checkout","usr":"bob","duration.ms":1500,"password":"secret"}`), source, message.StatusInfo)
+	msg.Structured = &message.Structured{Message: "checkout", Attributes: map[string]interface{}{
+		"usr":         "bob",
+		"duration.ms": json.Number("1500"),
+		"password":    "secret",
+	}}
+	assert.True(t, run(t, rule.Steps, msg))
+	assert.Equal(t, "checkout by bob", msg.Structured.Message)
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, map[string]interface{}{"user": "bob", "duration.ms": json.Number("1500"), "duration_s": 1.5}, msg.Structured.Attributes)
+
+	// the steps referring to the fields the log does not hold are skipped.
+	msg = newMessage([]byte("hello"), source, message.StatusInfo)
+	assert.True(t, run(t, rule.Steps, msg))
+	assert.Nil(t, msg.Structured)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+}
+
+func TestRunScriptBoundsTheValues(t *testing.T) {
+	rule := newScriptRule(t,
+		&config.ScriptStep{Action: config.SetAction, Field: "copy", Value: "message + message"},
+		&config.ScriptStep{Action: config.SetAction, Field: "status", Value: `"unknown"`},
+	)
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	content := []byte(strings.Repeat("a", config.MaxScriptValueLength/2+1))
+	msg := newMessage(content, source, message.StatusInfo)
+	assert.True(t, run(t, rule.Steps, msg))
+	assert.Nil(t, msg.Structured)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+
+	msg = newMessage([]byte("hello"), source, message.StatusInfo)
+	assert.True(t, run(t, rule.Steps, msg))
+	assert.Equal(t, "hellohello", msg.Structured.Attributes["copy"])
+}
+
+func TestRunScriptBoundsTheConcatenations(t *testing.T) {
+	rule := newScriptRule(t,
+		&config.ScriptStep{Action: config.DropAction, If: `message + message == ""`},
+		&config.ScriptStep{Action: config.SetAction, Field: "prefixed", Value: `"prefix: " + message`},
+		&config.ScriptStep{Action: config.SetAction, Field: "length", Value: `message == "" ? 0 : 1`},
+	)
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	// the expressions concatenating too long strings are not evaluated.
+	content := []byte(strings.Repeat("a", config.MaxScriptValueLength-1))
+	msg := newMessage(content, source, message.StatusInfo)
+	parameters := scriptParameters{msg: msg, redactedMsg: content}
+	assert.False(t, withinValueLength(rule.Steps[0].Condition, parameters))
+	assert.False(t, withinValueLength(rule.Steps[1].Expression, parameters))
+	assert.True(t, withinValueLength(rule.Steps[2].Expression, parameters))
+	assert.True(t, run(t, rule.Steps, msg))
+	assert.Equal(t, map[string]interface{}{"length": 1.0}, msg.Structured.Attributes)
+
+	msg = newMessage([]byte("hello"), source, message.StatusInfo)
+	assert.True(t, run(t, rule.Steps, msg))
+	assert.Equal(t, "prefix: hello", msg.Structured.Attributes["prefixed"])
+}
+
+func TestRunScriptStopsAtTheDeadline(t *testing.T) {
+	rule := newScriptRule(t, &config.ScriptStep{Action: config.SetAction, Field: "team", Value: `"checkout"`})
+	msg := newMessage([]byte("hello"), config.NewLogSource("", &config.LogsConfig{}), message.StatusInfo)
+
+	keep, completed := runScript(rule.Steps, msg, msg.Content, time.Now().Add(-time.Second))
+	assert.True(t, keep)
+	assert.False(t, completed)
+	assert.Nil(t, msg.Structured)
+
+	// the log is kept as is by the processor, the timeout is counted.
+	p := &Processor{processingRules: []*config.ProcessingRule{rule}}
+	timeouts := metrics.ScriptTimeouts.Value()
+	assert.True(t, p.applyScriptsUntil(msg, msg.Content, time.Now().Add(-time.Second)))
+	assert.Nil(t, msg.Structured)
+	assert.Equal(t, timeouts+1, metrics.ScriptTimeouts.Value())
+}
+
+func TestProcessorAppliesTheScripts(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{
+		newScriptRule(t, &config.ScriptStep{Action: config.SetAction, Field: "team", Value: `"checkout"`}),
+	}}
+	source := config.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		newScriptRule(t, &config.ScriptStep{Action: config.DropAction, If: `team == "checkout" && message =~ "^health"`}),
+	}})
+
+	msg := newMessage([]byte("user created"), source, "")
+	assert.True(t, p.applyScripts(msg, msg.Content))
+	assert.Equal(t, "checkout", msg.Structured.Attributes["team"])
+
+	msg = newMessage([]byte("healthcheck"), source, "")
+	assert.False(t, p.applyScripts(msg, msg.Content))
+}
+
+func TestProcessorCountsTheLogsDroppedByTheScripts(t *testing.T) {
+	outputChan := make(chan *message.Message, 1)
+	p := New(nil, outputChan, []*config.ProcessingRule{
+		newScriptRule(t, &config.ScriptStep{Action: config.DropAction, If: `message =~ "^health"`}),
+	}, nil, NewEncoder(false), nil, nil, nil)
+	filtered := metrics.LogsFiltered.Value()
+
+	p.process(newMessage([]byte("healthcheck"), config.NewLogSource("", &config.LogsConfig{}), ""))
+	assert.Equal(t, filtered+1, metrics.LogsFiltered.Value())
+	assert.Len(t, outputChan, 0)
+}
@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// severityScanLength is the length of the beginning of the logs scanned for a severity,
+// the common layouts put it before the message.
+const severityScanLength = 256
+
+// jsonSeverityPattern matches the severity fields of the JSON logs.
+var jsonSeverityPattern = regexp.MustCompile(`"(?:status|severity|level)"\s*:\s*"([A-Za-z]+)"`)
+
+// severityTokenStatuses maps the upper case severity tokens of the common logger layouts, e.g. Python's logging
+// or Log4j and java.util.logging, to the statuses of the messages.
+var severityTokenStatuses = map[string]string{
+	"SEVERE": message.StatusError,
+	"FINE":   message.StatusDebug,
+	"FINER":  message.StatusDebug,
+	"FINEST": message.StatusDebug,
+}
+
+func init() {
+	for name, status := range severityStatuses {
+		severityTokenStatuses[strings.ToUpper(name)] = status
+	}
+}
+
+// detectSeverity returns the status matching the severity of content found in its syslog priority,
+// in a JSON severity field or in a severity token of the common logger layouts, or an empty string if there is none.
+// The usual severity tokens are only recognized in upper case or between brackets, e.g. ERROR or [error],
+// mapping maps upper case severity names to statuses and takes precedence over the usual names in any case.
+func detectSeverity(content []byte, mapping map[string]string) string {
+	if priority, _, ok := parseSyslogPriority(content); ok {
+		return syslogSeverityStatuses[priority%8]
+	}
+	truncated := len(content) > severityScanLength
+	if truncated {
+		content = content[:severityScanLength]
+	}
+	if match := jsonSeverityPattern.FindSubmatch(content); match != nil {
+		return severityStatus(match[1], mapping, true)
+	}
+	for i := 0; i < len(content); {
+		if !isLetter(content[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(content) && isLetter(content[j]) {
+			j++
+		}
+		if j == len(content) && truncated {
+			// the token may go on after the scanned content
+			break
+		}
+		bracketed := i > 0 && content[i-1] == '[' && j < len(content) && content[j] == ']'
+		if status := severityStatus(content[i:j], mapping, bracketed); status != "" {
+			return status
+		}
+		i = j
+	}
+	return ""
+}
+
+// severityStatus returns the status of the severity name looked up in mapping and then in the usual names,
+// which the name only matches in upper case unless anyCase is set, or an empty string if there is none.
+func severityStatus(name []byte, mapping map[string]string, anyCase bool) string {
+	if len(mapping) == 0 && !anyCase {
+		return severityTokenStatuses[string(name)]
+	}
+	upper := bytes.ToUpper(name)
+	if status, exists := mapping[string(upper)]; exists {
+		return status
+	}
+	if anyCase {
+		return severityTokenStatuses[string(upper)]
+	}
+	return severityTokenStatuses[string(name)]
+}
+
+// isLetter returns true if c is an ASCII letter.
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestDetectSeverity(t *testing.T) {
+	for content, status := range map[string]string{
+		"<11>Mar  1 12:00:00 host app: disk full":                               message.StatusError,
+		"<166>1 2019-03-01T12:00:00Z host app - - - hello":                      message.StatusInfo,
+		`{"level":"warning","msg":"disk almost full"}`:                          message.StatusWarning,
+		`{"msg":"hello", "severity" : "Debug"}`:                                 message.StatusDebug,
+		"2019-03-01 12:00:00,123 - app.db - ERROR - connection lost":            message.StatusError,
+		"2019-03-01 12:00:00.123  WARN 4242 --- [main] com.app.Db : slow query": message.StatusWarning,
+		"Mar 01, 2019 12:00:00 PM com.app.Db connect\nSEVERE: connection lost":  message.StatusError,
+		"2019-03-01 12:00:00 CRITICAL app: out of memory":                       message.StatusCritical,
+		"[Fri Mar 01 12:00:00 2019] [error] [client 10.0.0.1] File not found":   message.StatusError,
+		"FATAL: the database system is shutting down":                           message.StatusCritical,
+		// the usual names are only recognized in upper case or between brackets.
+		"no error was found":   "",
+		"ERRORS were found":    "",
+		"the request was slow": "",
+		"":                     "",
+	} {
+		assert.Equal(t, status, detectSeverity([]byte(content), nil), content)
+	}
+}
+
+func TestDetectSeverityOnlyScansTheBeginningOfTheLogs(t *testing.T) {
+	assert.Equal(t, "", detectSeverity([]byte(strings.Repeat("a ", severityScanLength)+"ERROR"), nil))
+	// a token cut by the end of the scanned content is not recognized.
+	assert.Equal(t, "", detectSeverity([]byte(strings.Repeat(" ", severityScanLength-4)+"WARNING"), nil))
+}
+
+func TestDetectSeverityWithMapping(t *testing.T) {
+	mapping := map[string]string{"OOPS": message.StatusError, "WARN": message.StatusNotice}
+
+	assert.Equal(t, message.StatusError, detectSeverity([]byte("12:00:00 oops: disk full"), mapping))
+	assert.Equal(t, message.StatusNotice, detectSeverity([]byte("12:00:00 WARN disk almost full"), mapping))
+	assert.Equal(t, message.StatusNotice, detectSeverity([]byte(`{"level":"warn"}`), mapping))
+	assert.Equal(t, message.StatusError, detectSeverity([]byte("12:00:00 ERROR disk full"), mapping))
+	assert.Equal(t, "", detectSeverity([]byte("12:00:00 error disk full"), mapping))
+}
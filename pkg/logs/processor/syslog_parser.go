@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// syslogNilValue represents an empty field of an RFC5424 message.
+const syslogNilValue = "-"
+
+// utf8BOM may start the message of an RFC5424 message.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// syslogSeverityStatuses maps the severities of the syslog messages to the statuses of the messages.
+var syslogSeverityStatuses = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// parseSyslog returns the message, the timestamp and the header fields of content along with its status
+// when it's an RFC5424 or an RFC3164 syslog message, or nil otherwise.
+// The year of the RFC3164 timestamps is guessed from now.
+func parseSyslog(content []byte, now time.Time) (*message.Structured, string) {
+	priority, rest, ok := parseSyslogPriority(content)
+	if !ok {
+		return nil, ""
+	}
+	attributes := map[string]interface{}{
+		"facility": priority / 8,
+		"severity": priority % 8,
+	}
+	structured := &message.Structured{
+		Attributes: map[string]interface{}{"syslog": attributes},
+	}
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+		parseRFC5424(rest, structured, attributes)
+	} else {
+		parseRFC3164(rest, now, structured, attributes)
+	}
+	return structured, syslogSeverityStatuses[priority%8]
+}
+
+// parseSyslogPriority parses the <PRI> part starting content and returns its value with the rest of content.
+func parseSyslogPriority(content []byte) (int, []byte, bool) {
+	if len(content) < 3 || content[0] != '<' {
+		return 0, nil, false
+	}
+	end := bytes.IndexByte(content, '>')
+	if end < 2 || end > 4 {
+		return 0, nil, false
+	}
+	priority, err := strconv.Atoi(string(content[1:end]))
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, nil, false
+	}
+	return priority, content[end+1:], true
+}
+
+// parseRFC5424 parses VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG].
+func parseRFC5424(content []byte, structured *message.Structured, attributes map[string]interface{}) {
+	keys := []string{"version", "timestamp", "hostname", "appname", "procid", "msgid"}
+	for _, key := range keys {
+		var field string
+		field, content = nextSyslogField(content)
+		if field == "" || field == syslogNilValue {
+			continue
+		}
+		if key == "timestamp" {
+			if timestamp, err := time.Parse(time.RFC3339Nano, field); err == nil {
+				structured.Timestamp = timestamp
+			}
+			continue
+		}
+		attributes[key] = field
+	}
+	structuredData, content := parseStructuredData(content)
+	if len(structuredData) > 0 {
+		attributes["structured_data"] = structuredData
+	}
+	if len(content) > 0 && content[0] == ' ' {
+		content = content[1:]
+	}
+	structured.Message = string(bytes.TrimPrefix(content, utf8BOM))
+}
+
+// parseStructuredData parses the STRUCTURED-DATA of an RFC5424 message, e.g. [id param="value"][id2 param="value"],
+// and returns the parameters by element identifier with the rest of content.
+func parseStructuredData(content []byte) (map[string]interface{}, []byte) {
+	if bytes.HasPrefix(content, []byte(syslogNilValue)) {
+		return nil, content[1:]
+	}
+	elements := make(map[string]interface{})
+	for len(content) > 0 && content[0] == '[' {
+		end := structuredDataElementEnd(content)
+		if end < 0 {
+			// the element is not terminated, everything is part of the message
+			break
+		}
+		id, params := parseStructuredDataElement(content[1:end])
+		elements[id] = params
+		content = content[end+1:]
+	}
+	return elements, content
+}
+
+// structuredDataElementEnd returns the index of the ']' ending the element starting content, or -1 if there is none,
+// the characters '"', '\' and ']' are escaped with a '\' in the values of the parameters.
+func structuredDataElementEnd(content []byte) int {
+	inValue := false
+	for i := 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '"':
+			inValue = !inValue
+		case ']':
+			if !inValue {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseStructuredDataElement parses SD-ID *(SP PARAM-NAME="PARAM-VALUE").
+func parseStructuredDataElement(element []byte) (string, map[string]interface{}) {
+	id, rest := nextSyslogField(element)
+	params := make(map[string]interface{})
+	for len(rest) > 0 {
+		equal := bytes.IndexByte(rest, '=')
+		if equal < 0 || equal+1 >= len(rest) || rest[equal+1] != '"' {
+			break
+		}
+		name := string(bytes.TrimSpace(rest[:equal]))
+		var value []byte
+		i := equal + 2
+		for ; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] == '\\' && i+1 < len(rest) {
+				i++
+			}
+			value = append(value, rest[i])
+		}
+		params[name] = string(value)
+		if i >= len(rest) {
+			break
+		}
+		rest = rest[i+1:]
+	}
+	return id, params
+}
+
+// nextSyslogField returns the field starting content and the rest of content after the space following it.
+func nextSyslogField(content []byte) (string, []byte) {
+	end := bytes.IndexByte(content, ' ')
+	if end < 0 {
+		return string(content), nil
+	}
+	return string(content[:end]), content[end+1:]
+}
+
+// rfc3164TimestampLayout is the layout of the timestamps of the RFC3164 messages, e.g. "Mar  1 12:00:00".
+const rfc3164TimestampLayout = time.Stamp
+
+// parseRFC3164 parses TIMESTAMP SP HOSTNAME SP TAG[PID]: MSG, the whole content is the message
+// when it does not start with a timestamp.
+func parseRFC3164(content []byte, now time.Time, structured *message.Structured, attributes map[string]interface{}) {
+	if len(content) < len(rfc3164TimestampLayout) {
+		structured.Message = string(content)
+		return
+	}
+	timestamp, err := time.ParseInLocation(rfc3164TimestampLayout, string(content[:len(rfc3164TimestampLayout)]), now.Location())
+	if err != nil {
+		structured.Message = string(content)
+		return
+	}
+	// the timestamps do not hold the year.
+	structured.Timestamp = guessYear(timestamp, now)
+	content = bytes.TrimLeft(content[len(rfc3164TimestampLayout):], " ")
+
+	hostname, rest := nextSyslogField(content)
+	if hostname != "" {
+		attributes["hostname"] = hostname
+	}
+	// the tag is made of alphanumeric characters and ends with ':' or '[' followed by the process identifier.
+	tagEnd := bytes.IndexAny(rest, ":[ ")
+	if tagEnd <= 0 || rest[tagEnd] == ' ' {
+		structured.Message = string(rest)
+		return
+	}
+	attributes["appname"] = string(rest[:tagEnd])
+	rest = rest[tagEnd:]
+	if rest[0] == '[' {
+		if end := bytes.IndexByte(rest, ']'); end > 0 {
+			attributes["procid"] = string(rest[1:end])
+			rest = rest[end+1:]
+		}
+	}
+	structured.Message = strings.TrimPrefix(strings.TrimPrefix(string(rest), ":"), " ")
+}
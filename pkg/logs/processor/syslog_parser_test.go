@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseSyslogRFC5424(t *testing.T) {
+	content := "<165>1 2019-03-01T12:00:00.5Z host app 1234 ID47 [exampleSDID@32473 iut=\"3\" eventSource=\"Appli\\\"cation\"][other a=\"]\"] \xEF\xBB\xBFhello world"
+	structured, status := parseSyslog([]byte(content), time.Now())
+	assert.NotNil(t, structured)
+	assert.Equal(t, message.StatusNotice, status)
+	assert.Equal(t, "hello world", structured.Message)
+	assert.Equal(t, time.Date(2019, 3, 1, 12, 0, 0, 500000000, time.UTC), structured.Timestamp.UTC())
+	assert.Equal(t, map[string]interface{}{
+		"syslog": map[string]interface{}{
+			"facility": 20,
+			"severity": 5,
+			"version":  "1",
+			"hostname": "host",
+			"appname":  "app",
+			"procid":   "1234",
+			"msgid":    "ID47",
+			"structured_data": map[string]interface{}{
+				"exampleSDID@32473": map[string]interface{}{"iut": "3", "eventSource": "Appli\"cation"},
+				"other":             map[string]interface{}{"a": "]"},
+			},
+		},
+	}, structured.Attributes)
+}
+
+func TestParseSyslogRFC5424WithNilValues(t *testing.T) {
+	structured, status := parseSyslog([]byte("<11>1 - - - - - -"), time.Now())
+	assert.NotNil(t, structured)
+	assert.Equal(t, message.StatusError, status)
+	assert.Equal(t, "", structured.Message)
+	assert.True(t, structured.Timestamp.IsZero())
+	assert.Equal(t, map[string]interface{}{"syslog": map[string]interface{}{"facility": 1, "severity": 3, "version": "1"}}, structured.Attributes)
+}
+
+func TestParseSyslogRFC3164(t *testing.T) {
+	now := time.Date(2019, 3, 1, 13, 0, 0, 0, time.UTC)
+	structured, status := parseSyslog([]byte("<34>Mar  1 12:00:00 mymachine su[42]: 'su root' failed"), now)
+	assert.NotNil(t, structured)
+	assert.Equal(t, message.StatusCritical, status)
+	assert.Equal(t, "'su root' failed", structured.Message)
+	assert.Equal(t, time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC), structured.Timestamp)
+	assert.Equal(t, map[string]interface{}{
+		"syslog": map[string]interface{}{
+			"facility": 4,
+			"severity": 2,
+			"hostname": "mymachine",
+			"appname":  "su",
+			"procid":   "42",
+		},
+	}, structured.Attributes)
+
+	// a timestamp in the future is from the previous year
+	structured, _ = parseSyslog([]byte("<34>Dec 31 23:00:00 mymachine cron: done"), now)
+	assert.Equal(t, time.Date(2018, 12, 31, 23, 0, 0, 0, time.UTC), structured.Timestamp)
+	assert.Equal(t, "done", structured.Message)
+	assert.Equal(t, "cron", structured.Attributes["syslog"].(map[string]interface{})["appname"])
+}
+
+func TestParseSyslogRFC3164WithoutHeader(t *testing.T) {
+	structured, status := parseSyslog([]byte("<13>hello world"), time.Now())
+	assert.NotNil(t, structured)
+	assert.Equal(t, message.StatusNotice, status)
+	assert.Equal(t, "hello world", structured.Message)
+	assert.True(t, structured.Timestamp.IsZero())
+}
+
+func TestParseSyslogIgnoresOtherFormats(t *testing.T) {
+	for _, content := range []string{"", "hello world", "<>hello", "<192>1 - - - - - -", "<abc>hello", "<1234>hello"} {
+		structured, status := parseSyslog([]byte(content), time.Now())
+		assert.Nil(t, structured, content)
+		assert.Equal(t, "", status)
+	}
+}
@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timestampFormat is a usual format of the timestamps starting the logs,
+// pattern matches the timestamp at the beginning of a log and layout parses it.
+type timestampFormat struct {
+	pattern *regexp.Regexp
+	layout  string
+}
+
+// timestampFormats are the usual formats of the timestamps starting the logs, optionally between brackets,
+// the fractional seconds are parsed even though the layouts do not hold them.
+var timestampFormats = []timestampFormat{
+	// 2019-03-01T12:00:00.000Z or 2019-03-01T12:00:00+01:00
+	{regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:\d{2}))`), time.RFC3339},
+	// 2019-03-01T12:00:00.000
+	{regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:[.,]\d+)?)`), "2006-01-02T15:04:05"},
+	// 2019-03-01 12:00:00,000
+	{regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:[.,]\d+)?)`), "2006-01-02 15:04:05"},
+	// 2019/03/01 12:00:00
+	{regexp.MustCompile(`^\[?(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:[.,]\d+)?)`), "2006/01/02 15:04:05"},
+	// 01/Mar/2019:12:00:00 +0100
+	{regexp.MustCompile(`^\[?(\d{2}/[A-Za-z]{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4})`), "02/Jan/2006:15:04:05 -0700"},
+	// Fri, 01 Mar 2019 12:00:00 +0100
+	{regexp.MustCompile(`^\[?([A-Za-z]{3}, \d{2} [A-Za-z]{3} \d{4} \d{2}:\d{2}:\d{2} [+-]\d{4})`), time.RFC1123Z},
+	// Fri Mar  1 12:00:00 2019
+	{regexp.MustCompile(`^\[?([A-Za-z]{3} [A-Za-z]{3} [ \d]\d \d{2}:\d{2}:\d{2} \d{4})`), time.ANSIC},
+	// Mar  1 12:00:00, the year is guessed.
+	{regexp.MustCompile(`^\[?([A-Za-z]{3} [ \d]\d \d{2}:\d{2}:\d{2}(?:[.,]\d+)?)`), time.Stamp},
+}
+
+// parseTimestampPrefix returns the time represented by the timestamp starting content, optionally between brackets,
+// parsed with the first of layouts matching it or with the usual formats if detect is set. The timestamps without
+// time zone are in location, the year of the timestamps without year is guessed from now.
+// The timestamps parsed with layouts must be as long as the layout.
+func parseTimestampPrefix(content []byte, layouts []string, detect bool, now time.Time, location *time.Location) (time.Time, bool) {
+	prefix := content
+	if len(prefix) > 0 && prefix[0] == '[' {
+		prefix = prefix[1:]
+	}
+	for _, layout := range layouts {
+		if len(prefix) < len(layout) {
+			continue
+		}
+		if timestamp, err := time.ParseInLocation(layout, string(prefix[:len(layout)]), location); err == nil {
+			return guessYear(timestamp, now), true
+		}
+	}
+	if !detect {
+		return time.Time{}, false
+	}
+	for _, format := range timestampFormats {
+		match := format.pattern.FindSubmatch(content)
+		if match == nil {
+			continue
+		}
+		value := string(match[1])
+		if !strings.Contains(format.layout, ",") {
+			// the fractional seconds can only be parsed after a dot.
+			value = strings.Replace(value, ",", ".", 1)
+		}
+		if timestamp, err := time.ParseInLocation(format.layout, value, location); err == nil {
+			return guessYear(timestamp, now), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// guessYear sets the year of a timestamp without year to the one of now,
+// a timestamp in the future comes from the previous year.
+func guessYear(timestamp time.Time, now time.Time) time.Time {
+	if timestamp.Year() != 0 {
+		return timestamp
+	}
+	timestamp = timestamp.AddDate(now.Year(), 0, 0)
+	if timestamp.After(now.Add(24 * time.Hour)) {
+		timestamp = timestamp.AddDate(-1, 0, 0)
+	}
+	return timestamp
+}
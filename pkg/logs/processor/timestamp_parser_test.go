@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimestampPrefixDetectsTheUsualFormats(t *testing.T) {
+	now := time.Date(2019, 3, 2, 0, 0, 0, 0, time.UTC)
+	for content, expected := range map[string]time.Time{
+		"2019-03-01T12:00:00.123Z hello":                   time.Date(2019, 3, 1, 12, 0, 0, 123e6, time.UTC),
+		"2019-03-01T12:00:00+01:00 hello":                  time.Date(2019, 3, 1, 11, 0, 0, 0, time.UTC),
+		"[2019-03-01T12:00:00.5] hello":                    time.Date(2019, 3, 1, 12, 0, 0, 5e8, time.UTC),
+		"2019-03-01 12:00:00,123 - app - ERROR - hello":    time.Date(2019, 3, 1, 12, 0, 0, 123e6, time.UTC),
+		"2019/03/01 12:00:00 hello":                        time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		"01/Mar/2019:12:00:00 +0100 GET /":                 time.Date(2019, 3, 1, 11, 0, 0, 0, time.UTC),
+		"Fri, 01 Mar 2019 12:00:00 +0100 hello":            time.Date(2019, 3, 1, 11, 0, 0, 0, time.UTC),
+		"Fri Mar  1 12:00:00 2019 hello":                   time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		"Mar  1 12:00:00 host app: hello":                  time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		"Dec 31 23:00:00 host app: from the previous year": time.Date(2018, 12, 31, 23, 0, 0, 0, time.UTC),
+	} {
+		timestamp, found := parseTimestampPrefix([]byte(content), nil, true, now, time.UTC)
+		assert.True(t, found, content)
+		assert.True(t, expected.Equal(timestamp), "%s: %v", content, timestamp)
+	}
+
+	// the timestamps without time zone are in location.
+	timestamp, found := parseTimestampPrefix([]byte("2019-03-01 13:00:00 hello"), nil, true, now, time.FixedZone("CET", 3600))
+	assert.True(t, found)
+	assert.True(t, time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC).Equal(timestamp))
+
+	for _, content := range []string{"", "hello", "12:00:00 hello", "2019-13-01 12:00:00 hello"} {
+		_, found := parseTimestampPrefix([]byte(content), nil, true, now, time.UTC)
+		assert.False(t, found, content)
+	}
+}
+
+func TestParseTimestampPrefixWithLayouts(t *testing.T) {
+	now := time.Date(2019, 3, 2, 0, 0, 0, 0, time.UTC)
+	layouts := []string{"02.01.2006 15:04:05", "20060102T150405"}
+
+	timestamp, found := parseTimestampPrefix([]byte("01.03.2019 12:00:00 hello"), layouts, false, now, time.UTC)
+	assert.True(t, found)
+	assert.Equal(t, time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC), timestamp)
+
+	timestamp, found = parseTimestampPrefix([]byte("[20190301T120000] hello"), layouts, false, now, time.UTC)
+	assert.True(t, found)
+	assert.Equal(t, time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC), timestamp)
+
+	// the usual formats are only detected when enabled.
+	_, found = parseTimestampPrefix([]byte("2019-03-01 12:00:00 hello"), layouts, false, now, time.UTC)
+	assert.False(t, found)
+	_, found = parseTimestampPrefix([]byte("2019-03-01 12:00:00 hello"), layouts, true, now, time.UTC)
+	assert.True(t, found)
+}
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+)
+
+// The patterns of the trace contexts injected in the log lines by the logger layouts.
+var (
+	// dd.trace_id=1234 dd.span_id=5678, injected by the Datadog tracers.
+	ddTraceIDPattern = regexp.MustCompile(`\bdd\.trace_id"?\s*[=:]\s*"?(\d+)`)
+	ddSpanIDPattern  = regexp.MustCompile(`\bdd\.span_id"?\s*[=:]\s*"?(\d+)`)
+	// 00-<trace-id>-<parent-id>-<trace-flags>, the W3C traceparent header.
+	traceparentPattern = regexp.MustCompile(`\b[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}\b`)
+	// trace_id=<hex> span_id=<hex>, injected by the OpenTelemetry logging instrumentations.
+	otelTraceIDPattern = regexp.MustCompile(`\b(?:trace_id|traceId|trace\.id|otelTraceID)"?\s*[=:]\s*"?([0-9a-fA-F]{32}|[0-9a-fA-F]{16})\b`)
+	otelSpanIDPattern  = regexp.MustCompile(`\b(?:span_id|spanId|span\.id|otelSpanID)"?\s*[=:]\s*"?([0-9a-fA-F]{16})\b`)
+)
+
+// The keys of the OpenTelemetry trace context in the JSON logs.
+var (
+	jsonTraceIDKeys = []string{"trace_id", "traceId", "traceID"}
+	jsonSpanIDKeys  = []string{"span_id", "spanId", "spanID"}
+)
+
+// parseTraceContext returns the trace and span identifiers, in the decimal format of the Datadog tracers, of the
+// trace context found in attributes or else in content: a Datadog trace context, a W3C traceparent or
+// an OpenTelemetry trace context, whose 128 bits trace identifiers are cut to their lower 64 bits.
+// traceID is empty if there is none, spanID is empty if the trace context does not hold it.
+func parseTraceContext(content []byte, attributes map[string]interface{}) (traceID string, spanID string) {
+	if traceID, spanID = attributesTraceContext(attributes); traceID != "" {
+		return traceID, spanID
+	}
+	if match := ddTraceIDPattern.FindSubmatch(content); match != nil {
+		if traceID = decimalID(string(match[1])); traceID != "" {
+			if match := ddSpanIDPattern.FindSubmatch(content); match != nil {
+				spanID = decimalID(string(match[1]))
+			}
+			return traceID, spanID
+		}
+	}
+	if match := traceparentPattern.FindSubmatch(content); match != nil {
+		if traceID = hexID(string(match[1])); traceID != "" {
+			return traceID, hexID(string(match[2]))
+		}
+	}
+	if match := otelTraceIDPattern.FindSubmatch(content); match != nil {
+		if traceID = hexID(string(match[1])); traceID != "" {
+			if match := otelSpanIDPattern.FindSubmatch(content); match != nil {
+				spanID = hexID(string(match[1]))
+			}
+			return traceID, spanID
+		}
+	}
+	return "", ""
+}
+
+// attributesTraceContext returns the identifiers of the trace context held in the flat dd.trace_id and
+// dd.span_id fields, in a traceparent field or in the OpenTelemetry fields of attributes.
+func attributesTraceContext(attributes map[string]interface{}) (traceID string, spanID string) {
+	if len(attributes) == 0 {
+		return "", ""
+	}
+	if traceID = decimalID(attributes["dd.trace_id"]); traceID != "" {
+		return traceID, decimalID(attributes["dd.span_id"])
+	}
+	if traceparent, ok := attributes["traceparent"].(string); ok {
+		if match := traceparentPattern.FindStringSubmatch(traceparent); match != nil {
+			if traceID = hexID(match[1]); traceID != "" {
+				return traceID, hexID(match[2])
+			}
+		}
+	}
+	if _, value, found := lookupString(attributes, jsonTraceIDKeys); found {
+		if traceID = hexID(value); traceID != "" {
+			if _, value, found := lookupString(attributes, jsonSpanIDKeys); found {
+				spanID = hexID(value)
+			}
+			return traceID, spanID
+		}
+	}
+	return "", ""
+}
+
+// decimalID returns the decimal identifier held in value, a string or a number, or an empty string if there is none.
+func decimalID(value interface{}) string {
+	var id string
+	switch v := value.(type) {
+	case string:
+		id = v
+	case json.Number:
+		id = v.String()
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || n == 0 {
+		return ""
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// hexID returns the decimal format of the lower 64 bits of the hexadecimal identifier id of 16 or 32 digits,
+// or an empty string if id is not one or is zero, which is not a valid identifier.
+func hexID(id string) string {
+	if len(id) != 16 && len(id) != 32 {
+		return ""
+	}
+	n, err := strconv.ParseUint(id[len(id)-16:], 16, 64)
+	if err != nil || n == 0 {
+		return ""
+	}
+	return strconv.FormatUint(n, 10)
+}
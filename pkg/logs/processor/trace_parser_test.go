@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceContext(t *testing.T) {
+	for content, ids := range map[string][2]string{
+		"2019-03-01 12:00:00 INFO [dd.service=app dd.trace_id=1234 dd.span_id=5678] hello": {"1234", "5678"},
+		`{"message":"hello","dd.trace_id":"1234"}`:                                         {"1234", ""},
+		"traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 hello":       {"11803532876627986230", "67667974448284343"},
+		"hello trace_id=5b8aa5a2d2c872e8321cf37308d69df2 span_id=051581bf3cb55c13":         {"3611028676639366642", "366341603057818643"},
+		"hello [otelTraceID=5b8aa5a2d2c872e8321cf37308d69df2 otelSpanID=051581bf3cb55c13]": {"3611028676639366642", "366341603057818643"},
+		"hello trace_id=00000000000000000000000000000000 span_id=0000000000000000":         {"", ""},
+		"traceparent: 00-00000000000000000000000000000000-00f067aa0ba902b7-01 hello":       {"", ""},
+		"hello trace_id=5b8aa5a2 span_id=051581bf3cb55c13":                                 {"", ""},
+		"hello dd.trace_id=0": {"", ""},
+		"hello world":         {"", ""},
+	} {
+		traceID, spanID := parseTraceContext([]byte(content), nil)
+		assert.Equal(t, ids[0], traceID, content)
+		assert.Equal(t, ids[1], spanID, content)
+	}
+}
+
+func TestParseTraceContextOfAttributes(t *testing.T) {
+	for _, test := range []struct {
+		attributes map[string]interface{}
+		traceID    string
+		spanID     string
+	}{
+		{map[string]interface{}{"dd.trace_id": json.Number("1234"), "dd.span_id": json.Number("5678")}, "1234", "5678"},
+		{map[string]interface{}{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "11803532876627986230", "67667974448284343"},
+		{map[string]interface{}{"traceId": "5b8aa5a2d2c872e8321cf37308d69df2", "spanId": "051581bf3cb55c13"}, "3611028676639366642", "366341603057818643"},
+		{map[string]interface{}{"trace_id": "a3ce929d0e0e4736"}, "11803532876627986230", ""},
+		{map[string]interface{}{"trace_id": "not an id"}, "", ""},
+		{map[string]interface{}{"user": "bob"}, "", ""},
+	} {
+		traceID, spanID := parseTraceContext(nil, test.attributes)
+		assert.Equal(t, test.traceID, traceID, test.attributes)
+		assert.Equal(t, test.spanID, spanID, test.attributes)
+	}
+}
@@ -8,6 +8,7 @@ package scheduler
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers"
@@ -25,13 +26,18 @@ import (
 type Scheduler struct {
 	sources  *logsConfig.LogSources
 	services *service.Services
+	// the sources of the configs without entity, defined in configuration files, by config digest
+	// so that they can be removed when their configs are unscheduled
+	fileSources map[string][]*logsConfig.LogSource
+	mu          sync.Mutex
 }
 
 // NewScheduler returns a new scheduler.
 func NewScheduler(sources *logsConfig.LogSources, services *service.Services) *Scheduler {
 	return &Scheduler{
-		sources:  sources,
-		services: services,
+		sources:     sources,
+		services:    services,
+		fileSources: make(map[string][]*logsConfig.LogSource),
 	}
 }
 
@@ -55,6 +61,12 @@ func (s *Scheduler) Schedule(configs []integration.Config) {
 				log.Warnf("Invalid configuration: %v", err)
 				continue
 			}
+			if config.Entity == "" {
+				s.mu.Lock()
+				digest := config.Digest()
+				s.fileSources[digest] = append(s.fileSources[digest], sources...)
+				s.mu.Unlock()
+			}
 			for _, source := range sources {
 				s.sources.AddSource(source)
 			}
@@ -80,6 +92,16 @@ func (s *Scheduler) Unschedule(configs []integration.Config) {
 			continue
 		}
 		switch {
+		case s.newSources(config) && config.Entity == "":
+			log.Infof("Logs config to remove: %v", s.configName(config))
+			s.mu.Lock()
+			digest := config.Digest()
+			sources := s.fileSources[digest]
+			delete(s.fileSources, digest)
+			s.mu.Unlock()
+			for _, source := range sources {
+				s.sources.RemoveSource(source)
+			}
 		case s.newSources(config):
 			log.Infof("New source to remove: entity: %v", config.Entity)
 
@@ -203,6 +225,8 @@ func (s *Scheduler) toService(config integration.Config) (*service.Service, erro
 		return service.NewService(provider, identifier, s.getCreationTime(config)), nil
 	case service.Containerd:
 		return service.NewService(provider, identifier, s.getCreationTime(config)), nil
+	case service.CRIO:
+		return service.NewService(provider, identifier, s.getCreationTime(config)), nil
 	default:
 		return nil, fmt.Errorf("%v is not supported yet", provider)
 	}
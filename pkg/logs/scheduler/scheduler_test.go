@@ -61,6 +61,26 @@ func TestScheduleConfigCreatesNewService(t *testing.T) {
 	assert.Equal(t, configService.Entity, svc.GetEntityID())
 }
 
+func TestScheduleConfigCreatesNewCRIOService(t *testing.T) {
+	logSources := config.NewLogSources()
+	services := service.NewServices()
+	scheduler := NewScheduler(logSources, services)
+
+	servicesStream := services.GetAddedServices(service.CRIO)
+
+	configService := integration.Config{
+		LogsConfig:   []byte(""),
+		Entity:       "cri-o://a1887023ed72a2b0d083ef465e8edfe4932a25731d4bda2f39f288f70af3405b",
+		ClusterCheck: false,
+		CreationTime: 0,
+	}
+
+	go scheduler.Schedule([]integration.Config{configService})
+	svc := <-servicesStream
+	assert.Equal(t, configService.Entity, svc.GetEntityID())
+	assert.Equal(t, service.CRIO, svc.Type)
+}
+
 func TestUnscheduleConfigRemovesSource(t *testing.T) {
 	logSources := config.NewLogSources()
 	services := service.NewServices()
@@ -108,3 +128,33 @@ func TestUnscheduleConfigRemovesService(t *testing.T) {
 	svc := <-servicesStream
 	assert.Equal(t, configService.Entity, svc.GetEntityID())
 }
+
+func TestUnscheduleFileConfigRemovesItsSources(t *testing.T) {
+	logSources := config.NewLogSources()
+	services := service.NewServices()
+	scheduler := NewScheduler(logSources, services)
+	addedSources := logSources.GetAddedForType(config.FileType)
+	removedSources := logSources.GetRemovedForType(config.FileType)
+
+	fooConfig := integration.Config{
+		Name:       "foo",
+		LogsConfig: []byte("logs:\n- type: file\n  path: /var/log/foo.log\n  service: foo\n  source: foo\n"),
+		Provider:   providers.File,
+	}
+	barConfig := integration.Config{
+		Name:       "foo",
+		LogsConfig: []byte("logs:\n- type: file\n  path: /var/log/bar.log\n  service: bar\n  source: bar\n"),
+		Provider:   providers.File,
+	}
+
+	go scheduler.Schedule([]integration.Config{fooConfig, barConfig})
+	<-addedSources
+	<-addedSources
+
+	go scheduler.Unschedule([]integration.Config{barConfig})
+	logSource := <-removedSources
+	assert.Equal(t, "foo", logSource.Name)
+	assert.Equal(t, "/var/log/bar.log", logSource.Config.Path)
+	assert.Len(t, logSources.GetSources(), 1)
+	assert.Equal(t, "/var/log/foo.log", logSources.GetSources()[0].Config.Path)
+}
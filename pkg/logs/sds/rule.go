@@ -0,0 +1,186 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sds
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+// The actions applied to the sensitive data matched by a rule.
+const (
+	// RedactAction replaces the matches with the replacement of the rule.
+	RedactAction = "redact"
+	// PartialRedactAction masks the first or the last characters of the matches.
+	PartialRedactAction = "partial_redact"
+	// HashAction replaces the matches with the beginning of their SHA-256 digest, so that they can still be correlated.
+	HashAction = "hash"
+	// MatchAction leaves the matches untouched, they are only counted.
+	MatchAction = "match"
+)
+
+// The directions of the characters masked by the partial_redact action.
+const (
+	FirstCharacters = "first"
+	LastCharacters  = "last"
+)
+
+const (
+	// defaultReplacement is the replacement of the matches of the redact rules without replacement.
+	defaultReplacement = "[REDACTED]"
+	// defaultKeywordCharacterCount is how far before a match one of the keywords of a rule is looked for.
+	defaultKeywordCharacterCount = 30
+	// hashLength is the number of hexadecimal characters of the digests of the hash action.
+	hashLength = 16
+	// maskCharacter replaces the characters masked by the partial_redact action.
+	maskCharacter = '*'
+)
+
+// Rule is a rule of the sensitive data scanner, it applies its action to the matches of its pattern,
+// restricted to the ones preceded by one of its keywords when it has some.
+type Rule struct {
+	Name    string `mapstructure:"name" json:"name"`
+	Pattern string `mapstructure:"pattern" json:"pattern"`
+	// Keywords are looked for, in any case, in the KeywordCharacterCount characters preceding a match,
+	// 30 if not set, to tell the sensitive data from the similar looking data, e.g. a card number from an identifier.
+	Keywords              []string `mapstructure:"keywords" json:"keywords"`
+	KeywordCharacterCount int      `mapstructure:"keyword_character_count" json:"keyword_character_count"`
+	Action                string   `mapstructure:"action" json:"action"`
+	// Replacement replaces the matches of the redact action, [REDACTED] if not set.
+	Replacement string `mapstructure:"replacement" json:"replacement"`
+	// Characters is the number of characters masked at the Direction end of the matches by the partial_redact action.
+	Characters int    `mapstructure:"characters" json:"characters"`
+	Direction  string `mapstructure:"direction" json:"direction"`
+
+	regex    *regexp.Regexp
+	keywords [][]byte
+}
+
+// Validate returns an error if the rule is misconfigured.
+func (r *Rule) Validate() error {
+	switch {
+	case r.Name == "":
+		return fmt.Errorf("sensitive data scanner rules must have a name")
+	case r.Pattern == "":
+		return fmt.Errorf("sensitive data scanner rule %s must have a pattern", r.Name)
+	case r.Action != RedactAction && r.Action != PartialRedactAction && r.Action != HashAction && r.Action != MatchAction:
+		return fmt.Errorf("unsupported action %s for sensitive data scanner rule %s, supported actions are %s, %s, %s and %s", r.Action, r.Name, RedactAction, PartialRedactAction, HashAction, MatchAction)
+	case r.Action == PartialRedactAction && r.Characters <= 0:
+		return fmt.Errorf("sensitive data scanner rule %s must mask a positive number of characters", r.Name)
+	case r.Action == PartialRedactAction && r.Direction != FirstCharacters && r.Direction != LastCharacters:
+		return fmt.Errorf("unsupported direction %s for sensitive data scanner rule %s, supported directions are %s and %s", r.Direction, r.Name, FirstCharacters, LastCharacters)
+	case r.KeywordCharacterCount < 0:
+		return fmt.Errorf("keyword_character_count of sensitive data scanner rule %s must not be negative", r.Name)
+	}
+	return nil
+}
+
+// compile compiles the pattern and the keywords of the rule.
+func (r *Rule) compile() error {
+	regex, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern for sensitive data scanner rule %s: %v", r.Name, err)
+	}
+	r.regex = regex
+	r.keywords = make([][]byte, 0, len(r.Keywords))
+	for _, keyword := range r.Keywords {
+		if keyword != "" {
+			r.keywords = append(r.keywords, bytes.ToLower([]byte(keyword)))
+		}
+	}
+	return nil
+}
+
+// apply returns content with the action of the rule applied to its matches and their number,
+// content is returned as is when nothing matched, it's never modified in place.
+func (r *Rule) apply(content []byte) ([]byte, int) {
+	matches := r.regex.FindAllIndex(content, -1)
+	if len(matches) == 0 {
+		return content, 0
+	}
+	var scanned []byte
+	count, last := 0, 0
+	for _, match := range matches {
+		if match[0] == match[1] || !r.hasKeyword(content, match[0]) {
+			continue
+		}
+		count++
+		if r.Action == MatchAction {
+			continue
+		}
+		scanned = append(scanned, content[last:match[0]]...)
+		scanned = append(scanned, r.replace(content[match[0]:match[1]])...)
+		last = match[1]
+	}
+	if count == 0 || r.Action == MatchAction {
+		return content, count
+	}
+	return append(scanned, content[last:]...), count
+}
+
+// hasKeyword returns true if the rule has no keywords or if one of them precedes the match starting at start.
+func (r *Rule) hasKeyword(content []byte, start int) bool {
+	if len(r.keywords) == 0 {
+		return true
+	}
+	count := r.KeywordCharacterCount
+	if count == 0 {
+		count = defaultKeywordCharacterCount
+	}
+	window := content[:start]
+	if len(window) > count {
+		window = window[len(window)-count:]
+	}
+	window = bytes.ToLower(window)
+	for _, keyword := range r.keywords {
+		if bytes.Contains(window, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// replace returns the replacement of match.
+func (r *Rule) replace(match []byte) []byte {
+	switch r.Action {
+	case RedactAction:
+		if r.Replacement == "" {
+			return []byte(defaultReplacement)
+		}
+		return []byte(r.Replacement)
+	case HashAction:
+		digest := sha256.Sum256(match)
+		return []byte(hex.EncodeToString(digest[:])[:hashLength])
+	case PartialRedactAction:
+		return mask(match, r.Characters, r.Direction)
+	default:
+		return match
+	}
+}
+
+// mask returns match with its first or last count characters replaced with the mask character.
+func mask(match []byte, count int, direction string) []byte {
+	runes := []rune(string(match))
+	if count > len(runes) {
+		count = len(runes)
+	}
+	start, end := 0, count
+	if direction == LastCharacters {
+		start, end = len(runes)-count, len(runes)
+	}
+	masked := make([]byte, 0, len(match))
+	for i, r := range runes {
+		if i >= start && i < end {
+			masked = append(masked, maskCharacter)
+			continue
+		}
+		masked = append(masked, string(r)...)
+	}
+	return masked
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sds
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newRule returns a compiled rule.
+func newRule(t *testing.T, rule Rule) *Rule {
+	assert.NoError(t, rule.Validate())
+	assert.NoError(t, rule.compile())
+	return &rule
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, (&Rule{Name: "email", Pattern: `\S+@\S+`, Action: RedactAction}).Validate())
+	assert.NoError(t, (&Rule{Name: "card", Pattern: `\d{16}`, Action: PartialRedactAction, Characters: 12, Direction: FirstCharacters}).Validate())
+
+	assert.Error(t, (&Rule{Pattern: `\S+@\S+`, Action: RedactAction}).Validate())
+	assert.Error(t, (&Rule{Name: "email", Action: RedactAction}).Validate())
+	assert.Error(t, (&Rule{Name: "email", Pattern: `\S+@\S+`}).Validate())
+	assert.Error(t, (&Rule{Name: "email", Pattern: `\S+@\S+`, Action: "drop"}).Validate())
+	assert.Error(t, (&Rule{Name: "card", Pattern: `\d{16}`, Action: PartialRedactAction, Direction: FirstCharacters}).Validate())
+	assert.Error(t, (&Rule{Name: "card", Pattern: `\d{16}`, Action: PartialRedactAction, Characters: 12, Direction: "middle"}).Validate())
+	assert.Error(t, (&Rule{Name: "card", Pattern: `\d{16}`, Action: RedactAction, KeywordCharacterCount: -1}).Validate())
+}
+
+func TestCompile(t *testing.T) {
+	rule := &Rule{Name: "email", Pattern: `\S+@\S+`, Keywords: []string{"Email", ""}, Action: RedactAction}
+	assert.NoError(t, rule.compile())
+	assert.Equal(t, [][]byte{[]byte("email")}, rule.keywords)
+
+	rule = &Rule{Name: "invalid", Pattern: `(`, Action: RedactAction}
+	assert.Error(t, rule.compile())
+}
+
+func TestApplyRedact(t *testing.T) {
+	rule := newRule(t, Rule{Name: "email", Pattern: `[\w.]+@[\w.]+`, Action: RedactAction})
+	content := []byte("from bob@example.com to alice@example.com")
+	scanned, count := rule.apply(content)
+	assert.Equal(t, "from [REDACTED] to [REDACTED]", string(scanned))
+	assert.Equal(t, 2, count)
+	// the content is never modified in place.
+	assert.Equal(t, "from bob@example.com to alice@example.com", string(content))
+
+	rule = newRule(t, Rule{Name: "email", Pattern: `[\w.]+@[\w.]+`, Action: RedactAction, Replacement: "<email>"})
+	scanned, count = rule.apply([]byte("from bob@example.com"))
+	assert.Equal(t, "from <email>", string(scanned))
+	assert.Equal(t, 1, count)
+
+	scanned, count = rule.apply([]byte("nothing to see"))
+	assert.Equal(t, "nothing to see", string(scanned))
+	assert.Equal(t, 0, count)
+}
+
+func TestApplyPartialRedact(t *testing.T) {
+	rule := newRule(t, Rule{Name: "card", Pattern: `\d{16}`, Action: PartialRedactAction, Characters: 12, Direction: FirstCharacters})
+	scanned, count := rule.apply([]byte("card 4111111111111111 charged"))
+	assert.Equal(t, "card ************1111 charged", string(scanned))
+	assert.Equal(t, 1, count)
+
+	rule = newRule(t, Rule{Name: "phone", Pattern: `\+\d+`, Action: PartialRedactAction, Characters: 4, Direction: LastCharacters})
+	scanned, _ = rule.apply([]byte("call +33612345678"))
+	assert.Equal(t, "call +3361234****", string(scanned))
+
+	// the whole match is masked when it's shorter than the characters to mask.
+	scanned, _ = rule.apply([]byte("call +33"))
+	assert.Equal(t, "call ***", string(scanned))
+}
+
+func TestApplyPartialRedactMasksCharacters(t *testing.T) {
+	assert.Equal(t, "**llo", string(mask([]byte("héllo"), 2, FirstCharacters)))
+	assert.Equal(t, "hé***", string(mask([]byte("héllo"), 3, LastCharacters)))
+}
+
+func TestApplyHash(t *testing.T) {
+	rule := newRule(t, Rule{Name: "email", Pattern: `[\w.]+@[\w.]+`, Action: HashAction})
+	scanned, count := rule.apply([]byte("from bob@example.com to bob@example.com"))
+	assert.Equal(t, "from 5ff860bf1190596c to 5ff860bf1190596c", string(scanned))
+	assert.Equal(t, 2, count)
+}
+
+func TestApplyMatch(t *testing.T) {
+	rule := newRule(t, Rule{Name: "email", Pattern: `[\w.]+@[\w.]+`, Action: MatchAction})
+	content := []byte("from bob@example.com to alice@example.com")
+	scanned, count := rule.apply(content)
+	assert.Equal(t, "from bob@example.com to alice@example.com", string(scanned))
+	assert.Equal(t, 2, count)
+}
+
+func TestApplyWithKeywords(t *testing.T) {
+	rule := newRule(t, Rule{Name: "card", Pattern: `\d{16}`, Keywords: []string{"card"}, Action: RedactAction})
+	scanned, count := rule.apply([]byte("order 1234567890123456 paid with CARD 4111111111111111"))
+	assert.Equal(t, "order 1234567890123456 paid with CARD [REDACTED]", string(scanned))
+	assert.Equal(t, 1, count)
+
+	// the keywords farther than keyword_character_count characters from a match are ignored.
+	rule = newRule(t, Rule{Name: "card", Pattern: `\d{16}`, Keywords: []string{"card"}, KeywordCharacterCount: 5, Action: RedactAction})
+	scanned, count = rule.apply([]byte("card number 4111111111111111"))
+	assert.Equal(t, "card number 4111111111111111", string(scanned))
+	assert.Equal(t, 0, count)
+	scanned, count = rule.apply([]byte("card 4111111111111111"))
+	assert.Equal(t, "card [REDACTED]", string(scanned))
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sds
+
+import (
+	"encoding/json"
+	"fmt"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// Scanner applies the rules of the sensitive data scanner to the content of the logs before they leave the host.
+type Scanner struct {
+	rules []*Rule
+}
+
+// NewScanner returns a new scanner applying rules, it returns an error if one of them is invalid.
+func NewScanner(rules []*Rule) (*Scanner, error) {
+	names := make(map[string]bool, len(rules))
+	compiled := make([]*Rule, 0, len(rules))
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("sensitive data scanner rule %s is defined more than once", rule.Name)
+		}
+		names[rule.Name] = true
+		// the rules are copied so that the caller can not change them while they are applied.
+		copied := *rule
+		copied.Keywords = append([]string(nil), rule.Keywords...)
+		if err := copied.compile(); err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, &copied)
+	}
+	return &Scanner{rules: compiled}, nil
+}
+
+// Scan returns content with the rules applied in order, a nil scanner returns content as is.
+// content is never modified in place, a new slice is returned when a rule changed it.
+func (s *Scanner) Scan(content []byte) []byte {
+	if s == nil {
+		return content
+	}
+	for _, rule := range s.rules {
+		var count int
+		content, count = rule.apply(content)
+		if count > 0 {
+			metrics.SDSMatches.Add(rule.Name, int64(count))
+		}
+	}
+	return content
+}
+
+// GlobalRules returns the sensitive data scanner rules configured with 'logs_config.sds_rules',
+// as a list or as a JSON string.
+func GlobalRules() ([]*Rule, error) {
+	var rules []*Rule
+	raw := coreConfig.Datadog.GetString("logs_config.sds_rules")
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+			return nil, fmt.Errorf("could not parse the sensitive data scanner rules: %v", err)
+		}
+		return rules, nil
+	}
+	if err := coreConfig.Datadog.UnmarshalKey("logs_config.sds_rules", &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sds
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+func TestScannerAppliesTheRulesInOrder(t *testing.T) {
+	scanner, err := NewScanner([]*Rule{
+		{Name: "scanner_email", Pattern: `[\w.]+@[\w.]+`, Action: RedactAction, Replacement: "<email>"},
+		{Name: "scanner_placeholder", Pattern: `<email>`, Action: MatchAction},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "user <email> logged in", string(scanner.Scan([]byte("user bob@example.com logged in"))))
+	assert.Equal(t, "1", metrics.SDSMatches.Get("scanner_email").String())
+	assert.Equal(t, "1", metrics.SDSMatches.Get("scanner_placeholder").String())
+}
+
+func TestScannerWithoutRules(t *testing.T) {
+	scanner, err := NewScanner(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob@example.com", string(scanner.Scan([]byte("bob@example.com"))))
+
+	var nilScanner *Scanner
+	assert.Equal(t, "bob@example.com", string(nilScanner.Scan([]byte("bob@example.com"))))
+}
+
+func TestNewScannerFailsWithInvalidRules(t *testing.T) {
+	_, err := NewScanner([]*Rule{{Name: "invalid", Pattern: `(`, Action: RedactAction}})
+	assert.Error(t, err)
+}
+
+func TestNewScannerCopiesTheRules(t *testing.T) {
+	rule := &Rule{Name: "token", Pattern: `tok_\w+`, Action: RedactAction}
+	scanner, err := NewScanner([]*Rule{rule})
+	assert.NoError(t, err)
+	assert.Equal(t, "bob@example.com [REDACTED]", string(scanner.Scan([]byte("bob@example.com tok_123"))))
+
+	// the rules are copied, changing them does not change the scanner.
+	rule.Pattern = `bob`
+	assert.Equal(t, "bob [REDACTED]", string(scanner.Scan([]byte("bob tok_123"))))
+}
+
+func TestNewScannerFailsWithDuplicateRules(t *testing.T) {
+	_, err := NewScanner([]*Rule{{Name: "email", Pattern: `\S+@\S+`, Action: RedactAction}, {Name: "email", Pattern: `\d+`, Action: RedactAction}})
+	assert.Error(t, err)
+}
+
+func TestGlobalRules(t *testing.T) {
+	rules, err := GlobalRules()
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	coreConfig.Datadog.Set("logs_config.sds_rules", `[{"name":"email","pattern":"\\S+@\\S+","action":"hash"}]`)
+	defer coreConfig.Datadog.Set("logs_config.sds_rules", nil)
+	rules, err = GlobalRules()
+	assert.NoError(t, err)
+	assert.Equal(t, []*Rule{{Name: "email", Pattern: `\S+@\S+`, Action: HashAction}}, rules)
+
+	coreConfig.Datadog.Set("logs_config.sds_rules", `[{"name":"card","pattern":"\\d{16}","keywords":["card"],"keyword_character_count":10,"action":"partial_redact","characters":12,"direction":"first"}]`)
+	rules, err = GlobalRules()
+	assert.NoError(t, err)
+	assert.Equal(t, []*Rule{{Name: "card", Pattern: `\d{16}`, Keywords: []string{"card"}, KeywordCharacterCount: 10, Action: PartialRedactAction, Characters: 12, Direction: FirstCharacters}}, rules)
+
+	coreConfig.Datadog.Set("logs_config.sds_rules", `{"name":"card"}`)
+	_, err = GlobalRules()
+	assert.Error(t, err)
+}
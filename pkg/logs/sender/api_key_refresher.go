@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// An APIKeyRefresher reads the logs API key from the configuration file periodically, fetching it again
+// from the secrets backend when it's encrypted, and rotates the API key of the endpoints when it changed.
+type APIKeyRefresher struct {
+	holder   *client.APIKeyHolder
+	readKey  func() (string, error)
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewAPIKeyRefresher returns a new APIKeyRefresher rotating the key of holder with the one of the configuration file at path.
+func NewAPIKeyRefresher(holder *client.APIKeyHolder, path string, interval time.Duration) *APIKeyRefresher {
+	return &APIKeyRefresher{
+		holder: holder,
+		readKey: func() (string, error) {
+			return readAPIKey(path)
+		},
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start starts refreshing the API key.
+func (r *APIKeyRefresher) Start() {
+	go r.run()
+}
+
+// Stop stops refreshing the API key.
+func (r *APIKeyRefresher) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+// run refreshes the API key every interval until the refresher is stopped.
+func (r *APIKeyRefresher) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.refresh()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// refresh rotates the API key if it changed.
+func (r *APIKeyRefresher) refresh() {
+	key, err := r.readKey()
+	if err != nil {
+		log.Warnf("Could not refresh the logs API key: %v", err)
+		return
+	}
+	if key == "" || key == r.holder.Get() {
+		return
+	}
+	log.Info("The logs API key changed, the logs are now sent with the new key")
+	r.holder.Set(key)
+}
+
+// readAPIKey returns the logs API key of the configuration file at path, or of the environment.
+func readAPIKey(path string) (string, error) {
+	cfg := config.NewConfig("datadog", "DD", strings.NewReplacer(".", "_"))
+	cfg.BindEnv("api_key")
+	cfg.BindEnv("logs_config.api_key")
+	cfg.SetConfigFile(path)
+	if err := cfg.ReadInConfig(); err != nil {
+		return "", err
+	}
+	return secrets.Refresh(strings.TrimSpace(getLogsAPIKey(cfg)), "datadog.yaml")
+}
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+)
+
+func TestAPIKeyRefresherRotatesTheAPIKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api-key-refresher")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "datadog.yaml")
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("api_key: foo\n"), 0644))
+	holder := client.NewAPIKeyHolder("foo")
+	refresher := NewAPIKeyRefresher(holder, path, time.Hour)
+
+	refresher.refresh()
+	assert.Equal(t, "foo", holder.Get())
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("api_key: bar\n"), 0644))
+	refresher.refresh()
+	assert.Equal(t, "bar", holder.Get())
+
+	// the logs API key takes precedence
+	require.Nil(t, ioutil.WriteFile(path, []byte("api_key: bar\nlogs_config:\n  api_key: \" baz \"\n"), 0644))
+	refresher.refresh()
+	assert.Equal(t, "baz", holder.Get())
+
+	// the key is kept when the file can't be read or has no key
+	require.Nil(t, ioutil.WriteFile(path, []byte("api_key: [\n"), 0644))
+	refresher.refresh()
+	assert.Equal(t, "baz", holder.Get())
+	require.Nil(t, ioutil.WriteFile(path, []byte("site: datadoghq.eu\n"), 0644))
+	refresher.refresh()
+	assert.Equal(t, "baz", holder.Get())
+}
+
+func TestBuildAPIKeyRefresher(t *testing.T) {
+	endpoints, err := BuildEndpoints()
+	require.Nil(t, err)
+	require.NotNil(t, endpoints.Main.APIKeyHolder)
+	// disabled by default
+	assert.Nil(t, BuildAPIKeyRefresher(endpoints))
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// batch accumulates messages to send them in a single payload, the messages of a batch share the same API key.
+type batch struct {
+	messages       []*message.Message
+	apiKey         string
+	contentSize    int
+	maxBatchSize   int
+	maxContentSize int
+}
+
+// newBatch returns a new empty batch.
+func newBatch(maxBatchSize, maxContentSize int) *batch {
+	return &batch{
+		messages:       make([]*message.Message, 0, maxBatchSize),
+		maxBatchSize:   maxBatchSize,
+		maxContentSize: maxContentSize,
+	}
+}
+
+// add adds a message to the batch, returns false if the message does not fit in the batch
+// or has another API key than the messages of the batch.
+// A message bigger than the maximum content size is always accepted in an empty batch
+// to not block the pipeline.
+func (b *batch) add(msg *message.Message) bool {
+	if b.isFull() {
+		return false
+	}
+	apiKey := sourceAPIKey(msg)
+	if !b.isEmpty() && (b.contentSize+len(msg.Content) > b.maxContentSize || apiKey != b.apiKey) {
+		return false
+	}
+	b.apiKey = apiKey
+	b.messages = append(b.messages, msg)
+	b.contentSize += len(msg.Content)
+	return true
+}
+
+// isEmpty returns true if the batch does not contain any message.
+func (b *batch) isEmpty() bool {
+	return len(b.messages) == 0
+}
+
+// isFull returns true if no more message can be added to the batch.
+func (b *batch) isFull() bool {
+	return len(b.messages) >= b.maxBatchSize || b.contentSize >= b.maxContentSize
+}
+
+// payload returns the messages of the batch in format, a json array of the messages encoded in json
+// or a protobuf message holding the messages encoded in protobuf in its repeated field 1.
+func (b *batch) payload(format string) []byte {
+	if format == client.ProtobufPayloadFormat {
+		return b.protoPayload()
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, b.contentSize+len(b.messages)+1))
+	buf.WriteByte('[')
+	for i, msg := range b.messages {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(msg.Content)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// encode returns the payload of messages in format, as sent in a batch.
+func encode(messages []*message.Message, format string) []byte {
+	b := &batch{messages: messages}
+	for _, msg := range messages {
+		b.contentSize += len(msg.Content)
+	}
+	return b.payload(format)
+}
+
+// protoPayloadTag is the key of the length-delimited field 1 of a protobuf message.
+const protoPayloadTag = 1<<3 | 2
+
+// protoPayload returns the messages of the batch as the elements of the repeated field 1 of a protobuf message,
+// each message is prefixed with the key of the field and its length as a varint.
+func (b *batch) protoPayload() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, b.contentSize+len(b.messages)*(1+binary.MaxVarintLen32)))
+	var length [binary.MaxVarintLen64]byte
+	for _, msg := range b.messages {
+		buf.WriteByte(protoPayloadTag)
+		buf.Write(length[:binary.PutUvarint(length[:], uint64(len(msg.Content)))])
+		buf.Write(msg.Content)
+	}
+	return buf.Bytes()
+}
+
+// flush returns all the messages of the batch and resets it.
+func (b *batch) flush() []*message.Message {
+	messages := b.messages
+	b.messages = make([]*message.Message, 0, b.maxBatchSize)
+	b.contentSize = 0
+	b.apiKey = ""
+	return messages
+}
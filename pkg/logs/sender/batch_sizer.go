@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"time"
+)
+
+const (
+	// minBatchSize is the number of logs a batch holds under low volume.
+	minBatchSize = 10
+	// minBatchWait is the time a log waits in a batch under low volume.
+	minBatchWait = 100 * time.Millisecond
+)
+
+// batchSizer adapts the number of logs of the batches and the time they wait before being sent
+// to the throughput: batches grow up to the maximum batch size and wait up to the maximum latency
+// when they fill up or when the intake is slow to acknowledge them so that fewer payloads are sent,
+// they shrink when they are sent mostly empty so that the logs are sent early under low volume.
+// It starts with the maximum sizes.
+type batchSizer struct {
+	size    int
+	minSize int
+	maxSize int
+	wait    time.Duration
+	minWait time.Duration
+	maxWait time.Duration
+}
+
+// newBatchSizer returns a new batch sizer bounded by maxSize logs and maxWait.
+func newBatchSizer(maxSize int, maxWait time.Duration) *batchSizer {
+	s := &batchSizer{
+		size:    maxSize,
+		minSize: minBatchSize,
+		maxSize: maxSize,
+		wait:    maxWait,
+		minWait: minBatchWait,
+		maxWait: maxWait,
+	}
+	if s.minSize > maxSize {
+		s.minSize = maxSize
+	}
+	if s.minWait > maxWait {
+		s.minWait = maxWait
+	}
+	return s
+}
+
+// grow doubles the size of the batches and the time they wait, up to their maximums.
+func (s *batchSizer) grow() {
+	s.size *= 2
+	if s.size > s.maxSize {
+		s.size = s.maxSize
+	}
+	s.wait *= 2
+	if s.wait > s.maxWait {
+		s.wait = s.maxWait
+	}
+}
+
+// shrink halves the size of the batches and the time they wait, down to their minimums.
+func (s *batchSizer) shrink() {
+	s.size /= 2
+	if s.size < s.minSize {
+		s.size = s.minSize
+	}
+	s.wait /= 2
+	if s.wait < s.minWait {
+		s.wait = s.minWait
+	}
+}
+
+// waited is called when a batch of count logs is sent because it waited long enough,
+// the batches shrink when it was less than half full.
+func (s *batchSizer) waited(count int) {
+	if count < s.size/2 {
+		s.shrink()
+	}
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchSizerStartsWithTheMaximumSizes(t *testing.T) {
+	sizer := newBatchSizer(200, 5*time.Second)
+	assert.Equal(t, 200, sizer.size)
+	assert.Equal(t, 5*time.Second, sizer.wait)
+}
+
+func TestBatchSizerShrinksUnderLowVolume(t *testing.T) {
+	sizer := newBatchSizer(200, 5*time.Second)
+
+	// a batch at least half full does not shrink the batches.
+	sizer.waited(100)
+	assert.Equal(t, 200, sizer.size)
+	assert.Equal(t, 5*time.Second, sizer.wait)
+
+	sizer.waited(1)
+	assert.Equal(t, 100, sizer.size)
+	assert.Equal(t, 2500*time.Millisecond, sizer.wait)
+
+	for i := 0; i < 10; i++ {
+		sizer.waited(0)
+	}
+	assert.Equal(t, minBatchSize, sizer.size)
+	assert.Equal(t, minBatchWait, sizer.wait)
+}
+
+func TestBatchSizerGrowsUnderHighVolume(t *testing.T) {
+	sizer := newBatchSizer(200, 5*time.Second)
+	for i := 0; i < 10; i++ {
+		sizer.shrink()
+	}
+
+	sizer.grow()
+	assert.Equal(t, 2*minBatchSize, sizer.size)
+	assert.Equal(t, 2*minBatchWait, sizer.wait)
+
+	for i := 0; i < 10; i++ {
+		sizer.grow()
+	}
+	assert.Equal(t, 200, sizer.size)
+	assert.Equal(t, 5*time.Second, sizer.wait)
+}
+
+func TestBatchSizerMinimumsAreBoundedByTheMaximums(t *testing.T) {
+	sizer := newBatchSizer(5, 10*time.Millisecond)
+	sizer.shrink()
+	assert.Equal(t, 5, sizer.size)
+	assert.Equal(t, 10*time.Millisecond, sizer.wait)
+}
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pb"
+)
+
+func TestBatchIsFullWithMaxBatchSize(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	batch := newBatch(2, 100)
+
+	assert.True(t, batch.isEmpty())
+	assert.True(t, batch.add(newMessage([]byte("a"), source, "")))
+	assert.False(t, batch.isFull())
+	assert.True(t, batch.add(newMessage([]byte("b"), source, "")))
+	assert.True(t, batch.isFull())
+	assert.False(t, batch.add(newMessage([]byte("c"), source, "")))
+}
+
+func TestBatchIsFullWithMaxContentSize(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	batch := newBatch(10, 5)
+
+	assert.True(t, batch.add(newMessage([]byte("abc"), source, "")))
+	assert.False(t, batch.add(newMessage([]byte("def"), source, "")))
+	assert.True(t, batch.add(newMessage([]byte("de"), source, "")))
+	assert.True(t, batch.isFull())
+}
+
+func TestBatchAcceptsOversizedMessageWhenEmpty(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	batch := newBatch(10, 5)
+
+	assert.True(t, batch.add(newMessage([]byte("abcdefgh"), source, "")))
+	assert.True(t, batch.isFull())
+}
+
+func TestBatchPayloadAndFlush(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	batch := newBatch(10, 100)
+
+	assert.Equal(t, "[]", string(batch.payload(client.JSONPayloadFormat)))
+
+	batch.add(newMessage([]byte(`{"message":"a"}`), source, ""))
+	batch.add(newMessage([]byte(`{"message":"b"}`), source, ""))
+	assert.Equal(t, `[{"message":"a"},{"message":"b"}]`, string(batch.payload(client.JSONPayloadFormat)))
+
+	messages := batch.flush()
+	assert.Equal(t, 2, len(messages))
+	assert.True(t, batch.isEmpty())
+	assert.Equal(t, "[]", string(batch.payload(client.JSONPayloadFormat)))
+}
+
+func TestBatchProtobufPayload(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	batch := newBatch(10, 1000)
+
+	assert.Empty(t, batch.payload(client.ProtobufPayloadFormat))
+
+	var contents []string
+	for _, message := range []string{"a", strings.Repeat("b", 200)} {
+		content, err := (&pb.Log{Message: message}).Marshal()
+		assert.Nil(t, err)
+		batch.add(newMessage(content, source, ""))
+		contents = append(contents, message)
+	}
+
+	// the payload is a sequence of logs, each one prefixed with the key of field 1 and its length.
+	payload := batch.payload(client.ProtobufPayloadFormat)
+	var messages []string
+	for len(payload) > 0 {
+		assert.Equal(t, byte(0x0a), payload[0])
+		length, n := binary.Uvarint(payload[1:])
+		assert.True(t, n > 0)
+		payload = payload[1+n:]
+		log := &pb.Log{}
+		assert.Nil(t, log.Unmarshal(payload[:length]))
+		messages = append(messages, log.Message)
+		payload = payload[length:]
+	}
+	assert.Equal(t, contents, messages)
+}
+
+func TestBatchRejectsMessagesWithAnotherAPIKey(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	keyedSource := config.NewLogSource("", &config.LogsConfig{APIKey: "foo"})
+	batch := newBatch(10, 100)
+
+	assert.True(t, batch.add(newMessage([]byte("a"), keyedSource, "")))
+	assert.True(t, batch.add(newMessage([]byte("b"), keyedSource, "")))
+	assert.False(t, batch.add(newMessage([]byte("c"), source, "")))
+	assert.Equal(t, "foo", batch.apiKey)
+
+	batch.flush()
+	assert.Equal(t, "", batch.apiKey)
+	assert.True(t, batch.add(newMessage([]byte("c"), source, "")))
+}
@@ -7,15 +7,25 @@ package sender
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
-const endpointPrefix = "agent-intake.logs."
+const (
+	endpointPrefix        = "agent-intake.logs."
+	httpEndpointPrefix    = "agent-http-intake.logs."
+	port443EndpointPrefix = "agent-443-intake.logs."
+	// fipsLogsPortOffset is the offset of the port of the logs intake of the FIPS proxy from 'fips.port_range_start'.
+	fipsLogsPortOffset = 5
+)
 
 var logsEndpoints = map[string]int{
 	"agent-intake.logs.datadoghq.com": 10516,
@@ -30,59 +40,300 @@ func BuildEndpoints() (*client.Endpoints, error) {
 		log.Warnf("Use of illegal configuration parameter, if you need to send your logs to a proxy, please use 'logs_config.logs_dd_url' and 'logs_config.logs_no_ssl' instead")
 	}
 
-	var useSSL bool
+	var useSSL, useAggregator bool
+	useHTTP := config.Datadog.GetBool("logs_config.use_http")
 	useProto := config.Datadog.GetBool("logs_config.dev_mode_use_proto")
 	proxyAddress := config.Datadog.GetString("logs_config.socks5_proxy_address")
+	proxyUsername := config.Datadog.GetString("logs_config.socks5_proxy_username")
+	proxyPassword := config.Datadog.GetString("logs_config.socks5_proxy_password")
+	backoff := getBackoffPolicy(config.Datadog)
+	connectionPoolSize := config.Datadog.GetInt("logs_config.connection_pool_size")
+	timeouts := getConnectionTimeouts(config.Datadog)
 	main := client.Endpoint{
-		APIKey:       getLogsAPIKey(config.Datadog),
-		UseProto:     useProto,
-		ProxyAddress: proxyAddress,
+		APIKey:             getLogsAPIKey(config.Datadog),
+		UseProto:           useProto,
+		UseOctetCounting:   useOctetCounting(config.Datadog),
+		ProxyAddress:       proxyAddress,
+		ProxyUsername:      proxyUsername,
+		ProxyPassword:      proxyPassword,
+		Backoff:            backoff,
+		Timeouts:           timeouts,
+		ConnectionPoolSize: connectionPoolSize,
+		PayloadFormat:      getPayloadFormat(config.Datadog),
+		UseCompression:     config.Datadog.GetBool("logs_config.use_compression"),
+		CompressionLevel:   config.Datadog.GetInt("logs_config.compression_level"),
+		CAFile:             config.Datadog.GetString("logs_config.ca_file"),
+		CertFile:           config.Datadog.GetString("logs_config.cert_file"),
+		KeyFile:            config.Datadog.GetString("logs_config.key_file"),
+		KeyPassphrase:      config.Datadog.GetString("logs_config.key_passphrase"),
+		MinTLSVersion:      config.Datadog.GetString("logs_config.min_tls_version"),
+		CipherSuites:       config.Datadog.GetStringSlice("logs_config.cipher_suites"),
+		PinnedPublicKeys:   config.Datadog.GetStringSlice("logs_config.pinned_public_keys"),
+		IPFamily:           getIPFamily(config.Datadog),
+		DNSRefreshInterval: time.Duration(config.Datadog.GetInt("logs_config.dns_refresh_interval")) * time.Second,
+		FIPS:               config.Datadog.GetBool("fips.enabled"),
 	}
+	// the API key of the main endpoint can be rotated by an APIKeyRefresher
+	main.APIKeyHolder = client.NewAPIKeyHolder(main.APIKey)
+	useFIPSProxy := main.FIPS && config.Datadog.GetBool("fips.use_proxy")
 	switch {
+	case useFIPSProxy:
+		// The FIPS proxy runs next to the agent and encrypts the logs with FIPS-compliant TLS,
+		// it only forwards to the HTTP intake.
+		if !useHTTP {
+			log.Infof("'fips.enabled' is set, sending logs over HTTP to the FIPS proxy")
+			useHTTP = true
+		}
+		main.Host = config.Datadog.GetString("fips.local_address")
+		main.Port = config.Datadog.GetInt("fips.port_range_start") + fipsLogsPortOffset
+		useSSL = false
+	case isSetAndNotEmpty(config.Datadog, "logs_config.aggregator_url"):
+		// The aggregator is an agent of the same network which sends the logs of the agents forwarding to it
+		// to the intake, expect 'logs_config.aggregator_url' to respect the format '[<SCHEME>://]<HOST>:<PORT>'.
+		host, port, err := parseAddress(config.Datadog.GetString("logs_config.aggregator_url"))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse aggregator_url: %v", err)
+		}
+		if useHTTP {
+			log.Infof("'logs_config.aggregator_url' is set, sending logs over TCP to the aggregator")
+			useHTTP = false
+		}
+		useAggregator = true
+		main.Host = host
+		main.Port = port
+		main.UseProto = false
+		main.UseOctetCounting = false
+		useSSL = !config.Datadog.GetBool("logs_config.aggregator_no_ssl")
 	case isSetAndNotEmpty(config.Datadog, "logs_config.logs_dd_url"):
-		// Proxy settings, expect 'logs_config.logs_dd_url' to respect the format '<HOST>:<PORT>'
+		// Proxy settings, expect 'logs_config.logs_dd_url' to respect the format '[<SCHEME>://]<HOST>:<PORT>'
 		// and '<PORT>' to be an integer.
 		// By default ssl is enabled ; to disable ssl set 'logs_config.logs_no_ssl' to true.
-		host, portString, err := net.SplitHostPort(config.Datadog.GetString("logs_config.logs_dd_url"))
+		host, port, err := parseAddress(config.Datadog.GetString("logs_config.logs_dd_url"))
 		if err != nil {
 			return nil, fmt.Errorf("could not parse logs_dd_url: %v", err)
 		}
-		port, err := strconv.Atoi(portString)
+		main.Host = host
+		main.Port = port
+		useSSL = !config.Datadog.GetBool("logs_config.logs_no_ssl")
+	case useHTTP:
+		// The HTTP intake is reachable on port 443 unless 'logs_config.dd_url' holds another port,
+		// we default to 'logs_config.dd_url' if set, or to 'site'.
+		host, port, err := getIntakeAddress(config.Datadog, httpEndpointPrefix, "logs_config.dd_url")
 		if err != nil {
-			return nil, fmt.Errorf("could not parse logs_dd_url port: %v", err)
+			return nil, err
+		}
+		if port == 0 {
+			port = 443
 		}
 		main.Host = host
 		main.Port = port
-		useSSL = !config.Datadog.GetBool("logs_config.logs_no_ssl")
+		useSSL = !config.Datadog.GetBool("logs_config.dev_mode_no_ssl")
 	case config.Datadog.GetBool("logs_config.use_port_443"):
-		main.Host = config.Datadog.GetString("logs_config.dd_url_443")
+		// we default to 'logs_config.dd_url_443' if set, or to the port 443 intake of 'site'.
+		main.Host = config.GetMainEndpointWithConfig(config.Datadog, port443EndpointPrefix, "logs_config.dd_url_443")
 		main.Port = 443
 		useSSL = true
 	default:
 		// If no proxy is set, we default to 'logs_config.dd_url' if set, or to 'site'.
 		// if none of them is set, we default to the US agent endpoint.
-		main.Host = config.GetMainEndpoint(endpointPrefix, "logs_config.dd_url")
-		if port, found := logsEndpoints[main.Host]; found {
-			main.Port = port
-		} else {
-			main.Port = config.Datadog.GetInt("logs_config.dd_port")
+		host, port, err := getIntakeAddress(config.Datadog, endpointPrefix, "logs_config.dd_url")
+		if err != nil {
+			return nil, err
+		}
+		if port == 0 {
+			if sitePort, found := logsEndpoints[host]; found {
+				port = sitePort
+			} else {
+				port = config.Datadog.GetInt("logs_config.dd_port")
+			}
 		}
+		main.Host = host
+		main.Port = port
 		useSSL = !config.Datadog.GetBool("logs_config.dev_mode_no_ssl")
 	}
 	main.UseSSL = useSSL
+	useHTTPSProxy := !useHTTP && config.Datadog.GetBool("logs_config.use_https_proxy")
+	if useHTTPSProxy {
+		if proxyAddress != "" {
+			log.Warnf("Both 'logs_config.socks5_proxy_address' and 'logs_config.use_https_proxy' are set, using the socks5 proxy")
+		}
+		main.HTTPProxyURL = getHTTPProxyURL(config.GetProxies(), main.Host)
+	}
+	if useSSL {
+		// fail early on a misconfigured CA bundle, client certificate, TLS version or pin rather than on every connection attempt.
+		if _, err := client.NewTLSConfig(main); err != nil {
+			return nil, err
+		}
+	}
+
+	main.Failover = getFailoverPolicy(config.Datadog, main)
+	if useFIPSProxy {
+		// the fallback endpoints are not reachable through the FIPS proxy, they are sent to with FIPS-compliant TLS.
+		for i := range main.Failover.Fallbacks {
+			main.Failover.Fallbacks[i].UseSSL = true
+		}
+	}
 
 	var additionals []client.Endpoint
 	err := config.Datadog.UnmarshalKey("logs_config.additional_endpoints", &additionals)
 	if err != nil {
 		log.Warnf("Could not parse additional_endpoints for logs: %v", err)
 	}
+	if useAggregator && len(additionals) > 0 {
+		log.Warnf("'logs_config.additional_endpoints' are ignored when forwarding to an aggregator, the aggregator sends the logs to its own endpoints")
+		additionals = nil
+	}
 	for i := 0; i < len(additionals); i++ {
-		additionals[i].UseSSL = useSSL
+		additionals[i].UseSSL = useSSL || useFIPSProxy
 		additionals[i].UseProto = useProto
+		additionals[i].UseOctetCounting = main.UseOctetCounting
 		additionals[i].ProxyAddress = proxyAddress
+		if additionals[i].ProxyUsername == "" {
+			// additional endpoints can use their own proxy credentials.
+			additionals[i].ProxyUsername = proxyUsername
+			additionals[i].ProxyPassword = proxyPassword
+		}
+		additionals[i].Backoff = backoff
+		additionals[i].CircuitBreaker = getCircuitBreakerPolicy(config.Datadog)
+		additionals[i].Timeouts = timeouts
+		additionals[i].ConnectionPoolSize = connectionPoolSize
+		// the logs are encoded once for all the endpoints.
+		additionals[i].PayloadFormat = main.PayloadFormat
+		additionals[i].UseCompression = main.UseCompression
+		additionals[i].CompressionLevel = main.CompressionLevel
+		if useHTTPSProxy {
+			additionals[i].HTTPProxyURL = getHTTPProxyURL(config.GetProxies(), additionals[i].Host)
+		}
+		additionals[i].CAFile = main.CAFile
+		additionals[i].CertFile = main.CertFile
+		additionals[i].KeyFile = main.KeyFile
+		additionals[i].KeyPassphrase = main.KeyPassphrase
+		additionals[i].MinTLSVersion = main.MinTLSVersion
+		additionals[i].CipherSuites = main.CipherSuites
+		additionals[i].PinnedPublicKeys = main.PinnedPublicKeys
+		additionals[i].IPFamily = main.IPFamily
+		additionals[i].DNSRefreshInterval = main.DNSRefreshInterval
+		additionals[i].FIPS = main.FIPS
+	}
+
+	batchWait := time.Duration(config.Datadog.GetInt("logs_config.batch_wait")) * time.Second
+
+	endpoints := client.NewEndpoints(main, additionals, useHTTP, batchWait)
+	endpoints.UseAggregator = useAggregator
+	endpoints.MaxInflightBatches = config.Datadog.GetInt("logs_config.max_inflight_batches")
+	endpoints.MaxBytesPerSecond = config.Datadog.GetInt("logs_config.max_bytes_per_second")
+	endpoints.MaxEventsPerSecond = config.Datadog.GetInt("logs_config.max_events_per_second")
+	endpoints.TCPMaxBatchSize = config.Datadog.GetInt("logs_config.tcp_max_batch_size")
+	endpoints.TCPBatchLinger = time.Duration(config.Datadog.GetInt("logs_config.tcp_batch_linger")) * time.Millisecond
+	return endpoints, nil
+}
+
+// BuildAPIKeyRefresher returns the refresher of the API key of the main endpoint,
+// or nil if the key is not refreshed.
+func BuildAPIKeyRefresher(endpoints *client.Endpoints) *APIKeyRefresher {
+	interval := config.Datadog.GetInt("logs_config.api_key_refresh_interval")
+	path := config.Datadog.ConfigFileUsed()
+	if interval <= 0 || path == "" || endpoints.Main.APIKeyHolder == nil {
+		return nil
+	}
+	return NewAPIKeyRefresher(endpoints.Main.APIKeyHolder, path, time.Duration(interval)*time.Second)
+}
+
+// BuildDiskBuffers returns the disk buffers the logs of each of the numberOfPipelines pipelines are spilled to
+// when the intake is unreachable, or nil if disk buffering is disabled.
+// Each pipeline has its own directory in 'logs_config.disk_buffer_path' and an equal share of the maximum size,
+// so that it replays its logs in order.
+func BuildDiskBuffers(numberOfPipelines int) ([]*DiskBuffer, error) {
+	path := config.Datadog.GetString("logs_config.disk_buffer_path")
+	if path == "" {
+		return nil, nil
+	}
+	maxSize := config.Datadog.GetInt64("logs_config.disk_buffer_max_size")
+	if maxSize < int64(numberOfPipelines) {
+		return nil, fmt.Errorf("invalid disk_buffer_max_size: %d", maxSize)
+	}
+	buffers := make([]*DiskBuffer, 0, numberOfPipelines)
+	for i := 0; i < numberOfPipelines; i++ {
+		buffer, err := NewDiskBuffer(filepath.Join(path, strconv.Itoa(i)), maxSize/int64(numberOfPipelines))
+		if err != nil {
+			for _, buffer := range buffers {
+				buffer.Close()
+			}
+			return nil, err
+		}
+		buffers = append(buffers, buffer)
+	}
+	warnAboutOrphanDiskBuffers(path, numberOfPipelines)
+	return buffers, nil
+}
+
+// warnAboutOrphanDiskBuffers warns about the directories of the pipelines of a previous run
+// which do not exist anymore, their logs are not sent.
+func warnAboutOrphanDiskBuffers(path string, numberOfPipelines int) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if i, err := strconv.Atoi(file.Name()); err == nil && file.IsDir() && i >= numberOfPipelines {
+			log.Warnf("The logs buffered on disk in %s are not sent, 'logs_config.pipelines' was lowered", filepath.Join(path, file.Name()))
+		}
+	}
+}
+
+// BuildQuarantine returns the quarantine the HTTP batches given up on are written to,
+// or nil if quarantine is disabled.
+func BuildQuarantine() (*Quarantine, error) {
+	path := config.Datadog.GetString("logs_config.quarantine_path")
+	if path == "" {
+		return nil, nil
+	}
+	maxSize := config.Datadog.GetInt64("logs_config.quarantine_max_size")
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid quarantine_max_size: %d", maxSize)
+	}
+	return NewQuarantine(path, maxSize)
+}
+
+// getIntakeAddress returns the host of the intake, from ddURLKey if set or from the prefix and 'site' otherwise,
+// and the port ddURLKey holds, or 0 if it holds none.
+func getIntakeAddress(cfg config.Config, prefix string, ddURLKey string) (string, int, error) {
+	address := config.GetMainEndpointWithConfig(cfg, prefix, ddURLKey)
+	if !isSetAndNotEmpty(cfg, ddURLKey) {
+		return address, 0, nil
 	}
+	address = trimScheme(address)
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		// no port in the override
+		return address, 0, nil
+	}
+	host, port, err := parseAddress(address)
+	if err != nil {
+		return "", 0, fmt.Errorf("could not parse %s: %v", ddURLKey, err)
+	}
+	return host, port, nil
+}
 
-	return client.NewEndpoints(main, additionals), nil
+// parseAddress returns the host and the port of an address in the format '[<SCHEME>://]<HOST>:<PORT>'.
+func parseAddress(address string) (string, int, error) {
+	host, portString, err := net.SplitHostPort(trimScheme(address))
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port %q: %v", portString, err)
+	}
+	return host, port, nil
+}
+
+// trimScheme removes the scheme and the trailing slash of an address.
+func trimScheme(address string) string {
+	address = strings.TrimSpace(address)
+	if i := strings.Index(address, "://"); i >= 0 {
+		address = address[i+len("://"):]
+	}
+	return strings.TrimSuffix(address, "/")
 }
 
 func isSetAndNotEmpty(config config.Config, key string) bool {
@@ -96,3 +347,127 @@ func getLogsAPIKey(config config.Config) string {
 	}
 	return config.GetString("api_key")
 }
+
+// getBackoffPolicy returns the backoff policy to apply between connection attempts.
+func getBackoffPolicy(config config.Config) client.BackoffPolicy {
+	return client.BackoffPolicy{
+		Base:              time.Duration(config.GetInt("logs_config.connection_backoff_base")) * time.Second,
+		Max:               time.Duration(config.GetInt("logs_config.connection_backoff_max")) * time.Second,
+		MaxRetries:        config.GetInt("logs_config.connection_max_retries"),
+		MaxPayloadRetries: config.GetInt("logs_config.batch_max_retries"),
+	}
+}
+
+// getCircuitBreakerPolicy returns the parameters of the circuit breakers of the additional endpoints.
+func getCircuitBreakerPolicy(config config.Config) client.CircuitBreakerPolicy {
+	return client.CircuitBreakerPolicy{
+		Threshold: config.GetInt("logs_config.circuit_breaker_threshold"),
+		Cooldown:  time.Duration(config.GetInt("logs_config.circuit_breaker_cooldown")) * time.Second,
+	}
+}
+
+// getHTTPProxyURL returns the URL of the proxy to tunnel the connections to host through,
+// or an empty string if no https proxy is set or host is excluded with 'proxy.no_proxy'.
+func getHTTPProxyURL(proxies *config.Proxy, host string) string {
+	if proxies == nil {
+		return ""
+	}
+	for _, noProxy := range proxies.NoProxy {
+		if host == noProxy {
+			return ""
+		}
+	}
+	return proxies.HTTPS
+}
+
+// getConnectionTimeouts returns the timeouts of the network operations of the connections.
+func getConnectionTimeouts(config config.Config) client.ConnectionTimeouts {
+	return client.ConnectionTimeouts{
+		Dial:      time.Duration(config.GetInt("logs_config.dial_timeout")) * time.Second,
+		Handshake: time.Duration(config.GetInt("logs_config.ssl_handshake_timeout")) * time.Second,
+		Write:     time.Duration(config.GetInt("logs_config.write_timeout")) * time.Second,
+		KeepAlive: time.Duration(config.GetInt("logs_config.tcp_keepalive")) * time.Second,
+		Idle:      time.Duration(config.GetInt("logs_config.idle_connection_timeout")) * time.Second,
+	}
+}
+
+// getIPFamily returns the address family to restrict the connections to,
+// or an empty string to use both IPv4 and IPv6.
+func getIPFamily(config config.Config) string {
+	family := strings.ToLower(config.GetString("logs_config.ip_family"))
+	switch family {
+	case "", "any":
+		return ""
+	case client.IPv4, client.IPv6:
+		return family
+	default:
+		log.Warnf("Invalid logs_config.ip_family: %v, using both IPv4 and IPv6", family)
+		return ""
+	}
+}
+
+// useOctetCounting returns true if the raw frames should be delimited with their length
+// instead of a line break, the frames are always delimited with their length with protobuf.
+func useOctetCounting(config config.Config) bool {
+	framing := strings.ToLower(config.GetString("logs_config.raw_framing"))
+	switch framing {
+	case "", "line_break":
+		return false
+	case "octet_counting":
+		return true
+	default:
+		log.Warnf("Invalid logs_config.raw_framing: %v, delimiting the logs with line breaks", framing)
+		return false
+	}
+}
+
+// getPayloadFormat returns the format of the payloads sent with use_http, json unless protobuf is configured.
+func getPayloadFormat(config config.Config) string {
+	format := strings.ToLower(config.GetString("logs_config.http_payload_format"))
+	switch format {
+	case "", client.JSONPayloadFormat:
+		return client.JSONPayloadFormat
+	case client.ProtobufPayloadFormat:
+		return client.ProtobufPayloadFormat
+	default:
+		log.Warnf("Invalid logs_config.http_payload_format: %v, sending the logs in json", format)
+		return client.JSONPayloadFormat
+	}
+}
+
+// getFailoverPolicy returns the endpoints to fail over to when the intake of main is unreachable,
+// the fallback endpoints share the settings of main unless overridden.
+func getFailoverPolicy(config config.Config, main client.Endpoint) client.FailoverPolicy {
+	var fallbacks []client.Endpoint
+	err := config.UnmarshalKey("logs_config.fallback_endpoints", &fallbacks)
+	if err != nil {
+		log.Warnf("Could not parse fallback_endpoints for logs: %v", err)
+	}
+	for i, fallback := range fallbacks {
+		endpoint := main
+		endpoint.Host = fallback.Host
+		endpoint.Port = fallback.Port
+		if fallback.APIKey != "" {
+			endpoint.APIKey = fallback.APIKey
+			endpoint.APIKeyHolder = nil
+		}
+		fallbacks[i] = endpoint
+	}
+	return client.FailoverPolicy{
+		Fallbacks:        fallbacks,
+		Retries:          config.GetInt("logs_config.failover_retries"),
+		FailbackInterval: time.Duration(config.GetInt("logs_config.failback_interval")) * time.Second,
+		Selection:        getEndpointSelection(config),
+	}
+}
+
+// getEndpointSelection returns how the endpoint to send logs to is selected among main and its fallbacks.
+func getEndpointSelection(config config.Config) string {
+	switch selection := config.GetString("logs_config.fallback_endpoints_selection"); selection {
+	case client.OrderedSelection, client.LatencySelection:
+		return selection
+	default:
+		log.Warnf("Invalid logs_config.fallback_endpoints_selection: %v, using the endpoints in order", selection)
+		return client.OrderedSelection
+	}
+}
@@ -6,7 +6,12 @@
 package sender
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 
@@ -162,6 +167,484 @@ func (suite *ConfigTestSuite) TestOverrideApiKey() {
 	suite.Equal("wassuplogskey", endpoints.Main.APIKey)
 }
 
+func (suite *ConfigTestSuite) TestBuildEndpointsWithHTTP() {
+	suite.config.Set("api_key", "azerty")
+	suite.config.Set("logs_config.use_http", true)
+
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.True(endpoints.UseHTTP)
+	suite.Equal(5*time.Second, endpoints.BatchWait)
+	suite.Equal("azerty", endpoints.Main.APIKey)
+	suite.Equal("agent-http-intake.logs.datadoghq.com", endpoints.Main.Host)
+	suite.Equal(443, endpoints.Main.Port)
+	suite.True(endpoints.Main.UseSSL)
+
+	suite.config.Set("site", "datadoghq.eu")
+	suite.config.Set("logs_config.batch_wait", 1)
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(time.Second, endpoints.BatchWait)
+	suite.Equal("agent-http-intake.logs.datadoghq.eu", endpoints.Main.Host)
+	suite.Equal(443, endpoints.Main.Port)
+
+	suite.config.Set("logs_config.logs_dd_url", "host:1234")
+	suite.config.Set("logs_config.logs_no_ssl", true)
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.True(endpoints.UseHTTP)
+	suite.Equal("host", endpoints.Main.Host)
+	suite.Equal(1234, endpoints.Main.Port)
+	suite.False(endpoints.Main.UseSSL)
+}
+
+func (suite *ConfigTestSuite) TestBuildEndpointsFromSite() {
+	suite.config.Set("site", "datadoghq.eu")
+	suite.config.Set("logs_config.use_port_443", true)
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("agent-443-intake.logs.datadoghq.eu", endpoints.Main.Host)
+	suite.Equal(443, endpoints.Main.Port)
+
+	suite.config.Set("logs_config.dd_url_443", "logs-443.example.com")
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("logs-443.example.com", endpoints.Main.Host)
+	suite.Equal(443, endpoints.Main.Port)
+}
+
+func (suite *ConfigTestSuite) TestBuildEndpointsWithPortInDDURL() {
+	suite.config.Set("logs_config.dd_url", "logs.example.com:10514")
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("logs.example.com", endpoints.Main.Host)
+	suite.Equal(10514, endpoints.Main.Port)
+
+	suite.config.Set("logs_config.use_http", true)
+	suite.config.Set("logs_config.dd_url", "https://http-logs.example.com:8443/")
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("http-logs.example.com", endpoints.Main.Host)
+	suite.Equal(8443, endpoints.Main.Port)
+
+	suite.config.Set("logs_config.dd_url", "http-logs.example.com")
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("http-logs.example.com", endpoints.Main.Host)
+	suite.Equal(443, endpoints.Main.Port)
+
+	suite.config.Set("logs_config.dd_url", "http-logs.example.com:foo")
+	_, err = BuildEndpoints()
+	suite.NotNil(err)
+}
+
+func (suite *ConfigTestSuite) TestBuildEndpointsWithSchemeInLogsDDURL() {
+	suite.config.Set("logs_config.logs_dd_url", "https://proxy.example.com:1234")
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("proxy.example.com", endpoints.Main.Host)
+	suite.Equal(1234, endpoints.Main.Port)
+}
+
+func (suite *ConfigTestSuite) TestBuildEndpointsWithAggregator() {
+	suite.config.Set("logs_config.aggregator_url", "aggregator.example.com:10520")
+	suite.config.Set("logs_config.use_http", true)
+	suite.config.Set("logs_config.dev_mode_use_proto", true)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 443}})
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.True(endpoints.UseAggregator)
+	suite.False(endpoints.UseHTTP)
+	suite.Equal("aggregator.example.com", endpoints.Main.Host)
+	suite.Equal(10520, endpoints.Main.Port)
+	suite.True(endpoints.Main.UseSSL)
+	suite.False(endpoints.Main.UseProto)
+	// the aggregator sends the logs to its own endpoints.
+	suite.Empty(endpoints.Additionals)
+
+	suite.config.Set("logs_config.aggregator_no_ssl", true)
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.False(endpoints.Main.UseSSL)
+
+	suite.config.Set("logs_config.aggregator_url", "aggregator.example.com")
+	_, err = BuildEndpoints()
+	suite.NotNil(err)
+}
+
+func (suite *ConfigTestSuite) TestBuildEndpointsWithFIPSProxy() {
+	suite.config.Set("fips.enabled", true)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 443}})
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.True(endpoints.UseHTTP)
+	suite.Equal("localhost", endpoints.Main.Host)
+	suite.Equal(9808, endpoints.Main.Port)
+	suite.False(endpoints.Main.UseSSL)
+	suite.True(endpoints.Main.FIPS)
+	// the additional endpoints are sent to directly
+	suite.Len(endpoints.Additionals, 1)
+	suite.True(endpoints.Additionals[0].UseSSL)
+	suite.True(endpoints.Additionals[0].FIPS)
+
+	// the FIPS proxy takes precedence over the other endpoints
+	suite.config.Set("logs_config.logs_dd_url", "host:1234")
+	suite.config.Set("fips.local_address", "127.0.0.1")
+	suite.config.Set("fips.port_range_start", 9900)
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("127.0.0.1", endpoints.Main.Host)
+	suite.Equal(9905, endpoints.Main.Port)
+}
+
+func (suite *ConfigTestSuite) TestBuildEndpointsWithFIPSWithoutProxy() {
+	suite.config.Set("fips.enabled", true)
+	suite.config.Set("fips.use_proxy", false)
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("agent-intake.logs.datadoghq.com", endpoints.Main.Host)
+	suite.True(endpoints.Main.UseSSL)
+	suite.True(endpoints.Main.FIPS)
+
+	// the TLS settings must be FIPS-approved
+	suite.config.Set("logs_config.min_tls_version", "tlsv1.0")
+	_, err = BuildEndpoints()
+	suite.NotNil(err)
+}
+
+func (suite *ConfigTestSuite) TestConnectionPoolSize() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(1, endpoints.Main.ConnectionPoolSize)
+
+	suite.config.Set("logs_config.connection_pool_size", 4)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(4, endpoints.Main.ConnectionPoolSize)
+	suite.Equal(4, endpoints.Additionals[0].ConnectionPoolSize)
+}
+
+func (suite *ConfigTestSuite) TestTLSFiles() {
+	suite.config.Set("logs_config.ca_file", "/path/to/ca.pem")
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	suite.config.Set("logs_config.dev_mode_no_ssl", true)
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("/path/to/ca.pem", endpoints.Main.CAFile)
+	suite.Equal("/path/to/ca.pem", endpoints.Additionals[0].CAFile)
+
+	// the files are loaded when SSL is enabled.
+	suite.config.Set("logs_config.dev_mode_no_ssl", false)
+	_, err = BuildEndpoints()
+	suite.NotNil(err)
+
+	suite.config.Set("logs_config.ca_file", "")
+	suite.config.Set("logs_config.cert_file", "/path/to/cert.pem")
+	_, err = BuildEndpoints()
+	suite.NotNil(err)
+}
+
+func (suite *ConfigTestSuite) TestTLSRestrictions() {
+	suite.config.Set("logs_config.min_tls_version", "tlsv1.2")
+	suite.config.Set("logs_config.cipher_suites", []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"})
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("tlsv1.2", endpoints.Main.MinTLSVersion)
+	suite.Equal([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}, endpoints.Main.CipherSuites)
+	suite.Equal("tlsv1.2", endpoints.Additionals[0].MinTLSVersion)
+	suite.Equal([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}, endpoints.Additionals[0].CipherSuites)
+
+	suite.config.Set("logs_config.min_tls_version", "sslv3")
+	_, err = BuildEndpoints()
+	suite.NotNil(err)
+}
+
+func (suite *ConfigTestSuite) TestPinnedPublicKeys() {
+	pin := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	suite.config.Set("logs_config.pinned_public_keys", []string{pin})
+	suite.config.Set("logs_config.fallback_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "bar", "port": 1234}})
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal([]string{pin}, endpoints.Main.PinnedPublicKeys)
+	suite.Equal([]string{pin}, endpoints.Main.Failover.Fallbacks[0].PinnedPublicKeys)
+	suite.Equal([]string{pin}, endpoints.Additionals[0].PinnedPublicKeys)
+
+	suite.config.Set("logs_config.pinned_public_keys", []string{"foo"})
+	_, err = BuildEndpoints()
+	suite.NotNil(err)
+}
+
+func (suite *ConfigTestSuite) TestProxyCredentials() {
+	suite.config.Set("logs_config.socks5_proxy_address", "boz:1234")
+	suite.config.Set("logs_config.socks5_proxy_username", "foo")
+	suite.config.Set("logs_config.socks5_proxy_password", "bar")
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{
+		{"host": "foo", "port": 1234},
+		{"host": "bar", "port": 1234, "proxy_username": "baz", "proxy_password": "qux"},
+	})
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("foo", endpoints.Main.ProxyUsername)
+	suite.Equal("bar", endpoints.Main.ProxyPassword)
+	suite.Equal("foo", endpoints.Additionals[0].ProxyUsername)
+	suite.Equal("bar", endpoints.Additionals[0].ProxyPassword)
+	suite.Equal("baz", endpoints.Additionals[1].ProxyUsername)
+	suite.Equal("qux", endpoints.Additionals[1].ProxyPassword)
+}
+
+func (suite *ConfigTestSuite) TestHTTPSProxy() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("", endpoints.Main.HTTPProxyURL)
+
+	proxies := &coreConfig.Proxy{
+		HTTPS:   "https://proxy:3128",
+		NoProxy: []string{"foo"},
+	}
+	suite.Equal("https://proxy:3128", getHTTPProxyURL(proxies, "agent-intake.logs.datadoghq.com"))
+	suite.Equal("", getHTTPProxyURL(proxies, "foo"))
+	suite.Equal("", getHTTPProxyURL(nil, "agent-intake.logs.datadoghq.com"))
+}
+
+func (suite *ConfigTestSuite) TestFailoverPolicy() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Empty(endpoints.Main.Failover.Fallbacks)
+	suite.Equal(3, endpoints.Main.Failover.Retries)
+	suite.Equal(300*time.Second, endpoints.Main.Failover.FailbackInterval)
+	suite.Equal(client.OrderedSelection, endpoints.Main.Failover.Selection)
+
+	suite.config.Set("api_key", "azerty")
+	suite.config.Set("logs_config.failover_retries", 5)
+	suite.config.Set("logs_config.fallback_endpoints", []map[string]interface{}{
+		{"host": "foo", "port": 1234},
+		{"host": "bar", "port": 5678, "api_key": "qwerty"},
+	})
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	failover := endpoints.Main.Failover
+	suite.Equal(5, failover.Retries)
+	suite.Len(failover.Fallbacks, 2)
+	suite.Equal("foo", failover.Fallbacks[0].Host)
+	suite.Equal(1234, failover.Fallbacks[0].Port)
+	suite.Equal("azerty", failover.Fallbacks[0].APIKey)
+	suite.True(failover.Fallbacks[0].UseSSL)
+	suite.Equal("bar", failover.Fallbacks[1].Host)
+	suite.Equal("qwerty", failover.Fallbacks[1].APIKey)
+
+	suite.config.Set("logs_config.fallback_endpoints_selection", "latency")
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.LatencySelection, endpoints.Main.Failover.Selection)
+
+	suite.config.Set("logs_config.fallback_endpoints_selection", "fastest")
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.OrderedSelection, endpoints.Main.Failover.Selection)
+}
+
+func (suite *ConfigTestSuite) TestCompression() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.False(endpoints.Main.UseCompression)
+	suite.Equal(6, endpoints.Main.CompressionLevel)
+
+	suite.config.Set("logs_config.use_compression", true)
+	suite.config.Set("logs_config.compression_level", 9)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.True(endpoints.Main.UseCompression)
+	suite.Equal(9, endpoints.Main.CompressionLevel)
+	suite.True(endpoints.Additionals[0].UseCompression)
+	suite.Equal(9, endpoints.Additionals[0].CompressionLevel)
+}
+
+func (suite *ConfigTestSuite) TestMaxInflightBatches() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(1, endpoints.MaxInflightBatches)
+
+	suite.config.Set("logs_config.max_inflight_batches", 4)
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(4, endpoints.MaxInflightBatches)
+}
+
+func (suite *ConfigTestSuite) TestTCPBatching() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(100, endpoints.TCPMaxBatchSize)
+	suite.Equal(time.Duration(0), endpoints.TCPBatchLinger)
+
+	suite.config.Set("logs_config.tcp_max_batch_size", 10)
+	suite.config.Set("logs_config.tcp_batch_linger", 5)
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(10, endpoints.TCPMaxBatchSize)
+	suite.Equal(5*time.Millisecond, endpoints.TCPBatchLinger)
+}
+
+func (suite *ConfigTestSuite) TestConnectionTimeouts() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.ConnectionTimeouts{Dial: 20 * time.Second, Handshake: 20 * time.Second, Write: 30 * time.Second, KeepAlive: 30 * time.Second}, endpoints.Main.Timeouts)
+
+	suite.config.Set("logs_config.dial_timeout", 5)
+	suite.config.Set("logs_config.ssl_handshake_timeout", 10)
+	suite.config.Set("logs_config.write_timeout", 0)
+	suite.config.Set("logs_config.tcp_keepalive", 0)
+	suite.config.Set("logs_config.idle_connection_timeout", 60)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	expected := client.ConnectionTimeouts{Dial: 5 * time.Second, Handshake: 10 * time.Second, Idle: 60 * time.Second}
+	suite.Equal(expected, endpoints.Main.Timeouts)
+	suite.Equal(expected, endpoints.Additionals[0].Timeouts)
+}
+
+func (suite *ConfigTestSuite) TestIPFamily() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("", endpoints.Main.IPFamily)
+
+	suite.config.Set("logs_config.ip_family", "IPv6")
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.IPv6, endpoints.Main.IPFamily)
+	suite.Equal(client.IPv6, endpoints.Additionals[0].IPFamily)
+
+	suite.config.Set("logs_config.ip_family", "foo")
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal("", endpoints.Main.IPFamily)
+}
+
+func (suite *ConfigTestSuite) TestRawFraming() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.False(endpoints.Main.UseOctetCounting)
+
+	suite.config.Set("logs_config.raw_framing", "octet_counting")
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.True(endpoints.Main.UseOctetCounting)
+	suite.True(endpoints.Additionals[0].UseOctetCounting)
+
+	suite.config.Set("logs_config.raw_framing", "foo")
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.False(endpoints.Main.UseOctetCounting)
+}
+
+func (suite *ConfigTestSuite) TestHTTPPayloadFormat() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.JSONPayloadFormat, endpoints.Main.PayloadFormat)
+
+	suite.config.Set("logs_config.http_payload_format", "Protobuf")
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.ProtobufPayloadFormat, endpoints.Main.PayloadFormat)
+	suite.Equal(client.ProtobufPayloadFormat, endpoints.Additionals[0].PayloadFormat)
+
+	suite.config.Set("logs_config.http_payload_format", "foo")
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.JSONPayloadFormat, endpoints.Main.PayloadFormat)
+}
+
+func (suite *ConfigTestSuite) TestDNSRefreshInterval() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(5*time.Minute, endpoints.Main.DNSRefreshInterval)
+
+	suite.config.Set("logs_config.dns_refresh_interval", 0)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(time.Duration(0), endpoints.Main.DNSRefreshInterval)
+	suite.Equal(time.Duration(0), endpoints.Additionals[0].DNSRefreshInterval)
+}
+
+func (suite *ConfigTestSuite) TestBackoffPolicy() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.BackoffPolicy{Base: time.Second, Max: 2 * time.Minute, MaxPayloadRetries: 10}, endpoints.Main.Backoff)
+
+	suite.config.Set("logs_config.connection_backoff_base", 2)
+	suite.config.Set("logs_config.connection_backoff_max", 30)
+	suite.config.Set("logs_config.connection_max_retries", 5)
+	suite.config.Set("logs_config.batch_max_retries", 3)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	expected := client.BackoffPolicy{Base: 2 * time.Second, Max: 30 * time.Second, MaxRetries: 5, MaxPayloadRetries: 3}
+	suite.Equal(expected, endpoints.Main.Backoff)
+	suite.Equal(1, len(endpoints.Additionals))
+	suite.Equal(expected, endpoints.Additionals[0].Backoff)
+}
+
+func (suite *ConfigTestSuite) TestCircuitBreakerPolicy() {
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{{"host": "foo", "port": 1234}})
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.CircuitBreakerPolicy{}, endpoints.Main.CircuitBreaker)
+	suite.Equal(client.CircuitBreakerPolicy{Threshold: 5, Cooldown: 30 * time.Second}, endpoints.Additionals[0].CircuitBreaker)
+
+	suite.config.Set("logs_config.circuit_breaker_threshold", 0)
+	suite.config.Set("logs_config.circuit_breaker_cooldown", 60)
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(client.CircuitBreakerPolicy{Cooldown: time.Minute}, endpoints.Additionals[0].CircuitBreaker)
+}
+
 func TestConfigTestSuite(t *testing.T) {
 	suite.Run(t, new(ConfigTestSuite))
 }
+
+func (suite *ConfigTestSuite) TestRateLimits() {
+	endpoints, err := BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(0, endpoints.MaxBytesPerSecond)
+	suite.Equal(0, endpoints.MaxEventsPerSecond)
+
+	suite.config.Set("logs_config.max_bytes_per_second", 1000000)
+	suite.config.Set("logs_config.max_events_per_second", 500)
+	endpoints, err = BuildEndpoints()
+	suite.Nil(err)
+	suite.Equal(1000000, endpoints.MaxBytesPerSecond)
+	suite.Equal(500, endpoints.MaxEventsPerSecond)
+}
+
+func (suite *ConfigTestSuite) TestBuildDiskBuffers() {
+	buffers, err := BuildDiskBuffers(2)
+	suite.Nil(err)
+	suite.Nil(buffers)
+
+	dir, err := ioutil.TempDir("", "disk-buffers")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+	suite.config.Set("logs_config.disk_buffer_path", dir)
+	suite.config.Set("logs_config.disk_buffer_max_size", 1000)
+
+	// each pipeline has its own directory and an equal share of the maximum size.
+	buffers, err = BuildDiskBuffers(2)
+	suite.Nil(err)
+	suite.Len(buffers, 2)
+	for i, buffer := range buffers {
+		suite.Equal(filepath.Join(dir, strconv.Itoa(i)), buffer.path)
+		suite.Equal(int64(500), buffer.maxSize)
+		buffer.Close()
+	}
+
+	suite.config.Set("logs_config.disk_buffer_max_size", 0)
+	_, err = BuildDiskBuffers(2)
+	suite.NotNil(err)
+}
@@ -0,0 +1,217 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// maxSegmentSize is the size after which a new segment file is created.
+	maxSegmentSize = 10 * 1024 * 1024
+	// segmentExtension is the extension of the segment files.
+	segmentExtension = ".buf"
+	// recordHeaderSize is the size of the length prefixing each record.
+	recordHeaderSize = 4
+)
+
+// errDiskBufferFull is returned when a payload does not fit in the disk buffer anymore.
+var errDiskBufferFull = errors.New("disk buffer is full")
+
+// DiskBuffer is a FIFO queue of payloads persisted on disk.
+// Payloads are appended to segment files named after an increasing sequence number,
+// each record of a segment is the length of the payload as a 4 bytes big endian integer
+// followed by the payload itself. A segment is deleted once all its records have been read,
+// as the read offset is not persisted, the payloads of a partially read segment are read again
+// after a restart.
+// A DiskBuffer is safe for concurrent use.
+type DiskBuffer struct {
+	mutex   sync.Mutex
+	path    string
+	maxSize int64
+	size    int64
+	// segments are the sequence numbers of the segments on disk, oldest first.
+	segments   []int
+	writer     *os.File
+	writerSize int64
+	reader     *os.File
+	bufReader  *bufio.Reader
+}
+
+// NewDiskBuffer returns a disk buffer storing at most maxSize bytes in path,
+// the segments left by a previous run are read first.
+func NewDiskBuffer(path string, maxSize int64) (*DiskBuffer, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &DiskBuffer{
+		path:    path,
+		maxSize: maxSize,
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), segmentExtension) {
+			continue
+		}
+		seq, err := strconv.Atoi(strings.TrimSuffix(file.Name(), segmentExtension))
+		if err != nil {
+			continue
+		}
+		b.segments = append(b.segments, seq)
+		b.size += file.Size()
+	}
+	sort.Ints(b.segments)
+	return b, nil
+}
+
+// Push appends a payload to the buffer.
+func (b *DiskBuffer) Push(payload []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	recordSize := int64(recordHeaderSize + len(payload))
+	if b.size+recordSize > b.maxSize {
+		return errDiskBufferFull
+	}
+	if b.writer == nil || b.writerSize+recordSize > maxSegmentSize {
+		if err := b.rotate(); err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, recordSize)
+	binary.BigEndian.PutUint32(record, uint32(len(payload)))
+	copy(record[recordHeaderSize:], payload)
+	n, err := b.writer.Write(record)
+	b.writerSize += int64(n)
+	b.size += int64(n)
+	return err
+}
+
+// Pop returns the oldest payload of the buffer, or nil if the buffer is empty.
+func (b *DiskBuffer) Pop() ([]byte, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for len(b.segments) > 0 {
+		if b.reader == nil {
+			reader, err := os.Open(b.segmentPath(b.segments[0]))
+			if err != nil {
+				b.dropOldestSegment()
+				return nil, err
+			}
+			b.reader = reader
+			b.bufReader = bufio.NewReader(reader)
+		}
+
+		payload, err := b.readRecord()
+		if err == nil {
+			return payload, nil
+		}
+		if err != io.EOF {
+			log.Warnf("Could not read %v, dropping it: %v", b.reader.Name(), err)
+		} else if len(b.segments) == 1 && b.writer != nil {
+			// the reader caught up with the writer, start over with a new segment on the next push.
+			b.writer.Close()
+			b.writer = nil
+		}
+		b.dropOldestSegment()
+	}
+	return nil, nil
+}
+
+// readRecord reads the next record of the oldest segment.
+func (b *DiskBuffer) readRecord() ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(b.bufReader, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("truncated record header")
+		}
+		return nil, err
+	}
+	length := int64(binary.BigEndian.Uint32(header))
+	if length > b.maxSize {
+		return nil, fmt.Errorf("invalid record length %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(b.bufReader, payload); err != nil {
+		return nil, fmt.Errorf("truncated record: %v", err)
+	}
+	return payload, nil
+}
+
+// rotate closes the current segment and creates a new one.
+func (b *DiskBuffer) rotate() error {
+	if b.writer != nil {
+		b.writer.Close()
+		b.writer = nil
+	}
+	seq := 0
+	if len(b.segments) > 0 {
+		seq = b.segments[len(b.segments)-1] + 1
+	}
+	writer, err := os.OpenFile(b.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	b.segments = append(b.segments, seq)
+	b.writer = writer
+	b.writerSize = 0
+	return nil
+}
+
+// dropOldestSegment closes and removes the oldest segment.
+func (b *DiskBuffer) dropOldestSegment() {
+	path := b.segmentPath(b.segments[0])
+	if b.reader != nil {
+		b.reader.Close()
+		b.reader = nil
+		b.bufReader = nil
+	}
+	if info, err := os.Stat(path); err == nil {
+		b.size -= info.Size()
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove %v: %v", path, err)
+	}
+	b.segments = b.segments[1:]
+}
+
+// segmentPath returns the path of the segment with sequence number seq.
+func (b *DiskBuffer) segmentPath(seq int) string {
+	return filepath.Join(b.path, fmt.Sprintf("%020d%s", seq, segmentExtension))
+}
+
+// Close closes the open segments.
+func (b *DiskBuffer) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.writer != nil {
+		b.writer.Close()
+		b.writer = nil
+	}
+	if b.reader != nil {
+		b.reader.Close()
+		b.reader = nil
+		b.bufReader = nil
+	}
+}
@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDiskBuffer(t *testing.T, maxSize int64) (*DiskBuffer, string) {
+	dir, err := ioutil.TempDir("", "disk-buffer")
+	assert.NoError(t, err)
+	buffer, err := NewDiskBuffer(dir, maxSize)
+	assert.NoError(t, err)
+	return buffer, dir
+}
+
+func TestDiskBufferPopsInOrder(t *testing.T) {
+	buffer, dir := newTestDiskBuffer(t, 1024)
+	defer os.RemoveAll(dir)
+
+	payload, err := buffer.Pop()
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+
+	assert.NoError(t, buffer.Push([]byte("foo")))
+	assert.NoError(t, buffer.Push([]byte("bar")))
+
+	payload, err = buffer.Pop()
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(payload))
+
+	assert.NoError(t, buffer.Push([]byte("baz")))
+
+	payload, err = buffer.Pop()
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", string(payload))
+	payload, err = buffer.Pop()
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", string(payload))
+
+	// the segment is removed once fully read.
+	payload, err = buffer.Pop()
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 0)
+	assert.Equal(t, int64(0), buffer.size)
+}
+
+func TestDiskBufferIsBounded(t *testing.T) {
+	buffer, dir := newTestDiskBuffer(t, 2*(recordHeaderSize+3))
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, buffer.Push([]byte("foo")))
+	assert.NoError(t, buffer.Push([]byte("bar")))
+	assert.Equal(t, errDiskBufferFull, buffer.Push([]byte("baz")))
+
+	// space is released once the segment is fully read.
+	buffer.Pop()
+	buffer.Pop()
+	buffer.Pop()
+	assert.NoError(t, buffer.Push([]byte("baz")))
+}
+
+func TestDiskBufferSurvivesRestarts(t *testing.T) {
+	buffer, dir := newTestDiskBuffer(t, 1024)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, buffer.Push([]byte("foo")))
+	assert.NoError(t, buffer.Push([]byte("bar")))
+	buffer.Close()
+
+	buffer, err := NewDiskBuffer(dir, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2*(recordHeaderSize+3)), buffer.size)
+
+	// new payloads go to a new segment read after the previous ones.
+	assert.NoError(t, buffer.Push([]byte("baz")))
+	for _, expected := range []string{"foo", "bar", "baz"} {
+		payload, err := buffer.Pop()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(payload))
+	}
+	payload, err := buffer.Pop()
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+	buffer.Close()
+}
+
+func TestDiskBufferDropsCorruptedSegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "disk-buffer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// a record announcing more bytes than available.
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000000.buf"), []byte{0, 0, 0, 10, 'f', 'o', 'o'}, 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000001.buf"), []byte{0, 0, 0, 3, 'b', 'a', 'r'}, 0600))
+
+	buffer, err := NewDiskBuffer(dir, 1024)
+	assert.NoError(t, err)
+	payload, err := buffer.Pop()
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", string(payload))
+	buffer.Close()
+}
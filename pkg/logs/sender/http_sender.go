@@ -0,0 +1,277 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"context"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// maxBatchSize is the maximum number of logs sent in a single payload.
+	maxBatchSize = 200
+	// maxContentSize is the maximum size of the logs sent in a single payload.
+	maxContentSize = 1000000
+	// defaultBatchWait is the default maximum time a log waits in a batch before being sent.
+	defaultBatchWait = 5 * time.Second
+)
+
+// HTTPSender is responsible for sending batches of logs to different HTTP destinations.
+// Up to maxInflight batches are sent concurrently, a batch is acknowledged by the intake
+// with a 2xx response and its messages are committed to the auditor in order once all the previous
+// batches have been acknowledged too.
+type HTTPSender struct {
+	inputChan   chan *message.Message
+	outputChan  chan *message.Message
+	main        *client.HTTPDestination
+	additionals []*client.HTTPDestination
+	batchWait   time.Duration
+	limiter     *RateLimiter
+	quarantine  *Quarantine
+	sizer       *batchSizer
+	// window bounds the number of batches sent but not committed yet.
+	window    chan struct{}
+	pending   chan *inflightBatch
+	flushChan chan chan struct{}
+	health    *health.Handle
+	done      chan struct{}
+}
+
+// inflightBatch is a batch being sent to the main destination.
+type inflightBatch struct {
+	messages []*message.Message
+	sent     chan struct{}
+	// flushed, when set, marks the end of a flush instead of a batch, it's closed
+	// once all the batches sent before it have been committed.
+	flushed chan struct{}
+}
+
+// NewHTTPSender returns a new HTTP sender that flushes a batch when it's full or after it waited
+// at most batchWait, the size of the batches is adapted to the throughput,
+// and sends at most maxInflight batches concurrently, the messages are batched no faster than limiter allows.
+// The batches the intake would never accept are written to quarantine if it's not nil.
+func NewHTTPSender(inputChan, outputChan chan *message.Message, main *client.HTTPDestination, additionals []*client.HTTPDestination, batchWait time.Duration, maxInflight int, limiter *RateLimiter, quarantine *Quarantine) *HTTPSender {
+	if batchWait <= 0 {
+		batchWait = defaultBatchWait
+	}
+	if maxInflight < 1 {
+		maxInflight = 1
+	}
+	return &HTTPSender{
+		inputChan:   inputChan,
+		outputChan:  outputChan,
+		main:        main,
+		additionals: additionals,
+		batchWait:   batchWait,
+		limiter:     limiter,
+		quarantine:  quarantine,
+		sizer:       newBatchSizer(maxBatchSize, batchWait),
+		window:      make(chan struct{}, maxInflight),
+		flushChan:   make(chan chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start starts the HTTPSender, it reports itself unhealthy when it's stuck waiting for the intake.
+func (s *HTTPSender) Start() {
+	s.health = health.Register("logs-sender")
+	go s.run()
+}
+
+// Stop stops the HTTPSender,
+// this call blocks until inputChan is flushed, the idle connections of the destinations are closed once done.
+func (s *HTTPSender) Stop() {
+	close(s.inputChan)
+	<-s.done
+}
+
+// Flush sends the messages queued in inputChan and the pending batch right away,
+// this call blocks until all the batches sent so far are committed or ctx is done.
+func (s *HTTPSender) Flush(ctx context.Context) error {
+	return flush(ctx, s.flushChan)
+}
+
+// run lets the sender batch and send messages.
+func (s *HTTPSender) run() {
+	s.pending = make(chan *inflightBatch, cap(s.window))
+	committed := make(chan struct{})
+	go s.commit(committed)
+	defer func() {
+		// wait for the inflight batches to be committed.
+		close(s.pending)
+		<-committed
+		s.main.Close()
+		for _, destination := range s.additionals {
+			destination.Close()
+		}
+		s.health.Deregister()
+		s.done <- struct{}{}
+	}()
+
+	batch := newBatch(s.sizer.size, maxContentSize)
+	wait := s.sizer.wait
+	flushTicker := time.NewTicker(wait)
+	defer func() {
+		flushTicker.Stop()
+	}()
+
+	for {
+		select {
+		case <-s.health.C:
+		case payload, isOpen := <-s.inputChan:
+			if !isOpen {
+				// inputChan has been closed, send the remaining messages.
+				s.send(batch)
+				return
+			}
+			s.add(batch, payload)
+		case <-flushTicker.C:
+			s.sizer.waited(len(batch.messages))
+			s.send(batch)
+		case flushed := <-s.flushChan:
+			for queued := len(s.inputChan); queued > 0; queued-- {
+				s.add(batch, <-s.inputChan)
+			}
+			s.send(batch)
+			s.pending <- &inflightBatch{flushed: flushed}
+		}
+
+		// apply the sizes adapted to the throughput to the next batches.
+		batch.maxBatchSize = s.sizer.size
+		if s.sizer.wait != wait {
+			wait = s.sizer.wait
+			flushTicker.Stop()
+			flushTicker = time.NewTicker(wait)
+		}
+	}
+}
+
+// add adds a message to the batch, the batch is sent when it's full.
+func (s *HTTPSender) add(batch *batch, payload *message.Message) {
+	s.limiter.Wait(len(payload.Content))
+	if !batch.isEmpty() && batch.apiKey != sourceAPIKey(payload) {
+		// the logs sent with another API key go in another batch
+		s.send(batch)
+	}
+	if !batch.add(payload) {
+		s.sizer.grow()
+		s.send(batch)
+		batch.add(payload)
+	}
+	if batch.isFull() {
+		s.sizer.grow()
+		s.send(batch)
+	}
+}
+
+// send sends the batch to the main destination in the background, it blocks while the window is full,
+// the batch is sent to the additional destinations only once after it's been acknowledged.
+func (s *HTTPSender) send(batch *batch) {
+	if batch.isEmpty() {
+		return
+	}
+	payload := batch.payload(s.main.PayloadFormat())
+	apiKey := batch.apiKey
+	inflight := &inflightBatch{
+		messages: batch.flush(),
+		sent:     make(chan struct{}),
+	}
+
+	metrics.BatchSize.Set(int64(len(inflight.messages)))
+	metrics.BatchWait.Set(int64(s.sizer.wait / time.Millisecond))
+
+	select {
+	case s.window <- struct{}{}:
+	default:
+		// the intake is slow to acknowledge the batches, send fewer and bigger ones.
+		s.sizer.grow()
+		s.window <- struct{}{}
+	}
+	s.pending <- inflight
+	go func() {
+		defer close(inflight.sent)
+		// this call is blocking until payload is acknowledged, rejected or the destinations context cancelled.
+		payloads, sent, err := s.sendToMain(inflight.messages, payload, apiKey)
+		if err != nil {
+			metrics.DestinationErrors.Add(1)
+			log.Warnf("Could not send %d logs of a batch of %d logs, dropping them: %v", len(inflight.messages)-sent, len(inflight.messages), err)
+		}
+		for _, payload := range payloads {
+			for _, destination := range s.additionals {
+				// send to a queue then send asynchronously for additional endpoints,
+				// it will drop payloads if the queue is full
+				destination.SendAsync(payload)
+			}
+		}
+		metrics.LogsSent.Add(int64(sent))
+	}()
+}
+
+// sendToMain sends the payload of messages to the main destination and returns the payloads accepted and
+// the number of messages they hold. A payload rejected as too large is split in halves which are sent in turn,
+// down to a single log which is dropped if it's still too large. err is the first error that dropped messages.
+func (s *HTTPSender) sendToMain(messages []*message.Message, payload []byte, apiKey string) (payloads [][]byte, sent int, err error) {
+	err = s.main.SendWithAPIKey(payload, apiKey)
+	if err == nil {
+		return [][]byte{payload}, len(messages), nil
+	}
+	if err != client.ErrPayloadTooLarge || len(messages) == 1 {
+		if client.IsPoisonPayload(err) {
+			s.quarantinePayload(payload, len(messages))
+		}
+		return nil, 0, err
+	}
+	err = nil
+	half := len(messages) / 2
+	for _, part := range [][]*message.Message{messages[:half], messages[half:]} {
+		partPayloads, partSent, partErr := s.sendToMain(part, encode(part, s.main.PayloadFormat()), apiKey)
+		payloads = append(payloads, partPayloads...)
+		sent += partSent
+		if err == nil {
+			err = partErr
+		}
+	}
+	return payloads, sent, err
+}
+
+// commit forwards the messages of the batches to the auditor in the order the batches were sent,
+// it frees a slot in the window for every committed batch.
+func (s *HTTPSender) commit(committed chan struct{}) {
+	defer close(committed)
+	for inflight := range s.pending {
+		if inflight.flushed != nil {
+			close(inflight.flushed)
+			continue
+		}
+		<-inflight.sent
+		for _, message := range inflight.messages {
+			s.outputChan <- message
+		}
+		<-s.window
+	}
+}
+
+// quarantinePayload writes the payload of a batch of size logs the intake would never accept to the quarantine
+// so that it can be inspected, the batch is dropped either way.
+func (s *HTTPSender) quarantinePayload(payload []byte, size int) {
+	metrics.BatchesPoisoned.Add(1)
+	if s.quarantine == nil {
+		return
+	}
+	path, err := s.quarantine.Add(payload, s.main.PayloadFormat())
+	if err != nil {
+		log.Warnf("Could not quarantine a batch of %d logs: %v", size, err)
+		return
+	}
+	metrics.BatchesQuarantined.Add(1)
+	log.Warnf("Quarantined a batch of %d logs the intake would not accept in %s", size, path)
+}
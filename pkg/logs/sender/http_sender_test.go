@@ -0,0 +1,380 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+func newHTTPDestination(server *httptest.Server, destinationsCtx *client.DestinationsContext) *client.HTTPDestination {
+	host, port := client.AddrToHostPort(server.Listener.Addr())
+	destination, _ := client.NewHTTPDestination(client.Endpoint{Host: host, Port: port}, destinationsCtx)
+	return destination
+}
+
+func TestHTTPSenderFlushesPeriodically(t *testing.T) {
+	payloads := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		payloads <- string(content)
+	}))
+	defer server.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 1)
+	output := make(chan *message.Message, 1)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	sender := NewHTTPSender(input, output, newHTTPDestination(server, destinationsCtx), nil, 10*time.Millisecond, 1, nil, nil)
+	sender.Start()
+
+	expectedMessage := newMessage([]byte(`{"message":"a"}`), source, "")
+	input <- expectedMessage
+
+	// the batch is not full, it should be sent by the flush ticker.
+	assert.Equal(t, `[{"message":"a"}]`, <-payloads)
+	assert.Equal(t, expectedMessage, <-output)
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+func TestHTTPSenderSendsFullBatches(t *testing.T) {
+	payloads := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		payloads <- string(content)
+	}))
+	defer server.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, maxBatchSize)
+	output := make(chan *message.Message, maxBatchSize)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	sender := NewHTTPSender(input, output, newHTTPDestination(server, destinationsCtx), nil, time.Hour, 1, nil, nil)
+	sender.Start()
+
+	for i := 0; i < maxBatchSize; i++ {
+		input <- newMessage([]byte("{}"), source, "")
+	}
+
+	payload := <-payloads
+	assert.Equal(t, maxBatchSize, strings.Count(payload, "{}"))
+	for i := 0; i < maxBatchSize; i++ {
+		<-output
+	}
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+func TestHTTPSenderFlushesOnStop(t *testing.T) {
+	payloads := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		payloads <- string(content)
+	}))
+	defer server.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 1)
+	output := make(chan *message.Message, 1)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	sender := NewHTTPSender(input, output, newHTTPDestination(server, destinationsCtx), nil, time.Hour, 1, nil, nil)
+	sender.Start()
+
+	input <- newMessage([]byte(`{"message":"a"}`), source, "")
+	sender.Stop()
+
+	assert.Equal(t, `[{"message":"a"}]`, <-payloads)
+	assert.Equal(t, `{"message":"a"}`, string((<-output).Content))
+
+	destinationsCtx.Stop()
+}
+
+func TestHTTPSenderCommitsInflightBatchesInOrder(t *testing.T) {
+	payloads := make(chan string, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		payloads <- string(content)
+		if strings.Contains(string(content), "a") {
+			// the first batch is acknowledged last.
+			<-release
+		}
+	}))
+	defer server.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 1)
+	output := make(chan *message.Message, 2)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	sender := NewHTTPSender(input, output, newHTTPDestination(server, destinationsCtx), nil, 10*time.Millisecond, 2, nil, nil)
+	sender.Start()
+
+	input <- newMessage([]byte(`{"message":"a"}`), source, "")
+	assert.Equal(t, `[{"message":"a"}]`, <-payloads)
+	input <- newMessage([]byte(`{"message":"b"}`), source, "")
+	assert.Equal(t, `[{"message":"b"}]`, <-payloads)
+
+	// the second batch is acknowledged but can not be committed before the first one.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, len(output))
+
+	close(release)
+	assert.Equal(t, `{"message":"a"}`, string((<-output).Content))
+	assert.Equal(t, `{"message":"b"}`, string((<-output).Content))
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+func TestHTTPSenderFlush(t *testing.T) {
+	payloads := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		payloads <- string(content)
+	}))
+	defer server.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 10)
+	output := make(chan *message.Message, 10)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	sender := NewHTTPSender(input, output, newHTTPDestination(server, destinationsCtx), nil, time.Hour, 1, nil, nil)
+	sender.Start()
+
+	input <- newMessage([]byte(`{"message":"a"}`), source, "")
+	input <- newMessage([]byte(`{"message":"b"}`), source, "")
+
+	// the batch is sent right away and committed once flushed.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, sender.Flush(ctx))
+	assert.Equal(t, `[{"message":"a"},{"message":"b"}]`, <-payloads)
+	assert.Equal(t, 2, len(output))
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+func TestHTTPSenderFlushReturnsWhenContextDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 10)
+	output := make(chan *message.Message, 10)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	sender := NewHTTPSender(input, output, newHTTPDestination(server, destinationsCtx), nil, time.Hour, 1, nil, nil)
+	sender.Start()
+
+	input <- newMessage([]byte(`{"message":"a"}`), source, "")
+
+	// the intake keeps failing, the batch can not be committed.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, sender.Flush(ctx))
+
+	destinationsCtx.Stop()
+	sender.Stop()
+}
+
+func TestHTTPSenderSendsAdaptedBatches(t *testing.T) {
+	payloads := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		payloads <- string(content)
+	}))
+	defer server.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, maxBatchSize)
+	output := make(chan *message.Message, maxBatchSize)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	sender := NewHTTPSender(input, output, newHTTPDestination(server, destinationsCtx), nil, time.Hour, 1, nil, nil)
+	// the batches shrank under low volume.
+	for i := 0; i < 10; i++ {
+		sender.sizer.shrink()
+	}
+	sender.Start()
+
+	for i := 0; i < minBatchSize; i++ {
+		input <- newMessage([]byte("{}"), source, "")
+	}
+
+	// the batch is sent as soon as it holds the adapted number of logs.
+	payload := <-payloads
+	assert.Equal(t, minBatchSize, strings.Count(payload, "{}"))
+	assert.Equal(t, int64(minBatchSize), metrics.BatchSize.Value())
+	for i := 0; i < minBatchSize; i++ {
+		<-output
+	}
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+func TestHTTPSenderSendsTheMessagesOfASourceWithItsAPIKey(t *testing.T) {
+	apiKeys := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeys <- r.Header.Get("DD-API-KEY")
+	}))
+	defer server.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+	keyedSource := config.NewLogSource("", &config.LogsConfig{APIKey: "bar"})
+
+	input := make(chan *message.Message, 3)
+	output := make(chan *message.Message, 3)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	host, port := client.AddrToHostPort(server.Listener.Addr())
+	destination, err := client.NewHTTPDestination(client.Endpoint{APIKey: "foo", Host: host, Port: port}, destinationsCtx)
+	assert.Nil(t, err)
+	sender := NewHTTPSender(input, output, destination, nil, 10*time.Millisecond, 1, nil, nil)
+	sender.Start()
+
+	input <- newMessage([]byte(`{"message":"a"}`), source, "")
+	input <- newMessage([]byte(`{"message":"b"}`), keyedSource, "")
+
+	// the messages with another API key are sent in their own batch.
+	assert.Equal(t, "foo", <-apiKeys)
+	assert.Equal(t, "bar", <-apiKeys)
+	<-output
+	<-output
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+func TestHTTPSenderSplitsThePayloadsTooLarge(t *testing.T) {
+	payloads := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		if strings.Count(string(content), "message") > 1 || strings.Contains(string(content), "toolarge") {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		payloads <- string(content)
+	}))
+	defer server.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 3)
+	output := make(chan *message.Message, 3)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	sender := NewHTTPSender(input, output, newHTTPDestination(server, destinationsCtx), nil, 10*time.Millisecond, 1, nil, nil)
+	sender.Start()
+
+	sent := metrics.LogsSent.Value()
+	input <- newMessage([]byte(`{"message":"a"}`), source, "")
+	input <- newMessage([]byte(`{"message":"toolarge"}`), source, "")
+	input <- newMessage([]byte(`{"message":"b"}`), source, "")
+	for i := 0; i < 3; i++ {
+		<-output
+	}
+
+	// the payload is split down to single logs, the log too large is dropped.
+	assert.Equal(t, `[{"message":"a"}]`, <-payloads)
+	assert.Equal(t, `[{"message":"b"}]`, <-payloads)
+	assert.Len(t, payloads, 0)
+	assert.Equal(t, sent+2, metrics.LogsSent.Value())
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+func TestHTTPSenderQuarantinesTheRejectedBatches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "quarantine")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	quarantine, err := NewQuarantine(dir, 1024)
+	assert.NoError(t, err)
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 1)
+	output := make(chan *message.Message, 1)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	sender := NewHTTPSender(input, output, newHTTPDestination(server, destinationsCtx), nil, 10*time.Millisecond, 1, nil, quarantine)
+	sender.Start()
+
+	poisoned, quarantined := metrics.BatchesPoisoned.Value(), metrics.BatchesQuarantined.Value()
+	input <- newMessage([]byte(`{"message":"a"}`), source, "")
+	<-output
+
+	assert.Equal(t, poisoned+1, metrics.BatchesPoisoned.Value())
+	assert.Equal(t, quarantined+1, metrics.BatchesQuarantined.Value())
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1) {
+		content, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+		assert.NoError(t, err)
+		assert.Equal(t, `[{"message":"a"}]`, string(content))
+	}
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+)
+
+// quarantineExtensions are the extensions of the files of the quarantined batches by payload format.
+var quarantineExtensions = map[string]string{
+	client.JSONPayloadFormat:     ".json",
+	client.ProtobufPayloadFormat: ".pb",
+}
+
+// errQuarantineFull is returned when a batch does not fit in the quarantine anymore.
+var errQuarantineFull = errors.New("quarantine is full")
+
+// Quarantine keeps on disk the payloads of the batches the intake would never accept, to be inspected,
+// each payload is written uncompressed to its own file named after the time it was quarantined.
+// A Quarantine is safe for concurrent use.
+type Quarantine struct {
+	mutex    sync.Mutex
+	path     string
+	maxSize  int64
+	size     int64
+	sequence int
+}
+
+// NewQuarantine returns a quarantine storing at most maxSize bytes in path,
+// the files left by a previous run count towards maxSize.
+func NewQuarantine(path string, maxSize int64) (*Quarantine, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	q := &Quarantine{
+		path:    path,
+		maxSize: maxSize,
+	}
+	for _, file := range files {
+		if !file.IsDir() && isQuarantineFile(file.Name()) {
+			q.size += file.Size()
+		}
+	}
+	return q, nil
+}
+
+// Add writes payload, in format, to a new file of the quarantine and returns its path.
+func (q *Quarantine) Add(payload []byte, format string) (string, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.size+int64(len(payload)) > q.maxSize {
+		return "", errQuarantineFull
+	}
+	extension, exists := quarantineExtensions[format]
+	if !exists {
+		extension = quarantineExtensions[client.JSONPayloadFormat]
+	}
+	q.sequence++
+	path := filepath.Join(q.path, fmt.Sprintf("%s-%d%s", time.Now().UTC().Format("20060102T150405.000000000Z"), q.sequence, extension))
+	if err := ioutil.WriteFile(path, payload, 0600); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	q.size += int64(len(payload))
+	return path, nil
+}
+
+// isQuarantineFile returns true if name is the name of a file of the quarantine.
+func isQuarantineFile(name string) bool {
+	for _, extension := range quarantineExtensions {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+)
+
+func TestQuarantineWritesOneFilePerPayload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	quarantine, err := NewQuarantine(dir, 1024)
+	assert.NoError(t, err)
+
+	path, err := quarantine.Add([]byte(`[{"message":"foo"}]`), client.JSONPayloadFormat)
+	assert.NoError(t, err)
+	assert.Equal(t, ".json", filepath.Ext(path))
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"message":"foo"}]`, string(content))
+
+	path, err = quarantine.Add([]byte("bar"), client.ProtobufPayloadFormat)
+	assert.NoError(t, err)
+	assert.Equal(t, ".pb", filepath.Ext(path))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestQuarantineIsBoundedAcrossRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	quarantine, err := NewQuarantine(dir, 10)
+	assert.NoError(t, err)
+
+	_, err = quarantine.Add([]byte("foobar"), client.JSONPayloadFormat)
+	assert.NoError(t, err)
+	_, err = quarantine.Add([]byte("foobar"), client.JSONPayloadFormat)
+	assert.Equal(t, errQuarantineFull, err)
+
+	// the files of the previous run count towards the maximum size.
+	quarantine, err = NewQuarantine(dir, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), quarantine.size)
+	_, err = quarantine.Add([]byte("foobar"), client.JSONPayloadFormat)
+	assert.Equal(t, errQuarantineFull, err)
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+)
+
+// RateLimiter caps the outbound traffic of the senders sharing it, a sender waits for the limiter
+// before sending a message so that the pipeline backs up instead of dropping logs when the limit is hit.
+// A nil RateLimiter does not limit anything. A RateLimiter is safe for concurrent use.
+type RateLimiter struct {
+	bytes               *rate.Limiter
+	events              *rate.Limiter
+	destinationsContext *client.DestinationsContext
+}
+
+// NewRateLimiter returns a limiter allowing bytesPerSecond bytes and eventsPerSecond messages per second,
+// a limit that is not positive is disabled, it returns nil if both are.
+func NewRateLimiter(bytesPerSecond, eventsPerSecond int, destinationsContext *client.DestinationsContext) *RateLimiter {
+	if bytesPerSecond <= 0 && eventsPerSecond <= 0 {
+		return nil
+	}
+	limiter := &RateLimiter{
+		destinationsContext: destinationsContext,
+	}
+	if bytesPerSecond > 0 {
+		limiter.bytes = rate.NewLimiter(rate.Limit(bytesPerSecond), bytesPerSecond)
+	}
+	if eventsPerSecond > 0 {
+		limiter.events = rate.NewLimiter(rate.Limit(eventsPerSecond), eventsPerSecond)
+	}
+	return limiter
+}
+
+// Wait blocks until a message of size bytes can be sent or the destinations context is cancelled.
+func (l *RateLimiter) Wait(size int) {
+	if l == nil {
+		return
+	}
+	ctx := l.destinationsContext.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if l.events != nil {
+		l.events.Wait(ctx)
+	}
+	if l.bytes != nil {
+		if size > l.bytes.Burst() {
+			// a message larger than the burst would never be allowed, it uses a full second of traffic instead.
+			size = l.bytes.Burst()
+		}
+		l.bytes.WaitN(ctx, size)
+	}
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+)
+
+func TestRateLimiterIsDisabledByDefault(t *testing.T) {
+	limiter := NewRateLimiter(0, 0, client.NewDestinationsContext())
+	assert.Nil(t, limiter)
+	// a nil limiter never blocks.
+	limiter.Wait(1000)
+}
+
+func TestRateLimiterLimitsEvents(t *testing.T) {
+	limiter := NewRateLimiter(0, 100, client.NewDestinationsContext())
+
+	start := time.Now()
+	// the first 100 events are allowed right away, the next 10 take 100ms.
+	for i := 0; i < 110; i++ {
+		limiter.Wait(10)
+	}
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+}
+
+func TestRateLimiterLimitsBytes(t *testing.T) {
+	limiter := NewRateLimiter(1000, 0, client.NewDestinationsContext())
+
+	start := time.Now()
+	// a message larger than the limit does not block forever.
+	limiter.Wait(5000)
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+	// the bucket is empty, 200 bytes take 200ms.
+	limiter.Wait(200)
+	assert.True(t, time.Since(start) >= 180*time.Millisecond)
+}
+
+func TestRateLimiterStopsWaitingWhenTheDestinationsContextIsCancelled(t *testing.T) {
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	limiter := NewRateLimiter(0, 1, destinationsCtx)
+	limiter.Wait(10)
+
+	destinationsCtx.Stop()
+	start := time.Now()
+	limiter.Wait(10)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
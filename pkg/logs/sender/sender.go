@@ -7,10 +7,13 @@ package sender
 
 import (
 	"context"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Sender is responsible for sending logs to different destinations.
@@ -18,74 +21,196 @@ type Sender struct {
 	inputChan    chan *message.Message
 	outputChan   chan *message.Message
 	destinations *client.Destinations
+	limiter      *RateLimiter
+	maxBatchSize int
+	batchLinger  time.Duration
+	flushChan    chan chan struct{}
+	health       *health.Handle
 	done         chan struct{}
 }
 
-// NewSender returns an new sender.
-func NewSender(inputChan, outputChan chan *message.Message, destinations *client.Destinations) *Sender {
+// NewSender returns an new sender, the messages are sent no faster than limiter allows.
+// Up to maxBatchSize messages are written at once to the main destination, the sender waits
+// up to batchLinger for more messages once it got one, 0 only batches the messages already queued.
+func NewSender(inputChan, outputChan chan *message.Message, destinations *client.Destinations, limiter *RateLimiter, maxBatchSize int, batchLinger time.Duration) *Sender {
+	if maxBatchSize < 1 {
+		maxBatchSize = 1
+	}
 	return &Sender{
 		inputChan:    inputChan,
 		outputChan:   outputChan,
 		destinations: destinations,
+		limiter:      limiter,
+		maxBatchSize: maxBatchSize,
+		batchLinger:  batchLinger,
+		flushChan:    make(chan chan struct{}),
 		done:         make(chan struct{}),
 	}
 }
 
-// Start starts the Sender
+// Start starts the Sender, it reports itself unhealthy when it's stuck sending a message.
 func (s *Sender) Start() {
+	s.health = health.Register("logs-sender")
 	go s.run()
 }
 
 // Stop stops the Sender,
-// this call blocks until inputChan is flushed
+// this call blocks until inputChan is flushed, the connections of the main destination are closed once done.
 func (s *Sender) Stop() {
 	close(s.inputChan)
 	<-s.done
 }
 
+// Flush sends the messages queued in inputChan,
+// this call blocks until they are sent or ctx is done.
+func (s *Sender) Flush(ctx context.Context) error {
+	return flush(ctx, s.flushChan)
+}
+
+// flush asks the run loop listening on flushChan to flush and waits for it to be done or ctx to be done.
+func flush(ctx context.Context, flushChan chan chan struct{}) error {
+	flushed := make(chan struct{})
+	select {
+	case flushChan <- flushed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // run lets the sender send messages.
 func (s *Sender) run() {
 	defer func() {
+		s.destinations.Main.Close()
+		s.health.Deregister()
 		s.done <- struct{}{}
 	}()
-	for payload := range s.inputChan {
-		s.send(payload)
+	for {
+		select {
+		case <-s.health.C:
+		case payload, isOpen := <-s.inputChan:
+			if !isOpen {
+				return
+			}
+			s.send(s.batch(payload, s.batchLinger))
+		case flushed := <-s.flushChan:
+			for queued := len(s.inputChan); queued > 0; {
+				payloads := s.batch(<-s.inputChan, 0)
+				queued -= len(payloads)
+				s.send(payloads)
+			}
+			close(flushed)
+		}
 	}
 }
 
-// send keeps trying to send the message to the main destination until it succeeds
-// and try to send the message to the additional destinations only once.
-func (s *Sender) send(payload *message.Message) {
-	for {
-		// this call is blocking until payload is sent (or the connection destination context cancelled)
-		err := s.destinations.Main.Send(payload.Content)
+// batch returns payload followed by the messages received within linger, up to maxBatchSize messages,
+// the messages already queued are batched whatever linger.
+func (s *Sender) batch(payload *message.Message, linger time.Duration) []*message.Message {
+	payloads := []*message.Message{payload}
+	var timeout <-chan time.Time
+	if linger > 0 && s.maxBatchSize > 1 {
+		timer := time.NewTimer(linger)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for len(payloads) < s.maxBatchSize {
+		select {
+		case payload, isOpen := <-s.inputChan:
+			if !isOpen {
+				// the messages received so far are sent before the sender stops.
+				return payloads
+			}
+			payloads = append(payloads, payload)
+			continue
+		default:
+		}
+		if timeout == nil {
+			return payloads
+		}
+		select {
+		case payload, isOpen := <-s.inputChan:
+			if !isOpen {
+				return payloads
+			}
+			payloads = append(payloads, payload)
+		case <-timeout:
+			return payloads
+		}
+	}
+	return payloads
+}
+
+// send keeps trying to send the messages to the main destination until it succeeds or gives up on connecting to it,
+// and try to send the messages to the additional destinations only once.
+func (s *Sender) send(payloads []*message.Message) {
+	frames := make([][]byte, 0, len(payloads))
+	framed := make([]*message.Message, 0, len(payloads))
+	for _, payload := range payloads {
+		// blocking here makes the pipeline back up when the rate limit is hit.
+		s.limiter.Wait(len(payload.Content))
+		frame, err := s.destinations.Main.Frame(payload.Content, sourceAPIKey(payload))
+		if err != nil {
+			metrics.DestinationErrors.Add(1)
+			// the message can not be framed properly,
+			// drop the message
+			continue
+		}
+		frames = append(frames, frame)
+		framed = append(framed, payload)
+	}
+	for len(frames) > 0 {
+		// this call is blocking until the frames are sent (or the connection destination context cancelled)
+		err := s.destinations.Main.SendFrames(frames)
 		if err != nil {
 			if err == context.Canceled {
 				metrics.DestinationErrors.Add(1)
 				// the context was cancelled, agent is stopping non-gracefully.
-				// drop the message
+				// drop the messages
 				break
 			}
-			switch err.(type) {
-			case *client.FramingError:
+			if err == client.ErrConnectionRetriesExhausted {
 				metrics.DestinationErrors.Add(1)
-				// the message can not be framed properly,
-				// drop the message
+				// the connection attempts of the backoff policy all failed,
+				// drop the messages
+				metrics.LogsGivenUp.Add(int64(len(framed)))
 				break
-			default:
-				metrics.DestinationErrors.Add(1)
-				// retry as the error can be related to network issues
-				continue
 			}
+			metrics.DestinationErrors.Add(1)
+			metrics.DestinationRetries.Add(1)
+			if _, isServerError := err.(*client.ServerError); isServerError {
+				// the intake closed the connection, the messages are sent again on a new one
+				log.Warnf("Reconnecting to the intake: %v", err)
+			}
+			// retry as the error can be related to network issues
+			continue
 		}
-		for _, destination := range s.destinations.Additionals {
-			// send to a queue then send asynchronously for additional endpoints,
-			// it will drop messages if the queue is full
-			destination.SendAsync(payload.Content)
+		for _, payload := range framed {
+			for _, destination := range s.destinations.Additionals {
+				// send to a queue then send asynchronously for additional endpoints,
+				// it will drop messages if the queue is full
+				destination.SendAsync(payload.Content)
+			}
 		}
 
-		metrics.LogsSent.Add(1)
+		metrics.LogsSent.Add(int64(len(framed)))
 		break
 	}
-	s.outputChan <- payload
+	for _, payload := range payloads {
+		s.outputChan <- payload
+	}
+}
+
+// sourceAPIKey returns the API key of the source of the message,
+// or an empty string when the message is sent with the API key of the endpoint.
+func sourceAPIKey(msg *message.Message) string {
+	if msg.Origin == nil || msg.Origin.LogSource == nil || msg.Origin.LogSource.Config == nil {
+		return ""
+	}
+	return msg.Origin.LogSource.Config.APIKey
 }
@@ -5,7 +5,12 @@
 package sender
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 
@@ -14,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client/mock"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 )
 
 func newMessage(content []byte, source *config.LogSource, status string) *message.Message {
@@ -37,7 +43,7 @@ func TestSender(t *testing.T) {
 	destination := client.AddrToDestination(l.Addr(), destinationsCtx)
 	destinations := client.NewDestinations(destination, nil)
 
-	sender := NewSender(input, output, destinations)
+	sender := NewSender(input, output, destinations, nil, 1, 0)
 	sender.Start()
 
 	expectedMessage := newMessage([]byte("fake line"), source, "")
@@ -70,7 +76,7 @@ func TestSenderNotBlockedByAdditional(t *testing.T) {
 	additionalDestination := client.NewDestination(client.Endpoint{Host: "dont.exist.local", Port: 0}, destinationsCtx)
 	destinations := client.NewDestinations(mainDestination, []*client.Destination{additionalDestination})
 
-	sender := NewSender(input, output, destinations)
+	sender := NewSender(input, output, destinations, nil, 1, 0)
 	sender.Start()
 
 	expectedMessage1 := newMessage([]byte("fake line"), source, "")
@@ -88,3 +94,190 @@ func TestSenderNotBlockedByAdditional(t *testing.T) {
 	sender.Stop()
 	destinationsCtx.Stop()
 }
+
+func TestSenderFlush(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 10)
+	output := make(chan *message.Message, 10)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	destination := client.AddrToDestination(l.Addr(), destinationsCtx)
+	sender := NewSender(input, output, client.NewDestinations(destination, nil), nil, 1, 0)
+	sender.Start()
+
+	for i := 0; i < 3; i++ {
+		input <- newMessage([]byte("fake line"), source, "")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, sender.Flush(ctx))
+	assert.Equal(t, 3, len(output))
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+func TestSenderAppliesBackpressureWhenRateLimited(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 10)
+	output := make(chan *message.Message, 10)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	destination := client.AddrToDestination(l.Addr(), destinationsCtx)
+	sender := NewSender(input, output, client.NewDestinations(destination, nil), NewRateLimiter(0, 10, destinationsCtx), 1, 0)
+	sender.Start()
+
+	start := time.Now()
+	for i := 0; i < 12; i++ {
+		input <- newMessage([]byte("fake line"), source, "")
+	}
+	// no message is dropped, the last ones are sent once the limiter allows them.
+	for i := 0; i < 12; i++ {
+		<-output
+	}
+	assert.True(t, time.Since(start) >= 150*time.Millisecond)
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+// newLineIntake returns a listener that sends every line it receives on lines.
+func newLineIntake(t *testing.T, lines chan<- string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func TestSenderBatchesMessages(t *testing.T) {
+	lines := make(chan string, 10)
+	l := newLineIntake(t, lines)
+	defer l.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 10)
+	output := make(chan *message.Message, 10)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	destination := client.AddrToDestination(l.Addr(), destinationsCtx)
+	sender := NewSender(input, output, client.NewDestinations(destination, nil), nil, 3, 100*time.Millisecond)
+
+	// the messages are written in batches of at most 3 messages, in order.
+	for i := 0; i < 5; i++ {
+		input <- newMessage([]byte(fmt.Sprintf("line %d", i)), source, "")
+	}
+	sender.Start()
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, fmt.Sprintf("line %d", i), string((<-output).Content))
+	}
+
+	// the sender waits up to the linger time for more messages.
+	input <- newMessage([]byte("line 5"), source, "")
+	time.Sleep(10 * time.Millisecond)
+	input <- newMessage([]byte("line 6"), source, "")
+	assert.Equal(t, "line 5", string((<-output).Content))
+	assert.Equal(t, "line 6", string((<-output).Content))
+
+	for i := 0; i < 7; i++ {
+		select {
+		case line := <-lines:
+			// the destination has no API key to prefix the logs with.
+			assert.Equal(t, fmt.Sprintf(" line %d", i), line)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "the intake did not receive the log")
+		}
+	}
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
+
+func TestSenderSendsTheBatchedMessagesWhenStopped(t *testing.T) {
+	l := mock.NewMockLogsIntake(t)
+	defer l.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 10)
+	output := make(chan *message.Message, 10)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	destination := client.AddrToDestination(l.Addr(), destinationsCtx)
+	sender := NewSender(input, output, client.NewDestinations(destination, nil), nil, 10, time.Hour)
+	sender.Start()
+
+	input <- newMessage([]byte("fake line"), source, "")
+	input <- newMessage([]byte("fake line"), source, "")
+	sender.Stop()
+	assert.Equal(t, 2, len(output))
+
+	destinationsCtx.Stop()
+}
+
+func TestSenderGivesUpOnTheLogsOnceTheConnectionRetriesAreExhausted(t *testing.T) {
+	// the intake is not reachable anymore.
+	l := mock.NewMockLogsIntake(t)
+	addr := l.Addr().(*net.TCPAddr)
+	l.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 10)
+	output := make(chan *message.Message, 10)
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+
+	endpoint := client.Endpoint{Host: addr.IP.String(), Port: addr.Port, Backoff: client.BackoffPolicy{Base: time.Millisecond, Max: time.Millisecond, MaxRetries: 2}}
+	destination := client.NewDestination(endpoint, destinationsCtx)
+	sender := NewSender(input, output, client.NewDestinations(destination, nil), nil, 1, 0)
+	sender.Start()
+
+	givenUp := metrics.LogsGivenUp.Value()
+	input <- newMessage([]byte("fake line"), source, "")
+	input <- newMessage([]byte("fake line"), source, "")
+	// the pipeline is not blocked, the logs are dropped and counted.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-output:
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "the sender did not give up on the log")
+		}
+	}
+	assert.Equal(t, givenUp+2, metrics.LogsGivenUp.Value())
+
+	sender.Stop()
+	destinationsCtx.Stop()
+}
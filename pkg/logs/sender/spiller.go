@@ -0,0 +1,168 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"encoding/json"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Spiller sits in front of a sender and spills the messages to a disk buffer
+// when the sender can not keep up, e.g. when the intake is unreachable.
+// The spilled messages are handed to the auditor right away as they are safely persisted,
+// and they are replayed to the sender, oldest first, as soon as it accepts messages again.
+// Each pipeline has its own spiller and disk buffer so that the messages are replayed in the order of their pipeline.
+type Spiller struct {
+	inputChan  chan *message.Message
+	outputChan chan *message.Message
+	auditChan  chan *message.Message
+	buffer     *DiskBuffer
+	done       chan struct{}
+}
+
+// NewSpiller returns a new spiller forwarding the messages from inputChan to outputChan.
+func NewSpiller(inputChan, outputChan, auditChan chan *message.Message, buffer *DiskBuffer) *Spiller {
+	return &Spiller{
+		inputChan:  inputChan,
+		outputChan: outputChan,
+		auditChan:  auditChan,
+		buffer:     buffer,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts the Spiller
+func (s *Spiller) Start() {
+	go s.run()
+}
+
+// Stop stops the Spiller,
+// this call blocks until inputChan is flushed, the messages left on disk are replayed on the next start.
+func (s *Spiller) Stop() {
+	close(s.inputChan)
+	<-s.done
+}
+
+// run forwards the messages to the sender, once a message has been spilled
+// the following ones are spilled too to preserve the order.
+func (s *Spiller) run() {
+	defer func() {
+		s.done <- struct{}{}
+	}()
+
+	var next *message.Message
+	for {
+		if next == nil {
+			next = s.pop()
+		}
+		if next == nil {
+			msg, isOpen := <-s.inputChan
+			if !isOpen {
+				return
+			}
+			select {
+			case s.outputChan <- msg:
+			default:
+				s.spill(msg)
+			}
+			continue
+		}
+
+		select {
+		case msg, isOpen := <-s.inputChan:
+			if !isOpen {
+				return
+			}
+			s.spill(msg)
+		case s.outputChan <- next:
+			next = nil
+		}
+	}
+}
+
+// spill persists the message on disk, the message is forwarded to the sender,
+// blocking, if it can not be persisted or if its source has an API key, which would be lost on disk.
+func (s *Spiller) spill(msg *message.Message) {
+	if sourceAPIKey(msg) != "" {
+		s.outputChan <- msg
+		return
+	}
+	record, err := json.Marshal(newSpilledRecord(msg))
+	if err == nil {
+		err = s.buffer.Push(record)
+	}
+	if err != nil {
+		if err != errDiskBufferFull {
+			log.Warnf("Could not buffer log on disk: %v", err)
+		}
+		s.outputChan <- msg
+		return
+	}
+	metrics.LogsBuffered.Add(1)
+	s.auditChan <- msg
+}
+
+// pop returns the oldest message of the disk buffer, or nil if it's empty.
+func (s *Spiller) pop() *message.Message {
+	for {
+		payload, err := s.buffer.Pop()
+		if err != nil {
+			log.Warnf("Could not read log from disk: %v", err)
+			continue
+		}
+		if payload == nil {
+			return nil
+		}
+		var record spilledRecord
+		if err := json.Unmarshal(payload, &record); err != nil {
+			log.Warnf("Could not decode log read from disk, dropping it: %v", err)
+			continue
+		}
+		return record.message()
+	}
+}
+
+// spilledRecord is a message persisted in the disk buffer along with its origin.
+type spilledRecord struct {
+	Content    []byte   `json:"content"`
+	Identifier string   `json:"identifier,omitempty"`
+	Offset     string   `json:"offset,omitempty"`
+	SourceName string   `json:"source_name,omitempty"`
+	Source     string   `json:"source,omitempty"`
+	Service    string   `json:"service,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// newSpilledRecord returns the record of a message to spill.
+func newSpilledRecord(msg *message.Message) *spilledRecord {
+	record := &spilledRecord{Content: msg.Content}
+	if msg.Origin != nil && msg.Origin.LogSource != nil && msg.Origin.LogSource.Config != nil {
+		record.Identifier = msg.Origin.Identifier
+		record.Offset = msg.Origin.Offset
+		record.SourceName = msg.Origin.LogSource.Name
+		record.Source = msg.Origin.Source()
+		record.Service = msg.Origin.Service()
+		record.Tags = msg.Origin.Tags()
+	}
+	return record
+}
+
+// message returns the replayed message of the record, the source of its origin only holds what was persisted
+// since the source of the message may not exist anymore, e.g. after a restart.
+func (r *spilledRecord) message() *message.Message {
+	source := config.NewLogSource(r.SourceName, &config.LogsConfig{Source: r.Source, Service: r.Service})
+	origin := message.NewOrigin(source)
+	origin.Identifier = r.Identifier
+	origin.Offset = r.Offset
+	origin.SetTags(r.Tags)
+	// the offsets of the replayed messages have already been committed when they were spilled.
+	origin.Replayed = true
+	return message.NewMessage(r.Content, origin, "")
+}
@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sender
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newSpilledMessage(content string) *message.Message {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	return message.NewMessage([]byte(content), message.NewOrigin(source), "")
+}
+
+func TestSpillerForwardsMessages(t *testing.T) {
+	buffer, dir := newTestDiskBuffer(t, 1024)
+	defer os.RemoveAll(dir)
+
+	input := make(chan *message.Message, 1)
+	output := make(chan *message.Message, 1)
+	audit := make(chan *message.Message, 1)
+	spiller := NewSpiller(input, output, audit, buffer)
+	spiller.Start()
+
+	input <- newSpilledMessage("foo")
+	msg := <-output
+	assert.Equal(t, "foo", string(msg.Content))
+	assert.NotNil(t, msg.Origin)
+	spiller.Stop()
+	assert.Equal(t, 0, len(audit))
+}
+
+func TestSpillerSpillsAndReplaysInOrder(t *testing.T) {
+	buffer, dir := newTestDiskBuffer(t, 1024)
+	defer os.RemoveAll(dir)
+
+	input := make(chan *message.Message)
+	// the sender is stuck, only one message can be queued.
+	output := make(chan *message.Message, 1)
+	audit := make(chan *message.Message, 10)
+	spiller := NewSpiller(input, output, audit, buffer)
+	spiller.Start()
+
+	for _, content := range []string{"foo", "bar", "baz"} {
+		input <- newSpilledMessage(content)
+	}
+	// the spilled messages are committed right away.
+	assert.Equal(t, "bar", string((<-audit).Content))
+
+	// the sender is back, the messages are received in order.
+	for _, expected := range []string{"foo", "bar", "baz"} {
+		msg := <-output
+		assert.Equal(t, expected, string(msg.Content))
+	}
+	assert.Equal(t, "baz", string((<-audit).Content))
+	spiller.Stop()
+}
+
+func TestSpillerBlocksWhenTheBufferIsFull(t *testing.T) {
+	buffer, dir := newTestDiskBuffer(t, 1)
+	defer os.RemoveAll(dir)
+
+	input := make(chan *message.Message)
+	output := make(chan *message.Message)
+	audit := make(chan *message.Message, 10)
+	spiller := NewSpiller(input, output, audit, buffer)
+	spiller.Start()
+
+	go func() {
+		input <- newSpilledMessage("foo")
+	}()
+	msg := <-output
+	assert.Equal(t, "foo", string(msg.Content))
+	assert.NotNil(t, msg.Origin)
+	spiller.Stop()
+	assert.Equal(t, 0, len(audit))
+}
+
+func TestSpillerDoesNotSpillTheMessagesOfSourcesWithAnAPIKey(t *testing.T) {
+	buffer, dir := newTestDiskBuffer(t, 1024)
+	defer os.RemoveAll(dir)
+
+	input := make(chan *message.Message)
+	// the sender is stuck, only one message can be queued.
+	output := make(chan *message.Message, 1)
+	audit := make(chan *message.Message, 10)
+	spiller := NewSpiller(input, output, audit, buffer)
+	spiller.Start()
+
+	source := config.NewLogSource("", &config.LogsConfig{APIKey: "bar"})
+	input <- message.NewMessage([]byte("foo"), message.NewOrigin(source), "")
+	received := make(chan struct{})
+	go func() {
+		input <- message.NewMessage([]byte("baz"), message.NewOrigin(source), "")
+		close(received)
+	}()
+
+	// the messages keep their origin, they are not committed before being sent.
+	for _, expected := range []string{"foo", "baz"} {
+		msg := <-output
+		assert.Equal(t, expected, string(msg.Content))
+		assert.Equal(t, source, msg.Origin.LogSource)
+	}
+	<-received
+	spiller.Stop()
+	assert.Equal(t, 0, len(audit))
+}
+
+func TestSpillerKeepsTheOriginOfTheReplayedMessages(t *testing.T) {
+	buffer, dir := newTestDiskBuffer(t, 1024)
+	defer os.RemoveAll(dir)
+
+	input := make(chan *message.Message)
+	output := make(chan *message.Message, 1)
+	audit := make(chan *message.Message, 10)
+	spiller := NewSpiller(input, output, audit, buffer)
+	spiller.Start()
+
+	source := config.NewLogSource("nginx", &config.LogsConfig{Source: "nginx", Tags: []string{"env:prod"}})
+	origin := message.NewOrigin(source)
+	origin.Identifier = "file:/var/log/nginx/access.log"
+	origin.Offset = "42"
+	origin.SetService("web")
+	origin.SetTags([]string{"team:edge"})
+	input <- newSpilledMessage("foo")
+	// the sender is stuck, the message is spilled.
+	input <- message.NewMessage([]byte("bar"), origin, "")
+	assert.Equal(t, "bar", string((<-audit).Content))
+
+	assert.Equal(t, "foo", string((<-output).Content))
+	msg := <-output
+	assert.Equal(t, "bar", string(msg.Content))
+	assert.True(t, msg.Origin.Replayed)
+	assert.Equal(t, "file:/var/log/nginx/access.log", msg.Origin.Identifier)
+	assert.Equal(t, "42", msg.Origin.Offset)
+	assert.Equal(t, "nginx", msg.Origin.LogSource.Name)
+	assert.Equal(t, "nginx", msg.Origin.Source())
+	assert.Equal(t, "web", msg.Origin.Service())
+	assert.Equal(t, []string{"team:edge", "env:prod"}, msg.Origin.Tags())
+	spiller.Stop()
+}
@@ -10,6 +10,7 @@ import (
 	"sync/atomic"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 )
 
 // Builder is used to build the status.
@@ -37,6 +38,7 @@ func (b *Builder) BuildStatus() Status {
 		Integrations: b.getIntegrations(),
 		Warnings:     b.getWarnings(),
 		Errors:       b.getErrors(),
+		Metrics:      b.getMetrics(),
 	}
 }
 
@@ -59,6 +61,28 @@ func (b *Builder) getErrors() []string {
 	return b.errors.GetMessages()
 }
 
+// getMetrics returns the counters of the pipelines and the destinations
+// that help troubleshooting the delivery of the logs.
+func (b *Builder) getMetrics() map[string]int64 {
+	return map[string]int64{
+		"LogsProcessed":      metrics.LogsProcessed.Value(),
+		"LogsFiltered":       metrics.LogsFiltered.Value(),
+		"LogsDropped":        metrics.LogsDropped.Value(),
+		"LogsTruncated":      metrics.LogsTruncated.Value(),
+		"LogsSent":           metrics.LogsSent.Value(),
+		"LogsBuffered":       metrics.LogsBuffered.Value(),
+		"BytesSent":          metrics.BytesSent.Value(),
+		"BatchesSent":        metrics.BatchesSent.Value(),
+		"BatchSize":          metrics.BatchSize.Value(),
+		"ConnectionAttempts": metrics.ConnectionAttempts.Value(),
+		"ConnectionFailures": metrics.ConnectionFailures.Value(),
+		"DestinationErrors":  metrics.DestinationErrors.Value(),
+		"DestinationRetries": metrics.DestinationRetries.Value(),
+		"OpenFiles":          metrics.OpenFiles.Value(),
+		"OpenFilesLimit":     metrics.OpenFilesLimit.Value(),
+	}
+}
+
 // getIntegrations returns all the information about the logs integrations.
 func (b *Builder) getIntegrations() []Integration {
 	var integrations []Integration
@@ -69,8 +93,11 @@ func (b *Builder) getIntegrations() []Integration {
 				Type:          source.Config.Type,
 				Configuration: b.toDictionary(source.Config),
 				Status:        b.toString(source.Status),
+				LastError:     source.Status.GetLastError(),
 				Inputs:        source.GetInputs(),
 				Messages:      source.Messages.GetMessages(),
+				BytesRead:     source.GetBytesRead(),
+				LinesRead:     source.GetLinesRead(),
 			})
 		}
 		integrations = append(integrations, Integration{
@@ -24,8 +24,11 @@ type Source struct {
 	Type          string                 `json:"type"`
 	Configuration map[string]interface{} `json:"configuration"`
 	Status        string                 `json:"status"`
+	LastError     string                 `json:"last_error"`
 	Inputs        []string               `json:"inputs"`
 	Messages      []string               `json:"messages"`
+	BytesRead     int64                  `json:"bytes_read"`
+	LinesRead     int64                  `json:"lines_read"`
 }
 
 // Integration provides some information about a logs integration.
@@ -36,10 +39,11 @@ type Integration struct {
 
 // Status provides some information about logs-agent.
 type Status struct {
-	IsRunning    bool          `json:"is_running"`
-	Integrations []Integration `json:"integrations"`
-	Errors       []string      `json:"errors"`
-	Warnings     []string      `json:"warnings"`
+	IsRunning    bool             `json:"is_running"`
+	Integrations []Integration    `json:"integrations"`
+	Errors       []string         `json:"errors"`
+	Warnings     []string         `json:"warnings"`
+	Metrics      map[string]int64 `json:"metrics"`
 }
 
 // Init instantiates the builder that builds the status on the fly.
@@ -43,6 +43,36 @@ func TestSourceAreGroupedByIntegrations(t *testing.T) {
 	}
 }
 
+func TestStatusSourceState(t *testing.T) {
+	defer Clear()
+	source := config.NewLogSource("foo", &config.LogsConfig{Type: "foo"})
+	CreateSources([]*config.LogSource{source})
+
+	source.Status.Error(fmt.Errorf("no such file"))
+	source.Status.Success()
+	source.RecordRead(42)
+
+	status := Get()
+	assert.Equal(t, 1, len(status.Integrations))
+	assert.Equal(t, 1, len(status.Integrations[0].Sources))
+	s := status.Integrations[0].Sources[0]
+	assert.Equal(t, "OK", s.Status)
+	assert.Equal(t, "Error: no such file", s.LastError)
+	assert.Equal(t, int64(42), s.BytesRead)
+	assert.Equal(t, int64(1), s.LinesRead)
+}
+
+func TestStatusMetrics(t *testing.T) {
+	defer Clear()
+	defer metrics.BytesSent.Set(0)
+	createSources()
+
+	metrics.BytesSent.Set(42)
+	status := Get()
+	assert.Equal(t, int64(42), status.Metrics["BytesSent"])
+	assert.Equal(t, int64(0), status.Metrics["ConnectionFailures"])
+}
+
 func TestStatusDeduplicateWarnings(t *testing.T) {
 	defer Clear()
 	createSources()
@@ -91,12 +121,12 @@ func TestStatusDeduplicateErrorsAndWarnings(t *testing.T) {
 func TestMetrics(t *testing.T) {
 	defer Clear()
 	Clear()
-	var expected = `{"DestinationErrors": 0, "DestinationLogsDropped": {}, "Errors": "", "IsRunning": false, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "Warnings": ""}`
+	var expected = `{"BatchSize": 0, "BatchWait": 0, "BatchesPoisoned": 0, "BatchesQuarantined": 0, "BatchesSent": 0, "BytesSent": 0, "CheckLogsDropped": 0, "ConnectionAttempts": 0, "ConnectionFailures": 0, "DestinationBackoff": {}, "DestinationConnectLatency": {}, "DestinationErrors": 0, "DestinationLatency": {}, "DestinationLogsDropped": {}, "DestinationRetries": 0, "Errors": "", "FilesEvicted": 0, "HookLogsDropped": {}, "IsRunning": false, "LogsBuffered": 0, "LogsDecoded": 0, "LogsDropped": 0, "LogsFiltered": 0, "LogsGivenUp": 0, "LogsProcessed": 0, "LogsSent": 0, "LogsShed": 0, "LogsTruncated": 0, "MemoryShedding": 0, "OpenFiles": 0, "OpenFilesLimit": 0, "SDSMatches": {}, "ScriptTimeouts": 0, "SourceCanariesDelivered": {}, "SourceCanariesInjected": {}, "SourceCanariesLost": {}, "SourceLogsDropped": {}, "SourceLogsOverQuota": {}, "SourceLogsShed": {}, "Tailers": {}, "Warnings": ""}`
 	assert.Equal(t, expected, metrics.LogsExpvars.String())
 
 	createSources()
 	AddGlobalWarning("bar", "Unique Warning")
 	AddGlobalError("bar", "I am an error")
-	expected = `{"DestinationErrors": 0, "DestinationLogsDropped": {}, "Errors": "I am an error", "IsRunning": true, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "Warnings": "Unique Warning"}`
+	expected = `{"BatchSize": 0, "BatchWait": 0, "BatchesPoisoned": 0, "BatchesQuarantined": 0, "BatchesSent": 0, "BytesSent": 0, "CheckLogsDropped": 0, "ConnectionAttempts": 0, "ConnectionFailures": 0, "DestinationBackoff": {}, "DestinationConnectLatency": {}, "DestinationErrors": 0, "DestinationLatency": {}, "DestinationLogsDropped": {}, "DestinationRetries": 0, "Errors": "I am an error", "FilesEvicted": 0, "HookLogsDropped": {}, "IsRunning": true, "LogsBuffered": 0, "LogsDecoded": 0, "LogsDropped": 0, "LogsFiltered": 0, "LogsGivenUp": 0, "LogsProcessed": 0, "LogsSent": 0, "LogsShed": 0, "LogsTruncated": 0, "MemoryShedding": 0, "OpenFiles": 0, "OpenFilesLimit": 0, "SDSMatches": {}, "ScriptTimeouts": 0, "SourceCanariesDelivered": {}, "SourceCanariesInjected": {}, "SourceCanariesLost": {}, "SourceLogsDropped": {}, "SourceLogsOverQuota": {}, "SourceLogsShed": {}, "Tailers": {}, "Warnings": "Unique Warning"}`
 	assert.Equal(t, expected, metrics.LogsExpvars.String())
 }
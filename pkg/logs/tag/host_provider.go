@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package tag
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/metadata/host"
+)
+
+// hostRefreshPeriod is the period at which the host tags are fetched again,
+// they rarely change but can be updated, e.g. when the EC2 tags of the instance are edited.
+const hostRefreshPeriod = 5 * time.Minute
+
+// hostProvider caches the tags of the host polling them periodically.
+type hostProvider struct {
+	fetch         func() []string
+	refreshPeriod time.Duration
+	tags          []string
+	done          chan struct{}
+	mu            sync.Mutex
+}
+
+// NewHostProvider returns a new Provider of the tags of the host.
+func NewHostProvider() Provider {
+	return newHostProvider(host.GetHostTags, hostRefreshPeriod)
+}
+
+// newHostProvider returns a new Provider of the tags returned by fetch.
+func newHostProvider(fetch func() []string, refreshPeriod time.Duration) *hostProvider {
+	return &hostProvider{
+		fetch:         fetch,
+		refreshPeriod: refreshPeriod,
+		tags:          []string{},
+		done:          make(chan struct{}),
+	}
+}
+
+// GetTags returns the list of up-to-date tags.
+func (p *hostProvider) GetTags() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tags
+}
+
+// Start fetches the tags of the host and polls them periodically on another go routine,
+// as fetching them can involve querying a cloud provider the first fetch does not block either.
+func (p *hostProvider) Start() {
+	go func() {
+		p.updateTags()
+		ticker := time.NewTicker(p.refreshPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.updateTags()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the polling of new tags.
+func (p *hostProvider) Stop() {
+	p.done <- struct{}{}
+}
+
+// updateTags updates the list of tags, the slice is replaced rather than modified
+// as it's shared with the messages it's attached to.
+func (p *hostProvider) updateTags() {
+	tags := p.fetch()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !reflect.DeepEqual(tags, p.tags) {
+		p.tags = tags
+	}
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package tag
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostProviderRefreshesTags(t *testing.T) {
+	var fetches int32
+	p := newHostProvider(func() []string {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			return []string{"env:staging"}
+		}
+		return []string{"env:prod"}
+	}, 10*time.Millisecond)
+	assert.Equal(t, 0, len(p.GetTags()))
+
+	p.Start()
+	defer p.Stop()
+
+	for _, expected := range []string{"env:staging", "env:prod"} {
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if tags := p.GetTags(); len(tags) == 1 && tags[0] == expected {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		assert.Equal(t, []string{expected}, p.GetTags())
+	}
+}
@@ -42,6 +42,12 @@ func appendAndSplitTags(target []string, tags []string, splits map[string]string
 	return target
 }
 
+// GetHostTags returns the tags of the host, including the Google Cloud Platform ones.
+func GetHostTags() []string {
+	hostTags := getHostTags()
+	return append(hostTags.System, hostTags.GoogleCloudPlatform...)
+}
+
 func getHostTags() *tags {
 	splits := config.Datadog.GetStringMapString("tag_value_split_separator")
 	appendToHostTags := func(old, new []string) []string {
@@ -21,6 +21,11 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 	return data, nil
 }
 
+// Refresh placeholder when compiled without the 'secrets' build tag
+func Refresh(value string, origin string) (string, error) {
+	return value, nil
+}
+
 // GetDebugInfo exposes debug informations about secrets to be included in a flare
 func GetDebugInfo() (*SecretInfo, error) {
 	return nil, fmt.Errorf("Secret feature is not available in this version of the agent")
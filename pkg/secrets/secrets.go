@@ -190,6 +190,23 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 	return finalConfig, nil
 }
 
+// Refresh returns the secret of value fetched again from "secret_backend_command", bypassing the cache,
+// so that a secret rotated in the backend is picked up. The values which are not encrypted are returned as is.
+func Refresh(value string, origin string) (string, error) {
+	ok, handle := isEnc(value)
+	if !ok {
+		return value, nil
+	}
+	if secretBackendCommand == "" {
+		return "", fmt.Errorf("secret '%s' can not be decrypted: no secret_backend_command set", handle)
+	}
+	secrets, err := secretFetcher([]string{handle}, origin)
+	if err != nil {
+		return "", err
+	}
+	return secrets[handle], nil
+}
+
 // GetDebugInfo exposes debug informations about secrets to be included in a flare
 func GetDebugInfo() (*SecretInfo, error) {
 	if secretBackendCommand == "" {
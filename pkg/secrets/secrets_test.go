@@ -300,3 +300,27 @@ func TestDebugInfo(t *testing.T) {
 		"pass3": {"test2"},
 	}, handles)
 }
+
+func TestRefresh(t *testing.T) {
+	secretBackendCommand = "some_command"
+	secretCache["pass1"] = "password1"
+	defer func() {
+		secretBackendCommand = ""
+		secretCache = map[string]string{}
+		secretFetcher = fetchSecret
+	}()
+
+	secretFetcher = func(secrets []string, origin string) (map[string]string, error) {
+		assert.Equal(t, []string{"pass1"}, secrets)
+		return map[string]string{"pass1": "rotated"}, nil
+	}
+
+	// the cache is bypassed
+	secret, err := Refresh("ENC[pass1]", "test")
+	require.Nil(t, err)
+	assert.Equal(t, "rotated", secret)
+
+	secret, err = Refresh("clear", "test")
+	require.Nil(t, err)
+	assert.Equal(t, "clear", secret)
+}